@@ -28,11 +28,23 @@ import (
 	_ "mosn.io/mosn/pkg/filter/listener/originaldst"
 	_ "mosn.io/mosn/pkg/filter/network/connectionmanager"
 	_ "mosn.io/mosn/pkg/filter/network/proxy"
+	_ "mosn.io/mosn/pkg/filter/network/rbac"
 	_ "mosn.io/mosn/pkg/filter/network/tcpproxy"
+	_ "mosn.io/mosn/pkg/filter/network/wasm"
+	_ "mosn.io/mosn/pkg/filter/stream/compressor"
+	_ "mosn.io/mosn/pkg/filter/stream/cors"
+	_ "mosn.io/mosn/pkg/filter/stream/extauthz"
 	_ "mosn.io/mosn/pkg/filter/stream/faultinject"
+	_ "mosn.io/mosn/pkg/filter/stream/headermutation"
+	_ "mosn.io/mosn/pkg/filter/stream/jwtauthn"
+	_ "mosn.io/mosn/pkg/filter/stream/localratelimit"
 	_ "mosn.io/mosn/pkg/filter/stream/mixer"
 	_ "mosn.io/mosn/pkg/filter/stream/payloadlimit"
+	_ "mosn.io/mosn/pkg/filter/stream/ratelimit"
+	_ "mosn.io/mosn/pkg/filter/stream/rbac"
+	_ "mosn.io/mosn/pkg/filter/stream/script"
 	_ "mosn.io/mosn/pkg/filter/stream/transcoder/http2bolt"
+	_ "mosn.io/mosn/pkg/filter/stream/wasm"
 	_ "mosn.io/mosn/pkg/metrics/sink"
 	_ "mosn.io/mosn/pkg/metrics/sink/prometheus"
 	_ "mosn.io/mosn/pkg/network"
@@ -48,9 +60,12 @@ import (
 	_ "mosn.io/mosn/pkg/stream/http"
 	_ "mosn.io/mosn/pkg/stream/http2"
 	_ "mosn.io/mosn/pkg/stream/xprotocol"
+	_ "mosn.io/mosn/pkg/trace/jaeger"
+	_ "mosn.io/mosn/pkg/trace/otel"
 	_ "mosn.io/mosn/pkg/trace/sofa/http"
 	_ "mosn.io/mosn/pkg/trace/sofa/xprotocol"
 	_ "mosn.io/mosn/pkg/trace/sofa/xprotocol/bolt"
+	_ "mosn.io/mosn/pkg/trace/zipkin"
 	_ "mosn.io/mosn/pkg/upstream/healthcheck"
 	_ "mosn.io/mosn/pkg/xds"
 )
@@ -4,6 +4,7 @@ go 1.12
 
 require (
 	github.com/TarsCloud/TarsGo v0.0.0-20181112071624-2d42457f2025
+	github.com/andybalholm/brotli v1.0.5
 	github.com/apache/dubbo-go-hessian2 v1.3.0
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/c2h5oh/datasize v0.0.0-20171227191756-4eba002a5eae
@@ -26,9 +27,11 @@ require (
 	github.com/prometheus/procfs v0.0.3 // indirect
 	github.com/rcrowley/go-metrics v0.0.0-20190826022208-cac0b30c2563
 	github.com/stretchr/testify v1.3.0
+	github.com/tetratelabs/wazero v1.0.0-pre.4
 	github.com/tjfoc/gmsm v0.0.0-20190220013605-bfb01827afcb
 	github.com/urfave/cli v1.20.0
 	github.com/valyala/fasthttp v1.2.0
+	github.com/yuin/gopher-lua v0.0.0-20191220021717-ab39c6098bdb
 	golang.org/x/crypto v0.0.0-20190701094942-4def268fd1a4
 	golang.org/x/net v0.0.0-20190613194153-d28f0bde5980
 	golang.org/x/sys v0.0.0-20190804053845-51ab0e2deafa
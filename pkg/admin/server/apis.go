@@ -19,6 +19,7 @@ package server
 
 import (
 	"bytes"
+	rawjson "encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -30,6 +31,7 @@ import (
 	"mosn.io/mosn/pkg/metrics/sink/console"
 	"mosn.io/mosn/pkg/plugin"
 	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/upstream/cluster"
 )
 
 var levelMap = map[string]log.Level{
@@ -201,3 +203,23 @@ func pluginApi(w http.ResponseWriter, r *http.Request) {
 	log.DefaultLogger.Infof("[admin api] [plugin] url %s", r.URL.RequestURI())
 	plugin.AdminApi(w, r)
 }
+
+// outlierHosts dumps the ejected hosts
+// http://ip:port/api/v1/outlier_hosts
+// http://ip:port/api/v1/outlier_hosts?cluster=clustername
+func outlierHosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.DefaultLogger.Alertf(types.ErrorKeyAdmin, "api: %s, error: invalid method: %s", "outlier hosts", r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	hosts := cluster.GetOutlierHosts(r.URL.Query().Get("cluster"))
+	buf, err := rawjson.Marshal(hosts)
+	if err != nil {
+		log.DefaultLogger.Alertf(types.ErrorKeyAdmin, "api: %s, error: %v", "outlier hosts", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf)
+}
@@ -48,6 +48,7 @@ func init() {
 		"/api/v1/disbale_log":     disableLogger,
 		"/api/v1/states":          getState,
 		"/api/v1/plugin":          pluginApi,
+		"/api/v1/outlier_hosts":   outlierHosts,
 		"/":                       help,
 	}
 }
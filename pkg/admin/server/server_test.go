@@ -19,11 +19,13 @@ package server
 
 import (
 	"bufio"
+	"context"
 	rawjson "encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
 	"strings"
@@ -36,6 +38,7 @@ import (
 	mv2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/upstream/cluster"
 )
 
 func getEffectiveConfig(port uint32) (string, error) {
@@ -434,6 +437,58 @@ func TestHelpAPI(t *testing.T) {
 
 }
 
+func TestOutlierHosts(t *testing.T) {
+	cm := cluster.NewClusterManagerSingleton([]mv2.Cluster{
+		{
+			Name:   "outlier",
+			LbType: mv2.LB_RANDOM,
+			OutlierDetection: &mv2.OutlierDetection{
+				Consecutive5xx:     1,
+				MaxEjectionPercent: 100,
+			},
+		},
+		{
+			Name:   "no_outlier",
+			LbType: mv2.LB_RANDOM,
+		},
+	}, map[string][]mv2.Host{
+		"outlier":    {{HostConfig: mv2.HostConfig{Address: "127.0.0.1:10000"}}},
+		"no_outlier": {{HostConfig: mv2.HostConfig{Address: "127.0.0.1:10000"}}},
+	})
+	defer cm.Destroy()
+	snap := cm.GetClusterSnapshot(context.Background(), "outlier")
+	host := snap.HostSet().Hosts()[0]
+	snap.ClusterInfo().OutlierDetector().PutResult(host, http.StatusServiceUnavailable)
+
+	r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1/api/v1/outlier_hosts", nil)
+	w := httptest.NewRecorder()
+	outlierHosts(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	}
+	result := map[string][]cluster.OutlierHostStatus{}
+	if err := rawjson.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || len(result["outlier"]) != 1 || result["outlier"][0].Address != host.AddressString() {
+		t.Fatalf("unexpected outlier hosts: %s", w.Body.String())
+	}
+	// filter by cluster name
+	r = httptest.NewRequest(http.MethodGet, "http://127.0.0.1/api/v1/outlier_hosts?cluster=no_outlier", nil)
+	w = httptest.NewRecorder()
+	outlierHosts(w, r)
+	if w.Body.String() != "{}" {
+		t.Fatalf("unexpected outlier hosts: %s", w.Body.String())
+	}
+	// invalid method
+	r = httptest.NewRequest(http.MethodPost, "http://127.0.0.1/api/v1/outlier_hosts", nil)
+	w = httptest.NewRecorder()
+	outlierHosts(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	}
+}
+
 func readLines(path string) ([]string, error) {
 	file, err := os.Open(path)
 	if err != nil {
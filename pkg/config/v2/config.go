@@ -23,6 +23,7 @@ import (
 	"github.com/c2h5oh/datasize"
 	xdsboot "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v2"
 	"github.com/gogo/protobuf/jsonpb"
+	"mosn.io/api"
 )
 
 // MOSNConfig make up mosn to start the mosn project
@@ -63,6 +64,17 @@ type MetricsConfig struct {
 	StatsMatcher StatsMatcher      `json:"stats_matcher"`
 	ShmZone      string            `json:"shm_zone"`
 	ShmSize      datasize.ByteSize `json:"shm_size"`
+	Histogram    *HistogramConfig  `json:"histogram,omitempty"`
+}
+
+// HistogramConfig is the bucket layout of latency histograms.
+// Buckets lists the upper bounds, if it is empty, a log-linear layout from Min to Max
+// is generated, every power of ten is split into SubBuckets linear buckets.
+type HistogramConfig struct {
+	Buckets    []api.DurationConfig `json:"buckets,omitempty"`
+	Min        api.DurationConfig   `json:"min,omitempty"`
+	Max        api.DurationConfig   `json:"max,omitempty"`
+	SubBuckets int                  `json:"sub_buckets,omitempty"`
 }
 
 // PluginConfig for plugin config
@@ -56,13 +56,25 @@ const (
 	RPC_PROXY                   = "rpc_proxy"
 	X_PROXY                     = "x_proxy"
 	Transcoder                  = "transcoder"
+	RBAC_NETWORK_FILTER         = "rbac"
+	WASM_NETWORK_FILTER         = "wasm"
 )
 
 // Stream Filter's Type
 const (
-	MIXER        = "mixer"
-	FaultStream  = "fault"
-	PayloadLimit = "payload_limit"
+	MIXER          = "mixer"
+	FaultStream    = "fault"
+	PayloadLimit   = "payload_limit"
+	JwtAuthn       = "jwt_authn"
+	RBAC           = "rbac"
+	ExtAuthz       = "ext_authz"
+	RateLimit      = "ratelimit"
+	LocalRateLimit = "local_ratelimit"
+	Cors           = "cors"
+	Compressor     = "compressor"
+	HeaderMutation = "header_mutation"
+	Wasm           = "wasm"
+	Script         = "script"
 )
 
 // HealthCheckFilter
@@ -148,3 +160,389 @@ type AbortInject struct {
 type Mixer struct {
 	client.HttpClientConfig
 }
+
+// StreamJwtAuthn is the config of the jwt authentication stream filter
+type StreamJwtAuthn struct {
+	// Providers is the jwt providers that can be used in the requirements, the key is the provider name
+	Providers map[string]*JwtProvider `json:"providers,omitempty"`
+	// Requirement is the default requirement, it can be overridden by the route's per filter config
+	Requirement *JwtRequirement `json:"requirement,omitempty"`
+}
+
+// JwtProvider describes how to extract and verify a jwt
+type JwtProvider struct {
+	// Issuer is the expected iss claim, the iss is not checked if it is empty
+	Issuer string `json:"issuer,omitempty"`
+	// Audiences is the allowed aud claims, the aud is not checked if it is empty
+	Audiences []string `json:"audiences,omitempty"`
+	// JwksFile is the local file path of the json web key set
+	JwksFile string `json:"jwks_file,omitempty"`
+	// JwksReloadInterval is the interval to reload the jwks file, default is 5m
+	JwksReloadInterval *api.DurationConfig `json:"jwks_reload_interval,omitempty"`
+	// FromHeaders is the headers to extract the jwt, default is the Authorization header with the "Bearer " prefix
+	FromHeaders []JwtHeader `json:"from_headers,omitempty"`
+	// FromParams is the query parameters to extract the jwt
+	FromParams []string `json:"from_params,omitempty"`
+	// Forward keeps the jwt header in the request that sends to upstream
+	Forward bool `json:"forward,omitempty"`
+	// ForwardPayloadHeader is the header to forward the base64url encoded jwt payload if it is not empty
+	ForwardPayloadHeader string `json:"forward_payload_header,omitempty"`
+	// ClockSkewSeconds is the allowed clock skew when checks the exp and nbf claims, default is 60
+	ClockSkewSeconds uint32 `json:"clock_skew_seconds,omitempty"`
+}
+
+// JwtHeader is a header that the jwt is extracted from
+type JwtHeader struct {
+	Name        string `json:"name,omitempty"`
+	ValuePrefix string `json:"value_prefix,omitempty"`
+}
+
+// JwtRequirement describes the jwt that a request should carry, it is also used as the per route config
+type JwtRequirement struct {
+	// ProviderName is the provider used to verify the jwt
+	ProviderName string `json:"provider_name,omitempty"`
+	// AllowMissing allows the requests without jwt, but the requests with invalid jwt are still rejected
+	AllowMissing bool `json:"allow_missing,omitempty"`
+	// Disabled disables the jwt authentication
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// RBACConfig is the config of the rbac stream filter and network filter
+type RBACConfig struct {
+	// StatPrefix is the prefix of the rbac metrics, default is rbac
+	StatPrefix string `json:"stat_prefix,omitempty"`
+	// Rules is the enforced rules, all requests are allowed if it is nil
+	Rules *RBACRules `json:"rules,omitempty"`
+	// ShadowRules is the dry-run rules, the decisions are only logged and counted
+	ShadowRules *RBACRules `json:"shadow_rules,omitempty"`
+}
+
+// RBAC actions
+const (
+	RBACActionAllow = "ALLOW"
+	RBACActionDeny  = "DENY"
+)
+
+// RBACRules is a set of policies.
+// If the action is ALLOW, a request is allowed only if it matches any of the policies.
+// If the action is DENY, a request is denied if it matches any of the policies
+type RBACRules struct {
+	Action   string                 `json:"action,omitempty"`
+	Policies map[string]*RBACPolicy `json:"policies,omitempty"`
+}
+
+// RBACPolicy matches a request if any of the permissions and any of the principals are matched
+type RBACPolicy struct {
+	Permissions []*RBACPermission `json:"permissions,omitempty"`
+	Principals  []*RBACPrincipal  `json:"principals,omitempty"`
+}
+
+// RBACPermission describes the actions of a request.
+// All of the configured conditions should be matched, and a list condition is matched if any of the items is matched
+type RBACPermission struct {
+	Any bool `json:"any,omitempty"`
+	// Paths matches the http path
+	Paths []RBACStringMatcher `json:"paths,omitempty"`
+	// Methods matches the http method
+	Methods []string `json:"methods,omitempty"`
+	// Headers should be all matched
+	Headers []HeaderMatcher `json:"headers,omitempty"`
+	// Services matches the rpc service, such as the bolt service or the dubbo interface
+	Services []RBACStringMatcher `json:"services,omitempty"`
+	// RPCMethods matches the rpc method, such as the dubbo method
+	RPCMethods []string `json:"rpc_methods,omitempty"`
+	// DestinationPorts matches the local port of the connection
+	DestinationPorts []uint32 `json:"destination_ports,omitempty"`
+}
+
+// RBACPrincipal describes the identities of a request.
+// All of the configured conditions should be matched, and a list condition is matched if any of the items is matched
+type RBACPrincipal struct {
+	Any bool `json:"any,omitempty"`
+	// Authenticated matches the mtls peer certificate's URI SAN, DNS SAN or subject common name
+	Authenticated []RBACStringMatcher `json:"authenticated,omitempty"`
+	// SourceIPs matches the remote address of the connection in CIDR, such as 10.0.0.0/8
+	SourceIPs []string `json:"source_ips,omitempty"`
+	// Headers should be all matched
+	Headers []HeaderMatcher `json:"headers,omitempty"`
+	// JwtClaims matches the verified jwt claims, the key is the claim name.
+	// The claims should be all matched, an array claim is matched if any of the items is matched
+	JwtClaims map[string]RBACStringMatcher `json:"jwt_claims,omitempty"`
+}
+
+// RBACStringMatcher matches a string, only one of the fields should be set
+type RBACStringMatcher struct {
+	Exact  string `json:"exact,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	Suffix string `json:"suffix,omitempty"`
+	Regex  string `json:"regex,omitempty"`
+}
+
+// External authorization service protocols
+const (
+	ExtAuthzGRPC = "grpc"
+	ExtAuthzHTTP = "http"
+)
+
+// StreamExtAuthz is the config of the external authorization stream filter
+type StreamExtAuthz struct {
+	// Cluster is the cluster of the authorization service
+	Cluster string `json:"cluster,omitempty"`
+	// Protocol is the protocol of the authorization service, grpc or http, default is grpc.
+	// The grpc service implements envoy.service.auth.v2.Authorization
+	Protocol string `json:"protocol,omitempty"`
+	// Timeout is the timeout of the authorization request, default is 200ms
+	Timeout *api.DurationConfig `json:"timeout,omitempty"`
+	// FailureModeAllow allows the request if the authorization service fails
+	FailureModeAllow bool `json:"failure_mode_allow,omitempty"`
+	// StatusOnError is the response status when the authorization service fails, default is 403
+	StatusOnError int `json:"status_on_error,omitempty"`
+	// WithRequestBody sends the request body prefix to the authorization service,
+	// only the http authorization service is supported, the v2 grpc attributes have no body
+	WithRequestBody *ExtAuthzRequestBody `json:"with_request_body,omitempty"`
+	// HTTPService is the options of the http authorization service
+	HTTPService *ExtAuthzHTTPService `json:"http_service,omitempty"`
+}
+
+// ExtAuthzRequestBody describes how much request body is sent to the authorization service
+type ExtAuthzRequestBody struct {
+	// MaxRequestBytes is the max size of the body prefix, default is 8192
+	MaxRequestBytes uint32 `json:"max_request_bytes,omitempty"`
+}
+
+// ExtAuthzHTTPService is the options of the http authorization service.
+// The authorization request uses the original method and path, and the request is allowed if the response status is 200
+type ExtAuthzHTTPService struct {
+	// PathPrefix is added to the original path
+	PathPrefix string `json:"path_prefix,omitempty"`
+	// AllowedHeaders is the request headers sent to the authorization service,
+	// the Host, Method, Path and Content-Length headers are always sent
+	AllowedHeaders []string `json:"allowed_headers,omitempty"`
+	// AllowedUpstreamHeaders is the headers in the ok response that added to the upstream request
+	AllowedUpstreamHeaders []string `json:"allowed_upstream_headers,omitempty"`
+	// AllowedClientHeaders is the headers in the denied response that sent to the client
+	AllowedClientHeaders []string `json:"allowed_client_headers,omitempty"`
+}
+
+// StreamRateLimit is the config of the global rate limit stream filter,
+// the filter queries a rate limit service that implements envoy.service.ratelimit.v2.RateLimitService
+type StreamRateLimit struct {
+	// Domain is the rate limit domain sent to the rate limit service
+	Domain string `json:"domain,omitempty"`
+	// Cluster is the cluster of the rate limit service
+	Cluster string `json:"cluster,omitempty"`
+	// Timeout is the timeout of the rate limit request, default is 20ms
+	Timeout *api.DurationConfig `json:"timeout,omitempty"`
+	// FailureModeDeny rejects the request if the rate limit service fails, default is false (fail open)
+	FailureModeDeny bool `json:"failure_mode_deny,omitempty"`
+	// StatPrefix is the prefix of the rate limit metrics, default is ratelimit
+	StatPrefix string `json:"stat_prefix,omitempty"`
+	// RateLimits describes how to make the descriptors, a rate limit policy makes one descriptor.
+	// It can be overwritten by the route's per filter config
+	RateLimits []RateLimitPolicy `json:"rate_limits,omitempty"`
+	// LocalTokenBucket is checked before the rate limit service is queried,
+	// the request is rejected without querying the service if there is no token
+	LocalTokenBucket *TokenBucketConfig `json:"local_token_bucket,omitempty"`
+	// EnableXRateLimitHeaders adds the x-ratelimit-limit, x-ratelimit-remaining and x-ratelimit-reset headers to the response
+	EnableXRateLimitHeaders bool `json:"enable_x_ratelimit_headers,omitempty"`
+}
+
+// RateLimitPerRoute is the route's per filter config of the global rate limit stream filter
+type RateLimitPerRoute struct {
+	// Disabled skips the rate limit for the route
+	Disabled bool `json:"disabled,omitempty"`
+	// RateLimits replaces the filter's rate limit policies
+	RateLimits []RateLimitPolicy `json:"rate_limits,omitempty"`
+}
+
+// RateLimitPolicy makes a descriptor by the actions, each action appends a descriptor entry.
+// The descriptor is not sent if any action can not make the entry
+type RateLimitPolicy struct {
+	Actions []RateLimitAction `json:"actions,omitempty"`
+}
+
+// RateLimitAction makes a descriptor entry, only one of the actions should be set
+type RateLimitAction struct {
+	// RequestHeaders makes an entry with the request header value
+	RequestHeaders *RateLimitRequestHeaders `json:"request_headers,omitempty"`
+	// RemoteAddress makes an entry with the key remote_address and the downstream ip
+	RemoteAddress *RateLimitRemoteAddress `json:"remote_address,omitempty"`
+	// GenericKey makes an entry with the constant value
+	GenericKey *RateLimitGenericKey `json:"generic_key,omitempty"`
+	// ServiceName makes an entry with the rpc service name, such as the bolt service
+	ServiceName *RateLimitServiceName `json:"service_name,omitempty"`
+}
+
+type RateLimitRequestHeaders struct {
+	HeaderName    string `json:"header_name,omitempty"`
+	DescriptorKey string `json:"descriptor_key,omitempty"`
+}
+
+type RateLimitRemoteAddress struct{}
+
+type RateLimitGenericKey struct {
+	// DescriptorKey is the entry key, default is generic_key
+	DescriptorKey   string `json:"descriptor_key,omitempty"`
+	DescriptorValue string `json:"descriptor_value,omitempty"`
+}
+
+type RateLimitServiceName struct {
+	// DescriptorKey is the entry key, default is service_name
+	DescriptorKey string `json:"descriptor_key,omitempty"`
+}
+
+// TokenBucketConfig is the config of a token bucket
+type TokenBucketConfig struct {
+	// MaxTokens is the bucket size, the bucket is full at the beginning
+	MaxTokens uint32 `json:"max_tokens,omitempty"`
+	// TokensPerFill is the tokens added every fill interval, default is MaxTokens
+	TokensPerFill uint32 `json:"tokens_per_fill,omitempty"`
+	// FillInterval is the interval of adding tokens
+	FillInterval *api.DurationConfig `json:"fill_interval,omitempty"`
+}
+
+// StreamLocalRateLimit is the config of the local rate limit stream filter,
+// it can be overwritten by the virtual host's or the route's per filter config
+type StreamLocalRateLimit struct {
+	// StatPrefix is the prefix of the local rate limit metrics, default is local_ratelimit
+	StatPrefix string `json:"stat_prefix,omitempty"`
+	// Disabled skips the rate limit, it is used in the per filter config usually
+	Disabled bool `json:"disabled,omitempty"`
+	// StatusCode is the response status of the limited request, default is 429.
+	// The xprotocol maps it to the protocol's status, such as the server busy status of bolt and dubbo
+	StatusCode int `json:"status_code,omitempty"`
+	// Descriptors are checked in order, a request consumes a token from every matched descriptor's bucket,
+	// and it is limited if any bucket is empty
+	Descriptors []LocalRateLimitDescriptor `json:"descriptors,omitempty"`
+}
+
+// Local rate limit descriptor key types
+const (
+	LocalRateLimitKeyRoute         = "route"
+	LocalRateLimitKeyHeader        = "header"
+	LocalRateLimitKeyRemoteAddress = "remote_address"
+)
+
+// LocalRateLimitDescriptor describes the token buckets keyed by the route, a header value or the client ip
+type LocalRateLimitDescriptor struct {
+	// Name is used in the metrics, default is the key type
+	Name string `json:"name,omitempty"`
+	// Key is the bucket key type, route, header or remote_address
+	Key string `json:"key,omitempty"`
+	// HeaderName is the header used as the bucket key if the key type is header
+	HeaderName string `json:"header_name,omitempty"`
+	// Values restricts the key values, the descriptor is not matched if the value is not in it.
+	// Empty means any value matches
+	Values []string `json:"values,omitempty"`
+	// TokenBucket is the config of the buckets, each key value has its own bucket
+	TokenBucket TokenBucketConfig `json:"token_bucket,omitempty"`
+	// MaxBuckets is the max number of the buckets, the least recently used bucket is evicted, default is 1024
+	MaxBuckets int `json:"max_buckets,omitempty"`
+}
+
+// StreamCors is the config of the cors stream filter,
+// the cors policies are configured in the virtual hosts and the routes
+type StreamCors struct {
+	// StatPrefix is the prefix of the cors metrics, default is cors
+	StatPrefix string `json:"stat_prefix,omitempty"`
+}
+
+// StreamCompressor is the config of the compressor stream filter,
+// the responses are compressed by the encoding negotiated with the request's Accept-Encoding
+type StreamCompressor struct {
+	// StatPrefix is the prefix of the compressor metrics, default is compressor
+	StatPrefix string `json:"stat_prefix,omitempty"`
+	// Encodings is the supported content codings in the order of preference, default is gzip, deflate and br
+	Encodings []string `json:"encodings,omitempty"`
+	// Level is the compression level from -2 (huffman only) to 9 (best compression),
+	// 0 means the default level
+	Level int `json:"level,omitempty"`
+	// MinContentLength is the min size of the response body to be compressed, default is 30
+	MinContentLength int `json:"min_content_length,omitempty"`
+	// ContentTypes is the media types of the responses to be compressed, default is the common text types
+	ContentTypes []string `json:"content_types,omitempty"`
+	// DisableOnETag disables the compression if the response has an ETag,
+	// otherwise a strong ETag is converted to a weak one
+	DisableOnETag bool `json:"disable_on_etag,omitempty"`
+	// RemoveAcceptEncoding removes the Accept-Encoding from the request, so the upstream does not compress the response
+	RemoveAcceptEncoding bool `json:"remove_accept_encoding,omitempty"`
+	// Decompressor decompresses the compressed request bodies before they are sent to the upstream, nil means disabled
+	Decompressor *CompressorDecompressor `json:"decompressor,omitempty"`
+}
+
+// CompressorDecompressor is the config of the request decompression
+type CompressorDecompressor struct {
+	// MaxBodyBytes is the max size of the decompressed request body, default is 4MB
+	MaxBodyBytes int64 `json:"max_body_bytes,omitempty"`
+}
+
+// StreamHeaderMutation is the config of the header mutation stream filter,
+// the mutations are applied in order
+type StreamHeaderMutation struct {
+	// RequestMutations mutates the request headers before they are sent to the upstream
+	RequestMutations []HeaderMutationRule `json:"request_mutations,omitempty"`
+	// ResponseMutations mutates the response headers before they are sent to the downstream
+	ResponseMutations []HeaderMutationRule `json:"response_mutations,omitempty"`
+}
+
+// The actions of the header mutation
+const (
+	// HeaderMutationAdd adds a value to the header, the value is joined to the existing value by a comma
+	HeaderMutationAdd = "add"
+	// HeaderMutationSet sets the header, the existing values are replaced
+	HeaderMutationSet = "set"
+	// HeaderMutationRemove removes the header
+	HeaderMutationRemove = "remove"
+	// HeaderMutationRename renames the header, the value is kept
+	HeaderMutationRename = "rename"
+)
+
+// HeaderMutationRule mutates a header
+type HeaderMutationRule struct {
+	Action string `json:"action,omitempty"`
+	Name   string `json:"name,omitempty"`
+	// Value is the value to be added or set, the variables can be used in it, such as %downstream_remote_address%.
+	// The header is not added or set if the value is empty
+	Value string `json:"value,omitempty"`
+	// NewName is the new name of the renamed header
+	NewName string `json:"new_name,omitempty"`
+	// Headers are the conditions of the mutation, matched against the headers to be mutated
+	Headers []HeaderMatcher `json:"headers,omitempty"`
+}
+
+// WasmPluginConfig is the config of a proxy-wasm plugin, it is used by the wasm stream filter and network filter.
+// The filters that have the same plugin name share the wasm instances
+type WasmPluginConfig struct {
+	// Name is the plugin name, it is also used as the vm id
+	Name string `json:"name,omitempty"`
+	// Engine is the name of the registered wasm engine, default is wazero which requires the wazero build tag
+	Engine string `json:"engine,omitempty"`
+	// Path is the path of the wasm module file
+	Path string `json:"path,omitempty"`
+	// InstanceNum is the number of the wasm instances, default is the number of cpus
+	InstanceNum int `json:"instance_num,omitempty"`
+	// RootID is the root context id of the plugin in the module
+	RootID string `json:"root_id,omitempty"`
+	// VMConfig is passed to proxy_on_vm_start, a non-string value is passed as json
+	VMConfig interface{} `json:"vm_config,omitempty"`
+	// Configuration is passed to proxy_on_configure, a non-string value is passed as json
+	Configuration interface{} `json:"configuration,omitempty"`
+	// ReloadInterval is the interval to check the module file, the plugin is reloaded if the file is changed.
+	// Empty means the module is never reloaded
+	ReloadInterval *api.DurationConfig `json:"reload_interval,omitempty"`
+}
+
+// StreamScript is the config of the script stream filter, it can be overwritten by the virtual host's or the route's per filter config.
+// The lua script defines the global functions on_receive(handle) and on_append(handle), both are optional
+type StreamScript struct {
+	// Disabled skips the script, it is used in the per filter config usually
+	Disabled bool `json:"disabled,omitempty"`
+	// Source is the lua script
+	Source string `json:"source,omitempty"`
+	// Path is the file of the lua script, it is used if the source is empty
+	Path string `json:"path,omitempty"`
+	// MaxInstructions is the max number of the lua vm instructions in an invocation, default is 100000
+	MaxInstructions int64 `json:"max_instructions,omitempty"`
+	// Timeout is the max execution time of an invocation, default is 10ms
+	Timeout *api.DurationConfig `json:"timeout,omitempty"`
+}
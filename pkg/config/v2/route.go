@@ -43,24 +43,39 @@ type RouterConfig struct {
 	Match           RouterMatch            `json:"match,omitempty"`
 	Route           RouteAction            `json:"route,omitempty"`
 	DirectResponse  *DirectResponseAction  `json:"direct_response,omitempty"`
+	Redirect        *RedirectAction        `json:"redirect,omitempty"`
 	MetadataConfig  *MetadataConfig        `json:"metadata,omitempty"`
 	PerFilterConfig map[string]interface{} `json:"per_filter_config,omitempty"`
+	// CorsPolicy overrides the virtual host's cors policy
+	CorsPolicy *CorsPolicy `json:"cors,omitempty"`
 }
 
 type RouterActionConfig struct {
-	ClusterName             string               `json:"cluster_name,omitempty"`
-	UpstreamProtocol        string               `json:"upstream_protocol,omitempty"`
-	ClusterHeader           string               `json:"cluster_header,omitempty"`
-	WeightedClusters        []WeightedCluster    `json:"weighted_clusters,omitempty"`
-	MetadataConfig          *MetadataConfig      `json:"metadata_match,omitempty"`
-	TimeoutConfig           api.DurationConfig   `json:"timeout,omitempty"`
-	RetryPolicy             *RetryPolicy         `json:"retry_policy,omitempty"`
-	PrefixRewrite           string               `json:"prefix_rewrite,omitempty"`
-	HostRewrite             string               `json:"host_rewrite,omitempty"`
-	AutoHostRewrite         bool                 `json:"auto_host_rewrite,omitempty"`
-	RequestHeadersToAdd     []*HeaderValueOption `json:"request_headers_to_add,omitempty"`
-	ResponseHeadersToAdd    []*HeaderValueOption `json:"response_headers_to_add,omitempty"`
-	ResponseHeadersToRemove []string             `json:"response_headers_to_remove,omitempty"`
+	ClusterName             string                 `json:"cluster_name,omitempty"`
+	UpstreamProtocol        string                 `json:"upstream_protocol,omitempty"`
+	ClusterHeader           string                 `json:"cluster_header,omitempty"`
+	WeightedClusters        []WeightedCluster      `json:"weighted_clusters,omitempty"`
+	MetadataConfig          *MetadataConfig        `json:"metadata_match,omitempty"`
+	TimeoutConfig           api.DurationConfig     `json:"timeout,omitempty"`
+	RetryPolicy             *RetryPolicy           `json:"retry_policy,omitempty"`
+	HashPolicy              []HashPolicy           `json:"hash_policy,omitempty"`
+	RequestMirrorPolicies   []*RequestMirrorPolicy `json:"request_mirror_policies,omitempty"`
+	PrefixRewrite           string                 `json:"prefix_rewrite,omitempty"`
+	RegexRewrite            *RegexRewrite          `json:"regex_rewrite,omitempty"`
+	HostRewrite             string                 `json:"host_rewrite,omitempty"`
+	HostRewriteHeader       string                 `json:"host_rewrite_header,omitempty"`
+	HostRewritePathRegex    *RegexRewrite          `json:"host_rewrite_path_regex,omitempty"`
+	AutoHostRewrite         bool                   `json:"auto_host_rewrite,omitempty"`
+	RequestHeadersToAdd     []*HeaderValueOption   `json:"request_headers_to_add,omitempty"`
+	ResponseHeadersToAdd    []*HeaderValueOption   `json:"response_headers_to_add,omitempty"`
+	ResponseHeadersToRemove []string               `json:"response_headers_to_remove,omitempty"`
+}
+
+// RegexRewrite rewrites the parts matched by the Pattern with the Substitution.
+// The Substitution can reference the capture groups in the Pattern, such as \1 or ${1}
+type RegexRewrite struct {
+	Pattern      string `json:"pattern,omitempty"`
+	Substitution string `json:"substitution,omitempty"`
 }
 
 type ClusterWeightConfig struct {
@@ -73,6 +88,17 @@ type RetryPolicyConfig struct {
 	RetryOn            bool               `json:"retry_on,omitempty"`
 	RetryTimeoutConfig api.DurationConfig `json:"retry_timeout,omitempty"`
 	NumRetries         uint32             `json:"num_retries,omitempty"`
+	// RetryOnConditions is the envoy style retry conditions separated by comma, such as "5xx,connect-failure".
+	// If it is empty, the default conditions are used when RetryOn is true.
+	RetryOnConditions    string              `json:"retry_on_conditions,omitempty"`
+	RetriableStatusCodes []uint32            `json:"retriable_status_codes,omitempty"`
+	RetryBackOff         *RetryBackOffConfig `json:"retry_back_off,omitempty"`
+}
+
+// RetryBackOffConfig is the exponential back off parameters between retries
+type RetryBackOffConfig struct {
+	BaseInterval api.DurationConfig `json:"base_interval,omitempty"`
+	MaxInterval  api.DurationConfig `json:"max_interval,omitempty"`
 }
 
 // Router, the list of routes that will be matched, in order, for incoming requests.
@@ -161,6 +187,56 @@ func (rp *RetryPolicy) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// HashPolicy specifies how the hash key used by consistent hash load balancers is generated.
+// Only one of the policy specifiers should be set.
+type HashPolicy struct {
+	Header   *HeaderHashPolicy   `json:"header,omitempty"`
+	Cookie   *CookieHashPolicy   `json:"cookie,omitempty"`
+	SourceIP *SourceIPHashPolicy `json:"source_ip,omitempty"`
+	Variable *VariableHashPolicy `json:"variable,omitempty"`
+}
+
+// HeaderHashPolicy uses a request header's value as the hash key
+type HeaderHashPolicy struct {
+	Key string `json:"key,omitempty"`
+}
+
+// CookieHashPolicy uses a request cookie's value as the hash key
+type CookieHashPolicy struct {
+	Name string `json:"name,omitempty"`
+}
+
+// SourceIPHashPolicy uses the downstream connection's source ip as the hash key
+type SourceIPHashPolicy struct {
+}
+
+// VariableHashPolicy uses a variable's value as the hash key
+type VariableHashPolicy struct {
+	Name string `json:"name,omitempty"`
+}
+
+// RequestMirrorPolicy mirrors the requests to a shadow cluster in fire-and-forget mode,
+// the responses of the shadow cluster are ignored.
+type RequestMirrorPolicy struct {
+	Cluster string `json:"cluster,omitempty"`
+	// RuntimeFraction is the percentage of requests to be mirrored, all the requests are mirrored if it is nil
+	RuntimeFraction *FractionalPercent `json:"runtime_fraction,omitempty"`
+}
+
+// The denominators of FractionalPercent
+const (
+	DenominatorHundred     = "HUNDRED"
+	DenominatorTenThousand = "TEN_THOUSAND"
+	DenominatorMillion     = "MILLION"
+)
+
+// FractionalPercent represents a fractional percentage: numerator / denominator,
+// the denominator is HUNDRED by default.
+type FractionalPercent struct {
+	Numerator   uint32 `json:"numerator,omitempty"`
+	Denominator string `json:"denominator,omitempty"`
+}
+
 // HeaderValueOption is header name/value pair plus option to control append behavior.
 type HeaderValueOption struct {
 	Header *HeaderValue `json:"header,omitempty"`
@@ -265,14 +341,52 @@ type VirtualHost struct {
 	RequestHeadersToAdd     []*HeaderValueOption `json:"request_headers_to_add,omitempty"`
 	ResponseHeadersToAdd    []*HeaderValueOption `json:"response_headers_to_add,omitempty"`
 	ResponseHeadersToRemove []string             `json:"response_headers_to_remove,omitempty"`
+	// PerFilterConfig is the default per filter config of the routes in the virtual host,
+	// the route's per filter config overrides it by the filter name
+	PerFilterConfig map[string]interface{} `json:"per_filter_config,omitempty"`
+	// CorsPolicy is the default cors policy of the routes in the virtual host
+	CorsPolicy *CorsPolicy `json:"cors,omitempty"`
+}
+
+// CorsPolicy is the cors policy handled by the cors stream filter
+type CorsPolicy struct {
+	// AllowOrigins are the allowed origins, an exact origin "*" allows any origin
+	AllowOrigins []CorsOriginMatcher `json:"allow_origins,omitempty"`
+	// AllowMethods is the value of the Access-Control-Allow-Methods header
+	AllowMethods []string `json:"allow_methods,omitempty"`
+	// AllowHeaders is the value of the Access-Control-Allow-Headers header
+	AllowHeaders []string `json:"allow_headers,omitempty"`
+	// ExposeHeaders is the value of the Access-Control-Expose-Headers header
+	ExposeHeaders []string `json:"expose_headers,omitempty"`
+	// MaxAge is the seconds of the Access-Control-Max-Age header, zero means the header is not set
+	MaxAge int `json:"max_age,omitempty"`
+	// AllowCredentials sets the Access-Control-Allow-Credentials header
+	AllowCredentials bool `json:"allow_credentials,omitempty"`
+	// Disabled disables the policy, the requests are not handled by the cors filter
+	Disabled bool `json:"disabled,omitempty"`
+	// ShadowMode evaluates the policy and records the stats, but does not change the requests and responses
+	ShadowMode bool `json:"shadow_mode,omitempty"`
+}
+
+// CorsOriginMatcher matches the origin, only one of the fields should be set
+type CorsOriginMatcher struct {
+	Exact string `json:"exact,omitempty"`
+	Regex string `json:"regex,omitempty"`
 }
 
 // RouterMatch represents the route matching parameters
 type RouterMatch struct {
-	Prefix  string          `json:"prefix,omitempty"`  // Match request's Path with Prefix Comparing
-	Path    string          `json:"path,omitempty"`    // Match request's Path with Exact Comparing
-	Regex   string          `json:"regex,omitempty"`   // Match request's Path with Regex Comparing
-	Headers []HeaderMatcher `json:"headers,omitempty"` // Match request's Headers
+	Prefix          string                  `json:"prefix,omitempty"`           // Match request's Path with Prefix Comparing
+	Path            string                  `json:"path,omitempty"`             // Match request's Path with Exact Comparing
+	Regex           string                  `json:"regex,omitempty"`            // Match request's Path with Regex Comparing
+	Headers         []HeaderMatcher         `json:"headers,omitempty"`          // Match request's Headers
+	QueryParameters []QueryParameterMatcher `json:"query_parameters,omitempty"` // Match request's Query Parameters
+	Methods         []string                `json:"methods,omitempty"`          // Match request's Method, case insensitive
+	// CaseSensitive indicates whether the path matching is case sensitive.
+	// If it is not set, the exact path matching is case insensitive and the others are case sensitive.
+	CaseSensitive *bool `json:"case_sensitive,omitempty"`
+	// RuntimeFraction matches the specified fraction of the requests
+	RuntimeFraction *FractionalPercent `json:"runtime_fraction,omitempty"`
 }
 
 // DirectResponseAction represents the direct response parameters
@@ -281,6 +395,19 @@ type DirectResponseAction struct {
 	Body       string `json:"body,omitempty"`
 }
 
+// RedirectAction represents the redirect response parameters
+type RedirectAction struct {
+	SchemeRedirect string `json:"scheme_redirect,omitempty"`
+	HostRedirect   string `json:"host_redirect,omitempty"`
+	PortRedirect   uint32 `json:"port_redirect,omitempty"`
+	// PathRedirect and PrefixRewrite are mutually exclusive
+	PathRedirect  string `json:"path_redirect,omitempty"`
+	PrefixRewrite string `json:"prefix_rewrite,omitempty"`
+	// ResponseCode is one of 301, 302, 303, 307 and 308, default is 301
+	ResponseCode int  `json:"response_code,omitempty"`
+	StripQuery   bool `json:"strip_query,omitempty"`
+}
+
 // WeightedCluster.
 // Multiple upstream clusters unsupport stream filter type:  healthcheckcan be specified for a given route.
 // The request is routed to one of the upstream
@@ -294,6 +421,28 @@ type HeaderMatcher struct {
 	Name  string `json:"name,omitempty"`
 	Value string `json:"value,omitempty"`
 	Regex bool   `json:"regex,omitempty"`
+	// The following matchers are mutually exclusive with Value and Regex
+	PresentMatch bool        `json:"present_match,omitempty"` // the header is present
+	AbsentMatch  bool        `json:"absent_match,omitempty"`  // the header is absent
+	PrefixMatch  string      `json:"prefix_match,omitempty"`  // the header value has the prefix
+	SuffixMatch  string      `json:"suffix_match,omitempty"`  // the header value has the suffix
+	RangeMatch   *Int64Range `json:"range_match,omitempty"`   // the header value is an integer in the range
+	// InvertMatch inverts the match result
+	InvertMatch bool `json:"invert_match,omitempty"`
+}
+
+// Int64Range specifies the int64 range [Start, End)
+type Int64Range struct {
+	Start int64 `json:"start,omitempty"`
+	End   int64 `json:"end,omitempty"`
+}
+
+// QueryParameterMatcher matches the request's query parameter.
+// If the Value is empty and Regex is false, only the presence of the parameter is matched.
+type QueryParameterMatcher struct {
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+	Regex bool   `json:"regex,omitempty"`
 }
 
 // TCP Proxy Route
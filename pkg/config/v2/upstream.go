@@ -47,6 +47,19 @@ type HostConfig struct {
 	Weight         uint32          `json:"weight,omitempty"`
 	MetaDataConfig *MetadataConfig `json:"metadata,omitempty"`
 	TLSDisable     bool            `json:"tls_disable,omitempty"`
+	// Priority is the priority level of the host, 0 is the highest priority
+	Priority uint32 `json:"priority,omitempty"`
+	// Locality is the location that the host belongs to
+	Locality *Locality `json:"locality,omitempty"`
+	// LocalityWeight is the load balancing weight of the host's locality
+	LocalityWeight uint32 `json:"locality_weight,omitempty"`
+}
+
+// Locality identifies where a host is running
+type Locality struct {
+	Region  string `json:"region,omitempty"`
+	Zone    string `json:"zone,omitempty"`
+	SubZone string `json:"sub_zone,omitempty"`
 }
 
 // ClusterType
@@ -67,6 +80,11 @@ type LbType string
 const (
 	LB_RANDOM     LbType = "LB_RANDOM"
 	LB_ROUNDROBIN LbType = "LB_ROUNDROBIN"
+
+	LB_WEIGHTED_ROUNDROBIN LbType = "LB_WEIGHTED_ROUNDROBIN"
+	LB_LEAST_REQUEST       LbType = "LB_LEAST_REQUEST"
+	LB_RINGHASH            LbType = "LB_RINGHASH"
+	LB_MAGLEV              LbType = "LB_MAGLEV"
 )
 
 // Cluster represents a cluster's information
@@ -85,6 +103,16 @@ type Cluster struct {
 	TLS                  TLSConfig           `json:"tls_context,omitempty"`
 	Hosts                []Host              `json:"hosts,omitempty"`
 	ConnectTimeout       *api.DurationConfig `json:"connect_timeout,omitempty"`
+	OutlierDetection     *OutlierDetection   `json:"outlier_detection,omitempty"`
+	// OverprovisioningFactor is used to calculate the health of a priority level or a locality,
+	// the health is min(100, healthy_percent * overprovisioning_factor / 100), default is 140
+	OverprovisioningFactor uint32 `json:"overprovisioning_factor,omitempty"`
+	// HealthyPanicThreshold is the percent of healthy hosts that below it the load balancer is in panic mode,
+	// and the requests are spread across all hosts regardless of the health. Zero means the panic mode is disabled
+	HealthyPanicThreshold uint32 `json:"healthy_panic_threshold,omitempty"`
+	// HeartbeatKeepAlive enables the heartbeat keepalive on the connections of the xprotocol sub protocols
+	// whose keepalive is optional, such as dubbo. The other sub protocols always keep alive
+	HeartbeatKeepAlive bool `json:"heartbeat_keepalive,omitempty"`
 }
 
 // HealthCheck is a configuration of health check
@@ -114,6 +142,54 @@ func (hc *HealthCheck) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// HTTPHealthCheckConfig is the check_config of the http health check
+type HTTPHealthCheckConfig struct {
+	Path string `json:"path,omitempty"` // the request path, default is "/"
+	Host string `json:"host,omitempty"` // the request host, default is the host's address
+	// ExpectedStatuses are the status code ranges that considered healthy, default is 200 only
+	ExpectedStatuses []Int64Range `json:"expected_statuses,omitempty"`
+	// ResponseContains is the substring that the response body must contain, ignored if empty
+	ResponseContains string `json:"response_contains,omitempty"`
+}
+
+// GRPCHealthCheckConfig is the check_config of the grpc health checking protocol
+type GRPCHealthCheckConfig struct {
+	ServiceName string `json:"service_name,omitempty"` // the service name in grpc.health.v1.HealthCheckRequest
+	Authority   string `json:"authority,omitempty"`    // the :authority header, default is the host's address
+}
+
+// OutlierDetection is a configuration of outlier detection, the detection is disabled if the threshold is zero
+type OutlierDetection struct {
+	// Consecutive5xx is the number of consecutive 5xx responses before a host is ejected
+	Consecutive5xx uint32 `json:"consecutive_5xx,omitempty"`
+	// ConsecutiveGatewayFailure is the number of consecutive 502, 503 and 504 responses before a host is ejected
+	ConsecutiveGatewayFailure uint32 `json:"consecutive_gateway_failure,omitempty"`
+	// Interval is the time interval between ejection analysis sweeps, default is 10s
+	Interval *api.DurationConfig `json:"interval,omitempty"`
+	// BaseEjectionTime is the base time that a host is ejected for, the real time is
+	// equal to the base time multiplied by the number of times the host has been ejected, default is 30s
+	BaseEjectionTime *api.DurationConfig `json:"base_ejection_time,omitempty"`
+	// MaxEjectionPercent is the maximum percent of hosts that can be ejected, default is 10
+	MaxEjectionPercent uint32 `json:"max_ejection_percent,omitempty"`
+	// SuccessRateMinimumHosts is the number of hosts with enough request volume
+	// required to do success rate analysis, default is 5
+	SuccessRateMinimumHosts uint32 `json:"success_rate_minimum_hosts,omitempty"`
+	// SuccessRateRequestVolume is the minimum number of requests in an interval
+	// to include a host in success rate analysis, default is 100
+	SuccessRateRequestVolume uint32 `json:"success_rate_request_volume,omitempty"`
+	// SuccessRateStdevFactor is used to determine the ejection threshold for success rate analysis,
+	// the threshold is mean - (stdev * success_rate_stdev_factor / 1000)
+	SuccessRateStdevFactor uint32 `json:"success_rate_stdev_factor,omitempty"`
+	// FailurePercentageThreshold is the failure percentage that a host will be ejected when reaches
+	FailurePercentageThreshold uint32 `json:"failure_percentage_threshold,omitempty"`
+	// FailurePercentageMinimumHosts is the number of hosts with enough request volume
+	// required to do failure percentage analysis, default is 5
+	FailurePercentageMinimumHosts uint32 `json:"failure_percentage_minimum_hosts,omitempty"`
+	// FailurePercentageRequestVolume is the minimum number of requests in an interval
+	// to include a host in failure percentage analysis, default is 50
+	FailurePercentageRequestVolume uint32 `json:"failure_percentage_request_volume,omitempty"`
+}
+
 // Host represenets a host information
 type Host struct {
 	HostConfig
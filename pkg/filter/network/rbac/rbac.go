@@ -0,0 +1,72 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rbac
+
+import (
+	"context"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/rbac"
+	"mosn.io/pkg/buffer"
+)
+
+// rbacFilter authorizes the connection when the first data is received,
+// so the tls handshake is finished and the peer certificate can be matched.
+// Only the connection attributes can be matched, such as the source ip, the destination port and the peer certificate
+type rbacFilter struct {
+	ctx           context.Context
+	authorizer    *rbac.Authorizer
+	authorized    bool
+	readCallbacks api.ReadFilterCallbacks
+}
+
+// NewRBACFilter makes a rbac filter as api.ReadFilter
+func NewRBACFilter(ctx context.Context, authorizer *rbac.Authorizer) api.ReadFilter {
+	return &rbacFilter{
+		ctx:        ctx,
+		authorizer: authorizer,
+	}
+}
+
+func (f *rbacFilter) OnData(buf buffer.IoBuffer) api.FilterStatus {
+	if f.authorized {
+		return api.Continue
+	}
+	conn := f.readCallbacks.Connection()
+	req := &rbac.Request{
+		Context: f.ctx,
+		Conn:    conn,
+	}
+	if !f.authorizer.Authorize(req) {
+		log.DefaultLogger.Infof("[network filter] [rbac] connection from %s is denied, close it", conn.RemoteAddr())
+		buf.Drain(buf.Len())
+		conn.Close(api.NoFlush, api.LocalClose)
+		return api.Stop
+	}
+	f.authorized = true
+	return api.Continue
+}
+
+func (f *rbacFilter) OnNewConnection() api.FilterStatus {
+	return api.Continue
+}
+
+func (f *rbacFilter) InitializeReadFilterCallbacks(cb api.ReadFilterCallbacks) {
+	f.readCallbacks = cb
+}
@@ -0,0 +1,112 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rbac
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"mosn.io/api"
+	"mosn.io/pkg/buffer"
+)
+
+type mockConnection struct {
+	api.Connection
+	remote net.Addr
+	closed bool
+}
+
+func (c *mockConnection) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *mockConnection) LocalAddr() net.Addr {
+	return nil
+}
+
+func (c *mockConnection) RawConn() net.Conn {
+	return nil
+}
+
+func (c *mockConnection) Close(ccType api.ConnectionCloseType, eventType api.ConnectionEvent) error {
+	c.closed = true
+	return nil
+}
+
+type mockReadFilterCallbacks struct {
+	api.ReadFilterCallbacks
+	conn *mockConnection
+}
+
+func (cb *mockReadFilterCallbacks) Connection() api.Connection {
+	return cb.conn
+}
+
+func TestRBACFilter(t *testing.T) {
+	conf := map[string]interface{}{
+		"stat_prefix": "test_network_rbac",
+		"rules": map[string]interface{}{
+			"action": "ALLOW",
+			"policies": map[string]interface{}{
+				"internal": map[string]interface{}{
+					"permissions": []interface{}{
+						map[string]interface{}{"any": true},
+					},
+					"principals": []interface{}{
+						map[string]interface{}{"source_ips": []string{"10.0.0.0/8"}},
+					},
+				},
+			},
+		},
+	}
+	factory, err := CreateRBACFactory(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorizer := factory.(*rbacConfigFactory).authorizer
+	for idx, tc := range []struct {
+		remote string
+		status api.FilterStatus
+	}{
+		{"10.1.1.1:1234", api.Continue},
+		{"192.168.1.1:1234", api.Stop},
+	} {
+		addr, _ := net.ResolveTCPAddr("tcp", tc.remote)
+		conn := &mockConnection{remote: addr}
+		f := NewRBACFilter(context.Background(), authorizer)
+		f.InitializeReadFilterCallbacks(&mockReadFilterCallbacks{conn: conn})
+		if status := f.OnNewConnection(); status != api.Continue {
+			t.Fatalf("case %d unexpected status on new connection: %v", idx, status)
+		}
+		buf := buffer.NewIoBufferString("data")
+		if status := f.OnData(buf); status != tc.status {
+			t.Fatalf("case %d expected status %v, but got %v", idx, tc.status, status)
+		}
+		if conn.closed != (tc.status == api.Stop) {
+			t.Fatalf("case %d unexpected connection closed: %v", idx, conn.closed)
+		}
+		// the connection is authorized only once
+		if tc.status == api.Continue {
+			f.OnData(buf)
+		}
+	}
+	if authorizer.Stats().Allowed.Count() != 1 || authorizer.Stats().Denied.Count() != 1 {
+		t.Fatal("unexpected stats")
+	}
+}
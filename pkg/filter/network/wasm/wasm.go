@@ -0,0 +1,161 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wasm
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/wasm/proxywasm"
+	"mosn.io/pkg/buffer"
+	"mosn.io/pkg/utils"
+)
+
+var errPluginStopped = errors.New("wasm plugin is stopped")
+
+// wasmFilter runs the connection in a wasm context, the read data is the downstream data
+// and the written data is the upstream data. The context is deleted when the connection is closed
+type wasmFilter struct {
+	ctx           context.Context
+	instance      *proxywasm.Instance
+	wasmCtx       *proxywasm.Context
+	readCallbacks api.ReadFilterCallbacks
+	// the data being processed, they are accessed with the instance locked
+	downstreamData buffer.IoBuffer
+	upstreamData   buffer.IoBuffer
+	closeOnce      sync.Once
+}
+
+// newWasmFilter makes a wasm filter as api.ReadFilter and api.WriteFilter
+func newWasmFilter(ctx context.Context, plugin *proxywasm.Plugin) (*wasmFilter, error) {
+	instance := plugin.GetInstance()
+	if instance == nil {
+		return nil, errPluginStopped
+	}
+	f := &wasmFilter{
+		ctx:      ctx,
+		instance: instance,
+	}
+	wasmCtx, err := instance.NewContext(ctx, f)
+	if err != nil {
+		instance.Release()
+		return nil, err
+	}
+	f.wasmCtx = wasmCtx
+	return f, nil
+}
+
+func (f *wasmFilter) InitializeReadFilterCallbacks(cb api.ReadFilterCallbacks) {
+	f.readCallbacks = cb
+}
+
+func (f *wasmFilter) OnNewConnection() api.FilterStatus {
+	f.readCallbacks.Connection().AddConnectionEventListener(f)
+	return f.filterStatus(f.wasmCtx.OnNewConnection())
+}
+
+func (f *wasmFilter) OnData(buf buffer.IoBuffer) api.FilterStatus {
+	f.downstreamData = buf
+	return f.filterStatus(f.wasmCtx.OnDownstreamData(buf.Len(), false))
+}
+
+func (f *wasmFilter) OnWrite(bufs []buffer.IoBuffer) api.FilterStatus {
+	if len(bufs) == 0 {
+		return api.Continue
+	}
+	// the module sees the written data as one buffer
+	for _, buf := range bufs[1:] {
+		bufs[0].Write(buf.Bytes())
+		buf.Drain(buf.Len())
+	}
+	f.upstreamData = bufs[0]
+	return f.filterStatus(f.wasmCtx.OnUpstreamData(bufs[0].Len(), false))
+}
+
+func (f *wasmFilter) filterStatus(action proxywasm.Action, err error) api.FilterStatus {
+	if err != nil {
+		log.DefaultLogger.Errorf("[network filter] [wasm] call wasm module failed, close the connection: %v", err)
+		f.readCallbacks.Connection().Close(api.NoFlush, api.LocalClose)
+		return api.Stop
+	}
+	if action == proxywasm.ActionPause {
+		return api.Stop
+	}
+	return api.Continue
+}
+
+// OnEvent deletes the wasm context when the connection is closed
+func (f *wasmFilter) OnEvent(event api.ConnectionEvent) {
+	if !event.IsClose() {
+		return
+	}
+	f.closeOnce.Do(func() {
+		peer := proxywasm.PeerTypeLocal
+		if event == api.RemoteClose {
+			peer = proxywasm.PeerTypeRemote
+		}
+		if err := f.wasmCtx.OnDownstreamConnectionClose(peer); err != nil {
+			log.DefaultLogger.Errorf("[network filter] [wasm] call proxy_on_downstream_connection_close failed: %v", err)
+		}
+		f.wasmCtx.Delete()
+		f.instance.Release()
+	})
+}
+
+// GetHeaderMap implements proxywasm.Handler, a connection has no header maps
+func (f *wasmFilter) GetHeaderMap(mapType proxywasm.MapType) api.HeaderMap {
+	return nil
+}
+
+// GetBuffer implements proxywasm.Handler
+func (f *wasmFilter) GetBuffer(bufferType proxywasm.BufferType) buffer.IoBuffer {
+	switch bufferType {
+	case proxywasm.BufferTypeDownstreamData:
+		return f.downstreamData
+	case proxywasm.BufferTypeUpstreamData:
+		return f.upstreamData
+	}
+	return nil
+}
+
+// SendLocalResponse implements proxywasm.Handler, it is not supported by a connection
+func (f *wasmFilter) SendLocalResponse(status int, headers [][2]string, body []byte) {
+	log.DefaultLogger.Warnf("[network filter] [wasm] local response is not supported by the network filter")
+}
+
+// Resume implements proxywasm.Handler, the reading is continued asynchronously
+// because the filters are called again and the instance is locked now
+func (f *wasmFilter) Resume(stream proxywasm.StreamType) {
+	if stream != proxywasm.StreamTypeDownstream {
+		return
+	}
+	utils.GoWithRecover(func() {
+		f.readCallbacks.ContinueReading()
+	}, nil)
+}
+
+// Close implements proxywasm.Handler, the connection is closed asynchronously
+// because the close event deletes the context and the instance is locked now
+func (f *wasmFilter) Close(stream proxywasm.StreamType) {
+	utils.GoWithRecover(func() {
+		f.readCallbacks.Connection().Close(api.NoFlush, api.LocalClose)
+	}, nil)
+}
@@ -0,0 +1,175 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wasm
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/internal/wasmtest"
+	"mosn.io/mosn/pkg/wasm/proxywasm"
+	"mosn.io/pkg/buffer"
+)
+
+const testModule = "network_wasm_test"
+
+var (
+	closedMu    sync.Mutex
+	closedPeers []proxywasm.PeerType
+)
+
+func init() {
+	wasmtest.RegisterModule(testModule, func(g *wasmtest.Guest) wasmtest.Exports {
+		setBuffer := func(bufferType proxywasm.BufferType, start, size int, data string) {
+			dp, ds := g.WriteString(data)
+			g.Host("proxy_set_buffer_bytes", uint64(bufferType), uint64(start), uint64(size), dp, ds)
+		}
+		return wasmtest.Exports{
+			"proxy_on_vm_start": func(args ...uint64) uint64 {
+				return 1
+			},
+			"proxy_on_configure": func(args ...uint64) uint64 {
+				return 1
+			},
+			"proxy_on_downstream_data": func(args ...uint64) uint64 {
+				data, _ := g.HostReturnBytes("proxy_get_buffer_bytes", uint64(proxywasm.BufferTypeDownstreamData), 0, args[1])
+				switch string(data) {
+				case "block":
+					g.Host("proxy_close_stream", uint64(proxywasm.StreamTypeDownstream))
+					return uint64(proxywasm.ActionPause)
+				case "wait":
+					return uint64(proxywasm.ActionPause)
+				}
+				setBuffer(proxywasm.BufferTypeDownstreamData, 0, 1, "H")
+				return uint64(proxywasm.ActionContinue)
+			},
+			"proxy_on_upstream_data": func(args ...uint64) uint64 {
+				setBuffer(proxywasm.BufferTypeUpstreamData, 0, 0, "up:")
+				return uint64(proxywasm.ActionContinue)
+			},
+			"proxy_on_downstream_connection_close": func(args ...uint64) uint64 {
+				closedMu.Lock()
+				closedPeers = append(closedPeers, proxywasm.PeerType(args[1]))
+				closedMu.Unlock()
+				return 0
+			},
+		}
+	})
+}
+
+type mockConnection struct {
+	api.Connection
+	listeners []api.ConnectionEventListener
+	closed    chan struct{}
+}
+
+func (c *mockConnection) AddConnectionEventListener(listener api.ConnectionEventListener) {
+	c.listeners = append(c.listeners, listener)
+}
+
+func (c *mockConnection) Close(ccType api.ConnectionCloseType, eventType api.ConnectionEvent) error {
+	close(c.closed)
+	return nil
+}
+
+type mockReadFilterCallbacks struct {
+	api.ReadFilterCallbacks
+	conn *mockConnection
+}
+
+func (cb *mockReadFilterCallbacks) Connection() api.Connection {
+	return cb.conn
+}
+
+func createTestFilter(t *testing.T) (*wasmFilter, *mockConnection) {
+	dir, err := ioutil.TempDir("", "wasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "filter.wasm")
+	if err := ioutil.WriteFile(path, []byte(testModule), 0644); err != nil {
+		t.Fatal(err)
+	}
+	factory, err := CreateWasmFactory(map[string]interface{}{
+		"name":         "test_network_wasm",
+		"engine":       wasmtest.EngineName,
+		"path":         path,
+		"instance_num": 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := newWasmFilter(context.Background(), factory.(*wasmConfigFactory).plugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := &mockConnection{closed: make(chan struct{})}
+	filter.InitializeReadFilterCallbacks(&mockReadFilterCallbacks{conn: conn})
+	if filter.OnNewConnection() != api.Continue {
+		t.Fatal("unexpected new connection status")
+	}
+	if len(conn.listeners) != 1 {
+		t.Fatal("the connection event listener is not added")
+	}
+	return filter, conn
+}
+
+func TestWasmFilter(t *testing.T) {
+	filter, conn := createTestFilter(t)
+	buf := buffer.NewIoBufferString("hello")
+	if filter.OnData(buf) != api.Continue || buf.String() != "Hello" {
+		t.Fatalf("unexpected downstream data: %s", buf.String())
+	}
+	if filter.OnData(buffer.NewIoBufferString("wait")) != api.Stop {
+		t.Fatal("expected the reading is stopped")
+	}
+	bufs := []buffer.IoBuffer{buffer.NewIoBufferString("wor"), buffer.NewIoBufferString("ld")}
+	if filter.OnWrite(bufs) != api.Continue || bufs[0].String() != "up:world" || bufs[1].Len() != 0 {
+		t.Fatalf("unexpected upstream data: %s", bufs[0].String())
+	}
+	closedMu.Lock()
+	closedPeers = nil
+	closedMu.Unlock()
+	conn.listeners[0].OnEvent(api.RemoteClose)
+	conn.listeners[0].OnEvent(api.LocalClose)
+	closedMu.Lock()
+	defer closedMu.Unlock()
+	if len(closedPeers) != 1 || closedPeers[0] != proxywasm.PeerTypeRemote {
+		t.Fatalf("unexpected closed peers: %v", closedPeers)
+	}
+}
+
+func TestWasmFilterClose(t *testing.T) {
+	filter, conn := createTestFilter(t)
+	defer filter.OnEvent(api.LocalClose)
+	if filter.OnData(buffer.NewIoBufferString("block")) != api.Stop {
+		t.Fatal("expected the reading is stopped")
+	}
+	select {
+	case <-conn.closed:
+	case <-time.After(time.Second):
+		t.Fatal("the connection is not closed")
+	}
+}
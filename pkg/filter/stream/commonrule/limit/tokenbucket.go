@@ -0,0 +1,102 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package limit
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// TokenBucket limiter, the bucket is full at the beginning,
+// and tokensPerFill tokens are added every fillInterval
+type TokenBucket struct {
+	maxTokens     int64
+	tokensPerFill int64
+	fillInterval  time.Duration
+
+	tokens   int64
+	lastFill time.Time
+	mutex    sync.Mutex
+
+	// now is used to mock the time in test
+	now func() time.Time
+}
+
+// NewTokenBucket new
+func NewTokenBucket(maxTokens int64, tokensPerFill int64, fillInterval time.Duration) (*TokenBucket, error) {
+	if maxTokens <= 0 || tokensPerFill <= 0 || fillInterval <= 0 {
+		return nil, errors.New("maxTokens, tokensPerFill and fillInterval must be positive")
+	}
+	b := &TokenBucket{
+		maxTokens:     maxTokens,
+		tokensPerFill: tokensPerFill,
+		fillInterval:  fillInterval,
+		tokens:        maxTokens,
+		now:           time.Now,
+	}
+	b.lastFill = b.now()
+	return b, nil
+}
+
+// TryAcquire limit
+func (b *TokenBucket) TryAcquire() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.fill()
+	if b.tokens <= 0 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Remaining returns the tokens in the bucket
+func (b *TokenBucket) Remaining() int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.fill()
+	return b.tokens
+}
+
+// NextFill returns the duration until the next fill
+func (b *TokenBucket) NextFill() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.fill()
+	return b.fillInterval - b.now().Sub(b.lastFill)
+}
+
+// fill adds the tokens of the past intervals
+func (b *TokenBucket) fill() {
+	now := b.now()
+	intervals := int64(now.Sub(b.lastFill) / b.fillInterval)
+	if intervals <= 0 {
+		return
+	}
+	b.lastFill = b.lastFill.Add(time.Duration(intervals) * b.fillInterval)
+	if intervals > b.maxTokens/b.tokensPerFill+1 {
+		// avoid overflow, the bucket is full anyway
+		b.tokens = b.maxTokens
+		return
+	}
+	b.tokens += intervals * b.tokensPerFill
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
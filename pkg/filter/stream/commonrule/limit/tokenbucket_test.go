@@ -0,0 +1,53 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package limit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	if _, err := NewTokenBucket(0, 1, time.Second); err == nil {
+		t.Fatal("expected create token bucket failed")
+	}
+	b, err := NewTokenBucket(3, 2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	b.lastFill = now
+	for i := 0; i < 3; i++ {
+		if !b.TryAcquire() {
+			t.Fatalf("acquire %d expected success", i)
+		}
+	}
+	if b.TryAcquire() || b.Remaining() != 0 {
+		t.Fatal("expected no token")
+	}
+	now = now.Add(1500 * time.Millisecond)
+	if b.Remaining() != 2 || b.NextFill() != 500*time.Millisecond {
+		t.Fatalf("unexpected bucket: %d %v", b.Remaining(), b.NextFill())
+	}
+	// the bucket never exceeds max tokens
+	now = now.Add(time.Hour)
+	if b.Remaining() != 3 {
+		t.Fatalf("expected full bucket, but got %d", b.Remaining())
+	}
+}
@@ -0,0 +1,322 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compressor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/gzip"
+	gometrics "github.com/rcrowley/go-metrics"
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/buffer"
+)
+
+const (
+	defaultStatPrefix       = "compressor"
+	defaultMinContentLength = 30
+	defaultMaxBodyBytes     = 4 * 1024 * 1024
+)
+
+// http headers used by the compressor
+const (
+	headerAcceptEncoding  = "accept-encoding"
+	headerContentEncoding = "content-encoding"
+	headerContentLength   = "content-length"
+	headerContentType     = "content-type"
+	headerCacheControl    = "cache-control"
+	headerETag            = "etag"
+	headerVary            = "vary"
+)
+
+var defaultEncodings = []string{EncodingGzip, EncodingDeflate, EncodingBrotli}
+
+var defaultContentTypes = []string{
+	"text/html",
+	"text/plain",
+	"text/css",
+	"text/xml",
+	"text/javascript",
+	"application/javascript",
+	"application/x-javascript",
+	"application/json",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// Stats is the compressor metrics
+type Stats struct {
+	Compressed             gometrics.Counter
+	NotCompressed          gometrics.Counter
+	TotalUncompressedBytes gometrics.Counter
+	TotalCompressedBytes   gometrics.Counter
+	Decompressed           gometrics.Counter
+	DecompressError        gometrics.Counter
+}
+
+func newStats(statPrefix string) *Stats {
+	s := metrics.NewCompressorStats(statPrefix)
+	return &Stats{
+		Compressed:             s.Counter(metrics.CompressorCompressed),
+		NotCompressed:          s.Counter(metrics.CompressorNotCompressed),
+		TotalUncompressedBytes: s.Counter(metrics.CompressorTotalUncompressedBytes),
+		TotalCompressedBytes:   s.Counter(metrics.CompressorTotalCompressedBytes),
+		Decompressed:           s.Counter(metrics.CompressorDecompressed),
+		DecompressError:        s.Counter(metrics.CompressorDecompressError),
+	}
+}
+
+// compressorConfig is parsed from v2.StreamCompressor
+type compressorConfig struct {
+	encodings            []string
+	level                int
+	minContentLength     int
+	contentTypes         []string
+	disableOnETag        bool
+	removeAcceptEncoding bool
+	decompress           bool
+	maxBodyBytes         int64
+	stats                *Stats
+}
+
+func makeCompressorConfig(cfg *v2.StreamCompressor) (*compressorConfig, error) {
+	config := &compressorConfig{
+		encodings:            defaultEncodings,
+		level:                gzip.DefaultCompression,
+		minContentLength:     defaultMinContentLength,
+		contentTypes:         defaultContentTypes,
+		disableOnETag:        cfg.DisableOnETag,
+		removeAcceptEncoding: cfg.RemoveAcceptEncoding,
+	}
+	if len(cfg.Encodings) > 0 {
+		config.encodings = make([]string, 0, len(cfg.Encodings))
+		for _, name := range cfg.Encodings {
+			name = strings.ToLower(name)
+			if GetEncoding(name) == nil {
+				return nil, fmt.Errorf("unsupported compressor encoding: %s", name)
+			}
+			config.encodings = append(config.encodings, name)
+		}
+	}
+	if cfg.Level != 0 {
+		if cfg.Level < gzip.HuffmanOnly || cfg.Level > gzip.BestCompression {
+			return nil, fmt.Errorf("invalid compressor level: %d", cfg.Level)
+		}
+		config.level = cfg.Level
+	}
+	if cfg.MinContentLength > 0 {
+		config.minContentLength = cfg.MinContentLength
+	}
+	if len(cfg.ContentTypes) > 0 {
+		config.contentTypes = make([]string, 0, len(cfg.ContentTypes))
+		for _, ct := range cfg.ContentTypes {
+			config.contentTypes = append(config.contentTypes, strings.ToLower(ct))
+		}
+	}
+	if cfg.Decompressor != nil {
+		config.decompress = true
+		config.maxBodyBytes = cfg.Decompressor.MaxBodyBytes
+		if config.maxBodyBytes <= 0 {
+			config.maxBodyBytes = defaultMaxBodyBytes
+		}
+	}
+	statPrefix := cfg.StatPrefix
+	if statPrefix == "" {
+		statPrefix = defaultStatPrefix
+	}
+	config.stats = newStats(statPrefix)
+	return config, nil
+}
+
+// streamCompressorFilter is an implement of api.StreamReceiverFilter and api.StreamSenderFilter,
+// it decompresses the compressed requests, and compresses the responses by the negotiated encoding
+type streamCompressorFilter struct {
+	ctx           context.Context
+	config        *compressorConfig
+	handler       api.StreamReceiverFilterHandler
+	senderHandler api.StreamSenderFilterHandler
+	// encoding is the negotiated encoding of the response, empty means no encoding is accepted
+	encoding string
+}
+
+func newCompressorFilter(ctx context.Context, config *compressorConfig) *streamCompressorFilter {
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [compressor] create a new compressor filter")
+	}
+	return &streamCompressorFilter{
+		ctx:    ctx,
+		config: config,
+	}
+}
+
+func (f *streamCompressorFilter) SetReceiveFilterHandler(handler api.StreamReceiverFilterHandler) {
+	f.handler = handler
+}
+
+func (f *streamCompressorFilter) SetSenderFilterHandler(handler api.StreamSenderFilterHandler) {
+	f.senderHandler = handler
+}
+
+func (f *streamCompressorFilter) OnReceive(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	if f.config.decompress && buf != nil && buf.Len() > 0 {
+		if status, ok := f.decompressRequest(ctx, headers, buf); !ok {
+			f.handler.SendHijackReply(status, headers)
+			return api.StreamFilterStop
+		}
+	}
+	// the response of a HEAD request has no body
+	if method, _ := headers.Get(types.HeaderMethod); method == http.MethodHead {
+		return api.StreamFilterContinue
+	}
+	if acceptEncoding, ok := headers.Get(headerAcceptEncoding); ok {
+		f.encoding = negotiate(acceptEncoding, f.config.encodings)
+		if f.config.removeAcceptEncoding {
+			headers.Del(headerAcceptEncoding)
+		}
+	}
+	return api.StreamFilterContinue
+}
+
+// decompressRequest decompresses the request body in place, the response status is returned if it fails
+func (f *streamCompressorFilter) decompressRequest(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer) (int, bool) {
+	contentEncoding, ok := headers.Get(headerContentEncoding)
+	if !ok {
+		return 0, true
+	}
+	name := strings.ToLower(strings.TrimSpace(contentEncoding))
+	// multiple or unknown codings are passed through to the upstream
+	encoding := GetEncoding(name)
+	if encoding == nil {
+		return 0, true
+	}
+	data := buffer.GetIoBuffer(buf.Len() * 4)
+	defer buffer.PutIoBuffer(data)
+	if err := encoding.Decompress(data, buf.Bytes(), f.config.maxBodyBytes); err != nil {
+		f.config.stats.DecompressError.Inc(1)
+		log.Proxy.Errorf(ctx, "[stream filter] [compressor] decompress the %s request body failed: %v", name, err)
+		if err == errBodyTooLarge {
+			return http.StatusRequestEntityTooLarge, false
+		}
+		return http.StatusBadRequest, false
+	}
+	f.config.stats.Decompressed.Inc(1)
+	buf.Reset()
+	buf.Write(data.Bytes())
+	headers.Del(headerContentEncoding)
+	if _, ok := headers.Get(headerContentLength); ok {
+		headers.Set(headerContentLength, strconv.Itoa(buf.Len()))
+	}
+	return 0, true
+}
+
+func (f *streamCompressorFilter) Append(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	if buf == nil || !f.compressible(headers, buf) {
+		return api.StreamFilterContinue
+	}
+	// the response varies by the Accept-Encoding even if it is not compressed for this request
+	addVary(headers)
+	if f.encoding == "" {
+		f.config.stats.NotCompressed.Inc(1)
+		return api.StreamFilterContinue
+	}
+	if etag, ok := headers.Get(headerETag); ok && etag != "" {
+		if f.config.disableOnETag {
+			f.config.stats.NotCompressed.Inc(1)
+			return api.StreamFilterContinue
+		}
+		// the compressed body is not byte-for-byte equal to the original one
+		if !strings.HasPrefix(etag, "W/") {
+			headers.Set(headerETag, "W/"+etag)
+		}
+	}
+	size := buf.Len()
+	data := buffer.GetIoBuffer(size)
+	defer buffer.PutIoBuffer(data)
+	if err := GetEncoding(f.encoding).Compress(data, buf.Bytes(), f.config.level); err != nil {
+		f.config.stats.NotCompressed.Inc(1)
+		log.Proxy.Errorf(ctx, "[stream filter] [compressor] compress the response body by %s failed: %v", f.encoding, err)
+		return api.StreamFilterContinue
+	}
+	f.config.stats.Compressed.Inc(1)
+	f.config.stats.TotalUncompressedBytes.Inc(int64(size))
+	f.config.stats.TotalCompressedBytes.Inc(int64(data.Len()))
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [compressor] compress the response body by %s from %d to %d bytes", f.encoding, size, data.Len())
+	}
+	buf.Reset()
+	buf.Write(data.Bytes())
+	headers.Set(headerContentEncoding, f.encoding)
+	if _, ok := headers.Get(headerContentLength); ok {
+		headers.Set(headerContentLength, strconv.Itoa(buf.Len()))
+	}
+	return api.StreamFilterContinue
+}
+
+func (f *streamCompressorFilter) OnDestroy() {}
+
+// compressible returns whether the response can be compressed
+func (f *streamCompressorFilter) compressible(headers api.HeaderMap, buf buffer.IoBuffer) bool {
+	if buf.Len() < f.config.minContentLength {
+		return false
+	}
+	if status, ok := headers.Get(types.HeaderStatus); ok {
+		if code, err := strconv.Atoi(status); err == nil && (code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified) {
+			return false
+		}
+	}
+	if contentEncoding, ok := headers.Get(headerContentEncoding); ok && contentEncoding != "" && !strings.EqualFold(contentEncoding, EncodingIdentity) {
+		return false
+	}
+	if cacheControl, ok := headers.Get(headerCacheControl); ok && strings.Contains(strings.ToLower(cacheControl), "no-transform") {
+		return false
+	}
+	contentType, ok := headers.Get(headerContentType)
+	if !ok {
+		return false
+	}
+	return f.allowedContentType(contentType)
+}
+
+func (f *streamCompressorFilter) allowedContentType(contentType string) bool {
+	// the parameters such as charset are ignored
+	if idx := strings.IndexByte(contentType, ';'); idx >= 0 {
+		contentType = contentType[:idx]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, ct := range f.config.contentTypes {
+		if ct == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+func addVary(headers api.HeaderMap) {
+	if vary, ok := headers.Get(headerVary); !ok || vary == "" {
+		headers.Set(headerVary, "Accept-Encoding")
+	} else if !strings.Contains(strings.ToLower(vary), headerAcceptEncoding) && vary != "*" {
+		headers.Set(headerVary, vary+", Accept-Encoding")
+	}
+}
@@ -0,0 +1,250 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compressor
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/buffer"
+)
+
+// mocks the interface that used for test
+// only implement the function that used in test
+type mockStreamReceiverFilterCallbacks struct {
+	api.StreamReceiverFilterHandler
+	hijackCode int
+}
+
+func (cb *mockStreamReceiverFilterCallbacks) SendHijackReply(code int, headers api.HeaderMap) {
+	cb.hijackCode = code
+}
+
+func newFilterForTest(t *testing.T, conf map[string]interface{}) (*streamCompressorFilter, *mockStreamReceiverFilterCallbacks) {
+	factory, err := CreateCompressorFilterFactory(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newCompressorFilter(context.Background(), factory.(*FilterConfigFactory).config)
+	cb := &mockStreamReceiverFilterCallbacks{}
+	f.SetReceiveFilterHandler(cb)
+	return f, cb
+}
+
+var testBody = strings.Repeat(`{"message": "hello mosn"}`, 20)
+
+func newResponseHeaders() protocol.CommonHeader {
+	return protocol.CommonHeader{
+		types.HeaderStatus:  "200",
+		headerContentType:   "application/json; charset=utf-8",
+		headerContentLength: strconv.Itoa(len(testBody)),
+		headerETag:          `"abc"`,
+	}
+}
+
+func decompress(t *testing.T, name string, body []byte) string {
+	out := &bytes.Buffer{}
+	if err := GetEncoding(name).Decompress(out, body, defaultMaxBodyBytes); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestCreateFactoryInvalid(t *testing.T) {
+	for idx, conf := range []map[string]interface{}{
+		{"encodings": []string{"zstd"}},
+		{"level": 10},
+		{"level": -3},
+	} {
+		if _, err := CreateCompressorFilterFactory(conf); err == nil {
+			t.Errorf("case %d expected create factory failed", idx)
+		}
+	}
+}
+
+func TestCompressResponse(t *testing.T) {
+	f, _ := newFilterForTest(t, map[string]interface{}{
+		"stat_prefix":            "test_compress_response",
+		"remove_accept_encoding": true,
+	})
+	reqHeaders := protocol.CommonHeader{headerAcceptEncoding: "deflate;q=0.5, gzip"}
+	if status := f.OnReceive(context.Background(), reqHeaders, nil, nil); status != api.StreamFilterContinue {
+		t.Fatal("expected continue")
+	}
+	if _, ok := reqHeaders.Get(headerAcceptEncoding); ok {
+		t.Fatal("expected accept-encoding is removed")
+	}
+	headers := newResponseHeaders()
+	headers.Set(headerVary, "Origin")
+	buf := buffer.NewIoBufferString(testBody)
+	f.Append(context.Background(), headers, buf, nil)
+	if ce, _ := headers.Get(headerContentEncoding); ce != EncodingGzip {
+		t.Fatalf("expected gzip content encoding, but got %s", ce)
+	}
+	if cl, _ := headers.Get(headerContentLength); cl != strconv.Itoa(buf.Len()) || buf.Len() >= len(testBody) {
+		t.Fatalf("unexpected content length %s, body length %d", cl, buf.Len())
+	}
+	if vary, _ := headers.Get(headerVary); vary != "Origin, Accept-Encoding" {
+		t.Fatalf("unexpected vary: %s", vary)
+	}
+	if etag, _ := headers.Get(headerETag); etag != `W/"abc"` {
+		t.Fatalf("unexpected etag: %s", etag)
+	}
+	if body := decompress(t, EncodingGzip, buf.Bytes()); body != testBody {
+		t.Fatalf("unexpected body: %s", body)
+	}
+	stats := f.config.stats
+	if stats.Compressed.Count() != 1 || stats.TotalUncompressedBytes.Count() != int64(len(testBody)) ||
+		stats.TotalCompressedBytes.Count() != int64(buf.Len()) {
+		t.Fatal("unexpected stats")
+	}
+}
+
+func TestCompressResponseBrotli(t *testing.T) {
+	f, _ := newFilterForTest(t, map[string]interface{}{
+		"stat_prefix": "test_compress_response_br",
+	})
+	reqHeaders := protocol.CommonHeader{headerAcceptEncoding: "gzip;q=0.8, deflate;q=0.5, br"}
+	if status := f.OnReceive(context.Background(), reqHeaders, nil, nil); status != api.StreamFilterContinue {
+		t.Fatal("expected continue")
+	}
+	headers := newResponseHeaders()
+	buf := buffer.NewIoBufferString(testBody)
+	f.Append(context.Background(), headers, buf, nil)
+	if ce, _ := headers.Get(headerContentEncoding); ce != EncodingBrotli {
+		t.Fatalf("expected br content encoding, but got %s", ce)
+	}
+	if cl, _ := headers.Get(headerContentLength); cl != strconv.Itoa(buf.Len()) || buf.Len() >= len(testBody) {
+		t.Fatalf("unexpected content length %s, body length %d", cl, buf.Len())
+	}
+	if body := decompress(t, EncodingBrotli, buf.Bytes()); body != testBody {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+func TestNotCompressResponse(t *testing.T) {
+	for idx, tc := range []struct {
+		conf           map[string]interface{}
+		method         string
+		acceptEncoding string
+		modify         func(headers protocol.CommonHeader)
+		body           string
+		vary           bool
+	}{
+		// no encoding is accepted
+		{acceptEncoding: "zstd", vary: true},
+		// head request
+		{method: http.MethodHead, acceptEncoding: "gzip", vary: true},
+		// already encoded
+		{acceptEncoding: "gzip", modify: func(h protocol.CommonHeader) { h.Set(headerContentEncoding, "br") }},
+		// no content
+		{acceptEncoding: "gzip", modify: func(h protocol.CommonHeader) { h.Set(types.HeaderStatus, "304") }},
+		// content type is not allowed
+		{acceptEncoding: "gzip", modify: func(h protocol.CommonHeader) { h.Set(headerContentType, "image/png") }},
+		{acceptEncoding: "gzip", modify: func(h protocol.CommonHeader) { h.Del(headerContentType) }},
+		// no transform
+		{acceptEncoding: "gzip", modify: func(h protocol.CommonHeader) { h.Set(headerCacheControl, "no-transform") }},
+		// too small
+		{acceptEncoding: "gzip", body: "{}"},
+		// etag
+		{conf: map[string]interface{}{"disable_on_etag": true}, acceptEncoding: "gzip", vary: true},
+	} {
+		f, _ := newFilterForTest(t, tc.conf)
+		reqHeaders := protocol.CommonHeader{headerAcceptEncoding: tc.acceptEncoding}
+		if tc.method != "" {
+			reqHeaders.Set(types.HeaderMethod, tc.method)
+		}
+		f.OnReceive(context.Background(), reqHeaders, nil, nil)
+		headers := newResponseHeaders()
+		if tc.modify != nil {
+			tc.modify(headers)
+		}
+		body := testBody
+		if tc.body != "" {
+			body = tc.body
+		}
+		buf := buffer.NewIoBufferString(body)
+		f.Append(context.Background(), headers, buf, nil)
+		if buf.String() != body {
+			t.Errorf("case %d expected body is not compressed", idx)
+		}
+		if ce, _ := headers.Get(headerContentEncoding); ce == EncodingGzip {
+			t.Errorf("case %d expected no gzip content encoding", idx)
+		}
+		if _, ok := headers.Get(headerVary); ok != tc.vary {
+			t.Errorf("case %d expected vary %v", idx, tc.vary)
+		}
+	}
+}
+
+func TestDecompressRequest(t *testing.T) {
+	f, cb := newFilterForTest(t, map[string]interface{}{
+		"stat_prefix":  "test_decompress_request",
+		"decompressor": map[string]interface{}{"max_body_bytes": len(testBody)},
+	})
+	compressed := &bytes.Buffer{}
+	if err := GetEncoding(EncodingDeflate).Compress(compressed, []byte(testBody), -1); err != nil {
+		t.Fatal(err)
+	}
+	headers := protocol.CommonHeader{
+		headerContentEncoding: "Deflate",
+		headerContentLength:   strconv.Itoa(compressed.Len()),
+	}
+	buf := buffer.NewIoBufferBytes(compressed.Bytes())
+	if status := f.OnReceive(context.Background(), headers, buf, nil); status != api.StreamFilterContinue {
+		t.Fatal("expected continue")
+	}
+	if buf.String() != testBody {
+		t.Fatalf("unexpected body: %s", buf.String())
+	}
+	if _, ok := headers.Get(headerContentEncoding); ok {
+		t.Fatal("expected content-encoding is removed")
+	}
+	if cl, _ := headers.Get(headerContentLength); cl != strconv.Itoa(len(testBody)) {
+		t.Fatalf("unexpected content length: %s", cl)
+	}
+	// unknown encodings are passed through
+	headers = protocol.CommonHeader{headerContentEncoding: "zstd"}
+	buf = buffer.NewIoBufferString("zstd body")
+	if status := f.OnReceive(context.Background(), headers, buf, nil); status != api.StreamFilterContinue || buf.String() != "zstd body" {
+		t.Fatal("expected zstd body is passed through")
+	}
+	// invalid body
+	headers = protocol.CommonHeader{headerContentEncoding: "gzip"}
+	if status := f.OnReceive(context.Background(), headers, buffer.NewIoBufferString("invalid"), nil); status != api.StreamFilterStop || cb.hijackCode != http.StatusBadRequest {
+		t.Fatalf("expected bad request, but got %d", cb.hijackCode)
+	}
+	// too large
+	compressed.Reset()
+	GetEncoding(EncodingGzip).Compress(compressed, []byte(testBody+"!"), -1)
+	headers = protocol.CommonHeader{headerContentEncoding: "gzip"}
+	if status := f.OnReceive(context.Background(), headers, buffer.NewIoBufferBytes(compressed.Bytes()), nil); status != api.StreamFilterStop || cb.hijackCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected request entity too large, but got %d", cb.hijackCode)
+	}
+	stats := f.config.stats
+	if stats.Decompressed.Count() != 1 || stats.DecompressError.Count() != 2 {
+		t.Fatal("unexpected stats")
+	}
+}
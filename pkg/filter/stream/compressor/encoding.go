@@ -0,0 +1,278 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compressor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+)
+
+// content codings
+const (
+	EncodingGzip     = "gzip"
+	EncodingDeflate  = "deflate"
+	EncodingBrotli   = "br"
+	EncodingIdentity = "identity"
+)
+
+var errBodyTooLarge = errors.New("the decompressed body is too large")
+
+// Encoding compresses and decompresses the bodies of a content coding
+type Encoding interface {
+	// Compress writes the compressed src into dst
+	Compress(dst io.Writer, src []byte, level int) error
+	// Decompress writes the decompressed src into dst,
+	// an error is returned if the decompressed body is larger than maxBytes
+	Decompress(dst io.Writer, src []byte, maxBytes int64) error
+}
+
+var encodings sync.Map
+
+// RegisterEncoding registers an encoding by the content coding name.
+// gzip, deflate and br are registered by default, other codings can be
+// registered by the extensions that provide the implementations
+func RegisterEncoding(name string, encoding Encoding) {
+	encodings.Store(strings.ToLower(name), encoding)
+}
+
+// GetEncoding returns the encoding of the content coding name, nil if it is not registered
+func GetEncoding(name string) Encoding {
+	if v, ok := encodings.Load(strings.ToLower(name)); ok {
+		return v.(Encoding)
+	}
+	return nil
+}
+
+func init() {
+	RegisterEncoding(EncodingGzip, &gzipEncoding{
+		writers: writerPool{
+			newWriter: func(w io.Writer, level int) (resetWriter, error) {
+				return gzip.NewWriterLevel(w, level)
+			},
+		},
+	})
+	RegisterEncoding(EncodingDeflate, &deflateEncoding{
+		writers: writerPool{
+			newWriter: func(w io.Writer, level int) (resetWriter, error) {
+				return zlib.NewWriterLevel(w, level)
+			},
+		},
+	})
+	RegisterEncoding(EncodingBrotli, &brotliEncoding{
+		writers: writerPool{
+			newWriter: func(w io.Writer, level int) (resetWriter, error) {
+				return brotli.NewWriterLevel(w, brotliLevel(level)), nil
+			},
+		},
+	})
+}
+
+// resetWriter is a compression writer that can be reused
+type resetWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// writerPool caches the compression writers by the level
+type writerPool struct {
+	pools     sync.Map
+	newWriter func(w io.Writer, level int) (resetWriter, error)
+}
+
+func (p *writerPool) compress(dst io.Writer, src []byte, level int) error {
+	v, _ := p.pools.LoadOrStore(level, &sync.Pool{})
+	pool := v.(*sync.Pool)
+	var w resetWriter
+	if pw := pool.Get(); pw != nil {
+		w = pw.(resetWriter)
+		w.Reset(dst)
+	} else {
+		var err error
+		if w, err = p.newWriter(dst, level); err != nil {
+			return err
+		}
+	}
+	// the writer is reset before it is reused, so it is put back even if an error occurs
+	defer pool.Put(w)
+	if _, err := w.Write(src); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// copyLimited copies the decompressed body, at most maxBytes can be copied
+func copyLimited(dst io.Writer, r io.Reader, maxBytes int64) error {
+	n, err := io.Copy(dst, io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return err
+	}
+	if n > maxBytes {
+		return errBodyTooLarge
+	}
+	return nil
+}
+
+// gzipEncoding is the gzip content coding
+type gzipEncoding struct {
+	writers writerPool
+	readers sync.Pool
+}
+
+func (e *gzipEncoding) Compress(dst io.Writer, src []byte, level int) error {
+	return e.writers.compress(dst, src, level)
+}
+
+func (e *gzipEncoding) Decompress(dst io.Writer, src []byte, maxBytes int64) error {
+	var r *gzip.Reader
+	if pr := e.readers.Get(); pr != nil {
+		r = pr.(*gzip.Reader)
+		if err := r.Reset(bytes.NewReader(src)); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if r, err = gzip.NewReader(bytes.NewReader(src)); err != nil {
+			return err
+		}
+	}
+	defer e.readers.Put(r)
+	return copyLimited(dst, r, maxBytes)
+}
+
+// deflateEncoding is the deflate content coding, which is the zlib format
+type deflateEncoding struct {
+	writers writerPool
+	readers sync.Pool
+}
+
+func (e *deflateEncoding) Compress(dst io.Writer, src []byte, level int) error {
+	return e.writers.compress(dst, src, level)
+}
+
+func (e *deflateEncoding) Decompress(dst io.Writer, src []byte, maxBytes int64) error {
+	var r io.ReadCloser
+	if pr := e.readers.Get(); pr != nil {
+		r = pr.(io.ReadCloser)
+		if err := r.(zlib.Resetter).Reset(bytes.NewReader(src), nil); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if r, err = zlib.NewReader(bytes.NewReader(src)); err != nil {
+			return err
+		}
+	}
+	defer e.readers.Put(r)
+	return copyLimited(dst, r, maxBytes)
+}
+
+// brotliEncoding is the br content coding
+type brotliEncoding struct {
+	writers writerPool
+	readers sync.Pool
+}
+
+// brotliLevel converts the compression level to the brotli quality,
+// the levels from 0 to 9 are the same, and the brotli default is used for the negative levels
+func brotliLevel(level int) int {
+	switch {
+	case level == gzip.HuffmanOnly:
+		return brotli.BestSpeed
+	case level < 0:
+		return brotli.DefaultCompression
+	default:
+		return level
+	}
+}
+
+func (e *brotliEncoding) Compress(dst io.Writer, src []byte, level int) error {
+	return e.writers.compress(dst, src, level)
+}
+
+func (e *brotliEncoding) Decompress(dst io.Writer, src []byte, maxBytes int64) error {
+	var r *brotli.Reader
+	if pr := e.readers.Get(); pr != nil {
+		r = pr.(*brotli.Reader)
+		if err := r.Reset(bytes.NewReader(src)); err != nil {
+			return err
+		}
+	} else {
+		r = brotli.NewReader(bytes.NewReader(src))
+	}
+	defer e.readers.Put(r)
+	return copyLimited(dst, r, maxBytes)
+}
+
+// negotiate chooses the encoding by the Accept-Encoding, the encoding with the highest quality is chosen,
+// the encodings that have the same quality are chosen in the order of the preference
+func negotiate(acceptEncoding string, preferences []string) string {
+	qualities := make(map[string]float64)
+	star := -1.0
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		parts := strings.Split(item, ";")
+		coding := strings.ToLower(strings.TrimSpace(parts[0]))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		valid := true
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if len(param) > 2 && (param[0] == 'q' || param[0] == 'Q') && param[1] == '=' {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil || v < 0 || v > 1 {
+					valid = false
+					break
+				}
+				q = v
+			}
+		}
+		if !valid {
+			continue
+		}
+		if coding == "*" {
+			star = q
+		} else {
+			qualities[coding] = q
+		}
+	}
+	best := ""
+	bestQ := 0.0
+	for _, coding := range preferences {
+		q, ok := qualities[coding]
+		if !ok {
+			if star < 0 {
+				continue
+			}
+			q = star
+		}
+		if q > bestQ {
+			best = coding
+			bestQ = q
+		}
+	}
+	return best
+}
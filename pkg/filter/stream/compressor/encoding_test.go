@@ -0,0 +1,88 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compressor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	preferences := []string{EncodingGzip, EncodingDeflate, EncodingBrotli}
+	for idx, tc := range []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"gzip, deflate", EncodingGzip},
+		{"deflate, gzip", EncodingGzip},
+		{"deflate", EncodingDeflate},
+		{"GZIP", EncodingGzip},
+		{"gzip;q=0.5, deflate", EncodingDeflate},
+		{"gzip;q=0, deflate;q=0", ""},
+		{"br", EncodingBrotli},
+		{"gzip;q=0.8, br", EncodingBrotli},
+		{"br, gzip", EncodingGzip},
+		{"zstd", ""},
+		{"*", EncodingGzip},
+		{"*;q=0.1, gzip;q=0", EncodingDeflate},
+		{"identity", ""},
+		{"gzip;q=abc, deflate;q=0.1", EncodingDeflate},
+		{"", ""},
+	} {
+		if got := negotiate(tc.acceptEncoding, preferences); got != tc.expected {
+			t.Errorf("case %d expected %q, but got %q", idx, tc.expected, got)
+		}
+	}
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	body := []byte(strings.Repeat("hello mosn, ", 100))
+	for _, name := range []string{EncodingGzip, EncodingDeflate, EncodingBrotli} {
+		encoding := GetEncoding(name)
+		if encoding == nil {
+			t.Fatalf("encoding %s is not registered", name)
+		}
+		// the pooled writers and readers are reused
+		for i := 0; i < 3; i++ {
+			compressed := &bytes.Buffer{}
+			if err := encoding.Compress(compressed, body, -1); err != nil {
+				t.Fatal(err)
+			}
+			if compressed.Len() >= len(body) {
+				t.Fatalf("%s body is not compressed", name)
+			}
+			decompressed := &bytes.Buffer{}
+			if err := encoding.Decompress(decompressed, compressed.Bytes(), int64(len(body))); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decompressed.Bytes(), body) {
+				t.Fatalf("%s body is not matched", name)
+			}
+			if err := encoding.Decompress(&bytes.Buffer{}, compressed.Bytes(), int64(len(body)-1)); err != errBodyTooLarge {
+				t.Fatalf("%s expected body too large, but got %v", name, err)
+			}
+		}
+		if err := encoding.Decompress(&bytes.Buffer{}, []byte("invalid"), 1024); err == nil {
+			t.Fatalf("%s expected decompress invalid body failed", name)
+		}
+	}
+	if GetEncoding("zstd") != nil {
+		t.Fatal("zstd is not registered by default")
+	}
+}
@@ -0,0 +1,194 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cors
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/buffer"
+)
+
+const defaultStatPrefix = "cors"
+
+// cors headers
+const (
+	headerOrigin                        = "origin"
+	headerVary                          = "vary"
+	headerAccessControlRequestMethod    = "access-control-request-method"
+	headerAccessControlAllowOrigin      = "access-control-allow-origin"
+	headerAccessControlAllowMethods     = "access-control-allow-methods"
+	headerAccessControlAllowHeaders     = "access-control-allow-headers"
+	headerAccessControlExposeHeaders    = "access-control-expose-headers"
+	headerAccessControlMaxAge           = "access-control-max-age"
+	headerAccessControlAllowCredentials = "access-control-allow-credentials"
+)
+
+// Stats is the cors metrics
+type Stats struct {
+	OriginValid         gometrics.Counter
+	OriginInvalid       gometrics.Counter
+	ShadowOriginValid   gometrics.Counter
+	ShadowOriginInvalid gometrics.Counter
+}
+
+func newStats(statPrefix string) *Stats {
+	s := metrics.NewCorsStats(statPrefix)
+	return &Stats{
+		OriginValid:         s.Counter(metrics.CorsOriginValid),
+		OriginInvalid:       s.Counter(metrics.CorsOriginInvalid),
+		ShadowOriginValid:   s.Counter(metrics.CorsShadowOriginValid),
+		ShadowOriginInvalid: s.Counter(metrics.CorsShadowOriginInvalid),
+	}
+}
+
+// streamCorsFilter is an implement of api.StreamReceiverFilter and api.StreamSenderFilter,
+// it replies the preflight requests, and adds the cors headers to the responses of the allowed origins
+type streamCorsFilter struct {
+	ctx           context.Context
+	stats         *Stats
+	handler       api.StreamReceiverFilterHandler
+	senderHandler api.StreamSenderFilterHandler
+	// policy and origin are set if the request's origin is allowed by an enabled policy
+	policy types.CorsPolicy
+	origin string
+}
+
+func newCorsFilter(ctx context.Context, stats *Stats) *streamCorsFilter {
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [cors] create a new cors filter")
+	}
+	return &streamCorsFilter{
+		ctx:   ctx,
+		stats: stats,
+	}
+}
+
+func (f *streamCorsFilter) SetReceiveFilterHandler(handler api.StreamReceiverFilterHandler) {
+	f.handler = handler
+}
+
+func (f *streamCorsFilter) SetSenderFilterHandler(handler api.StreamSenderFilterHandler) {
+	f.senderHandler = handler
+}
+
+func (f *streamCorsFilter) OnReceive(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	policy := routeCorsPolicy(f.handler.Route())
+	if policy == nil || (!policy.Enabled() && !policy.ShadowEnabled()) {
+		return api.StreamFilterContinue
+	}
+	origin, ok := headers.Get(headerOrigin)
+	if !ok || origin == "" {
+		return api.StreamFilterContinue
+	}
+	allowed := policy.AllowOrigin(origin)
+	if policy.ShadowEnabled() {
+		if allowed {
+			f.stats.ShadowOriginValid.Inc(1)
+		} else {
+			f.stats.ShadowOriginInvalid.Inc(1)
+		}
+		if log.Proxy.GetLogLevel() >= log.DEBUG {
+			log.Proxy.Debugf(ctx, "[stream filter] [cors] shadow policy allows the origin %s: %v", origin, allowed)
+		}
+		return api.StreamFilterContinue
+	}
+	if !allowed {
+		f.stats.OriginInvalid.Inc(1)
+		if log.Proxy.GetLogLevel() >= log.DEBUG {
+			log.Proxy.Debugf(ctx, "[stream filter] [cors] the origin %s is not allowed", origin)
+		}
+		return api.StreamFilterContinue
+	}
+	f.stats.OriginValid.Inc(1)
+	if isPreflight(headers) {
+		f.handler.SendHijackReply(http.StatusOK, preflightHeaders(policy, origin))
+		return api.StreamFilterStop
+	}
+	f.policy = policy
+	f.origin = origin
+	return api.StreamFilterContinue
+}
+
+func (f *streamCorsFilter) Append(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	if f.policy == nil {
+		return api.StreamFilterContinue
+	}
+	headers.Set(headerAccessControlAllowOrigin, f.origin)
+	if f.policy.AllowCredentials() {
+		headers.Set(headerAccessControlAllowCredentials, "true")
+	}
+	if expose := f.policy.ExposeHeaders(); expose != "" {
+		headers.Set(headerAccessControlExposeHeaders, expose)
+	}
+	// the response varies by the origin
+	if vary, ok := headers.Get(headerVary); !ok || vary == "" {
+		headers.Set(headerVary, "Origin")
+	} else if !strings.Contains(strings.ToLower(vary), headerOrigin) {
+		headers.Set(headerVary, vary+", Origin")
+	}
+	return api.StreamFilterContinue
+}
+
+func (f *streamCorsFilter) OnDestroy() {}
+
+func routeCorsPolicy(route api.Route) types.CorsPolicy {
+	if route == nil {
+		return nil
+	}
+	if r, ok := route.(types.CorsRoute); ok {
+		return r.CorsPolicy()
+	}
+	return nil
+}
+
+// isPreflight returns whether the request is a cors preflight request
+func isPreflight(headers api.HeaderMap) bool {
+	method, _ := headers.Get(types.HeaderMethod)
+	if method != http.MethodOptions {
+		return false
+	}
+	reqMethod, ok := headers.Get(headerAccessControlRequestMethod)
+	return ok && reqMethod != ""
+}
+
+func preflightHeaders(policy types.CorsPolicy, origin string) api.HeaderMap {
+	headers := protocol.CommonHeader{
+		headerAccessControlAllowOrigin: origin,
+	}
+	if policy.AllowCredentials() {
+		headers.Set(headerAccessControlAllowCredentials, "true")
+	}
+	if methods := policy.AllowMethods(); methods != "" {
+		headers.Set(headerAccessControlAllowMethods, methods)
+	}
+	if allowHeaders := policy.AllowHeaders(); allowHeaders != "" {
+		headers.Set(headerAccessControlAllowHeaders, allowHeaders)
+	}
+	if maxAge := policy.MaxAge(); maxAge != "" {
+		headers.Set(headerAccessControlMaxAge, maxAge)
+	}
+	return headers
+}
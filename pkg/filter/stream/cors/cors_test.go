@@ -0,0 +1,184 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cors
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/router"
+	"mosn.io/mosn/pkg/types"
+)
+
+// mocks the interface that used for test
+// only implement the function that used in test
+type mockStreamReceiverFilterCallbacks struct {
+	api.StreamReceiverFilterHandler
+	route         api.Route
+	hijackCode    int
+	hijackHeaders api.HeaderMap
+}
+
+func (cb *mockStreamReceiverFilterCallbacks) Route() api.Route {
+	return cb.route
+}
+func (cb *mockStreamReceiverFilterCallbacks) SendHijackReply(code int, headers api.HeaderMap) {
+	cb.hijackCode = code
+	cb.hijackHeaders = headers
+}
+
+const testVirtualHost = `{
+	"name": "test",
+	"domains": ["*"],
+	"cors": {
+		"allow_origins": [{"exact": "https://mosn.io"}],
+		"allow_methods": ["GET", "PUT"],
+		"allow_headers": ["x-custom"],
+		"expose_headers": ["x-trace"],
+		"max_age": 600,
+		"allow_credentials": true
+	},
+	"routers": [
+		{
+			"match": {"prefix": "/shadow"},
+			"route": {"cluster_name": "test"},
+			"cors": {"allow_origins": [{"exact": "https://mosn.io"}], "shadow_mode": true}
+		},
+		{
+			"match": {"prefix": "/disabled"},
+			"route": {"cluster_name": "test"},
+			"cors": {"disabled": true}
+		},
+		{
+			"match": {"prefix": "/"},
+			"route": {"cluster_name": "test"}
+		}
+	]
+}`
+
+func newFilterForTest(t *testing.T, statPrefix, path string) (*streamCorsFilter, *mockStreamReceiverFilterCallbacks) {
+	vhCfg := &v2.VirtualHost{}
+	if err := json.Unmarshal([]byte(testVirtualHost), vhCfg); err != nil {
+		t.Fatal(err)
+	}
+	vh, err := router.NewVirtualHostImpl(vhCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	factory, err := CreateCorsFilterFactory(map[string]interface{}{"stat_prefix": statPrefix})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newCorsFilter(context.Background(), factory.(*FilterConfigFactory).stats)
+	cb := &mockStreamReceiverFilterCallbacks{
+		route: vh.GetRouteFromEntries(protocol.CommonHeader{strings.ToLower(protocol.MosnHeaderPathKey): path}, 1),
+	}
+	f.SetReceiveFilterHandler(cb)
+	return f, cb
+}
+
+func TestCorsPreflight(t *testing.T) {
+	f, cb := newFilterForTest(t, "test_cors_preflight", "/api")
+	headers := protocol.CommonHeader{
+		types.HeaderMethod:               http.MethodOptions,
+		"origin":                         "https://mosn.io",
+		headerAccessControlRequestMethod: "PUT",
+	}
+	if status := f.OnReceive(context.Background(), headers, nil, nil); status != api.StreamFilterStop || cb.hijackCode != http.StatusOK {
+		t.Fatal("expected the preflight request is replied")
+	}
+	for k, v := range map[string]string{
+		headerAccessControlAllowOrigin:      "https://mosn.io",
+		headerAccessControlAllowMethods:     "GET,PUT",
+		headerAccessControlAllowHeaders:     "x-custom",
+		headerAccessControlMaxAge:           "600",
+		headerAccessControlAllowCredentials: "true",
+	} {
+		if got, _ := cb.hijackHeaders.Get(k); got != v {
+			t.Errorf("header %s expected %s, but got %s", k, v, got)
+		}
+	}
+	// the origin is not allowed, the request is sent to the upstream
+	f, cb = newFilterForTest(t, "test_cors_preflight", "/api")
+	headers["origin"] = "https://example.com"
+	if status := f.OnReceive(context.Background(), headers, nil, nil); status != api.StreamFilterContinue || cb.hijackCode != 0 {
+		t.Fatal("expected the request is not replied")
+	}
+	if f.stats.OriginValid.Count() != 1 || f.stats.OriginInvalid.Count() != 1 {
+		t.Fatal("unexpected stats")
+	}
+}
+
+func TestCorsResponse(t *testing.T) {
+	f, _ := newFilterForTest(t, "test_cors_response", "/api")
+	headers := protocol.CommonHeader{
+		types.HeaderMethod: http.MethodGet,
+		"origin":           "https://mosn.io",
+	}
+	if status := f.OnReceive(context.Background(), headers, nil, nil); status != api.StreamFilterContinue {
+		t.Fatal("expected the request is continued")
+	}
+	respHeaders := protocol.CommonHeader{"vary": "Accept-Encoding"}
+	f.Append(context.Background(), respHeaders, nil, nil)
+	for k, v := range map[string]string{
+		headerAccessControlAllowOrigin:      "https://mosn.io",
+		headerAccessControlExposeHeaders:    "x-trace",
+		headerAccessControlAllowCredentials: "true",
+		"vary":                              "Accept-Encoding, Origin",
+	} {
+		if got, _ := respHeaders.Get(k); got != v {
+			t.Errorf("header %s expected %s, but got %s", k, v, got)
+		}
+	}
+	// no origin header, not a cors request
+	f, _ = newFilterForTest(t, "test_cors_response", "/api")
+	f.OnReceive(context.Background(), protocol.CommonHeader{types.HeaderMethod: http.MethodGet}, nil, nil)
+	respHeaders = protocol.CommonHeader{}
+	f.Append(context.Background(), respHeaders, nil, nil)
+	if _, ok := respHeaders.Get(headerAccessControlAllowOrigin); ok {
+		t.Fatal("the cors headers should not be added")
+	}
+}
+
+func TestCorsShadowAndDisabled(t *testing.T) {
+	headers := protocol.CommonHeader{
+		types.HeaderMethod:               http.MethodOptions,
+		"origin":                         "https://mosn.io",
+		headerAccessControlRequestMethod: "PUT",
+	}
+	for _, path := range []string{"/shadow", "/disabled"} {
+		f, cb := newFilterForTest(t, "test_cors_shadow", path)
+		if status := f.OnReceive(context.Background(), headers, nil, nil); status != api.StreamFilterContinue || cb.hijackCode != 0 {
+			t.Fatalf("%s: expected the request is not replied", path)
+		}
+		respHeaders := protocol.CommonHeader{}
+		f.Append(context.Background(), respHeaders, nil, nil)
+		if len(respHeaders) != 0 {
+			t.Fatalf("%s: the response should not be changed", path)
+		}
+		if path == "/shadow" && f.stats.ShadowOriginValid.Count() != 1 {
+			t.Fatal("expected the shadow stats")
+		}
+	}
+}
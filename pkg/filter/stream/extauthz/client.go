@@ -0,0 +1,328 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package extauthz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	authv2 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/upstream/cluster"
+	"mosn.io/pkg/utils"
+)
+
+const (
+	// maxDeniedBodySize is the max size of the http authorization service's denied response body
+	maxDeniedBodySize = 64 * 1024
+	// connCleanInterval is the interval to close the grpc connections of the removed hosts
+	connCleanInterval = 30 * time.Second
+)
+
+var (
+	errNoAuthzHost     = errors.New("no available host in the authorization cluster")
+	errPanic           = errors.New("panic when calling the authorization service")
+	errGRPCRequestBody = errors.New("with_request_body is only supported by the http authorization service")
+)
+
+// checkRequest contains the attributes sent to the authorization service
+type checkRequest struct {
+	attributes *authv2.AttributeContext
+	body       []byte
+}
+
+// headerValue is a header in the authorization response
+type headerValue struct {
+	key    string
+	value  string
+	append bool
+}
+
+// checkResult is the authorization decision
+type checkResult struct {
+	allowed bool
+	// status is the response status if the request is denied
+	status int
+	// headers are added to the upstream request if the request is allowed,
+	// or sent to the client if the request is denied
+	headers []headerValue
+	body    string
+}
+
+// authzClient calls the authorization service
+type authzClient interface {
+	Check(ctx context.Context, host string, req *checkRequest) (*checkResult, error)
+}
+
+func newAuthzClient(cfg *v2.StreamExtAuthz) (authzClient, error) {
+	switch cfg.Protocol {
+	case "", v2.ExtAuthzGRPC:
+		return &grpcClient{
+			cluster: cfg.Cluster,
+			conns:   grpcConns,
+		}, nil
+	case v2.ExtAuthzHTTP:
+		c := &httpClient{
+			client: &http.Client{},
+		}
+		if cfg.HTTPService != nil {
+			c.config = *cfg.HTTPService
+		}
+		return c, nil
+	default:
+		return nil, fmt.Errorf("unknown ext authz protocol: %s", cfg.Protocol)
+	}
+}
+
+// clusterHost chooses a host in the authorization cluster
+func clusterHost(ctx context.Context, clusterName string) (string, error) {
+	adapter := cluster.GetClusterMngAdapterInstance()
+	if adapter == nil {
+		return "", errNoAuthzHost
+	}
+	snapshot := adapter.GetClusterSnapshot(ctx, clusterName)
+	if snapshot == nil {
+		return "", fmt.Errorf("authorization cluster %s is not found", clusterName)
+	}
+	host := snapshot.LoadBalancer().ChooseHost(cluster.NewLoadBalancerContext(ctx))
+	if host == nil {
+		return "", errNoAuthzHost
+	}
+	return host.AddressString(), nil
+}
+
+// connKey is the key of the cached grpc connections
+type connKey struct {
+	cluster string
+	host    string
+}
+
+// grpcConns is shared by the grpc clients, so the connections are not leaked when the filter factory is replaced
+var grpcConns = newConnCache(clusterHostExists)
+
+// connCache caches the grpc connections of the authorization hosts,
+// the connections of the hosts that are removed from the cluster are closed periodically
+type connCache struct {
+	mutex sync.Mutex
+	conns map[connKey]*grpc.ClientConn
+	timer *utils.Timer
+	// exists checks whether the host is still in the cluster
+	exists func(cluster, host string) bool
+}
+
+func newConnCache(exists func(cluster, host string) bool) *connCache {
+	return &connCache{
+		conns:  map[connKey]*grpc.ClientConn{},
+		exists: exists,
+	}
+}
+
+func (c *connCache) get(cluster, host string) (*grpc.ClientConn, error) {
+	key := connKey{cluster: cluster, host: host}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if conn, ok := c.conns[key]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.Dial(host, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	c.conns[key] = conn
+	if c.timer == nil {
+		c.timer = utils.NewTimer(connCleanInterval, c.onClean)
+	}
+	return conn, nil
+}
+
+func (c *connCache) onClean() {
+	c.clean()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.timer = nil
+	if len(c.conns) > 0 {
+		c.timer = utils.NewTimer(connCleanInterval, c.onClean)
+	}
+}
+
+// clean closes the connections of the removed hosts
+func (c *connCache) clean() {
+	c.mutex.Lock()
+	keys := make([]connKey, 0, len(c.conns))
+	for key := range c.conns {
+		keys = append(keys, key)
+	}
+	c.mutex.Unlock()
+	for _, key := range keys {
+		if c.exists(key.cluster, key.host) {
+			continue
+		}
+		c.mutex.Lock()
+		conn := c.conns[key]
+		delete(c.conns, key)
+		c.mutex.Unlock()
+		if conn != nil {
+			conn.Close()
+		}
+	}
+}
+
+// clusterHostExists checks whether the host is in the cluster
+func clusterHostExists(clusterName, host string) bool {
+	adapter := cluster.GetClusterMngAdapterInstance()
+	if adapter == nil {
+		return false
+	}
+	snapshot := adapter.GetClusterSnapshot(context.Background(), clusterName)
+	if snapshot == nil {
+		return false
+	}
+	for _, h := range snapshot.HostSet().Hosts() {
+		if h.AddressString() == host {
+			return true
+		}
+	}
+	return false
+}
+
+// grpcClient calls the envoy.service.auth.v2.Authorization service
+type grpcClient struct {
+	cluster string
+	conns   *connCache
+}
+
+func (c *grpcClient) Check(ctx context.Context, host string, req *checkRequest) (*checkResult, error) {
+	conn, err := c.conns.get(c.cluster, host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := authv2.NewAuthorizationClient(conn).Check(ctx, &authv2.CheckRequest{
+		Attributes: req.attributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetStatus().GetCode() == int32(codes.OK) {
+		return &checkResult{
+			allowed: true,
+			headers: convertHeaderValueOptions(resp.GetOkResponse().GetHeaders()),
+		}, nil
+	}
+	result := &checkResult{
+		status: http.StatusForbidden,
+	}
+	if denied := resp.GetDeniedResponse(); denied != nil {
+		if code := denied.GetStatus().GetCode(); code != 0 {
+			result.status = int(code)
+		}
+		result.headers = convertHeaderValueOptions(denied.GetHeaders())
+		result.body = denied.GetBody()
+	}
+	return result, nil
+}
+
+func convertHeaderValueOptions(options []*core.HeaderValueOption) []headerValue {
+	headers := make([]headerValue, 0, len(options))
+	for _, opt := range options {
+		if opt.GetHeader() == nil {
+			continue
+		}
+		headers = append(headers, headerValue{
+			key:   opt.GetHeader().GetKey(),
+			value: opt.GetHeader().GetValue(),
+			// the default is append
+			append: opt.GetAppend() == nil || opt.GetAppend().GetValue(),
+		})
+	}
+	return headers
+}
+
+// httpClient calls the http authorization service, the request is allowed if the response status is 200
+type httpClient struct {
+	client *http.Client
+	config v2.ExtAuthzHTTPService
+}
+
+func (c *httpClient) Check(ctx context.Context, host string, req *checkRequest) (*checkResult, error) {
+	attrs := req.attributes.GetRequest().GetHttp()
+	method := attrs.GetMethod()
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if len(req.body) > 0 {
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequest(method, "http://"+host+c.config.PathPrefix+attrs.GetPath(), body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	if h := attrs.GetHost(); h != "" {
+		httpReq.Host = h
+	}
+	for _, key := range c.config.AllowedHeaders {
+		if value, ok := attrs.GetHeaders()[strings.ToLower(key)]; ok {
+			httpReq.Header.Set(key, value)
+		}
+	}
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return &checkResult{
+			allowed: true,
+			headers: pickHeaders(resp.Header, c.config.AllowedUpstreamHeaders),
+		}, nil
+	}
+	deniedBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxDeniedBodySize))
+	if err != nil {
+		return nil, err
+	}
+	return &checkResult{
+		status:  resp.StatusCode,
+		headers: pickHeaders(resp.Header, c.config.AllowedClientHeaders),
+		body:    string(deniedBody),
+	}, nil
+}
+
+func pickHeaders(header http.Header, keys []string) []headerValue {
+	var headers []headerValue
+	for _, key := range keys {
+		if value := header.Get(key); value != "" {
+			headers = append(headers, headerValue{
+				key:   key,
+				value: value,
+			})
+		}
+	}
+	return headers
+}
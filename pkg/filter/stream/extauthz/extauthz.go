@@ -0,0 +1,281 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package extauthz
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	authv2 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v2"
+	"github.com/gogo/protobuf/types"
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/mtls"
+	mosntypes "mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/buffer"
+	"mosn.io/pkg/utils"
+)
+
+const (
+	defaultTimeout         = 200 * time.Millisecond
+	defaultMaxRequestBytes = 8192
+	// mosnHeaderPrefix is the prefix of the mosn internal headers, such as x-mosn-path
+	mosnHeaderPrefix = "x-mosn-"
+)
+
+// extAuthzConfig is parsed from v2.StreamExtAuthz
+type extAuthzConfig struct {
+	cluster          string
+	timeout          time.Duration
+	failureModeAllow bool
+	statusOnError    int
+	withBody         bool
+	maxRequestBytes  int
+	client           authzClient
+	// getHost chooses a host of the authorization service
+	getHost func(ctx context.Context, cluster string) (string, error)
+}
+
+func makeExtAuthzConfig(cfg *v2.StreamExtAuthz) (*extAuthzConfig, error) {
+	if cfg.WithRequestBody != nil && cfg.Protocol != v2.ExtAuthzHTTP {
+		return nil, errGRPCRequestBody
+	}
+	client, err := newAuthzClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	config := &extAuthzConfig{
+		cluster:          cfg.Cluster,
+		timeout:          defaultTimeout,
+		failureModeAllow: cfg.FailureModeAllow,
+		statusOnError:    http.StatusForbidden,
+		client:           client,
+		getHost:          clusterHost,
+	}
+	if cfg.Timeout != nil && cfg.Timeout.Duration > 0 {
+		config.timeout = cfg.Timeout.Duration
+	}
+	if cfg.StatusOnError > 0 {
+		config.statusOnError = cfg.StatusOnError
+	}
+	if cfg.WithRequestBody != nil {
+		config.withBody = true
+		config.maxRequestBytes = int(cfg.WithRequestBody.MaxRequestBytes)
+		if config.maxRequestBytes == 0 {
+			config.maxRequestBytes = defaultMaxRequestBytes
+		}
+	}
+	return config, nil
+}
+
+// streamExtAuthzFilter is an implement of api.StreamReceiverFilter
+type streamExtAuthzFilter struct {
+	ctx     context.Context
+	handler api.StreamReceiverFilterHandler
+	config  *extAuthzConfig
+	stop    chan struct{}
+}
+
+func NewFilter(ctx context.Context, config *extAuthzConfig) api.StreamReceiverFilter {
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [ext authz] create a new ext authz filter")
+	}
+	return &streamExtAuthzFilter{
+		ctx:    ctx,
+		config: config,
+		stop:   make(chan struct{}),
+	}
+}
+
+func (f *streamExtAuthzFilter) SetReceiveFilterHandler(handler api.StreamReceiverFilterHandler) {
+	f.handler = handler
+}
+
+type checkResponse struct {
+	result *checkResult
+	err    error
+}
+
+// OnReceive waits for the authorization decision, the stream is paused until the decision is made
+func (f *streamExtAuthzFilter) OnReceive(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	req := f.newCheckRequest(headers, buf)
+	checkCtx, cancel := context.WithTimeout(context.Background(), f.config.timeout)
+	defer cancel()
+	ch := make(chan checkResponse, 1)
+	utils.GoWithRecover(func() {
+		host, err := f.config.getHost(checkCtx, f.config.cluster)
+		if err != nil {
+			ch <- checkResponse{err: err}
+			return
+		}
+		result, err := f.config.client.Check(checkCtx, host, req)
+		ch <- checkResponse{result: result, err: err}
+	}, func(r interface{}) {
+		ch <- checkResponse{err: errPanic}
+	})
+	var resp checkResponse
+	select {
+	case resp = <-ch:
+	case <-f.stop:
+		if log.Proxy.GetLogLevel() >= log.DEBUG {
+			log.Proxy.Debugf(ctx, "[stream filter] [ext authz] stream is destroyed before the authorization is finished")
+		}
+		return api.StreamFilterStop
+	}
+	if resp.err != nil {
+		log.Proxy.Errorf(ctx, "[stream filter] [ext authz] call authorization service %s failed: %v", f.config.cluster, resp.err)
+		if f.config.failureModeAllow {
+			return api.StreamFilterContinue
+		}
+		f.handler.RequestInfo().SetResponseFlag(mosntypes.Unauthorized)
+		f.handler.SendHijackReply(f.config.statusOnError, headers)
+		return api.StreamFilterStop
+	}
+	result := resp.result
+	if result.allowed {
+		for _, h := range result.headers {
+			if h.append {
+				headers.Add(h.key, h.value)
+			} else {
+				headers.Set(h.key, h.value)
+			}
+		}
+		return api.StreamFilterContinue
+	}
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [ext authz] the request is denied with status %d", result.status)
+	}
+	f.handler.RequestInfo().SetResponseFlag(mosntypes.Unauthorized)
+	// the hijack reply is built from the request headers, the xprotocol needs the request frame
+	for _, h := range result.headers {
+		headers.Set(h.key, h.value)
+	}
+	if handler, ok := f.handler.(mosntypes.HijackReplyWithBodyHandler); ok && result.body != "" {
+		handler.SendHijackReplyWithBody(result.status, headers, result.body)
+	} else {
+		f.handler.SendHijackReply(result.status, headers)
+	}
+	return api.StreamFilterStop
+}
+
+func (f *streamExtAuthzFilter) OnDestroy() {
+	close(f.stop)
+}
+
+// newCheckRequest makes the attributes of the request
+func (f *streamExtAuthzFilter) newCheckRequest(headers api.HeaderMap, buf buffer.IoBuffer) *checkRequest {
+	httpReq := &authv2.AttributeContext_HttpRequest{
+		Headers: make(map[string]string),
+		Size_:   -1,
+	}
+	headers.Range(func(key, value string) bool {
+		lowerKey := strings.ToLower(key)
+		switch lowerKey {
+		case mosntypes.HeaderMethod:
+			httpReq.Method = value
+		case mosntypes.HeaderPath:
+			httpReq.Path = value + httpReq.Path
+		case mosntypes.HeaderQueryString:
+			if value != "" {
+				httpReq.Query = value
+				httpReq.Path = httpReq.Path + "?" + value
+			}
+		case mosntypes.HeaderHost:
+			httpReq.Host = value
+		default:
+			if !strings.HasPrefix(lowerKey, mosnHeaderPrefix) {
+				httpReq.Headers[lowerKey] = value
+			}
+		}
+		return true
+	})
+	if httpReq.Host == "" {
+		httpReq.Host = httpReq.Headers["host"]
+	}
+	req := &checkRequest{
+		attributes: &authv2.AttributeContext{
+			Request: &authv2.AttributeContext_Request{
+				Time: types.TimestampNow(),
+				Http: httpReq,
+			},
+		},
+	}
+	if buf != nil {
+		httpReq.Size_ = int64(buf.Len())
+		if f.config.withBody {
+			body := buf.Bytes()
+			if len(body) > f.config.maxRequestBytes {
+				body = body[:f.config.maxRequestBytes]
+			}
+			req.body = body
+		}
+	}
+	if conn := f.handler.Connection(); conn != nil {
+		req.attributes.Source = &authv2.AttributeContext_Peer{
+			Address:   convertAddress(conn.RemoteAddr()),
+			Principal: peerPrincipal(conn),
+		}
+		req.attributes.Destination = &authv2.AttributeContext_Peer{
+			Address: convertAddress(conn.LocalAddr()),
+		}
+	}
+	return req
+}
+
+func convertAddress(addr net.Addr) *core.Address {
+	if addr == nil {
+		return nil
+	}
+	host, portStr, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+	port, _ := strconv.ParseUint(portStr, 10, 32)
+	return &core.Address{
+		Address: &core.Address_SocketAddress{
+			SocketAddress: &core.SocketAddress{
+				Address: host,
+				PortSpecifier: &core.SocketAddress_PortValue{
+					PortValue: uint32(port),
+				},
+			},
+		},
+	}
+}
+
+// peerPrincipal returns the mtls peer certificate's URI SAN, or the subject if the URI SAN is not found
+func peerPrincipal(conn api.Connection) string {
+	tlsConn, ok := conn.RawConn().(*mtls.TLSConn)
+	if !ok {
+		return ""
+	}
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return ""
+	}
+	if len(certs[0].URIs) > 0 {
+		return certs[0].URIs[0].String()
+	}
+	return certs[0].Subject.String()
+}
@@ -0,0 +1,349 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package extauthz
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	authv2 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v2"
+	envoytype "github.com/envoyproxy/go-control-plane/envoy/type"
+	"github.com/gogo/googleapis/google/rpc"
+	gogotypes "github.com/gogo/protobuf/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/buffer"
+)
+
+// mocks the interface that used for test
+// only implement the function that used in test
+type mockStreamReceiverFilterCallbacks struct {
+	api.StreamReceiverFilterHandler
+	hijackCode    int
+	hijackHeaders api.HeaderMap
+	hijackBody    string
+	info          *mockRequestInfo
+}
+
+func (cb *mockStreamReceiverFilterCallbacks) Connection() api.Connection {
+	return nil
+}
+func (cb *mockStreamReceiverFilterCallbacks) RequestInfo() api.RequestInfo {
+	return cb.info
+}
+func (cb *mockStreamReceiverFilterCallbacks) SendHijackReply(code int, headers api.HeaderMap) {
+	cb.hijackCode = code
+	cb.hijackHeaders = headers
+}
+func (cb *mockStreamReceiverFilterCallbacks) SendHijackReplyWithBody(code int, headers api.HeaderMap, body string) {
+	cb.hijackCode = code
+	cb.hijackHeaders = headers
+	cb.hijackBody = body
+}
+
+type mockRequestInfo struct {
+	api.RequestInfo
+	flag api.ResponseFlag
+}
+
+func (info *mockRequestInfo) SetResponseFlag(flag api.ResponseFlag) {
+	info.flag = flag
+}
+
+// mockAuthorizationServer allows the request with the header user: admin
+type mockAuthorizationServer struct{}
+
+func (s *mockAuthorizationServer) Check(ctx context.Context, req *authv2.CheckRequest) (*authv2.CheckResponse, error) {
+	httpReq := req.GetAttributes().GetRequest().GetHttp()
+	if httpReq.GetHeaders()["user"] == "admin" && httpReq.GetPath() == "/api?a=b" {
+		return &authv2.CheckResponse{
+			Status: &rpc.Status{Code: int32(codes.OK)},
+			HttpResponse: &authv2.CheckResponse_OkResponse{
+				OkResponse: &authv2.OkHttpResponse{
+					Headers: []*core.HeaderValueOption{
+						{
+							Header: &core.HeaderValue{Key: "x-auth-user", Value: "admin"},
+							Append: &gogotypes.BoolValue{Value: false},
+						},
+					},
+				},
+			},
+		}, nil
+	}
+	return &authv2.CheckResponse{
+		Status: &rpc.Status{Code: int32(codes.PermissionDenied)},
+		HttpResponse: &authv2.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv2.DeniedHttpResponse{
+				Status: &envoytype.HttpStatus{Code: envoytype.StatusCode_Unauthorized},
+				Headers: []*core.HeaderValueOption{
+					{Header: &core.HeaderValue{Key: "x-reason", Value: "no user"}},
+				},
+				Body: "denied",
+			},
+		},
+	}, nil
+}
+
+func newFilterForTest(t *testing.T, conf map[string]interface{}, host string) (*streamExtAuthzFilter, *mockStreamReceiverFilterCallbacks) {
+	factory, err := CreateExtAuthzFilterFactory(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := factory.(*FilterConfigFactory).config
+	config.getHost = func(ctx context.Context, cluster string) (string, error) {
+		if host == "" {
+			return "", errNoAuthzHost
+		}
+		return host, nil
+	}
+	f := NewFilter(context.Background(), config).(*streamExtAuthzFilter)
+	cb := &mockStreamReceiverFilterCallbacks{info: &mockRequestInfo{}}
+	f.SetReceiveFilterHandler(cb)
+	return f, cb
+}
+
+func TestCreateExtAuthzFilterFactory(t *testing.T) {
+	for idx, conf := range []map[string]interface{}{
+		{},
+		{"cluster": "authz", "protocol": "thrift"},
+		{"cluster": "authz", "timeout": "1x"},
+		{"cluster": "authz", "with_request_body": map[string]interface{}{}},
+	} {
+		if _, err := CreateExtAuthzFilterFactory(conf); err == nil {
+			t.Errorf("case %d expected create factory failed", idx)
+		}
+	}
+	factory, err := CreateExtAuthzFilterFactory(map[string]interface{}{
+		"cluster":           "authz",
+		"protocol":          "http",
+		"with_request_body": map[string]interface{}{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := factory.(*FilterConfigFactory).config
+	if config.timeout != defaultTimeout || config.statusOnError != http.StatusForbidden ||
+		!config.withBody || config.maxRequestBytes != defaultMaxRequestBytes {
+		t.Fatalf("unexpected default config: %+v", config)
+	}
+}
+
+func TestExtAuthzGRPC(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	authz := &mockAuthorizationServer{}
+	authv2.RegisterAuthorizationServer(server, authz)
+	go server.Serve(ln)
+	defer server.Stop()
+
+	f, cb := newFilterForTest(t, map[string]interface{}{
+		"cluster":  "authz",
+		"protocol": "grpc",
+		"timeout":  "1s",
+	}, ln.Addr().String())
+	// allowed
+	headers := protocol.CommonHeader{
+		types.HeaderPath:        "/api",
+		types.HeaderQueryString: "a=b",
+		"user":                  "admin",
+		"x-auth-user":           "guest",
+	}
+	if status := f.OnReceive(context.Background(), headers, buffer.NewIoBufferString("request body"), nil); status != api.StreamFilterContinue {
+		t.Fatal("expected the request is allowed")
+	}
+	if v, _ := headers.Get("x-auth-user"); v != "admin" {
+		t.Fatalf("expected the header is overwritten, but got %s", v)
+	}
+	// denied
+	headers = protocol.CommonHeader{types.HeaderPath: "/api"}
+	if status := f.OnReceive(context.Background(), headers, nil, nil); status != api.StreamFilterStop {
+		t.Fatal("expected the request is denied")
+	}
+	if cb.hijackCode != http.StatusUnauthorized || cb.hijackBody != "denied" || cb.info.flag != types.Unauthorized {
+		t.Fatalf("unexpected hijack reply: %d %s %v", cb.hijackCode, cb.hijackBody, cb.info.flag)
+	}
+	if v, _ := cb.hijackHeaders.Get("x-reason"); v != "no user" {
+		t.Fatalf("expected the denied response headers, but got %s", v)
+	}
+	if v, _ := cb.hijackHeaders.Get(types.HeaderPath); v != "/api" {
+		t.Fatal("expected the hijack reply is built from the request headers")
+	}
+}
+
+func TestExtAuthzHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/api" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if body, _ := ioutil.ReadAll(r.Body); len(body) > 0 && string(body) != "requ" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") == "token" {
+			w.Header().Set("x-auth-user", "admin")
+			w.Header().Set("x-ignored", "true")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", "token")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("denied"))
+	}))
+	defer server.Close()
+
+	f, cb := newFilterForTest(t, map[string]interface{}{
+		"cluster":  "authz",
+		"protocol": "http",
+		"http_service": map[string]interface{}{
+			"path_prefix":              "/auth",
+			"allowed_headers":          []string{"Authorization"},
+			"allowed_upstream_headers": []string{"x-auth-user"},
+			"allowed_client_headers":   []string{"WWW-Authenticate"},
+		},
+		"with_request_body": map[string]interface{}{
+			"max_request_bytes": 4,
+		},
+	}, server.Listener.Addr().String())
+	headers := protocol.CommonHeader{
+		types.HeaderPath: "/api",
+		"Authorization":  "token",
+	}
+	// the body prefix is sent
+	if status := f.OnReceive(context.Background(), headers, buffer.NewIoBufferString("request body"), nil); status != api.StreamFilterContinue {
+		t.Fatal("expected the request is allowed")
+	}
+	if v, _ := headers.Get("x-auth-user"); v != "admin" {
+		t.Fatalf("expected the upstream header is added, but got %s", v)
+	}
+	if _, ok := headers.Get("x-ignored"); ok {
+		t.Fatal("the header is not allowed to add")
+	}
+	headers = protocol.CommonHeader{types.HeaderPath: "/api"}
+	if status := f.OnReceive(context.Background(), headers, nil, nil); status != api.StreamFilterStop {
+		t.Fatal("expected the request is denied")
+	}
+	if cb.hijackCode != http.StatusUnauthorized || cb.hijackBody != "denied" {
+		t.Fatalf("unexpected hijack reply: %d %s", cb.hijackCode, cb.hijackBody)
+	}
+	if v, _ := cb.hijackHeaders.Get("WWW-Authenticate"); v != "token" {
+		t.Fatalf("expected the client header, but got %s", v)
+	}
+}
+
+func TestExtAuthzFailure(t *testing.T) {
+	conf := map[string]interface{}{
+		"cluster":         "authz",
+		"status_on_error": 503,
+	}
+	// failure closed
+	f, cb := newFilterForTest(t, conf, "")
+	if status := f.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil); status != api.StreamFilterStop {
+		t.Fatal("expected the request is denied")
+	}
+	if cb.hijackCode != http.StatusServiceUnavailable || cb.info.flag != types.Unauthorized {
+		t.Fatalf("unexpected hijack reply: %d", cb.hijackCode)
+	}
+	// failure open
+	conf["failure_mode_allow"] = true
+	f, cb = newFilterForTest(t, conf, "")
+	if status := f.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil); status != api.StreamFilterContinue {
+		t.Fatal("expected the request is allowed")
+	}
+	if cb.hijackCode != 0 {
+		t.Fatal("unexpected hijack reply")
+	}
+}
+
+type blockingClient struct{}
+
+func (c *blockingClient) Check(ctx context.Context, host string, req *checkRequest) (*checkResult, error) {
+	<-ctx.Done()
+	return nil, errors.New("timeout")
+}
+
+func TestExtAuthzDestroy(t *testing.T) {
+	f, cb := newFilterForTest(t, map[string]interface{}{
+		"cluster": "authz",
+		"timeout": "10s",
+	}, "127.0.0.1:1")
+	f.config.client = &blockingClient{}
+	ch := make(chan api.StreamFilterStatus, 1)
+	go func() {
+		ch <- f.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	f.OnDestroy()
+	select {
+	case status := <-ch:
+		if status != api.StreamFilterStop || cb.hijackCode != 0 {
+			t.Fatal("expected the stream stopped without reply")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the filter is not stopped after destroyed")
+	}
+}
+
+func TestConnCacheClean(t *testing.T) {
+	removed := map[string]bool{}
+	cache := newConnCache(func(cluster, host string) bool {
+		return !removed[host]
+	})
+	conn1, err := cache.get("authz", "127.0.0.1:10001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn2, _ := cache.get("authz", "127.0.0.1:10002")
+	if conn, _ := cache.get("authz", "127.0.0.1:10001"); conn != conn1 {
+		t.Fatal("expected the connection is cached")
+	}
+	// the connections of the removed hosts are closed
+	removed["127.0.0.1:10001"] = true
+	cache.clean()
+	if conn1.GetState() != connectivity.Shutdown || conn2.GetState() == connectivity.Shutdown {
+		t.Fatalf("unexpected connection states: %s %s", conn1.GetState(), conn2.GetState())
+	}
+	if conn, _ := cache.get("authz", "127.0.0.1:10001"); conn == conn1 {
+		t.Fatal("expected a new connection after the host is removed")
+	}
+	removed["127.0.0.1:10001"] = false
+	removed["127.0.0.1:10002"] = true
+	cache.onClean()
+	if len(cache.conns) != 1 || cache.timer == nil {
+		t.Fatalf("unexpected cache state, conns %d", len(cache.conns))
+	}
+	removed["127.0.0.1:10001"] = true
+	cache.onClean()
+	if len(cache.conns) != 0 || cache.timer != nil {
+		t.Fatalf("expected the clean timer is stopped without connections, conns %d", len(cache.conns))
+	}
+}
@@ -0,0 +1,182 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package headermutation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol/xprotocol"
+	"mosn.io/mosn/pkg/router"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/buffer"
+)
+
+// mutation is parsed from v2.HeaderMutation
+type mutation struct {
+	action string
+	// the http header names are case-insensitive and lowercased,
+	// the xprotocol header names are case-sensitive and kept as configured
+	name         string
+	newName      string
+	lowerName    string
+	lowerNewName string
+	value        *template
+	headers      []*types.HeaderData
+}
+
+func newMutation(cfg v2.HeaderMutationRule) (*mutation, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("header mutation %s has no header name", cfg.Action)
+	}
+	m := &mutation{
+		action:    cfg.Action,
+		name:      cfg.Name,
+		lowerName: strings.ToLower(cfg.Name),
+		headers:   router.GetRouterHeaders(cfg.Headers),
+	}
+	switch cfg.Action {
+	case v2.HeaderMutationAdd, v2.HeaderMutationSet:
+		value, err := parseTemplate(cfg.Value)
+		if err != nil {
+			return nil, fmt.Errorf("header mutation %s %s: %v", cfg.Action, cfg.Name, err)
+		}
+		m.value = value
+	case v2.HeaderMutationRemove:
+	case v2.HeaderMutationRename:
+		if cfg.NewName == "" {
+			return nil, fmt.Errorf("header mutation rename %s has no new name", cfg.Name)
+		}
+		m.newName = cfg.NewName
+		m.lowerNewName = strings.ToLower(cfg.NewName)
+	default:
+		return nil, fmt.Errorf("unknown header mutation action: %s", cfg.Action)
+	}
+	return m, nil
+}
+
+func newMutations(cfgs []v2.HeaderMutationRule) ([]*mutation, error) {
+	mutations := make([]*mutation, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		m, err := newMutation(cfg)
+		if err != nil {
+			return nil, err
+		}
+		mutations = append(mutations, m)
+	}
+	return mutations, nil
+}
+
+func (m *mutation) apply(ctx context.Context, headers api.HeaderMap) {
+	if len(m.headers) > 0 && !router.ConfigUtilityInst.MatchHeaders(headers, m.headers) {
+		return
+	}
+	name, newName := m.lowerName, m.lowerNewName
+	if _, ok := headers.(xprotocol.XFrame); ok {
+		name, newName = m.name, m.newName
+	}
+	switch m.action {
+	case v2.HeaderMutationAdd, v2.HeaderMutationSet:
+		value := m.value.render(ctx)
+		if value == "" {
+			return
+		}
+		// the xprotocol header maps do not support Add, so the values are joined
+		if m.action == v2.HeaderMutationAdd {
+			if existing, ok := headers.Get(name); ok && existing != "" {
+				value = existing + "," + value
+			}
+		}
+		headers.Set(name, value)
+	case v2.HeaderMutationRemove:
+		headers.Del(name)
+	case v2.HeaderMutationRename:
+		if value, ok := headers.Get(name); ok {
+			headers.Del(name)
+			headers.Set(newName, value)
+		}
+	}
+}
+
+// headerMutationConfig is parsed from v2.StreamHeaderMutation
+type headerMutationConfig struct {
+	requestMutations  []*mutation
+	responseMutations []*mutation
+}
+
+func makeHeaderMutationConfig(cfg *v2.StreamHeaderMutation) (*headerMutationConfig, error) {
+	requestMutations, err := newMutations(cfg.RequestMutations)
+	if err != nil {
+		return nil, err
+	}
+	responseMutations, err := newMutations(cfg.ResponseMutations)
+	if err != nil {
+		return nil, err
+	}
+	return &headerMutationConfig{
+		requestMutations:  requestMutations,
+		responseMutations: responseMutations,
+	}, nil
+}
+
+// streamHeaderMutationFilter is an implement of api.StreamReceiverFilter and api.StreamSenderFilter,
+// it mutates the request and response headers of any protocol
+type streamHeaderMutationFilter struct {
+	ctx           context.Context
+	config        *headerMutationConfig
+	handler       api.StreamReceiverFilterHandler
+	senderHandler api.StreamSenderFilterHandler
+}
+
+func newHeaderMutationFilter(ctx context.Context, config *headerMutationConfig) *streamHeaderMutationFilter {
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [header mutation] create a new header mutation filter")
+	}
+	return &streamHeaderMutationFilter{
+		ctx:    ctx,
+		config: config,
+	}
+}
+
+func (f *streamHeaderMutationFilter) SetReceiveFilterHandler(handler api.StreamReceiverFilterHandler) {
+	f.handler = handler
+}
+
+func (f *streamHeaderMutationFilter) SetSenderFilterHandler(handler api.StreamSenderFilterHandler) {
+	f.senderHandler = handler
+}
+
+func (f *streamHeaderMutationFilter) OnReceive(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	for _, m := range f.config.requestMutations {
+		m.apply(ctx, headers)
+	}
+	return api.StreamFilterContinue
+}
+
+func (f *streamHeaderMutationFilter) Append(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	for _, m := range f.config.responseMutations {
+		m.apply(ctx, headers)
+	}
+	return api.StreamFilterContinue
+}
+
+func (f *streamHeaderMutationFilter) OnDestroy() {}
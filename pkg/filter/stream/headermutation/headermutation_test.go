@@ -0,0 +1,153 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package headermutation
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	mosnhttp "mosn.io/mosn/pkg/protocol/http"
+	"mosn.io/mosn/pkg/protocol/xprotocol/bolt"
+	"mosn.io/mosn/pkg/variable"
+)
+
+func TestCreateFactoryInvalid(t *testing.T) {
+	for idx, conf := range []map[string]interface{}{
+		{"request_mutations": []interface{}{map[string]interface{}{"action": "unknown", "name": "x-test"}}},
+		{"request_mutations": []interface{}{map[string]interface{}{"action": "set"}}},
+		{"request_mutations": []interface{}{map[string]interface{}{"action": "rename", "name": "x-test"}}},
+		{"response_mutations": []interface{}{map[string]interface{}{"action": "set", "name": "x-test", "value": "%unclosed"}}},
+	} {
+		if _, err := CreateHeaderMutationFilterFactory(conf); err == nil {
+			t.Errorf("case %d expected create factory failed", idx)
+		}
+	}
+}
+
+const testConfig = `{
+	"request_mutations": [
+		{"action": "set", "name": "x-user", "value": "%test_header_mutation_user%"},
+		{"action": "add", "name": "x-tag", "value": "canary", "headers": [{"name": "x-env", "value": "gray"}]},
+		{"action": "remove", "name": "x-internal"},
+		{"action": "rename", "name": "x-old", "new_name": "x-new"},
+		{"action": "set", "name": "x-empty", "value": "%test_header_mutation_user%", "headers": [{"name": "x-empty-cond", "present_match": true}]}
+	],
+	"response_mutations": [
+		{"action": "set", "name": "x-served-by", "value": "mosn/%test_header_mutation_user%"},
+		{"action": "remove", "name": "x-upstream-version"}
+	]
+}`
+
+func newFilterForTest(t *testing.T) *streamHeaderMutationFilter {
+	conf := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(testConfig), &conf); err != nil {
+		t.Fatal(err)
+	}
+	factory, err := CreateHeaderMutationFilterFactory(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newHeaderMutationFilter(context.Background(), factory.(*FilterConfigFactory).config)
+}
+
+func TestHeaderMutation(t *testing.T) {
+	f := newFilterForTest(t)
+	ctx := variable.NewVariableContext(context.Background())
+	variable.SetVariableValue(ctx, "test_header_mutation_user", "alice")
+
+	check := func(headers api.HeaderMap, key, expected string, exists bool) {
+		t.Helper()
+		value, ok := headers.Get(key)
+		if ok != exists || value != expected {
+			t.Errorf("header %s expected %q %v, but got %q %v", key, expected, exists, value, ok)
+		}
+	}
+
+	// the xprotocol header map
+	headers := protocol.CommonHeader{
+		"x-env":        "gray",
+		"x-tag":        "stable",
+		"x-internal":   "secret",
+		"x-old":        "value",
+		"x-empty-cond": "",
+	}
+	if status := f.OnReceive(ctx, headers, nil, nil); status != api.StreamFilterContinue {
+		t.Fatal("expected continue")
+	}
+	check(headers, "x-user", "alice", true)
+	check(headers, "x-tag", "stable,canary", true)
+	check(headers, "x-internal", "", false)
+	check(headers, "x-old", "", false)
+	check(headers, "x-new", "value", true)
+	check(headers, "x-empty", "alice", true)
+
+	// the http header map, the condition is not matched and the variable is not found
+	req := &fasthttp.RequestHeader{}
+	req.Set("X-Env", "prod")
+	httpHeaders := mosnhttp.RequestHeader{RequestHeader: req}
+	f.OnReceive(variable.NewVariableContext(context.Background()), httpHeaders, nil, nil)
+	check(httpHeaders, "x-user", "", false)
+	check(httpHeaders, "x-tag", "", false)
+
+	resp := &fasthttp.ResponseHeader{}
+	resp.Set("X-Upstream-Version", "v1")
+	respHeaders := mosnhttp.ResponseHeader{ResponseHeader: resp}
+	f.Append(ctx, respHeaders, nil, nil)
+	check(respHeaders, "x-served-by", "mosn/alice", true)
+	check(respHeaders, "x-upstream-version", "", false)
+}
+
+func TestHeaderMutationBoltCaseSensitive(t *testing.T) {
+	config, err := makeHeaderMutationConfig(&v2.StreamHeaderMutation{
+		RequestMutations: []v2.HeaderMutationRule{
+			{Action: v2.HeaderMutationRemove, Name: "rpc_trace_context.sofaTraceId"},
+			{Action: v2.HeaderMutationRename, Name: "sofaRpcId", NewName: "rpc_trace_context.sofaRpcId"},
+			{Action: v2.HeaderMutationSet, Name: "X-Caller-App", Value: "mosn"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newHeaderMutationFilter(context.Background(), config)
+	req := bolt.NewRpcRequest(1, protocol.CommonHeader{
+		"rpc_trace_context.sofaTraceId": "0a0fe8ab1598",
+		"sofaRpcId":                     "0.1",
+	}, nil)
+	f.OnReceive(variable.NewVariableContext(context.Background()), req, nil, nil)
+
+	if _, ok := req.Get("rpc_trace_context.sofaTraceId"); ok {
+		t.Error("the mixed-case header is not removed")
+	}
+	if _, ok := req.Get("sofaRpcId"); ok {
+		t.Error("the renamed header is not removed")
+	}
+	if v, _ := req.Get("rpc_trace_context.sofaRpcId"); v != "0.1" {
+		t.Errorf("the renamed header keeps the configured case, but got %q", v)
+	}
+	if v, _ := req.Get("X-Caller-App"); v != "mosn" {
+		t.Errorf("the set header keeps the configured case, but got %q", v)
+	}
+	if _, ok := req.Get("x-caller-app"); ok {
+		t.Error("the xprotocol header should not be lowercased")
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package headermutation
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"mosn.io/mosn/pkg/variable"
+)
+
+var (
+	errEmptyVariable    = errors.New("empty variable definition: %%")
+	errUnclosedVariable = errors.New("unclosed variable definition")
+)
+
+// templateEntry is a text or a variable of the template
+type templateEntry struct {
+	text     string
+	variable string
+}
+
+// template is a header value that can contain variables, such as "%downstream_remote_address%",
+// the '%' can be escaped by '\'
+type template struct {
+	entries []templateEntry
+	// static is true if the template has no variables
+	static bool
+}
+
+func parseTemplate(value string) (*template, error) {
+	t := &template{
+		static: true,
+	}
+	text := &strings.Builder{}
+	for pos := 0; pos < len(value); pos++ {
+		ch := value[pos]
+		if ch == '\\' && pos+1 < len(value) && value[pos+1] == '%' {
+			text.WriteByte('%')
+			pos++
+			continue
+		}
+		if ch != '%' {
+			text.WriteByte(ch)
+			continue
+		}
+		end := strings.IndexByte(value[pos+1:], '%')
+		if end < 0 {
+			return nil, errUnclosedVariable
+		}
+		if end == 0 {
+			return nil, errEmptyVariable
+		}
+		name := value[pos+1 : pos+1+end]
+		// the variable must be registered
+		if _, err := variable.AddVariable(name); err != nil {
+			return nil, err
+		}
+		if text.Len() > 0 {
+			t.entries = append(t.entries, templateEntry{text: text.String()})
+			text.Reset()
+		}
+		t.entries = append(t.entries, templateEntry{variable: name})
+		t.static = false
+		pos += end + 1
+	}
+	if text.Len() > 0 {
+		t.entries = append(t.entries, templateEntry{text: text.String()})
+	}
+	return t, nil
+}
+
+// render returns the header value, the variables that are not found are rendered as empty
+func (t *template) render(ctx context.Context) string {
+	if t.static {
+		if len(t.entries) == 0 {
+			return ""
+		}
+		return t.entries[0].text
+	}
+	b := &strings.Builder{}
+	for _, entry := range t.entries {
+		if entry.variable == "" {
+			b.WriteString(entry.text)
+			continue
+		}
+		value, err := variable.GetVariableValue(ctx, entry.variable)
+		if err != nil || value == variable.ValueNotFound {
+			continue
+		}
+		b.WriteString(value)
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package headermutation
+
+import (
+	"context"
+	"testing"
+
+	"mosn.io/mosn/pkg/variable"
+)
+
+func init() {
+	variable.RegisterVariable(variable.NewIndexedVariable("test_header_mutation_user", nil, nil, variable.BasicSetter, 0))
+}
+
+func TestParseTemplate(t *testing.T) {
+	ctx := variable.NewVariableContext(context.Background())
+	variable.SetVariableValue(ctx, "test_header_mutation_user", "mosn")
+	for idx, tc := range []struct {
+		value    string
+		expected string
+	}{
+		{"", ""},
+		{"static", "static"},
+		{"%test_header_mutation_user%", "mosn"},
+		{"user=%test_header_mutation_user%;", "user=mosn;"},
+		{`100\% %test_header_mutation_user%`, "100% mosn"},
+	} {
+		tmpl, err := parseTemplate(tc.value)
+		if err != nil {
+			t.Fatalf("case %d parse template failed: %v", idx, err)
+		}
+		if got := tmpl.render(ctx); got != tc.expected {
+			t.Errorf("case %d expected %q, but got %q", idx, tc.expected, got)
+		}
+	}
+	// the variable is not found
+	tmpl, _ := parseTemplate("%test_header_mutation_user%")
+	if got := tmpl.render(variable.NewVariableContext(context.Background())); got != "" {
+		t.Fatalf("expected empty value, but got %q", got)
+	}
+	for _, value := range []string{"%%", "%unclosed", "%undefined_variable_for_test%"} {
+		if _, err := parseTemplate(value); err == nil {
+			t.Errorf("expected parse %s failed", value)
+		}
+	}
+}
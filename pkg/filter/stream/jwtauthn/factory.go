@@ -0,0 +1,84 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwtauthn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+)
+
+func init() {
+	api.RegisterStream(v2.JwtAuthn, CreateJwtAuthnFilterFactory)
+}
+
+// FilterConfigFactory creates the jwt authn filters, the providers are shared by the filters
+type FilterConfigFactory struct {
+	Config    *v2.StreamJwtAuthn
+	providers map[string]*provider
+}
+
+func (f *FilterConfigFactory) CreateFilterChain(context context.Context, callbacks api.StreamFilterChainFactoryCallbacks) {
+	filter := NewFilter(context, f.providers, f.Config.Requirement)
+	callbacks.AddStreamReceiverFilter(filter, api.AfterRoute)
+}
+
+func CreateJwtAuthnFilterFactory(conf map[string]interface{}) (api.StreamFilterChainFactory, error) {
+	log.DefaultLogger.Debugf("create jwt authn stream filter factory")
+	cfg, err := ParseStreamJwtAuthnFilter(conf)
+	if err != nil {
+		return nil, err
+	}
+	providers := make(map[string]*provider, len(cfg.Providers))
+	for name, pc := range cfg.Providers {
+		if pc == nil {
+			return nil, fmt.Errorf("jwt provider %s has no config", name)
+		}
+		p, err := newProvider(name, pc)
+		if err != nil {
+			return nil, fmt.Errorf("create jwt provider %s failed: %v", name, err)
+		}
+		providers[name] = p
+	}
+	if req := cfg.Requirement; req != nil && !req.Disabled {
+		if _, ok := providers[req.ProviderName]; !ok {
+			return nil, fmt.Errorf("jwt provider %s in requirement is not configured", req.ProviderName)
+		}
+	}
+	return &FilterConfigFactory{
+		Config:    cfg,
+		providers: providers,
+	}, nil
+}
+
+// ParseStreamJwtAuthnFilter
+func ParseStreamJwtAuthnFilter(cfg map[string]interface{}) (*v2.StreamJwtAuthn, error) {
+	filterConfig := &v2.StreamJwtAuthn{}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, filterConfig); err != nil {
+		return nil, err
+	}
+	return filterConfig, nil
+}
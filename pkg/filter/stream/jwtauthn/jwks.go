@@ -0,0 +1,134 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwtauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"math/big"
+
+	"mosn.io/mosn/pkg/log"
+)
+
+// jwksConfig is the json web key set defined in RFC 7517
+type jwksConfig struct {
+	Keys []jwkConfig `json:"keys"`
+}
+
+type jwkConfig struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// rsa public key
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// ec public key
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	// symmetric key
+	K string `json:"k,omitempty"`
+}
+
+// jwk is a parsed key, the key is *rsa.PublicKey, *ecdsa.PublicKey or []byte
+type jwk struct {
+	kid string
+	alg string
+	key interface{}
+}
+
+// parseJwks parses the json web key set, the keys that are not supported are ignored
+func parseJwks(data []byte) ([]*jwk, error) {
+	cfg := &jwksConfig{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	keys := make([]*jwk, 0, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		key, err := parseJwk(kc)
+		if err != nil {
+			log.DefaultLogger.Warnf("[stream filter] [jwt authn] ignore the jwk %s: %v", kc.Kid, err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, ErrJwksNoValidKeys
+	}
+	return keys, nil
+}
+
+func parseJwk(kc jwkConfig) (*jwk, error) {
+	key := &jwk{
+		kid: kc.Kid,
+		alg: kc.Alg,
+	}
+	switch kc.Kty {
+	case "RSA":
+		n, err := decodeSegment(kc.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeSegment(kc.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(n) == 0 || len(e) == 0 {
+			return nil, ErrJwksNoValidKeys
+		}
+		key.key = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	case "EC":
+		if kc.Crv != "P-256" {
+			return nil, ErrJwtUnsupportedAlg
+		}
+		x, err := decodeSegment(kc.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeSegment(kc.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, ErrJwksNoValidKeys
+		}
+		key.key = pub
+	case "oct":
+		k, err := decodeSegment(kc.K)
+		if err != nil {
+			return nil, err
+		}
+		if len(k) == 0 {
+			return nil, ErrJwksNoValidKeys
+		}
+		key.key = k
+	default:
+		return nil, ErrJwtUnsupportedAlg
+	}
+	return key, nil
+}
@@ -0,0 +1,202 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwtauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Supported signing algorithms
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgHS256 = "HS256"
+)
+
+// Errors of jwt verification
+var (
+	ErrJwtNotFound          = errors.New("jwt is missing")
+	ErrJwtBadFormat         = errors.New("jwt is not in the form of header.payload.signature")
+	ErrJwtUnsupportedAlg    = errors.New("jwt algorithm is not supported")
+	ErrJwtVerificationFail  = errors.New("jwt verification fails")
+	ErrJwtExpired           = errors.New("jwt is expired")
+	ErrJwtNotYetValid       = errors.New("jwt is not yet valid")
+	ErrJwtUnknownIssuer     = errors.New("jwt issuer is not configured")
+	ErrJwtAudienceNotAllow  = errors.New("jwt audience is not allowed")
+	ErrJwtProviderNotFound  = errors.New("jwt provider is not configured")
+	ErrJwksNoValidKeys      = errors.New("jwks does not have valid keys")
+	errJwtClaimTypeMismatch = errors.New("jwt claim type is not expected")
+)
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// jwt is a parsed json web token, the signature is not verified yet
+type jwt struct {
+	header    jwtHeader
+	payload   []byte
+	claims    map[string]interface{}
+	signed    string // the header.payload part to be signed
+	signature []byte
+}
+
+func decodeSegment(seg string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(seg, "="))
+}
+
+// parseJwt parses the token, the claims are decoded but not verified
+func parseJwt(token string) (*jwt, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrJwtBadFormat
+	}
+	t := &jwt{
+		signed: parts[0] + "." + parts[1],
+	}
+	header, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, ErrJwtBadFormat
+	}
+	if err := json.Unmarshal(header, &t.header); err != nil {
+		return nil, ErrJwtBadFormat
+	}
+	if t.payload, err = decodeSegment(parts[1]); err != nil {
+		return nil, ErrJwtBadFormat
+	}
+	if err := json.Unmarshal(t.payload, &t.claims); err != nil {
+		return nil, ErrJwtBadFormat
+	}
+	if t.signature, err = decodeSegment(parts[2]); err != nil {
+		return nil, ErrJwtBadFormat
+	}
+	return t, nil
+}
+
+// verifySignature verifies the signature by the keys, the jwt is verified if any key matches
+func (t *jwt) verifySignature(keys []*jwk) error {
+	switch t.header.Alg {
+	case AlgRS256, AlgES256, AlgHS256:
+	default:
+		return ErrJwtUnsupportedAlg
+	}
+	hashed := sha256.Sum256([]byte(t.signed))
+	for _, key := range keys {
+		if t.header.Kid != "" && key.kid != "" && key.kid != t.header.Kid {
+			continue
+		}
+		if key.alg != "" && key.alg != t.header.Alg {
+			continue
+		}
+		switch k := key.key.(type) {
+		case *rsa.PublicKey:
+			if t.header.Alg == AlgRS256 && rsa.VerifyPKCS1v15(k, crypto.SHA256, hashed[:], t.signature) == nil {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			// the signature is the concatenation of r and s, each is 32 bytes
+			if t.header.Alg == AlgES256 && len(t.signature) == 64 {
+				r := new(big.Int).SetBytes(t.signature[:32])
+				s := new(big.Int).SetBytes(t.signature[32:])
+				if ecdsa.Verify(k, hashed[:], r, s) {
+					return nil
+				}
+			}
+		case []byte:
+			if t.header.Alg == AlgHS256 {
+				mac := hmac.New(sha256.New, k)
+				mac.Write([]byte(t.signed))
+				if hmac.Equal(mac.Sum(nil), t.signature) {
+					return nil
+				}
+			}
+		}
+	}
+	return ErrJwtVerificationFail
+}
+
+// verifyClaims checks the iss, aud, exp and nbf claims
+func (t *jwt) verifyClaims(issuer string, audiences []string, now time.Time, skew time.Duration) error {
+	if issuer != "" {
+		if iss, _ := t.claims["iss"].(string); iss != issuer {
+			return ErrJwtUnknownIssuer
+		}
+	}
+	if len(audiences) > 0 && !t.matchAudience(audiences) {
+		return ErrJwtAudienceNotAllow
+	}
+	if exp, ok, err := t.timeClaim("exp"); err != nil {
+		return err
+	} else if ok && !now.Before(exp.Add(skew)) {
+		return ErrJwtExpired
+	}
+	if nbf, ok, err := t.timeClaim("nbf"); err != nil {
+		return err
+	} else if ok && now.Add(skew).Before(nbf) {
+		return ErrJwtNotYetValid
+	}
+	return nil
+}
+
+// matchAudience checks whether the aud claim contains any of the audiences,
+// the aud claim can be a string or an array of strings
+func (t *jwt) matchAudience(audiences []string) bool {
+	var auds []string
+	switch aud := t.claims["aud"].(type) {
+	case string:
+		auds = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+	}
+	for _, aud := range auds {
+		for _, allowed := range audiences {
+			if aud == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// timeClaim returns the claim as a time, the claim is the seconds since the epoch
+func (t *jwt) timeClaim(name string) (time.Time, bool, error) {
+	v, ok := t.claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	sec, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false, errJwtClaimTypeMismatch
+	}
+	return time.Unix(int64(sec), 0), true, nil
+}
@@ -0,0 +1,340 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwtauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+// testKeys holds the keys to sign the test tokens
+type testKeys struct {
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	hmacKey []byte
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeys{
+		rsaKey:  rsaKey,
+		ecKey:   ecKey,
+		hmacKey: []byte("test-hmac-secret"),
+	}
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (k *testKeys) jwks() []byte {
+	padded := func(i *big.Int) []byte {
+		b := make([]byte, 32)
+		ib := i.Bytes()
+		copy(b[32-len(ib):], ib)
+		return b
+	}
+	data, _ := json.Marshal(jwksConfig{
+		Keys: []jwkConfig{
+			{
+				Kty: "RSA",
+				Kid: "rsa",
+				Alg: AlgRS256,
+				N:   encode(k.rsaKey.N.Bytes()),
+				E:   encode(big.NewInt(int64(k.rsaKey.E)).Bytes()),
+			},
+			{
+				Kty: "EC",
+				Kid: "ec",
+				Crv: "P-256",
+				X:   encode(padded(k.ecKey.X)),
+				Y:   encode(padded(k.ecKey.Y)),
+			},
+			{
+				Kty: "oct",
+				Kid: "hmac",
+				K:   encode(k.hmacKey),
+			},
+		},
+	})
+	return data
+}
+
+func (k *testKeys) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(jwtHeader{Alg: alg, Kid: kid})
+	payload, _ := json.Marshal(claims)
+	signed := encode(header) + "." + encode(payload)
+	hashed := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch alg {
+	case AlgRS256:
+		s, err := rsa.SignPKCS1v15(rand.Reader, k.rsaKey, crypto.SHA256, hashed[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = s
+	case AlgES256:
+		r, s, err := ecdsa.Sign(rand.Reader, k.ecKey, hashed[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rb):32], rb)
+		copy(sig[64-len(sb):], sb)
+	case AlgHS256:
+		mac := hmac.New(sha256.New, k.hmacKey)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	default:
+		sig = []byte("unknown")
+	}
+	return signed + "." + encode(sig)
+}
+
+func writeJwksFile(t *testing.T, dir string, data []byte) string {
+	path := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseJwks(t *testing.T) {
+	keys := newTestKeys(t)
+	jwks, err := parseJwks(keys.jwks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks) != 3 {
+		t.Fatalf("expected 3 keys, but got %d", len(jwks))
+	}
+	if _, ok := jwks[0].key.(*rsa.PublicKey); !ok || jwks[0].kid != "rsa" {
+		t.Fatalf("unexpected rsa key: %+v", jwks[0])
+	}
+	if _, ok := jwks[1].key.(*ecdsa.PublicKey); !ok || jwks[1].kid != "ec" {
+		t.Fatalf("unexpected ec key: %+v", jwks[1])
+	}
+	if _, ok := jwks[2].key.([]byte); !ok || jwks[2].kid != "hmac" {
+		t.Fatalf("unexpected hmac key: %+v", jwks[2])
+	}
+	// unsupported keys are ignored
+	if _, err := parseJwks([]byte(`{"keys":[{"kty":"EC","crv":"P-384"}]}`)); err != ErrJwksNoValidKeys {
+		t.Fatalf("expected no valid keys error, but got %v", err)
+	}
+	if _, err := parseJwks([]byte(`not json`)); err == nil {
+		t.Fatal("expected parse jwks failed")
+	}
+}
+
+func TestJwtVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	jwks, err := parseJwks(keys.jwks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": "mosn",
+		"aud": []string{"a", "b"},
+		"sub": "user",
+		"exp": now.Add(time.Hour).Unix(),
+		"nbf": now.Add(-time.Hour).Unix(),
+	}
+	for _, tc := range []struct {
+		alg string
+		kid string
+	}{
+		{AlgRS256, "rsa"},
+		{AlgES256, "ec"},
+		{AlgHS256, "hmac"},
+		// the kid is optional
+		{AlgRS256, ""},
+		{AlgES256, ""},
+	} {
+		token, err := parseJwt(keys.sign(t, tc.alg, tc.kid, claims))
+		if err != nil {
+			t.Fatalf("%s parse jwt failed: %v", tc.alg, err)
+		}
+		if err := token.verifySignature(jwks); err != nil {
+			t.Fatalf("%s verify signature failed: %v", tc.alg, err)
+		}
+		if err := token.verifyClaims("mosn", []string{"b"}, now, defaultClockSkew); err != nil {
+			t.Fatalf("%s verify claims failed: %v", tc.alg, err)
+		}
+	}
+	// the signature is signed by another key
+	other := newTestKeys(t)
+	token, _ := parseJwt(other.sign(t, AlgRS256, "rsa", claims))
+	if err := token.verifySignature(jwks); err != ErrJwtVerificationFail {
+		t.Fatalf("expected verification failed, but got %v", err)
+	}
+	// the kid is not matched
+	token, _ = parseJwt(keys.sign(t, AlgRS256, "ec", claims))
+	if err := token.verifySignature(jwks); err != ErrJwtVerificationFail {
+		t.Fatalf("expected verification failed, but got %v", err)
+	}
+	token, _ = parseJwt(keys.sign(t, "none", "", claims))
+	if err := token.verifySignature(jwks); err != ErrJwtUnsupportedAlg {
+		t.Fatalf("expected unsupported alg, but got %v", err)
+	}
+	if _, err := parseJwt("a.b"); err != ErrJwtBadFormat {
+		t.Fatalf("expected bad format, but got %v", err)
+	}
+	if _, err := parseJwt("a.b.c"); err != ErrJwtBadFormat {
+		t.Fatalf("expected bad format, but got %v", err)
+	}
+}
+
+func TestJwtVerifyClaims(t *testing.T) {
+	keys := newTestKeys(t)
+	now := time.Now()
+	skew := time.Minute
+	for idx, tc := range []struct {
+		claims    map[string]interface{}
+		issuer    string
+		audiences []string
+		expected  error
+	}{
+		{map[string]interface{}{"iss": "mosn", "aud": "a"}, "mosn", []string{"a"}, nil},
+		{map[string]interface{}{}, "", nil, nil},
+		{map[string]interface{}{"iss": "other"}, "mosn", nil, ErrJwtUnknownIssuer},
+		{map[string]interface{}{}, "mosn", nil, ErrJwtUnknownIssuer},
+		{map[string]interface{}{"aud": "c"}, "", []string{"a", "b"}, ErrJwtAudienceNotAllow},
+		{map[string]interface{}{"aud": []string{"c", "b"}}, "", []string{"a", "b"}, nil},
+		{map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()}, "", nil, ErrJwtExpired},
+		// in the clock skew
+		{map[string]interface{}{"exp": now.Add(-30 * time.Second).Unix()}, "", nil, nil},
+		{map[string]interface{}{"nbf": now.Add(2 * time.Minute).Unix()}, "", nil, ErrJwtNotYetValid},
+		{map[string]interface{}{"nbf": now.Add(30 * time.Second).Unix()}, "", nil, nil},
+		{map[string]interface{}{"exp": "tomorrow"}, "", nil, errJwtClaimTypeMismatch},
+	} {
+		token, err := parseJwt(keys.sign(t, AlgHS256, "", tc.claims))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := token.verifyClaims(tc.issuer, tc.audiences, now, skew); err != tc.expected {
+			t.Errorf("case %d expected %v, but got %v", idx, tc.expected, err)
+		}
+	}
+}
+
+func TestProviderExtract(t *testing.T) {
+	keys := newTestKeys(t)
+	dir, err := ioutil.TempDir("", "jwtauthn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := writeJwksFile(t, dir, keys.jwks())
+	// default header
+	p, err := newProvider("default", &v2.JwtProvider{JwksFile: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, header := p.extract(protocol.CommonHeader{"Authorization": "Bearer abc"}); token != "abc" || header != "Authorization" {
+		t.Fatalf("unexpected token %s from %s", token, header)
+	}
+	if token, _ := p.extract(protocol.CommonHeader{"Authorization": "Basic abc"}); token != "" {
+		t.Fatalf("unexpected token %s", token)
+	}
+	// custom header and query parameter
+	p, err = newProvider("custom", &v2.JwtProvider{
+		JwksFile:    file,
+		FromHeaders: []v2.JwtHeader{{Name: "x-jwt"}},
+		FromParams:  []string{"token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, header := p.extract(protocol.CommonHeader{"x-jwt": "abc"}); token != "abc" || header != "x-jwt" {
+		t.Fatalf("unexpected token %s from %s", token, header)
+	}
+	if token, header := p.extract(protocol.CommonHeader{types.HeaderQueryString: "a=1&token=abc"}); token != "abc" || header != "" {
+		t.Fatalf("unexpected token %s from %s", token, header)
+	}
+	if token, _ := p.extract(protocol.CommonHeader{"Authorization": "Bearer abc"}); token != "" {
+		t.Fatalf("unexpected token %s", token)
+	}
+	// the jwks file is not found
+	if _, err := newProvider("invalid", &v2.JwtProvider{JwksFile: filepath.Join(dir, "not_exists")}); err == nil {
+		t.Fatal("expected create provider failed")
+	}
+}
+
+func TestProviderReloadJwks(t *testing.T) {
+	keys := newTestKeys(t)
+	dir, err := ioutil.TempDir("", "jwtauthn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := writeJwksFile(t, dir, keys.jwks())
+	p, err := newProvider("reload", &v2.JwtProvider{JwksFile: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	p.timeNowFun = func() time.Time { return now }
+	claims := map[string]interface{}{"sub": "user"}
+	if _, err := p.verify(keys.sign(t, AlgRS256, "rsa", claims)); err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	// rotate the keys, the old keys are used before the reload interval
+	newKeys := newTestKeys(t)
+	writeJwksFile(t, dir, newKeys.jwks())
+	if _, err := p.verify(newKeys.sign(t, AlgRS256, "rsa", claims)); err != ErrJwtVerificationFail {
+		t.Fatalf("expected verification failed, but got %v", err)
+	}
+	// triggers the reload
+	now = now.Add(defaultJwksReloadInterval)
+	p.getKeys()
+	for i := 0; i < 100; i++ {
+		if _, err = p.verify(newKeys.sign(t, AlgRS256, "rsa", claims)); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("verify with the reloaded keys failed: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwtauthn
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/variable"
+	"mosn.io/pkg/buffer"
+)
+
+const headerWWWAuthenticate = "WWW-Authenticate"
+
+// parseJwtRequirement parses the per route config
+func parseJwtRequirement(c interface{}) (*v2.JwtRequirement, bool) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		log.DefaultLogger.Errorf("[stream filter] [jwt authn] per route config is not a json, %v", err)
+		return nil, false
+	}
+	req := &v2.JwtRequirement{}
+	if err := json.Unmarshal(b, req); err != nil {
+		log.DefaultLogger.Errorf("[stream filter] [jwt authn] per route config is not a jwt requirement, %v", err)
+		return nil, false
+	}
+	return req, true
+}
+
+// streamJwtAuthnFilter is an implement of api.StreamReceiverFilter
+type streamJwtAuthnFilter struct {
+	ctx         context.Context
+	handler     api.StreamReceiverFilterHandler
+	providers   map[string]*provider
+	requirement *v2.JwtRequirement
+}
+
+func NewFilter(ctx context.Context, providers map[string]*provider, requirement *v2.JwtRequirement) api.StreamReceiverFilter {
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [jwt authn] create a new jwt authn filter")
+	}
+	return &streamJwtAuthnFilter{
+		ctx:         ctx,
+		providers:   providers,
+		requirement: requirement,
+	}
+}
+
+// ReadPerRouteConfig makes route-level requirement override filter-level requirement
+func (f *streamJwtAuthnFilter) ReadPerRouteConfig(cfg map[string]interface{}) {
+	if cfg == nil {
+		return
+	}
+	if c, ok := cfg[v2.JwtAuthn]; ok {
+		if req, ok := parseJwtRequirement(c); ok {
+			if log.Proxy.GetLogLevel() >= log.DEBUG {
+				log.Proxy.Debugf(f.ctx, "[stream filter] [jwt authn] use router config to replace stream filter config, config: %v", c)
+			}
+			f.requirement = req
+		}
+	}
+}
+
+func (f *streamJwtAuthnFilter) SetReceiveFilterHandler(handler api.StreamReceiverFilterHandler) {
+	f.handler = handler
+}
+
+func (f *streamJwtAuthnFilter) OnReceive(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	if route := f.handler.Route(); route != nil {
+		f.ReadPerRouteConfig(route.RouteRule().PerFilterConfig())
+	}
+	req := f.requirement
+	if req == nil || req.Disabled {
+		return api.StreamFilterContinue
+	}
+	p, ok := f.providers[req.ProviderName]
+	if !ok {
+		log.Proxy.Errorf(ctx, "[stream filter] [jwt authn] jwt provider %s is not configured", req.ProviderName)
+		return f.reject(headers, ErrJwtProviderNotFound)
+	}
+	token, header := p.extract(headers)
+	if token == "" {
+		if req.AllowMissing {
+			return api.StreamFilterContinue
+		}
+		return f.reject(headers, ErrJwtNotFound)
+	}
+	t, err := p.verify(token)
+	if err != nil {
+		if log.Proxy.GetLogLevel() >= log.DEBUG {
+			log.Proxy.Debugf(ctx, "[stream filter] [jwt authn] provider %s verify jwt failed: %v", p.name, err)
+		}
+		return f.reject(headers, err)
+	}
+	if header != "" && !p.forward {
+		headers.Del(header)
+	}
+	if p.payloadHeader != "" {
+		headers.Set(p.payloadHeader, base64.RawURLEncoding.EncodeToString(t.payload))
+	}
+	if err := variable.SetVariableValue(ctx, VarJwtProvider, p.name); err != nil {
+		log.Proxy.Warnf(ctx, "[stream filter] [jwt authn] set variable %s failed: %v", VarJwtProvider, err)
+	}
+	if err := variable.SetVariableValue(ctx, VarJwtPayload, string(t.payload)); err != nil {
+		log.Proxy.Warnf(ctx, "[stream filter] [jwt authn] set variable %s failed: %v", VarJwtPayload, err)
+	}
+	// the claims are parsed already, keep them for the claim variables
+	mosnctx.WithValue(ctx, types.ContextKeyJwtClaims, t.claims)
+	return api.StreamFilterContinue
+}
+
+// reject replies with the request headers, so that the protocols such as xprotocol can build the response
+func (f *streamJwtAuthnFilter) reject(headers api.HeaderMap, err error) api.StreamFilterStatus {
+	f.handler.RequestInfo().SetResponseFlag(types.Unauthorized)
+	headers.Set(headerWWWAuthenticate, fmt.Sprintf("Bearer realm=\"mosn\", error=\"invalid_token\", error_description=\"%s\"", err.Error()))
+	f.handler.SendHijackReply(http.StatusUnauthorized, headers)
+	return api.StreamFilterStop
+}
+
+func (f *streamJwtAuthnFilter) OnDestroy() {}
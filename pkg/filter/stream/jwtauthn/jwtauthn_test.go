@@ -0,0 +1,242 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwtauthn
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/xprotocol"
+	"mosn.io/mosn/pkg/protocol/xprotocol/bolt"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/variable"
+)
+
+func TestCreateJwtAuthnFilterFactory(t *testing.T) {
+	keys := newTestKeys(t)
+	dir, err := ioutil.TempDir("", "jwtauthn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := writeJwksFile(t, dir, keys.jwks())
+	conf := map[string]interface{}{
+		"providers": map[string]interface{}{
+			"mosn": map[string]interface{}{
+				"issuer":               "mosn",
+				"audiences":            []string{"a"},
+				"jwks_file":            file,
+				"jwks_reload_interval": "10m",
+				"clock_skew_seconds":   30,
+			},
+		},
+		"requirement": map[string]interface{}{
+			"provider_name": "mosn",
+		},
+	}
+	factory, err := CreateJwtAuthnFilterFactory(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := factory.(*FilterConfigFactory).providers["mosn"]
+	if p == nil || p.issuer != "mosn" || p.reloadInterval.Minutes() != 10 || p.clockSkew.Seconds() != 30 {
+		t.Fatalf("unexpected provider: %+v", p)
+	}
+	// the provider in requirement is not configured
+	conf["requirement"] = map[string]interface{}{
+		"provider_name": "unknown",
+	}
+	if _, err := CreateJwtAuthnFilterFactory(conf); err == nil {
+		t.Fatal("expected create factory failed")
+	}
+}
+
+func TestJwtAuthnFilter(t *testing.T) {
+	keys := newTestKeys(t)
+	dir, err := ioutil.TempDir("", "jwtauthn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := writeJwksFile(t, dir, keys.jwks())
+	p, err := newProvider("mosn", &v2.JwtProvider{
+		Issuer:               "mosn",
+		JwksFile:             file,
+		ForwardPayloadHeader: "x-jwt-payload",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	providers := map[string]*provider{"mosn": p}
+	validToken := keys.sign(t, AlgES256, "ec", map[string]interface{}{
+		"iss":    "mosn",
+		"sub":    "user",
+		"groups": []string{"admin"},
+	})
+	invalidToken := keys.sign(t, AlgES256, "ec", map[string]interface{}{
+		"iss": "other",
+	})
+
+	for idx, tc := range []struct {
+		requirement *v2.JwtRequirement
+		routeConfig map[string]interface{}
+		headers     protocol.CommonHeader
+		status      api.StreamFilterStatus
+	}{
+		// no requirement
+		{nil, nil, protocol.CommonHeader{}, api.StreamFilterContinue},
+		{&v2.JwtRequirement{ProviderName: "mosn"}, nil, protocol.CommonHeader{"Authorization": "Bearer " + validToken}, api.StreamFilterContinue},
+		{&v2.JwtRequirement{ProviderName: "mosn"}, nil, protocol.CommonHeader{"Authorization": "Bearer " + invalidToken}, api.StreamFilterStop},
+		{&v2.JwtRequirement{ProviderName: "mosn"}, nil, protocol.CommonHeader{}, api.StreamFilterStop},
+		{&v2.JwtRequirement{ProviderName: "mosn", AllowMissing: true}, nil, protocol.CommonHeader{}, api.StreamFilterContinue},
+		// the invalid jwt is rejected even if missing is allowed
+		{&v2.JwtRequirement{ProviderName: "mosn", AllowMissing: true}, nil, protocol.CommonHeader{"Authorization": "Bearer " + invalidToken}, api.StreamFilterStop},
+		{&v2.JwtRequirement{ProviderName: "unknown"}, nil, protocol.CommonHeader{}, api.StreamFilterStop},
+		// the route disables the jwt authn
+		{&v2.JwtRequirement{ProviderName: "mosn"}, map[string]interface{}{
+			v2.JwtAuthn: map[string]interface{}{"disabled": true},
+		}, protocol.CommonHeader{}, api.StreamFilterContinue},
+		// the route requires jwt authn
+		{nil, map[string]interface{}{
+			v2.JwtAuthn: map[string]interface{}{"provider_name": "mosn"},
+		}, protocol.CommonHeader{}, api.StreamFilterStop},
+	} {
+		cb := &mockStreamReceiverFilterCallbacks{
+			route: &mockRoute{rule: &mockRouteRule{config: tc.routeConfig}},
+			info:  &mockRequestInfo{},
+		}
+		ctx := variable.NewVariableContext(context.Background())
+		f := NewFilter(ctx, providers, tc.requirement)
+		f.SetReceiveFilterHandler(cb)
+		if status := f.OnReceive(ctx, tc.headers, nil, nil); status != tc.status {
+			t.Fatalf("case %d expected status %v, but got %v", idx, tc.status, status)
+		}
+		if tc.status == api.StreamFilterStop {
+			if cb.hijackCode != http.StatusUnauthorized || cb.info.flag != types.Unauthorized {
+				t.Fatalf("case %d unexpected hijack code %d, flag %v", idx, cb.hijackCode, cb.info.flag)
+			}
+			if _, ok := cb.hijackHeaders.Get(headerWWWAuthenticate); !ok {
+				t.Fatalf("case %d expected %s header", idx, headerWWWAuthenticate)
+			}
+		} else if cb.hijackCode != 0 {
+			t.Fatalf("case %d unexpected hijack code %d", idx, cb.hijackCode)
+		}
+	}
+}
+
+func TestJwtAuthnFilterVerified(t *testing.T) {
+	keys := newTestKeys(t)
+	dir, err := ioutil.TempDir("", "jwtauthn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := writeJwksFile(t, dir, keys.jwks())
+	p, err := newProvider("mosn", &v2.JwtProvider{
+		JwksFile:             file,
+		FromHeaders:          []v2.JwtHeader{{Name: "x-jwt"}},
+		ForwardPayloadHeader: "x-jwt-payload",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := keys.sign(t, AlgRS256, "rsa", map[string]interface{}{
+		"sub":    "user",
+		"groups": []string{"admin"},
+	})
+
+	cb := &mockStreamReceiverFilterCallbacks{
+		route: &mockRoute{rule: &mockRouteRule{}},
+		info:  &mockRequestInfo{},
+	}
+	ctx := variable.NewVariableContext(context.Background())
+	f := NewFilter(ctx, map[string]*provider{"mosn": p}, &v2.JwtRequirement{ProviderName: "mosn"})
+	f.SetReceiveFilterHandler(cb)
+	headers := protocol.CommonHeader{"x-jwt": token}
+	if status := f.OnReceive(ctx, headers, nil, nil); status != api.StreamFilterContinue {
+		t.Fatalf("unexpected status %v", status)
+	}
+	// the jwt is not forwarded, the payload is forwarded
+	if _, ok := headers.Get("x-jwt"); ok {
+		t.Fatal("the jwt header should be removed")
+	}
+	payload, ok := headers.Get("x-jwt-payload")
+	if !ok {
+		t.Fatal("the payload header is not set")
+	}
+	if b, err := base64.RawURLEncoding.DecodeString(payload); err != nil || string(b) != `{"groups":["admin"],"sub":"user"}` {
+		t.Fatalf("unexpected payload %s", payload)
+	}
+	// the verified claims are written into the variables
+	for name, expected := range map[string]string{
+		VarJwtProvider:       "mosn",
+		VarJwtPayload:        `{"groups":["admin"],"sub":"user"}`,
+		"jwt_claim_sub":      "user",
+		"jwt_claim_groups":   `["admin"]`,
+		"jwt_claim_notfound": variable.ValueNotFound,
+	} {
+		if v, err := variable.GetVariableValue(ctx, name); err != nil || v != expected {
+			t.Errorf("variable %s expected %s, but got %s, error: %v", name, expected, v, err)
+		}
+	}
+}
+
+func TestJwtAuthnFilterRejectBolt(t *testing.T) {
+	cb := &mockStreamReceiverFilterCallbacks{
+		route: &mockRoute{rule: &mockRouteRule{}},
+		info:  &mockRequestInfo{},
+	}
+	ctx := variable.NewVariableContext(context.Background())
+	f := NewFilter(ctx, map[string]*provider{}, &v2.JwtRequirement{ProviderName: "mosn"})
+	f.SetReceiveFilterHandler(cb)
+	req := bolt.NewRpcRequest(1, protocol.CommonHeader{"service": "test"}, nil)
+	if status := f.OnReceive(ctx, req, nil, nil); status != api.StreamFilterStop {
+		t.Fatalf("unexpected status %v", status)
+	}
+	// the xprotocol stream can only reply with a frame
+	if _, ok := cb.hijackHeaders.(xprotocol.XFrame); !ok {
+		t.Fatalf("the hijack headers is not a frame: %T", cb.hijackHeaders)
+	}
+	if _, ok := cb.hijackHeaders.Get(headerWWWAuthenticate); !ok {
+		t.Fatalf("expected %s header", headerWWWAuthenticate)
+	}
+}
+
+func TestJwtClaimsCached(t *testing.T) {
+	ctx := variable.NewVariableContext(context.Background())
+	if err := variable.SetVariableValue(ctx, VarJwtPayload, `{"sub":"user"}`); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := variable.GetVariableValue(ctx, "jwt_claim_sub"); v != "user" {
+		t.Fatalf("unexpected claim %s", v)
+	}
+	// the claims are parsed once, the later payload change is not parsed again
+	if err := variable.SetVariableValue(ctx, VarJwtPayload, `{"sub":"other"}`); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := variable.GetVariableValue(ctx, "jwt_claim_sub"); v != "user" {
+		t.Fatalf("the claims are not cached, got %s", v)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwtauthn
+
+import (
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/utils"
+)
+
+const (
+	defaultJwksReloadInterval = 5 * time.Minute
+	defaultClockSkew          = 60 * time.Second
+	defaultHeader             = "Authorization"
+	defaultValuePrefix        = "Bearer "
+)
+
+// provider verifies the jwt by the keys loaded from the jwks file
+type provider struct {
+	name           string
+	issuer         string
+	audiences      []string
+	jwksFile       string
+	reloadInterval time.Duration
+	clockSkew      time.Duration
+	fromHeaders    []v2.JwtHeader
+	fromParams     []string
+	forward        bool
+	payloadHeader  string
+
+	mutex      sync.RWMutex
+	keys       []*jwk
+	loadTime   time.Time
+	reloading  int32
+	timeNowFun func() time.Time
+}
+
+func newProvider(name string, cfg *v2.JwtProvider) (*provider, error) {
+	p := &provider{
+		name:           name,
+		issuer:         cfg.Issuer,
+		audiences:      cfg.Audiences,
+		jwksFile:       cfg.JwksFile,
+		reloadInterval: defaultJwksReloadInterval,
+		clockSkew:      defaultClockSkew,
+		fromHeaders:    cfg.FromHeaders,
+		fromParams:     cfg.FromParams,
+		forward:        cfg.Forward,
+		payloadHeader:  cfg.ForwardPayloadHeader,
+		timeNowFun:     time.Now,
+	}
+	if cfg.JwksReloadInterval != nil && cfg.JwksReloadInterval.Duration > 0 {
+		p.reloadInterval = cfg.JwksReloadInterval.Duration
+	}
+	if cfg.ClockSkewSeconds > 0 {
+		p.clockSkew = time.Duration(cfg.ClockSkewSeconds) * time.Second
+	}
+	if len(p.fromHeaders) == 0 && len(p.fromParams) == 0 {
+		p.fromHeaders = []v2.JwtHeader{
+			{Name: defaultHeader, ValuePrefix: defaultValuePrefix},
+		}
+	}
+	if err := p.loadJwks(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *provider) loadJwks() error {
+	data, err := ioutil.ReadFile(p.jwksFile)
+	if err != nil {
+		return err
+	}
+	keys, err := parseJwks(data)
+	if err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	p.keys = keys
+	p.loadTime = p.timeNowFun()
+	p.mutex.Unlock()
+	return nil
+}
+
+// getKeys returns the current keys, and reloads the jwks file in background if the keys are expired.
+// the current keys are kept if the reload fails
+func (p *provider) getKeys() []*jwk {
+	p.mutex.RLock()
+	keys, loadTime := p.keys, p.loadTime
+	p.mutex.RUnlock()
+	if p.timeNowFun().Sub(loadTime) >= p.reloadInterval && atomic.CompareAndSwapInt32(&p.reloading, 0, 1) {
+		utils.GoWithRecover(func() {
+			defer atomic.StoreInt32(&p.reloading, 0)
+			if err := p.loadJwks(); err != nil {
+				log.DefaultLogger.Errorf("[stream filter] [jwt authn] provider %s reload jwks file %s failed: %v", p.name, p.jwksFile, err)
+				// retry after the next interval
+				p.mutex.Lock()
+				p.loadTime = p.timeNowFun()
+				p.mutex.Unlock()
+			}
+		}, nil)
+	}
+	return keys
+}
+
+// extract finds the jwt in the request, returns the token and the header that contains it.
+// the header is empty if the token is extracted from the query parameters
+func (p *provider) extract(headers api.HeaderMap) (token string, header string) {
+	for _, h := range p.fromHeaders {
+		value, ok := headers.Get(h.Name)
+		if !ok {
+			continue
+		}
+		if h.ValuePrefix != "" {
+			if !strings.HasPrefix(value, h.ValuePrefix) {
+				continue
+			}
+			value = value[len(h.ValuePrefix):]
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			return value, h.Name
+		}
+	}
+	if len(p.fromParams) > 0 {
+		if qs, ok := headers.Get(types.HeaderQueryString); ok && qs != "" {
+			values, err := url.ParseQuery(qs)
+			if err != nil {
+				return "", ""
+			}
+			for _, param := range p.fromParams {
+				if value := values.Get(param); value != "" {
+					return value, ""
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+// verify parses and verifies the token
+func (p *provider) verify(token string) (*jwt, error) {
+	t, err := parseJwt(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.verifySignature(p.getKeys()); err != nil {
+		return nil, err
+	}
+	if err := t.verifyClaims(p.issuer, p.audiences, p.timeNowFun(), p.clockSkew); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,102 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwtauthn
+
+import (
+	"context"
+	"encoding/json"
+
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/variable"
+)
+
+// The variables of the verified jwt
+const (
+	// VarJwtProvider is the provider name that verifies the jwt
+	VarJwtProvider string = "jwt_authn_provider"
+	// VarJwtPayload is the json payload of the verified jwt
+	VarJwtPayload string = "jwt_authn_payload"
+
+	// jwtClaimPrefix is the prefix of the verified jwt's claim, such as jwt_claim_sub
+	jwtClaimPrefix string = "jwt_claim_"
+	jwtClaimIndex         = len(jwtClaimPrefix)
+)
+
+var (
+	builtinVariables = []variable.Variable{
+		variable.NewIndexedVariable(VarJwtProvider, nil, nil, variable.BasicSetter, 0),
+		variable.NewIndexedVariable(VarJwtPayload, nil, nil, variable.BasicSetter, 0),
+	}
+
+	prefixVariables = []variable.Variable{
+		variable.NewBasicVariable(jwtClaimPrefix, nil, jwtClaimGetter, nil, 0),
+	}
+)
+
+func init() {
+	// register built-in variables
+	for idx := range builtinVariables {
+		variable.RegisterVariable(builtinVariables[idx])
+	}
+
+	// register prefix variables
+	for idx := range prefixVariables {
+		variable.RegisterPrefixVariable(prefixVariables[idx].Name(), prefixVariables[idx])
+	}
+}
+
+// jwtClaims returns the claims of the verified jwt, the claims are parsed once per stream
+func jwtClaims(ctx context.Context) (map[string]interface{}, bool) {
+	if claims, ok := mosnctx.Get(ctx, types.ContextKeyJwtClaims).(map[string]interface{}); ok {
+		return claims, true
+	}
+	// the payload is set without the filter, parse and cache it
+	payload, err := variable.GetVariableValue(ctx, VarJwtPayload)
+	if err != nil || payload == "" {
+		return nil, false
+	}
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(payload), &claims); err != nil {
+		return nil, false
+	}
+	mosnctx.WithValue(ctx, types.ContextKeyJwtClaims, claims)
+	return claims, true
+}
+
+// jwtClaimGetter gets the claim from the verified jwt payload,
+// the string claim is returned directly, and other claims are returned as json
+func jwtClaimGetter(ctx context.Context, value *variable.IndexedValue, data interface{}) (string, error) {
+	claims, ok := jwtClaims(ctx)
+	if !ok {
+		return variable.ValueNotFound, nil
+	}
+	claimName := data.(string)
+	claim, ok := claims[claimName[jwtClaimIndex:]]
+	if !ok {
+		return variable.ValueNotFound, nil
+	}
+	if s, ok := claim.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(claim)
+	if err != nil {
+		return variable.ValueNotFound, nil
+	}
+	return string(b), nil
+}
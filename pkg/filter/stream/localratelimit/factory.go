@@ -0,0 +1,91 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package localratelimit
+
+import (
+	"context"
+	"encoding/json"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/router"
+)
+
+func init() {
+	api.RegisterStream(v2.LocalRateLimit, CreateLocalRateLimitFilterFactory)
+	router.RegisterPerFilterConfigParser(v2.LocalRateLimit, parsePerRouteConfig)
+}
+
+// FilterConfigFactory creates the local rate limit filters, the buckets are shared by the filters
+type FilterConfigFactory struct {
+	Config  *v2.StreamLocalRateLimit
+	limiter *localRateLimiter
+}
+
+func (f *FilterConfigFactory) CreateFilterChain(context context.Context, callbacks api.StreamFilterChainFactoryCallbacks) {
+	filter := NewFilter(context, f.limiter)
+	callbacks.AddStreamReceiverFilter(filter, api.AfterRoute)
+}
+
+func CreateLocalRateLimitFilterFactory(conf map[string]interface{}) (api.StreamFilterChainFactory, error) {
+	log.DefaultLogger.Debugf("create local rate limit stream filter factory")
+	cfg, err := ParseStreamLocalRateLimitFilter(conf)
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := newLocalRateLimiter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &FilterConfigFactory{
+		Config:  cfg,
+		limiter: limiter,
+	}, nil
+}
+
+// ParseStreamLocalRateLimitFilter
+func ParseStreamLocalRateLimitFilter(cfg map[string]interface{}) (*v2.StreamLocalRateLimit, error) {
+	filterConfig := &v2.StreamLocalRateLimit{}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, filterConfig); err != nil {
+		return nil, err
+	}
+	return filterConfig, nil
+}
+
+// parsePerRouteConfig creates the limiter of the per filter config when the route is created,
+// so the buckets are kept between the requests of the route
+func parsePerRouteConfig(cfg interface{}) (interface{}, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	conf := &v2.StreamLocalRateLimit{}
+	if err := json.Unmarshal(data, conf); err != nil {
+		return nil, err
+	}
+	limiter, err := newLocalRateLimiter(conf)
+	if err != nil {
+		return nil, err
+	}
+	return limiter, nil
+}
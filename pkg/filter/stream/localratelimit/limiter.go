@@ -0,0 +1,249 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package localratelimit
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/filter/stream/commonrule/limit"
+	"mosn.io/mosn/pkg/metrics"
+)
+
+const (
+	defaultStatPrefix = "local_ratelimit"
+	defaultMaxBuckets = 1024
+)
+
+// Stats is the metrics of a descriptor
+type Stats struct {
+	OK        gometrics.Counter
+	OverLimit gometrics.Counter
+}
+
+func newStats(statPrefix, descriptor string) *Stats {
+	s := metrics.NewLocalRateLimitStats(statPrefix, descriptor)
+	return &Stats{
+		OK:        s.Counter(metrics.LocalRateLimitOK),
+		OverLimit: s.Counter(metrics.LocalRateLimitOverLimit),
+	}
+}
+
+// bucketCache is a lru cache of the token buckets
+type bucketCache struct {
+	mutex   sync.Mutex
+	max     int
+	ll      *list.List
+	buckets map[string]*list.Element
+	// newBucket creates a bucket for the new key
+	newBucket func() *limit.TokenBucket
+}
+
+type bucketEntry struct {
+	key    string
+	bucket *limit.TokenBucket
+}
+
+func newBucketCache(max int, newBucket func() *limit.TokenBucket) *bucketCache {
+	return &bucketCache{
+		max:       max,
+		ll:        list.New(),
+		buckets:   make(map[string]*list.Element, max),
+		newBucket: newBucket,
+	}
+}
+
+func (c *bucketCache) get(key string) *limit.TokenBucket {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if elem, ok := c.buckets[key]; ok {
+		c.ll.MoveToFront(elem)
+		return elem.Value.(*bucketEntry).bucket
+	}
+	if c.ll.Len() >= c.max {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.buckets, oldest.Value.(*bucketEntry).key)
+	}
+	entry := &bucketEntry{
+		key:    key,
+		bucket: c.newBucket(),
+	}
+	c.buckets[key] = c.ll.PushFront(entry)
+	return entry.bucket
+}
+
+func (c *bucketCache) len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ll.Len()
+}
+
+// descriptor is parsed from v2.LocalRateLimitDescriptor
+type descriptor struct {
+	name       string
+	keyType    string
+	headerName string
+	values     map[string]struct{}
+	buckets    *bucketCache
+	stats      *Stats
+}
+
+func newDescriptor(statPrefix string, cfg v2.LocalRateLimitDescriptor) (*descriptor, error) {
+	switch cfg.Key {
+	case v2.LocalRateLimitKeyRoute, v2.LocalRateLimitKeyRemoteAddress:
+	case v2.LocalRateLimitKeyHeader:
+		if cfg.HeaderName == "" {
+			return nil, errors.New("local rate limit descriptor with header key should set header_name")
+		}
+	default:
+		return nil, fmt.Errorf("unknown local rate limit descriptor key: %s", cfg.Key)
+	}
+	maxTokens := int64(cfg.TokenBucket.MaxTokens)
+	tokensPerFill := int64(cfg.TokenBucket.TokensPerFill)
+	if tokensPerFill == 0 {
+		tokensPerFill = maxTokens
+	}
+	var interval time.Duration
+	if cfg.TokenBucket.FillInterval != nil {
+		interval = cfg.TokenBucket.FillInterval.Duration
+	}
+	// validates the bucket config
+	if _, err := limit.NewTokenBucket(maxTokens, tokensPerFill, interval); err != nil {
+		return nil, err
+	}
+	d := &descriptor{
+		name:       cfg.Name,
+		keyType:    cfg.Key,
+		headerName: cfg.HeaderName,
+	}
+	if d.name == "" {
+		d.name = d.keyType
+	}
+	if len(cfg.Values) > 0 {
+		d.values = make(map[string]struct{}, len(cfg.Values))
+		for _, v := range cfg.Values {
+			d.values[v] = struct{}{}
+		}
+	}
+	maxBuckets := cfg.MaxBuckets
+	if maxBuckets <= 0 {
+		maxBuckets = defaultMaxBuckets
+	}
+	d.buckets = newBucketCache(maxBuckets, func() *limit.TokenBucket {
+		b, _ := limit.NewTokenBucket(maxTokens, tokensPerFill, interval)
+		return b
+	})
+	d.stats = newStats(statPrefix, d.name)
+	return d, nil
+}
+
+// key returns the bucket key of the request, returns false if the descriptor is not matched
+func (d *descriptor) key(headers api.HeaderMap, conn api.Connection, route api.Route) (string, bool) {
+	var key string
+	switch d.keyType {
+	case v2.LocalRateLimitKeyRoute:
+		if route == nil || route.RouteRule() == nil {
+			return "", false
+		}
+		// the routes in a virtual host share the limiter if the virtual host's per filter config is used,
+		// so the route is identified by its match criterion
+		criterion := route.RouteRule().PathMatchCriterion()
+		if criterion == nil {
+			return "", false
+		}
+		key = fmt.Sprintf("%d:%s", criterion.MatchType(), criterion.Matcher())
+	case v2.LocalRateLimitKeyHeader:
+		value, ok := headers.Get(d.headerName)
+		if !ok || value == "" {
+			return "", false
+		}
+		key = value
+	case v2.LocalRateLimitKeyRemoteAddress:
+		if conn == nil || conn.RemoteAddr() == nil {
+			return "", false
+		}
+		host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+		if err != nil {
+			return "", false
+		}
+		key = host
+	}
+	if d.values != nil {
+		if _, ok := d.values[key]; !ok {
+			return "", false
+		}
+	}
+	return key, true
+}
+
+// localRateLimiter is parsed from v2.StreamLocalRateLimit
+type localRateLimiter struct {
+	disabled    bool
+	statusCode  int
+	descriptors []*descriptor
+}
+
+func newLocalRateLimiter(cfg *v2.StreamLocalRateLimit) (*localRateLimiter, error) {
+	l := &localRateLimiter{
+		disabled:   cfg.Disabled,
+		statusCode: cfg.StatusCode,
+	}
+	if l.statusCode == 0 {
+		l.statusCode = http.StatusTooManyRequests
+	}
+	statPrefix := cfg.StatPrefix
+	if statPrefix == "" {
+		statPrefix = defaultStatPrefix
+	}
+	for _, dc := range cfg.Descriptors {
+		d, err := newDescriptor(statPrefix, dc)
+		if err != nil {
+			return nil, err
+		}
+		l.descriptors = append(l.descriptors, d)
+	}
+	return l, nil
+}
+
+// check returns the descriptor that limits the request, or nil if the request is allowed
+func (l *localRateLimiter) check(headers api.HeaderMap, conn api.Connection, route api.Route) *descriptor {
+	if l.disabled {
+		return nil
+	}
+	for _, d := range l.descriptors {
+		key, ok := d.key(headers, conn, route)
+		if !ok {
+			continue
+		}
+		if !d.buckets.get(key).TryAcquire() {
+			d.stats.OverLimit.Inc(1)
+			return d
+		}
+		d.stats.OK.Inc(1)
+	}
+	return nil
+}
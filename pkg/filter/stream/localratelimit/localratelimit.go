@@ -0,0 +1,85 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package localratelimit
+
+import (
+	"context"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/pkg/buffer"
+)
+
+// streamLocalRateLimitFilter is an implement of api.StreamReceiverFilter
+type streamLocalRateLimitFilter struct {
+	ctx     context.Context
+	handler api.StreamReceiverFilterHandler
+	limiter *localRateLimiter
+}
+
+func NewFilter(ctx context.Context, limiter *localRateLimiter) api.StreamReceiverFilter {
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [local ratelimit] create a new local rate limit filter")
+	}
+	return &streamLocalRateLimitFilter{
+		ctx:     ctx,
+		limiter: limiter,
+	}
+}
+
+// ReadPerRouteConfig makes route-level configuration override filter-level configuration
+func (f *streamLocalRateLimitFilter) ReadPerRouteConfig(cfg map[string]interface{}) {
+	if cfg == nil {
+		return
+	}
+	if c, ok := cfg[v2.LocalRateLimit]; ok {
+		l, ok := c.(*localRateLimiter)
+		if !ok {
+			log.Proxy.Errorf(f.ctx, "[stream filter] [local ratelimit] invalid router config: %v", c)
+			return
+		}
+		if log.Proxy.GetLogLevel() >= log.DEBUG {
+			log.Proxy.Debugf(f.ctx, "[stream filter] [local ratelimit] use router config to replace stream filter config, config: %v", c)
+		}
+		f.limiter = l
+	}
+}
+
+func (f *streamLocalRateLimitFilter) SetReceiveFilterHandler(handler api.StreamReceiverFilterHandler) {
+	f.handler = handler
+}
+
+func (f *streamLocalRateLimitFilter) OnReceive(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	route := f.handler.Route()
+	if route != nil && route.RouteRule() != nil {
+		f.ReadPerRouteConfig(route.RouteRule().PerFilterConfig())
+	}
+	d := f.limiter.check(headers, f.handler.Connection(), route)
+	if d == nil {
+		return api.StreamFilterContinue
+	}
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [local ratelimit] the request is limited by the descriptor %s", d.name)
+	}
+	f.handler.RequestInfo().SetResponseFlag(api.RateLimited)
+	f.handler.SendHijackReply(f.limiter.statusCode, headers)
+	return api.StreamFilterStop
+}
+
+func (f *streamLocalRateLimitFilter) OnDestroy() {}
@@ -0,0 +1,270 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package localratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/filter/stream/commonrule/limit"
+	"mosn.io/mosn/pkg/protocol"
+)
+
+// mocks the interface that used for test
+// only implement the function that used in test
+type mockStreamReceiverFilterCallbacks struct {
+	api.StreamReceiverFilterHandler
+	route      *mockRoute
+	conn       api.Connection
+	hijackCode int
+	info       *mockRequestInfo
+}
+
+func (cb *mockStreamReceiverFilterCallbacks) Route() api.Route {
+	if cb.route == nil {
+		return nil
+	}
+	return cb.route
+}
+func (cb *mockStreamReceiverFilterCallbacks) Connection() api.Connection {
+	return cb.conn
+}
+func (cb *mockStreamReceiverFilterCallbacks) RequestInfo() api.RequestInfo {
+	return cb.info
+}
+func (cb *mockStreamReceiverFilterCallbacks) SendHijackReply(code int, headers api.HeaderMap) {
+	cb.hijackCode = code
+}
+
+type mockRoute struct {
+	api.Route
+	rule *mockRouteRule
+}
+
+func (r *mockRoute) RouteRule() api.RouteRule {
+	return r.rule
+}
+
+type mockRouteRule struct {
+	api.RouteRule
+	path   string
+	config map[string]interface{}
+}
+
+func (r *mockRouteRule) PerFilterConfig() map[string]interface{} {
+	return r.config
+}
+
+func (r *mockRouteRule) PathMatchCriterion() api.PathMatchCriterion {
+	return r
+}
+
+func (r *mockRouteRule) MatchType() api.PathMatchType {
+	return api.Prefix
+}
+
+func (r *mockRouteRule) Matcher() string {
+	return r.path
+}
+
+type mockRequestInfo struct {
+	api.RequestInfo
+	flag api.ResponseFlag
+}
+
+func (info *mockRequestInfo) SetResponseFlag(flag api.ResponseFlag) {
+	info.flag = flag
+}
+
+type mockConnection struct {
+	api.Connection
+	remote net.Addr
+}
+
+func (c *mockConnection) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func newMockConnection(remote string) *mockConnection {
+	addr, _ := net.ResolveTCPAddr("tcp", remote)
+	return &mockConnection{remote: addr}
+}
+
+func TestBucketCache(t *testing.T) {
+	created := 0
+	c := newBucketCache(2, func() *limit.TokenBucket {
+		created++
+		b, _ := limit.NewTokenBucket(1, 1, 1<<40)
+		return b
+	})
+	a := c.get("a")
+	c.get("b")
+	if c.get("a") != a {
+		t.Fatal("expected the cached bucket")
+	}
+	// b is the least recently used
+	c.get("c")
+	if c.len() != 2 || created != 3 {
+		t.Fatalf("unexpected cache: %d %d", c.len(), created)
+	}
+	if c.get("a") != a {
+		t.Fatal("a should not be evicted")
+	}
+	c.get("b")
+	if created != 4 {
+		t.Fatal("b should be evicted and recreated")
+	}
+}
+
+func TestCreateLocalRateLimitFilterFactory(t *testing.T) {
+	for idx, conf := range []map[string]interface{}{
+		{"descriptors": []interface{}{map[string]interface{}{"key": "cookie"}}},
+		{"descriptors": []interface{}{map[string]interface{}{
+			"key":          "header",
+			"token_bucket": map[string]interface{}{"max_tokens": 1, "fill_interval": "1s"},
+		}}},
+		{"descriptors": []interface{}{map[string]interface{}{
+			"key":          "route",
+			"token_bucket": map[string]interface{}{"max_tokens": 1},
+		}}},
+	} {
+		if _, err := CreateLocalRateLimitFilterFactory(conf); err == nil {
+			t.Errorf("case %d expected create factory failed", idx)
+		}
+	}
+}
+
+func newFilterForTest(t *testing.T, conf map[string]interface{}) (*FilterConfigFactory, func() (*streamLocalRateLimitFilter, *mockStreamReceiverFilterCallbacks)) {
+	factory, err := CreateLocalRateLimitFilterFactory(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ff := factory.(*FilterConfigFactory)
+	return ff, func() (*streamLocalRateLimitFilter, *mockStreamReceiverFilterCallbacks) {
+		f := NewFilter(context.Background(), ff.limiter).(*streamLocalRateLimitFilter)
+		cb := &mockStreamReceiverFilterCallbacks{
+			conn: newMockConnection("10.1.1.1:12345"),
+			info: &mockRequestInfo{},
+		}
+		f.SetReceiveFilterHandler(cb)
+		return f, cb
+	}
+}
+
+func TestLocalRateLimitFilter(t *testing.T) {
+	factory, newFilter := newFilterForTest(t, map[string]interface{}{
+		"stat_prefix": "test_local_ratelimit",
+		"descriptors": []interface{}{
+			map[string]interface{}{
+				"name":         "vip",
+				"key":          "header",
+				"header_name":  "user",
+				"values":       []string{"alice"},
+				"token_bucket": map[string]interface{}{"max_tokens": 3, "fill_interval": "1h"},
+			},
+			map[string]interface{}{
+				"key":          "remote_address",
+				"token_bucket": map[string]interface{}{"max_tokens": 2, "fill_interval": "1h"},
+			},
+		},
+	})
+	for idx, tc := range []struct {
+		headers api.HeaderMap
+		remote  string
+		limited bool
+	}{
+		{headers: protocol.CommonHeader{"user": "alice"}, remote: "10.1.1.1:1"},
+		{headers: protocol.CommonHeader{"user": "alice"}, remote: "10.1.1.1:2"},
+		// the remote address bucket is empty
+		{headers: protocol.CommonHeader{"user": "alice"}, remote: "10.1.1.1:3", limited: true},
+		// another client ip has its own bucket, the vip bucket is empty now
+		{headers: protocol.CommonHeader{"user": "alice"}, remote: "10.1.1.2:1", limited: true},
+		// the user bob does not match the vip descriptor
+		{headers: protocol.CommonHeader{"user": "bob"}, remote: "10.1.1.3:1"},
+	} {
+		f, cb := newFilter()
+		cb.conn = newMockConnection(tc.remote)
+		status := f.OnReceive(context.Background(), tc.headers, nil, nil)
+		if tc.limited {
+			if status != api.StreamFilterStop || cb.hijackCode != http.StatusTooManyRequests || cb.info.flag != api.RateLimited {
+				t.Errorf("case %d expected limited", idx)
+			}
+		} else if status != api.StreamFilterContinue {
+			t.Errorf("case %d expected allowed", idx)
+		}
+	}
+	vip, remote := factory.limiter.descriptors[0], factory.limiter.descriptors[1]
+	if vip.stats.OK.Count() != 3 || vip.stats.OverLimit.Count() != 1 ||
+		remote.stats.OK.Count() != 3 || remote.stats.OverLimit.Count() != 1 {
+		t.Fatalf("unexpected stats: vip %d %d, remote %d %d", vip.stats.OK.Count(), vip.stats.OverLimit.Count(),
+			remote.stats.OK.Count(), remote.stats.OverLimit.Count())
+	}
+}
+
+func TestLocalRateLimitPerRoute(t *testing.T) {
+	_, newFilter := newFilterForTest(t, map[string]interface{}{})
+	// the per filter configs are parsed when the routes are created
+	parse := func(cfg map[string]interface{}) map[string]interface{} {
+		limiter, err := parsePerRouteConfig(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]interface{}{v2.LocalRateLimit: limiter}
+	}
+	perRoute := parse(map[string]interface{}{
+		"stat_prefix": "test_local_ratelimit_route",
+		"status_code": 503,
+		"descriptors": []interface{}{
+			map[string]interface{}{
+				"key":          "route",
+				"token_bucket": map[string]interface{}{"max_tokens": 1, "fill_interval": "1h"},
+			},
+		},
+	})
+	if _, err := parsePerRouteConfig(map[string]interface{}{"descriptors": "invalid"}); err == nil {
+		t.Fatal("expected the invalid per filter config is rejected")
+	}
+	for idx, tc := range []struct {
+		route   *mockRoute
+		limited bool
+	}{
+		// the filter level config has no descriptors
+		{},
+		{route: &mockRoute{rule: &mockRouteRule{path: "/a", config: perRoute}}},
+		{route: &mockRoute{rule: &mockRouteRule{path: "/a", config: perRoute}}, limited: true},
+		// the routes share the per filter config, but have their own buckets
+		{route: &mockRoute{rule: &mockRouteRule{path: "/b", config: perRoute}}},
+		// the route disables the limit
+		{route: &mockRoute{rule: &mockRouteRule{path: "/a", config: parse(map[string]interface{}{"disabled": true})}}},
+	} {
+		f, cb := newFilter()
+		cb.route = tc.route
+		status := f.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil)
+		if tc.limited {
+			if status != api.StreamFilterStop || cb.hijackCode != http.StatusServiceUnavailable {
+				t.Errorf("case %d expected limited", idx)
+			}
+		} else if status != api.StreamFilterContinue {
+			t.Errorf("case %d expected allowed", idx)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	rls "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v2"
+	"google.golang.org/grpc"
+	"mosn.io/mosn/pkg/upstream/cluster"
+)
+
+var (
+	errNoRateLimitHost = errors.New("no available host in the rate limit cluster")
+	errPanic           = errors.New("panic when calling the rate limit service")
+)
+
+// rateLimitClient calls the rate limit service
+type rateLimitClient interface {
+	ShouldRateLimit(ctx context.Context, host string, req *rls.RateLimitRequest) (*rls.RateLimitResponse, error)
+}
+
+// grpcClient calls the envoy.service.ratelimit.v2.RateLimitService
+type grpcClient struct {
+	// conns caches the grpc connections by the host address
+	conns sync.Map
+}
+
+func (c *grpcClient) getConn(host string) (*grpc.ClientConn, error) {
+	if conn, ok := c.conns.Load(host); ok {
+		return conn.(*grpc.ClientConn), nil
+	}
+	conn, err := grpc.Dial(host, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	if actual, loaded := c.conns.LoadOrStore(host, conn); loaded {
+		conn.Close()
+		return actual.(*grpc.ClientConn), nil
+	}
+	return conn, nil
+}
+
+func (c *grpcClient) ShouldRateLimit(ctx context.Context, host string, req *rls.RateLimitRequest) (*rls.RateLimitResponse, error) {
+	conn, err := c.getConn(host)
+	if err != nil {
+		return nil, err
+	}
+	return rls.NewRateLimitServiceClient(conn).ShouldRateLimit(ctx, req)
+}
+
+// clusterHost chooses a host in the rate limit cluster
+func clusterHost(ctx context.Context, clusterName string) (string, error) {
+	adapter := cluster.GetClusterMngAdapterInstance()
+	if adapter == nil {
+		return "", errNoRateLimitHost
+	}
+	snapshot := adapter.GetClusterSnapshot(ctx, clusterName)
+	if snapshot == nil {
+		return "", fmt.Errorf("rate limit cluster %s is not found", clusterName)
+	}
+	host := snapshot.LoadBalancer().ChooseHost(cluster.NewLoadBalancerContext(ctx))
+	if host == nil {
+		return "", errNoRateLimitHost
+	}
+	return host.AddressString(), nil
+}
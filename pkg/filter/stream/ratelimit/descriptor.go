@@ -0,0 +1,186 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"errors"
+	"net"
+
+	"github.com/envoyproxy/go-control-plane/envoy/api/v2/ratelimit"
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+)
+
+const (
+	remoteAddressKey = "remote_address"
+	genericKey       = "generic_key"
+	serviceNameKey   = "service_name"
+	// serviceHeader is the bolt protocol's service header key
+	serviceHeader = types.SofaRouteMatchKey
+)
+
+var (
+	errInvalidAction = errors.New("rate limit action should set only one of request_headers, remote_address, generic_key and service_name")
+	errEmptyPolicy   = errors.New("rate limit policy has no actions")
+)
+
+// descriptorAction makes a descriptor entry, returns false if the entry can not be made
+type descriptorAction interface {
+	entry(headers api.HeaderMap, conn api.Connection) (*ratelimit.RateLimitDescriptor_Entry, bool)
+}
+
+type requestHeadersAction struct {
+	headerName    string
+	descriptorKey string
+}
+
+func (a *requestHeadersAction) entry(headers api.HeaderMap, conn api.Connection) (*ratelimit.RateLimitDescriptor_Entry, bool) {
+	value, ok := headers.Get(a.headerName)
+	if !ok || value == "" {
+		return nil, false
+	}
+	return &ratelimit.RateLimitDescriptor_Entry{Key: a.descriptorKey, Value: value}, true
+}
+
+type remoteAddressAction struct{}
+
+func (a *remoteAddressAction) entry(headers api.HeaderMap, conn api.Connection) (*ratelimit.RateLimitDescriptor_Entry, bool) {
+	if conn == nil || conn.RemoteAddr() == nil {
+		return nil, false
+	}
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return nil, false
+	}
+	return &ratelimit.RateLimitDescriptor_Entry{Key: remoteAddressKey, Value: host}, true
+}
+
+type genericKeyAction struct {
+	descriptorKey   string
+	descriptorValue string
+}
+
+func (a *genericKeyAction) entry(headers api.HeaderMap, conn api.Connection) (*ratelimit.RateLimitDescriptor_Entry, bool) {
+	return &ratelimit.RateLimitDescriptor_Entry{Key: a.descriptorKey, Value: a.descriptorValue}, true
+}
+
+type serviceNameAction struct {
+	descriptorKey string
+}
+
+func (a *serviceNameAction) entry(headers api.HeaderMap, conn api.Connection) (*ratelimit.RateLimitDescriptor_Entry, bool) {
+	// the xprotocol stream sets the rpc service header if the protocol is service aware
+	value, ok := headers.Get(types.HeaderRPCService)
+	if !ok || value == "" {
+		value, ok = headers.Get(serviceHeader)
+	}
+	if !ok || value == "" {
+		return nil, false
+	}
+	return &ratelimit.RateLimitDescriptor_Entry{Key: a.descriptorKey, Value: value}, true
+}
+
+func newDescriptorAction(cfg v2.RateLimitAction) (descriptorAction, error) {
+	var actions []descriptorAction
+	if h := cfg.RequestHeaders; h != nil {
+		if h.HeaderName == "" || h.DescriptorKey == "" {
+			return nil, errors.New("rate limit request_headers action should set header_name and descriptor_key")
+		}
+		actions = append(actions, &requestHeadersAction{
+			headerName:    h.HeaderName,
+			descriptorKey: h.DescriptorKey,
+		})
+	}
+	if cfg.RemoteAddress != nil {
+		actions = append(actions, &remoteAddressAction{})
+	}
+	if g := cfg.GenericKey; g != nil {
+		if g.DescriptorValue == "" {
+			return nil, errors.New("rate limit generic_key action should set descriptor_value")
+		}
+		key := g.DescriptorKey
+		if key == "" {
+			key = genericKey
+		}
+		actions = append(actions, &genericKeyAction{
+			descriptorKey:   key,
+			descriptorValue: g.DescriptorValue,
+		})
+	}
+	if s := cfg.ServiceName; s != nil {
+		key := s.DescriptorKey
+		if key == "" {
+			key = serviceNameKey
+		}
+		actions = append(actions, &serviceNameAction{descriptorKey: key})
+	}
+	if len(actions) != 1 {
+		return nil, errInvalidAction
+	}
+	return actions[0], nil
+}
+
+// rateLimitPolicy makes a descriptor by the actions
+type rateLimitPolicy struct {
+	actions []descriptorAction
+}
+
+func newRateLimitPolicies(cfgs []v2.RateLimitPolicy) ([]*rateLimitPolicy, error) {
+	policies := make([]*rateLimitPolicy, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		if len(cfg.Actions) == 0 {
+			return nil, errEmptyPolicy
+		}
+		p := &rateLimitPolicy{}
+		for _, ac := range cfg.Actions {
+			action, err := newDescriptorAction(ac)
+			if err != nil {
+				return nil, err
+			}
+			p.actions = append(p.actions, action)
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+func (p *rateLimitPolicy) descriptor(headers api.HeaderMap, conn api.Connection) (*ratelimit.RateLimitDescriptor, bool) {
+	d := &ratelimit.RateLimitDescriptor{
+		Entries: make([]*ratelimit.RateLimitDescriptor_Entry, 0, len(p.actions)),
+	}
+	for _, action := range p.actions {
+		entry, ok := action.entry(headers, conn)
+		if !ok {
+			return nil, false
+		}
+		d.Entries = append(d.Entries, entry)
+	}
+	return d, true
+}
+
+// makeDescriptors makes the descriptors of the policies, the policy that can not make a descriptor is skipped
+func makeDescriptors(policies []*rateLimitPolicy, headers api.HeaderMap, conn api.Connection) []*ratelimit.RateLimitDescriptor {
+	var descriptors []*ratelimit.RateLimitDescriptor
+	for _, p := range policies {
+		if d, ok := p.descriptor(headers, conn); ok {
+			descriptors = append(descriptors, d)
+		}
+	}
+	return descriptors
+}
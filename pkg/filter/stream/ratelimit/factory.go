@@ -0,0 +1,76 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+)
+
+func init() {
+	api.RegisterStream(v2.RateLimit, CreateRateLimitFilterFactory)
+}
+
+// FilterConfigFactory creates the rate limit filters, the local token bucket and the stats are shared by the filters
+type FilterConfigFactory struct {
+	Config *v2.StreamRateLimit
+	config *rateLimitConfig
+}
+
+func (f *FilterConfigFactory) CreateFilterChain(context context.Context, callbacks api.StreamFilterChainFactoryCallbacks) {
+	filter := newRateLimitFilter(context, f.config)
+	callbacks.AddStreamReceiverFilter(filter, api.AfterRoute)
+	callbacks.AddStreamSenderFilter(filter)
+}
+
+func CreateRateLimitFilterFactory(conf map[string]interface{}) (api.StreamFilterChainFactory, error) {
+	log.DefaultLogger.Debugf("create rate limit stream filter factory")
+	cfg, err := ParseStreamRateLimitFilter(conf)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Domain == "" || cfg.Cluster == "" {
+		return nil, errors.New("rate limit filter should set domain and cluster")
+	}
+	config, err := makeRateLimitConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &FilterConfigFactory{
+		Config: cfg,
+		config: config,
+	}, nil
+}
+
+// ParseStreamRateLimitFilter
+func ParseStreamRateLimitFilter(cfg map[string]interface{}) (*v2.StreamRateLimit, error) {
+	filterConfig := &v2.StreamRateLimit{}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, filterConfig); err != nil {
+		return nil, err
+	}
+	return filterConfig, nil
+}
@@ -0,0 +1,331 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	rls "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v2"
+	gometrics "github.com/rcrowley/go-metrics"
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/filter/stream/commonrule/limit"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/pkg/buffer"
+	"mosn.io/pkg/utils"
+)
+
+const (
+	defaultTimeout    = 20 * time.Millisecond
+	defaultStatPrefix = "ratelimit"
+)
+
+// the draft rate limit headers, see https://tools.ietf.org/id/draft-polli-ratelimit-headers-03.html
+const (
+	headerRateLimitLimit     = "x-ratelimit-limit"
+	headerRateLimitRemaining = "x-ratelimit-remaining"
+	headerRateLimitReset     = "x-ratelimit-reset"
+)
+
+// Stats is the rate limit metrics
+type Stats struct {
+	OK                 gometrics.Counter
+	OverLimit          gometrics.Counter
+	Error              gometrics.Counter
+	FailureModeAllowed gometrics.Counter
+	LocalOverLimit     gometrics.Counter
+}
+
+func newStats(statPrefix string) *Stats {
+	s := metrics.NewRateLimitStats(statPrefix)
+	return &Stats{
+		OK:                 s.Counter(metrics.RateLimitOK),
+		OverLimit:          s.Counter(metrics.RateLimitOverLimit),
+		Error:              s.Counter(metrics.RateLimitError),
+		FailureModeAllowed: s.Counter(metrics.RateLimitFailureModeAllowed),
+		LocalOverLimit:     s.Counter(metrics.RateLimitLocalOverLimit),
+	}
+}
+
+// rateLimitConfig is parsed from v2.StreamRateLimit
+type rateLimitConfig struct {
+	domain            string
+	cluster           string
+	timeout           time.Duration
+	failureModeDeny   bool
+	policies          []*rateLimitPolicy
+	localBucket       *limit.TokenBucket
+	xRateLimitHeaders bool
+	stats             *Stats
+	client            rateLimitClient
+	// getHost chooses a host of the rate limit service
+	getHost func(ctx context.Context, cluster string) (string, error)
+}
+
+func makeRateLimitConfig(cfg *v2.StreamRateLimit) (*rateLimitConfig, error) {
+	policies, err := newRateLimitPolicies(cfg.RateLimits)
+	if err != nil {
+		return nil, err
+	}
+	config := &rateLimitConfig{
+		domain:            cfg.Domain,
+		cluster:           cfg.Cluster,
+		timeout:           defaultTimeout,
+		failureModeDeny:   cfg.FailureModeDeny,
+		policies:          policies,
+		xRateLimitHeaders: cfg.EnableXRateLimitHeaders,
+		client:            &grpcClient{},
+		getHost:           clusterHost,
+	}
+	if cfg.Timeout != nil && cfg.Timeout.Duration > 0 {
+		config.timeout = cfg.Timeout.Duration
+	}
+	if b := cfg.LocalTokenBucket; b != nil {
+		tokensPerFill := b.TokensPerFill
+		if tokensPerFill == 0 {
+			tokensPerFill = b.MaxTokens
+		}
+		var interval time.Duration
+		if b.FillInterval != nil {
+			interval = b.FillInterval.Duration
+		}
+		if config.localBucket, err = limit.NewTokenBucket(int64(b.MaxTokens), int64(tokensPerFill), interval); err != nil {
+			return nil, err
+		}
+	}
+	statPrefix := cfg.StatPrefix
+	if statPrefix == "" {
+		statPrefix = defaultStatPrefix
+	}
+	config.stats = newStats(statPrefix)
+	return config, nil
+}
+
+// parseRateLimitPerRoute parses the route's per filter config
+func parseRateLimitPerRoute(cfg interface{}) (*v2.RateLimitPerRoute, []*rateLimitPolicy, bool) {
+	perRoute := &v2.RateLimitPerRoute{}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, nil, false
+	}
+	if err := json.Unmarshal(data, perRoute); err != nil {
+		return nil, nil, false
+	}
+	policies, err := newRateLimitPolicies(perRoute.RateLimits)
+	if err != nil {
+		return nil, nil, false
+	}
+	return perRoute, policies, true
+}
+
+// streamRateLimitFilter is an implement of api.StreamReceiverFilter and api.StreamSenderFilter,
+// the sender filter adds the rate limit headers to the response
+type streamRateLimitFilter struct {
+	ctx           context.Context
+	config        *rateLimitConfig
+	policies      []*rateLimitPolicy
+	disabled      bool
+	handler       api.StreamReceiverFilterHandler
+	senderHandler api.StreamSenderFilterHandler
+	// respHeaders are added to the response
+	respHeaders map[string]string
+	stop        chan struct{}
+}
+
+func newRateLimitFilter(ctx context.Context, config *rateLimitConfig) *streamRateLimitFilter {
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [ratelimit] create a new rate limit filter")
+	}
+	return &streamRateLimitFilter{
+		ctx:      ctx,
+		config:   config,
+		policies: config.policies,
+		stop:     make(chan struct{}),
+	}
+}
+
+// ReadPerRouteConfig makes route-level configuration override filter-level configuration
+func (f *streamRateLimitFilter) ReadPerRouteConfig(cfg map[string]interface{}) {
+	if cfg == nil {
+		return
+	}
+	if c, ok := cfg[v2.RateLimit]; ok {
+		if perRoute, policies, ok := parseRateLimitPerRoute(c); ok {
+			if log.Proxy.GetLogLevel() >= log.DEBUG {
+				log.Proxy.Debugf(f.ctx, "[stream filter] [ratelimit] use router config to replace stream filter config, config: %v", c)
+			}
+			f.disabled = perRoute.Disabled
+			if len(policies) > 0 {
+				f.policies = policies
+			}
+		}
+	}
+}
+
+func (f *streamRateLimitFilter) SetReceiveFilterHandler(handler api.StreamReceiverFilterHandler) {
+	f.handler = handler
+}
+
+func (f *streamRateLimitFilter) SetSenderFilterHandler(handler api.StreamSenderFilterHandler) {
+	f.senderHandler = handler
+}
+
+type rateLimitResult struct {
+	resp *rls.RateLimitResponse
+	err  error
+}
+
+func (f *streamRateLimitFilter) OnReceive(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	if route := f.handler.Route(); route != nil {
+		f.ReadPerRouteConfig(route.RouteRule().PerFilterConfig())
+	}
+	if f.disabled {
+		return api.StreamFilterContinue
+	}
+	descriptors := makeDescriptors(f.policies, headers, f.handler.Connection())
+	if len(descriptors) == 0 {
+		return api.StreamFilterContinue
+	}
+	// the local token bucket protects the rate limit service
+	if f.config.localBucket != nil && !f.config.localBucket.TryAcquire() {
+		f.config.stats.LocalOverLimit.Inc(1)
+		if log.Proxy.GetLogLevel() >= log.DEBUG {
+			log.Proxy.Debugf(ctx, "[stream filter] [ratelimit] the request is limited by the local token bucket")
+		}
+		return f.reject(headers, http.StatusTooManyRequests)
+	}
+	req := &rls.RateLimitRequest{
+		Domain:      f.config.domain,
+		Descriptors: descriptors,
+		HitsAddend:  1,
+	}
+	rlsCtx, cancel := context.WithTimeout(context.Background(), f.config.timeout)
+	defer cancel()
+	ch := make(chan rateLimitResult, 1)
+	utils.GoWithRecover(func() {
+		host, err := f.config.getHost(rlsCtx, f.config.cluster)
+		if err != nil {
+			ch <- rateLimitResult{err: err}
+			return
+		}
+		resp, err := f.config.client.ShouldRateLimit(rlsCtx, host, req)
+		ch <- rateLimitResult{resp: resp, err: err}
+	}, func(r interface{}) {
+		ch <- rateLimitResult{err: errPanic}
+	})
+	var result rateLimitResult
+	select {
+	case result = <-ch:
+	case <-f.stop:
+		if log.Proxy.GetLogLevel() >= log.DEBUG {
+			log.Proxy.Debugf(ctx, "[stream filter] [ratelimit] stream is destroyed before the rate limit is finished")
+		}
+		return api.StreamFilterStop
+	}
+	if result.err != nil {
+		f.config.stats.Error.Inc(1)
+		log.Proxy.Errorf(ctx, "[stream filter] [ratelimit] call rate limit service %s failed: %v", f.config.cluster, result.err)
+		if f.config.failureModeDeny {
+			return f.reject(headers, http.StatusInternalServerError)
+		}
+		f.config.stats.FailureModeAllowed.Inc(1)
+		return api.StreamFilterContinue
+	}
+	resp := result.resp
+	f.respHeaders = make(map[string]string, len(resp.GetHeaders())+3)
+	for _, h := range resp.GetHeaders() {
+		f.respHeaders[h.GetKey()] = h.GetValue()
+	}
+	if f.config.xRateLimitHeaders {
+		setXRateLimitHeaders(f.respHeaders, resp.GetStatuses(), time.Now())
+	}
+	if resp.GetOverallCode() == rls.RateLimitResponse_OVER_LIMIT {
+		f.config.stats.OverLimit.Inc(1)
+		if log.Proxy.GetLogLevel() >= log.DEBUG {
+			log.Proxy.Debugf(ctx, "[stream filter] [ratelimit] the request is over limit, descriptors: %v", descriptors)
+		}
+		return f.reject(headers, http.StatusTooManyRequests)
+	}
+	f.config.stats.OK.Inc(1)
+	return api.StreamFilterContinue
+}
+
+// reject replies with the request headers, so that the protocols such as xprotocol can build the response
+func (f *streamRateLimitFilter) reject(headers api.HeaderMap, code int) api.StreamFilterStatus {
+	if code == http.StatusTooManyRequests {
+		f.handler.RequestInfo().SetResponseFlag(api.RateLimited)
+	}
+	for k, v := range f.respHeaders {
+		headers.Set(k, v)
+	}
+	f.handler.SendHijackReply(code, headers)
+	return api.StreamFilterStop
+}
+
+func (f *streamRateLimitFilter) Append(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	for k, v := range f.respHeaders {
+		headers.Set(k, v)
+	}
+	return api.StreamFilterContinue
+}
+
+func (f *streamRateLimitFilter) OnDestroy() {
+	close(f.stop)
+}
+
+// setXRateLimitHeaders sets the headers by the descriptor status that has the least remaining
+func setXRateLimitHeaders(headers map[string]string, statuses []*rls.RateLimitResponse_DescriptorStatus, now time.Time) {
+	var status *rls.RateLimitResponse_DescriptorStatus
+	for _, s := range statuses {
+		if s.GetCurrentLimit() == nil || unitSeconds(s.GetCurrentLimit().GetUnit()) == 0 {
+			continue
+		}
+		if status == nil || s.GetLimitRemaining() < status.GetLimitRemaining() {
+			status = s
+		}
+	}
+	if status == nil {
+		return
+	}
+	limit := strconv.FormatUint(uint64(status.GetCurrentLimit().GetRequestsPerUnit()), 10)
+	window := unitSeconds(status.GetCurrentLimit().GetUnit())
+	headers[headerRateLimitLimit] = limit + ", " + limit + ";w=" + strconv.FormatInt(window, 10)
+	headers[headerRateLimitRemaining] = strconv.FormatUint(uint64(status.GetLimitRemaining()), 10)
+	// the rate limit service uses the fixed window
+	headers[headerRateLimitReset] = strconv.FormatInt(window-now.Unix()%window, 10)
+}
+
+func unitSeconds(unit rls.RateLimitResponse_RateLimit_Unit) int64 {
+	switch unit {
+	case rls.RateLimitResponse_RateLimit_SECOND:
+		return 1
+	case rls.RateLimitResponse_RateLimit_MINUTE:
+		return 60
+	case rls.RateLimitResponse_RateLimit_HOUR:
+		return 60 * 60
+	case rls.RateLimitResponse_RateLimit_DAY:
+		return 24 * 60 * 60
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,457 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	"github.com/envoyproxy/go-control-plane/envoy/api/v2/ratelimit"
+	rls "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v2"
+	"google.golang.org/grpc"
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/xprotocol"
+	"mosn.io/mosn/pkg/protocol/xprotocol/bolt"
+	"mosn.io/mosn/pkg/types"
+)
+
+// mocks the interface that used for test
+// only implement the function that used in test
+type mockStreamReceiverFilterCallbacks struct {
+	api.StreamReceiverFilterHandler
+	route         *mockRoute
+	conn          api.Connection
+	hijackCode    int
+	hijackHeaders api.HeaderMap
+	info          *mockRequestInfo
+}
+
+func (cb *mockStreamReceiverFilterCallbacks) Route() api.Route {
+	if cb.route == nil {
+		return nil
+	}
+	return cb.route
+}
+func (cb *mockStreamReceiverFilterCallbacks) Connection() api.Connection {
+	return cb.conn
+}
+func (cb *mockStreamReceiverFilterCallbacks) RequestInfo() api.RequestInfo {
+	return cb.info
+}
+func (cb *mockStreamReceiverFilterCallbacks) SendHijackReply(code int, headers api.HeaderMap) {
+	cb.hijackCode = code
+	cb.hijackHeaders = headers
+}
+
+type mockRoute struct {
+	api.Route
+	rule *mockRouteRule
+}
+
+func (r *mockRoute) RouteRule() api.RouteRule {
+	return r.rule
+}
+
+type mockRouteRule struct {
+	api.RouteRule
+	config map[string]interface{}
+}
+
+func (r *mockRouteRule) PerFilterConfig() map[string]interface{} {
+	return r.config
+}
+
+type mockRequestInfo struct {
+	api.RequestInfo
+	flag api.ResponseFlag
+}
+
+func (info *mockRequestInfo) SetResponseFlag(flag api.ResponseFlag) {
+	info.flag = flag
+}
+
+type mockConnection struct {
+	api.Connection
+	remote net.Addr
+}
+
+func (c *mockConnection) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// fakeRateLimitService allows 2 requests for each descriptor
+type fakeRateLimitService struct {
+	mutex    sync.Mutex
+	counters map[string]uint32
+	requests []*rls.RateLimitRequest
+}
+
+func descriptorKey(d *ratelimit.RateLimitDescriptor) string {
+	key := ""
+	for _, e := range d.Entries {
+		key += e.Key + "=" + e.Value + ";"
+	}
+	return key
+}
+
+func (s *fakeRateLimitService) ShouldRateLimit(ctx context.Context, req *rls.RateLimitRequest) (*rls.RateLimitResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = append(s.requests, req)
+	resp := &rls.RateLimitResponse{
+		OverallCode: rls.RateLimitResponse_OK,
+		Headers:     []*core.HeaderValue{{Key: "x-rls", Value: "fake"}},
+	}
+	for _, d := range req.Descriptors {
+		key := descriptorKey(d)
+		s.counters[key]++
+		status := &rls.RateLimitResponse_DescriptorStatus{
+			Code: rls.RateLimitResponse_OK,
+			CurrentLimit: &rls.RateLimitResponse_RateLimit{
+				RequestsPerUnit: 2,
+				Unit:            rls.RateLimitResponse_RateLimit_MINUTE,
+			},
+		}
+		if s.counters[key] > 2 {
+			status.Code = rls.RateLimitResponse_OVER_LIMIT
+			resp.OverallCode = rls.RateLimitResponse_OVER_LIMIT
+		} else {
+			status.LimitRemaining = 2 - s.counters[key]
+		}
+		resp.Statuses = append(resp.Statuses, status)
+	}
+	return resp, nil
+}
+
+func startFakeRateLimitService(t *testing.T) (*fakeRateLimitService, string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	service := &fakeRateLimitService{counters: map[string]uint32{}}
+	rls.RegisterRateLimitServiceServer(server, service)
+	go server.Serve(ln)
+	return service, ln.Addr().String(), server.Stop
+}
+
+func newFilterForTest(t *testing.T, conf map[string]interface{}, host string) (*streamRateLimitFilter, *mockStreamReceiverFilterCallbacks) {
+	factory, err := CreateRateLimitFilterFactory(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := factory.(*FilterConfigFactory).config
+	config.getHost = func(ctx context.Context, cluster string) (string, error) {
+		if host == "" {
+			return "", errNoRateLimitHost
+		}
+		return host, nil
+	}
+	f := newRateLimitFilter(context.Background(), config)
+	remote, _ := net.ResolveTCPAddr("tcp", "10.1.1.1:12345")
+	cb := &mockStreamReceiverFilterCallbacks{
+		conn: &mockConnection{remote: remote},
+		info: &mockRequestInfo{},
+	}
+	f.SetReceiveFilterHandler(cb)
+	return f, cb
+}
+
+func TestCreateRateLimitFilterFactory(t *testing.T) {
+	for idx, conf := range []map[string]interface{}{
+		{"cluster": "rls"},
+		{"domain": "mosn", "cluster": "rls", "rate_limits": []interface{}{map[string]interface{}{}}},
+		{"domain": "mosn", "cluster": "rls", "rate_limits": []interface{}{
+			map[string]interface{}{"actions": []interface{}{map[string]interface{}{}}},
+		}},
+		{"domain": "mosn", "cluster": "rls", "rate_limits": []interface{}{
+			map[string]interface{}{"actions": []interface{}{map[string]interface{}{
+				"remote_address": map[string]interface{}{},
+				"generic_key":    map[string]interface{}{"descriptor_value": "v"},
+			}}},
+		}},
+		{"domain": "mosn", "cluster": "rls", "local_token_bucket": map[string]interface{}{"max_tokens": 1}},
+	} {
+		if _, err := CreateRateLimitFilterFactory(conf); err == nil {
+			t.Errorf("case %d expected create factory failed", idx)
+		}
+	}
+}
+
+func TestMakeDescriptors(t *testing.T) {
+	policies, err := newRateLimitPolicies([]v2.RateLimitPolicy{
+		{Actions: []v2.RateLimitAction{
+			{GenericKey: &v2.RateLimitGenericKey{DescriptorValue: "api"}},
+			{RemoteAddress: &v2.RateLimitRemoteAddress{}},
+		}},
+		{Actions: []v2.RateLimitAction{
+			{RequestHeaders: &v2.RateLimitRequestHeaders{HeaderName: "user", DescriptorKey: "user_id"}},
+		}},
+		{Actions: []v2.RateLimitAction{
+			{ServiceName: &v2.RateLimitServiceName{}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, _ := net.ResolveTCPAddr("tcp", "10.1.1.1:12345")
+	conn := &mockConnection{remote: remote}
+	// the user header is absent, the policy is skipped
+	descriptors := makeDescriptors(policies, protocol.CommonHeader{types.SofaRouteMatchKey: "com.alipay.HelloService"}, conn)
+	if len(descriptors) != 2 ||
+		descriptorKey(descriptors[0]) != "generic_key=api;remote_address=10.1.1.1;" ||
+		descriptorKey(descriptors[1]) != "service_name=com.alipay.HelloService;" {
+		t.Fatalf("unexpected descriptors: %v", descriptors)
+	}
+	descriptors = makeDescriptors(policies, protocol.CommonHeader{"user": "alice", types.HeaderRPCService: "com.alipay.EchoService"}, nil)
+	if len(descriptors) != 2 ||
+		descriptorKey(descriptors[0]) != "user_id=alice;" ||
+		descriptorKey(descriptors[1]) != "service_name=com.alipay.EchoService;" {
+		t.Fatalf("unexpected descriptors: %v", descriptors)
+	}
+}
+
+func TestRateLimitFilter(t *testing.T) {
+	service, addr, stop := startFakeRateLimitService(t)
+	defer stop()
+	conf := map[string]interface{}{
+		"domain":                     "mosn",
+		"cluster":                    "rls",
+		"timeout":                    "1s",
+		"stat_prefix":                "test_ratelimit_filter",
+		"enable_x_ratelimit_headers": true,
+		"rate_limits": []interface{}{
+			map[string]interface{}{
+				"actions": []interface{}{
+					map[string]interface{}{"remote_address": map[string]interface{}{}},
+				},
+			},
+		},
+	}
+	var stats *Stats
+	for i := 0; i < 3; i++ {
+		f, cb := newFilterForTest(t, conf, addr)
+		stats = f.config.stats
+		status := f.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil)
+		if i < 2 {
+			if status != api.StreamFilterContinue {
+				t.Fatalf("request %d expected allowed", i)
+			}
+			respHeaders := protocol.CommonHeader{}
+			f.Append(context.Background(), respHeaders, nil, nil)
+			if v, _ := respHeaders.Get(headerRateLimitRemaining); v != []string{"1", "0"}[i] {
+				t.Fatalf("request %d unexpected remaining: %s", i, v)
+			}
+			if v, _ := respHeaders.Get(headerRateLimitLimit); v != "2, 2;w=60" {
+				t.Fatalf("request %d unexpected limit: %s", i, v)
+			}
+			if v, _ := respHeaders.Get("x-rls"); v != "fake" {
+				t.Fatalf("request %d expected the rls response headers", i)
+			}
+			continue
+		}
+		if status != api.StreamFilterStop || cb.hijackCode != http.StatusTooManyRequests || cb.info.flag != api.RateLimited {
+			t.Fatalf("request %d expected over limit, but got %d", i, cb.hijackCode)
+		}
+		if v, _ := cb.hijackHeaders.Get(headerRateLimitReset); v == "" {
+			t.Fatal("expected the reset header in the rejected response")
+		}
+	}
+	if len(service.requests) != 3 || service.requests[0].Domain != "mosn" {
+		t.Fatalf("unexpected rate limit requests: %v", service.requests)
+	}
+	if stats.OK.Count() != 2 || stats.OverLimit.Count() != 1 {
+		t.Fatalf("unexpected stats: %d %d", stats.OK.Count(), stats.OverLimit.Count())
+	}
+
+	// the route disables the rate limit
+	f, cb := newFilterForTest(t, conf, addr)
+	cb.route = &mockRoute{rule: &mockRouteRule{config: map[string]interface{}{
+		v2.RateLimit: map[string]interface{}{"disabled": true},
+	}}}
+	if status := f.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil); status != api.StreamFilterContinue {
+		t.Fatal("expected the rate limit is disabled by the route")
+	}
+	// the route replaces the rate limit policies
+	f, cb = newFilterForTest(t, conf, addr)
+	cb.route = &mockRoute{rule: &mockRouteRule{config: map[string]interface{}{
+		v2.RateLimit: map[string]interface{}{
+			"rate_limits": []interface{}{
+				map[string]interface{}{
+					"actions": []interface{}{
+						map[string]interface{}{"generic_key": map[string]interface{}{"descriptor_value": "route"}},
+					},
+				},
+			},
+		},
+	}}}
+	if status := f.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil); status != api.StreamFilterContinue {
+		t.Fatal("expected the request is allowed by the route policies")
+	}
+	if key := descriptorKey(service.requests[3].Descriptors[0]); key != "generic_key=route;" {
+		t.Fatalf("unexpected route descriptor: %s", key)
+	}
+}
+
+func TestRateLimitFailure(t *testing.T) {
+	conf := map[string]interface{}{
+		"domain":      "mosn",
+		"cluster":     "rls",
+		"stat_prefix": "test_ratelimit_failure",
+		"rate_limits": []interface{}{
+			map[string]interface{}{
+				"actions": []interface{}{
+					map[string]interface{}{"generic_key": map[string]interface{}{"descriptor_value": "all"}},
+				},
+			},
+		},
+	}
+	// fail open
+	f, cb := newFilterForTest(t, conf, "")
+	if status := f.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil); status != api.StreamFilterContinue || cb.hijackCode != 0 {
+		t.Fatal("expected the request is allowed")
+	}
+	if f.config.stats.Error.Count() != 1 || f.config.stats.FailureModeAllowed.Count() != 1 {
+		t.Fatal("unexpected stats")
+	}
+	// fail closed
+	conf["failure_mode_deny"] = true
+	f, cb = newFilterForTest(t, conf, "")
+	if status := f.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil); status != api.StreamFilterStop || cb.hijackCode != http.StatusInternalServerError {
+		t.Fatal("expected the request is denied")
+	}
+}
+
+func TestRateLimitLocalTokenBucket(t *testing.T) {
+	service, addr, stop := startFakeRateLimitService(t)
+	defer stop()
+	f, _ := newFilterForTest(t, map[string]interface{}{
+		"domain":      "mosn",
+		"cluster":     "rls",
+		"timeout":     "1s",
+		"stat_prefix": "test_ratelimit_local",
+		"rate_limits": []interface{}{
+			map[string]interface{}{
+				"actions": []interface{}{
+					map[string]interface{}{"generic_key": map[string]interface{}{"descriptor_value": "all"}},
+				},
+			},
+		},
+		"local_token_bucket": map[string]interface{}{
+			"max_tokens":    1,
+			"fill_interval": "1h",
+		},
+	}, addr)
+	config := f.config
+	if status := f.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil); status != api.StreamFilterContinue {
+		t.Fatal("expected the first request is allowed")
+	}
+	f = newRateLimitFilter(context.Background(), config)
+	cb := &mockStreamReceiverFilterCallbacks{info: &mockRequestInfo{}}
+	f.SetReceiveFilterHandler(cb)
+	if status := f.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil); status != api.StreamFilterStop || cb.hijackCode != http.StatusTooManyRequests {
+		t.Fatal("expected the request is limited by the local token bucket")
+	}
+	if len(service.requests) != 1 || config.stats.LocalOverLimit.Count() != 1 {
+		t.Fatal("the limited request should not query the rate limit service")
+	}
+}
+
+func TestRateLimitDestroy(t *testing.T) {
+	// the listener never accepts the grpc handshake, so the request blocks until the timeout
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, cb := newFilterForTest(t, map[string]interface{}{
+		"domain":      "mosn",
+		"cluster":     "rls",
+		"timeout":     "10s",
+		"stat_prefix": "test_ratelimit_destroy",
+		"rate_limits": []interface{}{
+			map[string]interface{}{
+				"actions": []interface{}{
+					map[string]interface{}{"generic_key": map[string]interface{}{"descriptor_value": "all"}},
+				},
+			},
+		},
+	}, ln.Addr().String())
+	ch := make(chan api.StreamFilterStatus, 1)
+	go func() {
+		ch <- f.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	f.OnDestroy()
+	select {
+	case status := <-ch:
+		if status != api.StreamFilterStop || cb.hijackCode != 0 {
+			t.Fatal("expected the stream stopped without reply")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the filter is not stopped after destroyed")
+	}
+}
+
+func TestRateLimitFilterBolt(t *testing.T) {
+	service, addr, stop := startFakeRateLimitService(t)
+	defer stop()
+	conf := map[string]interface{}{
+		"domain":      "mosn",
+		"cluster":     "rls",
+		"timeout":     "1s",
+		"stat_prefix": "test_ratelimit_bolt",
+		"rate_limits": []interface{}{
+			map[string]interface{}{
+				"actions": []interface{}{
+					map[string]interface{}{"service_name": map[string]interface{}{}},
+				},
+			},
+		},
+	}
+	var cb *mockStreamReceiverFilterCallbacks
+	var status api.StreamFilterStatus
+	for i := 0; i < 3; i++ {
+		var f *streamRateLimitFilter
+		f, cb = newFilterForTest(t, conf, addr)
+		req := bolt.NewRpcRequest(uint32(i), protocol.CommonHeader{types.SofaRouteMatchKey: "com.alipay.HelloService"}, nil)
+		status = f.OnReceive(context.Background(), req, nil, nil)
+	}
+	if len(service.requests) != 3 || service.requests[2].Descriptors[0].Entries[0].Value != "com.alipay.HelloService" {
+		t.Fatalf("unexpected rate limit requests: %v", service.requests)
+	}
+	if status != api.StreamFilterStop || cb.hijackCode != http.StatusTooManyRequests {
+		t.Fatalf("expected over limit, but got %d", cb.hijackCode)
+	}
+	// the xprotocol stream builds the hijack response from the request frame
+	if frame, ok := cb.hijackHeaders.(xprotocol.XFrame); !ok || frame.GetStreamType() != xprotocol.Request {
+		t.Fatalf("the hijack headers is not a request frame: %T", cb.hijackHeaders)
+	}
+	proto := xprotocol.GetProtocol(bolt.ProtocolName)
+	resp := proto.Hijack(proto.Mapping(http.StatusTooManyRequests))
+	if resp.GetStatusCode() != uint32(bolt.ResponseStatusServerThreadpoolBusy) {
+		t.Fatalf("unexpected bolt response status %d", resp.GetStatusCode())
+	}
+}
@@ -0,0 +1,69 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rbac
+
+import (
+	"context"
+	"net/http"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/rbac"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/buffer"
+)
+
+// streamRBACFilter is an implement of api.StreamReceiverFilter
+type streamRBACFilter struct {
+	ctx        context.Context
+	handler    api.StreamReceiverFilterHandler
+	authorizer *rbac.Authorizer
+}
+
+func NewFilter(ctx context.Context, authorizer *rbac.Authorizer) api.StreamReceiverFilter {
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [rbac] create a new rbac filter")
+	}
+	return &streamRBACFilter{
+		ctx:        ctx,
+		authorizer: authorizer,
+	}
+}
+
+func (f *streamRBACFilter) SetReceiveFilterHandler(handler api.StreamReceiverFilterHandler) {
+	f.handler = handler
+}
+
+func (f *streamRBACFilter) OnReceive(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	req := &rbac.Request{
+		Context: ctx,
+		Headers: headers,
+		Conn:    f.handler.Connection(),
+	}
+	if f.authorizer.Authorize(req) {
+		return api.StreamFilterContinue
+	}
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [rbac] the request is denied")
+	}
+	f.handler.RequestInfo().SetResponseFlag(types.Unauthorized)
+	f.handler.SendHijackReply(http.StatusForbidden, headers)
+	return api.StreamFilterStop
+}
+
+func (f *streamRBACFilter) OnDestroy() {}
@@ -0,0 +1,114 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rbac
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+// mocks the interface that used for test
+// only implement the function that used in test
+type mockStreamReceiverFilterCallbacks struct {
+	api.StreamReceiverFilterHandler
+	hijackCode int
+	info       *mockRequestInfo
+}
+
+func (cb *mockStreamReceiverFilterCallbacks) Connection() api.Connection {
+	return nil
+}
+func (cb *mockStreamReceiverFilterCallbacks) RequestInfo() api.RequestInfo {
+	return cb.info
+}
+func (cb *mockStreamReceiverFilterCallbacks) SendHijackReply(code int, headers api.HeaderMap) {
+	cb.hijackCode = code
+}
+
+type mockRequestInfo struct {
+	api.RequestInfo
+	flag api.ResponseFlag
+}
+
+func (info *mockRequestInfo) SetResponseFlag(flag api.ResponseFlag) {
+	info.flag = flag
+}
+
+func TestRBACFilter(t *testing.T) {
+	conf := map[string]interface{}{
+		"stat_prefix": "test_stream_rbac",
+		"rules": map[string]interface{}{
+			"action": "ALLOW",
+			"policies": map[string]interface{}{
+				"read": map[string]interface{}{
+					"permissions": []interface{}{
+						map[string]interface{}{
+							"methods": []string{"GET"},
+						},
+					},
+					"principals": []interface{}{
+						map[string]interface{}{
+							"headers": []interface{}{
+								map[string]interface{}{"name": "user", "present_match": true},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	factory, err := CreateRBACFilterFactory(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorizer := factory.(*FilterConfigFactory).authorizer
+	for idx, tc := range []struct {
+		headers api.HeaderMap
+		status  api.StreamFilterStatus
+	}{
+		{protocol.CommonHeader{types.HeaderMethod: "GET", "user": "a"}, api.StreamFilterContinue},
+		{protocol.CommonHeader{types.HeaderMethod: "POST", "user": "a"}, api.StreamFilterStop},
+		{protocol.CommonHeader{types.HeaderMethod: "GET"}, api.StreamFilterStop},
+	} {
+		cb := &mockStreamReceiverFilterCallbacks{
+			info: &mockRequestInfo{},
+		}
+		f := NewFilter(context.Background(), authorizer)
+		f.SetReceiveFilterHandler(cb)
+		if status := f.OnReceive(context.Background(), tc.headers, nil, nil); status != tc.status {
+			t.Fatalf("case %d expected status %v, but got %v", idx, tc.status, status)
+		}
+		if tc.status == api.StreamFilterStop && (cb.hijackCode != http.StatusForbidden || cb.info.flag != types.Unauthorized) {
+			t.Fatalf("case %d unexpected hijack code %d, flag %v", idx, cb.hijackCode, cb.info.flag)
+		}
+	}
+	if authorizer.Stats().Allowed.Count() != 1 || authorizer.Stats().Denied.Count() != 2 {
+		t.Fatal("unexpected stats")
+	}
+	// invalid config
+	if _, err := CreateRBACFilterFactory(map[string]interface{}{
+		"rules": map[string]interface{}{"action": "UNKNOWN"},
+	}); err == nil {
+		t.Fatal("expected create factory failed")
+	}
+}
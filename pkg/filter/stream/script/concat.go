@@ -0,0 +1,143 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package script
+
+import (
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/ast"
+)
+
+// concatFunction is the key of the function in the string library that the '..' operators are compiled to,
+// the key can not be written as a lua name, so the scripts can not call it by mistake
+const concatFunction = "\x00concat"
+
+// concatValues does the '..' operator with a length check, as the operator may make
+// a huge string in a single instruction
+func (s *state) concatValues(L *lua.LState) int {
+	lhs, rhs := L.Get(1), L.Get(2)
+	if lua.LVCanConvToString(lhs) && lua.LVCanConvToString(rhs) {
+		l, r := lua.LVAsString(lhs), lua.LVAsString(rhs)
+		if len(l)+len(r) > s.budget.opts.maxStringLength {
+			L.RaiseError(errStringLength.Error())
+		}
+		L.Push(lua.LString(l + r))
+		return 1
+	}
+	op := L.GetMetaField(lhs, "__concat")
+	if op.Type() != lua.LTFunction {
+		op = L.GetMetaField(rhs, "__concat")
+	}
+	if op.Type() != lua.LTFunction {
+		L.RaiseError("cannot perform concat operation between %v and %v", lhs.Type().String(), rhs.Type().String())
+	}
+	L.Push(op)
+	L.Push(lhs)
+	L.Push(rhs)
+	L.Call(2, 1)
+	return 1
+}
+
+// guardConcat rewrites the '..' operators in the chunk to the calls of ("")[concatFunction]
+func guardConcat(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		switch st := stmt.(type) {
+		case *ast.AssignStmt:
+			guardExprs(st.Lhs)
+			guardExprs(st.Rhs)
+		case *ast.LocalAssignStmt:
+			guardExprs(st.Exprs)
+		case *ast.FuncCallStmt:
+			st.Expr = guardExpr(st.Expr)
+		case *ast.DoBlockStmt:
+			guardConcat(st.Stmts)
+		case *ast.WhileStmt:
+			st.Condition = guardExpr(st.Condition)
+			guardConcat(st.Stmts)
+		case *ast.RepeatStmt:
+			st.Condition = guardExpr(st.Condition)
+			guardConcat(st.Stmts)
+		case *ast.IfStmt:
+			st.Condition = guardExpr(st.Condition)
+			guardConcat(st.Then)
+			guardConcat(st.Else)
+		case *ast.NumberForStmt:
+			st.Init = guardExpr(st.Init)
+			st.Limit = guardExpr(st.Limit)
+			st.Step = guardExpr(st.Step)
+			guardConcat(st.Stmts)
+		case *ast.GenericForStmt:
+			guardExprs(st.Exprs)
+			guardConcat(st.Stmts)
+		case *ast.FuncDefStmt:
+			st.Name.Func = guardExpr(st.Name.Func)
+			st.Name.Receiver = guardExpr(st.Name.Receiver)
+			guardConcat(st.Func.Stmts)
+		case *ast.ReturnStmt:
+			guardExprs(st.Exprs)
+		}
+	}
+}
+
+func guardExprs(exprs []ast.Expr) {
+	for i := range exprs {
+		exprs[i] = guardExpr(exprs[i])
+	}
+}
+
+func guardExpr(expr ast.Expr) ast.Expr {
+	switch ex := expr.(type) {
+	case *ast.StringConcatOpExpr:
+		fn := &ast.AttrGetExpr{Object: &ast.StringExpr{}, Key: &ast.StringExpr{Value: concatFunction}}
+		call := &ast.FuncCallExpr{Func: fn, Args: []ast.Expr{guardExpr(ex.Lhs), guardExpr(ex.Rhs)}, AdjustRet: true}
+		for _, node := range []ast.Expr{fn.Object, fn.Key, fn, call} {
+			node.SetLine(ex.Line())
+			node.SetLastLine(ex.LastLine())
+		}
+		return call
+	case *ast.AttrGetExpr:
+		ex.Object = guardExpr(ex.Object)
+		ex.Key = guardExpr(ex.Key)
+	case *ast.TableExpr:
+		for _, field := range ex.Fields {
+			field.Key = guardExpr(field.Key)
+			field.Value = guardExpr(field.Value)
+		}
+	case *ast.FuncCallExpr:
+		ex.Func = guardExpr(ex.Func)
+		ex.Receiver = guardExpr(ex.Receiver)
+		guardExprs(ex.Args)
+	case *ast.LogicalOpExpr:
+		ex.Lhs = guardExpr(ex.Lhs)
+		ex.Rhs = guardExpr(ex.Rhs)
+	case *ast.RelationalOpExpr:
+		ex.Lhs = guardExpr(ex.Lhs)
+		ex.Rhs = guardExpr(ex.Rhs)
+	case *ast.ArithmeticOpExpr:
+		ex.Lhs = guardExpr(ex.Lhs)
+		ex.Rhs = guardExpr(ex.Rhs)
+	case *ast.UnaryMinusOpExpr:
+		ex.Expr = guardExpr(ex.Expr)
+	case *ast.UnaryNotOpExpr:
+		ex.Expr = guardExpr(ex.Expr)
+	case *ast.UnaryLenOpExpr:
+		ex.Expr = guardExpr(ex.Expr)
+	case *ast.FunctionExpr:
+		guardConcat(ex.Stmts)
+	}
+	return expr
+}
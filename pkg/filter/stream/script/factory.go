@@ -0,0 +1,91 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package script
+
+import (
+	"context"
+	"encoding/json"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/router"
+)
+
+func init() {
+	api.RegisterStream(v2.Script, CreateScriptFilterFactory)
+	router.RegisterPerFilterConfigParser(v2.Script, parsePerRouteConfig)
+}
+
+// FilterConfigFactory creates the script filters, the compiled scripts are shared by the filters
+type FilterConfigFactory struct {
+	Config *v2.StreamScript
+	script *script
+}
+
+func (f *FilterConfigFactory) CreateFilterChain(context context.Context, callbacks api.StreamFilterChainFactoryCallbacks) {
+	filter := newScriptFilter(context, f.script)
+	callbacks.AddStreamReceiverFilter(filter, api.AfterRoute)
+	callbacks.AddStreamSenderFilter(filter)
+}
+
+func CreateScriptFilterFactory(conf map[string]interface{}) (api.StreamFilterChainFactory, error) {
+	log.DefaultLogger.Debugf("create script stream filter factory")
+	cfg, err := ParseStreamScriptFilter(conf)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newScript(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &FilterConfigFactory{
+		Config: cfg,
+		script: s,
+	}, nil
+}
+
+// ParseStreamScriptFilter
+func ParseStreamScriptFilter(cfg map[string]interface{}) (*v2.StreamScript, error) {
+	filterConfig := &v2.StreamScript{}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, filterConfig); err != nil {
+		return nil, err
+	}
+	return filterConfig, nil
+}
+
+// parsePerRouteConfig compiles the script of the per filter config when the route is created
+func parsePerRouteConfig(cfg interface{}) (interface{}, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	conf := &v2.StreamScript{}
+	if err := json.Unmarshal(data, conf); err != nil {
+		return nil, err
+	}
+	s, err := newScript(conf)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
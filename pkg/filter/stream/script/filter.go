@@ -0,0 +1,348 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package script
+
+import (
+	"context"
+	"net/http"
+
+	lua "github.com/yuin/gopher-lua"
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/variable"
+	"mosn.io/pkg/buffer"
+)
+
+const (
+	handleTypeName  = "mosn.handle"
+	headersTypeName = "mosn.headers"
+)
+
+// hijackReply is sent by handle:send_hijack_reply
+type hijackReply struct {
+	code    int
+	headers api.HeaderMap
+	body    string
+}
+
+// streamScriptFilter calls the lua hooks. A state is taken from the pool of the script for the stream,
+// so on_append can read the globals set by on_receive. The state is reused by the other streams
+// after the stream is destroyed, so the scripts should not depend on the globals left by the other streams.
+type streamScriptFilter struct {
+	ctx            context.Context
+	script         *script
+	state          *state
+	receiveHandler api.StreamReceiverFilterHandler
+	sendHandler    api.StreamSenderFilterHandler
+}
+
+func newScriptFilter(ctx context.Context, s *script) *streamScriptFilter {
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [script] create a new script filter")
+	}
+	return &streamScriptFilter{
+		ctx:    ctx,
+		script: s,
+	}
+}
+
+// ReadPerRouteConfig makes route-level configuration override filter-level configuration
+func (f *streamScriptFilter) ReadPerRouteConfig(cfg map[string]interface{}) {
+	if cfg == nil {
+		return
+	}
+	if c, ok := cfg[v2.Script]; ok {
+		s, ok := c.(*script)
+		if !ok {
+			log.Proxy.Errorf(f.ctx, "[stream filter] [script] invalid router config: %v", c)
+			return
+		}
+		if log.Proxy.GetLogLevel() >= log.DEBUG {
+			log.Proxy.Debugf(f.ctx, "[stream filter] [script] use router config to replace stream filter config")
+		}
+		f.script = s
+	}
+}
+
+func (f *streamScriptFilter) SetReceiveFilterHandler(handler api.StreamReceiverFilterHandler) {
+	f.receiveHandler = handler
+}
+
+func (f *streamScriptFilter) SetSenderFilterHandler(handler api.StreamSenderFilterHandler) {
+	f.sendHandler = handler
+}
+
+func (f *streamScriptFilter) OnReceive(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	route := f.receiveHandler.Route()
+	if route != nil && route.RouteRule() != nil {
+		f.ReadPerRouteConfig(route.RouteRule().PerFilterConfig())
+	}
+	if f.script.disabled {
+		return api.StreamFilterContinue
+	}
+	h := &handle{ctx: ctx, headers: headers, body: buf, trailers: trailers, receive: true}
+	if err := f.invoke(onReceiveFunction, h); err != nil {
+		log.Proxy.Errorf(ctx, "[stream filter] [script] call %s failed: %v", onReceiveFunction, err)
+		f.receiveHandler.SendHijackReply(http.StatusInternalServerError, headers)
+		return api.StreamFilterStop
+	}
+	if h.reply == nil {
+		return api.StreamFilterContinue
+	}
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [script] the request is hijacked by the script, code: %d", h.reply.code)
+	}
+	if handler, ok := f.receiveHandler.(types.HijackReplyWithBodyHandler); ok && h.reply.body != "" {
+		handler.SendHijackReplyWithBody(h.reply.code, h.reply.headers, h.reply.body)
+	} else {
+		f.receiveHandler.SendHijackReply(h.reply.code, h.reply.headers)
+	}
+	return api.StreamFilterStop
+}
+
+func (f *streamScriptFilter) Append(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	if f.script.disabled {
+		return api.StreamFilterContinue
+	}
+	h := &handle{ctx: ctx, headers: headers, body: buf, trailers: trailers}
+	if err := f.invoke(onAppendFunction, h); err != nil {
+		log.Proxy.Errorf(ctx, "[stream filter] [script] call %s failed: %v", onAppendFunction, err)
+	}
+	return api.StreamFilterContinue
+}
+
+// invoke calls the hook if the script defines it. The state is dropped if the hook fails,
+// the globals may be left half updated
+func (f *streamScriptFilter) invoke(name string, h *handle) error {
+	if f.state == nil {
+		st, err := f.script.getState()
+		if err != nil {
+			return err
+		}
+		f.state = st
+	}
+	ud := f.state.L.NewUserData()
+	ud.Value = h
+	f.state.L.SetMetatable(ud, f.state.L.GetTypeMetatable(handleTypeName))
+	if err := f.state.call(name, ud); err != nil {
+		f.state.close()
+		f.state = nil
+		return err
+	}
+	return nil
+}
+
+// OnDestroy returns the state to the pool, it is called by both the receiver and the sender filter chain
+func (f *streamScriptFilter) OnDestroy() {
+	if f.state != nil {
+		f.script.putState(f.state)
+		f.state = nil
+	}
+}
+
+// handle is the argument of the hooks, it exposes the stream to the script
+type handle struct {
+	ctx      context.Context
+	headers  api.HeaderMap
+	body     buffer.IoBuffer
+	trailers api.HeaderMap
+	receive  bool
+	reply    *hijackReply
+}
+
+var handleMethods = map[string]lua.LGFunction{
+	"headers": func(L *lua.LState) int {
+		L.Push(newHeaders(L, checkHandle(L).headers))
+		return 1
+	},
+	"trailers": func(L *lua.LState) int {
+		L.Push(newHeaders(L, checkHandle(L).trailers))
+		return 1
+	},
+	"body": func(L *lua.LState) int {
+		if h := checkHandle(L); h.body != nil {
+			L.Push(lua.LString(h.body.String()))
+		} else {
+			L.Push(lua.LNil)
+		}
+		return 1
+	},
+	"get_variable": func(L *lua.LState) int {
+		h := checkHandle(L)
+		value, err := variable.GetVariableValue(h.ctx, checkString(L, 2))
+		if err != nil {
+			L.Push(lua.LNil)
+		} else {
+			L.Push(lua.LString(value))
+		}
+		return 1
+	},
+	"set_variable": func(L *lua.LState) int {
+		h := checkHandle(L)
+		if err := variable.SetVariableValue(h.ctx, checkString(L, 2), checkString(L, 3)); err != nil {
+			L.Push(lua.LFalse)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		L.Push(lua.LTrue)
+		return 1
+	},
+	"log_debug":         logFunction(log.DEBUG),
+	"log_info":          logFunction(log.INFO),
+	"log_warn":          logFunction(log.WARN),
+	"log_error":         logFunction(log.ERROR),
+	"send_hijack_reply": sendHijackReply,
+}
+
+var headersMethods = map[string]lua.LGFunction{
+	"get": func(L *lua.LState) int {
+		if value, ok := checkHeaders(L).Get(checkString(L, 2)); ok {
+			L.Push(lua.LString(value))
+		} else {
+			L.Push(lua.LNil)
+		}
+		return 1
+	},
+	"set": func(L *lua.LState) int {
+		checkHeaders(L).Set(checkString(L, 2), checkString(L, 3))
+		return 0
+	},
+	// add appends the value to the existing values, the header maps of some protocols do not support Add
+	"add": func(L *lua.LState) int {
+		headers, key, value := checkHeaders(L), checkString(L, 2), checkString(L, 3)
+		if old, ok := headers.Get(key); ok && old != "" {
+			value = old + "," + value
+		}
+		headers.Set(key, value)
+		return 0
+	},
+	"remove": func(L *lua.LState) int {
+		checkHeaders(L).Del(checkString(L, 2))
+		return 0
+	},
+	// each calls the function with the key and the value, the iteration stops if the function returns false
+	"each": func(L *lua.LState) int {
+		headers, fn := checkHeaders(L), L.CheckFunction(2)
+		headers.Range(func(key, value string) bool {
+			L.Push(fn)
+			L.Push(lua.LString(key))
+			L.Push(lua.LString(value))
+			L.Call(2, 1)
+			ret := L.Get(-1)
+			L.Pop(1)
+			return ret != lua.LFalse
+		})
+		return 0
+	},
+}
+
+// registerTypes registers the metatables of the objects passed to the hooks
+func registerTypes(L *lua.LState) {
+	for name, methods := range map[string]map[string]lua.LGFunction{
+		handleTypeName:  handleMethods,
+		headersTypeName: headersMethods,
+	} {
+		mt := L.NewTypeMetatable(name)
+		L.SetField(mt, "__index", L.SetFuncs(L.NewTable(), methods))
+	}
+}
+
+func checkHandle(L *lua.LState) *handle {
+	if h, ok := L.CheckUserData(1).Value.(*handle); ok {
+		return h
+	}
+	L.ArgError(1, "handle expected")
+	return nil
+}
+
+// newHeaders wraps the header map as a lua object, nil if there is no header map
+func newHeaders(L *lua.LState, headers api.HeaderMap) lua.LValue {
+	if headers == nil {
+		return lua.LNil
+	}
+	ud := L.NewUserData()
+	ud.Value = headers
+	L.SetMetatable(ud, L.GetTypeMetatable(headersTypeName))
+	return ud
+}
+
+func checkHeaders(L *lua.LState) api.HeaderMap {
+	if headers, ok := L.CheckUserData(1).Value.(api.HeaderMap); ok {
+		return headers
+	}
+	L.ArgError(1, "headers expected")
+	return nil
+}
+
+// checkString checks the argument is a string or a number
+func checkString(L *lua.LState, n int) string {
+	if v := L.Get(n); lua.LVCanConvToString(v) {
+		return v.String()
+	}
+	L.TypeError(n, lua.LTString)
+	return ""
+}
+
+func logFunction(level log.Level) lua.LGFunction {
+	return func(L *lua.LState) int {
+		checkHandle(L).log(level, L.Get(2).String())
+		return 0
+	}
+}
+
+func (h *handle) log(level log.Level, msg string) {
+	if log.Proxy.GetLogLevel() < level {
+		return
+	}
+	switch level {
+	case log.DEBUG:
+		log.Proxy.Debugf(h.ctx, "[stream filter] [script] %s", msg)
+	case log.INFO:
+		log.Proxy.Infof(h.ctx, "[stream filter] [script] %s", msg)
+	case log.WARN:
+		log.Proxy.Warnf(h.ctx, "[stream filter] [script] %s", msg)
+	default:
+		log.Proxy.Errorf(h.ctx, "[stream filter] [script] %s", msg)
+	}
+}
+
+// sendHijackReply is handle:send_hijack_reply(code, headers, body), the headers and the body are optional.
+// The reply is sent after on_receive returns
+func sendHijackReply(L *lua.LState) int {
+	h := checkHandle(L)
+	if !h.receive {
+		L.RaiseError("send_hijack_reply can only be called in on_receive")
+	}
+	reply := &hijackReply{
+		code:    L.CheckInt(2),
+		headers: protocol.CommonHeader{},
+	}
+	if headers, ok := L.Get(3).(*lua.LTable); ok {
+		headers.ForEach(func(key, value lua.LValue) {
+			reply.headers.Set(key.String(), value.String())
+		})
+	}
+	if body := L.Get(4); body != lua.LNil {
+		reply.body = body.String()
+	}
+	h.reply = reply
+	return 0
+}
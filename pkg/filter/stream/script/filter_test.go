@@ -0,0 +1,247 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package script
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/variable"
+	"mosn.io/pkg/buffer"
+)
+
+// mocks the interface that used for test
+// only implement the function that used in test
+type mockStreamReceiverFilterCallbacks struct {
+	api.StreamReceiverFilterHandler
+	route         *mockRoute
+	hijackCode    int
+	hijackHeaders api.HeaderMap
+	hijackBody    string
+}
+
+func (cb *mockStreamReceiverFilterCallbacks) Route() api.Route {
+	if cb.route == nil {
+		return nil
+	}
+	return cb.route
+}
+func (cb *mockStreamReceiverFilterCallbacks) SendHijackReply(code int, headers api.HeaderMap) {
+	cb.hijackCode = code
+	cb.hijackHeaders = headers
+}
+func (cb *mockStreamReceiverFilterCallbacks) SendHijackReplyWithBody(code int, headers api.HeaderMap, body string) {
+	cb.hijackCode = code
+	cb.hijackHeaders = headers
+	cb.hijackBody = body
+}
+
+type mockRoute struct {
+	api.Route
+	rule *mockRouteRule
+}
+
+func (r *mockRoute) RouteRule() api.RouteRule {
+	return r.rule
+}
+
+type mockRouteRule struct {
+	api.RouteRule
+	config map[string]interface{}
+}
+
+func (r *mockRouteRule) PerFilterConfig() map[string]interface{} {
+	return r.config
+}
+
+const testScript = `
+local count = 0
+
+function on_receive(handle)
+	local headers = handle:headers()
+	if headers:get("x-token") ~= "secret" then
+		handle:log_info("the request is denied")
+		handle:send_hijack_reply(401, {["x-reason"] = "no token"}, "unauthorized")
+		return
+	end
+	headers:set("x-checked", "true")
+	headers:add("x-tag", "script")
+	headers:remove("x-token")
+	local keys = {}
+	headers:each(function(key, value)
+		keys[#keys + 1] = key
+	end)
+	headers:set("x-count", tostring(#keys))
+	local body = handle:body()
+	if body then
+		headers:set("x-body-length", tostring(#body))
+	end
+	handle:set_variable("test_script_var", "from_script")
+	-- the globals are kept between the hooks of the stream
+	count = count + 1
+	user = headers:get("x-user")
+end
+
+function on_append(handle)
+	local headers = handle:headers()
+	headers:set("x-user", user or "none")
+	headers:set("x-count", tostring(count))
+	headers:set("x-var", handle:get_variable("test_script_var") or "")
+end
+`
+
+func init() {
+	variable.RegisterVariable(variable.NewIndexedVariable("test_script_var", nil, nil, variable.BasicSetter, 0))
+}
+
+func newFilterForTest(t *testing.T, conf map[string]interface{}) (*streamScriptFilter, *mockStreamReceiverFilterCallbacks) {
+	f, err := CreateScriptFilterFactory(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	factory := f.(*FilterConfigFactory)
+	filter := newScriptFilter(context.Background(), factory.script)
+	cb := &mockStreamReceiverFilterCallbacks{}
+	filter.SetReceiveFilterHandler(cb)
+	return filter, cb
+}
+
+func TestScriptFilter(t *testing.T) {
+	filter, cb := newFilterForTest(t, map[string]interface{}{"source": testScript})
+	ctx := variable.NewVariableContext(context.Background())
+	headers := protocol.CommonHeader{"x-token": "secret", "x-tag": "mosn", "x-user": "alice"}
+	if status := filter.OnReceive(ctx, headers, buffer.NewIoBufferString("hello"), nil); status != api.StreamFilterContinue {
+		t.Fatalf("unexpected status %v, hijack %d", status, cb.hijackCode)
+	}
+	for key, expected := range map[string]string{
+		"x-checked":     "true",
+		"x-tag":         "mosn,script",
+		"x-count":       "3",
+		"x-body-length": "5",
+	} {
+		if value, _ := headers.Get(key); value != expected {
+			t.Errorf("header %s expected %s, but got %s", key, expected, value)
+		}
+	}
+	if _, ok := headers.Get("x-token"); ok {
+		t.Error("x-token is not removed")
+	}
+	respHeaders := protocol.CommonHeader{}
+	filter.Append(ctx, respHeaders, nil, nil)
+	for key, expected := range map[string]string{
+		"x-user":  "alice",
+		"x-count": "1",
+		"x-var":   "from_script",
+	} {
+		if value, _ := respHeaders.Get(key); value != expected {
+			t.Errorf("response header %s expected %s, but got %s", key, expected, value)
+		}
+	}
+}
+
+func TestScriptFilterHijack(t *testing.T) {
+	filter, cb := newFilterForTest(t, map[string]interface{}{"source": testScript})
+	if status := filter.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil); status != api.StreamFilterStop {
+		t.Fatal("expected the request is hijacked")
+	}
+	if cb.hijackCode != 401 || cb.hijackBody != "unauthorized" {
+		t.Fatalf("unexpected hijack %d %s", cb.hijackCode, cb.hijackBody)
+	}
+	if v, _ := cb.hijackHeaders.Get("x-reason"); v != "no token" {
+		t.Fatalf("unexpected hijack header: %s", v)
+	}
+}
+
+func TestScriptFilterErrors(t *testing.T) {
+	for _, source := range []string{
+		"function on_receive(handle) error('boom') end",
+		"function on_receive(handle) while true do end end",
+		"function on_receive(handle) handle:headers():set(nil, 'x') end",
+		"error('top level')",
+	} {
+		filter, cb := newFilterForTest(t, map[string]interface{}{"source": source, "max_instructions": 1000})
+		if status := filter.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil); status != api.StreamFilterStop || cb.hijackCode != 500 {
+			t.Errorf("%s: unexpected status %v, hijack %d", source, status, cb.hijackCode)
+		}
+	}
+	// hijack is not allowed in on_append, the response continues
+	filter, _ := newFilterForTest(t, map[string]interface{}{"source": "function on_append(handle) handle:send_hijack_reply(500) end"})
+	if status := filter.Append(context.Background(), protocol.CommonHeader{}, nil, nil); status != api.StreamFilterContinue {
+		t.Fatal("unexpected append status")
+	}
+	// invalid configs
+	for _, conf := range []map[string]interface{}{
+		{},
+		{"source": "function on_receive("},
+		{"path": "not_exists.lua"},
+	} {
+		if _, err := CreateScriptFilterFactory(conf); err == nil {
+			t.Errorf("expected create factory failed: %v", conf)
+		}
+	}
+}
+
+func TestScriptFilterPerRoute(t *testing.T) {
+	file, err := ioutil.TempFile("", "script*.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("function on_receive(handle) handle:headers():set('x-script', 'file') end")
+	file.Close()
+	filter, cb := newFilterForTest(t, map[string]interface{}{"path": file.Name()})
+	// the per filter configs are compiled when the routes are created
+	parse := func(cfg map[string]interface{}) map[string]interface{} {
+		s, err := parsePerRouteConfig(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]interface{}{v2.Script: s}
+	}
+	cb.route = &mockRoute{rule: &mockRouteRule{config: parse(map[string]interface{}{
+		"source": "function on_receive(handle) handle:headers():set('x-script', 'route') end",
+	})}}
+	headers := protocol.CommonHeader{}
+	filter.OnReceive(context.Background(), headers, nil, nil)
+	if v, _ := headers.Get("x-script"); v != "route" {
+		t.Fatalf("expected the route script is used, but got %s", v)
+	}
+	// the script is disabled in the route
+	filter, cb = newFilterForTest(t, map[string]interface{}{"path": file.Name()})
+	cb.route = &mockRoute{rule: &mockRouteRule{config: parse(map[string]interface{}{"disabled": true})}}
+	headers = protocol.CommonHeader{}
+	filter.OnReceive(context.Background(), headers, nil, nil)
+	if _, ok := headers.Get("x-script"); ok {
+		t.Fatal("expected the script is disabled")
+	}
+	if _, err := parsePerRouteConfig(map[string]interface{}{"source": "function on_receive("}); err == nil {
+		t.Fatal("expected the invalid per filter config is rejected")
+	}
+	// the file script is used without the per filter config
+	filter, _ = newFilterForTest(t, map[string]interface{}{"path": file.Name()})
+	headers = protocol.CommonHeader{}
+	filter.OnReceive(context.Background(), headers, nil, nil)
+	if v, _ := headers.Get("x-script"); v != "file" {
+		t.Fatalf("expected the file script is used, but got %s", v)
+	}
+}
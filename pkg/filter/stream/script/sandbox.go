@@ -0,0 +1,289 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package script
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+var (
+	errInstructionLimit = errors.New("lua: instruction limit exceeded")
+	errTimeout          = errors.New("lua: execution timeout")
+	errStringLength     = errors.New("lua: string length limit exceeded")
+	errFormat           = errors.New("lua: invalid format (width or precision too long)")
+)
+
+const (
+	defaultMaxStringLength = 1 << 20
+	maxCallDepth           = 200
+	// the time budget is checked every timeCheckInterval instructions
+	timeCheckInterval = 1024
+)
+
+// the base functions that can load the code or reach the process are removed from the sandbox
+var unsafeBaseFunctions = []string{
+	"collectgarbage", "dofile", "load", "loadfile", "loadstring", "module", "print", "_printregs", "require",
+}
+
+// closedChan is returned by budget.Done if the budget is exceeded
+var closedChan = make(chan struct{})
+
+func init() {
+	close(closedChan)
+}
+
+// budgetOptions is the budget of an invocation
+type budgetOptions struct {
+	// maxInstructions is the max number of the vm instructions, 0 means no limit
+	maxInstructions int64
+	// timeout is the max execution time, 0 means no limit
+	timeout time.Duration
+	// maxStringLength is the max length of the strings made by the script,
+	// it is checked by the concatenation and the string functions that may make a long string
+	maxStringLength int
+}
+
+// budget is the context of the lua state. gopher-lua calls Done before each instruction
+// when the state has a context, so the budget is checked there.
+type budget struct {
+	context.Context
+	opts     budgetOptions
+	steps    int64
+	deadline time.Time
+	err      error
+}
+
+// reset starts the budget of a new invocation
+func (b *budget) reset() {
+	b.steps = 0
+	b.err = nil
+	if b.opts.timeout > 0 {
+		b.deadline = time.Now().Add(b.opts.timeout)
+	}
+}
+
+func (b *budget) Done() <-chan struct{} {
+	if b.err == nil {
+		b.err = b.check()
+	}
+	if b.err != nil {
+		return closedChan
+	}
+	return nil
+}
+
+func (b *budget) Err() error {
+	return b.err
+}
+
+// check counts an instruction and checks the time every timeCheckInterval instructions
+func (b *budget) check() error {
+	b.steps++
+	if b.opts.maxInstructions > 0 && b.steps > b.opts.maxInstructions {
+		return errInstructionLimit
+	}
+	if b.opts.timeout > 0 && b.steps%timeCheckInterval == 0 && time.Now().After(b.deadline) {
+		return errTimeout
+	}
+	return nil
+}
+
+// state is a lua state with the safe libraries opened, it is not safe for concurrent use
+type state struct {
+	L      *lua.LState
+	budget *budget
+	// the original library functions wrapped by the length checks
+	format lua.LGFunction
+	concat lua.LGFunction
+}
+
+// newState creates a sandbox state, the scripts can not access the files, the environment or the processes
+func newState(opts budgetOptions) *state {
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:  true,
+		CallStackSize: maxCallDepth,
+	})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range unsafeBaseFunctions {
+		L.SetGlobal(name, lua.LNil)
+	}
+	s := &state{
+		L: L,
+		budget: &budget{
+			Context: context.Background(),
+			opts:    opts,
+		},
+	}
+	// the functions may make a huge string in a single instruction, so the length is checked before it is made
+	stringLib := L.GetGlobal(lua.StringLibName).(*lua.LTable)
+	s.format = stringLib.RawGetString("format").(*lua.LFunction).GFunction
+	stringLib.RawSetString("rep", L.NewFunction(s.stringRep))
+	stringLib.RawSetString("format", L.NewFunction(s.stringFormat))
+	stringLib.RawSetString(concatFunction, L.NewFunction(s.concatValues))
+	tableLib := L.GetGlobal(lua.TabLibName).(*lua.LTable)
+	s.concat = tableLib.RawGetString("concat").(*lua.LFunction).GFunction
+	tableLib.RawSetString("concat", L.NewFunction(s.tableConcat))
+	registerTypes(L)
+	L.SetContext(s.budget)
+	return s
+}
+
+func (s *state) stringRep(L *lua.LState) int {
+	str := L.CheckString(1)
+	n := L.CheckInt(2)
+	if n <= 0 {
+		L.Push(lua.LString(""))
+		return 1
+	}
+	if len(str) > 0 && n > s.budget.opts.maxStringLength/len(str) {
+		L.RaiseError(errStringLength.Error())
+	}
+	L.Push(lua.LString(strings.Repeat(str, n)))
+	return 1
+}
+
+// stringFormat checks the max length of the formatted string. As the lua format does,
+// the width and the precision have at most 2 digits, and the '*' widths of go are not allowed
+func (s *state) stringFormat(L *lua.LState) int {
+	format := L.CheckString(1)
+	size, arg := len(format), 2
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i++; i < len(format) && format[i] == '%' {
+			continue
+		}
+		for i < len(format) && strings.IndexByte("-+ #0", format[i]) >= 0 {
+			i++
+		}
+		i = skipFormatDigits(L, format, i)
+		if i < len(format) && format[i] == '.' {
+			i = skipFormatDigits(L, format, i+1)
+		}
+		if i >= len(format) {
+			break
+		}
+		if format[i] == '*' || format[i] == '[' {
+			L.RaiseError(errFormat.Error())
+		}
+		// the width and the precision pad at most 99 bytes
+		size += 99 + formatLength(format[i], L.Get(arg))
+		arg++
+	}
+	if size > s.budget.opts.maxStringLength {
+		L.RaiseError(errStringLength.Error())
+	}
+	return s.format(L)
+}
+
+func skipFormatDigits(L *lua.LState, format string, i int) int {
+	start := i
+	for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+		i++
+	}
+	if i-start > 2 {
+		L.RaiseError(errFormat.Error())
+	}
+	return i
+}
+
+// formatLength returns the max length of the formatted value
+func formatLength(verb byte, v lua.LValue) int {
+	str, ok := v.(lua.LString)
+	if !ok {
+		// the numbers and the names of the other values
+		return 512
+	}
+	switch verb {
+	case 's', 'v':
+		return len(str)
+	case 'x', 'X':
+		return 3 * len(str)
+	default:
+		// such as %q, which escapes a byte in 4 bytes at most
+		return 4*len(str) + 2
+	}
+}
+
+// tableConcat checks the length of the concatenated string
+func (s *state) tableConcat(L *lua.LState) int {
+	tbl := L.CheckTable(1)
+	sep := L.OptString(2, "")
+	i := L.OptInt(3, 1)
+	j := L.OptInt(4, tbl.Len())
+	if i < 1 {
+		i = 1
+	}
+	if n := tbl.Len(); j > n {
+		j = n
+	}
+	size := 0
+	for ; i <= j; i++ {
+		size += len(lua.LVAsString(tbl.RawGetInt(i)))
+		if i < j {
+			size += len(sep)
+		}
+		if size > s.budget.opts.maxStringLength {
+			L.RaiseError(errStringLength.Error())
+		}
+	}
+	return s.concat(L)
+}
+
+// run runs the compiled chunk, so the hook functions are defined
+func (s *state) run(proto *lua.FunctionProto) error {
+	s.budget.reset()
+	s.L.Push(s.L.NewFunctionFromProto(proto))
+	return s.L.PCall(0, 0, nil)
+}
+
+// call calls the global function with the arguments if the script defines it
+func (s *state) call(name string, args ...lua.LValue) error {
+	fn := s.L.GetGlobal(name)
+	if fn == lua.LNil {
+		return nil
+	}
+	s.budget.reset()
+	s.L.Push(fn)
+	for _, arg := range args {
+		s.L.Push(arg)
+	}
+	return s.L.PCall(len(args), 0, nil)
+}
+
+func (s *state) close() {
+	s.L.Close()
+}
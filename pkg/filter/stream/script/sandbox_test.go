@@ -0,0 +1,177 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package script
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+)
+
+func TestSandboxBudget(t *testing.T) {
+	for _, tc := range []struct {
+		source string
+		opts   budgetOptions
+		err    error
+	}{
+		{
+			source: "function on_receive() while true do end end",
+			opts:   budgetOptions{maxInstructions: 1000, maxStringLength: defaultMaxStringLength},
+			err:    errInstructionLimit,
+		},
+		// the budget error can not be caught by pcall
+		{
+			source: "function on_receive() while true do pcall(function() while true do end end) end end",
+			opts:   budgetOptions{maxInstructions: 1000, maxStringLength: defaultMaxStringLength},
+			err:    errInstructionLimit,
+		},
+		{
+			source: "function on_receive() while true do end end",
+			opts:   budgetOptions{timeout: 10 * time.Millisecond, maxStringLength: defaultMaxStringLength},
+			err:    errTimeout,
+		},
+		{
+			source: "function on_receive() local s = 'x' while true do s = s .. s end end",
+			opts:   budgetOptions{maxStringLength: 1024},
+			err:    errStringLength,
+		},
+		{
+			source: "function on_receive() local t = {} t[1] = string.rep('x', 1e12) end",
+			opts:   budgetOptions{maxStringLength: 1024},
+			err:    errStringLength,
+		},
+		{
+			source: "function on_receive() return ('x'):rep(2048) end",
+			opts:   budgetOptions{maxStringLength: 1024},
+			err:    errStringLength,
+		},
+		{
+			source: "function on_receive() local t = {} for i = 1, 100 do t[i] = ('x'):rep(100) end return table.concat(t) end",
+			opts:   budgetOptions{maxStringLength: 1024},
+			err:    errStringLength,
+		},
+		{
+			source: "function on_receive() local s = ('x'):rep(1000) return s .. s end",
+			opts:   budgetOptions{maxStringLength: 1024},
+			err:    errStringLength,
+		},
+		{
+			source: "function on_receive() return string.format('%99d%99d%99d%99d%99d%99d%99d%99d%99d%99d%99d', 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1) end",
+			opts:   budgetOptions{maxStringLength: 1024},
+			err:    errStringLength,
+		},
+		{
+			source: "function on_receive() return string.format('%0999999999d', 1) end",
+			opts:   budgetOptions{maxStringLength: 1024},
+			err:    errFormat,
+		},
+	} {
+		s, err := newScript(&v2.StreamScript{Source: tc.source})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.opts = tc.opts
+		st, err := s.getState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = st.call(onReceiveFunction)
+		if err == nil || !strings.Contains(err.Error(), tc.err.Error()) {
+			t.Errorf("%s: expected %v, but got %v", tc.source, tc.err, err)
+		}
+	}
+}
+
+func TestSandboxLibraries(t *testing.T) {
+	s, err := newScript(&v2.StreamScript{Source: `
+function on_receive()
+	for _, name in ipairs({"collectgarbage", "dofile", "load", "loadfile", "loadstring", "module", "print", "require", "io", "os", "debug"}) do
+		if _G[name] ~= nil then
+			error(name .. " is opened")
+		end
+	end
+	assert(string.upper("a") == "A" and ("b"):rep(2) == "bb")
+	assert(table.concat({"a", "b"}, ",") == "a,b")
+	assert(string.format("%s=%5.2f", "a", 1) == "a= 1.00" and 1 .. "b" .. 2 == "1b2")
+	local mt = {__concat = function(a, b) return "t" end}
+	assert(setmetatable({}, mt) .. "a" == "t" and "a" .. setmetatable({}, mt) == "t")
+	assert(math.max(1, 2) == 2)
+end
+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := s.getState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.call(onReceiveFunction); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScriptStatePool(t *testing.T) {
+	f, err := CreateScriptFilterFactory(map[string]interface{}{"source": `
+loaded = (loaded or 0) + 1
+
+function on_receive(handle)
+	handle:headers():set("x-loaded", tostring(loaded))
+end
+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := f.(*FilterConfigFactory).script
+	// the chunk is run once, the state is reused by the next stream
+	for i := 0; i < 3; i++ {
+		filter := newScriptFilter(context.Background(), s)
+		filter.SetReceiveFilterHandler(&mockStreamReceiverFilterCallbacks{})
+		headers := protocol.CommonHeader{}
+		if status := filter.OnReceive(context.Background(), headers, nil, nil); status != api.StreamFilterContinue {
+			t.Fatalf("unexpected status %v", status)
+		}
+		if v, _ := headers.Get("x-loaded"); v != "1" {
+			t.Fatalf("expected the chunk is run once, but got %s", v)
+		}
+		// OnDestroy is called by both the receiver and the sender filter chain
+		filter.OnDestroy()
+		filter.OnDestroy()
+		if len(s.states) != 1 {
+			t.Fatalf("expected the state is returned once, but got %d", len(s.states))
+		}
+	}
+	// the concurrent streams use their own states
+	first, second := newScriptFilter(context.Background(), s), newScriptFilter(context.Background(), s)
+	for _, filter := range []*streamScriptFilter{first, second} {
+		filter.SetReceiveFilterHandler(&mockStreamReceiverFilterCallbacks{})
+		filter.OnReceive(context.Background(), protocol.CommonHeader{}, nil, nil)
+	}
+	if first.state == second.state || len(s.states) != 0 {
+		t.Fatal("expected the concurrent streams use their own states")
+	}
+	first.OnDestroy()
+	second.OnDestroy()
+	if len(s.states) != 2 {
+		t.Fatalf("unexpected idle states: %d", len(s.states))
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package script
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+	v2 "mosn.io/mosn/pkg/config/v2"
+)
+
+const (
+	defaultMaxInstructions = 100000
+	defaultTimeout         = 10 * time.Millisecond
+	// maxIdleStates is the max number of the idle states kept by a script
+	maxIdleStates = 64
+
+	onReceiveFunction = "on_receive"
+	onAppendFunction  = "on_append"
+)
+
+// script is a compiled lua script with its budget, the states that ran the script
+// are kept in a pool and reused by the streams
+type script struct {
+	disabled bool
+	proto    *lua.FunctionProto
+	opts     budgetOptions
+	states   chan *state
+}
+
+func newScript(cfg *v2.StreamScript) (*script, error) {
+	s := &script{
+		disabled: cfg.Disabled,
+		opts: budgetOptions{
+			maxInstructions: defaultMaxInstructions,
+			timeout:         defaultTimeout,
+			maxStringLength: defaultMaxStringLength,
+		},
+	}
+	if cfg.MaxInstructions > 0 {
+		s.opts.maxInstructions = cfg.MaxInstructions
+	}
+	if cfg.Timeout != nil && cfg.Timeout.Duration > 0 {
+		s.opts.timeout = cfg.Timeout.Duration
+	}
+	if s.disabled {
+		return s, nil
+	}
+	source, name := cfg.Source, "script"
+	if source == "" {
+		if cfg.Path == "" {
+			return nil, errors.New("script filter has neither source nor path")
+		}
+		data, err := ioutil.ReadFile(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		source, name = string(data), cfg.Path
+	}
+	chunk, err := parse.Parse(strings.NewReader(source), name)
+	if err != nil {
+		return nil, err
+	}
+	guardConcat(chunk)
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return nil, err
+	}
+	s.proto = proto
+	s.states = make(chan *state, maxIdleStates)
+	return s, nil
+}
+
+// getState returns an idle state, or creates a state and runs the script, so the hook functions are defined
+func (s *script) getState() (*state, error) {
+	select {
+	case st := <-s.states:
+		return st, nil
+	default:
+	}
+	st := newState(s.opts)
+	if err := st.run(s.proto); err != nil {
+		st.close()
+		return nil, err
+	}
+	return st, nil
+}
+
+// putState makes the state idle, the state is closed if the pool is full
+func (s *script) putState(st *state) {
+	select {
+	case s.states <- st:
+	default:
+		st.close()
+	}
+}
@@ -0,0 +1,51 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transcoder
+
+import (
+	"testing"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestResponseFlagsNotCollide(t *testing.T) {
+	flags := []api.ResponseFlag{
+		api.NoHealthyUpstream,
+		api.UpstreamRequestTimeout,
+		api.UpstreamLocalReset,
+		api.UpstreamRemoteReset,
+		api.UpstreamConnectionFailure,
+		api.UpstreamConnectionTermination,
+		api.UpstreamOverflow,
+		api.NoRouteFound,
+		api.DelayInjected,
+		api.FaultInjected,
+		api.RateLimited,
+		api.ReqEntityTooLarge,
+		types.Unauthorized,
+		RequestTranscodeFail,
+	}
+	var all api.ResponseFlag
+	for _, f := range flags {
+		if all&f != 0 {
+			t.Errorf("response flag 0x%x collides with another flag", int(f))
+		}
+		all |= f
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/wasm/proxywasm"
+)
+
+func init() {
+	api.RegisterStream(v2.Wasm, CreateWasmFilterFactory)
+}
+
+// FilterConfigFactory creates the wasm filters, the filters of the same plugin share the wasm instances
+type FilterConfigFactory struct {
+	Config *v2.WasmPluginConfig
+	plugin *proxywasm.Plugin
+}
+
+func (f *FilterConfigFactory) CreateFilterChain(context context.Context, callbacks api.StreamFilterChainFactoryCallbacks) {
+	filter, err := newWasmFilter(context, f.plugin)
+	if err != nil {
+		log.Proxy.Errorf(context, "[stream filter] [wasm] create filter of plugin %s failed: %v", f.plugin.Name(), err)
+		return
+	}
+	callbacks.AddStreamReceiverFilter(filter, api.AfterRoute)
+	callbacks.AddStreamSenderFilter(filter)
+}
+
+func CreateWasmFilterFactory(conf map[string]interface{}) (api.StreamFilterChainFactory, error) {
+	log.DefaultLogger.Debugf("create wasm stream filter factory")
+	cfg, err := ParseStreamWasmFilter(conf)
+	if err != nil {
+		return nil, err
+	}
+	plugin, err := proxywasm.GetOrCreatePlugin(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &FilterConfigFactory{
+		Config: cfg,
+		plugin: plugin,
+	}, nil
+}
+
+// ParseStreamWasmFilter
+func ParseStreamWasmFilter(cfg map[string]interface{}) (*v2.WasmPluginConfig, error) {
+	filterConfig := &v2.WasmPluginConfig{}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, filterConfig); err != nil {
+		return nil, err
+	}
+	return filterConfig, nil
+}
@@ -0,0 +1,321 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wasm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/wasm/proxywasm"
+	"mosn.io/pkg/buffer"
+)
+
+var errPluginStopped = errors.New("wasm plugin is stopped")
+
+// localResponse is sent by proxy_send_local_response
+type localResponse struct {
+	status  int
+	headers [][2]string
+	body    []byte
+}
+
+// streamWasmFilter runs the stream in a wasm context.
+// When the module pauses the stream, the filter waits until the module continues it,
+// sends a local response or the stream is destroyed
+type streamWasmFilter struct {
+	ctx            context.Context
+	instance       *proxywasm.Instance
+	wasmCtx        *proxywasm.Context
+	receiveHandler api.StreamReceiverFilterHandler
+	sendHandler    api.StreamSenderFilterHandler
+	// the headers and the body being processed, they are accessed with the instance locked
+	requestHeaders   api.HeaderMap
+	requestBody      buffer.IoBuffer
+	requestTrailers  api.HeaderMap
+	responseHeaders  api.HeaderMap
+	responseBody     buffer.IoBuffer
+	responseTrailers api.HeaderMap
+	// mu protects local and closed, they may be set by the http callout response
+	mu     sync.Mutex
+	local  *localResponse
+	closed bool
+	// resume is notified when the module continues, responds or closes the stream
+	resume      chan struct{}
+	stop        chan struct{}
+	destroyOnce sync.Once
+}
+
+func newWasmFilter(ctx context.Context, plugin *proxywasm.Plugin) (*streamWasmFilter, error) {
+	instance := plugin.GetInstance()
+	if instance == nil {
+		return nil, errPluginStopped
+	}
+	f := &streamWasmFilter{
+		ctx:      ctx,
+		instance: instance,
+		resume:   make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+	wasmCtx, err := instance.NewContext(ctx, f)
+	if err != nil {
+		instance.Release()
+		return nil, err
+	}
+	f.wasmCtx = wasmCtx
+	return f, nil
+}
+
+func (f *streamWasmFilter) SetReceiveFilterHandler(handler api.StreamReceiverFilterHandler) {
+	f.receiveHandler = handler
+}
+
+func (f *streamWasmFilter) SetSenderFilterHandler(handler api.StreamSenderFilterHandler) {
+	f.sendHandler = handler
+}
+
+func (f *streamWasmFilter) OnReceive(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	f.requestHeaders, f.requestBody, f.requestTrailers = headers, buf, trailers
+	hasBody := buf != nil && buf.Len() > 0
+	if !f.run(func() (proxywasm.Action, error) {
+		return f.wasmCtx.OnRequestHeaders(headerCount(headers), !hasBody && trailers == nil)
+	}) {
+		return f.replyLocal(ctx)
+	}
+	if hasBody && !f.run(func() (proxywasm.Action, error) {
+		return f.wasmCtx.OnRequestBody(buf.Len(), trailers == nil)
+	}) {
+		return f.replyLocal(ctx)
+	}
+	if trailers != nil && !f.run(func() (proxywasm.Action, error) {
+		return f.wasmCtx.OnRequestTrailers(headerCount(trailers))
+	}) {
+		return f.replyLocal(ctx)
+	}
+	return api.StreamFilterContinue
+}
+
+func (f *streamWasmFilter) Append(ctx context.Context, headers api.HeaderMap, buf buffer.IoBuffer, trailers api.HeaderMap) api.StreamFilterStatus {
+	f.responseHeaders, f.responseBody, f.responseTrailers = headers, buf, trailers
+	hasBody := buf != nil && buf.Len() > 0
+	if !f.run(func() (proxywasm.Action, error) {
+		return f.wasmCtx.OnResponseHeaders(headerCount(headers), !hasBody && trailers == nil)
+	}) {
+		return f.replaceResponse(headers, buf)
+	}
+	if hasBody && !f.run(func() (proxywasm.Action, error) {
+		return f.wasmCtx.OnResponseBody(buf.Len(), trailers == nil)
+	}) {
+		return f.replaceResponse(headers, buf)
+	}
+	if trailers != nil && !f.run(func() (proxywasm.Action, error) {
+		return f.wasmCtx.OnResponseTrailers(headerCount(trailers))
+	}) {
+		return f.replaceResponse(headers, buf)
+	}
+	return api.StreamFilterContinue
+}
+
+// run calls the module, it returns false if the stream is ended by the module or the stream is destroyed
+func (f *streamWasmFilter) run(call func() (proxywasm.Action, error)) bool {
+	// drops the notification of the previous call
+	select {
+	case <-f.resume:
+	default:
+	}
+	action, err := call()
+	if err != nil {
+		log.Proxy.Errorf(f.ctx, "[stream filter] [wasm] call wasm module failed: %v", err)
+		f.SendLocalResponse(http.StatusInternalServerError, nil, nil)
+		return false
+	}
+	if action == proxywasm.ActionPause && f.ended() == nil {
+		if log.Proxy.GetLogLevel() >= log.DEBUG {
+			log.Proxy.Debugf(f.ctx, "[stream filter] [wasm] the stream is paused by the module")
+		}
+		timer := time.NewTimer(f.pauseTimeout())
+		defer timer.Stop()
+		select {
+		case <-f.resume:
+		case <-f.stop:
+			return false
+		case <-timer.C:
+			log.Proxy.Errorf(f.ctx, "[stream filter] [wasm] the paused stream is not continued by the module in time")
+			f.SendLocalResponse(http.StatusGatewayTimeout, nil, nil)
+			return false
+		}
+	}
+	return f.ended() == nil
+}
+
+// pauseTimeout returns how long a paused stream waits for the module, the route timeout is used if configured
+func (f *streamWasmFilter) pauseTimeout() time.Duration {
+	if f.receiveHandler != nil {
+		if route := f.receiveHandler.Route(); route != nil && route.RouteRule() != nil {
+			if timeout := route.RouteRule().GlobalTimeout(); timeout > 0 {
+				return timeout
+			}
+		}
+	}
+	return types.GlobalTimeout
+}
+
+// ended returns the local response if the module sends a local response or closes the stream.
+// A stream filter can not reset the stream, so the stream closed by the module is responded with 403
+func (f *streamWasmFilter) ended() *localResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.local == nil && f.closed {
+		return &localResponse{status: http.StatusForbidden}
+	}
+	return f.local
+}
+
+// replyLocal sends the local response to the downstream
+func (f *streamWasmFilter) replyLocal(ctx context.Context) api.StreamFilterStatus {
+	local := f.ended()
+	if local == nil {
+		// the stream is destroyed
+		return api.StreamFilterStop
+	}
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[stream filter] [wasm] the request is responded by the module with status %d", local.status)
+	}
+	headers := protocol.CommonHeader{}
+	for _, h := range local.headers {
+		headers.Set(h[0], h[1])
+	}
+	if handler, ok := f.receiveHandler.(types.HijackReplyWithBodyHandler); ok && len(local.body) > 0 {
+		handler.SendHijackReplyWithBody(local.status, headers, string(local.body))
+	} else {
+		f.receiveHandler.SendHijackReply(local.status, headers)
+	}
+	return api.StreamFilterStop
+}
+
+// replaceResponse replaces the upstream response with the local response
+func (f *streamWasmFilter) replaceResponse(headers api.HeaderMap, buf buffer.IoBuffer) api.StreamFilterStatus {
+	local := f.ended()
+	if local == nil {
+		// the stream is destroyed
+		return api.StreamFilterStop
+	}
+	if headers != nil {
+		headers.Set(types.HeaderStatus, strconv.Itoa(local.status))
+		for _, h := range local.headers {
+			headers.Set(h[0], h[1])
+		}
+		if _, ok := headers.Get("Content-Length"); ok {
+			headers.Set("Content-Length", strconv.Itoa(len(local.body)))
+		}
+	}
+	if buf != nil {
+		buf.Reset()
+		buf.Write(local.body)
+	} else if len(local.body) > 0 {
+		f.sendHandler.SetResponseData(buffer.NewIoBufferBytes(local.body))
+	}
+	return api.StreamFilterContinue
+}
+
+func (f *streamWasmFilter) OnDestroy() {
+	f.destroyOnce.Do(func() {
+		close(f.stop)
+		f.wasmCtx.Delete()
+		f.instance.Release()
+	})
+}
+
+// GetHeaderMap implements proxywasm.Handler
+func (f *streamWasmFilter) GetHeaderMap(mapType proxywasm.MapType) api.HeaderMap {
+	switch mapType {
+	case proxywasm.MapTypeHttpRequestHeaders:
+		return f.requestHeaders
+	case proxywasm.MapTypeHttpRequestTrailers:
+		return f.requestTrailers
+	case proxywasm.MapTypeHttpResponseHeaders:
+		return f.responseHeaders
+	case proxywasm.MapTypeHttpResponseTrailers:
+		return f.responseTrailers
+	}
+	return nil
+}
+
+// GetBuffer implements proxywasm.Handler
+func (f *streamWasmFilter) GetBuffer(bufferType proxywasm.BufferType) buffer.IoBuffer {
+	switch bufferType {
+	case proxywasm.BufferTypeHttpRequestBody:
+		return f.requestBody
+	case proxywasm.BufferTypeHttpResponseBody:
+		return f.responseBody
+	}
+	return nil
+}
+
+// SendLocalResponse implements proxywasm.Handler
+func (f *streamWasmFilter) SendLocalResponse(status int, headers [][2]string, body []byte) {
+	f.mu.Lock()
+	if f.local == nil {
+		f.local = &localResponse{
+			status:  status,
+			headers: headers,
+			body:    body,
+		}
+	}
+	f.mu.Unlock()
+	f.notify()
+}
+
+// Resume implements proxywasm.Handler
+func (f *streamWasmFilter) Resume(stream proxywasm.StreamType) {
+	f.notify()
+}
+
+// Close implements proxywasm.Handler
+func (f *streamWasmFilter) Close(stream proxywasm.StreamType) {
+	f.mu.Lock()
+	f.closed = true
+	f.mu.Unlock()
+	f.notify()
+}
+
+func (f *streamWasmFilter) notify() {
+	select {
+	case f.resume <- struct{}{}:
+	default:
+	}
+}
+
+func headerCount(headers api.HeaderMap) int {
+	if headers == nil {
+		return 0
+	}
+	n := 0
+	headers.Range(func(key, value string) bool {
+		n++
+		return true
+	})
+	return n
+}
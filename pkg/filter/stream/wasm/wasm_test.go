@@ -0,0 +1,285 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wasm
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/internal/wasmtest"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/wasm/proxywasm"
+	"mosn.io/pkg/buffer"
+)
+
+const testModule = "stream_wasm_test"
+
+func init() {
+	wasmtest.RegisterModule(testModule, func(g *wasmtest.Guest) wasmtest.Exports {
+		getHeader := func(mapType proxywasm.MapType, key string) string {
+			kp, ks := g.WriteString(key)
+			data, _ := g.HostReturnBytes("proxy_get_header_map_value", uint64(mapType), kp, ks)
+			return string(data)
+		}
+		setHeader := func(mapType proxywasm.MapType, key, value string) {
+			kp, ks := g.WriteString(key)
+			vp, vs := g.WriteString(value)
+			g.Host("proxy_replace_header_map_value", uint64(mapType), kp, ks, vp, vs)
+		}
+		sendLocalResponse := func(status int, body string) {
+			bp, bs := g.WriteString(body)
+			g.Host("proxy_send_local_response", uint64(status), 0, 0, bp, bs, 0, 0, 0)
+		}
+		return wasmtest.Exports{
+			"proxy_on_vm_start": func(args ...uint64) uint64 {
+				return 1
+			},
+			"proxy_on_configure": func(args ...uint64) uint64 {
+				return 1
+			},
+			"proxy_on_request_headers": func(args ...uint64) uint64 {
+				switch getHeader(proxywasm.MapTypeHttpRequestHeaders, ":path") {
+				case "/deny":
+					sendLocalResponse(401, "denied")
+					return uint64(proxywasm.ActionPause)
+				case "/close":
+					g.Host("proxy_close_stream", uint64(proxywasm.StreamTypeRequest))
+					return uint64(proxywasm.ActionPause)
+				case "/pause":
+					return uint64(proxywasm.ActionPause)
+				}
+				setHeader(proxywasm.MapTypeHttpRequestHeaders, "x-wasm", "on")
+				return uint64(proxywasm.ActionContinue)
+			},
+			"proxy_on_request_body": func(args ...uint64) uint64 {
+				bp, bs := g.WriteString("WASM")
+				g.Host("proxy_set_buffer_bytes", uint64(proxywasm.BufferTypeHttpRequestBody), 0, 4, bp, bs)
+				return uint64(proxywasm.ActionContinue)
+			},
+			"proxy_on_response_headers": func(args ...uint64) uint64 {
+				setHeader(proxywasm.MapTypeHttpResponseHeaders, "x-resp", "on")
+				return uint64(proxywasm.ActionContinue)
+			},
+			"proxy_on_response_body": func(args ...uint64) uint64 {
+				data, _ := g.HostReturnBytes("proxy_get_buffer_bytes", uint64(proxywasm.BufferTypeHttpResponseBody), 0, args[1])
+				if string(data) == "secret" {
+					sendLocalResponse(502, "masked")
+					return uint64(proxywasm.ActionPause)
+				}
+				return uint64(proxywasm.ActionContinue)
+			},
+		}
+	})
+}
+
+// mocks the interface that used for test
+// only implement the function that used in test
+type mockStreamReceiverFilterCallbacks struct {
+	api.StreamReceiverFilterHandler
+	route      api.Route
+	hijackCode int
+	hijackBody string
+}
+
+func (cb *mockStreamReceiverFilterCallbacks) Route() api.Route {
+	return cb.route
+}
+
+type mockRoute struct {
+	api.Route
+	rule *mockRouteRule
+}
+
+func (r *mockRoute) RouteRule() api.RouteRule {
+	return r.rule
+}
+
+type mockRouteRule struct {
+	api.RouteRule
+	timeout time.Duration
+}
+
+func (r *mockRouteRule) GlobalTimeout() time.Duration {
+	return r.timeout
+}
+
+func (cb *mockStreamReceiverFilterCallbacks) SendHijackReply(code int, headers api.HeaderMap) {
+	cb.hijackCode = code
+}
+
+func (cb *mockStreamReceiverFilterCallbacks) SendHijackReplyWithBody(code int, headers api.HeaderMap, body string) {
+	cb.hijackCode = code
+	cb.hijackBody = body
+}
+
+type mockStreamSenderFilterCallbacks struct {
+	api.StreamSenderFilterHandler
+}
+
+func createTestFactory(t *testing.T, name string) *FilterConfigFactory {
+	dir, err := ioutil.TempDir("", "wasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	path := filepath.Join(dir, "filter.wasm")
+	if err := ioutil.WriteFile(path, []byte(testModule), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := CreateWasmFilterFactory(map[string]interface{}{
+		"name":         name,
+		"engine":       wasmtest.EngineName,
+		"path":         path,
+		"instance_num": 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	factory := f.(*FilterConfigFactory)
+	t.Cleanup(factory.plugin.Stop)
+	return factory
+}
+
+func newTestFilter(t *testing.T, factory *FilterConfigFactory) (*streamWasmFilter, *mockStreamReceiverFilterCallbacks) {
+	filter, err := newWasmFilter(context.Background(), factory.plugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := &mockStreamReceiverFilterCallbacks{}
+	filter.SetReceiveFilterHandler(cb)
+	filter.SetSenderFilterHandler(&mockStreamSenderFilterCallbacks{})
+	return filter, cb
+}
+
+func TestCreateFactoryFailed(t *testing.T) {
+	for _, conf := range []map[string]interface{}{
+		{"engine": wasmtest.EngineName, "path": "filter.wasm"},
+		{"name": "test_no_engine", "engine": "unknown", "path": "filter.wasm"},
+		{"name": "test_no_file", "engine": wasmtest.EngineName, "path": "not_exists.wasm"},
+	} {
+		if _, err := CreateWasmFilterFactory(conf); err == nil {
+			t.Fatalf("expected create factory failed: %v", conf)
+		}
+	}
+}
+
+func TestWasmFilter(t *testing.T) {
+	factory := createTestFactory(t, "test_stream_filter")
+	filter, cb := newTestFilter(t, factory)
+	defer filter.OnDestroy()
+	headers := protocol.CommonHeader{types.HeaderPath: "/api"}
+	body := buffer.NewIoBufferString("body content")
+	if status := filter.OnReceive(context.Background(), headers, body, nil); status != api.StreamFilterContinue {
+		t.Fatalf("unexpected status: %v", status)
+	}
+	if v, _ := headers.Get("x-wasm"); v != "on" {
+		t.Fatal("the request header is not set")
+	}
+	if body.String() != "WASM content" {
+		t.Fatalf("unexpected request body: %s", body.String())
+	}
+	if cb.hijackCode != 0 {
+		t.Fatal("unexpected hijack")
+	}
+	respHeaders := protocol.CommonHeader{types.HeaderStatus: "200"}
+	respBody := buffer.NewIoBufferString("response")
+	filter.Append(context.Background(), respHeaders, respBody, nil)
+	if v, _ := respHeaders.Get("x-resp"); v != "on" || respBody.String() != "response" {
+		t.Fatal("unexpected response")
+	}
+}
+
+func TestWasmFilterLocalResponse(t *testing.T) {
+	factory := createTestFactory(t, "test_stream_filter_local")
+	for path, expected := range map[string]int{
+		"/deny":  401,
+		"/close": 403,
+	} {
+		filter, cb := newTestFilter(t, factory)
+		if status := filter.OnReceive(context.Background(), protocol.CommonHeader{types.HeaderPath: path}, nil, nil); status != api.StreamFilterStop {
+			t.Fatalf("%s: unexpected status: %v", path, status)
+		}
+		if cb.hijackCode != expected {
+			t.Fatalf("%s: expected hijack %d, but got %d", path, expected, cb.hijackCode)
+		}
+		if path == "/deny" && cb.hijackBody != "denied" {
+			t.Fatalf("unexpected hijack body: %s", cb.hijackBody)
+		}
+		filter.OnDestroy()
+	}
+	// the response is replaced by the local response
+	filter, _ := newTestFilter(t, factory)
+	defer filter.OnDestroy()
+	respHeaders := protocol.CommonHeader{types.HeaderStatus: "200", "Content-Length": "6"}
+	respBody := buffer.NewIoBufferString("secret")
+	filter.Append(context.Background(), respHeaders, respBody, nil)
+	if v, _ := respHeaders.Get(types.HeaderStatus); v != "502" || respBody.String() != "masked" {
+		t.Fatalf("unexpected response: %s %s", v, respBody.String())
+	}
+}
+
+func TestWasmFilterPauseDestroy(t *testing.T) {
+	factory := createTestFactory(t, "test_stream_filter_pause")
+	filter, cb := newTestFilter(t, factory)
+	ch := make(chan api.StreamFilterStatus, 1)
+	go func() {
+		ch <- filter.OnReceive(context.Background(), protocol.CommonHeader{types.HeaderPath: "/pause"}, nil, nil)
+	}()
+	select {
+	case <-ch:
+		t.Fatal("expected the stream is paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+	filter.OnDestroy()
+	select {
+	case status := <-ch:
+		if status != api.StreamFilterStop || cb.hijackCode != 0 {
+			t.Fatalf("unexpected status %v, hijack %d", status, cb.hijackCode)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the stream is not stopped")
+	}
+}
+
+func TestWasmFilterPauseTimeout(t *testing.T) {
+	factory := createTestFactory(t, "test_stream_filter_pause_timeout")
+	filter, cb := newTestFilter(t, factory)
+	defer filter.OnDestroy()
+	cb.route = &mockRoute{rule: &mockRouteRule{timeout: 10 * time.Millisecond}}
+	ch := make(chan api.StreamFilterStatus, 1)
+	go func() {
+		ch <- filter.OnReceive(context.Background(), protocol.CommonHeader{types.HeaderPath: "/pause"}, nil, nil)
+	}()
+	select {
+	case status := <-ch:
+		if status != api.StreamFilterStop || cb.hijackCode != http.StatusGatewayTimeout {
+			t.Fatalf("unexpected status %v, hijack %d", status, cb.hijackCode)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the paused stream is not timed out")
+	}
+}
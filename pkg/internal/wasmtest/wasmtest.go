@@ -0,0 +1,193 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package wasmtest provides a fake wasm engine for the tests, the modules are implemented in go.
+// Importing the package registers the engine named wasmtest, the bytes of a module file are the module name
+package wasmtest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"mosn.io/mosn/pkg/wasm"
+)
+
+// EngineName is the name of the fake engine
+const EngineName = "wasmtest"
+
+const memorySize = 4 * 1024 * 1024
+
+// Exports are the functions exported by a fake module instance
+type Exports map[string]func(args ...uint64) uint64
+
+// ModuleFunc creates the exports of a fake module instance, the guest calls the host functions
+type ModuleFunc func(g *Guest) Exports
+
+var modules sync.Map
+
+// RegisterModule registers a fake module by the name
+func RegisterModule(name string, f ModuleFunc) {
+	modules.Store(name, f)
+}
+
+type engine struct{}
+
+func init() {
+	wasm.RegisterEngine(&engine{})
+}
+
+func (e *engine) Name() string {
+	return EngineName
+}
+
+func (e *engine) Compile(wasmBytes []byte) (wasm.Module, error) {
+	f, ok := modules.Load(string(wasmBytes))
+	if !ok {
+		return nil, fmt.Errorf("fake module %s is not registered", wasmBytes)
+	}
+	return &module{newExports: f.(ModuleFunc)}, nil
+}
+
+type module struct {
+	newExports ModuleFunc
+}
+
+func (m *module) Instantiate(imports []wasm.HostFunction) (wasm.Instance, error) {
+	g := &Guest{
+		memory:  make([]byte, memorySize),
+		heap:    8,
+		imports: make(map[string]wasm.HostFunction, len(imports)),
+	}
+	for _, fn := range imports {
+		g.imports[fn.Name] = fn
+	}
+	g.exports = m.newExports(g)
+	if _, ok := g.exports["malloc"]; !ok {
+		g.exports["malloc"] = func(args ...uint64) uint64 {
+			return g.Alloc(int(args[0]))
+		}
+	}
+	return g, nil
+}
+
+func (m *module) Close() error {
+	return nil
+}
+
+// Guest is a fake module instance, it implements wasm.Instance for the host,
+// and provides the helpers for the fake module to call the host functions
+type Guest struct {
+	mu      sync.Mutex
+	memory  []byte
+	heap    uint64
+	imports map[string]wasm.HostFunction
+	exports Exports
+	closed  bool
+}
+
+func (g *Guest) HasFunction(name string) bool {
+	_, ok := g.exports[name]
+	return ok
+}
+
+func (g *Guest) Call(name string, args ...uint64) (uint64, error) {
+	f, ok := g.exports[name]
+	if !ok {
+		return 0, wasm.ErrFunctionNotFound
+	}
+	return f(args...), nil
+}
+
+func (g *Guest) Memory() []byte {
+	return g.memory
+}
+
+func (g *Guest) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+	return nil
+}
+
+// Closed returns whether the instance is closed
+func (g *Guest) Closed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.closed
+}
+
+// Alloc allocates the memory, the memory is never freed
+func (g *Guest) Alloc(size int) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	addr := g.heap
+	g.heap += uint64(size+7) &^ 7
+	if g.heap > uint64(len(g.memory)) {
+		panic("wasmtest: out of memory")
+	}
+	return addr
+}
+
+// Write allocates the memory and writes the data
+func (g *Guest) Write(data []byte) (ptr, size uint64) {
+	ptr = g.Alloc(len(data))
+	copy(g.memory[ptr:], data)
+	return ptr, uint64(len(data))
+}
+
+// Read reads the memory
+func (g *Guest) Read(ptr, size uint64) []byte {
+	data := make([]byte, size)
+	copy(data, g.memory[ptr:ptr+size])
+	return data
+}
+
+func (g *Guest) ReadUint32(ptr uint64) uint32 {
+	return binary.LittleEndian.Uint32(g.memory[ptr:])
+}
+
+func (g *Guest) ReadUint64(ptr uint64) uint64 {
+	return binary.LittleEndian.Uint64(g.memory[ptr:])
+}
+
+// Host calls the host function and returns the result
+func (g *Guest) Host(name string, args ...uint64) int32 {
+	fn, ok := g.imports[name]
+	if !ok {
+		panic("wasmtest: host function is not imported: " + name)
+	}
+	if len(args) != len(fn.Params) {
+		panic(fmt.Sprintf("wasmtest: host function %s expects %d params, but got %d", name, len(fn.Params), len(args)))
+	}
+	return int32(fn.Func(args))
+}
+
+// HostReturnBytes calls the host function that returns the bytes by the last two pointer params
+func (g *Guest) HostReturnBytes(name string, args ...uint64) ([]byte, int32) {
+	retPtr := g.Alloc(8)
+	result := g.Host(name, append(args, retPtr, retPtr+4)...)
+	if result != 0 {
+		return nil, result
+	}
+	return g.Read(uint64(g.ReadUint32(retPtr)), uint64(g.ReadUint32(retPtr+4))), 0
+}
+
+// WriteString is Write for the string
+func (g *Guest) WriteString(s string) (ptr, size uint64) {
+	return g.Write([]byte(s))
+}
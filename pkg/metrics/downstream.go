@@ -41,6 +41,11 @@ const (
 	DownstreamProcessTime        = "process_time"
 	DownstreamProcessTimeTotal   = "process_time_total"
 	DownstreamRequestFailed      = "request_failed"
+	// retried request landed on a host that has been attempted
+	DownstreamRequestRetrySameHost = "request_retry_same_host"
+	// requests mirrored to the shadow clusters
+	DownstreamMirrorRequestTotal  = "mirror_request_total"
+	DownstreamMirrorRequestFailed = "mirror_request_failed"
 )
 
 // NewProxyStats returns a stats with namespace prefix proxy
@@ -0,0 +1,144 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// maxHistogramBuckets limits the bucket count, each bucket takes one entry in the shm zone
+const maxHistogramBuckets = 64
+
+// DefaultHistogramBuckets is the default upper bounds of histograms, in nanoseconds,
+// it covers the latency from 500us to 10s.
+var DefaultHistogramBuckets = []int64{
+	int64(500 * time.Microsecond),
+	int64(time.Millisecond),
+	int64(2500 * time.Microsecond),
+	int64(5 * time.Millisecond),
+	int64(10 * time.Millisecond),
+	int64(25 * time.Millisecond),
+	int64(50 * time.Millisecond),
+	int64(100 * time.Millisecond),
+	int64(250 * time.Millisecond),
+	int64(500 * time.Millisecond),
+	int64(time.Second),
+	int64(2500 * time.Millisecond),
+	int64(5 * time.Second),
+	int64(10 * time.Second),
+}
+
+var (
+	histogramBuckets      = DefaultHistogramBuckets
+	histogramBucketsMutex sync.RWMutex
+
+	errInvalidBuckets = errors.New("histogram buckets must be positive, ascending and no more than 64")
+)
+
+// BucketHistogram is implemented by the histograms with a fixed bucket layout,
+// sinks can use it to export the whole distribution.
+type BucketHistogram interface {
+	gometrics.Histogram
+
+	// Buckets returns the upper bounds and the per-bucket (non-cumulative) counts,
+	// the last count belongs to the +Inf bucket.
+	Buckets() (bounds []int64, counts []int64)
+}
+
+// SetHistogramBuckets sets the bucket upper bounds of the histograms created afterwards.
+func SetHistogramBuckets(bounds []int64) error {
+	if !validHistogramBuckets(bounds) {
+		return errInvalidBuckets
+	}
+	histogramBucketsMutex.Lock()
+	histogramBuckets = bounds
+	histogramBucketsMutex.Unlock()
+	return nil
+}
+
+func validHistogramBuckets(bounds []int64) bool {
+	if len(bounds) == 0 || len(bounds) > maxHistogramBuckets {
+		return false
+	}
+	for i := range bounds {
+		if bounds[i] <= 0 || (i > 0 && bounds[i] <= bounds[i-1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetHistogramBuckets returns the bucket upper bounds used by new histograms.
+func GetHistogramBuckets() []int64 {
+	histogramBucketsMutex.RLock()
+	defer histogramBucketsMutex.RUnlock()
+	return histogramBuckets
+}
+
+// LinearBuckets returns count buckets, each width wide, the first upper bound is start.
+func LinearBuckets(start, width int64, count int) []int64 {
+	bounds := make([]int64, count)
+	for i := range bounds {
+		bounds[i] = start + int64(i)*width
+	}
+	return bounds
+}
+
+// ExponentialBuckets returns count buckets, the first upper bound is start,
+// and each following one is factor times of the previous one.
+func ExponentialBuckets(start int64, factor float64, count int) []int64 {
+	bounds := make([]int64, 0, count)
+	for v := float64(start); len(bounds) < count; v *= factor {
+		bounds = append(bounds, int64(v))
+	}
+	return bounds
+}
+
+// LogLinearBuckets returns the HDR-like log-linear layout from min to max:
+// every power of ten is split into subBuckets linear buckets, so the relative
+// error stays about the same across the whole range.
+// e.g. min=1ms, max=1s, subBuckets=2: 1ms 5.5ms 10ms 55ms 100ms 550ms 1s
+func LogLinearBuckets(min, max int64, subBuckets int) []int64 {
+	if min <= 0 || max < min || subBuckets <= 0 {
+		return nil
+	}
+	base := int64(1)
+	for base*10 <= min {
+		base *= 10
+	}
+	bounds := []int64{}
+	for {
+		step := base * 9 / int64(subBuckets)
+		if step == 0 {
+			step = 1
+		}
+		for v := base; v < base*10; v += step {
+			if v >= min {
+				bounds = append(bounds, v)
+			}
+			if v >= max {
+				return bounds
+			}
+		}
+		base *= 10
+	}
+}
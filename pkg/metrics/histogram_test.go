@@ -0,0 +1,112 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/metrics/shm"
+)
+
+func TestBucketsGenerator(t *testing.T) {
+	if b := LinearBuckets(10, 5, 3); !reflect.DeepEqual(b, []int64{10, 15, 20}) {
+		t.Errorf("unexpected linear buckets %v", b)
+	}
+	if b := ExponentialBuckets(1, 2, 4); !reflect.DeepEqual(b, []int64{1, 2, 4, 8}) {
+		t.Errorf("unexpected exponential buckets %v", b)
+	}
+	ms := int64(time.Millisecond)
+	expected := []int64{ms, 5500000, 10 * ms, 55 * ms, 100 * ms, 550 * ms, 1000 * ms}
+	if b := LogLinearBuckets(ms, int64(time.Second), 2); !reflect.DeepEqual(b, expected) {
+		t.Errorf("unexpected log-linear buckets %v", b)
+	}
+	if b := LogLinearBuckets(3, 30, 9); !reflect.DeepEqual(b, []int64{3, 4, 5, 6, 7, 8, 9, 10, 20, 30}) {
+		t.Errorf("unexpected log-linear buckets %v", b)
+	}
+	if b := LogLinearBuckets(0, 30, 9); b != nil {
+		t.Errorf("expected nil buckets for invalid range, but got %v", b)
+	}
+}
+
+func TestSetHistogramBuckets(t *testing.T) {
+	defer SetHistogramBuckets(DefaultHistogramBuckets)
+
+	for _, bounds := range [][]int64{
+		nil,
+		{0, 10},
+		{10, 10},
+		{20, 10},
+		LinearBuckets(1, 1, maxHistogramBuckets+1),
+	} {
+		if err := SetHistogramBuckets(bounds); err == nil {
+			t.Errorf("expected error for buckets %v", bounds)
+		}
+	}
+
+	zone := shm.InitMetricsZone("TestSetHistogramBuckets", 10*1024)
+	defer func() {
+		zone.Detach()
+		shm.Reset()
+	}()
+	ResetAll()
+
+	if err := SetHistogramBuckets([]int64{10, 100}); err != nil {
+		t.Fatal(err)
+	}
+	m, _ := NewMetrics("TestSetHistogramBuckets", map[string]string{"lk": "lv"})
+	h, ok := m.Histogram("latency").(BucketHistogram)
+	if !ok {
+		t.Fatal("histogram is not bucketed")
+	}
+	h.Update(50)
+	bounds, counts := h.Buckets()
+	if !reflect.DeepEqual(bounds, []int64{10, 100}) || !reflect.DeepEqual(counts, []int64{0, 1, 0}) {
+		t.Errorf("unexpected buckets %v %v", bounds, counts)
+	}
+}
+
+func TestHistogramWithBuckets(t *testing.T) {
+	zone := shm.InitMetricsZone("TestHistogramWithBuckets", 10*1024)
+	defer func() {
+		zone.Detach()
+		shm.Reset()
+	}()
+	ResetAll()
+
+	m, _ := NewMetrics("TestHistogramWithBuckets", map[string]string{"lk": "lv"})
+	h, ok := m.HistogramWithBuckets("size", []int64{1024, 4096}).(BucketHistogram)
+	if !ok {
+		t.Fatal("histogram is not bucketed")
+	}
+	h.Update(2048)
+	bounds, counts := h.Buckets()
+	if !reflect.DeepEqual(bounds, []int64{1024, 4096}) || !reflect.DeepEqual(counts, []int64{0, 1, 0}) {
+		t.Errorf("unexpected buckets %v %v", bounds, counts)
+	}
+	// invalid bounds, uses the default buckets
+	fallback, ok := m.HistogramWithBuckets("fallback", []int64{4096, 1024}).(BucketHistogram)
+	if !ok {
+		t.Fatal("histogram with invalid bounds is not bucketed")
+	}
+	fallback.Update(2048)
+	if bounds, _ := fallback.Buckets(); !reflect.DeepEqual(bounds, GetHistogramBuckets()) || fallback.Count() != 1 {
+		t.Errorf("unexpected fallback histogram, bounds %v, count %d", bounds, fallback.Count())
+	}
+}
@@ -49,6 +49,10 @@ func (m *NilMetrics) Histogram(key string) gometrics.Histogram {
 	return gometrics.NilHistogram{}
 }
 
+func (m *NilMetrics) HistogramWithBuckets(key string, bounds []int64) gometrics.Histogram {
+	return gometrics.NilHistogram{}
+}
+
 func (m *NilMetrics) Each(f func(string, interface{})) {
 	// do nothing
 }
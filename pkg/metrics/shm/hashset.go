@@ -212,5 +212,6 @@ func (s *hashSet) Free(entry *hashEntry) {
 
 		entry.next = s.meta.freeIndex
 		s.meta.freeIndex = index
+		s.meta.size--
 	}
 }
@@ -152,3 +152,35 @@ func BenchmarkHashSet_Free(b *testing.B) {
 	}
 	b.StopTimer()
 }
+
+func TestHashSet_FreeSize(t *testing.T) {
+	zone := InitMetricsZone("TestHashSet_FreeSize", 10*1024)
+	defer func() {
+		zone.Detach()
+		Reset()
+	}()
+
+	// alloc until the hash set is full
+	var entries []*hashEntry
+	for i := 0; ; i++ {
+		entry, err := defaultZone.alloc("testEntry" + strconv.Itoa(i))
+		if err != nil {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		t.Fatal("no entry allocated")
+	}
+	size := defaultZone.set.meta.size
+
+	defaultZone.free(entries[0])
+	if defaultZone.set.meta.size != size-1 {
+		t.Errorf("size is not decreased after free, size %d", defaultZone.set.meta.size)
+	}
+
+	// the freed entry can be reused by a new name
+	if _, err := defaultZone.alloc("testEntryNew"); err != nil {
+		t.Errorf("alloc after free failed: %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package shm
+
+import (
+	"math"
+	"strconv"
+	"sync/atomic"
+	"unsafe"
+
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// ShmHistogram is a fixed-bucket histogram, each bucket, the sum, the count and
+// the min/max are kept in their own metrics entry, so the distribution is shared
+// between processes and survives a hot upgrade just like counters and gauges.
+//
+// Values less than zero are recorded as zero.
+type ShmHistogram struct {
+	bounds  []int64      // sorted upper bounds (inclusive)
+	buckets []ShmCounter // len(bounds) + 1, the last one is the +Inf bucket
+	count   ShmCounter
+	sum     ShmCounter
+	min     ShmCounter // stores math.MaxInt64 - min, so zero means no value
+	max     ShmCounter
+	entries []*hashEntry
+	heap    []int64 // keeps the fallback values reachable, counters are only uintptr
+}
+
+// NewShmHistogramFunc returns a histogram constructor with the given bucket upper bounds.
+// The bounds are encoded into the entry names, so processes configured with different
+// layouts never mix their buckets.
+func NewShmHistogramFunc(name string, bounds []int64) func() gometrics.Histogram {
+	return func() gometrics.Histogram {
+		if defaultZone != nil {
+			if h, err := newZoneHistogram(defaultZone, name, bounds); err == nil {
+				return h
+			}
+		} else if fallback {
+			return newHeapHistogram(bounds)
+		}
+		return gometrics.NilHistogram{}
+	}
+}
+
+func newZoneHistogram(z *zone, name string, bounds []int64) (*ShmHistogram, error) {
+	h := &ShmHistogram{
+		bounds:  bounds,
+		entries: make([]*hashEntry, 0, len(bounds)+5),
+	}
+	alloc := func(suffix string) (ShmCounter, error) {
+		entry, err := z.alloc(name + suffix)
+		if err != nil {
+			return 0, err
+		}
+		h.entries = append(h.entries, entry)
+		return ShmCounter(unsafe.Pointer(&entry.value)), nil
+	}
+	var err error
+	h.buckets = make([]ShmCounter, len(bounds)+1)
+	for i := range bounds {
+		if h.buckets[i], err = alloc(".le_" + strconv.FormatInt(bounds[i], 10)); err != nil {
+			h.release(z)
+			return nil, err
+		}
+	}
+	for _, c := range []struct {
+		counter *ShmCounter
+		suffix  string
+	}{
+		{&h.buckets[len(bounds)], ".le_inf"},
+		{&h.count, ".count"},
+		{&h.sum, ".sum"},
+		{&h.min, ".min"},
+		{&h.max, ".max"},
+	} {
+		if *c.counter, err = alloc(c.suffix); err != nil {
+			h.release(z)
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// newHeapHistogram keeps the values in process memory, it is used when no zone is initialized.
+func newHeapHistogram(bounds []int64) *ShmHistogram {
+	values := make([]int64, len(bounds)+5)
+	h := &ShmHistogram{
+		bounds:  bounds,
+		buckets: make([]ShmCounter, len(bounds)+1),
+		heap:    values,
+	}
+	for i := range h.buckets {
+		h.buckets[i] = ShmCounter(unsafe.Pointer(&values[i]))
+	}
+	n := len(h.buckets)
+	h.count = ShmCounter(unsafe.Pointer(&values[n]))
+	h.sum = ShmCounter(unsafe.Pointer(&values[n+1]))
+	h.min = ShmCounter(unsafe.Pointer(&values[n+2]))
+	h.max = ShmCounter(unsafe.Pointer(&values[n+3]))
+	return h
+}
+
+func (h *ShmHistogram) release(z *zone) {
+	for _, entry := range h.entries {
+		z.free(entry)
+	}
+	h.entries = nil
+}
+
+// Update records a value.
+func (h *ShmHistogram) Update(v int64) {
+	if v < 0 {
+		v = 0
+	}
+	// the bucket count is small, a linear scan is faster than binary search
+	i := 0
+	for ; i < len(h.bounds); i++ {
+		if v <= h.bounds[i] {
+			break
+		}
+	}
+	h.buckets[i].Inc(1)
+	h.sum.Inc(v)
+	h.count.Inc(1)
+	storeMax((*int64)(unsafe.Pointer(h.max)), v)
+	storeMax((*int64)(unsafe.Pointer(h.min)), math.MaxInt64-v)
+}
+
+func storeMax(addr *int64, v int64) {
+	for {
+		old := atomic.LoadInt64(addr)
+		if v <= old || atomic.CompareAndSwapInt64(addr, old, v) {
+			return
+		}
+	}
+}
+
+// Clear resets all the buckets.
+func (h *ShmHistogram) Clear() {
+	for _, b := range h.buckets {
+		b.Clear()
+	}
+	h.count.Clear()
+	h.sum.Clear()
+	h.min.Clear()
+	h.max.Clear()
+}
+
+// Snapshot returns a read-only copy of the histogram.
+func (h *ShmHistogram) Snapshot() gometrics.Histogram {
+	s := &HistogramSnapshot{
+		bounds: h.bounds,
+		counts: make([]int64, len(h.buckets)),
+		count:  h.count.Count(),
+		sum:    h.sum.Count(),
+		min:    h.Min(),
+		max:    h.max.Count(),
+	}
+	for i, b := range h.buckets {
+		s.counts[i] = b.Count()
+	}
+	return s
+}
+
+// Min returns the minimal recorded value, or zero if there is none.
+func (h *ShmHistogram) Min() int64 {
+	if min := h.min.Count(); min != 0 {
+		return math.MaxInt64 - min
+	}
+	return 0
+}
+
+// Buckets returns the upper bounds and the per-bucket (non-cumulative) counts,
+// the last count belongs to the +Inf bucket.
+func (h *ShmHistogram) Buckets() ([]int64, []int64) {
+	return h.Snapshot().(*HistogramSnapshot).Buckets()
+}
+
+func (h *ShmHistogram) Count() int64                       { return h.count.Count() }
+func (h *ShmHistogram) Sum() int64                         { return h.sum.Count() }
+func (h *ShmHistogram) Max() int64                         { return h.max.Count() }
+func (h *ShmHistogram) Mean() float64                      { return h.Snapshot().Mean() }
+func (h *ShmHistogram) StdDev() float64                    { return h.Snapshot().StdDev() }
+func (h *ShmHistogram) Variance() float64                  { return h.Snapshot().Variance() }
+func (h *ShmHistogram) Percentile(p float64) float64       { return h.Snapshot().Percentile(p) }
+func (h *ShmHistogram) Percentiles(ps []float64) []float64 { return h.Snapshot().Percentiles(ps) }
+
+// Sample is not supported, bucketed histograms keep no raw values.
+func (h *ShmHistogram) Sample() gometrics.Sample { return gometrics.NilSample{} }
+
+// stoppable
+func (h *ShmHistogram) Stop() {
+	if defaultZone != nil {
+		h.release(defaultZone)
+	}
+}
+
+// HistogramSnapshot is a read-only copy of a ShmHistogram.
+type HistogramSnapshot struct {
+	bounds []int64
+	counts []int64
+	count  int64
+	sum    int64
+	min    int64
+	max    int64
+}
+
+// Buckets returns the upper bounds and the per-bucket (non-cumulative) counts,
+// the last count belongs to the +Inf bucket.
+func (s *HistogramSnapshot) Buckets() ([]int64, []int64) {
+	return s.bounds, s.counts
+}
+
+func (s *HistogramSnapshot) Count() int64 { return s.count }
+func (s *HistogramSnapshot) Sum() int64   { return s.sum }
+func (s *HistogramSnapshot) Min() int64   { return s.min }
+func (s *HistogramSnapshot) Max() int64   { return s.max }
+
+func (s *HistogramSnapshot) Mean() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return float64(s.sum) / float64(s.count)
+}
+
+// Variance is estimated with the middle of each bucket, the +Inf bucket uses the max value.
+func (s *HistogramSnapshot) Variance() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	mean := s.Mean()
+	var sum float64
+	for i, c := range s.counts {
+		if c == 0 {
+			continue
+		}
+		lower, upper := s.bucketRange(i)
+		d := (lower+upper)/2 - mean
+		sum += d * d * float64(c)
+	}
+	return sum / float64(s.count)
+}
+
+func (s *HistogramSnapshot) StdDev() float64 { return math.Sqrt(s.Variance()) }
+
+// Percentile is estimated by linear interpolation inside the bucket that holds the rank.
+func (s *HistogramSnapshot) Percentile(p float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	rank := p * float64(s.count)
+	var acc float64
+	for i, c := range s.counts {
+		if c == 0 {
+			continue
+		}
+		if acc+float64(c) >= rank {
+			lower, upper := s.bucketRange(i)
+			return lower + (upper-lower)*(rank-acc)/float64(c)
+		}
+		acc += float64(c)
+	}
+	return float64(s.max)
+}
+
+func (s *HistogramSnapshot) Percentiles(ps []float64) []float64 {
+	values := make([]float64, len(ps))
+	for i, p := range ps {
+		values[i] = s.Percentile(p)
+	}
+	return values
+}
+
+// bucketRange returns the value range of the bucket i, clamped by the recorded min and max.
+func (s *HistogramSnapshot) bucketRange(i int) (lower, upper float64) {
+	lower, upper = float64(s.min), float64(s.max)
+	if i > 0 && float64(s.bounds[i-1]) > lower {
+		lower = float64(s.bounds[i-1])
+	}
+	if i < len(s.bounds) && float64(s.bounds[i]) < upper {
+		upper = float64(s.bounds[i])
+	}
+	if upper < lower {
+		upper = lower
+	}
+	return
+}
+
+func (s *HistogramSnapshot) Sample() gometrics.Sample      { return gometrics.NilSample{} }
+func (s *HistogramSnapshot) Snapshot() gometrics.Histogram { return s }
+func (s *HistogramSnapshot) Clear()                        { panic("Clear called on a HistogramSnapshot") }
+func (s *HistogramSnapshot) Update(int64)                  { panic("Update called on a HistogramSnapshot") }
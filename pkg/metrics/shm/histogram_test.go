@@ -0,0 +1,136 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package shm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	zone := InitMetricsZone("TestHistogram", 10*1024)
+	defer func() {
+		zone.Detach()
+		Reset()
+	}()
+
+	bounds := []int64{10, 100, 1000}
+	h := NewShmHistogramFunc("TestHistogram", bounds)().(*ShmHistogram)
+	for _, v := range []int64{-1, 5, 10, 50, 500, 5000} {
+		h.Update(v)
+	}
+
+	gotBounds, counts := h.Buckets()
+	if len(gotBounds) != 3 || len(counts) != 4 {
+		t.Fatalf("unexpected buckets: %v %v", gotBounds, counts)
+	}
+	// -1 is recorded as 0
+	expected := []int64{3, 1, 1, 1}
+	for i := range expected {
+		if counts[i] != expected[i] {
+			t.Errorf("bucket %d expected %d, but got %d", i, expected[i], counts[i])
+		}
+	}
+	if h.Count() != 6 || h.Sum() != 5565 {
+		t.Errorf("unexpected count %d or sum %d", h.Count(), h.Sum())
+	}
+	if h.Min() != 0 || h.Max() != 5000 {
+		t.Errorf("unexpected min %d or max %d", h.Min(), h.Max())
+	}
+
+	// the median is in the first bucket, p99 is in the +Inf bucket
+	snapshot := h.Snapshot()
+	if p := snapshot.Percentile(0.5); p < 0 || p > 10 {
+		t.Errorf("unexpected p50 %f", p)
+	}
+	if p := snapshot.Percentile(0.99); p <= 1000 || p > 5000 {
+		t.Errorf("unexpected p99 %f", p)
+	}
+	if math.Abs(snapshot.Mean()-927.5) > 0.01 {
+		t.Errorf("unexpected mean %f", snapshot.Mean())
+	}
+
+	h.Clear()
+	if h.Count() != 0 || h.Min() != 0 || h.Max() != 0 {
+		t.Error("clear failed")
+	}
+}
+
+func TestHistogramShared(t *testing.T) {
+	zone := InitMetricsZone("TestHistogramShared", 10*1024)
+	defer func() {
+		zone.Detach()
+		Reset()
+	}()
+
+	bounds := []int64{10, 100}
+	// the same name with the same bounds shares the entries, like the new process in hot upgrade
+	h1 := NewShmHistogramFunc("shared", bounds)().(*ShmHistogram)
+	h2 := NewShmHistogramFunc("shared", bounds)().(*ShmHistogram)
+	h1.Update(1)
+	h2.Update(50)
+	if h1.Count() != 2 || h2.Sum() != 51 || h2.Min() != 1 {
+		t.Errorf("histogram is not shared, count %d sum %d min %d", h1.Count(), h2.Sum(), h2.Min())
+	}
+
+	// a different layout never mixes the buckets
+	h3 := NewShmHistogramFunc("shared", []int64{20, 100})().(*ShmHistogram)
+	if _, counts := h3.Buckets(); counts[0] != 0 || counts[1] != 1 {
+		t.Errorf("unexpected buckets %v", counts)
+	}
+
+	// entries are released after all references stopped
+	size := zone.set.meta.size
+	h1.Stop()
+	if zone.set.meta.size != size {
+		t.Error("entries released with references")
+	}
+	// h3 still holds the shared entries, only the '10' bucket is released
+	h2.Stop()
+	if zone.set.meta.size != size-1 {
+		t.Errorf("bucket entries are not released, size %d", zone.set.meta.size)
+	}
+}
+
+func TestHistogramAllocFailed(t *testing.T) {
+	zone := InitMetricsZone("TestHistogramAllocFailed", 4*1024)
+	defer func() {
+		zone.Detach()
+		Reset()
+	}()
+
+	bounds := make([]int64, 64)
+	for i := range bounds {
+		bounds[i] = int64(i + 1)
+	}
+	if _, ok := NewShmHistogramFunc("TestHistogramAllocFailed", bounds)().(*ShmHistogram); ok {
+		t.Fatal("expected a nil histogram if the zone is full")
+	}
+	if zone.set.meta.size != 0 {
+		t.Errorf("allocated entries are not released, size %d", zone.set.meta.size)
+	}
+}
+
+func TestHistogramFallback(t *testing.T) {
+	h := NewShmHistogramFunc("TestHistogramFallback", []int64{10})()
+	h.Update(5)
+	h.Update(20)
+	if h.Count() != 2 || h.Min() != 5 || h.Max() != 20 {
+		t.Errorf("fallback histogram failed, count %d min %d max %d", h.Count(), h.Min(), h.Max())
+	}
+}
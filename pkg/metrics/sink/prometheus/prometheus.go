@@ -126,6 +126,9 @@ func (psink *promSink) Flush(writer io.Writer, ms []types.Metrics) {
 }
 
 func (psink *promSink) flushHistogram(tracker map[string]bool, buf types.IoBuffer, name string, labels string, snapshot gometrics.Histogram) {
+	if h, ok := snapshot.(metrics.BucketHistogram); ok {
+		psink.flushBuckets(tracker, buf, name, labels, h)
+	}
 	// min
 	psink.flushGauge(tracker, buf, name+"_min", labels, float64(snapshot.Min()))
 	// max
@@ -133,6 +136,53 @@ func (psink *promSink) flushHistogram(tracker map[string]bool, buf types.IoBuffe
 	// TODO: flush P90 P95 P99 if configured
 }
 
+// flushBuckets writes a real prometheus histogram, the buckets are cumulative
+func (psink *promSink) flushBuckets(tracker map[string]bool, buf types.IoBuffer, name string, labels string, h metrics.BucketHistogram) {
+	// type
+	if !tracker[name] {
+		buf.WriteString("# TYPE ")
+		buf.WriteString(name)
+		buf.WriteString(" histogram\n")
+		tracker[name] = true
+	}
+	bucketLabels := "le=\""
+	if labels != "" {
+		bucketLabels = labels + ",le=\""
+	}
+	bounds, counts := h.Buckets()
+	var cumulative int64
+	for i, c := range counts {
+		cumulative += c
+		buf.WriteString(name)
+		buf.WriteString("_bucket{")
+		buf.WriteString(bucketLabels)
+		if i < len(bounds) {
+			writeFloat(buf, float64(bounds[i]))
+		} else {
+			buf.WriteString("+Inf")
+		}
+		buf.WriteString("\"} ")
+		writeFloat(buf, float64(cumulative))
+		buf.WriteString("\n")
+	}
+	// sum and count
+	for _, m := range []struct {
+		suffix string
+		val    int64
+	}{
+		{"_sum", h.Sum()},
+		{"_count", cumulative},
+	} {
+		buf.WriteString(name)
+		buf.WriteString(m.suffix)
+		buf.WriteString("{")
+		buf.WriteString(labels)
+		buf.WriteString("} ")
+		writeFloat(buf, float64(m.val))
+		buf.WriteString("\n")
+	}
+}
+
 func (psink *promSink) flushGauge(tracker map[string]bool, buf types.IoBuffer, name string, labels string, val float64) {
 	// type
 	if !tracker[name] {
@@ -145,6 +145,21 @@ func TestPrometheusMetrics(t *testing.T) {
 	if !bytes.Contains(body, []byte("t1_k4_min{lbk2=\"lbv2\"} 2.0")) {
 		t.Error("t1_k4_min{lbk2=\"lbv2\"} metric not correct")
 	}
+
+	// histogram buckets are cumulative
+	for _, line := range []string{
+		"# TYPE t1_k4 histogram",
+		"t1_k4_bucket{lbk1=\"lbv1\",le=\"500000.0\"} 4.0",
+		"t1_k4_bucket{lbk1=\"lbv1\",le=\"1e+10\"} 4.0",
+		"t1_k4_bucket{lbk1=\"lbv1\",le=\"+Inf\"} 4.0",
+		"t1_k4_sum{lbk1=\"lbv1\"} 10.0",
+		"t1_k4_count{lbk1=\"lbv1\"} 4.0",
+		"t1_k4_count{lbk2=\"lbv2\"} 1.0",
+	} {
+		if !bytes.Contains(body, []byte(line)) {
+			t.Errorf("%s metric not found", line)
+		}
+	}
 }
 
 func TestPrometheusMetricsFilter(t *testing.T) {
@@ -25,6 +25,7 @@ import (
 	"sort"
 
 	gometrics "github.com/rcrowley/go-metrics"
+	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/metrics/shm"
 	"mosn.io/mosn/pkg/types"
 )
@@ -171,8 +172,20 @@ func (s *metrics) Histogram(key string) gometrics.Histogram {
 		return gometrics.NilHistogram{}
 	}
 
-	// TODO: notice the histogram only keeps 100 values as we set
-	return s.registry.GetOrRegister(key, func() gometrics.Histogram { return gometrics.NewHistogram(gometrics.NewUniformSample(100)) }).(gometrics.Histogram)
+	return s.registry.GetOrRegister(key, shm.NewShmHistogramFunc(s.fullName(key), GetHistogramBuckets())).(gometrics.Histogram)
+}
+
+func (s *metrics) HistogramWithBuckets(key string, bounds []int64) gometrics.Histogram {
+	// support exclusion only
+	if defaultStore.matcher.isExclusionKey(key) {
+		return gometrics.NilHistogram{}
+	}
+
+	if !validHistogramBuckets(bounds) {
+		log.DefaultLogger.Errorf("[metrics] invalid histogram buckets %v of %s, use the default buckets", bounds, s.fullName(key))
+		bounds = GetHistogramBuckets()
+	}
+	return s.registry.GetOrRegister(key, shm.NewShmHistogramFunc(s.fullName(key), bounds)).(gometrics.Histogram)
 }
 
 func (s *metrics) Each(f func(string, interface{})) {
@@ -36,6 +36,7 @@ const (
 	UpstreamConnectionLocalCloseWithActiveRequest  = "connection_local_close_with_active_request"
 	UpstreamConnectionRemoteCloseWithActiveRequest = "connection_remote_close_with_active_request"
 	UpstreamConnectionCloseNotify                  = "connection_close_notify"
+	UpstreamConnectionConnectDuration              = "connection_connect_duration_time"
 	UpstreamRequestTotal                           = "request_total"
 	UpstreamRequestActive                          = "request_active"
 	UpstreamRequestLocalReset                      = "request_local_reset"
@@ -55,12 +56,24 @@ const (
 	UpstreamRequestRetryOverflow = "request_retry_overflow"
 	UpstreamLBSubSetsFallBack    = "lb_subsets_fallback"
 	UpstreamLBSubsetsCreated     = "lb_subsets_created"
+	UpstreamLBHealthyPanic       = "lb_healthy_panic"
 	UpstreamBytesReadTotal       = "connection_bytes_read_total"
 	UpstreamBytesReadBuffered    = "connection_bytes_read_buffered"
 	UpstreamBytesWriteTotal      = "connection_bytes_write"
 	UpstreamBytesWriteBuffered   = "connection_bytes_write_buffered"
 )
 
+// key in cluster, outlier detection
+const (
+	UpstreamOutlierEjectionsActive                    = "outlier_ejections_active"
+	UpstreamOutlierEjectionsTotal                     = "outlier_ejections_total"
+	UpstreamOutlierEjectionsOverflow                  = "outlier_ejections_overflow"
+	UpstreamOutlierEjectionsConsecutive5xx            = "outlier_ejections_consecutive_5xx"
+	UpstreamOutlierEjectionsConsecutiveGatewayFailure = "outlier_ejections_consecutive_gateway_failure"
+	UpstreamOutlierEjectionsSuccessRate               = "outlier_ejections_success_rate"
+	UpstreamOutlierEjectionsFailurePercentage         = "outlier_ejections_failure_percentage"
+)
+
 // NewHostStats returns a stats that namespace contains cluster and host address
 func NewHostStats(clusterName string, addr string) types.Metrics {
 	metrics, _ := NewMetrics(UpstreamType, map[string]string{"cluster": clusterName, "host": addr})
@@ -328,6 +328,12 @@ func initializeMetrics(config v2.MetricsConfig) {
 	// set metrics package
 	statsMatcher := config.StatsMatcher
 	metrics.SetStatsMatcher(statsMatcher.RejectAll, statsMatcher.ExclusionLabels, statsMatcher.ExclusionKeys)
+	// set histogram buckets before any stats is created
+	if config.Histogram != nil {
+		if err := metrics.SetHistogramBuckets(histogramBuckets(config.Histogram)); err != nil {
+			log.StartLogger.Errorf("[mosn] [init metrics] %v, use the default histogram buckets", err)
+		}
+	}
 	// create sinks
 	for _, cfg := range config.SinkConfigs {
 		_, err := sink.CreateMetricsSink(cfg.Type, cfg.Config)
@@ -340,6 +346,17 @@ func initializeMetrics(config v2.MetricsConfig) {
 	}
 }
 
+func histogramBuckets(config *v2.HistogramConfig) []int64 {
+	if len(config.Buckets) == 0 {
+		return metrics.LogLinearBuckets(int64(config.Min.Duration), int64(config.Max.Duration), config.SubBuckets)
+	}
+	bounds := make([]int64, 0, len(config.Buckets))
+	for _, b := range config.Buckets {
+		bounds = append(bounds, int64(b.Duration))
+	}
+	return bounds
+}
+
 func initializePidFile(pid string) {
 	keeper.SetPid(pid)
 }
@@ -96,6 +96,17 @@ func (r *Request) GetData() types.IoBuffer {
 	return r.Content
 }
 
+// Clone returns a deep copy of the request.
+// The raw data is not copied, so the cloned request will be encoded from scratch.
+func (r *Request) Clone() types.HeaderMap {
+	clone := &Request{}
+	clone.RequestHeader = *r.RequestHeader.Clone().(*RequestHeader)
+	if r.Content != nil {
+		clone.Content = r.Content.Clone()
+	}
+	return clone
+}
+
 // RequestHeader is the header part of bolt v1 response
 type ResponseHeader struct {
 	Protocol       byte // meta fields
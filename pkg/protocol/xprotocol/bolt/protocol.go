@@ -158,7 +158,7 @@ func (proto *boltProtocol) Mapping(httpStatusCode uint32) uint32 {
 		return uint32(ResponseStatusNoProcessor)
 	case types.NoHealthUpstreamCode:
 		return uint32(ResponseStatusConnectionClosed)
-	case types.UpstreamOverFlowCode:
+	case types.UpstreamOverFlowCode, http.StatusTooManyRequests:
 		return uint32(ResponseStatusServerThreadpoolBusy)
 	case types.CodecExceptionCode:
 		//Decode or Encode Error
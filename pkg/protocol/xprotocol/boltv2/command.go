@@ -75,6 +75,18 @@ func (r *Request) GetData() types.IoBuffer {
 	return r.Content
 }
 
+// Clone returns a deep copy of the request.
+// The raw data is not copied, so the cloned request will be encoded from scratch.
+func (r *Request) Clone() types.HeaderMap {
+	clone := &Request{}
+	clone.RequestHeader = r.RequestHeader
+	clone.RequestHeader.Header = *r.RequestHeader.Header.Clone()
+	if r.Content != nil {
+		clone.Content = r.Content.Clone()
+	}
+	return clone
+}
+
 type ResponseHeader struct {
 	bolt.ResponseHeader
 	Version1   byte //00
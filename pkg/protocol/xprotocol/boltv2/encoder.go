@@ -28,13 +28,11 @@ import (
 )
 
 func encodeRequest(ctx context.Context, request *Request) (types.IoBuffer, error) {
-	// 1. fast-path, use existed raw data
-	if request.rawData != nil {
+	// 1. fast-path, use existed raw data if the header is not mutated
+	if request.rawData != nil && !request.Header.Changed {
 		// 1. replace requestId
 		binary.BigEndian.PutUint32(request.rawMeta[RequestIdIndex:], request.RequestId)
 
-		// 2. TODO: header mutate
-
 		return request.Data, nil
 	}
 
@@ -44,6 +42,7 @@ func encodeRequest(ctx context.Context, request *Request) (types.IoBuffer, error
 	if request.Class != "" {
 		request.ClassLen = uint16(len(request.Class))
 	}
+	request.HeaderLen = 0
 	if len(request.Header.Kvs) != 0 {
 		request.HeaderLen = uint16(getHeaderEncodeLength(&request.Header))
 	}
@@ -86,13 +85,11 @@ func encodeRequest(ctx context.Context, request *Request) (types.IoBuffer, error
 }
 
 func encodeResponse(ctx context.Context, response *Response) (types.IoBuffer, error) {
-	// 1. fast-path, use existed raw data
-	if response.rawData != nil {
+	// 1. fast-path, use existed raw data if the header is not mutated
+	if response.rawData != nil && !response.Header.Changed {
 		// 1. replace requestId
 		binary.BigEndian.PutUint32(response.rawMeta[RequestIdIndex:], uint32(response.RequestId))
 
-		// 2. TODO: header mutate
-
 		return response.Data, nil
 	}
 
@@ -102,6 +99,7 @@ func encodeResponse(ctx context.Context, response *Response) (types.IoBuffer, er
 	if response.Class != "" {
 		response.ClassLen = uint16(len(response.Class))
 	}
+	response.HeaderLen = 0
 	if len(response.Header.Kvs) != 0 {
 		response.HeaderLen = uint16(getHeaderEncodeLength(&response.Header))
 	}
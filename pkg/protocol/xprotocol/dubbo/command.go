@@ -21,6 +21,7 @@ import (
 	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/protocol/xprotocol"
 	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/buffer"
 )
 
 type Header struct {
@@ -35,6 +36,21 @@ type Header struct {
 	Direction       int // 1 mean req
 	SerializationId int // 2 mean hessian
 	protocol.CommonHeader
+
+	// Changed is set once the header is mutated by Set or Del, the request payload is encoded again then.
+	Changed bool
+}
+
+// Set sets the header, the headers of a request are encoded into the attachments
+func (h *Header) Set(key string, value string) {
+	h.CommonHeader.Set(key, value)
+	h.Changed = true
+}
+
+// Del deletes the header
+func (h *Header) Del(key string) {
+	h.CommonHeader.Del(key)
+	h.Changed = true
 }
 
 type Frame struct {
@@ -78,6 +94,20 @@ func (r *Frame) GetData() types.IoBuffer {
 	return r.content
 }
 
+// Clone returns a deep copy of the frame.
+// The raw data is not copied, the frame is always encoded from the payload.
+func (r *Frame) Clone() types.HeaderMap {
+	clone := &Frame{}
+	clone.Header = r.Header
+	clone.Magic = append([]byte(nil), r.Magic...)
+	clone.CommonHeader = r.CommonHeader.Clone().(protocol.CommonHeader)
+	if r.payload != nil {
+		clone.payload = append([]byte(nil), r.payload...)
+		clone.content = buffer.NewIoBufferBytes(clone.payload)
+	}
+	return clone
+}
+
 func (r *Frame) GetStatusCode() uint32 {
 	return uint32(r.Header.Status)
 }
@@ -86,7 +86,7 @@ func decodeFrame(ctx context.Context, data types.IoBuffer) (cmd interface{}, err
 			return nil, err
 		}
 		for k, v := range meta {
-			frame.Set(k, v)
+			frame.CommonHeader.Set(k, v)
 		}
 	}
 
@@ -0,0 +1,173 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	hessian "github.com/apache/dubbo-go-hessian2"
+	"mosn.io/pkg/buffer"
+)
+
+// an object of the unregistered class com.test.User{name: "bob"}
+var userObject = []byte{'C', 0x0d, 'c', 'o', 'm', '.', 't', 'e', 's', 't', '.', 'U', 's', 'e', 'r', 0x91, 0x04, 'n', 'a', 'm', 'e', 0x60, 0x03, 'b', 'o', 'b'}
+
+func encodeTestRequest(t *testing.T, attachments map[interface{}]interface{}) []byte {
+	encoder := hessian.NewEncoder()
+	for _, v := range []string{"2.0.2", "com.test.Service", "1.0.0", "sayHello", "Ljava/lang/String;Lcom/test/User;"} {
+		if err := encoder.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := encoder.Encode("hello"); err != nil {
+		t.Fatal(err)
+	}
+	payload := append(encoder.Buffer(), userObject...)
+	if attachments != nil {
+		encoder = hessian.NewEncoder()
+		if err := encoder.Encode(attachments); err != nil {
+			t.Fatal(err)
+		}
+		payload = append(payload, encoder.Buffer()...)
+	}
+	header := make([]byte, HeaderLen)
+	copy(header, MagicTag)
+	header[FlagIdx] = 0xc2 // request, two way, hessian2
+	binary.BigEndian.PutUint64(header[IdIdx:], 1)
+	binary.BigEndian.PutUint32(header[DataLenIdx:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func decodeAttachments(t *testing.T, frame *Frame) map[interface{}]interface{} {
+	decoder := hessian.NewDecoderWithSkip(frame.payload)
+	for i := 0; i < 7; i++ {
+		if _, err := decoder.Decode(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	field, err := decoder.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return field.(map[interface{}]interface{})
+}
+
+func TestDubboRoundTrip(t *testing.T) {
+	proto := &dubboProtocol{}
+	for _, attachments := range []map[interface{}]interface{}{
+		{"interface": "com.test.Service", "timeout": "3000"},
+		nil,
+	} {
+		raw := encodeTestRequest(t, attachments)
+		cmd, err := proto.Decode(context.Background(), buffer.NewIoBufferBytes(raw))
+		if err != nil || cmd == nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		frame := cmd.(*Frame)
+		if v, _ := frame.Get(ServiceNameHeader); v != "com.test.Service" {
+			t.Fatalf("unexpected service: %s", v)
+		}
+		if v, _ := frame.Get(MethodNameHeader); v != "sayHello" {
+			t.Fatalf("unexpected method: %s", v)
+		}
+		// the unchanged frame is encoded as it is
+		buf, err := proto.Encode(context.Background(), frame)
+		if err != nil || !bytes.Equal(buf.Bytes(), raw) {
+			t.Fatalf("unexpected encoded frame: %v", err)
+		}
+		// the headers are added to the attachments
+		frame.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+		buf, err = proto.Encode(context.Background(), frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd, err = proto.Decode(context.Background(), buf)
+		if err != nil || cmd == nil {
+			t.Fatalf("decode the encoded frame failed: %v", err)
+		}
+		encoded := cmd.(*Frame)
+		if encoded.GetRequestId() != 1 || encoded.GetStreamType() != frame.GetStreamType() {
+			t.Fatal("unexpected encoded frame header")
+		}
+		if v, _ := encoded.Get(MethodNameHeader); v != "sayHello" {
+			t.Fatalf("unexpected method: %s", v)
+		}
+		got := decodeAttachments(t, encoded)
+		if got["traceparent"] != "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01" {
+			t.Fatalf("the header is not encoded into the attachments: %v", got)
+		}
+		for k, v := range attachments {
+			if got[k] != v {
+				t.Fatalf("the attachment %v is lost: %v", k, got)
+			}
+		}
+		if _, ok := got[ServiceNameHeader]; ok {
+			t.Fatalf("unexpected attachments: %v", got)
+		}
+		if !bytes.Contains(encoded.payload, userObject) {
+			t.Fatal("the args are changed")
+		}
+	}
+}
+
+func TestHessianSkip(t *testing.T) {
+	encoder := hessian.NewEncoder()
+	var offsets []int
+	for _, v := range []interface{}{
+		nil, true, false, "", "H", "中文😀", strings.Repeat("a", 100), strings.Repeat("中", 2000), strings.Repeat("b", 70000),
+		int32(0), int32(-16), int32(2047), int32(-2048), int32(262143), int32(1 << 30),
+		int64(0), int64(-8), int64(2047), int64(262143), int64(1 << 30), int64(1 << 40),
+		0.0, 1.0, 2.0, 300.0, 1.5, 3.1415926,
+		[]byte("M"), make([]byte, 2000), make([]byte, 70000),
+		[]interface{}{"a", int32(1), nil}, map[interface{}]interface{}{"a": "b", "c": []interface{}{int64(1)}},
+		time.Unix(1600000000, 0),
+	} {
+		if err := encoder.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, len(encoder.Buffer()))
+	}
+	data := append(encoder.Buffer(), userObject...)
+	offsets = append(offsets, len(data))
+	// the object of the same class
+	data = append(data, 0x60, 0x03, 'a', 'l', 'i')
+	offsets = append(offsets, len(data))
+
+	skipper := &hessianSkipper{data: data}
+	for i, offset := range offsets {
+		if err := skipper.skip(); err != nil {
+			t.Fatalf("skip the value %d failed: %v", i, err)
+		}
+		if skipper.offset != offset {
+			t.Fatalf("unexpected offset of the value %d: %d, expected %d", i, skipper.offset, offset)
+		}
+	}
+	if err := skipper.skip(); err != errHessianEOF {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the truncated data
+	skipper = &hessianSkipper{data: data[:offsets[8]-1], offset: offsets[7]}
+	if err := skipper.skip(); err != errHessianEOF {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -20,7 +20,9 @@ package dubbo
 import (
 	"context"
 	"encoding/binary"
+	"fmt"
 
+	hessian "github.com/apache/dubbo-go-hessian2"
 	mbuffer "mosn.io/mosn/pkg/buffer"
 	"mosn.io/mosn/pkg/types"
 	"mosn.io/pkg/buffer"
@@ -34,6 +36,17 @@ func encodeResponse(ctx context.Context, response *Frame) (types.IoBuffer, error
 }
 
 func encodeFrame(ctx context.Context, frame *Frame) (types.IoBuffer, error) {
+	// the mutated headers of a request are sent as the attachments
+	if frame.Changed && frame.Direction == EventRequest && frame.Event == 0 {
+		payload, err := encodeAttachments(frame)
+		if err != nil {
+			return nil, err
+		}
+		frame.payload = payload
+		frame.DataLen = uint32(len(payload))
+		frame.content = buffer.NewIoBufferBytes(payload)
+		frame.Changed = false
+	}
 	// alloc encode buffer
 	frameLen := int(HeaderLen + frame.DataLen)
 	buf := *mbuffer.GetBytesByContext(ctx, frameLen)
@@ -48,3 +61,61 @@ func encodeFrame(ctx context.Context, frame *Frame) (types.IoBuffer, error) {
 	copy(buf[HeaderLen:], frame.payload)
 	return buffer.NewIoBufferBytes(buf), nil
 }
+
+// encodeAttachments encodes the request payload again with the headers added to the attachments.
+// The payload is the dubbo version, the service, the service version, the method, the args types,
+// the args and the attachments, so the values before the attachments are skipped in order,
+// and the encoded attachments are replaced at the end of the payload.
+func encodeAttachments(frame *Frame) ([]byte, error) {
+	if frame.SerializationId != 2 {
+		return nil, fmt.Errorf("[xprotocol][dubbo] not hessian,do not support")
+	}
+	payload := frame.payload
+	skipper := &hessianSkipper{data: payload}
+	var start int
+	for i := 0; i < 5; i++ {
+		start = skipper.offset
+		if err := skipper.skip(); err != nil {
+			return nil, fmt.Errorf("[xprotocol][dubbo] decode request fail: %v", err)
+		}
+	}
+	// only the args types is decoded
+	field, err := hessian.NewDecoder(payload[start:skipper.offset]).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("[xprotocol][dubbo] decode args types fail: %v", err)
+	}
+	argsTypes, _ := field.(string)
+	// the args are skipped, the classes of them are not registered usually
+	for range hessian.DescRegex.FindAllString(argsTypes, -1) {
+		if err := skipper.skip(); err != nil {
+			return nil, fmt.Errorf("[xprotocol][dubbo] decode args fail: %v", err)
+		}
+	}
+	attachments := map[interface{}]interface{}{}
+	prefixLen := skipper.offset
+	if prefixLen < len(payload) {
+		field, err := hessian.NewDecoder(payload[prefixLen:]).Decode()
+		if err != nil {
+			return nil, fmt.Errorf("[xprotocol][dubbo] decode attachments fail: %v", err)
+		}
+		m, ok := field.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("[xprotocol][dubbo] attachments type error")
+		}
+		attachments = m
+	}
+	frame.Range(func(key, value string) bool {
+		if key != ServiceNameHeader && key != MethodNameHeader {
+			attachments[key] = value
+		}
+		return true
+	})
+	encoder := hessian.NewEncoder()
+	if err := encoder.Encode(attachments); err != nil {
+		return nil, fmt.Errorf("[xprotocol][dubbo] encode attachments fail: %v", err)
+	}
+	encoded := encoder.Buffer()
+	result := make([]byte, prefixLen, prefixLen+len(encoded))
+	copy(result, payload[:prefixLen])
+	return append(result, encoded...), nil
+}
@@ -0,0 +1,340 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"encoding/binary"
+	"errors"
+
+	hessian "github.com/apache/dubbo-go-hessian2"
+)
+
+// maxHessianDepth is the max depth of the nested lists, maps and objects
+const maxHessianDepth = 256
+
+var (
+	errHessianEOF   = errors.New("[xprotocol][dubbo] unexpected end of hessian data")
+	errHessianTag   = errors.New("[xprotocol][dubbo] unknown hessian tag")
+	errHessianDepth = errors.New("[xprotocol][dubbo] hessian data is nested too deep")
+)
+
+// hessianSkipper skips the hessian2 values in order without decoding them, so the offset
+// of each value in the data is known. The classes of the objects need not be registered.
+type hessianSkipper struct {
+	data   []byte
+	offset int
+	// classes is the fields number of the class definitions
+	classes []int
+	depth   int
+}
+
+func (s *hessianSkipper) peek() (byte, error) {
+	if s.offset >= len(s.data) {
+		return 0, errHessianEOF
+	}
+	return s.data[s.offset], nil
+}
+
+func (s *hessianSkipper) next(n int) ([]byte, error) {
+	if n < 0 || n > len(s.data)-s.offset {
+		return nil, errHessianEOF
+	}
+	b := s.data[s.offset : s.offset+n]
+	s.offset += n
+	return b, nil
+}
+
+func (s *hessianSkipper) readByte() (byte, error) {
+	b, err := s.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// readInt reads an int, which is used as the lengths and the references
+func (s *hessianSkipper) readInt() (int, error) {
+	tag, err := s.readByte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case 0x80 <= tag && tag <= 0xbf:
+		return int(tag) - int(hessian.BC_INT_ZERO), nil
+	case 0xc0 <= tag && tag <= 0xcf:
+		b, err := s.next(1)
+		if err != nil {
+			return 0, err
+		}
+		return (int(tag)-int(hessian.BC_INT_BYTE_ZERO))<<8 + int(b[0]), nil
+	case 0xd0 <= tag && tag <= 0xd7:
+		b, err := s.next(2)
+		if err != nil {
+			return 0, err
+		}
+		return (int(tag)-int(hessian.BC_INT_SHORT_ZERO))<<16 + int(b[0])<<8 + int(b[1]), nil
+	case tag == hessian.BC_INT:
+		b, err := s.next(4)
+		if err != nil {
+			return 0, err
+		}
+		return int(int32(binary.BigEndian.Uint32(b))), nil
+	}
+	return 0, errHessianTag
+}
+
+func isStringTag(tag byte) bool {
+	return tag <= hessian.STRING_DIRECT_MAX || (hessian.BC_STRING_SHORT <= tag && tag <= 0x33) ||
+		tag == hessian.BC_STRING || tag == hessian.BC_STRING_CHUNK
+}
+
+// skipString skips a string, the length of which is the number of the utf-16 chars
+func (s *hessianSkipper) skipString() error {
+	for {
+		tag, err := s.readByte()
+		if err != nil {
+			return err
+		}
+		var chars int
+		switch {
+		case tag <= hessian.STRING_DIRECT_MAX:
+			chars = int(tag)
+		case hessian.BC_STRING_SHORT <= tag && tag <= 0x33:
+			b, err := s.readByte()
+			if err != nil {
+				return err
+			}
+			chars = int(tag-hessian.BC_STRING_SHORT)<<8 + int(b)
+		case tag == hessian.BC_STRING || tag == hessian.BC_STRING_CHUNK:
+			b, err := s.next(2)
+			if err != nil {
+				return err
+			}
+			chars = int(binary.BigEndian.Uint16(b))
+		default:
+			return errHessianTag
+		}
+		for chars > 0 {
+			lead, err := s.peek()
+			if err != nil {
+				return err
+			}
+			n, c := 1, 1
+			switch {
+			case lead >= 0xf0:
+				// a surrogate pair
+				n, c = 4, 2
+			case lead >= 0xe0:
+				n = 3
+			case lead >= 0xc0:
+				n = 2
+			}
+			if _, err := s.next(n); err != nil {
+				return err
+			}
+			chars -= c
+		}
+		if tag != hessian.BC_STRING_CHUNK {
+			return nil
+		}
+	}
+}
+
+// skipBinary skips the binary data
+func (s *hessianSkipper) skipBinary(tag byte) error {
+	for {
+		var n int
+		switch {
+		case hessian.BC_BINARY_DIRECT <= tag && tag <= 0x2f:
+			n = int(tag - hessian.BC_BINARY_DIRECT)
+		case hessian.BC_BINARY_SHORT <= tag && tag <= 0x37:
+			b, err := s.readByte()
+			if err != nil {
+				return err
+			}
+			n = int(tag-hessian.BC_BINARY_SHORT)<<8 + int(b)
+		case tag == hessian.BC_BINARY || tag == hessian.BC_BINARY_CHUNK:
+			b, err := s.next(2)
+			if err != nil {
+				return err
+			}
+			n = int(binary.BigEndian.Uint16(b))
+		default:
+			return errHessianTag
+		}
+		if _, err := s.next(n); err != nil {
+			return err
+		}
+		if tag != hessian.BC_BINARY_CHUNK {
+			return nil
+		}
+		var err error
+		if tag, err = s.readByte(); err != nil {
+			return err
+		}
+	}
+}
+
+// skipType skips the type of a list or a map, which is a string or a reference
+func (s *hessianSkipper) skipType() error {
+	tag, err := s.peek()
+	if err != nil {
+		return err
+	}
+	if isStringTag(tag) {
+		return s.skipString()
+	}
+	_, err = s.readInt()
+	return err
+}
+
+// skipValues skips n values, or the values until the end tag if n is negative
+func (s *hessianSkipper) skipValues(n int) error {
+	for ; n != 0; n-- {
+		if n < 0 {
+			tag, err := s.peek()
+			if err != nil {
+				return err
+			}
+			if tag == hessian.BC_END {
+				s.offset++
+				return nil
+			}
+		}
+		if err := s.skip(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip skips a value
+func (s *hessianSkipper) skip() error {
+	if s.depth >= maxHessianDepth {
+		return errHessianDepth
+	}
+	s.depth++
+	defer func() { s.depth-- }()
+
+	tag, err := s.peek()
+	if err != nil {
+		return err
+	}
+	switch {
+	case isStringTag(tag):
+		return s.skipString()
+	case 0x80 <= tag && tag <= 0xd7, tag == hessian.BC_INT:
+		_, err := s.readInt()
+		return err
+	}
+	s.offset++
+	switch {
+	case tag == hessian.BC_NULL, tag == hessian.BC_TRUE, tag == hessian.BC_FALSE,
+		tag == hessian.BC_DOUBLE_ZERO, tag == hessian.BC_DOUBLE_ONE, 0xd8 <= tag && tag <= 0xef:
+		return nil
+	case 0xf0 <= tag, tag == hessian.BC_DOUBLE_BYTE:
+		_, err = s.next(1)
+	case 0x38 <= tag && tag <= 0x3f, tag == hessian.BC_DOUBLE_SHORT:
+		_, err = s.next(2)
+	case tag == hessian.BC_LONG_INT, tag == hessian.BC_DATE_MINUTE, tag == hessian.BC_DOUBLE_MILL:
+		_, err = s.next(4)
+	case tag == hessian.BC_LONG, tag == hessian.BC_DATE, tag == hessian.BC_DOUBLE:
+		_, err = s.next(8)
+	case hessian.BC_BINARY_DIRECT <= tag && tag <= 0x2f, hessian.BC_BINARY_SHORT <= tag && tag <= 0x37,
+		tag == hessian.BC_BINARY, tag == hessian.BC_BINARY_CHUNK:
+		err = s.skipBinary(tag)
+	case tag == hessian.BC_MAP:
+		if err = s.skipType(); err == nil {
+			err = s.skipValues(-1)
+		}
+	case tag == hessian.BC_MAP_UNTYPED, tag == hessian.BC_LIST_VARIABLE_UNTYPED:
+		err = s.skipValues(-1)
+	case tag == hessian.BC_LIST_VARIABLE:
+		if err = s.skipType(); err == nil {
+			err = s.skipValues(-1)
+		}
+	case tag == hessian.BC_LIST_FIXED:
+		if err = s.skipType(); err != nil {
+			return err
+		}
+		fallthrough
+	case tag == hessian.BC_LIST_FIXED_UNTYPED:
+		var n int
+		if n, err = s.readInt(); err == nil {
+			err = s.skipFixedValues(n)
+		}
+	case hessian.BC_LIST_DIRECT <= tag && tag <= 0x77:
+		if err = s.skipType(); err == nil {
+			err = s.skipValues(int(tag - hessian.BC_LIST_DIRECT))
+		}
+	case hessian.BC_LIST_DIRECT_UNTYPED <= tag && tag <= 0x7f:
+		err = s.skipValues(int(tag - hessian.BC_LIST_DIRECT_UNTYPED))
+	case tag == hessian.BC_OBJECT_DEF:
+		// the class definition is followed by the object
+		if err = s.skipClassDef(); err == nil {
+			err = s.skip()
+		}
+	case tag == hessian.BC_OBJECT:
+		var idx int
+		if idx, err = s.readInt(); err == nil {
+			err = s.skipObject(idx)
+		}
+	case hessian.BC_OBJECT_DIRECT <= tag && tag <= 0x6f:
+		err = s.skipObject(int(tag - hessian.BC_OBJECT_DIRECT))
+	case tag == hessian.BC_REF:
+		_, err = s.readInt()
+	default:
+		err = errHessianTag
+	}
+	return err
+}
+
+// skipFixedValues skips the values of a fixed length list, the negative length is invalid here
+func (s *hessianSkipper) skipFixedValues(n int) error {
+	if n < 0 {
+		return errHessianTag
+	}
+	return s.skipValues(n)
+}
+
+func (s *hessianSkipper) skipClassDef() error {
+	if err := s.skipString(); err != nil {
+		return err
+	}
+	n, err := s.readInt()
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return errHessianTag
+	}
+	for i := 0; i < n; i++ {
+		if err := s.skipString(); err != nil {
+			return err
+		}
+	}
+	s.classes = append(s.classes, n)
+	return nil
+}
+
+func (s *hessianSkipper) skipObject(idx int) error {
+	if idx < 0 || idx >= len(s.classes) {
+		return errHessianTag
+	}
+	return s.skipValues(s.classes[idx])
+}
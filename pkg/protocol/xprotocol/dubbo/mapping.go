@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"errors"
+	"net/http"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/xprotocol"
+)
+
+func init() {
+	protocol.RegisterMapping(ProtocolName, &dubboStatusMapping{})
+}
+
+type dubboStatusMapping struct{}
+
+// MappingHeaderStatusCode maps the dubbo response status into http status code
+func (m *dubboStatusMapping) MappingHeaderStatusCode(headers api.HeaderMap) (int, error) {
+	cmd, ok := headers.(xprotocol.XRespFrame)
+	if !ok {
+		return 0, errors.New("headers is not a dubbo response")
+	}
+	switch uint16(cmd.GetStatusCode()) {
+	case ResponseStatusSuccess:
+		return http.StatusOK, nil
+	case ResponseStatusClientTimeout, ResponseStatusServerTimeout:
+		return http.StatusGatewayTimeout, nil
+	case ResponseStatusBadRequest:
+		return http.StatusBadRequest, nil
+	case ResponseStatusServiceNotFound:
+		return http.StatusNotFound, nil
+	case ResponseStatusServerThreadpoolBusy:
+		return http.StatusServiceUnavailable, nil
+	case ResponseStatusBadResponse:
+		return http.StatusBadGateway, nil
+	default:
+		return http.StatusInternalServerError, nil
+	}
+}
@@ -21,8 +21,11 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"net/http"
 
+	hessian "github.com/apache/dubbo-go-hessian2"
 	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/protocol/xprotocol"
 	"mosn.io/mosn/pkg/types"
 )
@@ -91,8 +94,19 @@ func (proto *dubboProtocol) Decode(ctx context.Context, data types.IoBuffer) (in
 
 // heartbeater
 func (proto *dubboProtocol) Trigger(requestId uint64) xprotocol.XFrame {
-	// not support
-	return nil
+	return &Frame{
+		Header: Header{
+			Magic:           MagicTag,
+			Flag:            0xe2, // request, two way, event, hessian2
+			Id:              requestId,
+			DataLen:         0x01,
+			Event:           1,
+			TwoWay:          1,
+			Direction:       EventRequest,
+			SerializationId: 2,
+		},
+		payload: []byte{0x4e}, // hessian2 null
+	}
 }
 
 func (proto *dubboProtocol) Reply(requestId uint64) xprotocol.XRespFrame {
@@ -109,13 +123,45 @@ func (proto *dubboProtocol) Reply(requestId uint64) xprotocol.XRespFrame {
 	}
 }
 
+// the dubbo heartbeat keepalive is enabled by the cluster config
+func (proto *dubboProtocol) OptionalKeepAlive() bool {
+	return true
+}
+
 // hijacker
 func (proto *dubboProtocol) Hijack(statusCode uint32) xprotocol.XRespFrame {
-	// not support
-	return nil
+	// the error response's payload is the hessian2 encoded error message
+	encoder := hessian.NewEncoder()
+	encoder.Encode(fmt.Sprintf("mosn hijack response, status: %d", statusCode))
+	payload := encoder.Buffer()
+	return &Frame{
+		Header: Header{
+			Magic:           MagicTag,
+			Flag:            0x02, // response, hessian2
+			Status:          byte(statusCode),
+			Id:              0, // this would be overwrite by stream layer
+			DataLen:         uint32(len(payload)),
+			Direction:       EventResponse,
+			SerializationId: 2,
+			CommonHeader:    protocol.CommonHeader{},
+		},
+		payload: payload,
+	}
 }
 
 func (proto *dubboProtocol) Mapping(httpStatusCode uint32) uint32 {
-	// not support
-	return 0
+	switch httpStatusCode {
+	case http.StatusOK:
+		return uint32(ResponseStatusSuccess)
+	case types.RouterUnavailableCode:
+		return uint32(ResponseStatusServiceNotFound)
+	case types.UpstreamOverFlowCode, http.StatusTooManyRequests:
+		return uint32(ResponseStatusServerThreadpoolBusy)
+	case types.CodecExceptionCode:
+		return uint32(ResponseStatusBadRequest)
+	case types.TimeoutExceptionCode:
+		return uint32(ResponseStatusServerTimeout)
+	default:
+		return uint32(ResponseStatusServerError)
+	}
 }
@@ -52,5 +52,14 @@ const (
 )
 
 const (
-	ResponseStatusSuccess uint16 = 0x14 // 0x14 response status
+	ResponseStatusSuccess              uint16 = 0x14 // 0x14 response status
+	ResponseStatusClientTimeout        uint16 = 0x1e // 0x1e
+	ResponseStatusServerTimeout        uint16 = 0x1f // 0x1f
+	ResponseStatusBadRequest           uint16 = 0x28 // 0x28
+	ResponseStatusBadResponse          uint16 = 0x32 // 0x32
+	ResponseStatusServiceNotFound      uint16 = 0x3c // 0x3c
+	ResponseStatusServiceError         uint16 = 0x46 // 0x46
+	ResponseStatusServerError          uint16 = 0x50 // 0x50
+	ResponseStatusClientError          uint16 = 0x5a // 0x5a
+	ResponseStatusServerThreadpoolBusy uint16 = 0x64 // 0x64
 )
@@ -31,6 +31,9 @@ type BytesKV struct {
 // Header consists of multi key-value pair in byte slice formation. This could reduce the cost of []byte to string for protocol codec.
 type Header struct {
 	Kvs []BytesKV
+
+	// Changed is set once the header is mutated by Set or Del, codecs should not reuse the raw header bytes then.
+	Changed bool
 }
 
 // ~ HeaderMap
@@ -49,10 +52,12 @@ func (h *Header) Set(Key string, Value string) {
 		kv := &h.Kvs[i]
 		if Key == string(kv.Key) {
 			kv.Value = append(kv.Value[:0], Value...)
+			h.Changed = true
 			return
 		}
 	}
 
+	h.Changed = true
 	var kv *BytesKV
 	h.Kvs, kv = allocKV(h.Kvs)
 	kv.Key = append(kv.Key[:0], Key...)
@@ -72,6 +77,7 @@ func (h *Header) Del(Key string) {
 			n--
 			h.Kvs[n] = tmp
 			h.Kvs = h.Kvs[:n]
+			h.Changed = true
 			return
 		}
 	}
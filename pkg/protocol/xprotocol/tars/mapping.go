@@ -0,0 +1,58 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tars
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/TarsCloud/TarsGo/tars/protocol/res/basef"
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/xprotocol"
+)
+
+func init() {
+	protocol.RegisterMapping(ProtocolName, &tarsStatusMapping{})
+}
+
+type tarsStatusMapping struct{}
+
+// MappingHeaderStatusCode maps the tars response iRet into http status code
+func (m *tarsStatusMapping) MappingHeaderStatusCode(headers api.HeaderMap) (int, error) {
+	cmd, ok := headers.(xprotocol.XRespFrame)
+	if !ok {
+		return 0, errors.New("headers is not a tars response")
+	}
+	switch int32(cmd.GetStatusCode()) {
+	case basef.TARSSERVERSUCCESS:
+		return http.StatusOK, nil
+	case basef.TARSSERVERDECODEERR:
+		return http.StatusBadRequest, nil
+	case basef.TARSSERVERNOFUNCERR, basef.TARSSERVERNOSERVANTERR:
+		return http.StatusNotFound, nil
+	case basef.TARSSERVERQUEUETIMEOUT, basef.TARSINVOKETIMEOUT:
+		return http.StatusGatewayTimeout, nil
+	case basef.TARSSERVEROVERLOAD:
+		return http.StatusServiceUnavailable, nil
+	case basef.TARSPROXYCONNECTERR:
+		return http.StatusBadGateway, nil
+	default:
+		return http.StatusInternalServerError, nil
+	}
+}
@@ -119,6 +119,12 @@ type Heartbeater interface {
 	Reply(requestId uint64) XRespFrame
 }
 
+// OptionalKeepAlive is implemented by the protocols whose heartbeat does not keep the connections alive by default,
+// the keepalive is enabled by the cluster config. The heartbeat is still used by the health check
+type OptionalKeepAlive interface {
+	OptionalKeepAlive() bool
+}
+
 // Hijacker provides the ability to construct proper response command for xprotocol sub-protocols
 type Hijacker interface {
 	// BuildResponse build response with given status code
@@ -59,12 +59,15 @@ type downStream struct {
 	// ~~~ control args
 	timeout    Timeout
 	retryState *retryState
+	// the hosts' addresses that attempted by the stream, used to avoid retrying on the same host
+	attemptedHosts []string
 
 	requestInfo     types.RequestInfo
 	responseSender  types.StreamSender
 	upstreamRequest *upstreamRequest
 	perRetryTimer   *utils.Timer
 	responseTimer   *utils.Timer
+	retryTimer      *utils.Timer
 
 	// ~~~ downstream request buf
 	downstreamReqHeaders  types.HeaderMap
@@ -257,7 +260,7 @@ func (s *downStream) requestMetrics() {
 	s.proxy.listenerStats.DownstreamRequestActive.Dec(1)
 }
 
-const mosnProcessFailed = api.NoHealthyUpstream | api.NoRouteFound | api.FaultInjected | api.RateLimited
+const mosnProcessFailed = api.NoHealthyUpstream | api.NoRouteFound | api.FaultInjected | api.RateLimited | types.Unauthorized
 
 // isRequestFailed marks request failed due to mosn process
 func (s *downStream) isRequestFailed() bool {
@@ -468,6 +471,11 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 				log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 			}
 
+			// wait for the back off interval, it is broken by the reset or the timeout
+			if p, err := s.waitRetry(id); err != nil {
+				return p
+			}
+
 			if s.downstreamReqDataBuf != nil {
 				s.downstreamReqDataBuf.Count(1)
 			}
@@ -627,6 +635,21 @@ func (s *downStream) getUpstreamProtocol() (currentProtocol types.ProtocolName)
 	return currentProtocol
 }
 
+// statusMappingProtocol returns the protocol used to map the upstream response headers into http status code.
+// xprotocol's status code mapping is registered by the sub protocol.
+func (s *downStream) statusMappingProtocol(prot types.ProtocolName) types.ProtocolName {
+	if prot != protocol.Xprotocol {
+		return prot
+	}
+	if sub := subProtocolByContext(s.context); sub != "" {
+		return sub
+	}
+	if sub := subProtocolByContext(s.proxy.context); sub != "" {
+		return sub
+	}
+	return prot
+}
+
 func (s *downStream) receiveHeaders(endStream bool) {
 	s.downstreamRecvDone = endStream
 
@@ -648,6 +671,21 @@ func (s *downStream) receiveHeaders(endStream bool) {
 		}
 		return
 	}
+	// check if route have redirect rule
+	// redirect response will response now
+	if rr, ok := s.route.(types.RedirectRoute); ok {
+		if redirect := rr.RedirectRule(); !(redirect == nil || reflect.ValueOf(redirect).IsNil()) {
+			location := redirect.RedirectLocation(s.downstreamReqHeaders)
+			log.Proxy.Infof(s.context, "[proxy] [downstream] redirect response, proxyId = %d, location = %s", s.ID, location)
+			headers := s.downstreamReqHeaders
+			if headers == nil {
+				headers = protocol.CommonHeader(make(map[string]string, 2))
+			}
+			headers.Set(types.HeaderLocation, location)
+			s.sendHijackReply(redirect.RedirectCode(), headers)
+			return
+		}
+	}
 	// not direct response, needs a cluster snapshot and route rule
 	if rule := s.route.RouteRule(); rule == nil || reflect.ValueOf(rule).IsNil() {
 		log.Proxy.Warnf(s.context, "[proxy] [downstream] no route rule to init upstream")
@@ -687,7 +725,7 @@ func (s *downStream) receiveHeaders(endStream bool) {
 
 	prot := s.getUpstreamProtocol()
 
-	s.retryState = newRetryState(s.route.RouteRule().Policy().RetryPolicy(), s.downstreamReqHeaders, s.cluster, prot)
+	s.retryState = newRetryState(s.route.RouteRule().Policy().RetryPolicy(), s.downstreamReqHeaders, s.cluster, s.statusMappingProtocol(prot))
 
 	//Build Request
 	proxyBuffers := proxyBuffersByContext(s.context)
@@ -698,6 +736,9 @@ func (s *downStream) receiveHeaders(endStream bool) {
 	s.upstreamRequest.connPool = pool
 	s.route.RouteRule().FinalizeRequestHeaders(s.downstreamReqHeaders, s.requestInfo)
 
+	// mirror the request before it is sent to upstream
+	s.startMirrors()
+
 	//Call upstream's append header method to build upstream's request
 	s.upstreamRequest.appendHeaders(endStream)
 
@@ -823,6 +864,15 @@ func (s *downStream) onResponseTimeout() {
 			}
 		}
 
+		// the upstream request waiting for the retry ignores the reset, stops the retry directly
+		if s.upstreamRequest.setupRetry {
+			if atomic.CompareAndSwapUint32(&s.upstreamReset, 0, 1) {
+				s.resetReason = types.UpstreamGlobalTimeout
+				s.sendNotify()
+			}
+			return
+		}
+
 		s.upstreamRequest.resetStream()
 		s.upstreamRequest.OnResetStream(types.UpstreamGlobalTimeout)
 	}
@@ -1155,11 +1205,56 @@ func (s *downStream) setupRetry(endStream bool) bool {
 	return true
 }
 
+// waitRetry waits for the back off interval before the retry, the retry timer notifies the stream when it is fired.
+// It returns an error if the stream is reset or timed out during the interval.
 // Note: retry-timer MUST be stopped before active stream got recycled, otherwise resetting stream's properties will cause panic here
-func (s *downStream) doRetry() {
-	// retry interval
-	time.Sleep(10 * time.Millisecond)
+func (s *downStream) waitRetry(id uint32) (phase types.Phase, err error) {
+	if s.retryState == nil {
+		return types.Retry, nil
+	}
+	backOff := s.retryState.backOff()
+	if backOff <= 0 {
+		return types.Retry, nil
+	}
+
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(s.context, "[proxy] [downstream] retry after %s, proxyId = %d", backOff.String(), id)
+	}
+	var fired uint32
+	ID := s.ID
+	s.retryTimer = utils.NewTimer(backOff,
+		func() {
+			if atomic.LoadUint32(&s.downstreamCleaned) == 1 {
+				return
+			}
+			if ID != s.ID {
+				return
+			}
+			atomic.StoreUint32(&fired, 1)
+			s.sendNotify()
+		})
+
+	for {
+		// the stream waiting for the retry is always in the retry phase unless it is reset or timed out
+		if p, err := s.waitNotify(id); err != nil && p != types.Retry {
+			s.stopRetryTimer()
+			return p, err
+		}
+		if atomic.LoadUint32(&fired) == 1 {
+			s.retryTimer = nil
+			return types.Retry, nil
+		}
+	}
+}
 
+func (s *downStream) stopRetryTimer() {
+	if s.retryTimer != nil {
+		s.retryTimer.Stop()
+		s.retryTimer = nil
+	}
+}
+
+func (s *downStream) doRetry() {
 	// no reuse buffer
 	atomic.StoreUint32(&s.reuseBuffer, 0)
 
@@ -1262,6 +1357,9 @@ func (s *downStream) cleanUp() {
 		s.responseTimer = nil
 	}
 
+	// reset retry timer
+	s.stopRetryTimer()
+
 }
 
 func (s *downStream) setBufferLimit(bufferLimit uint32) {
@@ -1323,6 +1421,45 @@ func (s *downStream) DownstreamCluster() types.ClusterInfo {
 	return s.cluster
 }
 
+func (s *downStream) HashKey() (uint64, bool) {
+	if s.route == nil || s.route.RouteRule() == nil {
+		return 0, false
+	}
+	if p, ok := s.route.RouteRule().Policy().(types.Policy); ok {
+		if hp := p.HashPolicy(); hp != nil {
+			return hp.GenerateHash(s)
+		}
+	}
+	return 0, false
+}
+
+// ShouldSelectAnotherHost returns true if the host has been attempted by the stream,
+// so the retried request can be sent to another host
+func (s *downStream) ShouldSelectAnotherHost(host types.Host) bool {
+	return s.isAttemptedHost(host)
+}
+
+func (s *downStream) isAttemptedHost(host types.Host) bool {
+	addr := host.AddressString()
+	for _, attempted := range s.attemptedHosts {
+		if attempted == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// onUpstreamHostSelected records the host that the upstream request is sent to
+func (s *downStream) onUpstreamHostSelected(host types.Host) {
+	if s.isAttemptedHost(host) {
+		// retry landed on the same host
+		s.proxy.stats.DownstreamRequestRetrySameHost.Inc(1)
+		s.proxy.listenerStats.DownstreamRequestRetrySameHost.Inc(1)
+		return
+	}
+	s.attemptedHosts = append(s.attemptedHosts, host.AddressString())
+}
+
 func (s *downStream) giveStream() {
 	if atomic.LoadUint32(&s.reuseBuffer) != 1 {
 		return
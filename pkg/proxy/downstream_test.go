@@ -19,6 +19,7 @@ package proxy
 
 import (
 	"context"
+	"strconv"
 	"testing"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 	"mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/network"
 	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/router"
 	"mosn.io/mosn/pkg/trace"
 	"mosn.io/mosn/pkg/types"
 	"mosn.io/pkg/buffer"
@@ -122,6 +124,27 @@ func TestDirectResponse(t *testing.T) {
 				}
 			},
 		},
+		// redirect
+		{
+			client: &mockResponseSender{},
+			route: &mockRoute{
+				redirect: &mockRedirectRule{
+					code:     302,
+					location: "https://mosn.io/foo",
+				},
+			},
+			check: func(t *testing.T, client *mockResponseSender) {
+				if client.headers == nil {
+					t.Fatal("want to receive a header response")
+				}
+				if code, ok := client.headers.Get(types.HeaderStatus); !ok || code != "302" {
+					t.Error("response status code not expected")
+				}
+				if location, ok := client.headers.Get(types.HeaderLocation); !ok || location != "https://mosn.io/foo" {
+					t.Error("response location not expected")
+				}
+			},
+		},
 	}
 	for _, tc := range testCases {
 		s := &downStream{
@@ -298,3 +321,120 @@ func TestProcessError(t *testing.T) {
 		t.Errorf("TestprocessError Error")
 	}
 }
+
+func TestRetryAttemptedHost(t *testing.T) {
+	initGlobalStats()
+	proxy := &proxy{
+		config:         &v2.Proxy{},
+		routersWrapper: nil,
+		clusterManager: &mockClusterManager{},
+		readCallbacks:  &mockReadFilterCallbacks{},
+		stats:          newProxyStats("test_retry_attempted_host"),
+		listenerStats:  newListenerStats("test_retry_attempted_host"),
+	}
+	s := newActiveStream(context.Background(), proxy, nil, nil)
+	host1 := &mockHost{addr: "127.0.0.1:8080"}
+	host2 := &mockHost{addr: "127.0.0.1:8081"}
+	if s.ShouldSelectAnotherHost(host1) || s.ShouldSelectAnotherHost(host2) {
+		t.Fatal("no host is attempted")
+	}
+	s.onUpstreamHostSelected(host1)
+	if !s.ShouldSelectAnotherHost(host1) || s.ShouldSelectAnotherHost(host2) {
+		t.Fatal("host1 is attempted")
+	}
+	s.onUpstreamHostSelected(host2)
+	if proxy.stats.DownstreamRequestRetrySameHost.Count() != 0 {
+		t.Fatal("retry should not land on the same host")
+	}
+	// retry landed on the same host
+	s.onUpstreamHostSelected(host1)
+	if proxy.stats.DownstreamRequestRetrySameHost.Count() != 1 ||
+		proxy.listenerStats.DownstreamRequestRetrySameHost.Count() != 1 {
+		t.Fatal("retry landed on the same host should be counted")
+	}
+}
+
+func TestWaitRetry(t *testing.T) {
+	initGlobalStats()
+	newStream := func(interval time.Duration) *downStream {
+		proxy := &proxy{
+			config:         &v2.Proxy{},
+			routersWrapper: nil,
+			clusterManager: &mockClusterManager{},
+			readCallbacks:  &mockReadFilterCallbacks{},
+			stats:          newProxyStats("test_wait_retry"),
+			listenerStats:  newListenerStats("test_wait_retry"),
+		}
+		s := newActiveStream(context.Background(), proxy, nil, nil)
+		s.oneway = false
+		s.cluster = &fakeClusterInfo{mgr: &fakeResourceManager{}}
+		rcfg := &v2.Router{}
+		rcfg.Route.RetryPolicy = &v2.RetryPolicy{
+			RetryPolicyConfig: v2.RetryPolicyConfig{
+				RetryOn:    true,
+				NumRetries: 3,
+				RetryBackOff: &v2.RetryBackOffConfig{
+					BaseInterval: api.DurationConfig{Duration: interval},
+					MaxInterval:  api.DurationConfig{Duration: interval},
+				},
+			},
+		}
+		r, _ := router.NewRouteRuleImplBase(nil, rcfg)
+		s.retryState = newRetryState(r.Policy().RetryPolicy(), nil, s.cluster, protocol.HTTP1)
+		if s.retryState.retry(nil, types.StreamConnectionFailed) != api.ShouldRetry {
+			t.Fatal("retry state failed")
+		}
+		s.upstreamRequest = &upstreamRequest{downStream: s, setupRetry: true}
+		return s
+	}
+	wait := func(s *downStream) chan types.Phase {
+		ch := make(chan types.Phase, 1)
+		go func() {
+			p, err := s.waitRetry(s.ID)
+			if p == types.Retry && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			ch <- p
+		}()
+		return ch
+	}
+	// retry after the back off interval
+	s := newStream(10 * time.Millisecond)
+	select {
+	case p := <-wait(s):
+		if p != types.Retry {
+			t.Fatalf("expected retry, but got phase %d", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the retry timer is not fired")
+	}
+	// the global timeout stops the retry
+	s = newStream(time.Hour)
+	ch := wait(s)
+	time.Sleep(10 * time.Millisecond)
+	s.onResponseTimeout()
+	select {
+	case p := <-ch:
+		if p != types.UpFilter || s.retryTimer != nil {
+			t.Fatalf("expected timeout response, but got phase %d", p)
+		}
+		if code, _ := s.downstreamRespHeaders.Get(types.HeaderStatus); code != strconv.Itoa(types.TimeoutExceptionCode) {
+			t.Fatalf("unexpected response code: %s", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the retry is not stopped by the timeout")
+	}
+	// the downstream reset stops the retry
+	s = newStream(time.Hour)
+	ch = wait(s)
+	time.Sleep(10 * time.Millisecond)
+	s.OnResetStream(types.StreamRemoteReset)
+	select {
+	case p := <-ch:
+		if p == types.Retry {
+			t.Fatal("expected the retry is stopped")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the retry is not stopped by the reset")
+	}
+}
@@ -0,0 +1,304 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/api"
+	mbuffer "mosn.io/mosn/pkg/buffer"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/utils"
+)
+
+// shadowHostSuffix is appended to the host of the mirrored requests
+const shadowHostSuffix = "-shadow"
+
+// types.LoadBalancerContext
+// types.PoolEventListener
+// types.StreamReceiveListener
+// types.StreamEventListener
+//
+// mirror sends a copy of the downstream request to a shadow cluster in fire-and-forget mode.
+// The response of the shadow cluster is ignored, and the failures never affect the downstream request.
+type mirror struct {
+	proxy    *proxy
+	ctx      context.Context
+	cluster  string
+	snapshot types.ClusterSnapshot
+	conn     net.Conn
+	oneway   bool
+	timeout  time.Duration
+
+	// protocol convert, from downstream protocol to upstream protocol
+	noConvert bool
+	dp        types.ProtocolName
+	up        types.ProtocolName
+
+	headers  types.HeaderMap
+	data     types.IoBuffer
+	trailers types.HeaderMap
+
+	sender types.StreamSender
+	timer  *utils.Timer
+	done   uint32
+}
+
+// startMirrors sends the downstream request to the shadow clusters configured in the route's mirror policies.
+// it should be called after the downstream request is received, and before the request is sent to upstream,
+// so the request can be copied without data race.
+func (s *downStream) startMirrors() {
+	p, ok := s.route.RouteRule().Policy().(types.Policy)
+	if !ok {
+		return
+	}
+	for _, mp := range p.MirrorPolicies() {
+		if !mp.IsMirror() {
+			continue
+		}
+		s.proxy.stats.DownstreamMirrorRequestTotal.Inc(1)
+		s.proxy.listenerStats.DownstreamMirrorRequestTotal.Inc(1)
+		m := s.newMirror(mp.ClusterName())
+		if m == nil {
+			s.proxy.stats.DownstreamMirrorRequestFailed.Inc(1)
+			s.proxy.listenerStats.DownstreamMirrorRequestFailed.Inc(1)
+			continue
+		}
+		m.start()
+	}
+}
+
+func (s *downStream) newMirror(clusterName string) *mirror {
+	snapshot := s.proxy.clusterManager.GetClusterSnapshot(context.Background(), clusterName)
+	if snapshot == nil || reflect.ValueOf(snapshot).IsNil() {
+		log.Proxy.Warnf(s.context, "[proxy] [mirror] cluster snapshot of %s is nil", clusterName)
+		return nil
+	}
+	// the downstream context and buffers are reused after the downstream request finished,
+	// so the mirror needs its own context and a copy of the request
+	ctx := mbuffer.NewBufferPoolContext(mosnctx.Clone(s.context))
+	dp, up := s.convertProtocol()
+	m := &mirror{
+		proxy:     s.proxy,
+		ctx:       ctx,
+		cluster:   clusterName,
+		snapshot:  snapshot,
+		conn:      s.DownstreamConnection(),
+		oneway:    s.oneway,
+		timeout:   s.timeout.GlobalTimeout,
+		noConvert: s.noConvert,
+		dp:        dp,
+		up:        up,
+	}
+	if m.timeout <= 0 {
+		m.timeout = types.GlobalTimeout
+	}
+	if s.downstreamReqHeaders != nil {
+		m.headers = s.downstreamReqHeaders.Clone()
+		shadowHost(m.headers)
+	}
+	if s.downstreamReqDataBuf != nil {
+		m.data = s.downstreamReqDataBuf.Clone()
+	}
+	if s.downstreamReqTrailers != nil {
+		m.trailers = s.downstreamReqTrailers.Clone()
+	}
+	return m
+}
+
+// shadowHost appends the shadow suffix to the host of the request
+func shadowHost(headers types.HeaderMap) {
+	for _, key := range []string{protocol.IstioHeaderHostKey, protocol.MosnHeaderHostKey} {
+		if host, ok := headers.Get(key); ok && host != "" {
+			headers.Set(key, host+shadowHostSuffix)
+		}
+	}
+}
+
+func (m *mirror) start() {
+	pool := m.proxy.clusterManager.ConnPoolForCluster(m, m.snapshot, m.up)
+	if pool == nil {
+		log.Proxy.Warnf(m.ctx, "[proxy] [mirror] no healthy upstream in cluster %s", m.cluster)
+		m.onFailed()
+		return
+	}
+	if !m.oneway {
+		m.timer = utils.NewTimer(m.timeout, m.onTimeout)
+		pool.NewStream(m.ctx, m, m)
+	} else {
+		pool.NewStream(m.ctx, nil, m)
+	}
+}
+
+func (m *mirror) finish() bool {
+	if !atomic.CompareAndSwapUint32(&m.done, 0, 1) {
+		return false
+	}
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	return true
+}
+
+func (m *mirror) onFailed() {
+	m.proxy.stats.DownstreamMirrorRequestFailed.Inc(1)
+	m.proxy.listenerStats.DownstreamMirrorRequestFailed.Inc(1)
+}
+
+func (m *mirror) onTimeout() {
+	if !m.finish() {
+		return
+	}
+	if m.sender != nil {
+		m.sender.GetStream().RemoveEventListener(m)
+		m.sender.GetStream().ResetStream(types.StreamLocalReset)
+	}
+	m.onFailed()
+}
+
+// types.PoolEventListener
+func (m *mirror) OnFailure(reason types.PoolFailureReason, host types.Host) {
+	if !m.finish() {
+		return
+	}
+	log.Proxy.Warnf(m.ctx, "[proxy] [mirror] OnFailure host:%s, reason:%v", host.AddressString(), reason)
+	m.onFailed()
+}
+
+func (m *mirror) OnReady(sender types.StreamSender, host types.Host) {
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(m.ctx, "[proxy] [mirror] connPool ready, host = %s", host.AddressString())
+	}
+	// timeout before the connection pool is ready
+	if atomic.LoadUint32(&m.done) == 1 {
+		sender.GetStream().ResetStream(types.StreamLocalReset)
+		return
+	}
+	m.sender = sender
+	m.sender.GetStream().AddEventListener(m)
+
+	endStream := m.data == nil && m.trailers == nil
+	m.sender.AppendHeaders(m.ctx, m.convertHeader(m.headers), endStream)
+	if m.data != nil {
+		m.sender.AppendData(m.ctx, m.convertData(m.data), m.trailers == nil)
+	}
+	if m.trailers != nil {
+		m.sender.AppendTrailers(m.ctx, m.convertTrailer(m.trailers))
+	}
+	// the oneway request has no response
+	if m.oneway {
+		m.finish()
+	}
+}
+
+// types.StreamReceiveListener
+// the response of the shadow cluster is ignored
+func (m *mirror) OnReceive(ctx context.Context, headers types.HeaderMap, data types.IoBuffer, trailers types.HeaderMap) {
+	m.finish()
+}
+
+func (m *mirror) OnDecodeError(ctx context.Context, err error, headers types.HeaderMap) {
+	if !m.finish() {
+		return
+	}
+	log.Proxy.Warnf(m.ctx, "[proxy] [mirror] OnDecodeError error: %v", err)
+	m.onFailed()
+}
+
+// types.StreamEventListener
+func (m *mirror) OnResetStream(reason types.StreamResetReason) {
+	if !m.finish() {
+		return
+	}
+	log.Proxy.Warnf(m.ctx, "[proxy] [mirror] OnResetStream reason: %v", reason)
+	m.onFailed()
+}
+
+func (m *mirror) OnDestroyStream() {}
+
+func (m *mirror) convertHeader(headers types.HeaderMap) types.HeaderMap {
+	if m.noConvert || m.dp == m.up {
+		return headers
+	}
+	convHeader, err := protocol.ConvertHeader(m.ctx, m.dp, m.up, headers)
+	if err != nil {
+		log.Proxy.Warnf(m.ctx, "[proxy] [mirror] convert header from %s to %s failed, %s", m.dp, m.up, err.Error())
+		return headers
+	}
+	return convHeader
+}
+
+func (m *mirror) convertData(data types.IoBuffer) types.IoBuffer {
+	if m.noConvert || m.dp == m.up {
+		return data
+	}
+	convData, err := protocol.ConvertData(m.ctx, m.dp, m.up, data)
+	if err != nil {
+		log.Proxy.Warnf(m.ctx, "[proxy] [mirror] convert data from %s to %s failed, %s", m.dp, m.up, err.Error())
+		return data
+	}
+	return convData
+}
+
+func (m *mirror) convertTrailer(trailers types.HeaderMap) types.HeaderMap {
+	if m.noConvert || m.dp == m.up {
+		return trailers
+	}
+	convTrailer, err := protocol.ConvertTrailer(m.ctx, m.dp, m.up, trailers)
+	if err != nil {
+		log.Proxy.Warnf(m.ctx, "[proxy] [mirror] convert trailer from %s to %s failed, %s", m.dp, m.up, err.Error())
+		return trailers
+	}
+	return convTrailer
+}
+
+// types.LoadBalancerContext
+func (m *mirror) MetadataMatchCriteria() api.MetadataMatchCriteria {
+	return nil
+}
+
+func (m *mirror) DownstreamConnection() net.Conn {
+	return m.conn
+}
+
+func (m *mirror) DownstreamHeaders() types.HeaderMap {
+	return m.headers
+}
+
+func (m *mirror) DownstreamContext() context.Context {
+	return m.ctx
+}
+
+func (m *mirror) DownstreamCluster() types.ClusterInfo {
+	return m.snapshot.ClusterInfo()
+}
+
+func (m *mirror) HashKey() (uint64, bool) {
+	return 0, false
+}
+
+func (m *mirror) ShouldSelectAnotherHost(host types.Host) bool {
+	return false
+}
@@ -0,0 +1,109 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/router"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/buffer"
+)
+
+func newMirrorDownstream(t *testing.T, name string, pool types.ConnectionPool, policies []*v2.RequestMirrorPolicy) *downStream {
+	rcfg := &v2.Router{}
+	rcfg.Route.ClusterName = "test"
+	rcfg.Route.RequestMirrorPolicies = policies
+	rule, err := router.NewRouteRuleImplBase(nil, rcfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := &proxy{
+		config:         &v2.Proxy{},
+		clusterManager: &mockMirrorClusterManager{pool: pool},
+		readCallbacks:  &mockReadFilterCallbacks{},
+		stats:          newProxyStats(name),
+		listenerStats:  newListenerStats(name),
+	}
+	s := newActiveStream(context.Background(), proxy, nil, nil)
+	s.route = &mockRoute{rule: &mockRouteRule{policy: rule.Policy()}}
+	s.oneway = false
+	return s
+}
+
+func TestMirror(t *testing.T) {
+	sender := &mockRequestSender{}
+	s := newMirrorDownstream(t, "test_mirror", &mockConnPool{sender: sender}, []*v2.RequestMirrorPolicy{
+		{Cluster: "shadow"},
+		// never mirrored
+		{Cluster: "shadow2", RuntimeFraction: &v2.FractionalPercent{Numerator: 0}},
+	})
+	s.downstreamReqHeaders = protocol.CommonHeader{
+		protocol.MosnHeaderHostKey: "test.com",
+		"service":                  "test",
+	}
+	s.downstreamReqDataBuf = buffer.NewIoBufferString("mirror body")
+
+	s.startMirrors()
+
+	if s.proxy.stats.DownstreamMirrorRequestTotal.Count() != 1 {
+		t.Fatalf("expected 1 mirror request, but got %d", s.proxy.stats.DownstreamMirrorRequestTotal.Count())
+	}
+	if sender.headers == nil || sender.data == nil {
+		t.Fatal("mirror request is not sent")
+	}
+	if host, _ := sender.headers.Get(protocol.MosnHeaderHostKey); host != "test.com-shadow" {
+		t.Errorf("unexpected mirror host: %s", host)
+	}
+	if svc, _ := sender.headers.Get("service"); svc != "test" {
+		t.Errorf("unexpected mirror header: %s", svc)
+	}
+	if sender.data.String() != "mirror body" {
+		t.Errorf("unexpected mirror body: %s", sender.data.String())
+	}
+	// the downstream request should not be changed
+	if host, _ := s.downstreamReqHeaders.Get(protocol.MosnHeaderHostKey); host != "test.com" {
+		t.Errorf("downstream host changed: %s", host)
+	}
+	if sender.data == s.downstreamReqDataBuf {
+		t.Error("mirror body should be a copy")
+	}
+	// shadow cluster failures are counted
+	sender.listener.OnResetStream(types.StreamConnectionFailed)
+	if s.proxy.stats.DownstreamMirrorRequestFailed.Count() != 1 ||
+		s.proxy.listenerStats.DownstreamMirrorRequestFailed.Count() != 1 {
+		t.Error("mirror failure is not counted")
+	}
+}
+
+func TestMirrorNoHealthyUpstream(t *testing.T) {
+	s := newMirrorDownstream(t, "test_mirror_no_healthy", nil, []*v2.RequestMirrorPolicy{
+		{Cluster: "shadow"},
+	})
+	s.downstreamReqHeaders = protocol.CommonHeader{}
+
+	s.startMirrors()
+
+	if s.proxy.stats.DownstreamMirrorRequestTotal.Count() != 1 ||
+		s.proxy.stats.DownstreamMirrorRequestFailed.Count() != 1 {
+		t.Error("mirror failure is not counted")
+	}
+}
@@ -63,8 +63,9 @@ func (r *mockRouters) MatchRoute(types.HeaderMap, uint64) types.Route {
 
 type mockRoute struct {
 	api.Route
-	rule   api.RouteRule
-	direct api.DirectResponseRule
+	rule     api.RouteRule
+	direct   api.DirectResponseRule
+	redirect types.RedirectRule
 }
 
 func (r *mockRoute) RouteRule() api.RouteRule {
@@ -81,8 +82,17 @@ func (r *mockRoute) DirectResponseRule() api.DirectResponseRule {
 	return nil
 }
 
+func (r *mockRoute) RedirectRule() types.RedirectRule {
+	return r.redirect
+}
+
 type mockRouteRule struct {
 	api.RouteRule
+	policy api.Policy
+}
+
+func (r *mockRouteRule) Policy() api.Policy {
+	return r.policy
 }
 
 func (r *mockRouteRule) ClusterName() string {
@@ -110,6 +120,19 @@ func (r *mockDirectRule) Body() string {
 	return r.body
 }
 
+type mockRedirectRule struct {
+	code     int
+	location string
+}
+
+func (r *mockRedirectRule) RedirectCode() int {
+	return r.code
+}
+
+func (r *mockRedirectRule) RedirectLocation(headers api.HeaderMap) string {
+	return r.location
+}
+
 type mockClusterManager struct {
 	types.ClusterManager
 }
@@ -174,6 +197,10 @@ func (c *mockConnection) ID() uint64 {
 	return 0
 }
 
+func (c *mockConnection) RawConn() net.Conn {
+	return nil
+}
+
 func (c *mockConnection) LocalAddr() net.Addr {
 	addr, _ := net.ResolveTCPAddr("tcp", "127.0.0.1")
 	return addr
@@ -231,3 +258,73 @@ func (s *mockSpan) InjectContext(requestHeaders types.HeaderMap) {
 func (s *mockSpan) SpawnChild(operationName string, startTime time.Time) types.Span {
 	return nil
 }
+
+type mockHost struct {
+	types.Host
+	addr string
+}
+
+func (h *mockHost) AddressString() string {
+	return h.addr
+}
+
+type mockMirrorClusterManager struct {
+	types.ClusterManager
+	pool types.ConnectionPool
+}
+
+func (m *mockMirrorClusterManager) GetClusterSnapshot(ctx context.Context, name string) types.ClusterSnapshot {
+	return &mockClusterSnapshot{}
+}
+
+func (m *mockMirrorClusterManager) ConnPoolForCluster(balancerContext types.LoadBalancerContext, snapshot types.ClusterSnapshot, protocol types.ProtocolName) types.ConnectionPool {
+	return m.pool
+}
+
+type mockConnPool struct {
+	types.ConnectionPool
+	sender *mockRequestSender
+}
+
+func (p *mockConnPool) NewStream(ctx context.Context, receiver types.StreamReceiveListener, listener types.PoolEventListener) {
+	listener.OnReady(p.sender, &mockHost{addr: "127.0.0.1:8080"})
+}
+
+type mockRequestSender struct {
+	mockResponseSender
+	listener types.StreamEventListener
+}
+
+func (s *mockRequestSender) GetStream() types.Stream {
+	return &mockRequestStream{sender: s}
+}
+
+type mockRequestStream struct {
+	mockStream
+	sender *mockRequestSender
+}
+
+func (s *mockRequestStream) AddEventListener(listener types.StreamEventListener) {
+	s.sender.listener = listener
+}
+
+func (s *mockRequestStream) RemoveEventListener(listener types.StreamEventListener) {
+	s.sender.listener = nil
+}
+
+type mockOutlierDetector struct {
+	results []int
+}
+
+func (d *mockOutlierDetector) PutResult(host types.Host, code int) {
+	d.results = append(d.results, code)
+}
+
+type mockOutlierClusterInfo struct {
+	types.ClusterInfo
+	detector types.OutlierDetector
+}
+
+func (ci *mockOutlierClusterInfo) OutlierDetector() types.OutlierDetector {
+	return ci.detector
+}
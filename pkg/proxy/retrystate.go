@@ -18,19 +18,33 @@
 package proxy
 
 import (
+	"math/rand"
+	"sync"
+	"time"
+
 	"mosn.io/api"
 	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/protocol/http"
 	"mosn.io/mosn/pkg/types"
 )
 
+var (
+	backOffRandLock sync.Mutex
+	backOffRand     = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
 type retryState struct {
-	retryPolicy      api.RetryPolicy
-	requestHeaders   types.HeaderMap // TODO: support retry policy by header
-	cluster          types.ClusterInfo
-	retryOn          bool
-	retiesRemaining  uint32
-	upstreamProtocol types.ProtocolName
+	retryPolicy          api.RetryPolicy
+	requestHeaders       types.HeaderMap // TODO: support retry policy by header
+	cluster              types.ClusterInfo
+	retryOn              bool
+	retiesRemaining      uint32
+	upstreamProtocol     types.ProtocolName
+	retryOnConditions    types.RetryOnCondition
+	retriableStatusCodes []uint32
+	baseInterval         time.Duration
+	maxInterval          time.Duration
+	retries              uint32
 }
 
 func newRetryState(retryPolicy api.RetryPolicy,
@@ -48,6 +62,12 @@ func newRetryState(retryPolicy api.RetryPolicy,
 		rs.retiesRemaining = retryPolicy.NumRetries()
 	}
 
+	if p, ok := retryPolicy.(types.RetryPolicy); ok {
+		rs.retryOnConditions = p.RetryOnConditions()
+		rs.retriableStatusCodes = p.RetriableStatusCodes()
+		rs.baseInterval, rs.maxInterval = p.BackOffInterval()
+	}
+
 	return rs
 }
 
@@ -62,10 +82,31 @@ func (r *retryState) retry(headers api.HeaderMap, reason types.StreamResetReason
 
 	r.cluster.ResourceManager().Retries().Increase()
 	r.cluster.Stats().UpstreamRequestRetry.Inc(1)
+	r.retries++
 
 	return 0
 }
 
+// backOff returns the interval before next retry, the interval is a random value
+// in [0, min(base * 2^(retries-1), max)), aka the full jitter exponential back off
+func (r *retryState) backOff() time.Duration {
+	if r.baseInterval <= 0 {
+		return 0
+	}
+	interval := r.maxInterval
+	if r.retries > 0 && r.retries <= 32 {
+		if i := r.baseInterval << (r.retries - 1); i > 0 && i < interval {
+			interval = i
+		}
+	}
+	if interval <= 0 {
+		return 0
+	}
+	backOffRandLock.Lock()
+	defer backOffRandLock.Unlock()
+	return time.Duration(backOffRand.Int63n(int64(interval)))
+}
+
 func (r *retryState) shouldRetry(headers api.HeaderMap, reason types.StreamResetReason) api.RetryCheckStatus {
 	if r.retiesRemaining == 0 {
 		return api.NoRetry
@@ -91,8 +132,12 @@ func (r *retryState) doRetryCheck(headers types.HeaderMap, reason types.StreamRe
 		return false
 	}
 
+	if r.retryOn && r.retryOnConditions != 0 {
+		return r.checkRetryOnConditions(headers, reason)
+	}
+
 	if r.retryOn {
-		// TODO: add retry policy to decide retry or not. use default policy now
+		// no retry on conditions configured, use default policy
 		if headers != nil {
 			// default policy , mapping all headers to http status code
 			code, err := protocol.MappingHeaderStatusCode(r.upstreamProtocol, headers)
@@ -123,6 +168,51 @@ func (r *retryState) doRetryCheck(headers types.HeaderMap, reason types.StreamRe
 	return false
 }
 
+func (r *retryState) checkRetryOnConditions(headers types.HeaderMap, reason types.StreamResetReason) bool {
+	if headers != nil {
+		code, err := protocol.MappingHeaderStatusCode(r.upstreamProtocol, headers)
+		if err != nil {
+			return false
+		}
+		return r.retryOnStatusCode(code)
+	}
+	return r.retryOnReset(reason)
+}
+
+func (r *retryState) retryOnStatusCode(code int) bool {
+	conditions := r.retryOnConditions
+	if conditions&types.RetryOn5xx != 0 && code >= http.InternalServerError {
+		return true
+	}
+	if conditions&types.RetryOnGatewayError != 0 {
+		switch code {
+		case http.BadGateway, http.ServiceUnavailable, http.GatewayTimeout:
+			return true
+		}
+	}
+	if conditions&types.RetryOnRetriableStatusCodes != 0 {
+		for _, c := range r.retriableStatusCodes {
+			if int(c) == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *retryState) retryOnReset(reason types.StreamResetReason) bool {
+	conditions := r.retryOnConditions
+	switch reason {
+	case types.StreamConnectionFailed:
+		return conditions&(types.RetryOn5xx|types.RetryOnGatewayError|types.RetryOnConnectFailure) != 0
+	case types.UpstreamPerTryTimeout:
+		return conditions&(types.RetryOn5xx|types.RetryOnGatewayError|types.RetryOnReset) != 0
+	case types.StreamConnectionTermination, types.StreamRemoteReset, types.UpstreamReset:
+		return conditions&(types.RetryOn5xx|types.RetryOnReset) != 0
+	}
+	return false
+}
+
 func (r *retryState) reset() {
 	r.cluster.ResourceManager().Retries().Decrease()
 }
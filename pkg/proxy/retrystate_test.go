@@ -43,6 +43,7 @@ func (ci *fakeClusterInfo) Stats() types.ClusterStats {
 	return types.ClusterStats{
 		UpstreamRequestRetryOverflow: metrics.NewCounter(),
 		UpstreamRequestRetry:         metrics.NewCounter(),
+		UpstreamRequestTimeout:       metrics.NewCounter(),
 	}
 }
 
@@ -132,3 +133,92 @@ func TestRetryConnetionFailed(t *testing.T) {
 		}
 	}
 }
+
+func TestRetryOnConditions(t *testing.T) {
+	clusterInfo := &fakeClusterInfo{
+		mgr: &fakeResourceManager{},
+	}
+	header := func(code string) types.HeaderMap {
+		return protocol.CommonHeader{
+			types.HeaderStatus: code,
+		}
+	}
+	testcases := []struct {
+		Conditions string
+		Codes      []uint32
+		Header     types.HeaderMap
+		Reason     types.StreamResetReason
+		Expected   api.RetryCheckStatus
+	}{
+		{"5xx", nil, header("500"), "", api.ShouldRetry},
+		{"5xx", nil, header("404"), "", api.NoRetry},
+		{"5xx", nil, nil, types.StreamConnectionTermination, api.ShouldRetry},
+		{"gateway-error", nil, header("503"), "", api.ShouldRetry},
+		{"gateway-error", nil, header("500"), "", api.NoRetry},
+		{"gateway-error", nil, nil, types.UpstreamPerTryTimeout, api.ShouldRetry},
+		{"connect-failure", nil, header("500"), "", api.NoRetry},
+		{"connect-failure", nil, nil, types.StreamConnectionFailed, api.ShouldRetry},
+		{"connect-failure", nil, nil, types.StreamConnectionTermination, api.NoRetry},
+		{"reset", nil, nil, types.StreamRemoteReset, api.ShouldRetry},
+		{"reset", nil, nil, types.StreamConnectionFailed, api.NoRetry},
+		{"retriable-status-codes", []uint32{409}, header("409"), "", api.ShouldRetry},
+		{"retriable-status-codes", []uint32{409}, header("500"), "", api.NoRetry},
+		{"connect-failure,retriable-status-codes", []uint32{409}, header("409"), "", api.ShouldRetry},
+		// retriable status codes without condition
+		{"5xx", []uint32{409}, header("409"), "", api.NoRetry},
+	}
+	for i, tc := range testcases {
+		rcfg := &v2.Router{}
+		rcfg.Route = v2.RouteAction{}
+		rcfg.Route.RetryPolicy = &v2.RetryPolicy{
+			RetryPolicyConfig: v2.RetryPolicyConfig{
+				RetryOn:              true,
+				NumRetries:           10,
+				RetryOnConditions:    tc.Conditions,
+				RetriableStatusCodes: tc.Codes,
+			},
+		}
+		r, _ := router.NewRouteRuleImplBase(nil, rcfg)
+		rs := newRetryState(r.Policy().RetryPolicy(), nil, clusterInfo, protocol.HTTP1)
+		if rs.retry(tc.Header, tc.Reason) != tc.Expected {
+			t.Errorf("#%d retry state failed", i)
+		}
+	}
+}
+
+func TestRetryBackOff(t *testing.T) {
+	rcfg := &v2.Router{}
+	rcfg.Route = v2.RouteAction{}
+	rcfg.Route.RetryPolicy = &v2.RetryPolicy{
+		RetryPolicyConfig: v2.RetryPolicyConfig{
+			RetryOn:    true,
+			NumRetries: 10,
+			RetryBackOff: &v2.RetryBackOffConfig{
+				BaseInterval: api.DurationConfig{Duration: 10 * time.Millisecond},
+				MaxInterval:  api.DurationConfig{Duration: 50 * time.Millisecond},
+			},
+		},
+	}
+	r, _ := router.NewRouteRuleImplBase(nil, rcfg)
+	clusterInfo := &fakeClusterInfo{
+		mgr: &fakeResourceManager{},
+	}
+	rs := newRetryState(r.Policy().RetryPolicy(), nil, clusterInfo, protocol.HTTP1)
+	maxIntervals := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond,
+		50 * time.Millisecond,
+	}
+	for i, max := range maxIntervals {
+		if rs.retry(nil, types.StreamConnectionFailed) != api.ShouldRetry {
+			t.Fatalf("#%d retry state failed", i)
+		}
+		for j := 0; j < 100; j++ {
+			if d := rs.backOff(); d < 0 || d >= max {
+				t.Fatalf("#%d back off interval %s out of range %s", i, d, max)
+			}
+		}
+	}
+}
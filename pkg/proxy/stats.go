@@ -37,6 +37,11 @@ type Stats struct {
 	DownstreamProcessTime       gometrics.Histogram
 	DownstreamProcessTimeTotal  gometrics.Counter
 	DownstreamRequestFailed     gometrics.Counter
+	// DownstreamRequestRetrySameHost counts the retried requests that landed on an attempted host
+	DownstreamRequestRetrySameHost gometrics.Counter
+	// mirror requests are counted separately, they never affect the downstream requests
+	DownstreamMirrorRequestTotal  gometrics.Counter
+	DownstreamMirrorRequestFailed gometrics.Counter
 }
 
 func newListenerStats(listenerName string) *Stats {
@@ -50,18 +55,21 @@ func newProxyStats(proxyName string) *Stats {
 
 func newStats(s types.Metrics) *Stats {
 	return &Stats{
-		DownstreamConnectionTotal:   s.Counter(metrics.DownstreamConnectionTotal),
-		DownstreamConnectionDestroy: s.Counter(metrics.DownstreamConnectionDestroy),
-		DownstreamConnectionActive:  s.Counter(metrics.DownstreamConnectionActive),
-		DownstreamBytesReadTotal:    s.Counter(metrics.DownstreamBytesReadTotal),
-		DownstreamBytesWriteTotal:   s.Counter(metrics.DownstreamBytesWriteTotal),
-		DownstreamRequestTotal:      s.Counter(metrics.DownstreamRequestTotal),
-		DownstreamRequestActive:     s.Counter(metrics.DownstreamRequestActive),
-		DownstreamRequestReset:      s.Counter(metrics.DownstreamRequestReset),
-		DownstreamRequestTime:       s.Histogram(metrics.DownstreamRequestTime),
-		DownstreamRequestTimeTotal:  s.Counter(metrics.DownstreamRequestTimeTotal),
-		DownstreamProcessTime:       s.Histogram(metrics.DownstreamProcessTime),
-		DownstreamProcessTimeTotal:  s.Counter(metrics.DownstreamProcessTimeTotal),
-		DownstreamRequestFailed:     s.Counter(metrics.DownstreamRequestFailed),
+		DownstreamConnectionTotal:      s.Counter(metrics.DownstreamConnectionTotal),
+		DownstreamConnectionDestroy:    s.Counter(metrics.DownstreamConnectionDestroy),
+		DownstreamConnectionActive:     s.Counter(metrics.DownstreamConnectionActive),
+		DownstreamBytesReadTotal:       s.Counter(metrics.DownstreamBytesReadTotal),
+		DownstreamBytesWriteTotal:      s.Counter(metrics.DownstreamBytesWriteTotal),
+		DownstreamRequestTotal:         s.Counter(metrics.DownstreamRequestTotal),
+		DownstreamRequestActive:        s.Counter(metrics.DownstreamRequestActive),
+		DownstreamRequestReset:         s.Counter(metrics.DownstreamRequestReset),
+		DownstreamRequestTime:          s.Histogram(metrics.DownstreamRequestTime),
+		DownstreamRequestTimeTotal:     s.Counter(metrics.DownstreamRequestTimeTotal),
+		DownstreamProcessTime:          s.Histogram(metrics.DownstreamProcessTime),
+		DownstreamProcessTimeTotal:     s.Counter(metrics.DownstreamProcessTimeTotal),
+		DownstreamRequestFailed:        s.Counter(metrics.DownstreamRequestFailed),
+		DownstreamRequestRetrySameHost: s.Counter(metrics.DownstreamRequestRetrySameHost),
+		DownstreamMirrorRequestTotal:   s.Counter(metrics.DownstreamMirrorRequestTotal),
+		DownstreamMirrorRequestFailed:  s.Counter(metrics.DownstreamMirrorRequestFailed),
 	}
 }
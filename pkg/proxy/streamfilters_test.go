@@ -257,6 +257,32 @@ func TestRunSenderFiltersStop(t *testing.T) {
 }
 
 // Mock stream filters
+func TestSendHijackReplyWithBody(t *testing.T) {
+	s := &downStream{
+		proxy: &proxy{
+			routersWrapper: &mockRouterWrapper{},
+			clusterManager: &mockClusterManager{},
+		},
+		requestInfo: &network.RequestInfo{},
+	}
+	f := &mockStreamReceiverFilter{}
+	newActiveStreamReceiverFilter(s, f, types.DownFilterAfterRoute)
+	handler, ok := f.handler.(types.HijackReplyWithBodyHandler)
+	if !ok {
+		t.Fatal("stream receiver filter handler should implement types.HijackReplyWithBodyHandler")
+	}
+	handler.SendHijackReplyWithBody(403, nil, "denied")
+	if !s.directResponse || s.requestInfo.ResponseCode() != 403 {
+		t.Fatal("expected a direct response with code 403")
+	}
+	if code, _ := s.downstreamRespHeaders.Get(types.HeaderStatus); code != "403" {
+		t.Fatalf("unexpected status header: %s", code)
+	}
+	if s.downstreamRespDataBuf == nil || s.downstreamRespDataBuf.String() != "denied" {
+		t.Fatal("unexpected response body")
+	}
+}
+
 type mockStreamReceiverFilter struct {
 	handler api.StreamReceiverFilterHandler
 	// api called count
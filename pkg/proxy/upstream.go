@@ -20,12 +20,14 @@ package proxy
 import (
 	"container/list"
 	"context"
+	"net/http"
 	"time"
 
 	"sync/atomic"
 
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/trace"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -81,9 +83,32 @@ func (r *upstreamRequest) OnResetStream(reason types.StreamResetReason) {
 	}
 
 	r.downStream.resetReason = reason
+	r.putOutlierResetResult(reason)
 	r.downStream.sendNotify()
 }
 
+// putOutlierResult reports the upstream result to the cluster's outlier detector
+func (r *upstreamRequest) putOutlierResult(code int) {
+	if r.host == nil || r.downStream.cluster == nil {
+		return
+	}
+	if detector := r.downStream.cluster.OutlierDetector(); detector != nil {
+		detector.PutResult(r.host, code)
+	}
+}
+
+// putOutlierResetResult reports the upstream reset as a gateway failure,
+// the local reset and overflow are not the upstream host's failure
+func (r *upstreamRequest) putOutlierResetResult(reason types.StreamResetReason) {
+	switch reason {
+	case types.StreamLocalReset, types.StreamOverflow:
+	case types.UpstreamGlobalTimeout, types.UpstreamPerTryTimeout:
+		r.putOutlierResult(http.StatusGatewayTimeout)
+	default:
+		r.putOutlierResult(http.StatusServiceUnavailable)
+	}
+}
+
 func (r *upstreamRequest) OnDestroyStream() {}
 
 func (r *upstreamRequest) endStream() {
@@ -105,8 +130,9 @@ func (r *upstreamRequest) OnReceive(ctx context.Context, headers types.HeaderMap
 
 	r.endStream()
 
-	if code, err := protocol.MappingHeaderStatusCode(r.protocol, headers); err == nil {
+	if code, err := mappingHeaderStatusCode(ctx, r.protocol, headers); err == nil {
 		r.downStream.requestInfo.SetResponseCode(code)
+		r.putOutlierResult(code)
 	}
 
 	r.downStream.requestInfo.SetResponseReceivedDuration(time.Now())
@@ -264,6 +290,7 @@ func (r *upstreamRequest) OnFailure(reason types.PoolFailureReason, host types.H
 	}
 
 	r.host = host
+	r.downStream.onUpstreamHostSelected(host)
 	r.OnResetStream(resetReason)
 }
 
@@ -275,12 +302,20 @@ func (r *upstreamRequest) OnReady(sender types.StreamSender, host types.Host) {
 
 	r.requestSender = sender
 	r.host = host
+	r.downStream.onUpstreamHostSelected(host)
 	r.requestSender.GetStream().AddEventListener(r)
 	// start a upstream send
 	r.startTime = time.Now()
 
 	endStream := r.sendComplete && !r.dataSent && !r.trailerSent
-	r.requestSender.AppendHeaders(r.downStream.context, r.convertHeader(r.downStream.downstreamReqHeaders), endStream)
+	headers := r.convertHeader(r.downStream.downstreamReqHeaders)
+	// propagate the trace context to the upstream
+	if trace.IsEnabled() {
+		if span := trace.SpanFromContext(r.downStream.context); span != nil && headers != nil {
+			span.InjectContext(headers)
+		}
+	}
+	r.requestSender.AppendHeaders(r.downStream.context, headers, endStream)
 
 	r.downStream.requestInfo.OnUpstreamHostSelected(host)
 	r.downStream.requestInfo.SetUpstreamLocalAddress(host.AddressString())
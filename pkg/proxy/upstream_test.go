@@ -0,0 +1,59 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"reflect"
+	"testing"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestUpstreamRequestOutlierResult(t *testing.T) {
+	detector := &mockOutlierDetector{}
+	r := &upstreamRequest{
+		downStream: &downStream{
+			cluster: &mockOutlierClusterInfo{
+				detector: detector,
+			},
+		},
+	}
+	// no host is chosen
+	r.putOutlierResult(500)
+	if len(detector.results) != 0 {
+		t.Fatal("no result should be reported without host")
+	}
+	r.host = &mockHost{addr: "127.0.0.1:8080"}
+	r.putOutlierResult(200)
+	for _, reason := range []types.StreamResetReason{
+		types.StreamLocalReset,
+		types.StreamOverflow,
+		types.UpstreamPerTryTimeout,
+		types.StreamConnectionFailed,
+		types.StreamRemoteReset,
+	} {
+		r.putOutlierResetResult(reason)
+	}
+	expected := []int{200, 504, 503, 503}
+	if !reflect.DeepEqual(detector.results, expected) {
+		t.Fatalf("reported results %v, expected %v", detector.results, expected)
+	}
+	// no outlier detector
+	r.downStream.cluster = &mockOutlierClusterInfo{}
+	r.putOutlierResult(500)
+}
@@ -18,9 +18,12 @@
 package proxy
 
 import (
+	"context"
 	"strconv"
 	"time"
 
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -53,3 +56,24 @@ func parseProxyTimeout(timeout *Timeout, route types.Route, headers types.Header
 		timeout.TryTimeout = 0
 	}
 }
+
+func subProtocolByContext(ctx context.Context) types.ProtocolName {
+	if ctx == nil {
+		return ""
+	}
+	if sub, ok := mosnctx.Get(ctx, types.ContextSubProtocol).(string); ok {
+		return types.ProtocolName(sub)
+	}
+	return ""
+}
+
+// mappingHeaderStatusCode maps the headers into http status code,
+// if the protocol is xprotocol, use the sub protocol's mapping
+func mappingHeaderStatusCode(ctx context.Context, prot types.ProtocolName, headers types.HeaderMap) (int, error) {
+	if prot == protocol.Xprotocol {
+		if sub := subProtocolByContext(ctx); sub != "" {
+			prot = sub
+		}
+	}
+	return protocol.MappingHeaderStatusCode(prot, headers)
+}
@@ -0,0 +1,351 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rbac
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/mtls"
+	"mosn.io/mosn/pkg/router"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/variable"
+)
+
+const (
+	// the rpc service and method headers, same as the dubbo and tars protocol's header keys
+	headerService = "service"
+	headerMethod  = "method"
+	// jwtClaimVariablePrefix is the prefix of the jwt claim variables that registered by the jwt_authn stream filter
+	jwtClaimVariablePrefix = "jwt_claim_"
+)
+
+var (
+	errEmptyStringMatcher = errors.New("string matcher has no match condition")
+	errEmptyPermission    = errors.New("permission has no match condition")
+	errEmptyPrincipal     = errors.New("principal has no match condition")
+)
+
+type stringMatcher struct {
+	exact  string
+	prefix string
+	suffix string
+	regex  *regexp.Regexp
+}
+
+func newStringMatcher(cfg v2.RBACStringMatcher) (*stringMatcher, error) {
+	m := &stringMatcher{
+		exact:  cfg.Exact,
+		prefix: cfg.Prefix,
+		suffix: cfg.Suffix,
+	}
+	if cfg.Regex != "" {
+		regex, err := regexp.Compile(cfg.Regex)
+		if err != nil {
+			return nil, err
+		}
+		m.regex = regex
+	}
+	if m.exact == "" && m.prefix == "" && m.suffix == "" && m.regex == nil {
+		return nil, errEmptyStringMatcher
+	}
+	return m, nil
+}
+
+func newStringMatchers(cfgs []v2.RBACStringMatcher) ([]*stringMatcher, error) {
+	matchers := make([]*stringMatcher, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		m, err := newStringMatcher(cfg)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+func (m *stringMatcher) match(s string) bool {
+	switch {
+	case m.exact != "":
+		return s == m.exact
+	case m.prefix != "":
+		return strings.HasPrefix(s, m.prefix)
+	case m.suffix != "":
+		return strings.HasSuffix(s, m.suffix)
+	default:
+		return m.regex.MatchString(s)
+	}
+}
+
+func matchAnyString(matchers []*stringMatcher, values ...string) bool {
+	for _, m := range matchers {
+		for _, v := range values {
+			if m.match(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// permission is parsed from v2.RBACPermission
+type permission struct {
+	any        bool
+	paths      []*stringMatcher
+	methods    []string
+	headers    []*types.HeaderData
+	services   []*stringMatcher
+	rpcMethods []string
+	ports      []uint32
+}
+
+func newPermission(cfg *v2.RBACPermission) (*permission, error) {
+	p := &permission{
+		any:        cfg.Any,
+		methods:    cfg.Methods,
+		headers:    router.GetRouterHeaders(cfg.Headers),
+		rpcMethods: cfg.RPCMethods,
+		ports:      cfg.DestinationPorts,
+	}
+	var err error
+	if p.paths, err = newStringMatchers(cfg.Paths); err != nil {
+		return nil, err
+	}
+	if p.services, err = newStringMatchers(cfg.Services); err != nil {
+		return nil, err
+	}
+	if !p.any && len(p.paths) == 0 && len(p.methods) == 0 && len(p.headers) == 0 &&
+		len(p.services) == 0 && len(p.rpcMethods) == 0 && len(p.ports) == 0 {
+		return nil, errEmptyPermission
+	}
+	return p, nil
+}
+
+func (p *permission) match(req *Request) bool {
+	if p.any {
+		return true
+	}
+	if len(p.paths) > 0 {
+		path, ok := req.header(types.HeaderPath)
+		if !ok || !matchAnyString(p.paths, path) {
+			return false
+		}
+	}
+	if len(p.methods) > 0 {
+		method, ok := req.header(types.HeaderMethod)
+		if !ok || !containsString(p.methods, method) {
+			return false
+		}
+	}
+	if len(p.headers) > 0 && (req.Headers == nil || !router.ConfigUtilityInst.MatchHeaders(req.Headers, p.headers)) {
+		return false
+	}
+	if len(p.services) > 0 {
+		service, ok := req.header(headerService)
+		if !ok || !matchAnyString(p.services, service) {
+			return false
+		}
+	}
+	if len(p.rpcMethods) > 0 {
+		method, ok := req.header(headerMethod)
+		if !ok || !containsString(p.rpcMethods, method) {
+			return false
+		}
+	}
+	if len(p.ports) > 0 && !p.matchPort(req) {
+		return false
+	}
+	return true
+}
+
+func (p *permission) matchPort(req *Request) bool {
+	if req.Conn == nil || req.Conn.LocalAddr() == nil {
+		return false
+	}
+	_, portStr, err := net.SplitHostPort(req.Conn.LocalAddr().String())
+	if err != nil {
+		return false
+	}
+	port, err := strconv.ParseUint(portStr, 10, 32)
+	if err != nil {
+		return false
+	}
+	for _, p := range p.ports {
+		if uint64(p) == port {
+			return true
+		}
+	}
+	return false
+}
+
+// principal is parsed from v2.RBACPrincipal
+type principal struct {
+	any           bool
+	authenticated []*stringMatcher
+	sourceIPs     []*net.IPNet
+	headers       []*types.HeaderData
+	jwtClaims     map[string]*stringMatcher
+}
+
+func newPrincipal(cfg *v2.RBACPrincipal) (*principal, error) {
+	p := &principal{
+		any:       cfg.Any,
+		headers:   router.GetRouterHeaders(cfg.Headers),
+		jwtClaims: make(map[string]*stringMatcher, len(cfg.JwtClaims)),
+	}
+	var err error
+	if p.authenticated, err = newStringMatchers(cfg.Authenticated); err != nil {
+		return nil, err
+	}
+	for _, cidr := range cfg.SourceIPs {
+		// a single ip is treated as a /32 or /128 cidr
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		p.sourceIPs = append(p.sourceIPs, ipNet)
+	}
+	for name, cfg := range cfg.JwtClaims {
+		m, err := newStringMatcher(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("jwt claim %s: %v", name, err)
+		}
+		p.jwtClaims[name] = m
+	}
+	if !p.any && len(p.authenticated) == 0 && len(p.sourceIPs) == 0 && len(p.headers) == 0 && len(p.jwtClaims) == 0 {
+		return nil, errEmptyPrincipal
+	}
+	return p, nil
+}
+
+func (p *principal) match(req *Request) bool {
+	if p.any {
+		return true
+	}
+	if len(p.authenticated) > 0 && !p.matchAuthenticated(req) {
+		return false
+	}
+	if len(p.sourceIPs) > 0 && !p.matchSourceIP(req) {
+		return false
+	}
+	if len(p.headers) > 0 && (req.Headers == nil || !router.ConfigUtilityInst.MatchHeaders(req.Headers, p.headers)) {
+		return false
+	}
+	for name, m := range p.jwtClaims {
+		if !matchJwtClaim(req, name, m) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAuthenticated matches the mtls peer certificate
+func (p *principal) matchAuthenticated(req *Request) bool {
+	cert := peerCertificate(req)
+	if cert == nil {
+		return false
+	}
+	for _, uri := range cert.URIs {
+		if matchAnyString(p.authenticated, uri.String()) {
+			return true
+		}
+	}
+	return matchAnyString(p.authenticated, cert.DNSNames...) || matchAnyString(p.authenticated, cert.Subject.CommonName)
+}
+
+func peerCertificate(req *Request) *x509.Certificate {
+	if req.Conn == nil {
+		return nil
+	}
+	conn, ok := req.Conn.RawConn().(*mtls.TLSConn)
+	if !ok {
+		return nil
+	}
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+	return certs[0]
+}
+
+func (p *principal) matchSourceIP(req *Request) bool {
+	if req.Conn == nil || req.Conn.RemoteAddr() == nil {
+		return false
+	}
+	host, _, err := net.SplitHostPort(req.Conn.RemoteAddr().String())
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range p.sourceIPs {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchJwtClaim matches the claim written by the jwt_authn stream filter,
+// an array claim is matched if any of the string items is matched
+func matchJwtClaim(req *Request, name string, m *stringMatcher) bool {
+	if req.Context == nil {
+		return false
+	}
+	value, err := variable.GetVariableValue(req.Context, jwtClaimVariablePrefix+name)
+	if err != nil || value == variable.ValueNotFound {
+		return false
+	}
+	if strings.HasPrefix(value, "[") {
+		var items []interface{}
+		if err := json.Unmarshal([]byte(value), &items); err == nil {
+			for _, item := range items {
+				if s, ok := item.(string); ok && m.match(s) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return m.match(value)
+}
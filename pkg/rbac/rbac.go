@@ -0,0 +1,215 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+)
+
+const defaultStatPrefix = "rbac"
+
+// Request is the attributes of a request or a connection to be authorized
+type Request struct {
+	Context context.Context
+	// Headers is the request headers, it is nil if a connection is authorized
+	Headers api.HeaderMap
+	// Conn is the downstream connection
+	Conn api.Connection
+}
+
+func (r *Request) header(key string) (string, bool) {
+	if r.Headers == nil {
+		return "", false
+	}
+	return r.Headers.Get(key)
+}
+
+// Engine evaluates the request by the policies
+type Engine struct {
+	deny     bool
+	policies []*policy
+}
+
+type policy struct {
+	name        string
+	permissions []*permission
+	principals  []*principal
+}
+
+func (p *policy) match(req *Request) bool {
+	matched := false
+	for _, perm := range p.permissions {
+		if perm.match(req) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	for _, prin := range p.principals {
+		if prin.match(req) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewEngine creates an engine by the rules
+func NewEngine(cfg *v2.RBACRules) (*Engine, error) {
+	e := &Engine{}
+	switch cfg.Action {
+	case "", v2.RBACActionAllow:
+	case v2.RBACActionDeny:
+		e.deny = true
+	default:
+		return nil, fmt.Errorf("unknown rbac action: %s", cfg.Action)
+	}
+	for name, pc := range cfg.Policies {
+		if pc == nil {
+			return nil, fmt.Errorf("rbac policy %s has no config", name)
+		}
+		p := &policy{
+			name: name,
+		}
+		for _, c := range pc.Permissions {
+			perm, err := newPermission(c)
+			if err != nil {
+				return nil, fmt.Errorf("rbac policy %s: %v", name, err)
+			}
+			p.permissions = append(p.permissions, perm)
+		}
+		for _, c := range pc.Principals {
+			prin, err := newPrincipal(c)
+			if err != nil {
+				return nil, fmt.Errorf("rbac policy %s: %v", name, err)
+			}
+			p.principals = append(p.principals, prin)
+		}
+		e.policies = append(e.policies, p)
+	}
+	// the policies are matched in the order of names, so the matched policy is stable
+	sort.Slice(e.policies, func(i, j int) bool {
+		return e.policies[i].name < e.policies[j].name
+	})
+	return e, nil
+}
+
+// Allowed returns whether the request is allowed, and the matched policy name
+func (e *Engine) Allowed(req *Request) (bool, string) {
+	for _, p := range e.policies {
+		if p.match(req) {
+			return !e.deny, p.name
+		}
+	}
+	return e.deny, ""
+}
+
+// Stats is the rbac metrics
+type Stats struct {
+	Allowed       gometrics.Counter
+	Denied        gometrics.Counter
+	ShadowAllowed gometrics.Counter
+	ShadowDenied  gometrics.Counter
+}
+
+func newStats(statPrefix string) *Stats {
+	s := metrics.NewRBACStats(statPrefix)
+	return &Stats{
+		Allowed:       s.Counter(metrics.RBACAllowed),
+		Denied:        s.Counter(metrics.RBACDenied),
+		ShadowAllowed: s.Counter(metrics.RBACShadowAllowed),
+		ShadowDenied:  s.Counter(metrics.RBACShadowDenied),
+	}
+}
+
+// Authorizer authorizes the requests by the enforced rules,
+// the shadow rules are also evaluated, but the decisions are only logged and counted
+type Authorizer struct {
+	engine *Engine
+	shadow *Engine
+	stats  *Stats
+}
+
+// NewAuthorizer creates an authorizer by the rbac config
+func NewAuthorizer(cfg *v2.RBACConfig) (*Authorizer, error) {
+	a := &Authorizer{}
+	var err error
+	if cfg.Rules != nil {
+		if a.engine, err = NewEngine(cfg.Rules); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.ShadowRules != nil {
+		if a.shadow, err = NewEngine(cfg.ShadowRules); err != nil {
+			return nil, err
+		}
+	}
+	statPrefix := cfg.StatPrefix
+	if statPrefix == "" {
+		statPrefix = defaultStatPrefix
+	}
+	a.stats = newStats(statPrefix)
+	return a, nil
+}
+
+// Stats returns the authorizer's metrics
+func (a *Authorizer) Stats() *Stats {
+	return a.stats
+}
+
+// Authorize returns whether the request is allowed by the enforced rules
+func (a *Authorizer) Authorize(req *Request) bool {
+	ctx := req.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if a.shadow != nil {
+		allowed, policy := a.shadow.Allowed(req)
+		if allowed {
+			a.stats.ShadowAllowed.Inc(1)
+			if log.Proxy.GetLogLevel() >= log.DEBUG {
+				log.Proxy.Debugf(ctx, "[rbac] shadow rules allow the request, matched policy: %s", policy)
+			}
+		} else {
+			a.stats.ShadowDenied.Inc(1)
+			log.Proxy.Infof(ctx, "[rbac] shadow rules deny the request, matched policy: %s", policy)
+		}
+	}
+	if a.engine == nil {
+		return true
+	}
+	allowed, policy := a.engine.Allowed(req)
+	if allowed {
+		a.stats.Allowed.Inc(1)
+	} else {
+		a.stats.Denied.Inc(1)
+	}
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[rbac] rules allow the request: %v, matched policy: %s", allowed, policy)
+	}
+	return allowed
+}
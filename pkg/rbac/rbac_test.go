@@ -0,0 +1,260 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rbac
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/variable"
+)
+
+type mockConnection struct {
+	api.Connection
+	local  net.Addr
+	remote net.Addr
+}
+
+func (c *mockConnection) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *mockConnection) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *mockConnection) RawConn() net.Conn {
+	return nil
+}
+
+func newMockConnection(local, remote string) *mockConnection {
+	l, _ := net.ResolveTCPAddr("tcp", local)
+	r, _ := net.ResolveTCPAddr("tcp", remote)
+	return &mockConnection{local: l, remote: r}
+}
+
+func init() {
+	// the jwt claim variables are registered by the jwt_authn stream filter,
+	// register a mock one that reads the claim from the indexed variable
+	variable.RegisterVariable(variable.NewIndexedVariable("test_jwt_claim_groups", nil, nil, variable.BasicSetter, 0))
+	variable.RegisterPrefixVariable(jwtClaimVariablePrefix, variable.NewBasicVariable(jwtClaimVariablePrefix, nil,
+		func(ctx context.Context, value *variable.IndexedValue, data interface{}) (string, error) {
+			if data.(string) != jwtClaimVariablePrefix+"groups" {
+				return variable.ValueNotFound, nil
+			}
+			return variable.GetVariableValue(ctx, "test_jwt_claim_groups")
+		}, nil, 0))
+}
+
+func TestNewEngineInvalid(t *testing.T) {
+	for idx, cfg := range []*v2.RBACRules{
+		{Action: "LOG"},
+		{Policies: map[string]*v2.RBACPolicy{"p": nil}},
+		{Policies: map[string]*v2.RBACPolicy{"p": {Permissions: []*v2.RBACPermission{{}}}}},
+		{Policies: map[string]*v2.RBACPolicy{"p": {Principals: []*v2.RBACPrincipal{{}}}}},
+		{Policies: map[string]*v2.RBACPolicy{"p": {Permissions: []*v2.RBACPermission{{Paths: []v2.RBACStringMatcher{{}}}}}}},
+		{Policies: map[string]*v2.RBACPolicy{"p": {Permissions: []*v2.RBACPermission{{Paths: []v2.RBACStringMatcher{{Regex: "("}}}}}}},
+		{Policies: map[string]*v2.RBACPolicy{"p": {Principals: []*v2.RBACPrincipal{{SourceIPs: []string{"10.0.0.0/33"}}}}}},
+	} {
+		if _, err := NewEngine(cfg); err == nil {
+			t.Errorf("case %d expected create engine failed", idx)
+		}
+	}
+}
+
+func TestEngineAllow(t *testing.T) {
+	engine, err := NewEngine(&v2.RBACRules{
+		Action: v2.RBACActionAllow,
+		Policies: map[string]*v2.RBACPolicy{
+			"http": {
+				Permissions: []*v2.RBACPermission{
+					{
+						Paths:   []v2.RBACStringMatcher{{Prefix: "/api/"}, {Exact: "/health"}},
+						Methods: []string{"GET", "POST"},
+					},
+				},
+				Principals: []*v2.RBACPrincipal{
+					{SourceIPs: []string{"10.0.0.0/8", "192.168.1.1"}},
+				},
+			},
+			"rpc": {
+				Permissions: []*v2.RBACPermission{
+					{
+						Services:   []v2.RBACStringMatcher{{Suffix: ".HelloService"}},
+						RPCMethods: []string{"sayHello"},
+					},
+				},
+				Principals: []*v2.RBACPrincipal{
+					{Headers: []v2.HeaderMatcher{{Name: "app", Value: "client"}}},
+				},
+			},
+			"admin": {
+				Permissions: []*v2.RBACPermission{
+					{DestinationPorts: []uint32{9090}},
+				},
+				Principals: []*v2.RBACPrincipal{
+					{JwtClaims: map[string]v2.RBACStringMatcher{"groups": {Exact: "admin"}}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := variable.NewVariableContext(context.Background())
+	variable.SetVariableValue(ctx, "test_jwt_claim_groups", `["dev","admin"]`)
+	for idx, tc := range []struct {
+		ctx     context.Context
+		headers api.HeaderMap
+		conn    api.Connection
+		allowed bool
+		policy  string
+	}{
+		{
+			headers: protocol.CommonHeader{types.HeaderPath: "/api/users", types.HeaderMethod: "GET"},
+			conn:    newMockConnection("127.0.0.1:80", "10.1.1.1:1234"),
+			allowed: true,
+			policy:  "http",
+		},
+		{
+			headers: protocol.CommonHeader{types.HeaderPath: "/health", types.HeaderMethod: "POST"},
+			conn:    newMockConnection("127.0.0.1:80", "192.168.1.1:1234"),
+			allowed: true,
+			policy:  "http",
+		},
+		// source ip is not matched
+		{
+			headers: protocol.CommonHeader{types.HeaderPath: "/api/users", types.HeaderMethod: "GET"},
+			conn:    newMockConnection("127.0.0.1:80", "192.168.1.2:1234"),
+		},
+		// method is not matched
+		{
+			headers: protocol.CommonHeader{types.HeaderPath: "/api/users", types.HeaderMethod: "DELETE"},
+			conn:    newMockConnection("127.0.0.1:80", "10.1.1.1:1234"),
+		},
+		{
+			headers: protocol.CommonHeader{"service": "com.alipay.HelloService", "method": "sayHello", "app": "client"},
+			conn:    newMockConnection("127.0.0.1:12200", "127.0.0.1:1234"),
+			allowed: true,
+			policy:  "rpc",
+		},
+		{
+			headers: protocol.CommonHeader{"service": "com.alipay.HelloService", "method": "sayBye", "app": "client"},
+			conn:    newMockConnection("127.0.0.1:12200", "127.0.0.1:1234"),
+		},
+		{
+			ctx:     ctx,
+			headers: protocol.CommonHeader{},
+			conn:    newMockConnection("127.0.0.1:9090", "127.0.0.1:1234"),
+			allowed: true,
+			policy:  "admin",
+		},
+		// no jwt claims
+		{
+			ctx:     variable.NewVariableContext(context.Background()),
+			headers: protocol.CommonHeader{},
+			conn:    newMockConnection("127.0.0.1:9090", "127.0.0.1:1234"),
+		},
+		// the connection only matches the connection attributes
+		{
+			conn: newMockConnection("127.0.0.1:80", "10.1.1.1:1234"),
+		},
+	} {
+		allowed, policy := engine.Allowed(&Request{
+			Context: tc.ctx,
+			Headers: tc.headers,
+			Conn:    tc.conn,
+		})
+		if allowed != tc.allowed || policy != tc.policy {
+			t.Errorf("case %d expected %v %s, but got %v %s", idx, tc.allowed, tc.policy, allowed, policy)
+		}
+	}
+}
+
+func TestEngineDeny(t *testing.T) {
+	engine, err := NewEngine(&v2.RBACRules{
+		Action: v2.RBACActionDeny,
+		Policies: map[string]*v2.RBACPolicy{
+			"deny-admin": {
+				Permissions: []*v2.RBACPermission{
+					{Paths: []v2.RBACStringMatcher{{Regex: "^/admin(/.*)?$"}}},
+				},
+				Principals: []*v2.RBACPrincipal{
+					{Any: true},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed, policy := engine.Allowed(&Request{Headers: protocol.CommonHeader{types.HeaderPath: "/admin/config"}}); allowed || policy != "deny-admin" {
+		t.Fatalf("expected denied by deny-admin, but got %v %s", allowed, policy)
+	}
+	if allowed, _ := engine.Allowed(&Request{Headers: protocol.CommonHeader{types.HeaderPath: "/api"}}); !allowed {
+		t.Fatal("expected allowed")
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	denyAll := &v2.RBACRules{
+		Action: v2.RBACActionDeny,
+		Policies: map[string]*v2.RBACPolicy{
+			"all": {
+				Permissions: []*v2.RBACPermission{{Any: true}},
+				Principals:  []*v2.RBACPrincipal{{Any: true}},
+			},
+		},
+	}
+	// shadow rules only
+	a, err := NewAuthorizer(&v2.RBACConfig{
+		StatPrefix:  "test_authorizer_shadow",
+		ShadowRules: denyAll,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Authorize(&Request{}) {
+		t.Fatal("shadow rules should not deny the request")
+	}
+	if a.Stats().ShadowDenied.Count() != 1 || a.Stats().Denied.Count() != 0 || a.Stats().Allowed.Count() != 0 {
+		t.Fatal("unexpected stats")
+	}
+	// enforced rules
+	a, err = NewAuthorizer(&v2.RBACConfig{
+		StatPrefix: "test_authorizer_enforced",
+		Rules:      denyAll,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Authorize(&Request{}) {
+		t.Fatal("expected denied")
+	}
+	if a.Stats().Denied.Count() != 1 || a.Stats().ShadowDenied.Count() != 0 {
+		t.Fatal("unexpected stats")
+	}
+	if _, err := NewAuthorizer(&v2.RBACConfig{Rules: &v2.RBACRules{Action: "unknown"}}); err == nil {
+		t.Fatal("expected create authorizer failed")
+	}
+}
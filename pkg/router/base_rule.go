@@ -36,10 +36,16 @@ type RouteRuleImplBase struct {
 	vHost                 *VirtualHostImpl
 	routerMatch           v2.RouterMatch
 	configHeaders         []*types.HeaderData
-	configQueryParameters []types.QueryParameterMatcher //TODO: not implement yet
+	configQueryParameters []types.QueryParameterMatcher
+	methods               []string
+	caseSensitive         *bool
+	runtimeFraction       *fractionalPercent
 	// rewrite
 	prefixRewrite         string
+	regexRewrite          *regexRewrite
 	hostRewrite           string
+	hostRewriteHeader     string
+	hostRewritePathRegex  *regexRewrite
 	autoHostRewrite       bool // TODO: not implement yet
 	requestHeadersParser  *headerParser
 	responseHeadersParser *headerParser
@@ -50,6 +56,10 @@ type RouteRuleImplBase struct {
 	policy *policy
 	// direct response
 	directResponseRule *directResponseImpl
+	// redirect
+	redirectRule *redirectImpl
+	// cors
+	corsPolicy *corsPolicyImpl
 	// action
 	routerAction       v2.RouteAction
 	defaultCluster     *weightedClusterEntry // cluster name and metadata
@@ -64,13 +74,17 @@ func NewRouteRuleImplBase(vHost *VirtualHostImpl, route *v2.Router) (*RouteRuleI
 		vHost:                 vHost,
 		routerMatch:           route.Match,
 		configHeaders:         getRouterHeaders(route.Match.Headers),
+		configQueryParameters: getQueryParameterMatchers(route.Match.QueryParameters),
+		methods:               route.Match.Methods,
+		caseSensitive:         route.Match.CaseSensitive,
+		runtimeFraction:       newFractionalPercent(route.Match.RuntimeFraction),
 		prefixRewrite:         route.Route.PrefixRewrite,
 		hostRewrite:           route.Route.HostRewrite,
+		hostRewriteHeader:     route.Route.HostRewriteHeader,
 		autoHostRewrite:       route.Route.AutoHostRewrite,
 		requestHeadersParser:  getHeaderParser(route.Route.RequestHeadersToAdd, nil),
 		responseHeadersParser: getHeaderParser(route.Route.ResponseHeadersToAdd, route.Route.ResponseHeadersToRemove),
 		upstreamProtocol:      route.Route.UpstreamProtocol,
-		perFilterConfig:       route.PerFilterConfig,
 		policy:                &policy{},
 		routerAction:          route.Route,
 		defaultCluster: &weightedClusterEntry{
@@ -78,6 +92,21 @@ func NewRouteRuleImplBase(vHost *VirtualHostImpl, route *v2.Router) (*RouteRuleI
 		},
 		lock: sync.Mutex{},
 	}
+	// add rewrite
+	if route.Route.PrefixRewrite != "" && route.Route.RegexRewrite != nil {
+		return nil, ErrRewriteConflict
+	}
+	perFilterConfig, err := parsePerFilterConfig(route.PerFilterConfig)
+	if err != nil {
+		return nil, err
+	}
+	base.perFilterConfig = mergePerFilterConfig(vHost, perFilterConfig)
+	if base.regexRewrite, err = newRegexRewrite(route.Route.RegexRewrite); err != nil {
+		return nil, err
+	}
+	if base.hostRewritePathRegex, err = newRegexRewrite(route.Route.HostRewritePathRegex); err != nil {
+		return nil, err
+	}
 	// add clusters
 	base.weightedClusters, base.totalClusterWeight = getWeightedClusterEntry(route.Route.WeightedClusters)
 	if len(route.Route.MetadataMatch) > 0 {
@@ -85,11 +114,14 @@ func NewRouteRuleImplBase(vHost *VirtualHostImpl, route *v2.Router) (*RouteRuleI
 	}
 	// add policy
 	if route.Route.RetryPolicy != nil {
-		base.policy.retryPolicy = &retryPolicyImpl{
-			retryOn:      route.Route.RetryPolicy.RetryOn,
-			retryTimeout: route.Route.RetryPolicy.RetryTimeout,
-			numRetries:   route.Route.RetryPolicy.NumRetries,
-		}
+		base.policy.retryPolicy = newRetryPolicy(route.Route.RetryPolicy)
+	}
+	if hp := newHashPolicy(route.Route.HashPolicy); hp != nil {
+		base.policy.hashPolicy = hp
+	}
+	if mps := getMirrorPolicies(route.Route.RequestMirrorPolicies); len(mps) > 0 {
+		base.policy.mirrorPolicies = mps
+		base.policy.shadowPolicy = mps[0].(*shadowPolicyImpl)
 	}
 	// add direct repsonse rule
 	if route.DirectResponse != nil {
@@ -98,6 +130,18 @@ func NewRouteRuleImplBase(vHost *VirtualHostImpl, route *v2.Router) (*RouteRuleI
 			body:   route.DirectResponse.Body,
 		}
 	}
+	// add redirect rule
+	if route.Redirect != nil {
+		redirect, err := newRedirectImpl(route.Redirect, route.Match)
+		if err != nil {
+			return nil, err
+		}
+		base.redirectRule = redirect
+	}
+	// add cors policy
+	if base.corsPolicy, err = newCorsPolicyImpl(route.CorsPolicy); err != nil {
+		return nil, err
+	}
 	return base, nil
 }
 
@@ -105,6 +149,14 @@ func (rri *RouteRuleImplBase) DirectResponseRule() api.DirectResponseRule {
 	return rri.directResponseRule
 }
 
+// types.RedirectRoute
+func (rri *RouteRuleImplBase) RedirectRule() types.RedirectRule {
+	if rri.redirectRule == nil {
+		return nil
+	}
+	return rri.redirectRule
+}
+
 // types.RouteRule
 // Select Cluster for Routing
 // if weighted cluster is nil, return clusterName directly, else
@@ -154,23 +206,60 @@ func (rri *RouteRuleImplBase) MetadataMatchCriteria(clusterName string) api.Meta
 
 }
 
+// mergePerFilterConfig merges the virtual host's per filter config into the route's,
+// the route's config is used if both of them have the config of a filter
+func mergePerFilterConfig(vHost *VirtualHostImpl, routeConfig map[string]interface{}) map[string]interface{} {
+	if vHost == nil || len(vHost.perFilterConfig) == 0 {
+		return routeConfig
+	}
+	merged := make(map[string]interface{}, len(vHost.perFilterConfig)+len(routeConfig))
+	for name, cfg := range vHost.perFilterConfig {
+		merged[name] = cfg
+	}
+	for name, cfg := range routeConfig {
+		merged[name] = cfg
+	}
+	return merged
+}
+
+// types.CorsRoute
+func (rri *RouteRuleImplBase) CorsPolicy() types.CorsPolicy {
+	if rri.corsPolicy != nil {
+		return rri.corsPolicy
+	}
+	if rri.vHost != nil && rri.vHost.corsPolicy != nil {
+		return rri.vHost.corsPolicy
+	}
+	return nil
+}
+
 func (rri *RouteRuleImplBase) PerFilterConfig() map[string]interface{} {
 	return rri.perFilterConfig
 }
 
 // matchRoute is a common matched for http
 func (rri *RouteRuleImplBase) matchRoute(headers api.HeaderMap, randomValue uint64) bool {
-	// 1. match headers' KV
+	// 1. match runtime fraction
+	if rri.runtimeFraction != nil && !rri.runtimeFraction.match(randomValue) {
+		log.DefaultLogger.Debugf(RouterLogFormat, "routerule", "match runtime fraction", randomValue)
+		return false
+	}
+	// 2. match method
+	if len(rri.methods) > 0 && !rri.matchMethod(headers) {
+		log.DefaultLogger.Debugf(RouterLogFormat, "routerule", "match method", headers)
+		return false
+	}
+	// 3. match headers' KV
 	if !ConfigUtilityInst.MatchHeaders(headers, rri.configHeaders) {
 		log.DefaultLogger.Debugf(RouterLogFormat, "routerule", "match header", headers)
 		return false
 	}
-	// 2. match query parameters
-	var queryParams types.QueryParams
-	if QueryString, ok := headers.Get(protocol.MosnHeaderQueryStringKey); ok {
-		queryParams = httpmosn.ParseQueryString(QueryString)
-	}
-	if len(queryParams) != 0 {
+	// 4. match query parameters
+	if len(rri.configQueryParameters) > 0 {
+		var queryParams types.QueryParams
+		if QueryString, ok := headers.Get(protocol.MosnHeaderQueryStringKey); ok {
+			queryParams = httpmosn.ParseQueryString(QueryString)
+		}
 		if !ConfigUtilityInst.MatchQueryParams(queryParams, rri.configQueryParameters) {
 			log.DefaultLogger.Debugf(RouterLogFormat, "routerule", "match query params", queryParams)
 			return false
@@ -179,15 +268,66 @@ func (rri *RouteRuleImplBase) matchRoute(headers api.HeaderMap, randomValue uint
 	return true
 }
 
+func (rri *RouteRuleImplBase) matchMethod(headers api.HeaderMap) bool {
+	method, ok := headers.Get(protocol.MosnHeaderMethod)
+	if !ok {
+		return false
+	}
+	for _, m := range rri.methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCaseSensitive returns whether the path matching is case sensitive, returns defaultValue if it is not configured
+func (rri *RouteRuleImplBase) isCaseSensitive(defaultValue bool) bool {
+	if rri.caseSensitive == nil {
+		return defaultValue
+	}
+	return *rri.caseSensitive
+}
+
 func (rri *RouteRuleImplBase) finalizePathHeader(headers api.HeaderMap, matchedPath string) {
-	if len(rri.prefixRewrite) < 1 {
+	if len(rri.prefixRewrite) < 1 && rri.regexRewrite == nil {
 		return
 	}
-	if path, ok := headers.Get(protocol.MosnHeaderPathKey); ok {
-		if strings.HasPrefix(path, matchedPath) {
-			headers.Set(protocol.MosnOriginalHeaderPathKey, path)
-			headers.Set(protocol.MosnHeaderPathKey, rri.prefixRewrite+path[len(matchedPath):])
-			log.DefaultLogger.Infof(RouterLogFormat, "routerule", "finalizePathHeader", "add prefix to path, prefix is "+rri.prefixRewrite)
+	path, ok := headers.Get(protocol.MosnHeaderPathKey)
+	if !ok {
+		return
+	}
+	if rri.regexRewrite != nil {
+		if rewritten, ok := rri.regexRewrite.rewrite(path); ok {
+			setPath(headers, path, rewritten)
+			log.DefaultLogger.Infof(RouterLogFormat, "routerule", "finalizePathHeader", "rewrite path by regex, path is "+rewritten)
+		}
+		return
+	}
+	if strings.HasPrefix(path, matchedPath) {
+		setPath(headers, path, rri.prefixRewrite+path[len(matchedPath):])
+		log.DefaultLogger.Infof(RouterLogFormat, "routerule", "finalizePathHeader", "add prefix to path, prefix is "+rri.prefixRewrite)
+	}
+}
+
+// finalizeHostHeader rewrites the host, the host_rewrite takes precedence over
+// the host_rewrite_header, and the host_rewrite_header takes precedence over the host_rewrite_path_regex
+func (rri *RouteRuleImplBase) finalizeHostHeader(headers api.HeaderMap) {
+	if len(rri.hostRewrite) > 0 {
+		headers.Set(protocol.IstioHeaderHostKey, rri.hostRewrite)
+		return
+	}
+	if len(rri.hostRewriteHeader) > 0 {
+		if host, ok := headers.Get(rri.hostRewriteHeader); ok && host != "" {
+			headers.Set(protocol.IstioHeaderHostKey, host)
+			return
+		}
+	}
+	if rri.hostRewritePathRegex != nil {
+		if path, ok := headers.Get(protocol.MosnHeaderPathKey); ok {
+			if host, ok := rri.hostRewritePathRegex.rewrite(path); ok && host != "" {
+				headers.Set(protocol.IstioHeaderHostKey, host)
+			}
 		}
 	}
 }
@@ -200,9 +340,7 @@ func (rri *RouteRuleImplBase) finalizeRequestHeaders(headers api.HeaderMap, requ
 	rri.requestHeadersParser.evaluateHeaders(headers, requestInfo)
 	rri.vHost.requestHeadersParser.evaluateHeaders(headers, requestInfo)
 	rri.vHost.globalRouteConfig.requestHeadersParser.evaluateHeaders(headers, requestInfo)
-	if len(rri.hostRewrite) > 0 {
-		headers.Set(protocol.IstioHeaderHostKey, rri.hostRewrite)
-	}
+	rri.finalizeHostHeader(headers)
 }
 
 func (rri *RouteRuleImplBase) FinalizeResponseHeaders(headers api.HeaderMap, requestInfo api.RequestInfo) {
@@ -481,3 +481,145 @@ func Test_RouteRuleImplBase_FinalizeResponseHeaders(t *testing.T) {
 		})
 	}
 }
+
+func Test_RouteRuleImplBase_regexRewrite(t *testing.T) {
+	route := &v2.Router{}
+	route.Route.RegexRewrite = &v2.RegexRewrite{
+		Pattern:      `^/v1/users/(\d+)/orders$`,
+		Substitution: `/orders?user=\1`,
+	}
+	rri, err := NewRouteRuleImplBase(nil, route)
+	if err != nil {
+		t.Fatal("create route rule failed: ", err)
+	}
+	tests := []struct {
+		name    string
+		headers types.HeaderMap
+		want    types.HeaderMap
+	}{
+		{
+			name:    "rewrite with query",
+			headers: protocol.CommonHeader{protocol.MosnHeaderPathKey: "/v1/users/123/orders", protocol.MosnHeaderQueryStringKey: "page=1"},
+			want: protocol.CommonHeader{
+				protocol.MosnHeaderPathKey:         "/orders",
+				protocol.MosnHeaderQueryStringKey:  "user=123&page=1",
+				protocol.MosnOriginalHeaderPathKey: "/v1/users/123/orders",
+			},
+		},
+		{
+			name:    "rewrite without query",
+			headers: protocol.CommonHeader{protocol.MosnHeaderPathKey: "/v1/users/456/orders"},
+			want: protocol.CommonHeader{
+				protocol.MosnHeaderPathKey:         "/orders",
+				protocol.MosnHeaderQueryStringKey:  "user=456",
+				protocol.MosnOriginalHeaderPathKey: "/v1/users/456/orders",
+			},
+		},
+		{
+			name:    "not matched",
+			headers: protocol.CommonHeader{protocol.MosnHeaderPathKey: "/v1/users/abc/orders"},
+			want:    protocol.CommonHeader{protocol.MosnHeaderPathKey: "/v1/users/abc/orders"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rri.finalizePathHeader(tt.headers, "/")
+			if !reflect.DeepEqual(tt.headers, tt.want) {
+				t.Errorf("finalizePathHeader() = %v, want %v", tt.headers, tt.want)
+			}
+		})
+	}
+	// prefix rewrite and regex rewrite are mutually exclusive
+	route.Route.PrefixRewrite = "/abc"
+	if _, err := NewRouteRuleImplBase(nil, route); err != ErrRewriteConflict {
+		t.Errorf("expected rewrite conflict error, but got %v", err)
+	}
+	// invalid pattern
+	route.Route.PrefixRewrite = ""
+	route.Route.RegexRewrite.Pattern = "/v1/("
+	if _, err := NewRouteRuleImplBase(nil, route); err == nil {
+		t.Error("expected regex compile error")
+	}
+}
+
+func Test_RouteRuleImplBase_finalizeHostHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  v2.RouterActionConfig
+		headers types.HeaderMap
+		want    string
+	}{
+		{
+			name: "host rewrite takes precedence",
+			action: v2.RouterActionConfig{
+				HostRewrite:       "www.mosn.io",
+				HostRewriteHeader: "x-host",
+			},
+			headers: protocol.CommonHeader{"x-host": "foo.mosn.io"},
+			want:    "www.mosn.io",
+		},
+		{
+			name: "host rewrite header",
+			action: v2.RouterActionConfig{
+				HostRewriteHeader: "x-host",
+			},
+			headers: protocol.CommonHeader{"x-host": "foo.mosn.io"},
+			want:    "foo.mosn.io",
+		},
+		{
+			name: "host rewrite path regex, the header is absent",
+			action: v2.RouterActionConfig{
+				HostRewriteHeader: "x-host",
+				HostRewritePathRegex: &v2.RegexRewrite{
+					Pattern:      `^/([a-z]+)/.*$`,
+					Substitution: `\1.mosn.io`,
+				},
+			},
+			headers: protocol.CommonHeader{protocol.MosnHeaderPathKey: "/bar/index.html"},
+			want:    "bar.mosn.io",
+		},
+		{
+			name: "host rewrite path regex not matched",
+			action: v2.RouterActionConfig{
+				HostRewritePathRegex: &v2.RegexRewrite{
+					Pattern:      `^/([a-z]+)/.*$`,
+					Substitution: `\1.mosn.io`,
+				},
+			},
+			headers: protocol.CommonHeader{protocol.MosnHeaderPathKey: "/"},
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := &v2.Router{}
+			route.Route.RouterActionConfig = tt.action
+			rri, err := NewRouteRuleImplBase(nil, route)
+			if err != nil {
+				t.Fatal("create route rule failed: ", err)
+			}
+			rri.finalizeHostHeader(tt.headers)
+			if host, _ := tt.headers.Get(protocol.IstioHeaderHostKey); host != tt.want {
+				t.Errorf("finalizeHostHeader() host = %s, want %s", host, tt.want)
+			}
+		})
+	}
+}
+
+func Test_convertSubstitution(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`/orders`, `/orders`},
+		{`/orders?user=\1`, `/orders?user=${1}`},
+		{`/\2/\1`, `/${2}/${1}`},
+		{`/\\1/${1}`, `/\1/${1}`},
+		{`/a\b`, `/a\b`},
+	}
+	for _, tt := range tests {
+		if got := convertSubstitution(tt.in); got != tt.want {
+			t.Errorf("convertSubstitution(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
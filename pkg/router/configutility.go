@@ -20,6 +20,8 @@ package router
 import (
 	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 
 	"mosn.io/api"
 	v2 "mosn.io/mosn/pkg/config/v2"
@@ -40,27 +42,45 @@ func (cu *configUtility) MatchHeaders(requestHeaders api.HeaderMap, configHeader
 		log.DefaultLogger.Debugf(RouterLogFormat, "config utility", "try match header", requestHeaders)
 	}
 	for _, cfgHeaderData := range configHeaders {
-		cfgName := cfgHeaderData.Name.Get()
-		cfgValue := cfgHeaderData.Value
 		// if a condition is not matched, return false
 		// all condition matched, return true
-		value, ok := requestHeaders.Get(cfgName)
-		if !ok {
+		value, ok := requestHeaders.Get(cfgHeaderData.Name.Get())
+		if matchHeader(cfgHeaderData, value, ok) == cfgHeaderData.InvertMatch {
 			return false
 		}
-		if cfgHeaderData.IsRegex {
-			if !cfgHeaderData.RegexPattern.MatchString(value) {
-				return false
-			}
-		} else {
-			if cfgValue != value {
-				return false
-			}
-		}
 	}
 	return true
 }
 
+// matchHeader matches a header by the header data, without invert
+func matchHeader(cfgHeaderData *types.HeaderData, value string, present bool) bool {
+	switch cfgHeaderData.MatchType {
+	case types.HeaderMatchPresent:
+		return present
+	case types.HeaderMatchAbsent:
+		return !present
+	}
+	if !present {
+		return false
+	}
+	switch cfgHeaderData.MatchType {
+	case types.HeaderMatchPrefix:
+		return strings.HasPrefix(value, cfgHeaderData.Value)
+	case types.HeaderMatchSuffix:
+		return strings.HasSuffix(value, cfgHeaderData.Value)
+	case types.HeaderMatchRange:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		return v >= cfgHeaderData.RangeStart && v < cfgHeaderData.RangeEnd
+	}
+	if cfgHeaderData.IsRegex {
+		return cfgHeaderData.RegexPattern.MatchString(value)
+	}
+	return cfgHeaderData.Value == value
+}
+
 // types.MatchQueryParams
 func (cu *configUtility) MatchQueryParams(queryParams types.QueryParams, configQueryParams []types.QueryParameterMatcher) bool {
 	if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
@@ -24,6 +24,8 @@ import (
 
 	"mosn.io/api"
 	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
 )
 
 func TestNewMetadataMatchCriteriaImpl(t *testing.T) {
@@ -215,3 +217,59 @@ func TestMetadataMatchCriteriaImplSort(t *testing.T) {
 		}
 	}
 }
+
+func TestMatchHeaders(t *testing.T) {
+	testCases := []struct {
+		name     string
+		matcher  v2.HeaderMatcher
+		headers  map[string]string
+		expected bool
+	}{
+		{"exact", v2.HeaderMatcher{Name: "service", Value: "test"}, map[string]string{"service": "test"}, true},
+		{"exact not match", v2.HeaderMatcher{Name: "service", Value: "test"}, map[string]string{"service": "foo"}, false},
+		{"regex", v2.HeaderMatcher{Name: "service", Value: "t.*", Regex: true}, map[string]string{"service": "test"}, true},
+		{"present", v2.HeaderMatcher{Name: "service", PresentMatch: true}, map[string]string{"service": ""}, true},
+		{"present not match", v2.HeaderMatcher{Name: "service", PresentMatch: true}, map[string]string{}, false},
+		{"absent", v2.HeaderMatcher{Name: "service", AbsentMatch: true}, map[string]string{}, true},
+		{"absent not match", v2.HeaderMatcher{Name: "service", AbsentMatch: true}, map[string]string{"service": "test"}, false},
+		{"prefix", v2.HeaderMatcher{Name: "service", PrefixMatch: "te"}, map[string]string{"service": "test"}, true},
+		{"prefix not match", v2.HeaderMatcher{Name: "service", PrefixMatch: "es"}, map[string]string{"service": "test"}, false},
+		{"suffix", v2.HeaderMatcher{Name: "service", SuffixMatch: "st"}, map[string]string{"service": "test"}, true},
+		{"suffix not present", v2.HeaderMatcher{Name: "service", SuffixMatch: "st"}, map[string]string{}, false},
+		{"range", v2.HeaderMatcher{Name: "id", RangeMatch: &v2.Int64Range{Start: -10, End: 10}}, map[string]string{"id": "-10"}, true},
+		{"range end exclusive", v2.HeaderMatcher{Name: "id", RangeMatch: &v2.Int64Range{Start: -10, End: 10}}, map[string]string{"id": "10"}, false},
+		{"range not integer", v2.HeaderMatcher{Name: "id", RangeMatch: &v2.Int64Range{Start: -10, End: 10}}, map[string]string{"id": "1.5"}, false},
+		{"invert exact", v2.HeaderMatcher{Name: "service", Value: "test", InvertMatch: true}, map[string]string{"service": "foo"}, true},
+		{"invert exact not present", v2.HeaderMatcher{Name: "service", Value: "test", InvertMatch: true}, map[string]string{}, true},
+		{"invert present", v2.HeaderMatcher{Name: "service", PresentMatch: true, InvertMatch: true}, map[string]string{"service": "test"}, false},
+	}
+	for _, tc := range testCases {
+		headers := getRouterHeaders([]v2.HeaderMatcher{tc.matcher})
+		if got := ConfigUtilityInst.MatchHeaders(protocol.CommonHeader(tc.headers), headers); got != tc.expected {
+			t.Errorf("case %s want matched %v, but got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestMatchQueryParams(t *testing.T) {
+	matchers := getQueryParameterMatchers([]v2.QueryParameterMatcher{
+		{Name: "version", Value: "v1"},
+		{Name: "user", Value: "^[0-9]+$", Regex: true},
+		{Name: "debug"},
+	})
+	testCases := []struct {
+		params   types.QueryParams
+		expected bool
+	}{
+		{types.QueryParams{"version": "v1", "user": "123", "debug": ""}, true},
+		{types.QueryParams{"version": "v2", "user": "123", "debug": ""}, false},
+		{types.QueryParams{"version": "v1", "user": "abc", "debug": ""}, false},
+		{types.QueryParams{"version": "v1", "user": "123"}, false},
+		{nil, false},
+	}
+	for i, tc := range testCases {
+		if got := ConfigUtilityInst.MatchQueryParams(tc.params, matchers); got != tc.expected {
+			t.Errorf("#%d want matched %v, but got %v", i, tc.expected, got)
+		}
+	}
+}
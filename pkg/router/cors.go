@@ -0,0 +1,123 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"mosn.io/mosn/pkg/config/v2"
+)
+
+var ErrCorsOriginMatcher = errors.New("cors origin matcher should set only one of exact and regex")
+
+// corsPolicyImpl implements types.CorsPolicy
+type corsPolicyImpl struct {
+	allowAnyOrigin   bool
+	exactOrigins     map[string]struct{}
+	regexOrigins     []*regexp.Regexp
+	allowMethods     string
+	allowHeaders     string
+	exposeHeaders    string
+	maxAge           string
+	allowCredentials bool
+	enabled          bool
+	shadowEnabled    bool
+}
+
+func newCorsPolicyImpl(cfg *v2.CorsPolicy) (*corsPolicyImpl, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	policy := &corsPolicyImpl{
+		exactOrigins:     make(map[string]struct{}, len(cfg.AllowOrigins)),
+		allowMethods:     strings.Join(cfg.AllowMethods, ","),
+		allowHeaders:     strings.Join(cfg.AllowHeaders, ","),
+		exposeHeaders:    strings.Join(cfg.ExposeHeaders, ","),
+		allowCredentials: cfg.AllowCredentials,
+		enabled:          !cfg.Disabled && !cfg.ShadowMode,
+		shadowEnabled:    !cfg.Disabled && cfg.ShadowMode,
+	}
+	if cfg.MaxAge > 0 {
+		policy.maxAge = strconv.Itoa(cfg.MaxAge)
+	}
+	for _, m := range cfg.AllowOrigins {
+		if (m.Exact == "") == (m.Regex == "") {
+			return nil, ErrCorsOriginMatcher
+		}
+		if m.Exact == "*" {
+			policy.allowAnyOrigin = true
+			continue
+		}
+		if m.Exact != "" {
+			policy.exactOrigins[m.Exact] = struct{}{}
+			continue
+		}
+		regex, err := regexp.Compile(m.Regex)
+		if err != nil {
+			return nil, err
+		}
+		policy.regexOrigins = append(policy.regexOrigins, regex)
+	}
+	return policy, nil
+}
+
+func (p *corsPolicyImpl) AllowOrigin(origin string) bool {
+	if p.allowAnyOrigin {
+		return true
+	}
+	if _, ok := p.exactOrigins[origin]; ok {
+		return true
+	}
+	for _, regex := range p.regexOrigins {
+		if regex.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *corsPolicyImpl) AllowMethods() string {
+	return p.allowMethods
+}
+
+func (p *corsPolicyImpl) AllowHeaders() string {
+	return p.allowHeaders
+}
+
+func (p *corsPolicyImpl) ExposeHeaders() string {
+	return p.exposeHeaders
+}
+
+func (p *corsPolicyImpl) MaxAge() string {
+	return p.maxAge
+}
+
+func (p *corsPolicyImpl) AllowCredentials() bool {
+	return p.allowCredentials
+}
+
+func (p *corsPolicyImpl) Enabled() bool {
+	return p.enabled
+}
+
+func (p *corsPolicyImpl) ShadowEnabled() bool {
+	return p.shadowEnabled
+}
@@ -0,0 +1,111 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"strings"
+	"testing"
+
+	"mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestCorsPolicy(t *testing.T) {
+	vhConfigStr := `{
+		"name": "test",
+		"domains": ["*"],
+		"cors": {
+			"allow_origins": [
+				{"exact": "https://mosn.io"},
+				{"regex": "^https://.*\\.mosn\\.io$"}
+			],
+			"allow_methods": ["GET", "POST"],
+			"allow_headers": ["x-custom"],
+			"expose_headers": ["x-trace"],
+			"max_age": 600,
+			"allow_credentials": true
+		},
+		"routers": [
+			{
+				"match": {"prefix": "/shadow"},
+				"route": {"cluster_name": "test"},
+				"cors": {
+					"allow_origins": [{"exact": "*"}],
+					"shadow_mode": true
+				}
+			},
+			{
+				"match": {"prefix": "/"},
+				"route": {"cluster_name": "test"}
+			}
+		]
+	}`
+	vhCfg := &v2.VirtualHost{}
+	if err := json.Unmarshal([]byte(vhConfigStr), vhCfg); err != nil {
+		t.Fatal("unmarshal config to virtual host failed, ", err)
+	}
+	vh, err := NewVirtualHostImpl(vhCfg)
+	if err != nil {
+		t.Fatal("create virtual host failed, ", err)
+	}
+	getPolicy := func(path string) types.CorsPolicy {
+		route := vh.GetRouteFromEntries(protocol.CommonHeader{strings.ToLower(protocol.MosnHeaderPathKey): path}, 1)
+		if route == nil {
+			t.Fatalf("no route is matched by %s", path)
+		}
+		return route.(types.CorsRoute).CorsPolicy()
+	}
+	// the virtual host's policy
+	policy := getPolicy("/index.html")
+	if !policy.Enabled() || policy.ShadowEnabled() {
+		t.Error("the virtual host's policy should be enabled")
+	}
+	for origin, allowed := range map[string]bool{
+		"https://mosn.io":      true,
+		"https://test.mosn.io": true,
+		"http://test.mosn.io":  false,
+		"https://example.com":  false,
+	} {
+		if policy.AllowOrigin(origin) != allowed {
+			t.Errorf("origin %s expected allowed: %v", origin, allowed)
+		}
+	}
+	if policy.AllowMethods() != "GET,POST" || policy.AllowHeaders() != "x-custom" || policy.ExposeHeaders() != "x-trace" ||
+		policy.MaxAge() != "600" || !policy.AllowCredentials() {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+	// the route's policy overrides the virtual host's
+	policy = getPolicy("/shadow")
+	if policy.Enabled() || !policy.ShadowEnabled() || !policy.AllowOrigin("https://example.com") {
+		t.Errorf("unexpected shadow policy: %+v", policy)
+	}
+	// no policy
+	noPolicyCfg := &v2.Router{}
+	noPolicyCfg.Match.Prefix = "/"
+	noPolicyCfg.Route.ClusterName = "test"
+	rule, _ := NewRouteRuleImplBase(nil, noPolicyCfg)
+	if rule.CorsPolicy() != nil {
+		t.Error("expected a nil cors policy, but not", rule.CorsPolicy())
+	}
+	// invalid origin matcher
+	vhCfg.CorsPolicy.AllowOrigins = append(vhCfg.CorsPolicy.AllowOrigins, v2.CorsOriginMatcher{})
+	if _, err := NewVirtualHostImpl(vhCfg); err != ErrCorsOriginMatcher {
+		t.Error("expected an invalid origin matcher error, but got", err)
+	}
+}
@@ -20,6 +20,7 @@ package router
 import (
 	"context"
 	"fmt"
+	"math/rand"
 
 	"mosn.io/api"
 	"mosn.io/mosn/pkg/config/v2"
@@ -89,7 +90,7 @@ func (h *simpleHandler) Route() api.Route {
 
 func DefaultMakeHandlerChain(ctx context.Context, headers api.HeaderMap, routers types.Routers, clusterManager types.ClusterManager) *RouteHandlerChain {
 	var handlers []types.RouteHandler
-	if r := routers.MatchRoute(headers, 1); r != nil {
+	if r := routers.MatchRoute(headers, rand.Uint64()); r != nil {
 		if log.Proxy.GetLogLevel() >= log.DEBUG {
 			log.Proxy.Debugf(ctx, RouterLogFormat, "DefaultHandklerChain", "MatchRoute", fmt.Sprintf("matched a route: %v", r))
 		}
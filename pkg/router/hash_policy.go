@@ -0,0 +1,130 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"hash/fnv"
+	"net"
+	"net/http"
+
+	"mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/variable"
+)
+
+// hashKeyGenerator returns the key string to be hashed, returns false if no key found
+type hashKeyGenerator func(ctx types.LoadBalancerContext) (string, bool)
+
+// hashPolicyImpl is a list of hash key generators,
+// the first generator that generates a key is used.
+type hashPolicyImpl struct {
+	generators []hashKeyGenerator
+}
+
+func newHashPolicy(cfgs []v2.HashPolicy) *hashPolicyImpl {
+	generators := make([]hashKeyGenerator, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		switch {
+		case cfg.Header != nil:
+			generators = append(generators, headerHashKey(cfg.Header.Key))
+		case cfg.Cookie != nil:
+			generators = append(generators, cookieHashKey(cfg.Cookie.Name))
+		case cfg.SourceIP != nil:
+			generators = append(generators, sourceIPHashKey)
+		case cfg.Variable != nil:
+			generators = append(generators, variableHashKey(cfg.Variable.Name))
+		}
+	}
+	if len(generators) == 0 {
+		return nil
+	}
+	return &hashPolicyImpl{
+		generators: generators,
+	}
+}
+
+func (hp *hashPolicyImpl) GenerateHash(ctx types.LoadBalancerContext) (uint64, bool) {
+	for _, generate := range hp.generators {
+		if key, ok := generate(ctx); ok {
+			return hashString(key), true
+		}
+	}
+	return 0, false
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+func headerHashKey(key string) hashKeyGenerator {
+	return func(ctx types.LoadBalancerContext) (string, bool) {
+		headers := ctx.DownstreamHeaders()
+		if headers == nil {
+			return "", false
+		}
+		value, ok := headers.Get(key)
+		return value, ok && value != ""
+	}
+}
+
+func cookieHashKey(name string) hashKeyGenerator {
+	return func(ctx types.LoadBalancerContext) (string, bool) {
+		headers := ctx.DownstreamHeaders()
+		if headers == nil {
+			return "", false
+		}
+		value, ok := headers.Get("Cookie")
+		if !ok {
+			return "", false
+		}
+		// use net/http to parse the cookie header
+		req := http.Request{Header: http.Header{"Cookie": []string{value}}}
+		cookie, err := req.Cookie(name)
+		if err != nil || cookie.Value == "" {
+			return "", false
+		}
+		return cookie.Value, true
+	}
+}
+
+func sourceIPHashKey(ctx types.LoadBalancerContext) (string, bool) {
+	conn := ctx.DownstreamConnection()
+	if conn == nil || conn.RemoteAddr() == nil {
+		return "", false
+	}
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host, true
+	}
+	return addr, true
+}
+
+func variableHashKey(name string) hashKeyGenerator {
+	return func(ctx types.LoadBalancerContext) (string, bool) {
+		if ctx.DownstreamContext() == nil {
+			return "", false
+		}
+		value, err := variable.GetVariableValue(ctx.DownstreamContext(), name)
+		if err != nil || value == "" || value == variable.ValueNotFound {
+			return "", false
+		}
+		return value, true
+	}
+}
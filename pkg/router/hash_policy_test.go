@@ -0,0 +1,149 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/variable"
+)
+
+type mockConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *mockConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+type mockLbContext struct {
+	types.LoadBalancerContext
+	ctx     context.Context
+	conn    net.Conn
+	headers api.HeaderMap
+}
+
+func (c *mockLbContext) DownstreamConnection() net.Conn {
+	return c.conn
+}
+
+func (c *mockLbContext) DownstreamHeaders() api.HeaderMap {
+	return c.headers
+}
+
+func (c *mockLbContext) DownstreamContext() context.Context {
+	return c.ctx
+}
+
+func TestHashPolicy(t *testing.T) {
+	routeConfigStr := `{
+		"match": {
+			"prefix": "/"
+		},
+		"route": {
+			"cluster_name":"testcluster",
+			"hash_policy": [
+				{"header": {"key": "user"}},
+				{"cookie": {"name": "session"}},
+				{"source_ip": {}}
+			]
+		}
+	}`
+	routeCfg := &v2.Router{}
+	if err := json.Unmarshal([]byte(routeConfigStr), routeCfg); err != nil {
+		t.Fatal("unmarshal config to router failed, ", err)
+	}
+	rule, _ := NewRouteRuleImplBase(nil, routeCfg)
+	p, ok := rule.Policy().(types.Policy)
+	if !ok || p.HashPolicy() == nil {
+		t.Fatal("rule have no hash policy")
+	}
+	hp := p.HashPolicy()
+	conn := &mockConn{
+		remote: &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 12345},
+	}
+	// header
+	k1, ok := hp.GenerateHash(&mockLbContext{
+		conn:    conn,
+		headers: protocol.CommonHeader{"user": "alice"},
+	})
+	if !ok || k1 != hashString("alice") {
+		t.Fatal("header hash key not expected")
+	}
+	// cookie
+	k2, ok := hp.GenerateHash(&mockLbContext{
+		conn:    conn,
+		headers: protocol.CommonHeader{"Cookie": "a=b; session=abc"},
+	})
+	if !ok || k2 != hashString("abc") {
+		t.Fatal("cookie hash key not expected")
+	}
+	// source ip, port is ignored
+	k3, ok := hp.GenerateHash(&mockLbContext{
+		conn:    conn,
+		headers: protocol.CommonHeader{},
+	})
+	if !ok || k3 != hashString("192.168.1.1") {
+		t.Fatal("source ip hash key not expected")
+	}
+	// no hash key
+	if _, ok := hp.GenerateHash(&mockLbContext{
+		headers: protocol.CommonHeader{},
+	}); ok {
+		t.Fatal("expected no hash key")
+	}
+}
+
+func TestHashPolicyVariable(t *testing.T) {
+	varName := "test_hash_policy_var"
+	variable.RegisterVariable(variable.NewIndexedVariable(varName, nil, nil, variable.BasicSetter, 0))
+	hp := newHashPolicy([]v2.HashPolicy{
+		{Variable: &v2.VariableHashPolicy{Name: varName}},
+	})
+	ctx := variable.NewVariableContext(context.Background())
+	if _, ok := hp.GenerateHash(&mockLbContext{ctx: ctx}); ok {
+		t.Fatal("expected no hash key")
+	}
+	variable.SetVariableValue(ctx, varName, "value")
+	if k, ok := hp.GenerateHash(&mockLbContext{ctx: ctx}); !ok || k != hashString("value") {
+		t.Fatal("variable hash key not expected")
+	}
+}
+
+func TestNoHashPolicy(t *testing.T) {
+	routeCfg := &v2.Router{
+		RouterConfig: v2.RouterConfig{
+			Route: v2.RouteAction{
+				RouterActionConfig: v2.RouterActionConfig{
+					ClusterName: "testcluster",
+				},
+			},
+		},
+	}
+	rule, _ := NewRouteRuleImplBase(nil, routeCfg)
+	if p := rule.Policy().(types.Policy); p.HashPolicy() != nil {
+		t.Fatal("expected no hash policy")
+	}
+}
@@ -58,9 +58,12 @@ func (prri *PathRouteRuleImpl) FinalizeRequestHeaders(headers api.HeaderMap, req
 func (prri *PathRouteRuleImpl) Match(headers api.HeaderMap, randomValue uint64) api.Route {
 	if prri.matchRoute(headers, randomValue) {
 		if headerPathValue, ok := headers.Get(protocol.MosnHeaderPathKey); ok {
-			// TODO: config to support case sensitive
-			// case insensitive
-			if strings.EqualFold(headerPathValue, prri.path) {
+			// case insensitive by default
+			if prri.isCaseSensitive(false) {
+				if headerPathValue == prri.path {
+					return prri
+				}
+			} else if strings.EqualFold(headerPathValue, prri.path) {
 				return prri
 			}
 		}
@@ -102,7 +105,12 @@ func (prei *PrefixRouteRuleImpl) FinalizeRequestHeaders(headers api.HeaderMap, r
 func (prei *PrefixRouteRuleImpl) Match(headers api.HeaderMap, randomValue uint64) api.Route {
 	if prei.matchRoute(headers, randomValue) {
 		if headerPathValue, ok := headers.Get(protocol.MosnHeaderPathKey); ok {
-			if strings.HasPrefix(headerPathValue, prei.prefix) {
+			// case sensitive by default
+			if prei.isCaseSensitive(true) {
+				if strings.HasPrefix(headerPathValue, prei.prefix) {
+					return prei
+				}
+			} else if len(headerPathValue) >= len(prei.prefix) && strings.EqualFold(headerPathValue[:len(prei.prefix)], prei.prefix) {
 				return prei
 			}
 		}
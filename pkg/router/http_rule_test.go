@@ -154,3 +154,99 @@ func TestRegexRouteRuleImpl(t *testing.T) {
 		}
 	}
 }
+
+func TestRouteRuleCaseSensitive(t *testing.T) {
+	virtualHostImpl := &VirtualHostImpl{virtualHostName: "test", fastIndex: make(map[string]map[string]api.Route)}
+	sensitive, insensitive := true, false
+	testCases := []struct {
+		match      v2.RouterMatch
+		headerpath string
+		expected   bool
+	}{
+		{v2.RouterMatch{Path: "/test", CaseSensitive: &sensitive}, "/Test", false},
+		{v2.RouterMatch{Path: "/test", CaseSensitive: &sensitive}, "/test", true},
+		{v2.RouterMatch{Prefix: "/foo"}, "/Foo/test", false},
+		{v2.RouterMatch{Prefix: "/foo", CaseSensitive: &insensitive}, "/Foo/test", true},
+		{v2.RouterMatch{Prefix: "/foo", CaseSensitive: &insensitive}, "/F", false},
+		{v2.RouterMatch{Regex: "/foo/[0-9]+"}, "/FOO/123", false},
+		{v2.RouterMatch{Regex: "/foo/[0-9]+", CaseSensitive: &insensitive}, "/FOO/123", true},
+	}
+	for i, tc := range testCases {
+		virtualHostImpl.RemoveAllRoutes()
+		route := &v2.Router{
+			RouterConfig: v2.RouterConfig{
+				Match: tc.match,
+				Route: v2.RouteAction{
+					RouterActionConfig: v2.RouterActionConfig{
+						ClusterName: "test",
+					},
+				},
+			},
+		}
+		if err := virtualHostImpl.AddRoute(route); err != nil {
+			t.Fatalf("#%d add route failed: %v", i, err)
+		}
+		headers := protocol.CommonHeader(map[string]string{protocol.MosnHeaderPathKey: tc.headerpath})
+		result := virtualHostImpl.GetRouteFromEntries(headers, 1)
+		if (result != nil) != tc.expected {
+			t.Errorf("#%d want matched %v, but get matched %v\n", i, tc.expected, result)
+		}
+	}
+}
+
+func TestRouteRuleMatchConditions(t *testing.T) {
+	virtualHostImpl := &VirtualHostImpl{virtualHostName: "test"}
+	route := &v2.Router{
+		RouterConfig: v2.RouterConfig{
+			Match: v2.RouterMatch{
+				Prefix:  "/",
+				Methods: []string{"GET", "HEAD"},
+				QueryParameters: []v2.QueryParameterMatcher{
+					{Name: "version", Value: "v1"},
+				},
+				RuntimeFraction: &v2.FractionalPercent{
+					Numerator:   30,
+					Denominator: v2.DenominatorHundred,
+				},
+			},
+			Route: v2.RouteAction{
+				RouterActionConfig: v2.RouterActionConfig{
+					ClusterName: "test",
+				},
+			},
+		},
+	}
+	base, err := NewRouteRuleImplBase(virtualHostImpl, route)
+	if err != nil {
+		t.Fatal("create route rule failed: ", err)
+	}
+	rr := &PrefixRouteRuleImpl{base, route.Match.Prefix}
+	testCases := []struct {
+		method      string
+		queryString string
+		randomValue uint64
+		expected    bool
+	}{
+		{"GET", "version=v1", 1, true},
+		{"get", "version=v1&user=mosn", 129, true},
+		{"POST", "version=v1", 1, false},
+		{"", "version=v1", 1, false},
+		{"GET", "version=v2", 1, false},
+		{"GET", "", 1, false},
+		{"GET", "version=v1", 30, false},
+		{"GET", "version=v1", 199, false},
+	}
+	for i, tc := range testCases {
+		headers := protocol.CommonHeader(map[string]string{protocol.MosnHeaderPathKey: "/test"})
+		if tc.method != "" {
+			headers.Set(protocol.MosnHeaderMethod, tc.method)
+		}
+		if tc.queryString != "" {
+			headers.Set(protocol.MosnHeaderQueryStringKey, tc.queryString)
+		}
+		result := rr.Match(headers, tc.randomValue)
+		if (result != nil) != tc.expected {
+			t.Errorf("#%d want matched %v, but get matched %v\n", i, tc.expected, result)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"fmt"
+
+	"mosn.io/mosn/pkg/log"
+)
+
+// PerFilterConfigParser parses the per filter config of a stream filter when the route is created,
+// the parsed value is returned by the route's PerFilterConfig instead of the raw config
+type PerFilterConfigParser func(config interface{}) (interface{}, error)
+
+var perFilterConfigParsers = map[string]PerFilterConfigParser{}
+
+// RegisterPerFilterConfigParser registers the per filter config parser of the stream filter,
+// it should be called in init
+func RegisterPerFilterConfigParser(name string, parser PerFilterConfigParser) {
+	log.DefaultLogger.Infof(RouterLogFormat, "Extend", "RegisterPerFilterConfigParser", fmt.Sprintf("filter is %s", name))
+	perFilterConfigParsers[name] = parser
+}
+
+// parsePerFilterConfig returns the per filter config with the registered parsers applied
+func parsePerFilterConfig(configs map[string]interface{}) (map[string]interface{}, error) {
+	if len(configs) == 0 {
+		return configs, nil
+	}
+	parsed := make(map[string]interface{}, len(configs))
+	for name, cfg := range configs {
+		parser, ok := perFilterConfigParsers[name]
+		if !ok {
+			parsed[name] = cfg
+			continue
+		}
+		v, err := parser(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid per filter config of %s: %v", name, err)
+		}
+		parsed[name] = v
+	}
+	return parsed, nil
+}
@@ -0,0 +1,102 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+)
+
+type parsedFilterConfig struct {
+	value string
+}
+
+func TestPerFilterConfigParser(t *testing.T) {
+	RegisterPerFilterConfigParser("filter_parsed", func(cfg interface{}) (interface{}, error) {
+		s, ok := cfg.(string)
+		if !ok {
+			return nil, errors.New("not a string")
+		}
+		return &parsedFilterConfig{value: s}, nil
+	})
+	defer delete(perFilterConfigParsers, "filter_parsed")
+	newVirtualHost := func(vhostConfig, routeConfig interface{}) (*VirtualHostImpl, error) {
+		return NewVirtualHostImpl(&v2.VirtualHost{
+			Name:    "test",
+			Domains: []string{"*"},
+			PerFilterConfig: map[string]interface{}{
+				"filter_parsed": vhostConfig,
+				"filter_raw":    "vhost_raw",
+			},
+			Routers: []v2.Router{
+				{
+					RouterConfig: v2.RouterConfig{
+						Match: v2.RouterMatch{Prefix: "/route"},
+						Route: v2.RouteAction{RouterActionConfig: v2.RouterActionConfig{ClusterName: "test"}},
+						PerFilterConfig: map[string]interface{}{
+							"filter_parsed": routeConfig,
+						},
+					},
+				},
+				{
+					RouterConfig: v2.RouterConfig{
+						Match: v2.RouterMatch{Prefix: "/"},
+						Route: v2.RouteAction{RouterActionConfig: v2.RouterActionConfig{ClusterName: "test"}},
+					},
+				},
+			},
+		})
+	}
+	vh, err := newVirtualHost("vhost", "route")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, expected := range map[string]string{
+		"/route": "route",
+		"/":      "vhost",
+	} {
+		route := vh.GetRouteFromEntries(protocol.CommonHeader{strings.ToLower(protocol.MosnHeaderPathKey): path}, 1)
+		if route == nil {
+			t.Fatalf("no route is matched: %s", path)
+		}
+		cfg := route.RouteRule().PerFilterConfig()
+		parsed, ok := cfg["filter_parsed"].(*parsedFilterConfig)
+		if !ok || parsed.value != expected {
+			t.Errorf("%s: unexpected parsed config: %v", path, cfg["filter_parsed"])
+		}
+		if cfg["filter_raw"] != "vhost_raw" {
+			t.Errorf("%s: unexpected raw config: %v", path, cfg["filter_raw"])
+		}
+	}
+	// the same parsed config is returned for each request
+	route := vh.GetRouteFromEntries(protocol.CommonHeader{strings.ToLower(protocol.MosnHeaderPathKey): "/route"}, 1)
+	if route.RouteRule().PerFilterConfig()["filter_parsed"] != route.RouteRule().PerFilterConfig()["filter_parsed"] {
+		t.Error("expected the config is parsed once")
+	}
+	// invalid configs
+	if _, err := newVirtualHost(1, "route"); err == nil {
+		t.Error("expected the invalid virtual host config is rejected")
+	}
+	if _, err := newVirtualHost("vhost", 1); err == nil {
+		t.Error("expected the invalid route config is rejected")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+)
+
+// headerForwardedProto is the header that contains the downstream request's scheme
+const headerForwardedProto = "x-forwarded-proto"
+
+var ErrRedirectCode = errors.New("redirect response code should be one of 301, 302, 303, 307 and 308")
+
+type redirectImpl struct {
+	code          int
+	scheme        string
+	host          string
+	port          string
+	path          string
+	prefixRewrite string
+	stripQuery    bool
+	// the route's match prefix or path, replaced by the prefix rewrite
+	matchPrefix string
+}
+
+func newRedirectImpl(redirect *v2.RedirectAction, match v2.RouterMatch) (*redirectImpl, error) {
+	rule := &redirectImpl{
+		code:          redirect.ResponseCode,
+		scheme:        strings.ToLower(redirect.SchemeRedirect),
+		host:          redirect.HostRedirect,
+		path:          redirect.PathRedirect,
+		prefixRewrite: redirect.PrefixRewrite,
+		stripQuery:    redirect.StripQuery,
+		matchPrefix:   match.Prefix,
+	}
+	if rule.matchPrefix == "" {
+		rule.matchPrefix = match.Path
+	}
+	if redirect.PortRedirect != 0 {
+		rule.port = strconv.FormatUint(uint64(redirect.PortRedirect), 10)
+	}
+	switch rule.code {
+	case 0:
+		rule.code = http.StatusMovedPermanently
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return nil, ErrRedirectCode
+	}
+	return rule, nil
+}
+
+func (rule *redirectImpl) RedirectCode() int {
+	return rule.code
+}
+
+func (rule *redirectImpl) RedirectLocation(headers api.HeaderMap) string {
+	// scheme
+	originalScheme := "http"
+	if proto, ok := headers.Get(headerForwardedProto); ok && proto != "" {
+		originalScheme = strings.ToLower(proto)
+	}
+	scheme := originalScheme
+	if rule.scheme != "" {
+		scheme = rule.scheme
+	}
+	// authority
+	authority, ok := headers.Get(protocol.MosnHeaderHostKey)
+	if !ok {
+		authority, _ = headers.Get(protocol.IstioHeaderHostKey)
+	}
+	host, port := splitHostPort(authority)
+	if rule.host != "" {
+		host = rule.host
+	}
+	if rule.port != "" {
+		port = rule.port
+	} else if scheme != originalScheme {
+		// the default port of the original scheme should be removed when the scheme changed
+		if (scheme == "https" && port == "80") || (scheme == "http" && port == "443") {
+			port = ""
+		}
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	// path and query
+	path, _ := headers.Get(protocol.MosnHeaderPathKey)
+	if rule.path != "" {
+		path = rule.path
+	} else if rule.prefixRewrite != "" {
+		if strings.HasPrefix(path, rule.matchPrefix) {
+			path = rule.prefixRewrite + path[len(rule.matchPrefix):]
+		} else {
+			path = rule.prefixRewrite
+		}
+	}
+	if !rule.stripQuery && !strings.Contains(path, "?") {
+		if query, ok := headers.Get(protocol.MosnHeaderQueryStringKey); ok && query != "" {
+			path = path + "?" + query
+		}
+	}
+	return scheme + "://" + host + path
+}
+
+// splitHostPort splits the authority into host and port, the port is empty if the authority has no port
+func splitHostPort(authority string) (string, string) {
+	host, port, err := net.SplitHostPort(authority)
+	if err != nil {
+		return authority, ""
+	}
+	return host, port
+}
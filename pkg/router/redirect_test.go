@@ -0,0 +1,160 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"testing"
+
+	"mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+)
+
+func TestRedirect(t *testing.T) {
+	routeConfigStr := `{
+		"match": {
+			"prefix": "/foo"
+		},
+		"redirect": {
+			"scheme_redirect": "https",
+			"prefix_rewrite": "/bar",
+			"response_code": 302
+		}
+	}`
+	routeCfg := &v2.Router{}
+	if err := json.Unmarshal([]byte(routeConfigStr), routeCfg); err != nil {
+		t.Fatal("unmarshal config to router failed, ", err)
+	}
+	rule, err := NewRouteRuleImplBase(nil, routeCfg)
+	if err != nil {
+		t.Fatal("create route rule failed, ", err)
+	}
+	redirect := rule.RedirectRule()
+	if redirect == nil {
+		t.Fatal("rule have no redirect rule")
+	}
+	if redirect.RedirectCode() != 302 {
+		t.Errorf("redirect code is not expected, got %d", redirect.RedirectCode())
+	}
+	headers := protocol.CommonHeader{
+		protocol.MosnHeaderHostKey:        "mosn.io:80",
+		protocol.MosnHeaderPathKey:        "/foo/index.html",
+		protocol.MosnHeaderQueryStringKey: "a=b",
+	}
+	if location := redirect.RedirectLocation(headers); location != "https://mosn.io/bar/index.html?a=b" {
+		t.Errorf("redirect location is not expected, got %s", location)
+	}
+	// no redirect by default
+	noRedirectCfg := &v2.Router{}
+	noRedirectCfg.Match.Prefix = "/"
+	noRedirectCfg.Route.ClusterName = "testcluster"
+	noRedirectRule, _ := NewRouteRuleImplBase(nil, noRedirectCfg)
+	if noRedirectRule.RedirectRule() != nil {
+		t.Error("expected a nil redirect rule, but not", noRedirectRule.RedirectRule())
+	}
+	// invalid response code
+	routeCfg.Redirect.ResponseCode = 200
+	if _, err := NewRouteRuleImplBase(nil, routeCfg); err != ErrRedirectCode {
+		t.Error("expected an invalid redirect code error, but got", err)
+	}
+}
+
+func TestRedirectLocation(t *testing.T) {
+	testCases := []struct {
+		redirect *v2.RedirectAction
+		match    v2.RouterMatch
+		headers  protocol.CommonHeader
+		expected string
+	}{
+		// keep the original request
+		{
+			redirect: &v2.RedirectAction{},
+			headers: protocol.CommonHeader{
+				protocol.IstioHeaderHostKey: "mosn.io",
+				protocol.MosnHeaderPathKey:  "/foo",
+			},
+			expected: "http://mosn.io/foo",
+		},
+		// host, port and path redirect, the query string is kept
+		{
+			redirect: &v2.RedirectAction{
+				HostRedirect: "example.com",
+				PortRedirect: 8080,
+				PathRedirect: "/bar",
+			},
+			headers: protocol.CommonHeader{
+				protocol.MosnHeaderHostKey:        "mosn.io:80",
+				protocol.MosnHeaderPathKey:        "/foo",
+				protocol.MosnHeaderQueryStringKey: "a=b",
+			},
+			expected: "http://example.com:8080/bar?a=b",
+		},
+		// strip query, the non default port is kept
+		{
+			redirect: &v2.RedirectAction{
+				SchemeRedirect: "https",
+				StripQuery:     true,
+			},
+			headers: protocol.CommonHeader{
+				protocol.MosnHeaderHostKey:        "mosn.io:8080",
+				protocol.MosnHeaderPathKey:        "/foo",
+				protocol.MosnHeaderQueryStringKey: "a=b",
+			},
+			expected: "https://mosn.io:8080/foo",
+		},
+		// the path redirect contains query string
+		{
+			redirect: &v2.RedirectAction{
+				PathRedirect: "/bar?c=d",
+			},
+			headers: protocol.CommonHeader{
+				protocol.MosnHeaderHostKey:        "mosn.io",
+				protocol.MosnHeaderPathKey:        "/foo",
+				protocol.MosnHeaderQueryStringKey: "a=b",
+			},
+			expected: "http://mosn.io/bar?c=d",
+		},
+		// scheme from x-forwarded-proto, prefix rewrite with path match
+		{
+			redirect: &v2.RedirectAction{
+				SchemeRedirect: "http",
+				PrefixRewrite:  "/bar",
+			},
+			match: v2.RouterMatch{
+				Path: "/foo",
+			},
+			headers: protocol.CommonHeader{
+				headerForwardedProto:       "https",
+				protocol.MosnHeaderHostKey: "mosn.io:443",
+				protocol.MosnHeaderPathKey: "/foo",
+			},
+			expected: "http://mosn.io/bar",
+		},
+	}
+	for i, tc := range testCases {
+		rule, err := newRedirectImpl(tc.redirect, tc.match)
+		if err != nil {
+			t.Fatalf("case %d create redirect rule failed: %v", i, err)
+		}
+		if rule.RedirectCode() != 301 {
+			t.Errorf("case %d expected default redirect code 301, but got %d", i, rule.RedirectCode())
+		}
+		if location := rule.RedirectLocation(tc.headers); location != tc.expected {
+			t.Errorf("case %d expected location %s, but got %s", i, tc.expected, location)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"strings"
+	"time"
+
+	"mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+// default back off intervals, same as envoy
+const defaultRetryBaseInterval = 25 * time.Millisecond
+
+var retryOnConditions = map[string]types.RetryOnCondition{
+	"5xx":                    types.RetryOn5xx,
+	"gateway-error":          types.RetryOnGatewayError,
+	"connect-failure":        types.RetryOnConnectFailure,
+	"reset":                  types.RetryOnReset,
+	"retriable-status-codes": types.RetryOnRetriableStatusCodes,
+}
+
+func newRetryPolicy(cfg *v2.RetryPolicy) *retryPolicyImpl {
+	p := &retryPolicyImpl{
+		retryOn:              cfg.RetryOn,
+		retryTimeout:         cfg.RetryTimeout,
+		numRetries:           cfg.NumRetries,
+		retryOnConditions:    parseRetryOnConditions(cfg.RetryOnConditions),
+		retriableStatusCodes: cfg.RetriableStatusCodes,
+		baseInterval:         defaultRetryBaseInterval,
+	}
+	if cfg.RetryBackOff != nil && cfg.RetryBackOff.BaseInterval.Duration > 0 {
+		p.baseInterval = cfg.RetryBackOff.BaseInterval.Duration
+	}
+	// max interval is 10 times of base interval by default
+	p.maxInterval = p.baseInterval * 10
+	if cfg.RetryBackOff != nil && cfg.RetryBackOff.MaxInterval.Duration > 0 {
+		p.maxInterval = cfg.RetryBackOff.MaxInterval.Duration
+	}
+	if p.maxInterval < p.baseInterval {
+		p.maxInterval = p.baseInterval
+	}
+	return p
+}
+
+// parseRetryOnConditions parses the envoy style retry conditions, such as "5xx,connect-failure"
+func parseRetryOnConditions(s string) types.RetryOnCondition {
+	var conditions types.RetryOnCondition
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if condition, ok := retryOnConditions[c]; ok {
+			conditions |= condition
+		} else {
+			log.DefaultLogger.Warnf(RouterLogFormat, "retry policy", "unsupported retry on condition", c)
+		}
+	}
+	return conditions
+}
@@ -0,0 +1,86 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"testing"
+	"time"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestParseRetryOnConditions(t *testing.T) {
+	testcases := []struct {
+		conditions string
+		expected   types.RetryOnCondition
+	}{
+		{"", 0},
+		{"5xx", types.RetryOn5xx},
+		{"5xx, connect-failure", types.RetryOn5xx | types.RetryOnConnectFailure},
+		{"gateway-error,reset,retriable-status-codes", types.RetryOnGatewayError | types.RetryOnReset | types.RetryOnRetriableStatusCodes},
+		{"unknown,reset", types.RetryOnReset},
+	}
+	for _, tc := range testcases {
+		if c := parseRetryOnConditions(tc.conditions); c != tc.expected {
+			t.Errorf("parse %s expected %d, but got %d", tc.conditions, tc.expected, c)
+		}
+	}
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	testcases := []struct {
+		backOff *v2.RetryBackOffConfig
+		base    time.Duration
+		max     time.Duration
+	}{
+		{nil, defaultRetryBaseInterval, 10 * defaultRetryBaseInterval},
+		{&v2.RetryBackOffConfig{
+			BaseInterval: api.DurationConfig{Duration: 100 * time.Millisecond},
+		}, 100 * time.Millisecond, time.Second},
+		{&v2.RetryBackOffConfig{
+			BaseInterval: api.DurationConfig{Duration: 100 * time.Millisecond},
+			MaxInterval:  api.DurationConfig{Duration: 300 * time.Millisecond},
+		}, 100 * time.Millisecond, 300 * time.Millisecond},
+		// max interval less than base interval
+		{&v2.RetryBackOffConfig{
+			BaseInterval: api.DurationConfig{Duration: 100 * time.Millisecond},
+			MaxInterval:  api.DurationConfig{Duration: 10 * time.Millisecond},
+		}, 100 * time.Millisecond, 100 * time.Millisecond},
+	}
+	for i, tc := range testcases {
+		p := newRetryPolicy(&v2.RetryPolicy{
+			RetryPolicyConfig: v2.RetryPolicyConfig{
+				RetryOn:              true,
+				NumRetries:           3,
+				RetryOnConditions:    "5xx,retriable-status-codes",
+				RetriableStatusCodes: []uint32{409},
+				RetryBackOff:         tc.backOff,
+			},
+		})
+		if !(p.RetryOn() && p.NumRetries() == 3 &&
+			p.RetryOnConditions() == types.RetryOn5xx|types.RetryOnRetriableStatusCodes &&
+			len(p.RetriableStatusCodes()) == 1 && p.RetriableStatusCodes()[0] == 409) {
+			t.Errorf("#%d unexpected retry policy: %+v", i, p)
+		}
+		if base, max := p.BackOffInterval(); base != tc.base || max != tc.max {
+			t.Errorf("#%d expected back off interval %s-%s, but got %s-%s", i, tc.base, tc.max, base, max)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+)
+
+var ErrRewriteConflict = errors.New("prefix_rewrite and regex_rewrite are mutually exclusive")
+
+// regexRewrite replaces the parts matched by the pattern with the substitution
+type regexRewrite struct {
+	pattern      *regexp.Regexp
+	substitution string
+}
+
+func newRegexRewrite(cfg *v2.RegexRewrite) (*regexRewrite, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &regexRewrite{
+		pattern:      pattern,
+		substitution: convertSubstitution(cfg.Substitution),
+	}, nil
+}
+
+// rewrite returns the rewritten value, and returns false if the pattern is not matched
+func (rr *regexRewrite) rewrite(value string) (string, bool) {
+	if !rr.pattern.MatchString(value) {
+		return value, false
+	}
+	return rr.pattern.ReplaceAllString(value, rr.substitution), true
+}
+
+// convertSubstitution converts the `\N` capture group references into go's `${N}` style,
+// and `\\` is converted to `\`
+func convertSubstitution(substitution string) string {
+	if !strings.Contains(substitution, "\\") {
+		return substitution
+	}
+	var sb strings.Builder
+	for i := 0; i < len(substitution); i++ {
+		c := substitution[i]
+		if c == '\\' && i+1 < len(substitution) {
+			next := substitution[i+1]
+			if next >= '0' && next <= '9' {
+				sb.WriteString("${")
+				sb.WriteByte(next)
+				sb.WriteByte('}')
+				i++
+				continue
+			}
+			if next == '\\' {
+				sb.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// setPath sets the rewritten path, the original path is kept in x-mosn-original-path.
+// if the rewritten path contains a query string, it is merged into the request's query string
+func setPath(headers api.HeaderMap, originalPath, path string) {
+	headers.Set(protocol.MosnOriginalHeaderPathKey, originalPath)
+	if idx := strings.IndexByte(path, '?'); idx >= 0 {
+		query := path[idx+1:]
+		path = path[:idx]
+		if originalQuery, ok := headers.Get(protocol.MosnHeaderQueryStringKey); ok && originalQuery != "" {
+			if query != "" {
+				query = query + "&" + originalQuery
+			} else {
+				query = originalQuery
+			}
+		}
+		if query != "" {
+			headers.Set(protocol.MosnHeaderQueryStringKey, query)
+		}
+	}
+	headers.Set(protocol.MosnHeaderPathKey, path)
+}
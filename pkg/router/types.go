@@ -20,6 +20,7 @@ package router
 import (
 	"context"
 	"errors"
+	"math/rand"
 	"strings"
 	"time"
 
@@ -84,22 +85,37 @@ type RouteBase interface {
 
 // Policy
 type policy struct {
-	retryPolicy  *retryPolicyImpl
-	shadowPolicy *shadowPolicyImpl //TODO: not implement yet
+	retryPolicy    *retryPolicyImpl
+	shadowPolicy   *shadowPolicyImpl
+	hashPolicy     types.HashPolicy
+	mirrorPolicies []types.MirrorPolicy
 }
 
 func (p *policy) RetryPolicy() api.RetryPolicy {
 	return p.retryPolicy
 }
 
+// ShadowPolicy returns the first mirror policy
 func (p *policy) ShadowPolicy() api.ShadowPolicy {
 	return p.shadowPolicy
 }
 
+func (p *policy) HashPolicy() types.HashPolicy {
+	return p.hashPolicy
+}
+
+func (p *policy) MirrorPolicies() []types.MirrorPolicy {
+	return p.mirrorPolicies
+}
+
 type retryPolicyImpl struct {
-	retryOn      bool
-	retryTimeout time.Duration
-	numRetries   uint32
+	retryOn              bool
+	retryTimeout         time.Duration
+	numRetries           uint32
+	retryOnConditions    types.RetryOnCondition
+	retriableStatusCodes []uint32
+	baseInterval         time.Duration
+	maxInterval          time.Duration
 }
 
 func (p *retryPolicyImpl) RetryOn() bool {
@@ -123,9 +139,31 @@ func (p *retryPolicyImpl) NumRetries() uint32 {
 	return p.numRetries
 }
 
+func (p *retryPolicyImpl) RetryOnConditions() types.RetryOnCondition {
+	if p == nil {
+		return 0
+	}
+	return p.retryOnConditions
+}
+
+func (p *retryPolicyImpl) RetriableStatusCodes() []uint32 {
+	if p == nil {
+		return nil
+	}
+	return p.retriableStatusCodes
+}
+
+func (p *retryPolicyImpl) BackOffInterval() (time.Duration, time.Duration) {
+	if p == nil {
+		return defaultRetryBaseInterval, defaultRetryBaseInterval * 10
+	}
+	return p.baseInterval, p.maxInterval
+}
+
 type shadowPolicyImpl struct {
-	cluster    string
-	runtimeKey string
+	cluster         string
+	runtimeKey      string
+	runtimeFraction *fractionalPercent
 }
 
 func (spi *shadowPolicyImpl) ClusterName() string {
@@ -136,6 +174,13 @@ func (spi *shadowPolicyImpl) RuntimeKey() string {
 	return spi.runtimeKey
 }
 
+func (spi *shadowPolicyImpl) IsMirror() bool {
+	if spi.runtimeFraction == nil {
+		return true
+	}
+	return spi.runtimeFraction.match(rand.Uint64())
+}
+
 // RouterRuleFactory creates a RouteBase
 type RouterRuleFactory func(base *RouteRuleImplBase, header []v2.HeaderMatcher) RouteBase
 
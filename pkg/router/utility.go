@@ -52,11 +52,27 @@ func getRouterHeaders(headers []v2.HeaderMatcher) []*types.HeaderData {
 			Name: &lowerCaseString{
 				header.Name,
 			},
-			Value:   header.Value,
-			IsRegex: header.Regex,
+			Value:       header.Value,
+			IsRegex:     header.Regex,
+			InvertMatch: header.InvertMatch,
 		}
 
-		if header.Regex {
+		switch {
+		case header.PresentMatch:
+			headerData.MatchType = types.HeaderMatchPresent
+		case header.AbsentMatch:
+			headerData.MatchType = types.HeaderMatchAbsent
+		case header.PrefixMatch != "":
+			headerData.MatchType = types.HeaderMatchPrefix
+			headerData.Value = header.PrefixMatch
+		case header.SuffixMatch != "":
+			headerData.MatchType = types.HeaderMatchSuffix
+			headerData.Value = header.SuffixMatch
+		case header.RangeMatch != nil:
+			headerData.MatchType = types.HeaderMatchRange
+			headerData.RangeStart = header.RangeMatch.Start
+			headerData.RangeEnd = header.RangeMatch.End
+		case header.Regex:
 			pattern, err := regexp.Compile(header.Value)
 			if err != nil {
 				log.DefaultLogger.Errorf("getRouterHeaders compile error")
@@ -72,6 +88,33 @@ func getRouterHeaders(headers []v2.HeaderMatcher) []*types.HeaderData {
 	return headerDatas
 }
 
+// isExactHeaderMatcher returns true if the header matcher matches the value exactly
+func isExactHeaderMatcher(header v2.HeaderMatcher) bool {
+	return !(header.Regex || header.PresentMatch || header.AbsentMatch || header.InvertMatch ||
+		header.PrefixMatch != "" || header.SuffixMatch != "" || header.RangeMatch != nil)
+}
+
+func getQueryParameterMatchers(params []v2.QueryParameterMatcher) []types.QueryParameterMatcher {
+	var matchers []types.QueryParameterMatcher
+	for _, param := range params {
+		matcher := &queryParameterMatcher{
+			name:    param.Name,
+			value:   param.Value,
+			isRegex: param.Regex,
+		}
+		if param.Regex {
+			pattern, err := regexp.Compile(param.Value)
+			if err != nil {
+				log.DefaultLogger.Errorf("getQueryParameterMatchers compile error")
+				continue
+			}
+			matcher.regexPattern = *pattern
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers
+}
+
 func getHeaderParser(headersToAdd []*v2.HeaderValueOption, headersToRemove []string) *headerParser {
 	if headersToAdd == nil && headersToRemove == nil {
 		return nil
@@ -125,3 +168,45 @@ func getHeadersToRemove(headersToRemove []string) []*lowerCaseString {
 	}
 	return lowerCaseHeaders
 }
+
+// fractionalPercent is a fractional percentage: numerator / denominator
+type fractionalPercent struct {
+	numerator   uint64
+	denominator uint64
+}
+
+func newFractionalPercent(cfg *v2.FractionalPercent) *fractionalPercent {
+	if cfg == nil {
+		return nil
+	}
+	fp := &fractionalPercent{
+		numerator:   uint64(cfg.Numerator),
+		denominator: 100,
+	}
+	switch cfg.Denominator {
+	case v2.DenominatorTenThousand:
+		fp.denominator = 10000
+	case v2.DenominatorMillion:
+		fp.denominator = 1000000
+	}
+	return fp
+}
+
+// match returns true if the value falls into the fraction
+func (fp *fractionalPercent) match(value uint64) bool {
+	return value%fp.denominator < fp.numerator
+}
+
+func getMirrorPolicies(policies []*v2.RequestMirrorPolicy) []types.MirrorPolicy {
+	var mirrorPolicies []types.MirrorPolicy
+	for _, p := range policies {
+		if p == nil || p.Cluster == "" {
+			continue
+		}
+		mirrorPolicies = append(mirrorPolicies, &shadowPolicyImpl{
+			cluster:         p.Cluster,
+			runtimeFraction: newFractionalPercent(p.RuntimeFraction),
+		})
+	}
+	return mirrorPolicies
+}
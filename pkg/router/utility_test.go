@@ -258,3 +258,44 @@ func Test_GetRouterHeaders_regex(t *testing.T) {
 	}
 
 }
+
+func Test_fractionalPercent(t *testing.T) {
+	testcases := []struct {
+		cfg      *v2.FractionalPercent
+		value    uint64
+		expected bool
+	}{
+		{&v2.FractionalPercent{Numerator: 50}, 49, true},
+		{&v2.FractionalPercent{Numerator: 50}, 50, false},
+		{&v2.FractionalPercent{Numerator: 50}, 149, true},
+		{&v2.FractionalPercent{Numerator: 0}, 0, false},
+		{&v2.FractionalPercent{Numerator: 100}, 99, true},
+		{&v2.FractionalPercent{Numerator: 50, Denominator: v2.DenominatorTenThousand}, 49, true},
+		{&v2.FractionalPercent{Numerator: 50, Denominator: v2.DenominatorTenThousand}, 99, false},
+		{&v2.FractionalPercent{Numerator: 50, Denominator: v2.DenominatorMillion}, 1000049, true},
+		{&v2.FractionalPercent{Numerator: 50, Denominator: v2.DenominatorMillion}, 9999, false},
+	}
+	for i, tc := range testcases {
+		if newFractionalPercent(tc.cfg).match(tc.value) != tc.expected {
+			t.Errorf("#%d fractional percent match %d expected %v", i, tc.value, tc.expected)
+		}
+	}
+}
+
+func Test_getMirrorPolicies(t *testing.T) {
+	policies := getMirrorPolicies([]*v2.RequestMirrorPolicy{
+		{Cluster: "shadow1"},
+		// invalid policy
+		{Cluster: ""},
+		{Cluster: "shadow2", RuntimeFraction: &v2.FractionalPercent{Numerator: 0}},
+	})
+	if len(policies) != 2 {
+		t.Fatalf("expected 2 mirror policies, but got %d", len(policies))
+	}
+	if policies[0].ClusterName() != "shadow1" || !policies[0].IsMirror() {
+		t.Error("shadow1 should always be mirrored")
+	}
+	if policies[1].ClusterName() != "shadow2" || policies[1].IsMirror() {
+		t.Error("shadow2 should never be mirrored")
+	}
+}
@@ -34,6 +34,8 @@ type VirtualHostImpl struct {
 	globalRouteConfig     *configImpl
 	requestHeadersParser  *headerParser
 	responseHeadersParser *headerParser
+	perFilterConfig       map[string]interface{}
+	corsPolicy            *corsPolicyImpl
 }
 
 func (vh *VirtualHostImpl) Name() string {
@@ -58,7 +60,12 @@ func (vh *VirtualHostImpl) addRouteBase(route *v2.Router) error {
 			path:              route.Match.Path,
 		}
 	} else if route.Match.Regex != "" {
-		regPattern, err := regexp.Compile(route.Match.Regex)
+		regexStr := route.Match.Regex
+		// case sensitive by default
+		if route.Match.CaseSensitive != nil && !*route.Match.CaseSensitive {
+			regexStr = "(?i)" + regexStr
+		}
+		regPattern, err := regexp.Compile(regexStr)
 		if err != nil {
 			log.DefaultLogger.Errorf(RouterLogFormat, "virtualhost", "addRouteBase", err)
 			return err
@@ -79,7 +86,7 @@ func (vh *VirtualHostImpl) addRouteBase(route *v2.Router) error {
 		vh.routes = append(vh.routes, router)
 		// make fast index, used in certain scenarios
 		// TODO: rule can be extended
-		if len(route.Match.Headers) == 1 && !route.Match.Headers[0].Regex {
+		if len(route.Match.Headers) == 1 && isExactHeaderMatcher(route.Match.Headers[0]) {
 			key := route.Match.Headers[0].Name
 			value := route.Match.Headers[0].Value
 			valueMap, ok := vh.fastIndex[key]
@@ -153,6 +160,13 @@ func NewVirtualHostImpl(virtualHost *v2.VirtualHost) (*VirtualHostImpl, error) {
 		requestHeadersParser:  getHeaderParser(virtualHost.RequestHeadersToAdd, nil),
 		responseHeadersParser: getHeaderParser(virtualHost.ResponseHeadersToAdd, virtualHost.ResponseHeadersToRemove),
 	}
+	var err error
+	if vhImpl.perFilterConfig, err = parsePerFilterConfig(virtualHost.PerFilterConfig); err != nil {
+		return nil, err
+	}
+	if vhImpl.corsPolicy, err = newCorsPolicyImpl(virtualHost.CorsPolicy); err != nil {
+		return nil, err
+	}
 	for _, route := range virtualHost.Routers {
 		if err := vhImpl.addRouteBase(&route); err != nil {
 			return nil, err
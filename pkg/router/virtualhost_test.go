@@ -195,3 +195,36 @@ func TestAllRouter(t *testing.T) {
 		}
 	}
 }
+
+func TestVirtualHostPerFilterConfig(t *testing.T) {
+	vh, err := NewVirtualHostImpl(&v2.VirtualHost{
+		Name:    "test",
+		Domains: []string{"*"},
+		PerFilterConfig: map[string]interface{}{
+			"filter_a": "vhost_a",
+			"filter_b": "vhost_b",
+		},
+		Routers: []v2.Router{
+			{
+				RouterConfig: v2.RouterConfig{
+					Match: v2.RouterMatch{Prefix: "/"},
+					Route: v2.RouteAction{RouterActionConfig: v2.RouterActionConfig{ClusterName: "test"}},
+					PerFilterConfig: map[string]interface{}{
+						"filter_b": "route_b",
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := vh.GetRouteFromEntries(protocol.CommonHeader{strings.ToLower(protocol.MosnHeaderPathKey): "/"}, 1)
+	if route == nil {
+		t.Fatal("no route is matched")
+	}
+	cfg := route.RouteRule().PerFilterConfig()
+	if len(cfg) != 2 || cfg["filter_a"] != "vhost_a" || cfg["filter_b"] != "route_b" {
+		t.Fatalf("unexpected per filter config: %v", cfg)
+	}
+}
@@ -253,14 +253,18 @@ func newActiveClient(ctx context.Context, pool *connPool) (*activeClient, types.
 	ac.client = codecClient
 	ac.host = data
 
+	connectStart := time.Now()
 	if err := ac.client.Connect(); err != nil {
 		return nil, types.ConnectionFailure
 	}
+	connectDurationNs := time.Since(connectStart).Nanoseconds()
 
 	pool.host.HostStats().UpstreamConnectionTotal.Inc(1)
 	pool.host.HostStats().UpstreamConnectionActive.Inc(1)
 	pool.host.ClusterInfo().Stats().UpstreamConnectionTotal.Inc(1)
 	pool.host.ClusterInfo().Stats().UpstreamConnectionActive.Inc(1)
+	pool.host.HostStats().UpstreamConnectionConnectDuration.Update(connectDurationNs)
+	pool.host.ClusterInfo().Stats().UpstreamConnectionConnectDuration.Update(connectDurationNs)
 
 	// bytes total adds all connections data together
 	codecClient.SetConnectionCollector(pool.host.ClusterInfo().Stats().UpstreamBytesReadTotal, pool.host.ClusterInfo().Stats().UpstreamBytesWriteTotal)
@@ -21,6 +21,7 @@ import (
 	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"mosn.io/api"
 	mosnctx "mosn.io/mosn/pkg/context"
@@ -180,9 +181,11 @@ func newActiveClient(ctx context.Context, pool *connPool) *activeClient {
 
 	data := pool.host.CreateConnection(ctx)
 	ac.host = data
+	connectStart := time.Now()
 	if err := ac.host.Connection.Connect(); err != nil {
 		return nil
 	}
+	connectDurationNs := time.Since(connectStart).Nanoseconds()
 
 	connCtx := mosnctx.WithValue(context.Background(), types.ContextKeyConnectionID, data.Connection.ID())
 	codecClient := pool.createStreamClient(connCtx, data)
@@ -195,6 +198,8 @@ func newActiveClient(ctx context.Context, pool *connPool) *activeClient {
 	pool.host.HostStats().UpstreamConnectionActive.Inc(1)
 	pool.host.ClusterInfo().Stats().UpstreamConnectionTotal.Inc(1)
 	pool.host.ClusterInfo().Stats().UpstreamConnectionActive.Inc(1)
+	pool.host.HostStats().UpstreamConnectionConnectDuration.Update(connectDurationNs)
+	pool.host.ClusterInfo().Stats().UpstreamConnectionConnectDuration.Update(connectDurationNs)
 
 	// bytes total adds all connections data together, but buffered data not
 	codecClient.SetConnectionCollector(pool.host.ClusterInfo().Stats().UpstreamBytesReadTotal, pool.host.ClusterInfo().Stats().UpstreamBytesWriteTotal)
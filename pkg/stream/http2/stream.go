@@ -25,6 +25,7 @@ import (
 	"net/url"
 	"strconv"
 	"sync"
+	"time"
 
 	"mosn.io/api"
 	mbuffer "mosn.io/mosn/pkg/buffer"
@@ -35,6 +36,7 @@ import (
 	"mosn.io/mosn/pkg/protocol"
 	mhttp2 "mosn.io/mosn/pkg/protocol/http2"
 	str "mosn.io/mosn/pkg/stream"
+	"mosn.io/mosn/pkg/trace"
 	"mosn.io/mosn/pkg/types"
 	"mosn.io/pkg/buffer"
 )
@@ -384,7 +386,15 @@ func (conn *serverStreamConnection) onNewStreamDetect(ctx context.Context, h2s *
 		conn.mutex.Unlock()
 	}
 
-	stream.receiver = conn.serverCallbacks.NewStreamDetect(stream.ctx, stream, nil)
+	var span types.Span
+	if trace.IsEnabled() {
+		tracer := trace.Tracer(protocol.HTTP2)
+		if tracer != nil {
+			span = tracer.Start(stream.ctx, mhttp2.NewReqHeader(h2s.Request), time.Now())
+		}
+	}
+
+	stream.receiver = conn.serverCallbacks.NewStreamDetect(stream.ctx, stream, span)
 	return stream, nil
 }
 
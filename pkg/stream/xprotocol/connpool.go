@@ -297,7 +297,7 @@ func newActiveClient(ctx context.Context, subProtocol types.ProtocolName, pool *
 	// TODO: support protocol convert
 
 	// TODO: support config
-	if subProtocol != "" {
+	if subProtocol != "" && keepAliveEnabled(subProtocol, pool.host.ClusterInfo()) {
 		rpcKeepAlive := NewKeepAlive(codecClient, subProtocol, time.Second, 6)
 		rpcKeepAlive.StartIdleTimeout()
 		ac.keepAlive = &keepAliveListener{
@@ -306,15 +306,19 @@ func newActiveClient(ctx context.Context, subProtocol types.ProtocolName, pool *
 		ac.client.AddConnectionEventListener(ac.keepAlive)
 	}
 
+	connectStart := time.Now()
 	if err := ac.client.Connect(); err != nil {
 		return nil
 	}
+	connectDurationNs := time.Since(connectStart).Nanoseconds()
 
 	// stats
 	pool.host.HostStats().UpstreamConnectionTotal.Inc(1)
 	pool.host.HostStats().UpstreamConnectionActive.Inc(1)
 	pool.host.ClusterInfo().Stats().UpstreamConnectionTotal.Inc(1)
 	pool.host.ClusterInfo().Stats().UpstreamConnectionActive.Inc(1)
+	pool.host.HostStats().UpstreamConnectionConnectDuration.Update(connectDurationNs)
+	pool.host.ClusterInfo().Stats().UpstreamConnectionConnectDuration.Update(connectDurationNs)
 
 	// bytes total adds all connections data together
 	codecClient.SetConnectionCollector(pool.host.ClusterInfo().Stats().UpstreamBytesReadTotal, pool.host.ClusterInfo().Stats().UpstreamBytesWriteTotal)
@@ -120,6 +120,15 @@ func (kp *xprotocolKeepAlive) sendKeepAlive() {
 	kp.mutex.Unlock()
 }
 
+// keepAliveEnabled checks whether the connections of the sub protocol are kept alive by the heartbeat,
+// the optional keepalive is enabled by the cluster config
+func keepAliveEnabled(subProtocol types.ProtocolName, info types.ClusterInfo) bool {
+	if proto, ok := xprotocol.GetProtocol(subProtocol).(xprotocol.OptionalKeepAlive); ok && proto.OptionalKeepAlive() {
+		return info.HeartbeatKeepAlive()
+	}
+	return true
+}
+
 func (kp *xprotocolKeepAlive) GetTimeout() time.Duration {
 	return kp.Timeout
 }
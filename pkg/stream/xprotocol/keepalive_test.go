@@ -28,6 +28,7 @@ import (
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/protocol/xprotocol/bolt"
+	"mosn.io/mosn/pkg/protocol/xprotocol/dubbo"
 	str "mosn.io/mosn/pkg/stream"
 	"mosn.io/mosn/pkg/types"
 	"mosn.io/mosn/pkg/upstream/cluster"
@@ -231,3 +232,15 @@ func TestKeepAliveIdleFreeWithData(t *testing.T) {
 	close(ch)
 	wg.Wait()
 }
+
+func TestKeepAliveEnabled(t *testing.T) {
+	if !keepAliveEnabled(bolt.ProtocolName, &mockClusterInfo{}) {
+		t.Error("bolt keepalive should be always enabled")
+	}
+	if keepAliveEnabled(dubbo.ProtocolName, &mockClusterInfo{}) {
+		t.Error("dubbo keepalive should be disabled by default")
+	}
+	if !keepAliveEnabled(dubbo.ProtocolName, &mockClusterInfo{keepAlive: true}) {
+		t.Error("dubbo keepalive should be enabled by the cluster config")
+	}
+}
@@ -126,8 +126,9 @@ func (s *mockServer) Reply(iobuf types.IoBuffer) []byte {
 }
 
 type mockClusterInfo struct {
-	name  string
-	limit uint32
+	name      string
+	limit     uint32
+	keepAlive bool
 	types.ClusterInfo
 }
 
@@ -139,6 +140,10 @@ func (ci *mockClusterInfo) ConnBufferLimitBytes() uint32 {
 	return ci.limit
 }
 
+func (ci *mockClusterInfo) HeartbeatKeepAlive() bool {
+	return ci.keepAlive
+}
+
 func (ci *mockClusterInfo) SourceAddress() net.Addr {
 	return nil
 }
@@ -0,0 +1,133 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jaeger
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// thrift compact protocol types, see https://github.com/apache/thrift/blob/master/doc/specs/thrift-compact-protocol.md
+const (
+	compactStop         = 0x00
+	compactBooleanTrue  = 0x01
+	compactBooleanFalse = 0x02
+	compactI32          = 0x05
+	compactI64          = 0x06
+	compactBinary       = 0x08
+	compactList         = 0x09
+	compactStruct       = 0x0c
+)
+
+const (
+	compactProtocolID = 0x82
+	compactVersion    = 1
+	messageOneway     = 4
+)
+
+// compactWriter writes the thrift compact protocol, only the types used by the jaeger agent are supported
+type compactWriter struct {
+	buf bytes.Buffer
+	// lastField is the last field id of the current struct, the field ids are delta encoded
+	lastField  int16
+	fieldStack []int16
+	tmp        [binary.MaxVarintLen64]byte
+}
+
+func (w *compactWriter) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+func (w *compactWriter) Len() int {
+	return w.buf.Len()
+}
+
+func (w *compactWriter) writeVarint(v uint64) {
+	n := binary.PutUvarint(w.tmp[:], v)
+	w.buf.Write(w.tmp[:n])
+}
+
+func (w *compactWriter) writeZigzag(v int64) {
+	w.writeVarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *compactWriter) writeString(s string) {
+	w.writeVarint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *compactWriter) writeMessageBegin(name string, typ byte, seq int32) {
+	w.buf.WriteByte(compactProtocolID)
+	w.buf.WriteByte(compactVersion | typ<<5)
+	w.writeVarint(uint64(uint32(seq)))
+	w.writeString(name)
+}
+
+func (w *compactWriter) writeStructBegin() {
+	w.fieldStack = append(w.fieldStack, w.lastField)
+	w.lastField = 0
+}
+
+func (w *compactWriter) writeStructEnd() {
+	w.buf.WriteByte(compactStop)
+	w.lastField = w.fieldStack[len(w.fieldStack)-1]
+	w.fieldStack = w.fieldStack[:len(w.fieldStack)-1]
+}
+
+func (w *compactWriter) writeFieldBegin(typ byte, id int16) {
+	if delta := id - w.lastField; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.writeZigzag(int64(id))
+	}
+	w.lastField = id
+}
+
+func (w *compactWriter) writeListBegin(elemType byte, size int) {
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elemType)
+	w.writeVarint(uint64(size))
+}
+
+func (w *compactWriter) writeStringField(id int16, s string) {
+	w.writeFieldBegin(compactBinary, id)
+	w.writeString(s)
+}
+
+func (w *compactWriter) writeI32Field(id int16, v int32) {
+	w.writeFieldBegin(compactI32, id)
+	w.writeZigzag(int64(v))
+}
+
+func (w *compactWriter) writeI64Field(id int16, v int64) {
+	w.writeFieldBegin(compactI64, id)
+	w.writeZigzag(v)
+}
+
+// writeBoolField writes the bool field, the value is encoded in the field type
+func (w *compactWriter) writeBoolField(id int16, v bool) {
+	if v {
+		w.writeFieldBegin(compactBooleanTrue, id)
+	} else {
+		w.writeFieldBegin(compactBooleanFalse, id)
+	}
+}
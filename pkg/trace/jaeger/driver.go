@@ -0,0 +1,51 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jaeger
+
+import (
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/trace"
+	"mosn.io/mosn/pkg/trace/otel"
+)
+
+// DriverName is the name of the jaeger trace driver
+const DriverName = "Jaeger"
+
+// ExporterJaeger is the exporter protocol that reports the spans to the jaeger agent
+// in the thrift compact format over udp, it can be used by the opentelemetry driver too
+const ExporterJaeger = "jaeger"
+
+func init() {
+	otel.RegisterExporter(ExporterJaeger, newReporter)
+
+	builder := otel.NewTracerBuilder(DriverName, setDefaults)
+	trace.RegisterDriver(DriverName, trace.NewDefaultDriverImpl())
+	trace.RegisterTracerBuilder(DriverName, protocol.HTTP1, builder)
+	trace.RegisterTracerBuilder(DriverName, protocol.HTTP2, builder)
+	trace.RegisterTracerBuilder(DriverName, protocol.Xprotocol, builder)
+}
+
+// setDefaults reports to the jaeger agent and propagates the uber-trace-id header by default
+func setDefaults(cfg *otel.Config) {
+	if cfg.Exporter.Protocol == "" {
+		cfg.Exporter.Protocol = ExporterJaeger
+	}
+	if len(cfg.Propagators) == 0 {
+		cfg.Propagators = []string{otel.PropagatorJaeger}
+	}
+}
@@ -0,0 +1,227 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jaeger
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"mosn.io/mosn/pkg/trace/otel"
+)
+
+// maxPacketSize is the max udp packet size accepted by the jaeger agent
+const maxPacketSize = 65000
+
+// jaeger tag types
+const (
+	tagString = 0
+	tagBool   = 2
+	tagLong   = 3
+)
+
+// reporter emits the spans to the jaeger agent, the batch is encoded as the Agent.emitBatch
+// oneway call in the thrift compact protocol, see jaeger-idl/thrift/agent.thrift and jaeger.thrift
+type reporter struct {
+	config *otel.Config
+	mutex  sync.Mutex
+	// conns caches the udp connections by the host address
+	conns map[string]net.Conn
+	seq   int32
+}
+
+func newReporter(cfg *otel.Config) (otel.Exporter, error) {
+	return &reporter{
+		config: cfg,
+		conns:  make(map[string]net.Conn),
+	}, nil
+}
+
+func (r *reporter) getConn(host string) (net.Conn, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if conn, ok := r.conns[host]; ok {
+		return conn, nil
+	}
+	conn, err := net.Dial("udp", host)
+	if err != nil {
+		return nil, err
+	}
+	r.conns[host] = conn
+	return conn, nil
+}
+
+func (r *reporter) Export(ctx context.Context, spans []*otel.Span) error {
+	host, err := otel.CollectorHost(ctx, &r.config.Exporter)
+	if err != nil {
+		return err
+	}
+	conn, err := r.getConn(host)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+	return r.emit(conn, spans)
+}
+
+// emit sends the spans in as few packets as possible, the batch is split if it exceeds the max packet size
+func (r *reporter) emit(conn net.Conn, spans []*otel.Span) error {
+	r.seq++
+	data := r.encodeBatch(spans, r.seq)
+	if len(data) > maxPacketSize {
+		if len(spans) == 1 {
+			return fmt.Errorf("span size %d exceeds the max packet size", len(data))
+		}
+		half := len(spans) / 2
+		if err := r.emit(conn, spans[:half]); err != nil {
+			return err
+		}
+		return r.emit(conn, spans[half:])
+	}
+	_, err := conn.Write(data)
+	return err
+}
+
+func (r *reporter) encodeBatch(spans []*otel.Span, seq int32) []byte {
+	w := &compactWriter{}
+	w.writeMessageBegin("emitBatch", messageOneway, seq)
+	// emitBatch_args
+	w.writeStructBegin()
+	w.writeFieldBegin(compactStruct, 1)
+	// Batch
+	w.writeStructBegin()
+	w.writeFieldBegin(compactStruct, 1)
+	r.writeProcess(w)
+	w.writeFieldBegin(compactList, 2)
+	w.writeListBegin(compactStruct, len(spans))
+	for _, s := range spans {
+		writeSpan(w, s)
+	}
+	w.writeStructEnd()
+	w.writeStructEnd()
+	return w.Bytes()
+}
+
+func (r *reporter) writeProcess(w *compactWriter) {
+	w.writeStructBegin()
+	w.writeStringField(1, r.config.ServiceName)
+	if len(r.config.ResourceAttributes) > 0 {
+		keys := make([]string, 0, len(r.config.ResourceAttributes))
+		for key := range r.config.ResourceAttributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		w.writeFieldBegin(compactList, 2)
+		w.writeListBegin(compactStruct, len(keys))
+		for _, key := range keys {
+			writeStringTag(w, key, r.config.ResourceAttributes[key])
+		}
+	}
+	w.writeStructEnd()
+}
+
+func writeSpan(w *compactWriter, s *otel.Span) {
+	traceHigh, traceLow := splitTraceID(s.TraceId())
+	w.writeStructBegin()
+	w.writeI64Field(1, traceLow)
+	w.writeI64Field(2, traceHigh)
+	w.writeI64Field(3, parseSpanID(s.SpanId()))
+	w.writeI64Field(4, parseSpanID(s.ParentSpanId()))
+	w.writeStringField(5, s.Name())
+	// the exported spans are always sampled
+	w.writeI32Field(7, 1)
+	w.writeI64Field(8, s.StartTime().UnixNano()/int64(time.Microsecond))
+	w.writeI64Field(9, s.EndTime().Sub(s.StartTime()).Nanoseconds()/int64(time.Microsecond))
+
+	type tag struct {
+		key, value string
+		long       bool
+	}
+	var tags []tag
+	if s.Kind() == otel.SpanKindServer {
+		tags = append(tags, tag{key: "span.kind", value: "server"})
+	}
+	s.RangeTags(func(key uint64, value string) bool {
+		tags = append(tags, tag{key: otel.TagName(key), value: value, long: otel.IsIntTag(key)})
+		return true
+	})
+	code, _ := s.Status()
+	failed := code == otel.StatusError
+	size := len(tags)
+	if failed {
+		size++
+	}
+	w.writeFieldBegin(compactList, 10)
+	w.writeListBegin(compactStruct, size)
+	for _, t := range tags {
+		if t.long {
+			if v, err := strconv.ParseInt(t.value, 10, 64); err == nil {
+				writeLongTag(w, t.key, v)
+				continue
+			}
+		}
+		writeStringTag(w, t.key, t.value)
+	}
+	// jaeger marks the failed span by the error tag
+	if failed {
+		w.writeStructBegin()
+		w.writeStringField(1, "error")
+		w.writeI32Field(2, tagBool)
+		w.writeBoolField(5, true)
+		w.writeStructEnd()
+	}
+	w.writeStructEnd()
+}
+
+func writeStringTag(w *compactWriter, key, value string) {
+	w.writeStructBegin()
+	w.writeStringField(1, key)
+	w.writeI32Field(2, tagString)
+	w.writeStringField(3, value)
+	w.writeStructEnd()
+}
+
+func writeLongTag(w *compactWriter, key string, value int64) {
+	w.writeStructBegin()
+	w.writeStringField(1, key)
+	w.writeI32Field(2, tagLong)
+	w.writeI64Field(6, value)
+	w.writeStructEnd()
+}
+
+// splitTraceID splits the 128 bits hex trace id into the high and low 64 bits
+func splitTraceID(traceID string) (int64, int64) {
+	var id [16]byte
+	hex.Decode(id[:], []byte(traceID))
+	return int64(binary.BigEndian.Uint64(id[:8])), int64(binary.BigEndian.Uint64(id[8:]))
+}
+
+// parseSpanID parses the 64 bits hex span id, the empty id is 0
+func parseSpanID(spanID string) int64 {
+	var id [8]byte
+	hex.Decode(id[:], []byte(spanID))
+	return int64(binary.BigEndian.Uint64(id[:]))
+}
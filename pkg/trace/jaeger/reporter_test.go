@@ -0,0 +1,309 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jaeger
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/trace/otel"
+)
+
+// compactReader decodes the thrift compact protocol into the generic values,
+// the structs are decoded as map[int16]interface{} and the lists as []interface{}
+type compactReader struct {
+	data []byte
+	pos  int
+}
+
+var errShortData = errors.New("short data")
+
+func (r *compactReader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errShortData
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *compactReader) readVarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, errShortData
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *compactReader) readZigzag() (int64, error) {
+	v, err := r.readVarint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (r *compactReader) readString() (string, error) {
+	n, err := r.readVarint()
+	if err != nil || r.pos+int(n) > len(r.data) {
+		return "", errShortData
+	}
+	s := string(r.data[r.pos : r.pos+int(n)])
+	r.pos += int(n)
+	return s, nil
+}
+
+func (r *compactReader) readMessageBegin() (string, byte, error) {
+	if b, err := r.readByte(); err != nil || b != compactProtocolID {
+		return "", 0, errors.New("bad protocol id")
+	}
+	b, err := r.readByte()
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := r.readVarint(); err != nil {
+		return "", 0, err
+	}
+	name, err := r.readString()
+	return name, b >> 5, err
+}
+
+func (r *compactReader) readValue(typ byte) (interface{}, error) {
+	switch typ {
+	case compactBooleanTrue:
+		return true, nil
+	case compactBooleanFalse:
+		return false, nil
+	case compactI32, compactI64:
+		return r.readZigzag()
+	case compactBinary:
+		return r.readString()
+	case compactList:
+		header, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		size := int(header >> 4)
+		if size == 15 {
+			n, err := r.readVarint()
+			if err != nil {
+				return nil, err
+			}
+			size = int(n)
+		}
+		list := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			v, err := r.readValue(header & 0x0f)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case compactStruct:
+		return r.readStruct()
+	default:
+		return nil, errors.New("unsupported type")
+	}
+}
+
+func (r *compactReader) readStruct() (map[int16]interface{}, error) {
+	fields := map[int16]interface{}{}
+	var last int16
+	for {
+		header, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if header == compactStop {
+			return fields, nil
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			v, err := r.readZigzag()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		v, err := r.readValue(header & 0x0f)
+		if err != nil {
+			return nil, err
+		}
+		fields[id] = v
+		last = id
+	}
+}
+
+// tags converts the jaeger tags to a map
+func tags(list interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for _, item := range list.([]interface{}) {
+		tag := item.(map[int16]interface{})
+		key := tag[1].(string)
+		switch tag[2].(int64) {
+		case tagString:
+			result[key] = tag[3]
+		case tagBool:
+			result[key] = tag[5]
+		case tagLong:
+			result[key] = tag[6]
+		}
+	}
+	return result
+}
+
+func TestReporter(t *testing.T) {
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer agent.Close()
+
+	builder := otel.NewTracerBuilder("jaeger_test", setDefaults)
+	tracer, err := builder(map[string]interface{}{
+		"service_name":        "jaeger-test",
+		"resource_attributes": map[string]interface{}{"hostname": "test"},
+		"exporter": map[string]interface{}{
+			"endpoint": agent.LocalAddr().String(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	s := tracer.Start(context.Background(), protocol.CommonHeader{
+		"uber-trace-id": "463ac35c9f6413ad48485a3953bb6124:a2fb4a1d1a96d312:0:1",
+	}, start)
+	upstream := protocol.CommonHeader{}
+	s.InjectContext(upstream)
+	if upstream["uber-trace-id"] != "463ac35c9f6413ad48485a3953bb6124:"+s.SpanId()+":0:1" {
+		t.Errorf("unexpected upstream headers: %v", upstream)
+	}
+	info := network.NewRequestInfo()
+	info.SetResponseCode(500)
+	info.SetBytesSent(128)
+	s.SetRequestInfo(info)
+	s.FinishSpan()
+	tracer.(*otel.Tracer).Flush()
+
+	buf := make([]byte, maxPacketSize)
+	agent.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, _, err := agent.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &compactReader{data: buf[:n]}
+	name, typ, err := r.readMessageBegin()
+	if err != nil || name != "emitBatch" || typ != messageOneway {
+		t.Fatalf("unexpected message: %s %d %v", name, typ, err)
+	}
+	args, err := r.readStruct()
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := args[1].(map[int16]interface{})
+	process := batch[1].(map[int16]interface{})
+	if process[1] != "jaeger-test" || tags(process[2])["hostname"] != "test" {
+		t.Errorf("unexpected process: %v", process)
+	}
+	spans := batch[2].([]interface{})
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	got := spans[0].(map[int16]interface{})
+	high, low := splitTraceID("463ac35c9f6413ad48485a3953bb6124")
+	if got[1] != low || got[2] != high || got[3] != parseSpanID(s.SpanId()) || got[4] != parseSpanID("a2fb4a1d1a96d312") {
+		t.Errorf("unexpected span ids: %v", got)
+	}
+	if got[5] != s.(*otel.Span).Name() || got[7] != int64(1) || got[8] != start.UnixNano()/int64(time.Microsecond) {
+		t.Errorf("unexpected span: %v", got)
+	}
+	spanTags := tags(got[10])
+	if spanTags["http.status_code"] != int64(500) || spanTags["mosn.response.size"] != int64(128) ||
+		spanTags["span.kind"] != "server" || spanTags["error"] != true {
+		t.Errorf("unexpected tags: %v", spanTags)
+	}
+}
+
+func TestSplitLargeBatch(t *testing.T) {
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer agent.Close()
+	cfg, err := otel.ParseConfig(map[string]interface{}{
+		"exporter": map[string]interface{}{
+			"protocol": ExporterJaeger,
+			"endpoint": agent.LocalAddr().String(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := otel.NewTracerBuilder("jaeger_split_test", setDefaults)
+	tracer, err := builder(map[string]interface{}{
+		"exporter": map[string]interface{}{"endpoint": agent.LocalAddr().String()},
+		"sampler":  map[string]interface{}{"type": otel.SamplerAlwaysOn},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spans []*otel.Span
+	for i := 0; i < 4; i++ {
+		s := tracer.Start(context.Background(), protocol.CommonHeader{}, time.Now())
+		s.SetOperation(strings.Repeat("x", maxPacketSize/3))
+		spans = append(spans, s.(*otel.Span))
+	}
+	exp, _ := newReporter(cfg)
+	if err := exp.Export(context.Background(), spans); err != nil {
+		t.Fatal(err)
+	}
+	received := 0
+	buf := make([]byte, maxPacketSize)
+	agent.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for received < 4 {
+		n, _, err := agent.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n > maxPacketSize {
+			t.Fatalf("packet size %d exceeds the max size", n)
+		}
+		r := &compactReader{data: buf[:n]}
+		if _, _, err := r.readMessageBegin(); err != nil {
+			t.Fatal(err)
+		}
+		args, err := r.readStruct()
+		if err != nil {
+			t.Fatal(err)
+		}
+		received += len(args[1].(map[int16]interface{})[2].([]interface{}))
+	}
+
+	// a span larger than the packet size can not be sent
+	spans[0].SetOperation(strings.Repeat("x", maxPacketSize))
+	if err := exp.Export(context.Background(), spans[:1]); err == nil {
+		t.Error("expected error for the large span")
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otel
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"mosn.io/api"
+)
+
+// DriverName is the name of the opentelemetry trace driver
+const DriverName = "OpenTelemetry"
+
+// propagator names
+const (
+	PropagatorTraceContext = "tracecontext"
+	PropagatorB3           = "b3"
+	PropagatorB3Multi      = "b3multi"
+	PropagatorJaeger       = "jaeger"
+)
+
+// sampler types, the names follow the OTEL_TRACES_SAMPLER values
+const (
+	SamplerAlwaysOn                = "always_on"
+	SamplerAlwaysOff               = "always_off"
+	SamplerTraceIDRatio            = "traceidratio"
+	SamplerParentBasedAlwaysOn     = "parentbased_always_on"
+	SamplerParentBasedAlwaysOff    = "parentbased_always_off"
+	SamplerParentBasedTraceIDRatio = "parentbased_traceidratio"
+)
+
+// exporter protocols
+const (
+	ExporterGRPC         = "grpc"
+	ExporterHTTPProtobuf = "http/protobuf"
+)
+
+const (
+	defaultServiceName   = "mosn"
+	defaultHTTPPath      = "/v1/traces"
+	defaultBatchSize     = 512
+	defaultQueueSize     = 2048
+	defaultFlushInterval = 5 * time.Second
+	defaultExportTimeout = 10 * time.Second
+)
+
+var defaultPropagators = []string{PropagatorTraceContext, PropagatorB3Multi}
+
+// Config is the config of the opentelemetry trace driver
+type Config struct {
+	ServiceName        string            `json:"service_name,omitempty"`
+	ResourceAttributes map[string]string `json:"resource_attributes,omitempty"`
+	// Propagators are used in order to extract the parent span context,
+	// and all of them are used to inject the span context into the upstream request.
+	Propagators []string       `json:"propagators,omitempty"`
+	Sampler     SamplerConfig  `json:"sampler,omitempty"`
+	Exporter    ExporterConfig `json:"exporter,omitempty"`
+}
+
+// SamplerConfig decides which traces are recorded and exported
+type SamplerConfig struct {
+	Type  string  `json:"type,omitempty"`
+	Ratio float64 `json:"ratio,omitempty"`
+}
+
+// ExporterConfig describes where and how the spans are exported
+type ExporterConfig struct {
+	Protocol string `json:"protocol,omitempty"`
+	// Cluster is the collector cluster, Endpoint is used if the cluster is not configured.
+	Cluster  string `json:"cluster,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	// Path is the request path of the http exporters, the default value depends on the exporter.
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// the spans are exported in batches when the batch is full or the flush interval is reached,
+	// new spans are dropped if the queue is full.
+	BatchSize     int                 `json:"max_export_batch_size,omitempty"`
+	QueueSize     int                 `json:"max_queue_size,omitempty"`
+	FlushInterval *api.DurationConfig `json:"schedule_delay,omitempty"`
+	Timeout       *api.DurationConfig `json:"export_timeout,omitempty"`
+}
+
+// ParseConfig parses the driver config and sets the default values
+func ParseConfig(config map[string]interface{}) (*Config, error) {
+	return parseConfig(config, nil)
+}
+
+func parseConfig(config map[string]interface{}, defaults func(cfg *Config)) (*Config, error) {
+	cfg := &Config{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	if defaults != nil {
+		defaults(cfg)
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = defaultServiceName
+	}
+	if len(cfg.Propagators) == 0 {
+		cfg.Propagators = defaultPropagators
+	}
+	for _, name := range cfg.Propagators {
+		if _, ok := propagators[name]; !ok {
+			return nil, fmt.Errorf("unknown propagator: %s", name)
+		}
+	}
+	if cfg.Sampler.Type == "" {
+		cfg.Sampler.Type = SamplerParentBasedAlwaysOn
+	}
+	if cfg.Sampler.Ratio < 0 || cfg.Sampler.Ratio > 1 {
+		return nil, fmt.Errorf("invalid sampler ratio: %v", cfg.Sampler.Ratio)
+	}
+	exp := &cfg.Exporter
+	if exp.Protocol == "" {
+		exp.Protocol = ExporterGRPC
+	}
+	if _, ok := exporterFactories[exp.Protocol]; !ok {
+		return nil, fmt.Errorf("unknown exporter protocol: %s", exp.Protocol)
+	}
+	if exp.Cluster == "" && exp.Endpoint == "" {
+		return nil, fmt.Errorf("exporter cluster or endpoint is required")
+	}
+	if exp.BatchSize <= 0 {
+		exp.BatchSize = defaultBatchSize
+	}
+	if exp.QueueSize <= 0 {
+		exp.QueueSize = defaultQueueSize
+	}
+	if exp.QueueSize < exp.BatchSize {
+		exp.QueueSize = exp.BatchSize
+	}
+	if exp.FlushInterval == nil || exp.FlushInterval.Duration <= 0 {
+		exp.FlushInterval = &api.DurationConfig{Duration: defaultFlushInterval}
+	}
+	if exp.Timeout == nil || exp.Timeout.Duration <= 0 {
+		exp.Timeout = &api.DurationConfig{Duration: defaultExportTimeout}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,204 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"mosn.io/mosn/pkg/upstream/cluster"
+)
+
+// traceExportMethod is the full method name of the otlp trace service
+const traceExportMethod = "/opentelemetry.proto.collector.trace.v1.TraceService/Export"
+
+var errNoCollectorHost = errors.New("no available host in the collector cluster")
+
+// Exporter sends a batch of the finished spans to the collector,
+// it is called by the batch processor in a single goroutine
+type Exporter interface {
+	Export(ctx context.Context, spans []*Span) error
+}
+
+// ExporterFactory creates the exporter of a protocol
+type ExporterFactory func(cfg *Config) (Exporter, error)
+
+var exporterFactories = map[string]ExporterFactory{
+	ExporterGRPC:         newGRPCExporter,
+	ExporterHTTPProtobuf: newHTTPExporter,
+}
+
+// RegisterExporter registers the exporter factory of the protocol, it should be called in init
+func RegisterExporter(protocol string, factory ExporterFactory) {
+	exporterFactories[protocol] = factory
+}
+
+func newExporter(cfg *Config) (Exporter, error) {
+	factory, ok := exporterFactories[cfg.Exporter.Protocol]
+	if !ok {
+		return nil, fmt.Errorf("unknown exporter protocol: %s", cfg.Exporter.Protocol)
+	}
+	return factory(cfg)
+}
+
+// CollectorHost chooses a host in the collector cluster, the endpoint is used if the cluster is not configured
+func CollectorHost(ctx context.Context, cfg *ExporterConfig) (string, error) {
+	if cfg.Cluster == "" {
+		return cfg.Endpoint, nil
+	}
+	adapter := cluster.GetClusterMngAdapterInstance()
+	if adapter == nil {
+		return "", errNoCollectorHost
+	}
+	snapshot := adapter.GetClusterSnapshot(ctx, cfg.Cluster)
+	if snapshot == nil {
+		return "", fmt.Errorf("collector cluster %s is not found", cfg.Cluster)
+	}
+	host := snapshot.LoadBalancer().ChooseHost(cluster.NewLoadBalancerContext(ctx))
+	if host == nil {
+		return "", errNoCollectorHost
+	}
+	return host.AddressString(), nil
+}
+
+// grpcExporter exports the spans by the otlp/grpc protocol
+type grpcExporter struct {
+	config *Config
+	// conns caches the grpc connections by the host address
+	conns sync.Map
+}
+
+func newGRPCExporter(cfg *Config) (Exporter, error) {
+	return &grpcExporter{config: cfg}, nil
+}
+
+func (e *grpcExporter) getConn(host string) (*grpc.ClientConn, error) {
+	if conn, ok := e.conns.Load(host); ok {
+		return conn.(*grpc.ClientConn), nil
+	}
+	conn, err := grpc.Dial(host, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	if actual, loaded := e.conns.LoadOrStore(host, conn); loaded {
+		conn.Close()
+		return actual.(*grpc.ClientConn), nil
+	}
+	return conn, nil
+}
+
+func (e *grpcExporter) Export(ctx context.Context, spans []*Span) error {
+	host, err := CollectorHost(ctx, &e.config.Exporter)
+	if err != nil {
+		return err
+	}
+	conn, err := e.getConn(host)
+	if err != nil {
+		return err
+	}
+	for key, value := range e.config.Exporter.Headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+	req := rawMessage(encodeTraceRequest(e.config, spans))
+	resp := rawMessage(nil)
+	return conn.Invoke(ctx, traceExportMethod, &req, &resp, grpc.ForceCodec(rawCodec{}))
+}
+
+// httpExporter exports the spans by the otlp/http protocol with the binary protobuf encoding
+type httpExporter struct {
+	config *Config
+	client *http.Client
+}
+
+func newHTTPExporter(cfg *Config) (Exporter, error) {
+	return &httpExporter{
+		config: cfg,
+		client: &http.Client{},
+	}, nil
+}
+
+func (e *httpExporter) Export(ctx context.Context, spans []*Span) error {
+	return PostSpans(ctx, e.client, &e.config.Exporter, defaultHTTPPath, "application/x-protobuf", encodeTraceRequest(e.config, spans))
+}
+
+// PostSpans posts the encoded spans to the collector, the defaultPath is used if the path is not configured
+func PostSpans(ctx context.Context, client *http.Client, cfg *ExporterConfig, defaultPath, contentType string, body []byte) error {
+	host, err := CollectorHost(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	path := cfg.Path
+	if path == "" {
+		path = defaultPath
+	}
+	req, err := http.NewRequest(http.MethodPost, "http://"+host+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for key, value := range cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responds with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// rawMessage is the encoded protobuf message
+type rawMessage []byte
+
+// rawCodec sends and receives the encoded protobuf messages as they are
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(*rawMessage)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return *msg, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(*rawMessage)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*msg = append((*msg)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
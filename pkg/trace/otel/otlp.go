@@ -0,0 +1,194 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otel
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// the otlp schemas are not vendored, the ExportTraceServiceRequest is encoded by hand,
+// the field numbers follow opentelemetry/proto/collector/trace/v1/trace_service.proto
+const (
+	// ExportTraceServiceRequest
+	fieldResourceSpans = 1
+	// ResourceSpans
+	fieldResource   = 1
+	fieldScopeSpans = 2
+	// Resource
+	fieldResourceAttributes = 1
+	// ScopeSpans
+	fieldScope = 1
+	fieldSpans = 2
+	// InstrumentationScope
+	fieldScopeName = 1
+	// Span
+	fieldTraceID      = 1
+	fieldSpanID       = 2
+	fieldTraceState   = 3
+	fieldParentSpanID = 4
+	fieldName         = 5
+	fieldKind         = 6
+	fieldStartTime    = 7
+	fieldEndTime      = 8
+	fieldAttributes   = 9
+	fieldStatus       = 15
+	// Status
+	fieldStatusMessage = 2
+	fieldStatusCode    = 3
+	// KeyValue
+	fieldKey   = 1
+	fieldValue = 2
+	// AnyValue
+	fieldStringValue = 1
+	fieldIntValue    = 3
+)
+
+// scopeName is the instrumentation scope of the spans
+const scopeName = "mosn.io/mosn/pkg/trace/otel"
+
+type protoEncoder struct {
+	*proto.Buffer
+}
+
+func newProtoEncoder() protoEncoder {
+	return protoEncoder{proto.NewBuffer(nil)}
+}
+
+func (e protoEncoder) tag(field int, wireType int) {
+	e.EncodeVarint(uint64(field<<3 | wireType))
+}
+
+func (e protoEncoder) bytes(field int, b []byte) {
+	e.tag(field, proto.WireBytes)
+	e.EncodeRawBytes(b)
+}
+
+// str omits the empty string like the proto3 scalar fields
+func (e protoEncoder) str(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.tag(field, proto.WireBytes)
+	e.EncodeStringBytes(s)
+}
+
+func (e protoEncoder) varint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, proto.WireVarint)
+	e.EncodeVarint(v)
+}
+
+func (e protoEncoder) fixed64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, proto.WireFixed64)
+	e.EncodeFixed64(v)
+}
+
+func (e protoEncoder) message(field int, fn func(protoEncoder)) {
+	sub := newProtoEncoder()
+	fn(sub)
+	e.bytes(field, sub.Bytes())
+}
+
+// stringAttribute encodes a KeyValue with a string value
+func (e protoEncoder) stringAttribute(field int, key, value string) {
+	e.message(field, func(kv protoEncoder) {
+		kv.str(fieldKey, key)
+		kv.message(fieldValue, func(v protoEncoder) {
+			// the oneof field is always encoded, even if it is empty
+			v.tag(fieldStringValue, proto.WireBytes)
+			v.EncodeStringBytes(value)
+		})
+	})
+}
+
+// intAttribute encodes a KeyValue with an int value
+func (e protoEncoder) intAttribute(field int, key string, value int64) {
+	e.message(field, func(kv protoEncoder) {
+		kv.str(fieldKey, key)
+		kv.message(fieldValue, func(v protoEncoder) {
+			v.tag(fieldIntValue, proto.WireVarint)
+			v.EncodeVarint(uint64(value))
+		})
+	})
+}
+
+// encodeTraceRequest encodes the spans as an ExportTraceServiceRequest
+func encodeTraceRequest(cfg *Config, spans []*Span) []byte {
+	req := newProtoEncoder()
+	req.message(fieldResourceSpans, func(rs protoEncoder) {
+		rs.message(fieldResource, func(r protoEncoder) {
+			r.stringAttribute(fieldResourceAttributes, "service.name", cfg.ServiceName)
+			keys := make([]string, 0, len(cfg.ResourceAttributes))
+			for key := range cfg.ResourceAttributes {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				r.stringAttribute(fieldResourceAttributes, key, cfg.ResourceAttributes[key])
+			}
+		})
+		rs.message(fieldScopeSpans, func(ss protoEncoder) {
+			ss.message(fieldScope, func(s protoEncoder) {
+				s.str(fieldScopeName, scopeName)
+			})
+			for _, span := range spans {
+				ss.message(fieldSpans, span.encode)
+			}
+		})
+	})
+	return req.Bytes()
+}
+
+func (s *Span) encode(e protoEncoder) {
+	e.bytes(fieldTraceID, s.ctx.traceID[:])
+	e.bytes(fieldSpanID, s.ctx.spanID[:])
+	e.str(fieldTraceState, s.ctx.traceState)
+	if s.parentSpanID != [8]byte{} {
+		e.bytes(fieldParentSpanID, s.parentSpanID[:])
+	}
+	e.str(fieldName, s.name)
+	e.varint(fieldKind, uint64(s.kind))
+	e.fixed64(fieldStartTime, uint64(s.startTime.UnixNano()))
+	e.fixed64(fieldEndTime, uint64(s.endTime.UnixNano()))
+	for key, value := range s.tags {
+		if value == "" {
+			continue
+		}
+		if intTags[key] {
+			if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+				e.intAttribute(fieldAttributes, tagNames[key], v)
+				continue
+			}
+		}
+		e.stringAttribute(fieldAttributes, tagNames[key], value)
+	}
+	if s.status != StatusUnset {
+		e.message(fieldStatus, func(st protoEncoder) {
+			st.str(fieldStatusMessage, s.statusMsg)
+			st.varint(fieldStatusCode, uint64(s.status))
+		})
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otel
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/utils"
+)
+
+// batchProcessor queues the finished spans and exports them in batches asynchronously,
+// the spans are dropped if the queue is full, so the requests are never blocked by the exporter
+type batchProcessor struct {
+	exporter      Exporter
+	stats         types.Metrics
+	queue         chan *Span
+	batchSize     int
+	flushInterval time.Duration
+	timeout       time.Duration
+	flushCh       chan chan struct{}
+	stopCh        chan struct{}
+	doneCh        chan struct{}
+	stopOnce      sync.Once
+	stopped       int32
+	dropped       uint64
+	exported      uint64
+}
+
+func newBatchProcessor(exp Exporter, cfg *ExporterConfig, stats types.Metrics) *batchProcessor {
+	p := &batchProcessor{
+		exporter:      exp,
+		stats:         stats,
+		queue:         make(chan *Span, cfg.QueueSize),
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval.Duration,
+		timeout:       cfg.Timeout.Duration,
+		flushCh:       make(chan chan struct{}),
+		stopCh:        make(chan struct{}),
+		doneCh:        make(chan struct{}),
+	}
+	utils.GoWithRecover(p.run, nil)
+	return p
+}
+
+func (p *batchProcessor) onEnd(s *Span) {
+	if atomic.LoadInt32(&p.stopped) == 1 {
+		p.drop()
+		return
+	}
+	select {
+	case p.queue <- s:
+	default:
+		// avoid flooding the log
+		if dropped := p.drop(); dropped%1000 == 1 {
+			log.DefaultLogger.Warnf("[trace] [otel] span queue is full, %d spans are dropped", dropped)
+		}
+	}
+}
+
+func (p *batchProcessor) drop() uint64 {
+	p.stats.Counter(metrics.TraceSpanDropped).Inc(1)
+	return atomic.AddUint64(&p.dropped, 1)
+}
+
+func (p *batchProcessor) run() {
+	defer close(p.doneCh)
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, p.batchSize)
+	export := func() {
+		if len(batch) > 0 {
+			p.export(batch)
+			batch = batch[:0]
+		}
+	}
+	// drain exports all the queued spans
+	drain := func() {
+		for {
+			select {
+			case s := <-p.queue:
+				batch = append(batch, s)
+				if len(batch) >= p.batchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case s := <-p.queue:
+			batch = append(batch, s)
+			if len(batch) >= p.batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case ch := <-p.flushCh:
+			drain()
+			close(ch)
+		case <-p.stopCh:
+			drain()
+			return
+		}
+	}
+}
+
+func (p *batchProcessor) export(batch []*Span) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	if err := p.exporter.Export(ctx, batch); err != nil {
+		p.stats.Counter(metrics.TraceExportFailed).Inc(int64(len(batch)))
+		log.DefaultLogger.Errorf("[trace] [otel] export %d spans failed: %v", len(batch), err)
+		return
+	}
+	p.stats.Counter(metrics.TraceSpanExported).Inc(int64(len(batch)))
+	atomic.AddUint64(&p.exported, uint64(len(batch)))
+}
+
+// flush exports the queued spans and waits for the export
+func (p *batchProcessor) flush() {
+	ch := make(chan struct{})
+	select {
+	case p.flushCh <- ch:
+		<-ch
+	case <-p.doneCh:
+	}
+}
+
+// shutdown exports the queued spans and stops the processor, it is called when the process is shut down
+func (p *batchProcessor) shutdown() error {
+	p.stopOnce.Do(func() {
+		atomic.StoreInt32(&p.stopped, 1)
+		close(p.stopCh)
+	})
+	<-p.doneCh
+	return nil
+}
@@ -0,0 +1,292 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otel
+
+import (
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"mosn.io/api"
+)
+
+// header keys, they are lower case so that the case sensitive header maps of the rpc protocols work
+const (
+	traceparentHeader  = "traceparent"
+	tracestateHeader   = "tracestate"
+	b3Header           = "b3"
+	b3TraceIDHeader    = "x-b3-traceid"
+	b3SpanIDHeader     = "x-b3-spanid"
+	b3ParentSpanHeader = "x-b3-parentspanid"
+	b3SampledHeader    = "x-b3-sampled"
+	b3FlagsHeader      = "x-b3-flags"
+	jaegerHeader       = "uber-trace-id"
+)
+
+const (
+	traceparentVersion = "00"
+	flagSampled        = 0x01
+)
+
+// spanContext is the part of the span that is propagated across the process boundary
+type spanContext struct {
+	traceID    [16]byte
+	spanID     [8]byte
+	traceState string
+	sampled    bool
+	// deferred means the parent does not make the sampling decision
+	deferred bool
+}
+
+func (sc spanContext) valid() bool {
+	return sc.traceID != [16]byte{} && sc.spanID != [8]byte{}
+}
+
+// propagator extracts and injects the span context from and into the request headers
+type propagator interface {
+	extract(headers api.HeaderMap) (spanContext, bool)
+	inject(sc spanContext, headers api.HeaderMap)
+}
+
+var propagators = map[string]propagator{
+	PropagatorTraceContext: traceContextPropagator{},
+	PropagatorB3:           b3Propagator{single: true},
+	PropagatorB3Multi:      b3Propagator{},
+	PropagatorJaeger:       jaegerPropagator{},
+}
+
+// getHeader returns the header value, an empty value is treated as absent,
+// as some header maps (e.g. http2) report every key as existing
+func getHeader(headers api.HeaderMap, key string) (string, bool) {
+	value, ok := headers.Get(key)
+	if !ok || value == "" {
+		return "", false
+	}
+	return strings.TrimSpace(value), true
+}
+
+// traceContextPropagator implements the W3C Trace Context, see https://www.w3.org/TR/trace-context/
+type traceContextPropagator struct{}
+
+func (traceContextPropagator) extract(headers api.HeaderMap) (spanContext, bool) {
+	value, ok := getHeader(headers, traceparentHeader)
+	if !ok {
+		return spanContext{}, false
+	}
+	sc, ok := parseTraceparent(value)
+	if !ok {
+		return spanContext{}, false
+	}
+	if state, ok := getHeader(headers, tracestateHeader); ok {
+		sc.traceState = state
+	}
+	return sc, true
+}
+
+func (traceContextPropagator) inject(sc spanContext, headers api.HeaderMap) {
+	headers.Set(traceparentHeader, formatTraceparent(sc))
+	if sc.traceState != "" {
+		headers.Set(tracestateHeader, sc.traceState)
+	}
+}
+
+// parseTraceparent parses the traceparent header in the format of version-traceid-parentid-flags
+func parseTraceparent(value string) (spanContext, bool) {
+	sc := spanContext{}
+	// 2 + 1 + 32 + 1 + 16 + 1 + 2
+	if len(value) < 55 {
+		return sc, false
+	}
+	version, err := hex.DecodeString(value[0:2])
+	if err != nil || version[0] == 0xff {
+		return sc, false
+	}
+	// the future versions may append fields, which are ignored
+	if (version[0] == 0 && len(value) != 55) || (len(value) > 55 && value[55] != '-') {
+		return sc, false
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, false
+	}
+	if !decodeHex(sc.traceID[:], value[3:35]) || !decodeHex(sc.spanID[:], value[36:52]) {
+		return sc, false
+	}
+	var flags [1]byte
+	if !decodeHex(flags[:], value[53:55]) {
+		return sc, false
+	}
+	sc.sampled = flags[0]&flagSampled != 0
+	return sc, sc.valid()
+}
+
+func formatTraceparent(sc spanContext) string {
+	flags := "00"
+	if sc.sampled {
+		flags = "01"
+	}
+	return traceparentVersion + "-" + hex.EncodeToString(sc.traceID[:]) + "-" + hex.EncodeToString(sc.spanID[:]) + "-" + flags
+}
+
+// b3Propagator implements the B3 propagation, see https://github.com/openzipkin/b3-propagation,
+// the single header and the multiple headers are both extracted, and only the configured one is injected.
+type b3Propagator struct {
+	single bool
+}
+
+func (p b3Propagator) extract(headers api.HeaderMap) (spanContext, bool) {
+	if value, ok := getHeader(headers, b3Header); ok {
+		if sc, ok := parseB3Single(value); ok {
+			return sc, true
+		}
+	}
+	traceID, ok := getHeader(headers, b3TraceIDHeader)
+	if !ok {
+		return spanContext{}, false
+	}
+	spanID, ok := getHeader(headers, b3SpanIDHeader)
+	if !ok {
+		return spanContext{}, false
+	}
+	sc := spanContext{}
+	if !decodeB3TraceID(sc.traceID[:], traceID) || !decodeHex(sc.spanID[:], spanID) {
+		return spanContext{}, false
+	}
+	sc.deferred = true
+	if flags, ok := getHeader(headers, b3FlagsHeader); ok && flags == "1" {
+		// debug implies sampled
+		sc.sampled, sc.deferred = true, false
+	} else if sampled, ok := getHeader(headers, b3SampledHeader); ok {
+		switch strings.ToLower(sampled) {
+		case "1", "true":
+			sc.sampled, sc.deferred = true, false
+		case "0", "false":
+			sc.sampled, sc.deferred = false, false
+		}
+	}
+	return sc, sc.valid()
+}
+
+func (p b3Propagator) inject(sc spanContext, headers api.HeaderMap) {
+	sampled := "0"
+	if sc.sampled {
+		sampled = "1"
+	}
+	traceID := hex.EncodeToString(sc.traceID[:])
+	spanID := hex.EncodeToString(sc.spanID[:])
+	if p.single {
+		headers.Set(b3Header, traceID+"-"+spanID+"-"+sampled)
+		return
+	}
+	headers.Set(b3TraceIDHeader, traceID)
+	headers.Set(b3SpanIDHeader, spanID)
+	headers.Set(b3SampledHeader, sampled)
+}
+
+// parseB3Single parses the b3 header in the format of traceid-spanid-sampled-parentspanid,
+// the sampled and the parent span id are optional
+func parseB3Single(value string) (spanContext, bool) {
+	sc := spanContext{}
+	parts := strings.Split(value, "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		// a single sampling state without the ids can not be a parent
+		return sc, false
+	}
+	if !decodeB3TraceID(sc.traceID[:], parts[0]) || !decodeHex(sc.spanID[:], parts[1]) {
+		return sc, false
+	}
+	sc.deferred = true
+	if len(parts) > 2 {
+		switch parts[2] {
+		case "1", "d":
+			sc.sampled, sc.deferred = true, false
+		case "0":
+			sc.sampled, sc.deferred = false, false
+		default:
+			return spanContext{}, false
+		}
+	}
+	return sc, sc.valid()
+}
+
+// jaegerPropagator implements the jaeger propagation, the header is in the format of
+// traceid:spanid:parentspanid:flags, see https://www.jaegertracing.io/docs/client-libraries/#propagation-format
+type jaegerPropagator struct{}
+
+func (jaegerPropagator) extract(headers api.HeaderMap) (spanContext, bool) {
+	value, ok := getHeader(headers, jaegerHeader)
+	if !ok {
+		return spanContext{}, false
+	}
+	// the value may be url encoded
+	if strings.Contains(value, "%") {
+		if unescaped, err := url.QueryUnescape(value); err == nil {
+			value = unescaped
+		}
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 4 || len(parts[0]) > 32 || len(parts[1]) > 16 {
+		return spanContext{}, false
+	}
+	sc := spanContext{}
+	// the leading zeros of the ids may be omitted
+	traceID := strings.Repeat("0", 32-len(parts[0])) + parts[0]
+	spanID := strings.Repeat("0", 16-len(parts[1])) + parts[1]
+	if !decodeHex(sc.traceID[:], traceID) || !decodeHex(sc.spanID[:], spanID) {
+		return spanContext{}, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return spanContext{}, false
+	}
+	// the debug flag implies sampled
+	sc.sampled = flags&0x03 != 0
+	return sc, sc.valid()
+}
+
+func (jaegerPropagator) inject(sc spanContext, headers api.HeaderMap) {
+	flags := "0"
+	if sc.sampled {
+		flags = "1"
+	}
+	// the parent span id is deprecated, it is always 0
+	headers.Set(jaegerHeader, hex.EncodeToString(sc.traceID[:])+":"+hex.EncodeToString(sc.spanID[:])+":0:"+flags)
+}
+
+// decodeB3TraceID decodes the 64 or 128 bits trace id, the 64 bits one is left padded with zeros
+func decodeB3TraceID(dst []byte, value string) bool {
+	if len(value) == 16 {
+		return decodeHex(dst[8:], value)
+	}
+	return decodeHex(dst, value)
+}
+
+// decodeHex decodes the lower case hex string which is exactly the length of the dst
+func decodeHex(dst []byte, value string) bool {
+	if len(value) != hex.EncodedLen(len(dst)) {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	_, err := hex.Decode(dst, []byte(value))
+	return err == nil
+}
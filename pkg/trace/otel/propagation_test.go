@@ -0,0 +1,236 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otel
+
+import (
+	"context"
+	"encoding/hex"
+	nethttp "net/http"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/http"
+	mhttp2 "mosn.io/mosn/pkg/protocol/http2"
+	"mosn.io/mosn/pkg/protocol/xprotocol"
+	"mosn.io/mosn/pkg/protocol/xprotocol/bolt"
+	"mosn.io/pkg/buffer"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	cases := []struct {
+		value   string
+		ok      bool
+		sampled bool
+	}{
+		{"00-" + testTraceID + "-" + testSpanID + "-01", true, true},
+		{"00-" + testTraceID + "-" + testSpanID + "-00", true, false},
+		// future version with extra fields
+		{"01-" + testTraceID + "-" + testSpanID + "-01-extra", true, true},
+		{"00-" + testTraceID + "-" + testSpanID + "-01-extra", false, false},
+		{"ff-" + testTraceID + "-" + testSpanID + "-01", false, false},
+		{"00-00000000000000000000000000000000-" + testSpanID + "-01", false, false},
+		{"00-" + testTraceID + "-0000000000000000-01", false, false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-" + testSpanID + "-01", false, false},
+		{"00-" + testTraceID + "-" + testSpanID, false, false},
+		{"", false, false},
+	}
+	for i, c := range cases {
+		sc, ok := parseTraceparent(c.value)
+		if ok != c.ok {
+			t.Errorf("case %d: expected %v, got %v", i, c.ok, ok)
+			continue
+		}
+		if ok && (sc.sampled != c.sampled || hex.EncodeToString(sc.traceID[:]) != testTraceID || hex.EncodeToString(sc.spanID[:]) != testSpanID) {
+			t.Errorf("case %d: unexpected span context %+v", i, sc)
+		}
+	}
+}
+
+func TestB3Extract(t *testing.T) {
+	cases := []struct {
+		headers  map[string]string
+		ok       bool
+		traceID  string
+		sampled  bool
+		deferred bool
+	}{
+		{
+			headers: map[string]string{b3TraceIDHeader: testTraceID, b3SpanIDHeader: testSpanID, b3SampledHeader: "1"},
+			ok:      true, traceID: testTraceID, sampled: true,
+		},
+		{
+			headers: map[string]string{b3TraceIDHeader: "a3ce929d0e0e4736", b3SpanIDHeader: testSpanID, b3SampledHeader: "0"},
+			ok:      true, traceID: "0000000000000000a3ce929d0e0e4736",
+		},
+		{
+			headers: map[string]string{b3TraceIDHeader: testTraceID, b3SpanIDHeader: testSpanID},
+			ok:      true, traceID: testTraceID, deferred: true,
+		},
+		{
+			headers: map[string]string{b3TraceIDHeader: testTraceID, b3SpanIDHeader: testSpanID, b3FlagsHeader: "1"},
+			ok:      true, traceID: testTraceID, sampled: true,
+		},
+		{
+			headers: map[string]string{b3Header: testTraceID + "-" + testSpanID + "-1-05e3ac9a4f6e3b90"},
+			ok:      true, traceID: testTraceID, sampled: true,
+		},
+		{
+			headers: map[string]string{b3Header: testTraceID + "-" + testSpanID},
+			ok:      true, traceID: testTraceID, deferred: true,
+		},
+		{
+			headers: map[string]string{b3Header: "1"},
+		},
+		{
+			headers: map[string]string{b3TraceIDHeader: testTraceID},
+		},
+	}
+	for i, c := range cases {
+		sc, ok := b3Propagator{}.extract(protocol.CommonHeader(c.headers))
+		if ok != c.ok {
+			t.Errorf("case %d: expected %v, got %v", i, c.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if hex.EncodeToString(sc.traceID[:]) != c.traceID || hex.EncodeToString(sc.spanID[:]) != testSpanID ||
+			sc.sampled != c.sampled || sc.deferred != c.deferred {
+			t.Errorf("case %d: unexpected span context %+v", i, sc)
+		}
+	}
+}
+
+func TestJaegerExtract(t *testing.T) {
+	cases := []struct {
+		value   string
+		ok      bool
+		traceID string
+		spanID  string
+		sampled bool
+	}{
+		{testTraceID + ":" + testSpanID + ":0:1", true, testTraceID, testSpanID, true},
+		{"a3ce929d0e0e4736:f067aa0ba902b7:0:0", true, "0000000000000000a3ce929d0e0e4736", testSpanID, false},
+		// debug flag
+		{testTraceID + "%3A" + testSpanID + "%3A0%3A2", true, testTraceID, testSpanID, true},
+		{testTraceID + ":" + testSpanID + ":0", false, "", "", false},
+		{testTraceID + ":" + testSpanID + ":0:x", false, "", "", false},
+		{"0:" + testSpanID + ":0:1", false, "", "", false},
+	}
+	for i, c := range cases {
+		sc, ok := jaegerPropagator{}.extract(protocol.CommonHeader{jaegerHeader: c.value})
+		if ok != c.ok {
+			t.Errorf("case %d: expected %v, got %v", i, c.ok, ok)
+			continue
+		}
+		if ok && (hex.EncodeToString(sc.traceID[:]) != c.traceID || hex.EncodeToString(sc.spanID[:]) != c.spanID || sc.sampled != c.sampled) {
+			t.Errorf("case %d: unexpected span context %+v", i, sc)
+		}
+	}
+}
+
+func newHTTP1Header() api.HeaderMap {
+	return http.RequestHeader{RequestHeader: &fasthttp.RequestHeader{}}
+}
+
+func newHTTP2Header() api.HeaderMap {
+	return mhttp2.NewReqHeader(&nethttp.Request{Header: nethttp.Header{}})
+}
+
+func newBoltHeader() api.HeaderMap {
+	return bolt.NewRpcRequest(1, protocol.CommonHeader{}, nil)
+}
+
+// TestPropagationRoundTrip injects the span context into the header maps of each protocol and extracts it back
+func TestPropagationRoundTrip(t *testing.T) {
+	sc := spanContext{
+		traceState: "congo=t61rcWkgMzE",
+		sampled:    true,
+	}
+	hex.Decode(sc.traceID[:], []byte(testTraceID))
+	hex.Decode(sc.spanID[:], []byte(testSpanID))
+	headerMaps := map[string]func() api.HeaderMap{
+		"common": func() api.HeaderMap { return protocol.CommonHeader{} },
+		"http1":  newHTTP1Header,
+		"http2":  newHTTP2Header,
+		"bolt":   newBoltHeader,
+	}
+	for name, newHeader := range headerMaps {
+		for pname, p := range propagators {
+			headers := newHeader()
+			p.inject(sc, headers)
+			got, ok := p.extract(headers)
+			if !ok {
+				t.Errorf("%s %s: extract failed", name, pname)
+				continue
+			}
+			if got.traceID != sc.traceID || got.spanID != sc.spanID || got.sampled != sc.sampled || got.deferred {
+				t.Errorf("%s %s: unexpected span context %+v", name, pname, got)
+			}
+			if pname == PropagatorTraceContext && got.traceState != sc.traceState {
+				t.Errorf("%s %s: unexpected trace state %s", name, pname, got.traceState)
+			}
+		}
+	}
+}
+
+// TestBoltHeaderMutation verifies the injected headers are encoded even if the request is decoded from the raw data
+func TestBoltHeaderMutation(t *testing.T) {
+	request := bolt.NewRpcRequest(1, protocol.CommonHeader{"service": "test"}, buffer.NewIoBufferString("content"))
+	ctx := context.Background()
+	codec := xprotocol.GetProtocol(bolt.ProtocolName)
+	data, err := codec.Encode(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := codec.Decode(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := decoded.(*bolt.Request)
+	sc := spanContext{sampled: true}
+	hex.Decode(sc.traceID[:], []byte(testTraceID))
+	hex.Decode(sc.spanID[:], []byte(testSpanID))
+	traceContextPropagator{}.inject(sc, req)
+
+	data, err = codec.Encode(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err = codec.Decode(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = decoded.(*bolt.Request)
+	if v, _ := req.Get(traceparentHeader); v != formatTraceparent(sc) {
+		t.Fatalf("unexpected traceparent: %s", v)
+	}
+	if v, _ := req.Get("service"); v != "test" {
+		t.Fatalf("unexpected service: %s", v)
+	}
+	if string(req.Content.Bytes()) != "content" {
+		t.Fatalf("unexpected content: %s", req.Content.Bytes())
+	}
+}
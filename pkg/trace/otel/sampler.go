@@ -0,0 +1,89 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otel
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// sampler decides whether a new span is sampled
+type sampler interface {
+	shouldSample(parent spanContext, hasParent bool, traceID [16]byte) bool
+}
+
+func newSampler(cfg SamplerConfig) (sampler, error) {
+	switch cfg.Type {
+	case SamplerAlwaysOn:
+		return alwaysOn{}, nil
+	case SamplerAlwaysOff:
+		return alwaysOff{}, nil
+	case SamplerTraceIDRatio:
+		return newRatioSampler(cfg.Ratio), nil
+	case SamplerParentBasedAlwaysOn:
+		return parentBased{root: alwaysOn{}}, nil
+	case SamplerParentBasedAlwaysOff:
+		return parentBased{root: alwaysOff{}}, nil
+	case SamplerParentBasedTraceIDRatio:
+		return parentBased{root: newRatioSampler(cfg.Ratio)}, nil
+	default:
+		return nil, fmt.Errorf("unknown sampler type: %s", cfg.Type)
+	}
+}
+
+type alwaysOn struct{}
+
+func (alwaysOn) shouldSample(spanContext, bool, [16]byte) bool {
+	return true
+}
+
+type alwaysOff struct{}
+
+func (alwaysOff) shouldSample(spanContext, bool, [16]byte) bool {
+	return false
+}
+
+// ratioSampler samples the given ratio of the traces, the decision depends on the trace id only,
+// so all the processes with the same ratio make the same decision for a trace
+type ratioSampler struct {
+	bound uint64
+}
+
+func newRatioSampler(ratio float64) ratioSampler {
+	if ratio >= 1 {
+		return ratioSampler{bound: 1 << 63}
+	}
+	return ratioSampler{bound: uint64(ratio * (1 << 63))}
+}
+
+func (s ratioSampler) shouldSample(_ spanContext, _ bool, traceID [16]byte) bool {
+	return binary.BigEndian.Uint64(traceID[8:16])>>1 < s.bound
+}
+
+// parentBased follows the decision of the parent, the root sampler is used if there is no parent
+// or the parent defers the decision
+type parentBased struct {
+	root sampler
+}
+
+func (s parentBased) shouldSample(parent spanContext, hasParent bool, traceID [16]byte) bool {
+	if hasParent && !parent.deferred {
+		return parent.sampled
+	}
+	return s.root.shouldSample(parent, hasParent, traceID)
+}
@@ -0,0 +1,283 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otel
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/types"
+)
+
+// span tag keys, the tags are exported as the span attributes
+const (
+	HTTPMethod = iota
+	HTTPTarget
+	HTTPHost
+	HTTPUserAgent
+	HTTPStatusCode
+	RPCSystem
+	RPCService
+	RPCMethod
+	RPCStatusCode
+	PeerAddress
+	UpstreamAddress
+	RequestSize
+	ResponseSize
+	ResponseFlags
+	ProcessTime
+
+	TagEnd
+)
+
+// tagNames are the attribute names of the tags, which follow the opentelemetry semantic conventions if possible
+var tagNames = [TagEnd]string{
+	HTTPMethod:      "http.method",
+	HTTPTarget:      "http.target",
+	HTTPHost:        "http.host",
+	HTTPUserAgent:   "http.user_agent",
+	HTTPStatusCode:  "http.status_code",
+	RPCSystem:       "rpc.system",
+	RPCService:      "rpc.service",
+	RPCMethod:       "rpc.method",
+	RPCStatusCode:   "mosn.rpc.status_code",
+	PeerAddress:     "net.peer.address",
+	UpstreamAddress: "mosn.upstream.address",
+	RequestSize:     "mosn.request.size",
+	ResponseSize:    "mosn.response.size",
+	ResponseFlags:   "mosn.response_flags",
+	ProcessTime:     "mosn.process_time_ns",
+}
+
+// intTags are exported as the int attributes
+var intTags = [TagEnd]bool{
+	HTTPStatusCode: true,
+	RPCStatusCode:  true,
+	RequestSize:    true,
+	ResponseSize:   true,
+	ProcessTime:    true,
+}
+
+// TagName returns the attribute name of the tag
+func TagName(key uint64) string {
+	if key >= TagEnd {
+		return ""
+	}
+	return tagNames[key]
+}
+
+// IsIntTag reports whether the tag value is an integer
+func IsIntTag(key uint64) bool {
+	return key < TagEnd && intTags[key]
+}
+
+// span kinds, the values are the same as the otlp SpanKind
+const (
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+)
+
+// status codes, the values are the same as the otlp StatusCode
+const (
+	StatusUnset = 0
+	StatusError = 2
+)
+
+// responseFlagNames are the short names of the response flags, which are the same as envoy's
+var responseFlagNames = []struct {
+	flag api.ResponseFlag
+	name string
+}{
+	{api.NoHealthyUpstream, "UH"},
+	{api.UpstreamRequestTimeout, "UT"},
+	{api.UpstreamLocalReset, "LR"},
+	{api.UpstreamRemoteReset, "UR"},
+	{api.UpstreamConnectionFailure, "UF"},
+	{api.UpstreamConnectionTermination, "UC"},
+	{api.UpstreamOverflow, "UO"},
+	{api.NoRouteFound, "NR"},
+	{api.DelayInjected, "DI"},
+	{api.FaultInjected, "FI"},
+	{api.RateLimited, "RL"},
+	{api.ReqEntityTooLarge, "RE"},
+}
+
+// ResponseFlagsString returns the comma separated short names of the response flags that are set
+func ResponseFlagsString(info api.RequestInfo) string {
+	var names []string
+	for _, f := range responseFlagNames {
+		if info.GetResponseFlag(f.flag) {
+			names = append(names, f.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Span is the span of the opentelemetry tracer
+type Span struct {
+	tracer       *Tracer
+	ctx          spanContext
+	parentSpanID [8]byte
+	kind         int
+	name         string
+	startTime    time.Time
+	endTime      time.Time
+	tags         [TagEnd]string
+	status       int
+	statusMsg    string
+	finished     int32
+}
+
+func (s *Span) TraceId() string {
+	return hex.EncodeToString(s.ctx.traceID[:])
+}
+
+func (s *Span) SpanId() string {
+	return hex.EncodeToString(s.ctx.spanID[:])
+}
+
+func (s *Span) ParentSpanId() string {
+	if s.parentSpanID == [8]byte{} {
+		return ""
+	}
+	return hex.EncodeToString(s.parentSpanID[:])
+}
+
+// Sampled reports whether the span is exported
+func (s *Span) Sampled() bool {
+	return s.ctx.sampled
+}
+
+func (s *Span) SetOperation(operation string) {
+	s.name = operation
+}
+
+// SetTag sets the tag, the unknown keys are ignored
+func (s *Span) SetTag(key uint64, value string) {
+	if key < TagEnd {
+		s.tags[key] = value
+	}
+}
+
+func (s *Span) Tag(key uint64) string {
+	if key < TagEnd {
+		return s.tags[key]
+	}
+	return ""
+}
+
+func (s *Span) SetRequestInfo(info api.RequestInfo) {
+	s.tags[RequestSize] = strconv.FormatUint(info.BytesReceived(), 10)
+	s.tags[ResponseSize] = strconv.FormatUint(info.BytesSent(), 10)
+	s.tags[ProcessTime] = strconv.FormatInt(info.ProcessTimeDuration().Nanoseconds(), 10)
+	if info.UpstreamHost() != nil {
+		s.tags[UpstreamAddress] = info.UpstreamHost().AddressString()
+	}
+	if info.DownstreamRemoteAddress() != nil {
+		s.tags[PeerAddress] = info.DownstreamRemoteAddress().String()
+	}
+	code := info.ResponseCode()
+	if s.tags[RPCSystem] != "" {
+		s.tags[RPCStatusCode] = strconv.Itoa(code)
+	} else {
+		s.tags[HTTPStatusCode] = strconv.Itoa(code)
+	}
+	flags := ResponseFlagsString(info)
+	s.tags[ResponseFlags] = flags
+	// the 4xx is not an error of the server span
+	if code >= 500 || flags != "" {
+		s.status = StatusError
+		s.statusMsg = flags
+	}
+}
+
+// FinishSpan ends the span and exports it if it is sampled, the span can only be finished once
+func (s *Span) FinishSpan() {
+	if !atomic.CompareAndSwapInt32(&s.finished, 0, 1) {
+		return
+	}
+	s.endTime = time.Now()
+	if s.ctx.sampled && s.tracer != nil {
+		s.tracer.processor.onEnd(s)
+	}
+}
+
+// InjectContext injects the span context into the upstream request headers,
+// so the upstream spans become the children of this span
+func (s *Span) InjectContext(requestHeaders api.HeaderMap) {
+	if s.tracer == nil || requestHeaders == nil {
+		return
+	}
+	for _, p := range s.tracer.propagators {
+		p.inject(s.ctx, requestHeaders)
+	}
+}
+
+func (s *Span) SpawnChild(operationName string, startTime time.Time) types.Span {
+	child := &Span{
+		tracer:       s.tracer,
+		ctx:          s.ctx,
+		parentSpanID: s.ctx.spanID,
+		kind:         SpanKindInternal,
+		name:         operationName,
+		startTime:    startTime,
+	}
+	child.ctx.spanID = newSpanID()
+	return child
+}
+
+// StartTime returns the start time of the span
+func (s *Span) StartTime() time.Time {
+	return s.startTime
+}
+
+// EndTime returns the end time of the span, it is zero before the span is finished
+func (s *Span) EndTime() time.Time {
+	return s.endTime
+}
+
+// Name returns the operation name of the span
+func (s *Span) Name() string {
+	return s.name
+}
+
+// Kind returns the span kind, which is SpanKindServer or SpanKindInternal
+func (s *Span) Kind() int {
+	return s.kind
+}
+
+// Status returns the status code and the status message of the span
+func (s *Span) Status() (int, string) {
+	return s.status, s.statusMsg
+}
+
+// RangeTags calls f for each tag that is set, the iteration stops if f returns false
+func (s *Span) RangeTags(f func(key uint64, value string) bool) {
+	for key, value := range s.tags {
+		if value == "" {
+			continue
+		}
+		if !f(uint64(key), value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,283 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otel
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"math/rand"
+	"sync"
+	"time"
+
+	"mosn.io/api"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/http"
+	mhttp2 "mosn.io/mosn/pkg/protocol/http2"
+	"mosn.io/mosn/pkg/protocol/xprotocol"
+	"mosn.io/mosn/pkg/server/keeper"
+	"mosn.io/mosn/pkg/trace"
+	"mosn.io/mosn/pkg/types"
+)
+
+func init() {
+	trace.RegisterDriver(DriverName, trace.NewDefaultDriverImpl())
+	trace.RegisterTracerBuilder(DriverName, protocol.HTTP1, NewTracer)
+	trace.RegisterTracerBuilder(DriverName, protocol.HTTP2, NewTracer)
+	trace.RegisterTracerBuilder(DriverName, protocol.Xprotocol, NewTracer)
+}
+
+// rpc header keys of the service and the method, bolt uses the sofa keys and dubbo uses the plain ones
+var (
+	rpcServiceKeys = []string{"service", "sofa_head_target_service"}
+	rpcMethodKeys  = []string{"sofa_head_method_name", "method"}
+)
+
+// the tracers of all the protocols of a driver share the exporter, it is rebuilt only if the config changes
+var (
+	sharedMutex   sync.Mutex
+	sharedTracers = map[string]*sharedTracer{}
+	shutdownOnce  sync.Once
+)
+
+type sharedTracer struct {
+	configKey string
+	tracer    *Tracer
+}
+
+// Tracer starts the spans of a protocol
+type Tracer struct {
+	config      *Config
+	sampler     sampler
+	propagators []propagator
+	processor   *batchProcessor
+}
+
+// NewTracer is the tracer builder of all the protocols
+var NewTracer = NewTracerBuilder(DriverName, nil)
+
+// NewTracerBuilder returns the tracer builder of a driver based on the opentelemetry tracer,
+// the defaults sets the driver specific default values before the config is parsed, it can be nil.
+func NewTracerBuilder(driver string, defaults func(cfg *Config)) types.TracerBuilder {
+	return func(config map[string]interface{}) (types.Tracer, error) {
+		data, err := json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		sharedMutex.Lock()
+		defer sharedMutex.Unlock()
+		shared := sharedTracers[driver]
+		if shared != nil && shared.configKey == string(data) {
+			return shared.tracer, nil
+		}
+		tracer, err := newTracer(driver, config, defaults)
+		if err != nil {
+			return nil, err
+		}
+		if shared != nil {
+			shared.tracer.processor.shutdown()
+		}
+		sharedTracers[driver] = &sharedTracer{
+			configKey: string(data),
+			tracer:    tracer,
+		}
+		shutdownOnce.Do(func() {
+			keeper.OnProcessShutDown(shutdownSharedTracers)
+		})
+		return tracer, nil
+	}
+}
+
+// shutdownSharedTracers flushes the spans of the current tracers when the process shuts down,
+// the replaced tracers are already shut down when they are replaced
+func shutdownSharedTracers() error {
+	sharedMutex.Lock()
+	defer sharedMutex.Unlock()
+	for _, shared := range sharedTracers {
+		shared.tracer.processor.shutdown()
+	}
+	return nil
+}
+
+func newTracer(driver string, config map[string]interface{}, defaults func(cfg *Config)) (*Tracer, error) {
+	cfg, err := parseConfig(config, defaults)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSampler(cfg.Sampler)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := newExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tracer := &Tracer{
+		config:    cfg,
+		sampler:   s,
+		processor: newBatchProcessor(exp, &cfg.Exporter, metrics.NewTraceStats(driver)),
+	}
+	for _, name := range cfg.Propagators {
+		tracer.propagators = append(tracer.propagators, propagators[name])
+	}
+	return tracer, nil
+}
+
+// Start starts a server span of the request, the parent span context is extracted from the request headers
+func (t *Tracer) Start(ctx context.Context, request interface{}, startTime time.Time) types.Span {
+	span := &Span{
+		tracer:    t,
+		kind:      SpanKindServer,
+		startTime: startTime,
+	}
+	var headers api.HeaderMap
+	switch req := request.(type) {
+	case http.RequestHeader:
+		if req.RequestHeader == nil {
+			break
+		}
+		headers = req
+		span.tags[HTTPMethod] = string(req.Method())
+		span.tags[HTTPTarget] = string(req.RequestURI())
+		span.tags[HTTPHost] = string(req.Host())
+		span.tags[HTTPUserAgent] = string(req.UserAgent())
+		span.name = "HTTP " + span.tags[HTTPMethod]
+	case *mhttp2.ReqHeader:
+		if req.Req == nil {
+			break
+		}
+		headers = req
+		span.tags[HTTPMethod] = req.Req.Method
+		span.tags[HTTPTarget] = req.Req.RequestURI
+		span.tags[HTTPHost] = req.Req.Host
+		span.tags[HTTPUserAgent] = req.Req.UserAgent()
+		span.name = "HTTP " + span.tags[HTTPMethod]
+	case xprotocol.XFrame:
+		if req.IsHeartbeatFrame() {
+			break
+		}
+		headers = req.GetHeader()
+		t.setRPCTags(ctx, span, req)
+	case api.HeaderMap:
+		headers = req
+	}
+
+	parent, hasParent := spanContext{}, false
+	if headers != nil {
+		parent, hasParent = t.extract(headers)
+	}
+	if hasParent {
+		span.ctx.traceID = parent.traceID
+		span.ctx.traceState = parent.traceState
+		span.parentSpanID = parent.spanID
+	} else {
+		span.ctx.traceID = newTraceID()
+	}
+	span.ctx.spanID = newSpanID()
+	// heartbeat and unknown requests are never exported
+	span.ctx.sampled = headers != nil && t.sampler.shouldSample(parent, hasParent, span.ctx.traceID)
+	if span.name == "" {
+		span.name = "mosn"
+	}
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(ctx, "[trace] [otel] start span, trace id: %s, span id: %s, parent span id: %s, sampled: %v",
+			span.TraceId(), span.SpanId(), span.ParentSpanId(), span.ctx.sampled)
+	}
+	return span
+}
+
+func (t *Tracer) setRPCTags(ctx context.Context, span *Span, frame xprotocol.XFrame) {
+	if sub, ok := mosnctx.Get(ctx, types.ContextSubProtocol).(string); ok {
+		span.tags[RPCSystem] = sub
+	}
+	headers := frame.GetHeader()
+	if aware, ok := frame.(xprotocol.ServiceAware); ok {
+		span.tags[RPCService] = aware.GetServiceName()
+		span.tags[RPCMethod] = aware.GetMethodName()
+	} else {
+		span.tags[RPCService] = firstHeader(headers, rpcServiceKeys)
+		span.tags[RPCMethod] = firstHeader(headers, rpcMethodKeys)
+	}
+	span.name = span.tags[RPCService] + "/" + span.tags[RPCMethod]
+}
+
+// extract returns the first parent span context found by the propagators
+func (t *Tracer) extract(headers api.HeaderMap) (spanContext, bool) {
+	for _, p := range t.propagators {
+		if sc, ok := p.extract(headers); ok {
+			return sc, true
+		}
+	}
+	return spanContext{}, false
+}
+
+// Flush exports the finished spans immediately, it is used in the tests mostly
+func (t *Tracer) Flush() {
+	t.processor.flush()
+}
+
+func firstHeader(headers api.HeaderMap, keys []string) string {
+	for _, key := range keys {
+		if value, ok := getHeader(headers, key); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// the random source of the ids, it is seeded by crypto/rand so that different processes generate different ids
+var idSource = newIDSource()
+
+type lockedSource struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newIDSource() *lockedSource {
+	var seed [8]byte
+	if _, err := crand.Read(seed[:]); err != nil {
+		binary.BigEndian.PutUint64(seed[:], uint64(time.Now().UnixNano()))
+	}
+	return &lockedSource{
+		r: rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(seed[:])))),
+	}
+}
+
+func (s *lockedSource) read(b []byte) {
+	s.mu.Lock()
+	s.r.Read(b)
+	s.mu.Unlock()
+}
+
+func newTraceID() (id [16]byte) {
+	for id == [16]byte{} {
+		idSource.read(id[:])
+	}
+	return
+}
+
+func newSpanID() (id [8]byte) {
+	for id == [8]byte{} {
+		idSource.read(id[:])
+	}
+	return
+}
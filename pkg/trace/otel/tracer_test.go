@@ -0,0 +1,542 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otel
+
+import (
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"mosn.io/api"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/xprotocol/bolt"
+	"mosn.io/mosn/pkg/types"
+)
+
+// the otlp messages used by the fake collector to decode the requests, only the tested fields are defined
+
+type otlpRequest struct {
+	ResourceSpans []*otlpResourceSpans `protobuf:"bytes,1,rep,name=resource_spans,proto3"`
+}
+
+func (m *otlpRequest) Reset()         { *m = otlpRequest{} }
+func (m *otlpRequest) String() string { return proto.CompactTextString(m) }
+func (*otlpRequest) ProtoMessage()    {}
+
+type otlpResourceSpans struct {
+	Resource   *otlpResource     `protobuf:"bytes,1,opt,name=resource,proto3"`
+	ScopeSpans []*otlpScopeSpans `protobuf:"bytes,2,rep,name=scope_spans,proto3"`
+}
+
+type otlpResource struct {
+	Attributes []*otlpKeyValue `protobuf:"bytes,1,rep,name=attributes,proto3"`
+}
+
+type otlpScopeSpans struct {
+	Scope *otlpScope  `protobuf:"bytes,1,opt,name=scope,proto3"`
+	Spans []*otlpSpan `protobuf:"bytes,2,rep,name=spans,proto3"`
+}
+
+type otlpScope struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3"`
+}
+
+type otlpSpan struct {
+	TraceId      []byte          `protobuf:"bytes,1,opt,name=trace_id,proto3"`
+	SpanId       []byte          `protobuf:"bytes,2,opt,name=span_id,proto3"`
+	TraceState   string          `protobuf:"bytes,3,opt,name=trace_state,proto3"`
+	ParentSpanId []byte          `protobuf:"bytes,4,opt,name=parent_span_id,proto3"`
+	Name         string          `protobuf:"bytes,5,opt,name=name,proto3"`
+	Kind         int32           `protobuf:"varint,6,opt,name=kind,proto3"`
+	StartTime    uint64          `protobuf:"fixed64,7,opt,name=start_time_unix_nano,proto3"`
+	EndTime      uint64          `protobuf:"fixed64,8,opt,name=end_time_unix_nano,proto3"`
+	Attributes   []*otlpKeyValue `protobuf:"bytes,9,rep,name=attributes,proto3"`
+	Status       *otlpStatus     `protobuf:"bytes,15,opt,name=status,proto3"`
+}
+
+type otlpStatus struct {
+	Message string `protobuf:"bytes,2,opt,name=message,proto3"`
+	Code    int32  `protobuf:"varint,3,opt,name=code,proto3"`
+}
+
+type otlpKeyValue struct {
+	Key   string        `protobuf:"bytes,1,opt,name=key,proto3"`
+	Value *otlpAnyValue `protobuf:"bytes,2,opt,name=value,proto3"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `protobuf:"bytes,1,opt,name=string_value,proto3"`
+	IntValue    int64  `protobuf:"varint,3,opt,name=int_value,proto3"`
+}
+
+func attribute(kvs []*otlpKeyValue, key string) *otlpAnyValue {
+	for _, kv := range kvs {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return nil
+}
+
+// fakeCollector receives the spans by both otlp/grpc and otlp/http
+type fakeCollector struct {
+	mu       sync.Mutex
+	spans    []*otlpSpan
+	resource *otlpResource
+	headers  map[string]string
+}
+
+func (c *fakeCollector) receive(req *otlpRequest, headers map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.headers = headers
+	for _, rs := range req.ResourceSpans {
+		c.resource = rs.Resource
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+}
+
+func (c *fakeCollector) received() []*otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spans
+}
+
+func (c *fakeCollector) export(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := &otlpRequest{}
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	headers := map[string]string{}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		headers[key] = strings.Join(values, ",")
+	}
+	c.receive(req, headers)
+	return &otlpRequest{}, nil
+}
+
+func (c *fakeCollector) serveGRPC(t *testing.T) (string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "opentelemetry.proto.collector.trace.v1.TraceService",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Export",
+			Handler:    c.export,
+		}},
+	}, c)
+	go server.Serve(ln)
+	return ln.Addr().String(), server.Stop
+}
+
+func (c *fakeCollector) ServeHTTP(w nethttp.ResponseWriter, r *nethttp.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	req := &otlpRequest{}
+	if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+		w.WriteHeader(nethttp.StatusNotFound)
+		return
+	}
+	if err := proto.Unmarshal(body, req); err != nil {
+		w.WriteHeader(nethttp.StatusBadRequest)
+		return
+	}
+	headers := map[string]string{}
+	for key := range r.Header {
+		headers[strings.ToLower(key)] = r.Header.Get(key)
+	}
+	c.receive(req, headers)
+}
+
+func newTestTracer(t *testing.T, exporter map[string]interface{}, sampler map[string]interface{}) *Tracer {
+	config := map[string]interface{}{
+		"service_name": "test-service",
+		"resource_attributes": map[string]interface{}{
+			"deployment.environment": "test",
+		},
+		"exporter": exporter,
+	}
+	if sampler != nil {
+		config["sampler"] = sampler
+	}
+	tracer, err := newTracer(DriverName, config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tracer
+}
+
+func TestExportGRPC(t *testing.T) {
+	collector := &fakeCollector{}
+	addr, stop := collector.serveGRPC(t)
+	defer stop()
+	tracer := newTestTracer(t, map[string]interface{}{
+		"endpoint": addr,
+		"headers":  map[string]interface{}{"x-token": "secret"},
+	}, nil)
+	defer tracer.processor.shutdown()
+
+	headers := newHTTP1Header().(interface {
+		api.HeaderMap
+		SetMethod(string)
+		SetRequestURI(string)
+		SetHost(string)
+	})
+	headers.SetMethod("POST")
+	headers.SetRequestURI("/api?x=1")
+	headers.SetHost("example.com")
+	headers.Set(traceparentHeader, "00-"+testTraceID+"-"+testSpanID+"-01")
+	headers.Set(tracestateHeader, "congo=t61rcWkgMzE")
+
+	start := time.Now()
+	span := tracer.Start(context.Background(), headers, start)
+	if span.TraceId() != testTraceID || span.ParentSpanId() != testSpanID || span.SpanId() == testSpanID {
+		t.Fatalf("unexpected span ids: %s %s %s", span.TraceId(), span.SpanId(), span.ParentSpanId())
+	}
+	info := network.NewRequestInfo()
+	info.SetResponseCode(504)
+	info.SetResponseFlag(api.UpstreamRequestTimeout)
+	info.SetBytesReceived(10)
+	info.SetDownstreamRemoteAddress(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 12345})
+	span.SetRequestInfo(info)
+	span.FinishSpan()
+	// finishing twice exports once
+	span.FinishSpan()
+	tracer.Flush()
+
+	spans := collector.received()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	got := spans[0]
+	if hex.EncodeToString(got.TraceId) != testTraceID || hex.EncodeToString(got.ParentSpanId) != testSpanID ||
+		hex.EncodeToString(got.SpanId) != span.SpanId() || got.TraceState != "congo=t61rcWkgMzE" {
+		t.Errorf("unexpected span ids: %+v", got)
+	}
+	if got.Name != "HTTP POST" || got.Kind != SpanKindServer || got.StartTime != uint64(start.UnixNano()) || got.EndTime < got.StartTime {
+		t.Errorf("unexpected span: %+v", got)
+	}
+	if v := attribute(got.Attributes, "http.method"); v == nil || v.StringValue != "POST" {
+		t.Errorf("unexpected http.method: %v", v)
+	}
+	if v := attribute(got.Attributes, "http.target"); v == nil || v.StringValue != "/api?x=1" {
+		t.Errorf("unexpected http.target: %v", v)
+	}
+	if v := attribute(got.Attributes, "http.status_code"); v == nil || v.IntValue != 504 {
+		t.Errorf("unexpected http.status_code: %v", v)
+	}
+	if v := attribute(got.Attributes, "mosn.request.size"); v == nil || v.IntValue != 10 {
+		t.Errorf("unexpected mosn.request.size: %v", v)
+	}
+	if v := attribute(got.Attributes, "net.peer.address"); v == nil || v.StringValue != "127.0.0.1:12345" {
+		t.Errorf("unexpected net.peer.address: %v", v)
+	}
+	if got.Status == nil || got.Status.Code != StatusError || got.Status.Message != "UT" {
+		t.Errorf("unexpected status: %+v", got.Status)
+	}
+	if v := attribute(collector.resource.Attributes, "service.name"); v == nil || v.StringValue != "test-service" {
+		t.Errorf("unexpected service.name: %v", v)
+	}
+	if v := attribute(collector.resource.Attributes, "deployment.environment"); v == nil || v.StringValue != "test" {
+		t.Errorf("unexpected deployment.environment: %v", v)
+	}
+	if collector.headers["x-token"] != "secret" {
+		t.Errorf("unexpected headers: %v", collector.headers)
+	}
+}
+
+func TestExportHTTP(t *testing.T) {
+	collector := &fakeCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+	tracer := newTestTracer(t, map[string]interface{}{
+		"protocol": ExporterHTTPProtobuf,
+		"endpoint": strings.TrimPrefix(server.URL, "http://"),
+	}, nil)
+	defer tracer.processor.shutdown()
+
+	frame := bolt.NewRpcRequest(1, protocol.CommonHeader{
+		"service":               "com.alipay.test.TestService:1.0",
+		"sofa_head_method_name": "sayHello",
+		b3TraceIDHeader:         testTraceID,
+		b3SpanIDHeader:          testSpanID,
+	}, nil)
+	ctx := mosnctx.WithValue(context.Background(), types.ContextSubProtocol, string(bolt.ProtocolName))
+	span := tracer.Start(ctx, frame, time.Now())
+	info := network.NewRequestInfo()
+	info.SetResponseCode(200)
+	span.SetRequestInfo(info)
+	span.FinishSpan()
+	tracer.Flush()
+
+	spans := collector.received()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	got := spans[0]
+	if hex.EncodeToString(got.TraceId) != testTraceID || hex.EncodeToString(got.ParentSpanId) != testSpanID {
+		t.Errorf("unexpected span ids: %+v", got)
+	}
+	if got.Name != "com.alipay.test.TestService:1.0/sayHello" || got.Status != nil {
+		t.Errorf("unexpected span: %+v", got)
+	}
+	if v := attribute(got.Attributes, "rpc.system"); v == nil || v.StringValue != "bolt" {
+		t.Errorf("unexpected rpc.system: %v", v)
+	}
+	if v := attribute(got.Attributes, "mosn.rpc.status_code"); v == nil || v.IntValue != 200 {
+		t.Errorf("unexpected mosn.rpc.status_code: %v", v)
+	}
+	if v := attribute(got.Attributes, "http.status_code"); v != nil {
+		t.Errorf("unexpected http.status_code: %v", v)
+	}
+}
+
+func TestExportHTTP2AndInject(t *testing.T) {
+	collector := &fakeCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+	tracer := newTestTracer(t, map[string]interface{}{
+		"protocol": ExporterHTTPProtobuf,
+		"endpoint": strings.TrimPrefix(server.URL, "http://"),
+	}, nil)
+	defer tracer.processor.shutdown()
+
+	headers := newHTTP2Header()
+	span := tracer.Start(context.Background(), headers, time.Now())
+	if span.ParentSpanId() != "" {
+		t.Fatalf("expected a root span, got parent %s", span.ParentSpanId())
+	}
+	upstream := protocol.CommonHeader{}
+	span.InjectContext(upstream)
+	if upstream[traceparentHeader] != "00-"+span.TraceId()+"-"+span.SpanId()+"-01" {
+		t.Errorf("unexpected traceparent: %s", upstream[traceparentHeader])
+	}
+	if upstream[b3TraceIDHeader] != span.TraceId() || upstream[b3SpanIDHeader] != span.SpanId() || upstream[b3SampledHeader] != "1" {
+		t.Errorf("unexpected b3 headers: %v", upstream)
+	}
+	child := span.SpawnChild("child", time.Now())
+	if child.TraceId() != span.TraceId() || child.ParentSpanId() != span.SpanId() {
+		t.Errorf("unexpected child span: %s %s", child.TraceId(), child.ParentSpanId())
+	}
+	child.FinishSpan()
+	span.FinishSpan()
+	tracer.Flush()
+	if spans := collector.received(); len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+}
+
+func TestSampling(t *testing.T) {
+	collector := &fakeCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+	tracer := newTestTracer(t, map[string]interface{}{
+		"protocol": ExporterHTTPProtobuf,
+		"endpoint": strings.TrimPrefix(server.URL, "http://"),
+	}, map[string]interface{}{
+		"type": SamplerParentBasedAlwaysOff,
+	})
+	defer tracer.processor.shutdown()
+
+	// the root span is not sampled
+	span := tracer.Start(context.Background(), protocol.CommonHeader{}, time.Now())
+	upstream := protocol.CommonHeader{}
+	span.InjectContext(upstream)
+	if !strings.HasSuffix(upstream[traceparentHeader], "-00") || upstream[b3SampledHeader] != "0" {
+		t.Errorf("unexpected headers: %v", upstream)
+	}
+	span.FinishSpan()
+
+	// the sampled parent is followed
+	span = tracer.Start(context.Background(), protocol.CommonHeader{
+		traceparentHeader: "00-" + testTraceID + "-" + testSpanID + "-01",
+	}, time.Now())
+	span.FinishSpan()
+
+	// the deferred parent uses the root sampler
+	span = tracer.Start(context.Background(), protocol.CommonHeader{
+		b3Header: testTraceID + "-" + testSpanID,
+	}, time.Now())
+	span.FinishSpan()
+	tracer.Flush()
+
+	spans := collector.received()
+	if len(spans) != 1 || hex.EncodeToString(spans[0].TraceId) != testTraceID {
+		t.Fatalf("expected the span with sampled parent only, got %d", len(spans))
+	}
+}
+
+func TestRatioSampler(t *testing.T) {
+	s := newRatioSampler(0.25)
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		if s.shouldSample(spanContext{}, false, newTraceID()) {
+			sampled++
+		}
+	}
+	if sampled < 2000 || sampled > 3000 {
+		t.Errorf("unexpected sampled count %d", sampled)
+	}
+	if newRatioSampler(0).shouldSample(spanContext{}, false, newTraceID()) {
+		t.Error("ratio 0 should not sample")
+	}
+	id := newTraceID()
+	if !newRatioSampler(1).shouldSample(spanContext{}, false, id) {
+		t.Error("ratio 1 should sample")
+	}
+	// the same trace id gets the same decision
+	if s.shouldSample(spanContext{}, false, id) != s.shouldSample(spanContext{}, false, id) {
+		t.Error("the decision should be consistent")
+	}
+}
+
+// blockingExporter blocks the export until it is released
+type blockingExporter struct {
+	release chan struct{}
+	mu      sync.Mutex
+	count   int
+}
+
+func (e *blockingExporter) Export(ctx context.Context, spans []*Span) error {
+	<-e.release
+	e.mu.Lock()
+	e.count += len(spans)
+	e.mu.Unlock()
+	return nil
+}
+
+func TestProcessorDropAndShutdown(t *testing.T) {
+	exp := &blockingExporter{release: make(chan struct{})}
+	cfg, err := ParseConfig(map[string]interface{}{
+		"exporter": map[string]interface{}{
+			"endpoint":              "127.0.0.1:4317",
+			"max_export_batch_size": 1,
+			"max_queue_size":        2,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := metrics.NewTraceStats("test")
+	droppedBefore := stats.Counter(metrics.TraceSpanDropped).Count()
+	exportedBefore := stats.Counter(metrics.TraceSpanExported).Count()
+	p := newBatchProcessor(exp, &cfg.Exporter, stats)
+	for i := 0; i < 10; i++ {
+		p.onEnd(&Span{})
+	}
+	// at most one span is being exported, and two spans are queued
+	if atomic.LoadUint64(&p.dropped) < 7 {
+		t.Errorf("expected at least 7 dropped spans, got %d", atomic.LoadUint64(&p.dropped))
+	}
+	close(exp.release)
+	p.shutdown()
+	if exp.count+int(atomic.LoadUint64(&p.dropped)) != 10 {
+		t.Errorf("expected all the queued spans exported on shutdown, exported %d, dropped %d", exp.count, atomic.LoadUint64(&p.dropped))
+	}
+	// the spans finished after shutdown are dropped
+	p.onEnd(&Span{})
+	if exp.count+int(atomic.LoadUint64(&p.dropped)) != 11 {
+		t.Errorf("expected the span dropped after shutdown")
+	}
+	if dropped := stats.Counter(metrics.TraceSpanDropped).Count() - droppedBefore; dropped != int64(atomic.LoadUint64(&p.dropped)) {
+		t.Errorf("unexpected dropped metrics %d", dropped)
+	}
+	if exported := stats.Counter(metrics.TraceSpanExported).Count() - exportedBefore; exported != int64(exp.count) {
+		t.Errorf("unexpected exported metrics %d", exported)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	invalid := []map[string]interface{}{
+		{},
+		{"exporter": map[string]interface{}{"endpoint": "127.0.0.1:4317", "protocol": "http/json"}},
+		{"exporter": map[string]interface{}{"endpoint": "127.0.0.1:4317"}, "propagators": []interface{}{"unknown"}},
+		{"exporter": map[string]interface{}{"endpoint": "127.0.0.1:4317"}, "sampler": map[string]interface{}{"ratio": 2}},
+	}
+	for i, config := range invalid {
+		if _, err := newTracer(DriverName, config, nil); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+	if _, err := newTracer(DriverName, map[string]interface{}{
+		"exporter": map[string]interface{}{"cluster": "otel_collector"},
+		"sampler":  map[string]interface{}{"type": "unknown"},
+	}, nil); err == nil {
+		t.Error("expected unknown sampler error")
+	}
+	cfg, err := ParseConfig(map[string]interface{}{
+		"exporter": map[string]interface{}{"cluster": "otel_collector", "schedule_delay": "1s"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServiceName != "mosn" || cfg.Exporter.Protocol != ExporterGRPC || cfg.Exporter.FlushInterval.Duration != time.Second ||
+		cfg.Exporter.BatchSize != defaultBatchSize || cfg.Sampler.Type != SamplerParentBasedAlwaysOn || len(cfg.Propagators) != 2 {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestNewTracerShared(t *testing.T) {
+	config := map[string]interface{}{
+		"exporter": map[string]interface{}{"cluster": "otel_collector"},
+	}
+	t1, err := NewTracer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, _ := NewTracer(config)
+	if t1 != t2 {
+		t.Error("the tracers of the same config should be shared")
+	}
+	config["service_name"] = "another"
+	t3, _ := NewTracer(config)
+	if t3 == t1 {
+		t.Error("a new tracer is expected for the new config")
+	}
+	// the replaced processor is shut down
+	if atomic.LoadInt32(&t1.(*Tracer).processor.stopped) != 1 {
+		t.Error("the replaced processor should be shut down")
+	}
+	shutdownSharedTracers()
+	if atomic.LoadInt32(&t3.(*Tracer).processor.stopped) != 1 {
+		t.Error("the current processor should be shut down on process shutdown")
+	}
+	sharedMutex.Lock()
+	delete(sharedTracers, DriverName)
+	sharedMutex.Unlock()
+}
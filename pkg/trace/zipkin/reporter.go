@@ -0,0 +1,146 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zipkin
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mosn.io/mosn/pkg/trace/otel"
+)
+
+const (
+	defaultPath = "/api/v2/spans"
+	contentType = "application/json"
+)
+
+// span is the zipkin v2 span, see https://zipkin.io/zipkin-api/#/default/post_spans
+type span struct {
+	TraceID        string            `json:"traceId"`
+	ID             string            `json:"id"`
+	ParentID       string            `json:"parentId,omitempty"`
+	Name           string            `json:"name,omitempty"`
+	Kind           string            `json:"kind,omitempty"`
+	Timestamp      int64             `json:"timestamp"`
+	Duration       int64             `json:"duration"`
+	LocalEndpoint  *endpoint         `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *endpoint         `json:"remoteEndpoint,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+type endpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Port        int    `json:"port,omitempty"`
+}
+
+// reporter posts the spans to the zipkin collector in the v2 json format
+type reporter struct {
+	config *otel.Config
+	client *http.Client
+}
+
+func newReporter(cfg *otel.Config) (otel.Exporter, error) {
+	return &reporter{
+		config: cfg,
+		client: &http.Client{},
+	}, nil
+}
+
+func (r *reporter) Export(ctx context.Context, spans []*otel.Span) error {
+	body, err := json.Marshal(r.convert(spans))
+	if err != nil {
+		return err
+	}
+	return otel.PostSpans(ctx, r.client, &r.config.Exporter, defaultPath, contentType, body)
+}
+
+func (r *reporter) convert(spans []*otel.Span) []*span {
+	local := &endpoint{
+		ServiceName: r.config.ServiceName,
+	}
+	result := make([]*span, 0, len(spans))
+	for _, s := range spans {
+		zs := &span{
+			TraceID:       s.TraceId(),
+			ID:            s.SpanId(),
+			ParentID:      s.ParentSpanId(),
+			Name:          s.Name(),
+			Timestamp:     s.StartTime().UnixNano() / int64(time.Microsecond),
+			Duration:      durationMicros(s.StartTime(), s.EndTime()),
+			LocalEndpoint: local,
+			Tags:          make(map[string]string),
+		}
+		if s.Kind() == otel.SpanKindServer {
+			zs.Kind = "SERVER"
+		}
+		for key, value := range r.config.ResourceAttributes {
+			zs.Tags[key] = value
+		}
+		s.RangeTags(func(key uint64, value string) bool {
+			if key == otel.PeerAddress {
+				zs.RemoteEndpoint = parseEndpoint(value)
+			}
+			zs.Tags[otel.TagName(key)] = value
+			return true
+		})
+		if code, msg := s.Status(); code == otel.StatusError {
+			// zipkin marks the failed span by the error tag
+			if msg == "" {
+				msg = "true"
+			}
+			zs.Tags["error"] = msg
+		}
+		result = append(result, zs)
+	}
+	return result
+}
+
+// durationMicros returns the duration in microseconds, which is at least 1 as zipkin requires
+func durationMicros(start, end time.Time) int64 {
+	d := end.Sub(start).Nanoseconds() / int64(time.Microsecond)
+	if d < 1 {
+		d = 1
+	}
+	return d
+}
+
+// parseEndpoint parses the ip:port address, nil is returned if the address is not an ip address
+func parseEndpoint(addr string) *endpoint {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+	ep := &endpoint{}
+	if ip.To4() != nil {
+		ep.IPv4 = ip.String()
+	} else {
+		ep.IPv6 = ip.String()
+	}
+	ep.Port, _ = strconv.Atoi(port)
+	return ep
+}
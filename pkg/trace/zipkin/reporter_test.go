@@ -0,0 +1,135 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zipkin
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/trace/otel"
+)
+
+type fakeCollector struct {
+	mu    sync.Mutex
+	spans []*span
+}
+
+func (c *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != defaultPath || r.Header.Get("Content-Type") != contentType {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	var spans []*span
+	if err := json.Unmarshal(body, &spans); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.spans = append(c.spans, spans...)
+	c.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func TestReporter(t *testing.T) {
+	collector := &fakeCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	builder := otel.NewTracerBuilder("zipkin_test", setDefaults)
+	tracer, err := builder(map[string]interface{}{
+		"service_name": "zipkin-test",
+		"exporter": map[string]interface{}{
+			"endpoint": strings.TrimPrefix(server.URL, "http://"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	s := tracer.Start(context.Background(), protocol.CommonHeader{
+		"x-b3-traceid": "463ac35c9f6413ad48485a3953bb6124",
+		"x-b3-spanid":  "a2fb4a1d1a96d312",
+		"x-b3-sampled": "1",
+	}, start)
+	// the b3 headers are propagated by default
+	upstream := protocol.CommonHeader{}
+	s.InjectContext(upstream)
+	if upstream["x-b3-traceid"] != "463ac35c9f6413ad48485a3953bb6124" || upstream["x-b3-spanid"] != s.SpanId() || upstream["traceparent"] != "" {
+		t.Errorf("unexpected upstream headers: %v", upstream)
+	}
+	info := network.NewRequestInfo()
+	info.SetResponseCode(503)
+	info.SetResponseFlag(api.NoHealthyUpstream)
+	info.SetDownstreamRemoteAddress(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 8080})
+	s.SetRequestInfo(info)
+	s.FinishSpan()
+	tracer.(*otel.Tracer).Flush()
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if len(collector.spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(collector.spans))
+	}
+	got := collector.spans[0]
+	if got.TraceID != "463ac35c9f6413ad48485a3953bb6124" || got.ParentID != "a2fb4a1d1a96d312" || got.ID != s.SpanId() {
+		t.Errorf("unexpected span ids: %+v", got)
+	}
+	if got.Kind != "SERVER" || got.LocalEndpoint == nil || got.LocalEndpoint.ServiceName != "zipkin-test" {
+		t.Errorf("unexpected span: %+v", got)
+	}
+	if got.Timestamp != start.UnixNano()/int64(time.Microsecond) || got.Duration < 1 {
+		t.Errorf("unexpected span time: %d %d", got.Timestamp, got.Duration)
+	}
+	if got.RemoteEndpoint == nil || got.RemoteEndpoint.IPv4 != "10.0.0.1" || got.RemoteEndpoint.Port != 8080 {
+		t.Errorf("unexpected remote endpoint: %+v", got.RemoteEndpoint)
+	}
+	if got.Tags["http.status_code"] != "503" || got.Tags["mosn.response_flags"] != "UH" || got.Tags["error"] != "UH" {
+		t.Errorf("unexpected tags: %v", got.Tags)
+	}
+}
+
+func TestConvertTags(t *testing.T) {
+	r := &reporter{config: &otel.Config{ServiceName: "mosn"}}
+	builder := otel.NewTracerBuilder("zipkin_tags_test", setDefaults)
+	tracer, err := builder(map[string]interface{}{
+		"exporter": map[string]interface{}{"cluster": "zipkin"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := tracer.Start(context.Background(), protocol.CommonHeader{}, time.Now())
+	s.SetTag(otel.UpstreamAddress, "127.0.0.1:9090")
+	s.SetTag(otel.TagEnd, "ignored")
+	s.FinishSpan()
+	spans := r.convert([]*otel.Span{s.(*otel.Span)})
+	if len(spans) != 1 || spans[0].Tags["mosn.upstream.address"] != "127.0.0.1:9090" || spans[0].ParentID != "" || spans[0].Tags["error"] != "" {
+		t.Errorf("unexpected spans: %+v", spans[0])
+	}
+}
@@ -38,6 +38,8 @@ const (
 	HeaderXprotocolRespStatus      = "x-mosn-xprotocol-resp-status"
 	HeaderXprotocolRespIsException = "x-mosn-xprotocol-resp-is-exception"
 	HeaderXprotocolHeartbeat       = "x-protocol-heartbeat"
+	// HeaderLocation is the standard header used in redirect responses
+	HeaderLocation = "location"
 )
 
 // Error messages
@@ -32,6 +32,11 @@ const (
 	RoundRobin   LoadBalancerType = "LB_ROUNDROBIN"
 	Random       LoadBalancerType = "LB_RANDOM"
 	ORIGINAL_DST LoadBalancerType = "LB_ORIGINAL_DST"
+
+	WeightedRoundRobin LoadBalancerType = "LB_WEIGHTED_ROUNDROBIN"
+	LeastActiveRequest LoadBalancerType = "LB_LEAST_REQUEST"
+	RingHash           LoadBalancerType = "LB_RINGHASH"
+	Maglev             LoadBalancerType = "LB_MAGLEV"
 )
 
 // LoadBalancer is a upstream load balancer.
@@ -63,6 +68,14 @@ type LoadBalancerContext interface {
 
 	// Downstream cluster info
 	DownstreamCluster() ClusterInfo
+
+	// HashKey returns the hash key generated by the route's hash policy,
+	// returns false if no hash key is generated
+	HashKey() (uint64, bool)
+
+	// ShouldSelectAnotherHost returns true if the host should be skipped when choosing a host,
+	// such as the host has been attempted by the previous retries
+	ShouldSelectAnotherHost(host Host) bool
 }
 
 // LBSubsetEntry is a entry that stored in the subset hierarchy.
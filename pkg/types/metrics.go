@@ -47,6 +47,11 @@ type Metrics interface {
 	// if the key is registered by other interface, it will be panic
 	Histogram(key string) metrics.Histogram
 
+	// HistogramWithBuckets creates or returns a go-metrics histogram by key with the bucket upper bounds,
+	// a histogram without valid bounds keeps a uniform sample instead of buckets
+	// if the key is registered by other interface, it will be panic
+	HistogramWithBuckets(key string, bounds []int64) metrics.Histogram
+
 	// Each call the given function for each registered metric.
 	Each(func(string, interface{}))
 
@@ -19,12 +19,26 @@ package types
 
 import (
 	"errors"
+
+	"mosn.io/api"
 )
 
 var (
 	ErrExit = errors.New("downstream process completed")
 )
 
+// Response flags that extend the api.ResponseFlag
+const (
+	// Unauthorized means the request is rejected by the authentication or authorization filters
+	Unauthorized api.ResponseFlag = 0x4000
+)
+
+// HijackReplyWithBodyHandler is implemented by the proxy's api.StreamReceiverFilterHandler,
+// a stream filter can type assert the handler to send a hijack reply with body
+type HijackReplyWithBodyHandler interface {
+	SendHijackReplyWithBody(code int, headers api.HeaderMap, body string)
+}
+
 type Phase int
 
 const (
@@ -106,6 +106,103 @@ type VirtualHost interface {
 	RemoveAllRoutes()
 }
 
+// Policy extends api.Policy with the policies that implemented in mosn only
+type Policy interface {
+	api.Policy
+
+	HashPolicy() HashPolicy
+
+	MirrorPolicies() []MirrorPolicy
+}
+
+// MirrorPolicy extends api.ShadowPolicy, the requests are mirrored to the shadow cluster
+type MirrorPolicy interface {
+	api.ShadowPolicy
+
+	// IsMirror returns true if the request should be mirrored, according to the runtime fraction
+	IsMirror() bool
+}
+
+// RetryPolicy extends api.RetryPolicy with the retry conditions and back off
+type RetryPolicy interface {
+	api.RetryPolicy
+
+	// RetryOnConditions returns the conditions that should be retried,
+	// zero means the default conditions are used
+	RetryOnConditions() RetryOnCondition
+
+	// RetriableStatusCodes returns the status codes that should be retried
+	// when RetryOnRetriableStatusCodes is set
+	RetriableStatusCodes() []uint32
+
+	// BackOffInterval returns the base and max interval of the exponential back off between retries
+	BackOffInterval() (base time.Duration, max time.Duration)
+}
+
+// RetryOnCondition is a bitmask of retry conditions
+type RetryOnCondition uint32
+
+// Retry conditions, see envoy's x-envoy-retry-on
+const (
+	// RetryOn5xx retries if the upstream responses 5xx, or does not respond at all
+	RetryOn5xx RetryOnCondition = 1 << iota
+	// RetryOnGatewayError retries if the upstream responses 502, 503 or 504, or connect failed or timeout
+	RetryOnGatewayError
+	// RetryOnConnectFailure retries if connect to upstream failed
+	RetryOnConnectFailure
+	// RetryOnReset retries if the upstream does not respond at all, such as disconnect/reset/timeout
+	RetryOnReset
+	// RetryOnRetriableStatusCodes retries if the upstream responses the configured status codes
+	RetryOnRetriableStatusCodes
+)
+
+// RedirectRoute is a route that may redirect the requests
+type RedirectRoute interface {
+	// RedirectRule returns the route's redirect rule, returns nil if the route does not redirect
+	RedirectRule() RedirectRule
+}
+
+// RedirectRule contains the redirect response info
+type RedirectRule interface {
+	// RedirectCode returns the status code of the redirect response
+	RedirectCode() int
+	// RedirectLocation returns the redirect location generated from the request headers
+	RedirectLocation(headers api.HeaderMap) string
+}
+
+// CorsRoute is a route that may have a cors policy
+type CorsRoute interface {
+	// CorsPolicy returns the route's cors policy, or the virtual host's if the route has no policy.
+	// returns nil if there is no policy
+	CorsPolicy() CorsPolicy
+}
+
+// CorsPolicy is the cors policy of a route or a virtual host
+type CorsPolicy interface {
+	// AllowOrigin returns whether the origin is allowed
+	AllowOrigin(origin string) bool
+	// AllowMethods returns the value of the Access-Control-Allow-Methods header
+	AllowMethods() string
+	// AllowHeaders returns the value of the Access-Control-Allow-Headers header
+	AllowHeaders() string
+	// ExposeHeaders returns the value of the Access-Control-Expose-Headers header
+	ExposeHeaders() string
+	// MaxAge returns the value of the Access-Control-Max-Age header
+	MaxAge() string
+	// AllowCredentials returns whether the Access-Control-Allow-Credentials header is set
+	AllowCredentials() bool
+	// Enabled returns whether the policy is enforced
+	Enabled() bool
+	// ShadowEnabled returns whether the policy is evaluated without changing the requests and responses
+	ShadowEnabled() bool
+}
+
+// HashPolicy generates the hash key for consistent hash load balancers
+type HashPolicy interface {
+	// GenerateHash returns the hash key, returns false if no hash key can be generated
+	GenerateHash(ctx LoadBalancerContext) (uint64, bool)
+}
+
 type HeaderFormat interface {
 	Format(info api.RequestInfo) string
 	Append() bool
@@ -129,8 +226,27 @@ type HeaderData struct {
 	Value        string
 	IsRegex      bool
 	RegexPattern *regexp.Regexp
+	MatchType    HeaderMatchType
+	// RangeStart and RangeEnd are used in HeaderMatchRange, the range is [RangeStart, RangeEnd)
+	RangeStart int64
+	RangeEnd   int64
+	// InvertMatch inverts the match result
+	InvertMatch bool
 }
 
+// HeaderMatchType is the way to match a header
+type HeaderMatchType uint8
+
+// Header match types, HeaderMatchValue matches the value exactly, or by regex if IsRegex is true
+const (
+	HeaderMatchValue HeaderMatchType = iota
+	HeaderMatchPresent
+	HeaderMatchAbsent
+	HeaderMatchPrefix
+	HeaderMatchSuffix
+	HeaderMatchRange
+)
+
 // ConfigUtility is utility routines for loading route configuration and matching runtime request headers.
 type ConfigUtility interface {
 	// MatchHeaders check whether the headers specified in the config are present in a request.
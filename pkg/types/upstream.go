@@ -116,6 +116,31 @@ type HostSet interface {
 
 	// HealthyHosts returns all healthy hosts
 	HealthyHosts() []Host
+
+	// PriorityLevels returns the hosts grouped by priority, ordered from the highest priority to the lowest
+	PriorityLevels() []PriorityLevel
+}
+
+// PriorityLevel is the hosts with the same priority in a HostSet
+type PriorityLevel interface {
+	HostSet
+
+	// Priority returns the priority of the level, 0 is the highest priority
+	Priority() uint32
+
+	// Localities returns the hosts in the level grouped by locality
+	Localities() []LocalityHostSet
+}
+
+// LocalityHostSet is the hosts with the same locality in a PriorityLevel
+type LocalityHostSet interface {
+	HostSet
+
+	// Locality returns the locality of the hosts
+	Locality() v2.Locality
+
+	// Weight returns the load balancing weight of the locality
+	Weight() uint32
 }
 
 // HealthFlag type
@@ -160,6 +185,15 @@ type Host interface {
 	Address() net.Addr
 	// Config creates a host config by the host attributes
 	Config() v2.Host
+
+	// Priority returns the host's priority level
+	Priority() uint32
+
+	// Locality returns the host's locality
+	Locality() v2.Locality
+
+	// LocalityWeight returns the load balancing weight of the host's locality
+	LocalityWeight() uint32
 }
 
 // ClusterInfo defines a cluster's information
@@ -193,6 +227,20 @@ type ClusterInfo interface {
 
 	// LbOriDstInfo returns the load balancer oridst config
 	LbOriDstInfo() LBOriDstInfo
+
+	// OutlierDetector returns the cluster's outlier detector, returns nil if outlier detection is not configured
+	OutlierDetector() OutlierDetector
+
+	// HeartbeatKeepAlive returns whether the optional heartbeat keepalive is enabled
+	HeartbeatKeepAlive() bool
+}
+
+// OutlierDetector ejects the hosts that behave abnormally from the load balancing, according to the upstream results
+type OutlierDetector interface {
+	// PutResult reports an upstream result of the host, the result is a http status code.
+	// The local failures should be reported as the gateway failures, for example,
+	// connect failure and reset as 503, timeout as 504
+	PutResult(host Host, code int)
 }
 
 // ResourceManager manages different types of Resource
@@ -229,6 +277,7 @@ type HostStats struct {
 	UpstreamConnectionLocalCloseWithActiveRequest  metrics.Counter
 	UpstreamConnectionRemoteCloseWithActiveRequest metrics.Counter
 	UpstreamConnectionCloseNotify                  metrics.Counter
+	UpstreamConnectionConnectDuration              metrics.Histogram
 	UpstreamRequestTotal                           metrics.Counter
 	UpstreamRequestActive                          metrics.Counter
 	UpstreamRequestLocalReset                      metrics.Counter
@@ -254,6 +303,7 @@ type ClusterStats struct {
 	UpstreamConnectionLocalCloseWithActiveRequest  metrics.Counter
 	UpstreamConnectionRemoteCloseWithActiveRequest metrics.Counter
 	UpstreamConnectionCloseNotify                  metrics.Counter
+	UpstreamConnectionConnectDuration              metrics.Histogram
 	UpstreamBytesReadTotal                         metrics.Counter
 	UpstreamBytesWriteTotal                        metrics.Counter
 	UpstreamRequestTotal                           metrics.Counter
@@ -271,6 +321,14 @@ type ClusterStats struct {
 	UpstreamResponseFailed                         metrics.Counter
 	LBSubSetsFallBack                              metrics.Counter
 	LBSubsetsCreated                               metrics.Gauge
+	LBHealthyPanic                                 metrics.Counter
+	OutlierEjectionsActive                         metrics.Gauge
+	OutlierEjectionsTotal                          metrics.Counter
+	OutlierEjectionsOverflow                       metrics.Counter
+	OutlierEjectionsConsecutive5xx                 metrics.Counter
+	OutlierEjectionsConsecutiveGatewayFailure      metrics.Counter
+	OutlierEjectionsSuccessRate                    metrics.Counter
+	OutlierEjectionsFailurePercentage              metrics.Counter
 }
 
 type CreateConnectionData struct {
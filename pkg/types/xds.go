@@ -21,15 +21,24 @@ import (
 	"strings"
 
 	"github.com/gogo/protobuf/types"
+	v2 "mosn.io/mosn/pkg/config/v2"
 )
 
 const serviceMetaSeparator = ":"
 
+// The service meta keys of the locality that the sidecar is running in
+const (
+	ServiceMetaRegion  = "region"
+	ServiceMetaZone    = "zone"
+	ServiceMetaSubZone = "sub_zone"
+)
+
 // XdsInfo The xds start parameters
 type XdsInfo struct {
 	ServiceCluster string
 	ServiceNode    string
 	Metadata       *types.Struct
+	Locality       v2.Locality
 }
 
 var globalXdsInfo = &XdsInfo{}
@@ -46,6 +55,7 @@ func InitXdsFlags(serviceCluster, serviceNode string, serviceMeta []string) {
 	globalXdsInfo.Metadata = &types.Struct{
 		Fields: map[string]*types.Value{},
 	}
+	globalXdsInfo.Locality = v2.Locality{}
 
 	for _, keyValue := range serviceMeta {
 		keyValueSep := strings.SplitN(keyValue, serviceMetaSeparator, 2)
@@ -60,5 +70,13 @@ func InitXdsFlags(serviceCluster, serviceNode string, serviceMeta []string) {
 				StringValue: value,
 			},
 		}
+		switch key {
+		case ServiceMetaRegion:
+			globalXdsInfo.Locality.Region = value
+		case ServiceMetaZone:
+			globalXdsInfo.Locality.Zone = value
+		case ServiceMetaSubZone:
+			globalXdsInfo.Locality.SubZone = value
+		}
 	}
 }
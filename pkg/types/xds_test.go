@@ -61,3 +61,19 @@ func TestInitXdsFlags(t *testing.T) {
 	}
 
 }
+
+func TestInitXdsFlagsLocality(t *testing.T) {
+	InitXdsFlags("cluster", "node", []string{
+		"region:r",
+		"zone:z",
+		"sub_zone:s",
+	})
+	locality := GetGlobalXdsInfo().Locality
+	if locality.Region != "r" || locality.Zone != "z" || locality.SubZone != "s" {
+		t.Fatalf("unexpected locality: %+v", locality)
+	}
+	InitXdsFlags("cluster", "node", []string{})
+	if GetGlobalXdsInfo().Locality.Zone != "" {
+		t.Fatal("locality should be reset")
+	}
+}
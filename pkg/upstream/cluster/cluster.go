@@ -47,15 +47,18 @@ type simpleCluster struct {
 
 func newSimpleCluster(clusterConfig v2.Cluster) *simpleCluster {
 	info := &clusterInfo{
-		name:                 clusterConfig.Name,
-		clusterType:          clusterConfig.ClusterType,
-		maxRequestsPerConn:   clusterConfig.MaxRequestPerConn,
-		connBufferLimitBytes: clusterConfig.ConnBufferLimitBytes,
-		stats:                newClusterStats(clusterConfig.Name),
-		lbSubsetInfo:         NewLBSubsetInfo(&clusterConfig.LBSubSetConfig), // new subset load balancer info
-		lbOriDstInfo:         NewLBOriDstInfo(&clusterConfig.LBOriDstConfig), // new oridst load balancer info
-		lbType:               types.LoadBalancerType(clusterConfig.LbType),
-		resourceManager:      NewResourceManager(clusterConfig.CirBreThresholds),
+		name:                   clusterConfig.Name,
+		clusterType:            clusterConfig.ClusterType,
+		maxRequestsPerConn:     clusterConfig.MaxRequestPerConn,
+		connBufferLimitBytes:   clusterConfig.ConnBufferLimitBytes,
+		stats:                  newClusterStats(clusterConfig.Name),
+		lbSubsetInfo:           NewLBSubsetInfo(&clusterConfig.LBSubSetConfig), // new subset load balancer info
+		lbOriDstInfo:           NewLBOriDstInfo(&clusterConfig.LBOriDstConfig), // new oridst load balancer info
+		lbType:                 types.LoadBalancerType(clusterConfig.LbType),
+		resourceManager:        NewResourceManager(clusterConfig.CirBreThresholds),
+		overprovisioningFactor: clusterConfig.OverprovisioningFactor,
+		healthyPanicThreshold:  clusterConfig.HealthyPanicThreshold,
+		heartbeatKeepAlive:     clusterConfig.HeartbeatKeepAlive,
 	}
 
 	// set ConnectTimeout
@@ -74,6 +77,17 @@ func newSimpleCluster(clusterConfig v2.Cluster) *simpleCluster {
 	cluster := &simpleCluster{
 		info: info,
 	}
+	if clusterConfig.OutlierDetection != nil {
+		log.DefaultLogger.Infof("[upstream] [cluster] [new cluster] cluster %s have outlier detection", clusterConfig.Name)
+		info.outlierDetector = newOutlierDetector(clusterConfig.Name, clusterConfig.OutlierDetection, info.stats)
+		// the detector runs in its own goroutine, so the host set is loaded from the snapshot
+		info.outlierDetector.onChanged = func(host types.Host) {
+			if hs, ok := cluster.snapshot.Load().(*clusterSnapshot).hostSet.(*hostSet); ok {
+				hs.refreshHealthHost(host)
+			}
+		}
+		info.outlierDetector.start()
+	}
 	// init a empty
 	hostSet := &hostSet{}
 	cluster.snapshot.Store(&clusterSnapshot{
@@ -104,7 +118,7 @@ func (sc *simpleCluster) UpdateHosts(newHosts []types.Host) {
 	if info.lbSubsetInfo.IsEnabled() {
 		lb = NewSubsetLoadBalancer(info, hostSet)
 	} else {
-		lb = NewLoadBalancer(info.lbType, hostSet)
+		lb = newClusterLoadBalancer(info, hostSet)
 	}
 	sc.lbInstance = lb
 	sc.hostSet = hostSet
@@ -113,6 +127,10 @@ func (sc *simpleCluster) UpdateHosts(newHosts []types.Host) {
 		hostSet: hostSet,
 		info:    info,
 	})
+	// apply the ejection states after the snapshot is stored, so the changed hosts are refreshed in the new host set
+	if info.outlierDetector != nil {
+		info.outlierDetector.updateHosts(newHosts)
+	}
 	if sc.healthChecker != nil {
 		utils.GoWithRecover(func() {
 			sc.healthChecker.SetHealthCheckerHostSet(hostSet)
@@ -139,20 +157,31 @@ func (sc *simpleCluster) StopHealthChecking() {
 	if sc.healthChecker != nil {
 		sc.healthChecker.Stop()
 	}
+	sc.stopOutlierDetection()
+}
+
+func (sc *simpleCluster) stopOutlierDetection() {
+	if sc.info.outlierDetector != nil {
+		sc.info.outlierDetector.stop()
+	}
 }
 
 type clusterInfo struct {
-	name                 string
-	clusterType          v2.ClusterType
-	lbType               types.LoadBalancerType // if use subset lb , lbType is used as inner LB algorithm for choosing subset's host
-	connBufferLimitBytes uint32
-	maxRequestsPerConn   uint32
-	resourceManager      types.ResourceManager
-	stats                types.ClusterStats
-	lbSubsetInfo         types.LBSubsetInfo
-	lbOriDstInfo         types.LBOriDstInfo
-	tlsMng               types.TLSContextManager
-	connectTimeout       time.Duration
+	name                   string
+	clusterType            v2.ClusterType
+	lbType                 types.LoadBalancerType // if use subset lb , lbType is used as inner LB algorithm for choosing subset's host
+	connBufferLimitBytes   uint32
+	maxRequestsPerConn     uint32
+	resourceManager        types.ResourceManager
+	stats                  types.ClusterStats
+	lbSubsetInfo           types.LBSubsetInfo
+	lbOriDstInfo           types.LBOriDstInfo
+	tlsMng                 types.TLSContextManager
+	connectTimeout         time.Duration
+	outlierDetector        *outlierDetector
+	overprovisioningFactor uint32
+	healthyPanicThreshold  uint32
+	heartbeatKeepAlive     bool
 }
 
 func (ci *clusterInfo) Name() string {
@@ -224,3 +253,14 @@ func (snapshot *clusterSnapshot) IsExistsHosts(metadata api.MetadataMatchCriteri
 func (snapshot *clusterSnapshot) HostNum(metadata api.MetadataMatchCriteria) int {
 	return snapshot.lb.HostNum(metadata)
 }
+
+func (ci *clusterInfo) HeartbeatKeepAlive() bool {
+	return ci.heartbeatKeepAlive
+}
+
+func (ci *clusterInfo) OutlierDetector() types.OutlierDetector {
+	if ci.outlierDetector == nil {
+		return nil
+	}
+	return ci.outlierDetector
+}
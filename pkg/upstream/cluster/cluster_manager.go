@@ -116,6 +116,14 @@ func (cm *clusterManager) AddOrUpdatePrimaryCluster(cluster v2.Cluster) error {
 	ci, exists := cm.clustersMap.Load(clusterName)
 	if exists {
 		c := ci.(types.Cluster)
+		// the hosts' ejection states are carried over to the new cluster's detector
+		if sc, ok := c.(*simpleCluster); ok {
+			if nc, ok := newCluster.(*simpleCluster); ok && nc.info.outlierDetector != nil && sc.info.outlierDetector != nil {
+				nc.info.outlierDetector.takeOver(sc.info.outlierDetector)
+			} else {
+				sc.stopOutlierDetection()
+			}
+		}
 		//FIXME: cluster info in hosts should be updated too
 		hosts := c.Snapshot().HostSet().Hosts()
 		// update hosts, refresh
@@ -276,6 +284,8 @@ func (cm *clusterManager) ConnPoolForCluster(balancerContext types.LoadBalancerC
 const (
 	maxHostsCounts  = 3
 	maxTryConnTimes = 7
+	// max attempts to choose a host that is not rejected by the load balancer context
+	maxHostSelectionAttempts = 10
 )
 
 var (
@@ -300,7 +310,7 @@ func (cm *clusterManager) getActiveConnectionPool(balancerContext types.LoadBala
 		try = maxHostsCounts
 	}
 	for i := 0; i < try; i++ {
-		host := clusterSnapshot.LoadBalancer().ChooseHost(balancerContext)
+		host := chooseHost(balancerContext, clusterSnapshot)
 		if host == nil {
 			return nil, errNilHostChoose
 		}
@@ -381,3 +391,24 @@ func (cm *clusterManager) getActiveConnectionPool(balancerContext types.LoadBala
 	}
 	return nil, errNoHealthyHost
 }
+
+// chooseHost chooses a host from the cluster snapshot's load balancer.
+// If the host is rejected by the load balancer context, such as it has been attempted by a retried request,
+// chooses again until another host is chosen, the first chosen host is used if no other host can be chosen
+func chooseHost(balancerContext types.LoadBalancerContext, clusterSnapshot types.ClusterSnapshot) types.Host {
+	lb := clusterSnapshot.LoadBalancer()
+	host := lb.ChooseHost(balancerContext)
+	if host == nil || !shouldSelectAnotherHost(balancerContext, host) {
+		return host
+	}
+	for i := 1; i < maxHostSelectionAttempts; i++ {
+		another := lb.ChooseHost(balancerContext)
+		if another == nil {
+			break
+		}
+		if !shouldSelectAnotherHost(balancerContext, another) {
+			return another
+		}
+	}
+	return host
+}
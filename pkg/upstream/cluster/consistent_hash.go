@@ -0,0 +1,345 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/types"
+)
+
+func init() {
+	RegisterLBType(types.RingHash, newRingHashLoadBalancer)
+	RegisterLBType(types.Maglev, newMaglevLoadBalancer)
+}
+
+const (
+	// ringHashMinSize is the minimal entries in the hash ring,
+	// each host has at least one entry
+	ringHashMinSize = 1024
+	// maglevTableSize should be a prime number
+	maglevTableSize = 65537
+)
+
+func hashKeyWithSeed(key string, seed uint64) uint64 {
+	h := fnv.New64a()
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], seed)
+	h.Write(b[:])
+	h.Write([]byte(key))
+	// fnv hash values of similar keys are not uniformly distributed,
+	// use the murmur3 finalizer to mix the bits
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// hashFallback chooses a random healthy host, it is used when the load balancer context
+// contains no hash key
+type hashFallback struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+func newHashFallback() hashFallback {
+	return hashFallback{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (f *hashFallback) hash() uint64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.rand.Uint64()
+}
+
+func contextHashKey(context types.LoadBalancerContext, fallback *hashFallback) uint64 {
+	if context != nil {
+		if key, ok := context.HashKey(); ok {
+			return key
+		}
+	}
+	return fallback.hash()
+}
+
+// healthyHostFilter reports whether a host is one of the host set's healthy hosts,
+// so the consistent hash load balancers follow the host set's view of health as the other load balancers.
+// The host set replaces the healthy hosts slice when the health changes, so the lookup is built
+// once for a healthy hosts slice and reused until the slice is replaced.
+type healthyHostFilter struct {
+	hosts  types.HostSet
+	lookup atomic.Value // *healthyHostLookup
+}
+
+type healthyHostLookup struct {
+	healthyHosts []types.Host
+	// healthy is nil if all the hosts are healthy
+	healthy map[string]struct{}
+}
+
+func newHealthyHostFilter(hosts types.HostSet) *healthyHostFilter {
+	return &healthyHostFilter{hosts: hosts}
+}
+
+// get returns the lookup of the current healthy hosts, returns nil if there is no healthy host
+func (f *healthyHostFilter) get() *healthyHostLookup {
+	healthyHosts := f.hosts.HealthyHosts()
+	if len(healthyHosts) == 0 {
+		return nil
+	}
+	if l, ok := f.lookup.Load().(*healthyHostLookup); ok &&
+		len(l.healthyHosts) == len(healthyHosts) && &l.healthyHosts[0] == &healthyHosts[0] {
+		return l
+	}
+	l := &healthyHostLookup{healthyHosts: healthyHosts}
+	if len(healthyHosts) != len(f.hosts.Hosts()) {
+		l.healthy = make(map[string]struct{}, len(healthyHosts))
+		for _, h := range healthyHosts {
+			l.healthy[h.AddressString()] = struct{}{}
+		}
+	}
+	f.lookup.Store(l)
+	return l
+}
+
+func (l *healthyHostLookup) isHealthy(h types.Host) bool {
+	if l.healthy == nil {
+		return true
+	}
+	_, ok := l.healthy[h.AddressString()]
+	return ok
+}
+
+// ringHashEntry is a virtual node in the hash ring
+type ringHashEntry struct {
+	hash uint64
+	host types.Host
+}
+
+// ringHashLoadBalancer is a consistent hash load balancer, the hosts are placed on a hash ring
+// with virtual nodes proportional to their weights. A request is routed to the first host clockwise
+// from the request's hash key. If the host is unhealthy or rejected by the context, the next healthy host
+// on the ring is chosen.
+type ringHashLoadBalancer struct {
+	hosts    types.HostSet
+	healthy  *healthyHostFilter
+	ring     []ringHashEntry
+	fallback hashFallback
+}
+
+func newRingHashLoadBalancer(hosts types.HostSet) types.LoadBalancer {
+	lb := &ringHashLoadBalancer{
+		hosts:    hosts,
+		healthy:  newHealthyHostFilter(hosts),
+		fallback: newHashFallback(),
+	}
+	lb.buildRing()
+	return lb
+}
+
+func (lb *ringHashLoadBalancer) buildRing() {
+	allHosts := lb.hosts.Hosts()
+	if len(allHosts) == 0 {
+		return
+	}
+	var totalWeight int64
+	for _, h := range allHosts {
+		totalWeight += hostWeight(h)
+	}
+	// entries per weight unit
+	scale := ringHashMinSize/totalWeight + 1
+	ring := make([]ringHashEntry, 0, scale*totalWeight)
+	for _, h := range allHosts {
+		addr := h.AddressString()
+		entries := scale * hostWeight(h)
+		for i := int64(0); i < entries; i++ {
+			ring = append(ring, ringHashEntry{
+				hash: hashKeyWithSeed(addr+"_"+strconv.FormatInt(i, 10), 0),
+				host: h,
+			})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+	lb.ring = ring
+}
+
+func (lb *ringHashLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	if len(lb.ring) == 0 {
+		return nil
+	}
+	healthy := lb.healthy.get()
+	if healthy == nil {
+		return nil
+	}
+	key := contextHashKey(context, &lb.fallback)
+	idx := sort.Search(len(lb.ring), func(i int) bool {
+		return lb.ring[i].hash >= key
+	})
+	var candidate types.Host
+	for i := 0; i < len(lb.ring); i++ {
+		entry := lb.ring[(idx+i)%len(lb.ring)]
+		if !healthy.isHealthy(entry.host) {
+			continue
+		}
+		if !shouldSelectAnotherHost(context, entry.host) {
+			return entry.host
+		}
+		if candidate == nil {
+			candidate = entry.host
+		}
+	}
+	// all the healthy hosts are rejected, use the first one
+	return candidate
+}
+
+func (lb *ringHashLoadBalancer) IsExistsHosts(metadata api.MetadataMatchCriteria) bool {
+	return len(lb.hosts.Hosts()) > 0
+}
+
+func (lb *ringHashLoadBalancer) HostNum(metadata api.MetadataMatchCriteria) int {
+	return len(lb.hosts.Hosts())
+}
+
+// maglevLoadBalancer is a consistent hash load balancer implemented the Maglev algorithm,
+// see https://research.google.com/pubs/pub44824.html
+// Weights are supported by letting a host with a higher weight fill the lookup table more frequently.
+// If the host in the lookup table is unhealthy or rejected by the context, the next healthy host in the table is chosen.
+type maglevLoadBalancer struct {
+	hosts    types.HostSet
+	healthy  *healthyHostFilter
+	table    []types.Host
+	fallback hashFallback
+}
+
+func newMaglevLoadBalancer(hosts types.HostSet) types.LoadBalancer {
+	lb := &maglevLoadBalancer{
+		hosts:    hosts,
+		healthy:  newHealthyHostFilter(hosts),
+		fallback: newHashFallback(),
+	}
+	lb.buildTable()
+	return lb
+}
+
+type maglevEntry struct {
+	host         types.Host
+	offset       uint64
+	skip         uint64
+	next         uint64
+	weight       int64
+	targetWeight int64
+}
+
+func (e *maglevEntry) permutation() uint64 {
+	return (e.offset + e.skip*e.next) % maglevTableSize
+}
+
+func (lb *maglevLoadBalancer) buildTable() {
+	allHosts := lb.hosts.Hosts()
+	if len(allHosts) == 0 {
+		return
+	}
+	var maxWeight int64
+	entries := make([]*maglevEntry, 0, len(allHosts))
+	for _, h := range allHosts {
+		addr := h.AddressString()
+		weight := hostWeight(h)
+		if weight > maxWeight {
+			maxWeight = weight
+		}
+		entries = append(entries, &maglevEntry{
+			host:   h,
+			offset: hashKeyWithSeed(addr, 0) % maglevTableSize,
+			skip:   hashKeyWithSeed(addr, 1)%(maglevTableSize-1) + 1,
+			weight: weight,
+		})
+	}
+	table := make([]types.Host, maglevTableSize)
+	filled := 0
+	for iteration := int64(1); filled < maglevTableSize; iteration++ {
+		for _, entry := range entries {
+			if filled >= maglevTableSize {
+				break
+			}
+			// a host with max weight fills the table in every iteration,
+			// a host with max weight / n fills the table every n iterations
+			if iteration*entry.weight < entry.targetWeight {
+				continue
+			}
+			entry.targetWeight += maxWeight
+			c := entry.permutation()
+			for table[c] != nil {
+				entry.next++
+				c = entry.permutation()
+			}
+			table[c] = entry.host
+			entry.next++
+			filled++
+		}
+	}
+	lb.table = table
+}
+
+func (lb *maglevLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	if len(lb.table) == 0 {
+		return nil
+	}
+	healthy := lb.healthy.get()
+	if healthy == nil {
+		return nil
+	}
+	key := contextHashKey(context, &lb.fallback)
+	idx := key % uint64(len(lb.table))
+	var candidate types.Host
+	for i := uint64(0); i < uint64(len(lb.table)); i++ {
+		host := lb.table[(idx+i)%uint64(len(lb.table))]
+		if !healthy.isHealthy(host) {
+			continue
+		}
+		if !shouldSelectAnotherHost(context, host) {
+			return host
+		}
+		if candidate == nil {
+			candidate = host
+		}
+	}
+	// all the healthy hosts are rejected, use the first one
+	return candidate
+}
+
+func (lb *maglevLoadBalancer) IsExistsHosts(metadata api.MetadataMatchCriteria) bool {
+	return len(lb.hosts.Hosts()) > 0
+}
+
+func (lb *maglevLoadBalancer) HostNum(metadata api.MetadataMatchCriteria) int {
+	return len(lb.hosts.Hosts())
+}
@@ -0,0 +1,229 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+func newHashLbContext(key uint64) types.LoadBalancerContext {
+	return &mockLbContext{
+		hashKey: &key,
+	}
+}
+
+func TestConsistentHashLoadBalancer(t *testing.T) {
+	for _, lbType := range []types.LoadBalancerType{types.RingHash, types.Maglev} {
+		hs := createHostset(exampleHostConfigs())
+		lb := NewLoadBalancer(lbType, hs)
+		// same key should choose same host
+		for key := uint64(0); key < 100; key++ {
+			h := lb.ChooseHost(newHashLbContext(key))
+			if h == nil {
+				t.Fatalf("%s choose host failed", lbType)
+			}
+			for i := 0; i < 10; i++ {
+				if lb.ChooseHost(newHashLbContext(key)) != h {
+					t.Fatalf("%s choose host not consistent", lbType)
+				}
+			}
+		}
+		// no hash key, choose a random host
+		if h := lb.ChooseHost(newMockLbContext(nil)); h == nil {
+			t.Fatalf("%s choose host without hash key failed", lbType)
+		}
+	}
+}
+
+func TestConsistentHashUnhealthy(t *testing.T) {
+	for _, lbType := range []types.LoadBalancerType{types.RingHash, types.Maglev} {
+		hs := createHostset(exampleHostConfigs())
+		lb := NewLoadBalancer(lbType, hs)
+		keys := map[uint64]types.Host{}
+		for key := uint64(0); key < 1000; key++ {
+			keys[key] = lb.ChooseHost(newHashLbContext(key))
+		}
+		// set one host unhealthy
+		unhealthy := hs.Hosts()[0]
+		unhealthy.SetHealthFlag(types.FAILED_ACTIVE_HC)
+		hs.refreshHealthHost(unhealthy)
+		for key, host := range keys {
+			h := lb.ChooseHost(newHashLbContext(key))
+			if h == unhealthy {
+				t.Fatalf("%s choose unhealthy host", lbType)
+			}
+			// the keys not mapped to unhealthy host should not be changed
+			if host != unhealthy && h != host {
+				t.Fatalf("%s key %d remapped when another host is unhealthy", lbType, key)
+			}
+		}
+	}
+}
+
+func TestConsistentHashWeight(t *testing.T) {
+	for _, lbType := range []types.LoadBalancerType{types.RingHash, types.Maglev} {
+		weights := map[string]uint32{}
+		for i := 0; i < 4; i++ {
+			weights[fmt.Sprintf("127.0.0.1:%d", 8080+i)] = 1
+		}
+		weights["127.0.0.1:9090"] = 4
+		hs := createWeightedHostset(weights)
+		lb := NewLoadBalancer(lbType, hs)
+		results := map[string]int{}
+		for i := 0; i < 10000; i++ {
+			h := lb.ChooseHost(newHashLbContext(hashKeyWithSeed(fmt.Sprintf("key-%d", i), 0)))
+			results[h.AddressString()]++
+		}
+		// expected 50%
+		if cnt := results["127.0.0.1:9090"]; cnt < 4000 || cnt > 6000 {
+			t.Fatalf("%s weighted host not expected: %v", lbType, results)
+		}
+	}
+}
+
+func TestConsistentHashSubset(t *testing.T) {
+	hs := createHostset(exampleHostConfigs())
+	subsetInfo := NewLBSubsetInfo(exampleSubsetConfig())
+	sublb := newSubsetLoadBalancer(types.Maglev, hs, newClusterStats("test"), subsetInfo)
+	version := "1.1"
+	for key := uint64(0); key < 100; key++ {
+		ctx := newMockLbContext(map[string]string{
+			"version": version,
+		}).(*mockLbContext)
+		k := key
+		ctx.hashKey = &k
+		h := sublb.ChooseHost(ctx)
+		if h == nil || h.Metadata()["version"] != version {
+			t.Fatalf("choose host not expected: %v", h)
+		}
+		if sublb.ChooseHost(ctx) != h {
+			t.Fatal("choose host not consistent")
+		}
+	}
+}
+
+func TestConsistentHashAttemptedHost(t *testing.T) {
+	for _, lbType := range []types.LoadBalancerType{types.RingHash, types.Maglev} {
+		hs := createHostset(exampleHostConfigs())
+		lb := NewLoadBalancer(lbType, hs)
+		for key := uint64(0); key < 100; key++ {
+			first := lb.ChooseHost(newHashLbContext(key))
+			ctx := &mockLbContext{
+				hashKey:        &key,
+				attemptedHosts: []string{first.AddressString()},
+			}
+			if h := lb.ChooseHost(ctx); h == first {
+				t.Fatalf("%s choose attempted host", lbType)
+			}
+		}
+		// all hosts are attempted, fallback to the first healthy host
+		ctx := newHashLbContext(0).(*mockLbContext)
+		for _, h := range hs.Hosts() {
+			ctx.attemptedHosts = append(ctx.attemptedHosts, h.AddressString())
+		}
+		if h := lb.ChooseHost(ctx); h == nil || h != lb.ChooseHost(newHashLbContext(0)) {
+			t.Fatalf("%s should fallback to the first healthy host", lbType)
+		}
+	}
+}
+
+// healthyViewHostSet reports the healthy hosts by its own view rather than the host's health flag,
+// like the host set in healthy panic
+type healthyViewHostSet struct {
+	types.HostSet
+	healthy []types.Host
+}
+
+func (hs *healthyViewHostSet) HealthyHosts() []types.Host {
+	return hs.healthy
+}
+
+func TestConsistentHashHostSetHealthy(t *testing.T) {
+	for _, lbType := range []types.LoadBalancerType{types.RingHash, types.Maglev} {
+		hs := createHostset(exampleHostConfigs())
+		// the host is healthy itself, but not healthy in the host set's view
+		excluded := hs.Hosts()[0]
+		view := &healthyViewHostSet{HostSet: hs, healthy: hs.Hosts()[1:]}
+		lb := NewLoadBalancer(lbType, view)
+		for key := uint64(0); key < 1000; key++ {
+			if h := lb.ChooseHost(newHashLbContext(hashKeyWithSeed(fmt.Sprintf("key-%d", key), 0))); h == nil || h == excluded {
+				t.Fatalf("%s choose host not in the healthy hosts: %v", lbType, h)
+			}
+		}
+		// the unhealthy host is chosen if the host set treats it as healthy
+		hs.Hosts()[1].SetHealthFlag(types.FAILED_ACTIVE_HC)
+		view.healthy = hs.Hosts()
+		found := false
+		for key := uint64(0); key < 1000 && !found; key++ {
+			found = lb.ChooseHost(newHashLbContext(hashKeyWithSeed(fmt.Sprintf("key-%d", key), 0))) == hs.Hosts()[1]
+		}
+		if !found {
+			t.Fatalf("%s never choose the host in the healthy hosts", lbType)
+		}
+		view.healthy = nil
+		if h := lb.ChooseHost(newHashLbContext(0)); h != nil {
+			t.Fatalf("%s expected no host, but got %v", lbType, h)
+		}
+	}
+}
+
+func TestHealthyHostFilterCache(t *testing.T) {
+	hs := createHostset(exampleHostConfigs())
+	unhealthy := hs.Hosts()[0]
+	unhealthy.SetHealthFlag(types.FAILED_ACTIVE_HC)
+	hs.refreshHealthHost(unhealthy)
+	f := newHealthyHostFilter(hs)
+	l := f.get()
+	if l == nil || l.isHealthy(unhealthy) || !l.isHealthy(hs.Hosts()[1]) {
+		t.Fatal("unexpected healthy hosts lookup")
+	}
+	// the lookup is reused until the healthy hosts are changed
+	if f.get() != l {
+		t.Fatal("the healthy hosts lookup is built again")
+	}
+	unhealthy.ClearHealthFlag(types.FAILED_ACTIVE_HC)
+	hs.refreshHealthHost(unhealthy)
+	if l = f.get(); l == nil || !l.isHealthy(unhealthy) {
+		t.Fatal("the healthy hosts lookup is not refreshed")
+	}
+}
+
+func TestConsistentHashHealthyPanic(t *testing.T) {
+	for _, lbType := range []types.LoadBalancerType{types.RingHash, types.Maglev} {
+		hs := createHostset(exampleHostConfigs())
+		stats := newClusterStats(fmt.Sprintf("TestConsistentHashHealthyPanic_%s", lbType))
+		lb := NewLoadBalancer(lbType, newHealthyPanicHostSet(hs, 50, stats))
+		for _, h := range hs.Hosts() {
+			h.SetHealthFlag(types.FAILED_ACTIVE_HC)
+			hs.refreshHealthHost(h)
+		}
+		// no healthy hosts, the hosts are chosen in panic mode
+		for key := uint64(0); key < 100; key++ {
+			if h := lb.ChooseHost(newHashLbContext(hashKeyWithSeed(fmt.Sprintf("key-%d", key), 0))); h == nil {
+				t.Fatalf("%s should choose a host in panic mode", lbType)
+			}
+		}
+		// counts once per host selection
+		if stats.LBHealthyPanic.Count() != 100 {
+			t.Fatalf("%s unexpected healthy panic count: %d", lbType, stats.LBHealthyPanic.Count())
+		}
+	}
+}
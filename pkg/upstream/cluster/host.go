@@ -32,28 +32,38 @@ import (
 
 // simpleHost is an implement of types.Host and types.HostInfo
 type simpleHost struct {
-	hostname      string
-	addressString string
-	clusterInfo   types.ClusterInfo
-	stats         types.HostStats
-	metaData      api.Metadata
-	tlsDisable    bool
-	weight        uint32
-	healthFlags   uint64
+	hostname       string
+	addressString  string
+	clusterInfo    types.ClusterInfo
+	stats          types.HostStats
+	metaData       api.Metadata
+	tlsDisable     bool
+	weight         uint32
+	priority       uint32
+	locality       v2.Locality
+	localityWeight uint32
+	healthFlags    uint64
 }
 
 func NewSimpleHost(config v2.Host, clusterInfo types.ClusterInfo) types.Host {
 	// clusterInfo should not be nil
 	// pre resolve address
 	GetOrCreateAddr(config.Address)
+	var locality v2.Locality
+	if config.Locality != nil {
+		locality = *config.Locality
+	}
 	return &simpleHost{
-		hostname:      config.Hostname,
-		addressString: config.Address,
-		clusterInfo:   clusterInfo,
-		stats:         newHostStats(clusterInfo.Name(), config.Address),
-		metaData:      config.MetaData,
-		tlsDisable:    config.TLSDisable,
-		weight:        config.Weight,
+		hostname:       config.Hostname,
+		addressString:  config.Address,
+		clusterInfo:    clusterInfo,
+		stats:          newHostStats(clusterInfo.Name(), config.Address),
+		metaData:       config.MetaData,
+		tlsDisable:     config.TLSDisable,
+		weight:         config.Weight,
+		priority:       config.Priority,
+		locality:       locality,
+		localityWeight: config.LocalityWeight,
 	}
 }
 
@@ -86,16 +96,35 @@ func (sh *simpleHost) Weight() uint32 {
 	return sh.weight
 }
 
+func (sh *simpleHost) Priority() uint32 {
+	return sh.priority
+}
+
+func (sh *simpleHost) Locality() v2.Locality {
+	return sh.locality
+}
+
+func (sh *simpleHost) LocalityWeight() uint32 {
+	return sh.localityWeight
+}
+
 func (sh *simpleHost) Config() v2.Host {
-	return v2.Host{
+	config := v2.Host{
 		HostConfig: v2.HostConfig{
-			Address:    sh.addressString,
-			Hostname:   sh.hostname,
-			TLSDisable: sh.tlsDisable,
-			Weight:     sh.weight,
+			Address:        sh.addressString,
+			Hostname:       sh.hostname,
+			TLSDisable:     sh.tlsDisable,
+			Weight:         sh.weight,
+			Priority:       sh.priority,
+			LocalityWeight: sh.localityWeight,
 		},
 		MetaData: sh.metaData,
 	}
+	if sh.locality != (v2.Locality{}) {
+		locality := sh.locality
+		config.Locality = &locality
+	}
+	return config
 }
 
 func (sh *simpleHost) SupportTLS() bool {
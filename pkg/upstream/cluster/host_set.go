@@ -18,8 +18,10 @@
 package cluster
 
 import (
+	"sort"
 	"sync"
 
+	v2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/types"
 )
@@ -33,6 +35,7 @@ type hostSet struct {
 	refresh       bool
 	refreshNotify []func(host types.Host)
 	healthyHosts  []types.Host
+	levels        []types.PriorityLevel
 }
 
 // Hosts do not needs lock, becasue it "immutable"
@@ -46,6 +49,11 @@ func (hs *hostSet) HealthyHosts() []types.Host {
 	return hs.healthyHosts
 }
 
+// PriorityLevels do not needs lock, it is created with the hosts
+func (hs *hostSet) PriorityLevels() []types.PriorityLevel {
+	return hs.levels
+}
+
 // refresh notify do not needs lock, the createSubset will not be called in concurrency
 // once the refreshNotify slice have been created, it will not be changed
 func (hs *hostSet) addRefreshNotify(f func(host types.Host)) {
@@ -80,6 +88,13 @@ func (hs *hostSet) refreshHealthHost(host types.Host) {
 }
 
 func (hs *hostSet) createSubset(predicate types.HostPredicate) types.HostSet {
+	sub := hs.newSubset(predicate)
+	sub.levels = createPriorityLevels(sub, sub.createChild)
+	return sub
+}
+
+// newSubset creates a subset without priority levels
+func (hs *hostSet) newSubset(predicate types.HostPredicate) *subHostSet {
 	allHosts := hs.Hosts()
 	var subHosts []types.Host
 	var healthyHosts []types.Host
@@ -118,6 +133,7 @@ func (hs *hostSet) setFinalHost(hosts []types.Host) {
 		}
 		hs.allHosts = allHosts
 		hs.resetHealthyHosts()
+		hs.levels = createPriorityLevels(hs, hs.newSubset)
 		if log.DefaultLogger.GetLogLevel() >= log.INFO {
 			log.DefaultLogger.Infof("[upstream] [host set] update host, final host total: %d", len(hs.allHosts))
 		}
@@ -132,6 +148,9 @@ type subHostSet struct {
 	allHosts     []types.Host
 	healthyHosts []types.Host
 	predicate    types.HostPredicate
+	levels       []types.PriorityLevel
+	// children is the subsets created for the priority levels and localities
+	children []*subHostSet
 }
 
 // Hosts do not need lock, because it is "immutable"
@@ -146,6 +165,10 @@ func (sub *subHostSet) HealthyHosts() []types.Host {
 	return sub.healthyHosts
 }
 
+func (sub *subHostSet) PriorityLevels() []types.PriorityLevel {
+	return sub.levels
+}
+
 func (sub *subHostSet) resetHealthyHosts() {
 	healthyHosts := make([]types.Host, 0, len(sub.allHosts))
 	for _, h := range sub.allHosts {
@@ -163,4 +186,131 @@ func (sub *subHostSet) refresh(host types.Host) {
 		return
 	}
 	sub.resetHealthyHosts()
+	for _, child := range sub.children {
+		child.refresh(host)
+	}
+}
+
+// createChild creates a subset of the subset, the child's healthy states is sync by the subset
+func (sub *subHostSet) createChild(predicate types.HostPredicate) *subHostSet {
+	var subHosts []types.Host
+	var healthyHosts []types.Host
+	for _, h := range sub.allHosts {
+		if predicate(h) {
+			subHosts = append(subHosts, h)
+			if h.Health() {
+				healthyHosts = append(healthyHosts, h)
+			}
+		}
+	}
+	child := &subHostSet{
+		predicate:    predicate,
+		allHosts:     subHosts,
+		healthyHosts: healthyHosts,
+	}
+	sub.children = append(sub.children, child)
+	return child
+}
+
+type localityKey struct {
+	priority uint32
+	locality v2.Locality
+}
+
+// createPriorityLevels groups the hosts by priority and locality, the subsets are created by the create function.
+// If all the hosts have the same priority (or locality), the host set is used directly instead of creating a subset
+func createPriorityLevels(hs types.HostSet, create func(types.HostPredicate) *subHostSet) []types.PriorityLevel {
+	var priorities []uint32
+	localities := map[uint32][]v2.Locality{}
+	weights := map[localityKey]uint32{}
+	for _, h := range hs.Hosts() {
+		priority, locality := h.Priority(), h.Locality()
+		key := localityKey{priority: priority, locality: locality}
+		if _, ok := localities[priority]; !ok {
+			priorities = append(priorities, priority)
+		}
+		if _, ok := weights[key]; !ok {
+			localities[priority] = append(localities[priority], locality)
+		}
+		// the locality weight should be same in a locality, use the max one if not
+		if w := h.LocalityWeight(); w >= weights[key] {
+			weights[key] = w
+		}
+	}
+	sort.Slice(priorities, func(i, j int) bool {
+		return priorities[i] < priorities[j]
+	})
+	levels := make([]types.PriorityLevel, 0, len(priorities))
+	for _, p := range priorities {
+		priority := p
+		level := &priorityLevel{
+			HostSet:  hs,
+			priority: priority,
+		}
+		if len(priorities) > 1 {
+			level.HostSet = create(func(h types.Host) bool {
+				return h.Priority() == priority
+			})
+		}
+		for _, l := range localities[priority] {
+			locality := l
+			ls := &localityHostSet{
+				HostSet:  level.HostSet,
+				locality: locality,
+				weight:   weights[localityKey{priority: priority, locality: locality}],
+			}
+			if len(localities[priority]) > 1 {
+				ls.HostSet = create(func(h types.Host) bool {
+					return h.Priority() == priority && h.Locality() == locality
+				})
+			}
+			ls.levels = []types.PriorityLevel{&priorityLevel{
+				HostSet:    ls,
+				priority:   priority,
+				localities: []types.LocalityHostSet{ls},
+			}}
+			level.localities = append(level.localities, ls)
+		}
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+// priorityLevel is an implementation of types.PriorityLevel
+type priorityLevel struct {
+	types.HostSet
+	priority   uint32
+	localities []types.LocalityHostSet
+}
+
+func (level *priorityLevel) Priority() uint32 {
+	return level.priority
+}
+
+func (level *priorityLevel) Localities() []types.LocalityHostSet {
+	return level.localities
+}
+
+func (level *priorityLevel) PriorityLevels() []types.PriorityLevel {
+	return []types.PriorityLevel{level}
+}
+
+// localityHostSet is an implementation of types.LocalityHostSet
+type localityHostSet struct {
+	types.HostSet
+	locality v2.Locality
+	weight   uint32
+	levels   []types.PriorityLevel
+}
+
+func (ls *localityHostSet) Locality() v2.Locality {
+	return ls.locality
+}
+
+func (ls *localityHostSet) Weight() uint32 {
+	return ls.weight
+}
+
+func (ls *localityHostSet) PriorityLevels() []types.PriorityLevel {
+	return ls.levels
 }
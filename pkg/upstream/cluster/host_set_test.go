@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -117,3 +118,67 @@ func TestHostSetRefresh(t *testing.T) {
 		t.Fatal("health check state changed not expected")
 	}
 }
+
+func TestHostSetPriorityLevels(t *testing.T) {
+	zoneA := v2.Locality{Region: "r", Zone: "a"}
+	zoneB := v2.Locality{Region: "r", Zone: "b"}
+	var hosts []types.Host
+	for i := 0; i < 10; i++ {
+		h := &mockHost{
+			addr:     fmt.Sprintf("127.0.0.1:%d", 10000+i),
+			priority: uint32(i / 6), // 6 hosts in priority 0, 4 hosts in priority 1
+			locality: zoneA,
+			lbWeight: 1,
+		}
+		if i%2 == 1 {
+			h.locality = zoneB
+			h.lbWeight = 3
+		}
+		hosts = append(hosts, h)
+	}
+	hs := &hostSet{}
+	hs.setFinalHost(hosts)
+	levels := hs.PriorityLevels()
+	if len(levels) != 2 ||
+		levels[0].Priority() != 0 || len(levels[0].Hosts()) != 6 ||
+		levels[1].Priority() != 1 || len(levels[1].Hosts()) != 4 {
+		t.Fatalf("unexpected priority levels: %v", levels)
+	}
+	localities := levels[0].Localities()
+	if len(localities) != 2 ||
+		localities[0].Locality() != zoneA || localities[0].Weight() != 1 || len(localities[0].Hosts()) != 3 ||
+		localities[1].Locality() != zoneB || localities[1].Weight() != 3 || len(localities[1].Hosts()) != 3 {
+		t.Fatalf("unexpected localities: %v", localities)
+	}
+	// healthy hosts refresh
+	host := levels[0].Localities()[1].Hosts()[0]
+	host.SetHealthFlag(types.FAILED_ACTIVE_HC)
+	hs.refreshHealthHost(host)
+	if len(hs.HealthyHosts()) != 9 ||
+		len(levels[0].HealthyHosts()) != 5 ||
+		len(levels[0].Localities()[0].HealthyHosts()) != 3 ||
+		len(levels[0].Localities()[1].HealthyHosts()) != 2 ||
+		len(levels[1].HealthyHosts()) != 4 {
+		t.Fatal("health check state changed not expected")
+	}
+	// subset keeps the priority levels
+	sub := hs.createSubset(func(h types.Host) bool {
+		return h.Locality() == zoneB
+	})
+	subLevels := sub.PriorityLevels()
+	if len(subLevels) != 2 || len(subLevels[0].Localities()) != 1 || len(subLevels[0].HealthyHosts()) != 2 {
+		t.Fatalf("unexpected subset priority levels: %v", subLevels)
+	}
+	host.ClearHealthFlag(types.FAILED_ACTIVE_HC)
+	hs.refreshHealthHost(host)
+	if len(subLevels[0].HealthyHosts()) != 3 || len(subLevels[0].Localities()[0].HealthyHosts()) != 3 {
+		t.Fatal("subset health check state changed not expected")
+	}
+	// the hosts without priority and locality have only one level
+	single := &hostSet{}
+	single.setFinalHost(makePool(3).MakeHosts(3, nil))
+	if levels := single.PriorityLevels(); len(levels) != 1 || len(levels[0].Localities()) != 1 ||
+		len(levels[0].Localities()[0].Hosts()) != 3 {
+		t.Fatal("unexpected priority levels for single level")
+	}
+}
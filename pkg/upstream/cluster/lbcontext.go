@@ -0,0 +1,65 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"net"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/types"
+)
+
+// contextLoadBalancerContext is the load balancer context of the requests that not proxied from a downstream,
+// such as the calls to the rate limit service or the trace collector. Only the context is carried
+type contextLoadBalancerContext struct {
+	ctx context.Context
+}
+
+// NewLoadBalancerContext returns a types.LoadBalancerContext that only carries the context
+func NewLoadBalancerContext(ctx context.Context) types.LoadBalancerContext {
+	return &contextLoadBalancerContext{ctx: ctx}
+}
+
+func (c *contextLoadBalancerContext) MetadataMatchCriteria() api.MetadataMatchCriteria {
+	return nil
+}
+
+func (c *contextLoadBalancerContext) DownstreamConnection() net.Conn {
+	return nil
+}
+
+func (c *contextLoadBalancerContext) DownstreamHeaders() api.HeaderMap {
+	return nil
+}
+
+func (c *contextLoadBalancerContext) DownstreamContext() context.Context {
+	return c.ctx
+}
+
+func (c *contextLoadBalancerContext) DownstreamCluster() types.ClusterInfo {
+	return nil
+}
+
+func (c *contextLoadBalancerContext) HashKey() (uint64, bool) {
+	return 0, false
+}
+
+func (c *contextLoadBalancerContext) ShouldSelectAnotherHost(host types.Host) bool {
+	return false
+}
@@ -0,0 +1,45 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestNewLoadBalancerContext(t *testing.T) {
+	ctx := mosnctx.WithValue(context.Background(), types.ContextKeyStreamID, uint64(1))
+	lbCtx := NewLoadBalancerContext(ctx)
+	if lbCtx.DownstreamContext() != ctx {
+		t.Fatal("the context is not carried")
+	}
+	if lbCtx.MetadataMatchCriteria() != nil || lbCtx.DownstreamHeaders() != nil || lbCtx.DownstreamConnection() != nil {
+		t.Fatal("the downstream values should be nil")
+	}
+	if _, ok := lbCtx.HashKey(); ok {
+		t.Fatal("the hash key should not be set")
+	}
+	// chooses the host by the load balancer
+	hs := createHostset(exampleHostConfigs())
+	if h := NewLoadBalancer(types.RoundRobin, hs).ChooseHost(lbCtx); h == nil {
+		t.Fatal("choose host failed")
+	}
+}
@@ -45,13 +45,24 @@ func init() {
 	}
 	RegisterLBType(types.RoundRobin, rrFactory.newRoundRobinLoadBalancer)
 	RegisterLBType(types.Random, newRandomLoadBalancer)
+	RegisterLBType(types.WeightedRoundRobin, newWeightedRoundRobinLoadBalancer)
+	RegisterLBType(types.LeastActiveRequest, newLeastActiveRequestLoadBalancer)
 }
 
 func NewLoadBalancer(lbType types.LoadBalancerType, hosts types.HostSet) types.LoadBalancer {
+	var lb types.LoadBalancer
 	if f, ok := lbFactories[lbType]; ok {
-		return f(hosts)
+		lb = f(hosts)
+	} else {
+		lb = rrFactory.newRoundRobinLoadBalancer(hosts)
 	}
-	return rrFactory.newRoundRobinLoadBalancer(hosts)
+	if hs, ok := hosts.(*healthyPanicHostSet); ok {
+		return &healthyPanicLoadBalancer{
+			LoadBalancer: lb,
+			hosts:        hs,
+		}
+	}
+	return lb
 }
 
 // LoadBalancer Implementations
@@ -129,5 +140,175 @@ func (lb *roundRobinLoadBalancer) HostNum(metadata api.MetadataMatchCriteria) in
 	return len(lb.hosts.Hosts())
 }
 
-// TODO:
-// WRR
+// healthyPanicHostSet wraps the host set used by the load balancer.
+// If the percent of healthy hosts is lower than the threshold, the load balancer is in panic mode:
+// all hosts are treated as healthy, so the requests are spread across all hosts
+// rather than overloading the few healthy hosts or failing when no hosts are healthy
+type healthyPanicHostSet struct {
+	types.HostSet
+	threshold uint64
+	stats     types.ClusterStats
+}
+
+// newHealthyPanicHostSet returns the host set itself if the threshold is zero
+func newHealthyPanicHostSet(hosts types.HostSet, threshold uint32, stats types.ClusterStats) types.HostSet {
+	if threshold == 0 {
+		return hosts
+	}
+	if threshold > 100 {
+		threshold = 100
+	}
+	return &healthyPanicHostSet{
+		HostSet:   hosts,
+		threshold: uint64(threshold),
+		stats:     stats,
+	}
+}
+
+func (hs *healthyPanicHostSet) HealthyHosts() []types.Host {
+	healthyHosts := hs.HostSet.HealthyHosts()
+	allHosts := hs.HostSet.Hosts()
+	if hs.panic(len(healthyHosts), len(allHosts)) {
+		return allHosts
+	}
+	return healthyHosts
+}
+
+func (hs *healthyPanicHostSet) panic(healthy, total int) bool {
+	return uint64(healthy)*100 < hs.threshold*uint64(total)
+}
+
+func (hs *healthyPanicHostSet) inPanic() bool {
+	return hs.panic(len(hs.HostSet.HealthyHosts()), len(hs.HostSet.Hosts()))
+}
+
+// healthyPanicLoadBalancer counts the healthy panic once per host selection,
+// the load balancer may get the healthy hosts more than once in a selection
+type healthyPanicLoadBalancer struct {
+	types.LoadBalancer
+	hosts *healthyPanicHostSet
+}
+
+func (lb *healthyPanicLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	if lb.hosts.inPanic() {
+		lb.hosts.stats.LBHealthyPanic.Inc(1)
+	}
+	return lb.LoadBalancer.ChooseHost(context)
+}
+
+// hostWeight returns the host's weight used by load balancers,
+// a zero weight is treated as the minimal weight 1
+func hostWeight(host types.Host) int64 {
+	if w := host.Weight(); w > 0 {
+		return int64(w)
+	}
+	return 1
+}
+
+// shouldSelectAnotherHost checks whether the host is rejected by the load balancer context
+func shouldSelectAnotherHost(context types.LoadBalancerContext, host types.Host) bool {
+	if context == nil {
+		return false
+	}
+	return context.ShouldSelectAnotherHost(host)
+}
+
+// weightedRoundRobinLoadBalancer is a smooth weighted round robin load balancer.
+// For hosts with weights {a:5, b:1, c:1}, the sequence is {a, a, b, a, c, a, a}
+// rather than {a, a, a, a, a, b, c}
+type weightedRoundRobinLoadBalancer struct {
+	mutex   sync.Mutex
+	hosts   types.HostSet
+	current map[string]int64 // host address -> current weight
+}
+
+func newWeightedRoundRobinLoadBalancer(hosts types.HostSet) types.LoadBalancer {
+	return &weightedRoundRobinLoadBalancer{
+		hosts:   hosts,
+		current: make(map[string]int64, len(hosts.Hosts())),
+	}
+}
+
+func (lb *weightedRoundRobinLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	targets := lb.hosts.HealthyHosts()
+	if len(targets) == 0 {
+		return nil
+	}
+	if len(targets) == 1 {
+		return targets[0]
+	}
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+	var selected types.Host
+	var selectedWeight, total int64
+	for _, h := range targets {
+		weight := hostWeight(h)
+		addr := h.AddressString()
+		current := lb.current[addr] + weight
+		lb.current[addr] = current
+		total += weight
+		if selected == nil || current > selectedWeight {
+			selected = h
+			selectedWeight = current
+		}
+	}
+	lb.current[selected.AddressString()] = selectedWeight - total
+	return selected
+}
+
+func (lb *weightedRoundRobinLoadBalancer) IsExistsHosts(metadata api.MetadataMatchCriteria) bool {
+	return len(lb.hosts.Hosts()) > 0
+}
+
+func (lb *weightedRoundRobinLoadBalancer) HostNum(metadata api.MetadataMatchCriteria) int {
+	return len(lb.hosts.Hosts())
+}
+
+// leastActiveRequestLoadBalancer uses the power of two choices algorithm:
+// choose two hosts randomly and picks the one with fewer active requests.
+// If the hosts' weights are different, the active requests count is scaled by the weight.
+type leastActiveRequestLoadBalancer struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+	hosts types.HostSet
+}
+
+func newLeastActiveRequestLoadBalancer(hosts types.HostSet) types.LoadBalancer {
+	return &leastActiveRequestLoadBalancer{
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		hosts: hosts,
+	}
+}
+
+func (lb *leastActiveRequestLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	targets := lb.hosts.HealthyHosts()
+	total := len(targets)
+	if total == 0 {
+		return nil
+	}
+	if total == 1 {
+		return targets[0]
+	}
+	lb.mutex.Lock()
+	first := lb.rand.Intn(total)
+	second := lb.rand.Intn(total - 1)
+	lb.mutex.Unlock()
+	// make sure two different hosts are chosen
+	if second >= first {
+		second++
+	}
+	h1, h2 := targets[first], targets[second]
+	// compare active1/weight1 with active2/weight2
+	if h1.HostStats().UpstreamRequestActive.Count()*hostWeight(h2) <= h2.HostStats().UpstreamRequestActive.Count()*hostWeight(h1) {
+		return h1
+	}
+	return h2
+}
+
+func (lb *leastActiveRequestLoadBalancer) IsExistsHosts(metadata api.MetadataMatchCriteria) bool {
+	return len(lb.hosts.Hosts()) > 0
+}
+
+func (lb *leastActiveRequestLoadBalancer) HostNum(metadata api.MetadataMatchCriteria) int {
+	return len(lb.hosts.Hosts())
+}
@@ -0,0 +1,233 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+func createWeightedHostset(weights map[string]uint32) *hostSet {
+	var hosts []types.Host
+	for addr, w := range weights {
+		hosts = append(hosts, &mockHost{
+			name:   addr,
+			addr:   addr,
+			weight: w,
+			stats:  newHostStats("test", addr),
+		})
+	}
+	hs := &hostSet{}
+	hs.setFinalHost(hosts)
+	return hs
+}
+
+func TestWeightedRoundRobinLoadBalancer(t *testing.T) {
+	hs := createWeightedHostset(map[string]uint32{
+		"127.0.0.1:8080": 5,
+		"127.0.0.1:8081": 95,
+	})
+	lb := NewLoadBalancer(types.WeightedRoundRobin, hs)
+	if _, ok := lb.(*weightedRoundRobinLoadBalancer); !ok {
+		t.Fatal("load balancer created not expected")
+	}
+	results := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		h := lb.ChooseHost(nil)
+		results[h.AddressString()]++
+	}
+	if results["127.0.0.1:8080"] != 50 || results["127.0.0.1:8081"] != 950 {
+		t.Fatalf("weighted round robin not expected: %v", results)
+	}
+}
+
+func TestWeightedRoundRobinSmooth(t *testing.T) {
+	hs := createWeightedHostset(map[string]uint32{
+		"a": 5,
+		"b": 1,
+		"c": 1,
+	})
+	lb := newWeightedRoundRobinLoadBalancer(hs)
+	// the heavy host should never be chosen more than 5 times in a row
+	// and each round contains 7 choices
+	for round := 0; round < 10; round++ {
+		results := map[string]int{}
+		for i := 0; i < 7; i++ {
+			results[lb.ChooseHost(nil).AddressString()]++
+		}
+		if results["a"] != 5 || results["b"] != 1 || results["c"] != 1 {
+			t.Fatalf("round %d not expected: %v", round, results)
+		}
+	}
+}
+
+func TestWeightedRoundRobinUnhealthy(t *testing.T) {
+	hs := createWeightedHostset(map[string]uint32{
+		"a": 5,
+		"b": 1,
+	})
+	lb := newWeightedRoundRobinLoadBalancer(hs)
+	for _, h := range hs.Hosts() {
+		if h.AddressString() == "a" {
+			h.SetHealthFlag(types.FAILED_ACTIVE_HC)
+			hs.refreshHealthHost(h)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if h := lb.ChooseHost(nil); h.AddressString() != "b" {
+			t.Fatalf("choose unhealthy host: %s", h.AddressString())
+		}
+	}
+}
+
+func TestLeastActiveRequestLoadBalancer(t *testing.T) {
+	weights := map[string]uint32{}
+	for i := 0; i < 5; i++ {
+		weights[fmt.Sprintf("127.0.0.1:%d", 8080+i)] = 1
+	}
+	hs := createWeightedHostset(weights)
+	lb := NewLoadBalancer(types.LeastActiveRequest, hs)
+	if _, ok := lb.(*leastActiveRequestLoadBalancer); !ok {
+		t.Fatal("load balancer created not expected")
+	}
+	// all hosts have active requests except one
+	var idle types.Host
+	for _, h := range hs.Hosts() {
+		if h.AddressString() == "127.0.0.1:8082" {
+			idle = h
+			continue
+		}
+		h.HostStats().UpstreamRequestActive.Inc(10)
+	}
+	// idle host should be always chosen when compared, so it takes more than a random share
+	count := 0
+	for i := 0; i < 1000; i++ {
+		if lb.ChooseHost(nil) == idle {
+			count++
+		}
+	}
+	// each choice compares 2 of 5 hosts, idle host is chosen with probability 2/5
+	if count < 300 {
+		t.Fatalf("least request load balancer not expected, idle host chosen %d times", count)
+	}
+}
+
+func TestLeastActiveRequestSubset(t *testing.T) {
+	hs := createHostset(exampleHostConfigs())
+	for _, h := range hs.Hosts() {
+		h.(*mockHost).stats = newHostStats("test", h.AddressString())
+	}
+	subsetInfo := NewLBSubsetInfo(exampleSubsetConfig())
+	sublb := newSubsetLoadBalancer(types.LeastActiveRequest, hs, newClusterStats("test"), subsetInfo)
+	ctx := newMockLbContext(map[string]string{
+		"stage":   "prod",
+		"version": "1.0",
+	})
+	for i := 0; i < 100; i++ {
+		h := sublb.ChooseHost(ctx)
+		if h == nil {
+			t.Fatal("choose host failed")
+		}
+		if v := h.Metadata()["version"]; v != "1.0" {
+			t.Fatalf("choose host not in subset: %v", h.Metadata())
+		}
+	}
+}
+
+func TestChooseHostAvoidAttemptedHost(t *testing.T) {
+	// random load balancers may choose the attempted host in all attempts, so only test the round robin ones
+	for _, lbType := range []types.LoadBalancerType{types.RoundRobin, types.WeightedRoundRobin} {
+		hs := createWeightedHostset(map[string]uint32{
+			"127.0.0.1:8080": 1,
+			"127.0.0.1:8081": 1,
+		})
+		snapshot := &clusterSnapshot{
+			hostSet: hs,
+			lb:      NewLoadBalancer(lbType, hs),
+		}
+		ctx := &mockLbContext{
+			attemptedHosts: []string{"127.0.0.1:8080"},
+		}
+		for i := 0; i < 10; i++ {
+			if h := chooseHost(ctx, snapshot); h == nil || h.AddressString() != "127.0.0.1:8081" {
+				t.Fatalf("%s choose attempted host", lbType)
+			}
+		}
+		// no other host can be chosen
+		ctx.attemptedHosts = append(ctx.attemptedHosts, "127.0.0.1:8081")
+		if h := chooseHost(ctx, snapshot); h == nil {
+			t.Fatalf("%s should fallback to an attempted host", lbType)
+		}
+	}
+}
+
+func TestHealthyPanicLoadBalancer(t *testing.T) {
+	for _, lbType := range []types.LoadBalancerType{types.RoundRobin, types.Random} {
+		weights := map[string]uint32{}
+		for i := 0; i < 10; i++ {
+			weights[fmt.Sprintf("127.0.0.1:%d", 8080+i)] = 1
+		}
+		hs := createWeightedHostset(weights)
+		stats := newClusterStats(fmt.Sprintf("TestHealthyPanicLoadBalancer_%s", lbType))
+		lb := NewLoadBalancer(lbType, newHealthyPanicHostSet(hs, 50, stats))
+		// 50% healthy, not in panic mode
+		for _, h := range hs.Hosts()[:5] {
+			h.SetHealthFlag(types.FAILED_ACTIVE_HC)
+			hs.refreshHealthHost(h)
+		}
+		for i := 0; i < 100; i++ {
+			if h := lb.ChooseHost(nil); h == nil || !h.Health() {
+				t.Fatalf("%s should choose healthy host when not in panic mode", lbType)
+			}
+		}
+		if stats.LBHealthyPanic.Count() != 0 {
+			t.Fatalf("%s healthy panic stats not expected", lbType)
+		}
+		// 40% healthy, spreads across all hosts
+		h := hs.Hosts()[5]
+		h.SetHealthFlag(types.FAILED_ACTIVE_HC)
+		hs.refreshHealthHost(h)
+		unhealthy := 0
+		for i := 0; i < 100; i++ {
+			h := lb.ChooseHost(nil)
+			if h == nil {
+				t.Fatalf("%s choose host failed in panic mode", lbType)
+			}
+			if !h.Health() {
+				unhealthy++
+			}
+		}
+		if unhealthy == 0 || stats.LBHealthyPanic.Count() != 100 {
+			t.Fatalf("%s should spread across all hosts in panic mode, unhealthy: %d, panic: %d", lbType, unhealthy, stats.LBHealthyPanic.Count())
+		}
+		// no healthy hosts, still returns a host in panic mode
+		for _, h := range hs.Hosts() {
+			h.SetHealthFlag(types.FAILED_ACTIVE_HC)
+			hs.refreshHealthHost(h)
+		}
+		if h := lb.ChooseHost(nil); h == nil {
+			t.Fatalf("%s should choose a host when no hosts are healthy in panic mode", lbType)
+		}
+		// panic mode disabled
+		if h := NewLoadBalancer(lbType, newHealthyPanicHostSet(hs, 0, stats)).ChooseHost(nil); h != nil {
+			t.Fatalf("%s should not choose a host when no hosts are healthy without panic mode", lbType)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+)
+
+const defaultOverprovisioningFactor = 140
+
+// newClusterLoadBalancer creates the cluster's load balancer.
+// If the hosts have more than one priority levels or localities, a locality load balancer is used,
+// otherwise the load balancer is created by the cluster's lb type directly
+func newClusterLoadBalancer(info *clusterInfo, hosts types.HostSet) types.LoadBalancer {
+	levels := hosts.PriorityLevels()
+	if len(levels) > 1 || (len(levels) == 1 && len(levels[0].Localities()) > 1) {
+		return newLocalityLoadBalancer(info, types.GetGlobalXdsInfo().Locality, hosts)
+	}
+	return NewLoadBalancer(info.lbType, newHealthyPanicHostSet(hosts, info.healthyPanicThreshold, info.stats))
+}
+
+// localityLoadBalancer chooses a priority level first, and then chooses a locality in the level,
+// the host is chosen by the locality's load balancer.
+// The traffic is sent to the highest priority level, and fails over to the next level when the level's health
+// is lower than 100, the health is min(100, healthy_percent * overprovisioning_factor / 100).
+// In a priority level, the localities in the same zone as the local locality is preferred, the traffic is sent to
+// other localities only if the local localities' health is lower than 100. The other localities are chosen by the
+// locality weight multiplied by the locality's health.
+type localityLoadBalancer struct {
+	mutex                  sync.Mutex
+	rand                   *rand.Rand
+	hosts                  types.HostSet
+	overprovisioningFactor uint64
+	levels                 []*localityLevel
+}
+
+type localityLevel struct {
+	localities []*localityEntry
+	hasLocal   bool
+}
+
+type localityEntry struct {
+	hosts  types.LocalityHostSet
+	lb     types.LoadBalancer
+	weight uint64
+	local  bool
+}
+
+// newLocalityLoadBalancer creates the locality load balancer, the healthy panic is checked in each locality
+func newLocalityLoadBalancer(info *clusterInfo, local v2.Locality, hosts types.HostSet) types.LoadBalancer {
+	factor := info.overprovisioningFactor
+	if factor == 0 {
+		factor = defaultOverprovisioningFactor
+	}
+	lb := &localityLoadBalancer{
+		rand:                   rand.New(rand.NewSource(time.Now().UnixNano())),
+		hosts:                  hosts,
+		overprovisioningFactor: uint64(factor),
+	}
+	for _, level := range hosts.PriorityLevels() {
+		l := &localityLevel{}
+		for _, ls := range level.Localities() {
+			locality := ls.Locality()
+			entry := &localityEntry{
+				hosts:  ls,
+				lb:     NewLoadBalancer(info.lbType, newHealthyPanicHostSet(ls, info.healthyPanicThreshold, info.stats)),
+				weight: uint64(ls.Weight()),
+				local:  local.Zone != "" && locality.Region == local.Region && locality.Zone == local.Zone,
+			}
+			// a zero weight is treated as the minimal weight 1
+			if entry.weight == 0 {
+				entry.weight = 1
+			}
+			if entry.local {
+				l.hasLocal = true
+			}
+			l.localities = append(l.localities, entry)
+		}
+		lb.levels = append(lb.levels, l)
+	}
+	return lb
+}
+
+func (lb *localityLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	level := lb.chooseLevel()
+	if level == nil {
+		return nil
+	}
+	return lb.chooseLocality(level).lb.ChooseHost(context)
+}
+
+func (lb *localityLoadBalancer) IsExistsHosts(metadata api.MetadataMatchCriteria) bool {
+	return len(lb.hosts.Hosts()) > 0
+}
+
+func (lb *localityLoadBalancer) HostNum(metadata api.MetadataMatchCriteria) int {
+	return len(lb.hosts.Hosts())
+}
+
+func (lb *localityLoadBalancer) intn(n uint64) uint64 {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+	return uint64(lb.rand.Int63n(int64(n)))
+}
+
+// health returns min(100, healthy_percent * overprovisioning_factor / 100)
+func (lb *localityLoadBalancer) health(healthy, total int) uint64 {
+	if total == 0 {
+		return 0
+	}
+	h := uint64(healthy) * lb.overprovisioningFactor / uint64(total)
+	if h > 100 {
+		return 100
+	}
+	return h
+}
+
+func (lb *localityLoadBalancer) levelHealth(level *localityLevel) uint64 {
+	var healthy, total int
+	for _, entry := range level.localities {
+		healthy += len(entry.hosts.HealthyHosts())
+		total += len(entry.hosts.Hosts())
+	}
+	return lb.health(healthy, total)
+}
+
+// chooseLevel chooses a priority level by the priority load.
+// The load of a level is the level's health, until the total load reaches 100.
+// If the total health is lower than 100, the loads are scaled up in proportion
+func (lb *localityLoadBalancer) chooseLevel() *localityLevel {
+	if len(lb.levels) == 0 {
+		return nil
+	}
+	if len(lb.levels) == 1 {
+		return lb.levels[0]
+	}
+	var total uint64
+	for _, level := range lb.levels {
+		total += lb.levelHealth(level)
+		if total >= 100 {
+			total = 100
+			break
+		}
+	}
+	// no healthy hosts, the highest priority level is used
+	if total == 0 {
+		return lb.levels[0]
+	}
+	r := lb.intn(total)
+	for _, level := range lb.levels {
+		load := lb.levelHealth(level)
+		if r < load {
+			return level
+		}
+		r -= load
+	}
+	// the health is changed during the choosing
+	return lb.levels[0]
+}
+
+// chooseLocality chooses a locality in the priority level, the local localities are preferred
+func (lb *localityLoadBalancer) chooseLocality(level *localityLevel) *localityEntry {
+	if len(level.localities) == 1 {
+		return level.localities[0]
+	}
+	if level.hasLocal {
+		if lb.intn(100) < lb.localHealth(level) {
+			if entry := lb.chooseByWeight(level, isLocalLocality); entry != nil {
+				return entry
+			}
+		}
+		if entry := lb.chooseByWeight(level, isRemoteLocality); entry != nil {
+			return entry
+		}
+	}
+	if entry := lb.chooseByWeight(level, nil); entry != nil {
+		return entry
+	}
+	// no healthy hosts in the level
+	return level.localities[0]
+}
+
+func (lb *localityLoadBalancer) localHealth(level *localityLevel) uint64 {
+	var healthy, total int
+	for _, entry := range level.localities {
+		if entry.local {
+			healthy += len(entry.hosts.HealthyHosts())
+			total += len(entry.hosts.Hosts())
+		}
+	}
+	return lb.health(healthy, total)
+}
+
+func isLocalLocality(entry *localityEntry) bool {
+	return entry.local
+}
+
+func isRemoteLocality(entry *localityEntry) bool {
+	return !entry.local
+}
+
+// chooseByWeight chooses a locality that matches the filter by the effective weight,
+// the effective weight is the locality weight multiplied by the locality's health.
+// returns nil if no locality's effective weight is greater than zero
+func (lb *localityLoadBalancer) chooseByWeight(level *localityLevel, filter func(*localityEntry) bool) *localityEntry {
+	var total uint64
+	for _, entry := range level.localities {
+		if filter == nil || filter(entry) {
+			total += lb.effectiveWeight(entry)
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	r := lb.intn(total)
+	for _, entry := range level.localities {
+		if filter == nil || filter(entry) {
+			w := lb.effectiveWeight(entry)
+			if r < w {
+				return entry
+			}
+			r -= w
+		}
+	}
+	return nil
+}
+
+func (lb *localityLoadBalancer) effectiveWeight(entry *localityEntry) uint64 {
+	return entry.weight * lb.health(len(entry.hosts.HealthyHosts()), len(entry.hosts.Hosts()))
+}
@@ -0,0 +1,244 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+)
+
+type localityHostsConfig struct {
+	priority uint32
+	locality v2.Locality
+	weight   uint32
+	size     int
+}
+
+func newLocalityHostSet(configs []localityHostsConfig) *hostSet {
+	var hosts []types.Host
+	port := 10000
+	for _, cfg := range configs {
+		for i := 0; i < cfg.size; i++ {
+			hosts = append(hosts, &mockHost{
+				addr:     fmt.Sprintf("127.0.0.1:%d", port),
+				priority: cfg.priority,
+				locality: cfg.locality,
+				lbWeight: cfg.weight,
+			})
+			port++
+		}
+	}
+	hs := &hostSet{}
+	hs.setFinalHost(hosts)
+	return hs
+}
+
+func newLocalityClusterInfo(lbType types.LoadBalancerType, factor uint32, threshold uint32) *clusterInfo {
+	return &clusterInfo{
+		name:                   "locality",
+		lbType:                 lbType,
+		overprovisioningFactor: factor,
+		healthyPanicThreshold:  threshold,
+	}
+}
+
+// setUnhealthy makes count healthy hosts that match the predicate unhealthy
+func setUnhealthy(hs *hostSet, predicate types.HostPredicate, count int) {
+	for _, h := range hs.Hosts() {
+		if count == 0 {
+			return
+		}
+		if h.Health() && predicate(h) {
+			h.SetHealthFlag(types.FAILED_ACTIVE_HC)
+			hs.refreshHealthHost(h)
+			count--
+		}
+	}
+}
+
+// chooseCount chooses hosts and counts the result by the key function
+func chooseCount(lb types.LoadBalancer, times int, key func(types.Host) string) map[string]int {
+	result := map[string]int{}
+	for i := 0; i < times; i++ {
+		h := lb.ChooseHost(nil)
+		if h == nil {
+			result["nil"]++
+			continue
+		}
+		result[key(h)]++
+	}
+	return result
+}
+
+func hostPriority(h types.Host) string {
+	return fmt.Sprintf("p%d", h.Priority())
+}
+
+func hostZone(h types.Host) string {
+	return h.Locality().Zone
+}
+
+func TestLocalityLoadBalancerPriorityFailover(t *testing.T) {
+	hs := newLocalityHostSet([]localityHostsConfig{
+		{priority: 0, size: 10},
+		{priority: 1, size: 10},
+	})
+	lb := newLocalityLoadBalancer(newLocalityClusterInfo(types.RoundRobin, 0, 0), v2.Locality{}, hs)
+	// all traffic goes to the highest priority
+	result := chooseCount(lb, 1000, hostPriority)
+	if result["p0"] != 1000 {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	// 8 healthy hosts in priority 0, the health is 8 * 1.4 / 10 = 100
+	isP0 := func(h types.Host) bool { return h.Priority() == 0 }
+	setUnhealthy(hs, isP0, 2)
+	result = chooseCount(lb, 1000, hostPriority)
+	if result["p0"] != 1000 {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	// 5 healthy hosts in priority 0, the health is 5 * 1.4 / 10 = 70
+	setUnhealthy(hs, isP0, 3)
+	result = chooseCount(lb, 10000, hostPriority)
+	if result["p0"] < 6500 || result["p0"] > 7500 || result["p0"]+result["p1"] != 10000 {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	// all hosts in priority 0 are unhealthy
+	setUnhealthy(hs, isP0, 5)
+	result = chooseCount(lb, 1000, hostPriority)
+	if result["p1"] != 1000 {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	// all hosts are unhealthy
+	setUnhealthy(hs, func(h types.Host) bool { return true }, 10)
+	if h := lb.ChooseHost(nil); h != nil {
+		t.Fatalf("expected no host, but got %s", h.AddressString())
+	}
+}
+
+func TestLocalityLoadBalancerHealthyPanic(t *testing.T) {
+	hs := newLocalityHostSet([]localityHostsConfig{
+		{priority: 0, size: 10},
+		{priority: 1, size: 10},
+	})
+	info := newLocalityClusterInfo(types.RoundRobin, 0, 50)
+	info.stats = newClusterStats("TestLocalityLoadBalancerHealthyPanic")
+	lb := newLocalityLoadBalancer(info, v2.Locality{}, hs)
+	// all hosts in priority 0 are unhealthy, the traffic goes to priority 1 without panic
+	setUnhealthy(hs, func(h types.Host) bool { return h.Priority() == 0 }, 10)
+	result := chooseCount(lb, 100, hostPriority)
+	if result["p1"] != 100 || info.stats.LBHealthyPanic.Count() != 0 {
+		t.Fatalf("unexpected result: %v, panic: %d", result, info.stats.LBHealthyPanic.Count())
+	}
+	// all hosts are unhealthy, the highest priority is used in panic mode
+	setUnhealthy(hs, func(h types.Host) bool { return true }, 10)
+	result = chooseCount(lb, 100, hostPriority)
+	if result["p0"] != 100 || info.stats.LBHealthyPanic.Count() != 100 {
+		t.Fatalf("unexpected result: %v, panic: %d", result, info.stats.LBHealthyPanic.Count())
+	}
+}
+
+func TestLocalityLoadBalancerPriorityNormalized(t *testing.T) {
+	hs := newLocalityHostSet([]localityHostsConfig{
+		{priority: 0, size: 10},
+		{priority: 1, size: 10},
+	})
+	// overprovisioning factor 100, the health is the healthy percent
+	lb := newLocalityLoadBalancer(newLocalityClusterInfo(types.Random, 100, 0), v2.Locality{}, hs)
+	setUnhealthy(hs, func(h types.Host) bool { return h.Priority() == 0 }, 8)
+	setUnhealthy(hs, func(h types.Host) bool { return h.Priority() == 1 }, 8)
+	// both health are 20, the load is normalized to 50 / 50
+	result := chooseCount(lb, 10000, hostPriority)
+	if result["p0"] < 4500 || result["p0"] > 5500 || result["p0"]+result["p1"] != 10000 {
+		t.Fatalf("unexpected result: %v", result)
+	}
+}
+
+func TestLocalityLoadBalancerWeight(t *testing.T) {
+	zoneA := v2.Locality{Region: "r", Zone: "a"}
+	zoneB := v2.Locality{Region: "r", Zone: "b"}
+	hs := newLocalityHostSet([]localityHostsConfig{
+		{locality: zoneA, weight: 1, size: 10},
+		{locality: zoneB, weight: 3, size: 10},
+	})
+	lb := newLocalityLoadBalancer(newLocalityClusterInfo(types.RoundRobin, 0, 0), v2.Locality{}, hs)
+	result := chooseCount(lb, 10000, hostZone)
+	if result["a"] < 2000 || result["a"] > 3000 || result["a"]+result["b"] != 10000 {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	// the effective weight of zone b is 3 * 42 = 126, zone a is 1 * 100
+	setUnhealthy(hs, func(h types.Host) bool { return h.Locality() == zoneB }, 7)
+	result = chooseCount(lb, 10000, hostZone)
+	if result["a"] < 3900 || result["a"] > 5000 || result["a"]+result["b"] != 10000 {
+		t.Fatalf("unexpected result: %v", result)
+	}
+}
+
+func TestLocalityLoadBalancerZoneAware(t *testing.T) {
+	zoneA := v2.Locality{Region: "r", Zone: "a"}
+	zoneB := v2.Locality{Region: "r", Zone: "b"}
+	local := v2.Locality{Region: "r", Zone: "a", SubZone: "s"}
+	hs := newLocalityHostSet([]localityHostsConfig{
+		{locality: zoneA, weight: 1, size: 10},
+		{locality: zoneB, weight: 3, size: 10},
+	})
+	lb := newLocalityLoadBalancer(newLocalityClusterInfo(types.RoundRobin, 0, 0), local, hs)
+	// all traffic goes to the local zone
+	result := chooseCount(lb, 1000, hostZone)
+	if result["a"] != 1000 {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	// 5 healthy hosts in the local zone, the health is 70
+	isZoneA := func(h types.Host) bool { return h.Locality() == zoneA }
+	setUnhealthy(hs, isZoneA, 5)
+	result = chooseCount(lb, 10000, hostZone)
+	if result["a"] < 6500 || result["a"] > 7500 || result["a"]+result["b"] != 10000 {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	// the other zones are unhealthy, the local zone is used
+	setUnhealthy(hs, func(h types.Host) bool { return h.Locality() == zoneB }, 10)
+	result = chooseCount(lb, 1000, hostZone)
+	if result["a"] != 1000 {
+		t.Fatalf("unexpected result: %v", result)
+	}
+}
+
+func TestNewClusterLoadBalancer(t *testing.T) {
+	info := &clusterInfo{
+		lbType: types.RoundRobin,
+	}
+	single := newLocalityHostSet([]localityHostsConfig{
+		{priority: 1, locality: v2.Locality{Zone: "a"}, size: 3},
+	})
+	if _, ok := newClusterLoadBalancer(info, single).(*roundRobinLoadBalancer); !ok {
+		t.Fatal("single priority level and locality should use the lb type directly")
+	}
+	multi := newLocalityHostSet([]localityHostsConfig{
+		{priority: 0, size: 3},
+		{priority: 1, size: 3},
+	})
+	lb, ok := newClusterLoadBalancer(info, multi).(*localityLoadBalancer)
+	if !ok {
+		t.Fatal("multiple priority levels should use the locality load balancer")
+	}
+	if lb.overprovisioningFactor != defaultOverprovisioningFactor || !lb.IsExistsHosts(nil) || lb.HostNum(nil) != 6 {
+		t.Fatal("unexpected locality load balancer")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/network"
 	"mosn.io/mosn/pkg/router"
 	"mosn.io/mosn/pkg/types"
@@ -32,6 +33,11 @@ type mockHost struct {
 	addr       string
 	meta       api.Metadata
 	healthFlag uint64
+	weight     uint32
+	stats      types.HostStats
+	priority   uint32
+	locality   v2.Locality
+	lbWeight   uint32 // locality weight
 	types.Host
 }
 
@@ -47,6 +53,26 @@ func (h *mockHost) Metadata() api.Metadata {
 	return h.meta
 }
 
+func (h *mockHost) Weight() uint32 {
+	return h.weight
+}
+
+func (h *mockHost) Priority() uint32 {
+	return h.priority
+}
+
+func (h *mockHost) Locality() v2.Locality {
+	return h.locality
+}
+
+func (h *mockHost) LocalityWeight() uint32 {
+	return h.lbWeight
+}
+
+func (h *mockHost) HostStats() types.HostStats {
+	return h.stats
+}
+
 func (h *mockHost) Health() bool {
 	return h.healthFlag == 0
 }
@@ -133,8 +159,10 @@ func init() {
 
 type mockLbContext struct {
 	types.LoadBalancerContext
-	mmc    api.MetadataMatchCriteria
-	header api.HeaderMap
+	mmc            api.MetadataMatchCriteria
+	header         api.HeaderMap
+	hashKey        *uint64
+	attemptedHosts []string
 }
 
 func newMockLbContext(m map[string]string) types.LoadBalancerContext {
@@ -166,3 +194,19 @@ func (ctx *mockLbContext) DownstreamHeaders() types.HeaderMap {
 func (ctx *mockLbContext) DownstreamContext() context.Context {
 	return nil
 }
+
+func (ctx *mockLbContext) HashKey() (uint64, bool) {
+	if ctx.hashKey == nil {
+		return 0, false
+	}
+	return *ctx.hashKey, true
+}
+
+func (ctx *mockLbContext) ShouldSelectAnotherHost(host types.Host) bool {
+	for _, addr := range ctx.attemptedHosts {
+		if addr == host.AddressString() {
+			return true
+		}
+	}
+	return false
+}
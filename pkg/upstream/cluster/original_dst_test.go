@@ -55,6 +55,14 @@ func (c *LbCtx) DownstreamCluster() types.ClusterInfo {
 	return c.cluster
 }
 
+func (c *LbCtx) HashKey() (uint64, bool) {
+	return 0, false
+}
+
+func (c *LbCtx) ShouldSelectAnotherHost(host types.Host) bool {
+	return false
+}
+
 type Header struct {
 	v map[string]string
 }
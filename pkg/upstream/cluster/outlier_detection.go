@@ -0,0 +1,463 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/utils"
+)
+
+// default outlier detection parameters, same as envoy
+const (
+	defaultOutlierInterval                = 10 * time.Second
+	defaultBaseEjectionTime               = 30 * time.Second
+	defaultMaxEjectionPercent             = 10
+	defaultSuccessRateMinimumHosts        = 5
+	defaultSuccessRateRequestVolume       = 100
+	defaultFailurePercentageMinimumHosts  = 5
+	defaultFailurePercentageRequestVolume = 50
+)
+
+// ejectionType is the reason that a host is ejected
+type ejectionType string
+
+const (
+	ejectionConsecutive5xx            ejectionType = "consecutive_5xx"
+	ejectionConsecutiveGatewayFailure ejectionType = "consecutive_gateway_failure"
+	ejectionSuccessRate               ejectionType = "success_rate"
+	ejectionFailurePercentage         ejectionType = "failure_percentage"
+)
+
+// hostMonitor records the upstream results of a host
+type hostMonitor struct {
+	host                      types.Host
+	consecutive5xx            uint32
+	consecutiveGatewayFailure uint32
+	// the results in the current interval
+	success uint64
+	total   uint64
+	// the ejection states are protected by the detector's lock
+	ejected      bool
+	ejectedTime  time.Time
+	numEjections uint32
+}
+
+// outlierDetector is an implementation of types.OutlierDetector
+// the hosts are monitored by address, so the ejection states are kept when the hosts are updated
+type outlierDetector struct {
+	clusterName                    string
+	stats                          types.ClusterStats
+	interval                       time.Duration
+	baseEjectionTime               time.Duration
+	consecutive5xx                 uint32
+	consecutiveGatewayFailure      uint32
+	maxEjectionPercent             uint32
+	successRateMinimumHosts        uint32
+	successRateRequestVolume       uint64
+	successRateStdevFactor         uint32
+	failurePercentageThreshold     uint32
+	failurePercentageMinimumHosts  uint32
+	failurePercentageRequestVolume uint64
+	// onChanged is called when a host is ejected or unejected
+	onChanged func(host types.Host)
+
+	mux          sync.RWMutex
+	monitors     map[string]*hostMonitor
+	ejectedCount int
+	timer        *utils.Timer
+	stopped      bool
+}
+
+func newOutlierDetector(clusterName string, config *v2.OutlierDetection, stats types.ClusterStats) *outlierDetector {
+	d := &outlierDetector{
+		clusterName:                    clusterName,
+		stats:                          stats,
+		interval:                       defaultOutlierInterval,
+		baseEjectionTime:               defaultBaseEjectionTime,
+		consecutive5xx:                 config.Consecutive5xx,
+		consecutiveGatewayFailure:      config.ConsecutiveGatewayFailure,
+		maxEjectionPercent:             config.MaxEjectionPercent,
+		successRateMinimumHosts:        config.SuccessRateMinimumHosts,
+		successRateRequestVolume:       uint64(config.SuccessRateRequestVolume),
+		successRateStdevFactor:         config.SuccessRateStdevFactor,
+		failurePercentageThreshold:     config.FailurePercentageThreshold,
+		failurePercentageMinimumHosts:  config.FailurePercentageMinimumHosts,
+		failurePercentageRequestVolume: uint64(config.FailurePercentageRequestVolume),
+		monitors:                       map[string]*hostMonitor{},
+	}
+	if config.Interval != nil && config.Interval.Duration > 0 {
+		d.interval = config.Interval.Duration
+	}
+	if config.BaseEjectionTime != nil && config.BaseEjectionTime.Duration > 0 {
+		d.baseEjectionTime = config.BaseEjectionTime.Duration
+	}
+	if d.maxEjectionPercent == 0 {
+		d.maxEjectionPercent = defaultMaxEjectionPercent
+	}
+	if d.successRateMinimumHosts == 0 {
+		d.successRateMinimumHosts = defaultSuccessRateMinimumHosts
+	}
+	if d.successRateRequestVolume == 0 {
+		d.successRateRequestVolume = defaultSuccessRateRequestVolume
+	}
+	if d.failurePercentageMinimumHosts == 0 {
+		d.failurePercentageMinimumHosts = defaultFailurePercentageMinimumHosts
+	}
+	if d.failurePercentageRequestVolume == 0 {
+		d.failurePercentageRequestVolume = defaultFailurePercentageRequestVolume
+	}
+	return d
+}
+
+func (d *outlierDetector) start() {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.timer = utils.NewTimer(d.interval, d.onInterval)
+}
+
+// stop stops the detection, the ejected hosts are brought back because no one will uneject them
+func (d *outlierDetector) stop() {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.stopped = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	for _, m := range d.monitors {
+		if m.ejected {
+			d.unejectHost(m)
+		}
+	}
+}
+
+func (d *outlierDetector) onInterval() {
+	d.evaluate(time.Now())
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if !d.stopped {
+		d.timer = utils.NewTimer(d.interval, d.onInterval)
+	}
+}
+
+// takeOver stops the old detector and takes over its ejection states, so the ejected hosts
+// are still ejected after the cluster is updated. The hosts are applied by updateHosts later
+func (d *outlierDetector) takeOver(old *outlierDetector) {
+	old.mux.Lock()
+	old.stopped = true
+	if old.timer != nil {
+		old.timer.Stop()
+	}
+	monitors := old.monitors
+	old.monitors = map[string]*hostMonitor{}
+	old.ejectedCount = 0
+	old.mux.Unlock()
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.monitors = monitors
+	d.ejectedCount = 0
+	for _, m := range monitors {
+		if m.ejected {
+			d.ejectedCount++
+		}
+	}
+}
+
+// updateHosts resets the monitored hosts, the ejection states are applied to the new hosts,
+// and onChanged is called for the hosts whose ejection states are changed
+func (d *outlierDetector) updateHosts(hosts []types.Host) {
+	var changed []types.Host
+	func() {
+		d.mux.Lock()
+		defer d.mux.Unlock()
+		monitors := make(map[string]*hostMonitor, len(hosts))
+		ejectedCount := 0
+		for _, h := range hosts {
+			addr := h.AddressString()
+			if _, exists := monitors[addr]; exists {
+				continue
+			}
+			m, ok := d.monitors[addr]
+			if !ok {
+				m = &hostMonitor{}
+			}
+			m.host = h
+			if m.ejected {
+				if !h.ContainHealthFlag(types.FAILED_OUTLIER_CHECK) {
+					h.SetHealthFlag(types.FAILED_OUTLIER_CHECK)
+					changed = append(changed, h)
+				}
+				ejectedCount++
+			} else if h.ContainHealthFlag(types.FAILED_OUTLIER_CHECK) {
+				h.ClearHealthFlag(types.FAILED_OUTLIER_CHECK)
+				changed = append(changed, h)
+			}
+			monitors[addr] = m
+		}
+		d.monitors = monitors
+		d.ejectedCount = ejectedCount
+		d.stats.OutlierEjectionsActive.Update(int64(ejectedCount))
+	}()
+	if d.onChanged != nil {
+		for _, host := range changed {
+			d.onChanged(host)
+		}
+	}
+}
+
+func (d *outlierDetector) PutResult(host types.Host, code int) {
+	d.mux.RLock()
+	m, ok := d.monitors[host.AddressString()]
+	d.mux.RUnlock()
+	if !ok {
+		return
+	}
+	atomic.AddUint64(&m.total, 1)
+	var reason ejectionType
+	if code >= http.StatusInternalServerError {
+		if n := atomic.AddUint32(&m.consecutive5xx, 1); n == d.consecutive5xx {
+			reason = ejectionConsecutive5xx
+		}
+	} else {
+		atomic.AddUint64(&m.success, 1)
+		atomic.StoreUint32(&m.consecutive5xx, 0)
+	}
+	if code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout {
+		if n := atomic.AddUint32(&m.consecutiveGatewayFailure, 1); n == d.consecutiveGatewayFailure && reason == "" {
+			reason = ejectionConsecutiveGatewayFailure
+		}
+	} else {
+		atomic.StoreUint32(&m.consecutiveGatewayFailure, 0)
+	}
+	if reason == "" {
+		return
+	}
+	d.mux.Lock()
+	ejected := d.ejectHost(m, reason, time.Now())
+	host = m.host
+	d.mux.Unlock()
+	if ejected && d.onChanged != nil {
+		d.onChanged(host)
+	}
+}
+
+// ejectHost ejects the host if the max ejection percent is not reached, should be called with lock
+func (d *outlierDetector) ejectHost(m *hostMonitor, reason ejectionType, now time.Time) bool {
+	// the host may be removed after the result is reported
+	if m.ejected || d.monitors[m.host.AddressString()] != m {
+		return false
+	}
+	if float64(d.ejectedCount)*100/float64(len(d.monitors)) >= float64(d.maxEjectionPercent) {
+		d.stats.OutlierEjectionsOverflow.Inc(1)
+		return false
+	}
+	m.ejected = true
+	m.ejectedTime = now
+	m.numEjections++
+	atomic.StoreUint32(&m.consecutive5xx, 0)
+	atomic.StoreUint32(&m.consecutiveGatewayFailure, 0)
+	m.host.SetHealthFlag(types.FAILED_OUTLIER_CHECK)
+	d.ejectedCount++
+	d.stats.OutlierEjectionsActive.Update(int64(d.ejectedCount))
+	d.stats.OutlierEjectionsTotal.Inc(1)
+	switch reason {
+	case ejectionConsecutive5xx:
+		d.stats.OutlierEjectionsConsecutive5xx.Inc(1)
+	case ejectionConsecutiveGatewayFailure:
+		d.stats.OutlierEjectionsConsecutiveGatewayFailure.Inc(1)
+	case ejectionSuccessRate:
+		d.stats.OutlierEjectionsSuccessRate.Inc(1)
+	case ejectionFailurePercentage:
+		d.stats.OutlierEjectionsFailurePercentage.Inc(1)
+	}
+	log.DefaultLogger.Infof("[upstream] [outlier detection] cluster %s host %s is ejected, reason: %s, ejection times: %d",
+		d.clusterName, m.host.AddressString(), reason, m.numEjections)
+	return true
+}
+
+// unejectHost brings the host back to the load balancing, should be called with lock
+func (d *outlierDetector) unejectHost(m *hostMonitor) {
+	m.ejected = false
+	m.host.ClearHealthFlag(types.FAILED_OUTLIER_CHECK)
+	d.ejectedCount--
+	d.stats.OutlierEjectionsActive.Update(int64(d.ejectedCount))
+	log.DefaultLogger.Infof("[upstream] [outlier detection] cluster %s host %s is unejected", d.clusterName, m.host.AddressString())
+}
+
+// intervalResult is a host's results in an interval
+type intervalResult struct {
+	monitor *hostMonitor
+	success uint64
+	total   uint64
+}
+
+// evaluate unejects the hosts that reach the ejection time,
+// and ejects the hosts by the success rate and failure percentage of the last interval
+func (d *outlierDetector) evaluate(now time.Time) {
+	var changed []types.Host
+	func() {
+		d.mux.Lock()
+		defer d.mux.Unlock()
+		results := make([]intervalResult, 0, len(d.monitors))
+		for _, m := range d.monitors {
+			if m.ejected {
+				if now.Sub(m.ejectedTime) >= d.baseEjectionTime*time.Duration(m.numEjections) {
+					d.unejectHost(m)
+					changed = append(changed, m.host)
+				}
+			} else if m.numEjections > 0 {
+				// the ejection time will be decreased if the host keeps healthy
+				m.numEjections--
+			}
+			result := intervalResult{
+				monitor: m,
+				success: atomic.SwapUint64(&m.success, 0),
+				total:   atomic.SwapUint64(&m.total, 0),
+			}
+			if !m.ejected {
+				results = append(results, result)
+			}
+		}
+		if d.successRateStdevFactor > 0 {
+			for _, m := range d.successRateOutliers(results) {
+				if d.ejectHost(m, ejectionSuccessRate, now) {
+					changed = append(changed, m.host)
+				}
+			}
+		}
+		if d.failurePercentageThreshold > 0 {
+			for _, m := range d.failurePercentageOutliers(results) {
+				if d.ejectHost(m, ejectionFailurePercentage, now) {
+					changed = append(changed, m.host)
+				}
+			}
+		}
+	}()
+	if d.onChanged != nil {
+		for _, host := range changed {
+			d.onChanged(host)
+		}
+	}
+}
+
+// successRateOutliers returns the hosts whose success rate is less than mean - (stdev * factor)
+func (d *outlierDetector) successRateOutliers(results []intervalResult) []*hostMonitor {
+	rates := make([]float64, 0, len(results))
+	monitors := make([]*hostMonitor, 0, len(results))
+	for _, r := range results {
+		if r.total >= d.successRateRequestVolume {
+			rates = append(rates, float64(r.success)*100/float64(r.total))
+			monitors = append(monitors, r.monitor)
+		}
+	}
+	if len(rates) == 0 || len(rates) < int(d.successRateMinimumHosts) {
+		return nil
+	}
+	var sum float64
+	for _, rate := range rates {
+		sum += rate
+	}
+	mean := sum / float64(len(rates))
+	var variance float64
+	for _, rate := range rates {
+		variance += (rate - mean) * (rate - mean)
+	}
+	stdev := math.Sqrt(variance / float64(len(rates)))
+	threshold := mean - stdev*float64(d.successRateStdevFactor)/1000
+	var outliers []*hostMonitor
+	for i, rate := range rates {
+		if rate < threshold {
+			outliers = append(outliers, monitors[i])
+		}
+	}
+	return outliers
+}
+
+// failurePercentageOutliers returns the hosts whose failure percentage reaches the threshold
+func (d *outlierDetector) failurePercentageOutliers(results []intervalResult) []*hostMonitor {
+	candidates := make([]intervalResult, 0, len(results))
+	for _, r := range results {
+		if r.total >= d.failurePercentageRequestVolume {
+			candidates = append(candidates, r)
+		}
+	}
+	if len(candidates) == 0 || len(candidates) < int(d.failurePercentageMinimumHosts) {
+		return nil
+	}
+	var outliers []*hostMonitor
+	for _, r := range candidates {
+		if float64(r.total-r.success)*100/float64(r.total) >= float64(d.failurePercentageThreshold) {
+			outliers = append(outliers, r.monitor)
+		}
+	}
+	return outliers
+}
+
+// OutlierHostStatus is the ejection state of a host, used in admin api
+type OutlierHostStatus struct {
+	Address      string    `json:"address"`
+	EjectedTime  time.Time `json:"ejected_time"`
+	NumEjections uint32    `json:"num_ejections"`
+}
+
+// ejectedHosts returns the hosts that are ejected currently
+func (d *outlierDetector) ejectedHosts() []OutlierHostStatus {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	hosts := make([]OutlierHostStatus, 0, d.ejectedCount)
+	for addr, m := range d.monitors {
+		if m.ejected {
+			hosts = append(hosts, OutlierHostStatus{
+				Address:      addr,
+				EjectedTime:  m.ejectedTime,
+				NumEjections: m.numEjections,
+			})
+		}
+	}
+	return hosts
+}
+
+// GetOutlierHosts returns the ejected hosts of the clusters that configured outlier detection,
+// if the cluster name is not empty, only the cluster's ejected hosts are returned
+func GetOutlierHosts(clusterName string) map[string][]OutlierHostStatus {
+	result := map[string][]OutlierHostStatus{}
+	cm := clusterMangerInstance.clusterManager
+	if cm == nil {
+		return result
+	}
+	cm.clustersMap.Range(func(key, value interface{}) bool {
+		name := key.(string)
+		if clusterName != "" && clusterName != name {
+			return true
+		}
+		c := value.(types.Cluster)
+		if d, ok := c.Snapshot().ClusterInfo().OutlierDetector().(*outlierDetector); ok {
+			result[name] = d.ejectedHosts()
+		}
+		return true
+	})
+	return result
+}
@@ -0,0 +1,306 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+)
+
+func makeOutlierHostConfigs(size int) []v2.Host {
+	hosts := make([]v2.Host, 0, size)
+	for i := 0; i < size; i++ {
+		hosts = append(hosts, v2.Host{
+			HostConfig: v2.HostConfig{
+				Address: fmt.Sprintf("127.0.0.1:%d", 10000+i),
+			},
+		})
+	}
+	return hosts
+}
+
+func newOutlierTestCluster(name string, size int, config *v2.OutlierDetection) *simpleCluster {
+	cluster := newSimpleCluster(v2.Cluster{
+		Name:             name,
+		LbType:           v2.LB_RANDOM,
+		OutlierDetection: config,
+	})
+	hosts := make([]types.Host, 0, size)
+	for _, cfg := range makeOutlierHostConfigs(size) {
+		hosts = append(hosts, NewSimpleHost(cfg, cluster.info))
+	}
+	cluster.UpdateHosts(hosts)
+	return cluster
+}
+
+func TestOutlierDetectionConsecutiveFailure(t *testing.T) {
+	cluster := newOutlierTestCluster("test_outlier_consecutive", 10, &v2.OutlierDetection{
+		Consecutive5xx:            5,
+		ConsecutiveGatewayFailure: 2,
+		MaxEjectionPercent:        20,
+		BaseEjectionTime: &api.DurationConfig{
+			Duration: time.Second,
+		},
+	})
+	defer cluster.StopHealthChecking()
+	detector := cluster.info.OutlierDetector()
+	hosts := cluster.Snapshot().HostSet().Hosts()
+	// 5xx is not consecutive
+	for i := 0; i < 4; i++ {
+		detector.PutResult(hosts[0], 500)
+	}
+	detector.PutResult(hosts[0], 200)
+	detector.PutResult(hosts[0], 500)
+	if !hosts[0].Health() || len(cluster.Snapshot().HostSet().HealthyHosts()) != 10 {
+		t.Fatal("host should not be ejected")
+	}
+	// consecutive 5xx
+	for i := 0; i < 5; i++ {
+		detector.PutResult(hosts[0], 500)
+	}
+	if hosts[0].Health() || len(cluster.Snapshot().HostSet().HealthyHosts()) != 9 {
+		t.Fatal("host should be ejected by consecutive 5xx")
+	}
+	// consecutive gateway failure
+	detector.PutResult(hosts[1], 503)
+	detector.PutResult(hosts[1], 504)
+	if hosts[1].Health() || len(cluster.Snapshot().HostSet().HealthyHosts()) != 8 {
+		t.Fatal("host should be ejected by consecutive gateway failure")
+	}
+	// max ejection percent reached
+	detector.PutResult(hosts[2], 502)
+	detector.PutResult(hosts[2], 502)
+	if !hosts[2].Health() || len(cluster.Snapshot().HostSet().HealthyHosts()) != 8 {
+		t.Fatal("host should not be ejected when max ejection percent reached")
+	}
+	stats := cluster.info.Stats()
+	if stats.OutlierEjectionsActive.Value() != 2 ||
+		stats.OutlierEjectionsTotal.Count() != 2 ||
+		stats.OutlierEjectionsConsecutive5xx.Count() != 1 ||
+		stats.OutlierEjectionsConsecutiveGatewayFailure.Count() != 1 ||
+		stats.OutlierEjectionsOverflow.Count() != 1 {
+		t.Fatal("outlier detection stats is not expected")
+	}
+	// uneject after the base ejection time
+	cluster.info.outlierDetector.evaluate(time.Now().Add(time.Second))
+	if !hosts[0].Health() || !hosts[1].Health() || len(cluster.Snapshot().HostSet().HealthyHosts()) != 10 {
+		t.Fatal("host should be unejected")
+	}
+	if stats.OutlierEjectionsActive.Value() != 0 {
+		t.Fatal("outlier detection stats is not expected")
+	}
+}
+
+func TestOutlierDetectionUpdateHosts(t *testing.T) {
+	cluster := newOutlierTestCluster("test_outlier_update_hosts", 5, &v2.OutlierDetection{
+		Consecutive5xx:     1,
+		MaxEjectionPercent: 100,
+	})
+	defer cluster.StopHealthChecking()
+	hosts := cluster.Snapshot().HostSet().Hosts()
+	cluster.info.OutlierDetector().PutResult(hosts[0], 500)
+	if len(cluster.Snapshot().HostSet().HealthyHosts()) != 4 {
+		t.Fatal("host should be ejected")
+	}
+	// the new host with the same address keeps ejected
+	newHosts := make([]types.Host, 0, 5)
+	for _, cfg := range makeOutlierHostConfigs(5) {
+		newHosts = append(newHosts, NewSimpleHost(cfg, cluster.info))
+	}
+	cluster.UpdateHosts(newHosts)
+	if newHosts[0].Health() || len(cluster.Snapshot().HostSet().HealthyHosts()) != 4 {
+		t.Fatal("host should be ejected after hosts updated")
+	}
+	// stop detection brings the ejected hosts back
+	cluster.StopHealthChecking()
+	if !newHosts[0].Health() {
+		t.Fatal("host should be unejected after detection stopped")
+	}
+}
+
+func TestOutlierDetectionSuccessRate(t *testing.T) {
+	cluster := newOutlierTestCluster("test_outlier_success_rate", 5, &v2.OutlierDetection{
+		SuccessRateStdevFactor:   1900,
+		SuccessRateRequestVolume: 100,
+		MaxEjectionPercent:       100,
+	})
+	defer cluster.StopHealthChecking()
+	detector := cluster.info.OutlierDetector()
+	hosts := cluster.Snapshot().HostSet().Hosts()
+	for i := 0; i < 100; i++ {
+		for j, h := range hosts {
+			// hosts[0]'s success rate is 50%, others are 100%
+			if j == 0 && i%2 == 0 {
+				detector.PutResult(h, 500)
+			} else {
+				detector.PutResult(h, 200)
+			}
+		}
+	}
+	// mean is 90, stdev is 20, the threshold is 90 - 20 * 1.9 = 52
+	cluster.info.outlierDetector.evaluate(time.Now())
+	if hosts[0].Health() || len(cluster.Snapshot().HostSet().HealthyHosts()) != 4 {
+		t.Fatal("host should be ejected by success rate")
+	}
+	if cluster.info.Stats().OutlierEjectionsSuccessRate.Count() != 1 {
+		t.Fatal("outlier detection stats is not expected")
+	}
+	// the results are cleared after evaluated
+	cluster.info.outlierDetector.evaluate(time.Now())
+	if len(cluster.Snapshot().HostSet().HealthyHosts()) != 4 {
+		t.Fatal("no more hosts should be ejected")
+	}
+}
+
+func TestOutlierDetectionFailurePercentage(t *testing.T) {
+	cluster := newOutlierTestCluster("test_outlier_failure_percentage", 5, &v2.OutlierDetection{
+		FailurePercentageThreshold: 60,
+		MaxEjectionPercent:         100,
+	})
+	defer cluster.StopHealthChecking()
+	detector := cluster.info.OutlierDetector()
+	hosts := cluster.Snapshot().HostSet().Hosts()
+	for i := 0; i < 10; i++ {
+		for j, h := range hosts {
+			// the failure percentage of hosts[0] is 60%, hosts[1] is 50%
+			if (j == 0 && i < 6) || (j == 1 && i < 5) {
+				detector.PutResult(h, 500)
+			} else {
+				detector.PutResult(h, 200)
+			}
+		}
+	}
+	// request volume is not enough
+	cluster.info.outlierDetector.evaluate(time.Now())
+	if len(cluster.Snapshot().HostSet().HealthyHosts()) != 5 {
+		t.Fatal("no hosts should be ejected if request volume is not enough")
+	}
+	for i := 0; i < 50; i++ {
+		for j, h := range hosts {
+			if (j == 0 && i < 30) || (j == 1 && i < 25) {
+				detector.PutResult(h, 500)
+			} else {
+				detector.PutResult(h, 200)
+			}
+		}
+	}
+	cluster.info.outlierDetector.evaluate(time.Now())
+	if hosts[0].Health() || !hosts[1].Health() || len(cluster.Snapshot().HostSet().HealthyHosts()) != 4 {
+		t.Fatal("host should be ejected by failure percentage")
+	}
+}
+
+func TestGetOutlierHosts(t *testing.T) {
+	clusterMangerInstance.Destroy() // Destroy for test
+	defer clusterMangerInstance.Destroy()
+	hostConfigs := makeOutlierHostConfigs(3)
+	NewClusterManagerSingleton([]v2.Cluster{
+		{
+			Name:   "outlier",
+			LbType: v2.LB_RANDOM,
+			OutlierDetection: &v2.OutlierDetection{
+				Consecutive5xx:     1,
+				MaxEjectionPercent: 100,
+			},
+		},
+		{
+			Name:   "no_outlier",
+			LbType: v2.LB_RANDOM,
+		},
+	}, map[string][]v2.Host{
+		"outlier":    hostConfigs,
+		"no_outlier": hostConfigs,
+	})
+	snap := clusterMangerInstance.GetClusterSnapshot(context.Background(), "outlier")
+	host := snap.HostSet().Hosts()[0]
+	snap.ClusterInfo().OutlierDetector().PutResult(host, 503)
+
+	result := GetOutlierHosts("")
+	if len(result) != 1 || len(result["outlier"]) != 1 {
+		t.Fatalf("unexpected outlier hosts: %v", result)
+	}
+	status := result["outlier"][0]
+	if status.Address != host.AddressString() || status.NumEjections != 1 {
+		t.Fatalf("unexpected outlier host status: %+v", status)
+	}
+	// filter by cluster name
+	if result := GetOutlierHosts("no_outlier"); len(result) != 0 {
+		t.Fatalf("unexpected outlier hosts: %v", result)
+	}
+}
+
+func TestOutlierDetectionClusterUpdate(t *testing.T) {
+	clusterMangerInstance.Destroy() // Destroy for test
+	defer clusterMangerInstance.Destroy()
+	config := v2.Cluster{
+		Name:   "outlier",
+		LbType: v2.LB_RANDOM,
+		OutlierDetection: &v2.OutlierDetection{
+			Consecutive5xx:     1,
+			MaxEjectionPercent: 100,
+		},
+	}
+	NewClusterManagerSingleton([]v2.Cluster{config}, map[string][]v2.Host{
+		"outlier": makeOutlierHostConfigs(3),
+	})
+	snap := clusterMangerInstance.GetClusterSnapshot(context.Background(), "outlier")
+	host := snap.HostSet().Hosts()[0]
+	snap.ClusterInfo().OutlierDetector().PutResult(host, 503)
+	ejectedTime := GetOutlierHosts("outlier")["outlier"][0].EjectedTime
+
+	// the ejection states are carried over to the updated cluster
+	config.OutlierDetection.Consecutive5xx = 2
+	if err := clusterMangerInstance.AddOrUpdatePrimaryCluster(config); err != nil {
+		t.Fatal(err)
+	}
+	snap = clusterMangerInstance.GetClusterSnapshot(context.Background(), "outlier")
+	if host.Health() || len(snap.HostSet().HealthyHosts()) != 2 {
+		t.Fatal("the ejected host should be still ejected after the cluster is updated")
+	}
+	result := GetOutlierHosts("outlier")["outlier"]
+	if len(result) != 1 || result[0].Address != host.AddressString() || result[0].NumEjections != 1 || !result[0].EjectedTime.Equal(ejectedTime) {
+		t.Fatalf("unexpected outlier hosts: %+v", result)
+	}
+	// the new detector unejects the host
+	detector := snap.ClusterInfo().OutlierDetector().(*outlierDetector)
+	detector.evaluate(ejectedTime.Add(detector.baseEjectionTime))
+	if !host.Health() || len(snap.HostSet().HealthyHosts()) != 3 {
+		t.Fatal("the host should be unejected by the new detector")
+	}
+
+	// the ejected hosts are brought back if the outlier detection is removed
+	detector.PutResult(host, 503)
+	detector.PutResult(host, 503)
+	if host.Health() {
+		t.Fatal("host should be ejected")
+	}
+	config.OutlierDetection = nil
+	if err := clusterMangerInstance.AddOrUpdatePrimaryCluster(config); err != nil {
+		t.Fatal(err)
+	}
+	snap = clusterMangerInstance.GetClusterSnapshot(context.Background(), "outlier")
+	if !host.Health() || len(snap.HostSet().HealthyHosts()) != 3 {
+		t.Fatal("the host should be healthy without outlier detection")
+	}
+}
@@ -35,6 +35,7 @@ func newHostStats(clustername string, addr string) types.HostStats {
 		UpstreamConnectionLocalCloseWithActiveRequest:  s.Counter(metrics.UpstreamConnectionLocalCloseWithActiveRequest),
 		UpstreamConnectionRemoteCloseWithActiveRequest: s.Counter(metrics.UpstreamConnectionRemoteCloseWithActiveRequest),
 		UpstreamConnectionCloseNotify:                  s.Counter(metrics.UpstreamConnectionCloseNotify),
+		UpstreamConnectionConnectDuration:              s.Histogram(metrics.UpstreamConnectionConnectDuration),
 		UpstreamRequestTotal:                           s.Counter(metrics.UpstreamRequestTotal),
 		UpstreamRequestActive:                          s.Counter(metrics.UpstreamRequestActive),
 		UpstreamRequestLocalReset:                      s.Counter(metrics.UpstreamRequestLocalReset),
@@ -62,6 +63,7 @@ func newClusterStats(clustername string) types.ClusterStats {
 		UpstreamConnectionLocalCloseWithActiveRequest:  s.Counter(metrics.UpstreamConnectionLocalCloseWithActiveRequest),
 		UpstreamConnectionRemoteCloseWithActiveRequest: s.Counter(metrics.UpstreamConnectionRemoteCloseWithActiveRequest),
 		UpstreamConnectionCloseNotify:                  s.Counter(metrics.UpstreamConnectionCloseNotify),
+		UpstreamConnectionConnectDuration:              s.Histogram(metrics.UpstreamConnectionConnectDuration),
 		UpstreamBytesReadTotal:                         s.Counter(metrics.UpstreamBytesReadTotal),
 		UpstreamBytesWriteTotal:                        s.Counter(metrics.UpstreamBytesWriteTotal),
 		UpstreamRequestTotal:                           s.Counter(metrics.UpstreamRequestTotal),
@@ -79,5 +81,13 @@ func newClusterStats(clustername string) types.ClusterStats {
 		UpstreamResponseFailed:                         s.Counter(metrics.UpstreamResponseFailed),
 		LBSubSetsFallBack:                              s.Counter(metrics.UpstreamLBSubSetsFallBack),
 		LBSubsetsCreated:                               s.Gauge(metrics.UpstreamLBSubsetsCreated),
+		LBHealthyPanic:                                 s.Counter(metrics.UpstreamLBHealthyPanic),
+		OutlierEjectionsActive:                         s.Gauge(metrics.UpstreamOutlierEjectionsActive),
+		OutlierEjectionsTotal:                          s.Counter(metrics.UpstreamOutlierEjectionsTotal),
+		OutlierEjectionsOverflow:                       s.Counter(metrics.UpstreamOutlierEjectionsOverflow),
+		OutlierEjectionsConsecutive5xx:                 s.Counter(metrics.UpstreamOutlierEjectionsConsecutive5xx),
+		OutlierEjectionsConsecutiveGatewayFailure:      s.Counter(metrics.UpstreamOutlierEjectionsConsecutiveGatewayFailure),
+		OutlierEjectionsSuccessRate:                    s.Counter(metrics.UpstreamOutlierEjectionsSuccessRate),
+		OutlierEjectionsFailurePercentage:              s.Counter(metrics.UpstreamOutlierEjectionsFailurePercentage),
 	}
 }
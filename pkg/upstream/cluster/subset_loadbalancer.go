@@ -33,15 +33,17 @@ type subsetLoadBalancer struct {
 	subSets        types.LbSubsetMap  // final trie-like structure used to stored easily searched subset
 	fallbackSubset *LBSubsetEntryImpl // subset entry generated according to fallback policy
 	hostSet        *hostSet
+	panicThreshold uint32
 }
 
 func NewSubsetLoadBalancer(info *clusterInfo, hostSet *hostSet) types.LoadBalancer {
 	subsetInfo := info.lbSubsetInfo
 	subsetLB := &subsetLoadBalancer{
-		lbType:  info.lbType,
-		stats:   info.stats,
-		subSets: make(map[string]types.ValueSubsetMap),
-		hostSet: hostSet,
+		lbType:         info.lbType,
+		stats:          info.stats,
+		subSets:        make(map[string]types.ValueSubsetMap),
+		hostSet:        hostSet,
+		panicThreshold: info.healthyPanicThreshold,
 	}
 	// create fallback
 	subsetLB.createFallbackSubset(subsetInfo.FallbackPolicy(), subsetInfo.DefaultSubset())
@@ -126,7 +128,7 @@ func (sslb *subsetLoadBalancer) createSubsets(subSetKeys []types.SortedStringSet
 						return HostMatches(kvs, host)
 					})
 					subsSetCount += 1
-					entry.CreateLoadBalancer(sslb.lbType, subHostset)
+					entry.CreateLoadBalancer(sslb.lbType, sslb.lbHostSet(subHostset))
 				}
 			}
 		}
@@ -147,7 +149,7 @@ func (sslb *subsetLoadBalancer) createFallbackSubset(policy types.FallBackPolicy
 		sslb.fallbackSubset = &LBSubsetEntryImpl{
 			children: nil, // no child
 		}
-		sslb.fallbackSubset.CreateLoadBalancer(sslb.lbType, hostSet)
+		sslb.fallbackSubset.CreateLoadBalancer(sslb.lbType, sslb.lbHostSet(hostSet))
 	case types.DefaultSubset:
 		sslb.fallbackSubset = &LBSubsetEntryImpl{
 			children: nil, // no child
@@ -155,10 +157,15 @@ func (sslb *subsetLoadBalancer) createFallbackSubset(policy types.FallBackPolicy
 		subHostset := hostSet.createSubset(func(host types.Host) bool {
 			return HostMatches(meta, host)
 		})
-		sslb.fallbackSubset.CreateLoadBalancer(sslb.lbType, subHostset)
+		sslb.fallbackSubset.CreateLoadBalancer(sslb.lbType, sslb.lbHostSet(subHostset))
 	}
 }
 
+// lbHostSet returns the host set used by the subset's load balancer, the healthy panic is checked in each subset
+func (sslb *subsetLoadBalancer) lbHostSet(hs types.HostSet) types.HostSet {
+	return newHealthyPanicHostSet(hs, sslb.panicThreshold, sslb.stats)
+}
+
 func (sslb *subsetLoadBalancer) findSubset(matchCriteria []api.MetadataMatchCriterion) types.LBSubsetEntry {
 	subSets := sslb.subSets
 	for i, mcCriterion := range matchCriteria {
@@ -696,3 +696,40 @@ func TestDynamicSubsetHost(t *testing.T) {
 	}
 
 }
+
+func TestSubsetLoadBalancerHealthyPanic(t *testing.T) {
+	ps := createHostset(exampleHostConfigs())
+	stats := newClusterStats("TestSubsetLoadBalancerHealthyPanic")
+	info := &clusterInfo{
+		lbType:                types.RoundRobin,
+		stats:                 stats,
+		lbSubsetInfo:          NewLBSubsetInfo(exampleSubsetConfig()),
+		healthyPanicThreshold: 50,
+	}
+	lb := NewSubsetLoadBalancer(info, ps)
+	ctx := newMockLbContext(map[string]string{
+		"stage":   "prod",
+		"version": "1.0",
+	})
+	// the subset contains e1, e2, e5, makes e1 and e2 unhealthy
+	for _, h := range ps.Hosts() {
+		if h.Hostname() == "e1" || h.Hostname() == "e2" {
+			h.SetHealthFlag(types.FAILED_ACTIVE_HC)
+			ps.refreshHealthHost(h)
+		}
+	}
+	chosen := map[string]int{}
+	for i := 0; i < 30; i++ {
+		h := lb.ChooseHost(ctx)
+		if h == nil {
+			t.Fatal("choose host failed")
+		}
+		chosen[h.Hostname()]++
+	}
+	if chosen["e1"] != 10 || chosen["e2"] != 10 || chosen["e5"] != 10 {
+		t.Fatalf("subset should spread across all hosts in panic mode: %v", chosen)
+	}
+	if stats.LBHealthyPanic.Count() != 30 {
+		t.Fatalf("healthy panic stats not expected: %d", stats.LBHealthyPanic.Count())
+	}
+}
@@ -19,6 +19,9 @@ package healthcheck
 
 import (
 	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/xprotocol/bolt"
+	"mosn.io/mosn/pkg/protocol/xprotocol/dubbo"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -27,6 +30,11 @@ var sessionFactories map[types.ProtocolName]types.HealthCheckSessionFactory
 func init() {
 	sessionFactories = make(map[types.ProtocolName]types.HealthCheckSessionFactory)
 	commonCallbacks = make(map[string]types.HealthCheckCb)
+	// builtin session factories
+	RegisterSessionFactory(protocol.HTTP1, &HTTPSessionFactory{})
+	RegisterSessionFactory(GRPC, &GRPCSessionFactory{})
+	RegisterSessionFactory(bolt.ProtocolName, &XProtocolHeartbeatSessionFactory{SubProtocol: bolt.ProtocolName})
+	RegisterSessionFactory(dubbo.ProtocolName, &XProtocolHeartbeatSessionFactory{SubProtocol: dubbo.ProtocolName})
 }
 
 func RegisterSessionFactory(p types.ProtocolName, f types.HealthCheckSessionFactory) {
@@ -35,6 +43,7 @@ func RegisterSessionFactory(p types.ProtocolName, f types.HealthCheckSessionFact
 
 // CreateHealthCheck is a extendable function that can create different health checker
 // by different health check session.
+// The builtin sessions are http, grpc and the heartbeat of bolt and dubbo.
 // The Default session is TCPDial session
 func CreateHealthCheck(cfg v2.HealthCheck) types.HealthChecker {
 	f, ok := sessionFactories[types.ProtocolName(cfg.Protocol)]
@@ -0,0 +1,97 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+// GRPC is the health check protocol name of the grpc health checking protocol
+const GRPC types.ProtocolName = "grpc"
+
+type GRPCSessionFactory struct{}
+
+func (f *GRPCSessionFactory) NewSession(cfg map[string]interface{}, host types.Host) types.HealthCheckSession {
+	config := &v2.GRPCHealthCheckConfig{}
+	if err := parseSessionConfig(cfg, config); err != nil {
+		log.DefaultLogger.Errorf("[upstream] [health check] [grpc session] parse config failed: %v", err)
+		return nil
+	}
+	return &GRPCSession{
+		addr:        host.AddressString(),
+		serviceName: config.ServiceName,
+		authority:   config.Authority,
+	}
+}
+
+// GRPCSession calls grpc.health.v1.Health/Check, the host is healthy if the serving status is SERVING
+type GRPCSession struct {
+	addr        string
+	serviceName string
+	authority   string
+	mutex       sync.Mutex
+	cancel      context.CancelFunc
+}
+
+func (s *GRPCSession) CheckHealth() bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.mutex.Lock()
+	s.cancel = cancel
+	s.mutex.Unlock()
+
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if s.authority != "" {
+		opts = append(opts, grpc.WithAuthority(s.authority))
+	}
+	// every check uses a new connection, same as the tcp dial session
+	conn, err := grpc.DialContext(ctx, s.addr, opts...)
+	if err != nil {
+		log.DefaultLogger.Infof("[upstream] [health check] [grpc session] dial %s error: %v", s.addr, err)
+		return false
+	}
+	defer conn.Close()
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{
+		Service: s.serviceName,
+	})
+	if err != nil {
+		log.DefaultLogger.Infof("[upstream] [health check] [grpc session] check %s service %s error: %v", s.addr, s.serviceName, err)
+		return false
+	}
+	if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		log.DefaultLogger.Infof("[upstream] [health check] [grpc session] check %s service %s status: %s", s.addr, s.serviceName, resp.GetStatus())
+		return false
+	}
+	return true
+}
+
+// OnTimeout cancels the running check
+func (s *GRPCSession) OnTimeout() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
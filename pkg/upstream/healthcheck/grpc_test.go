@@ -0,0 +1,66 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGRPCSession(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := health.NewServer()
+	hs.SetServingStatus("serving", grpc_health_v1.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("not_serving", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	s := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(s, hs)
+	go s.Serve(ln)
+	host := &mockHost{
+		addr: ln.Addr().String(),
+	}
+	factory := &GRPCSessionFactory{}
+	testCases := []struct {
+		service string
+		healthy bool
+	}{
+		{service: "", healthy: true}, // the server's overall health
+		{service: "serving", healthy: true},
+		{service: "not_serving", healthy: false},
+		{service: "unknown", healthy: false},
+	}
+	for _, tc := range testCases {
+		session := factory.NewSession(map[string]interface{}{
+			"service_name": tc.service,
+		}, host)
+		if session.CheckHealth() != tc.healthy {
+			t.Errorf("service %s check health expected %v", tc.service, tc.healthy)
+		}
+	}
+	s.Stop()
+	session := factory.NewSession(nil, host)
+	if session.CheckHealth() {
+		t.Error("check a stopped server, but returns ok")
+	}
+}
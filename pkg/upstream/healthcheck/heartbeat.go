@@ -0,0 +1,127 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol/xprotocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/buffer"
+)
+
+// XProtocolHeartbeatSessionFactory creates the heartbeat sessions for the xprotocol sub protocol
+type XProtocolHeartbeatSessionFactory struct {
+	SubProtocol types.ProtocolName
+}
+
+func (f *XProtocolHeartbeatSessionFactory) NewSession(cfg map[string]interface{}, host types.Host) types.HealthCheckSession {
+	proto := xprotocol.GetProtocol(f.SubProtocol)
+	if proto == nil {
+		log.DefaultLogger.Errorf("[upstream] [health check] [heartbeat session] xprotocol %s is not registered", f.SubProtocol)
+		return nil
+	}
+	return &XProtocolHeartbeatSession{
+		addr:  host.AddressString(),
+		proto: proto,
+	}
+}
+
+// XProtocolHeartbeatSession sends the heartbeat frame that used in keepalive,
+// the host is healthy if the heartbeat response is received with the protocol's success status
+type XProtocolHeartbeatSession struct {
+	addr      string
+	proto     xprotocol.XProtocol
+	requestID uint64
+	mutex     sync.Mutex
+	conn      net.Conn
+}
+
+func (s *XProtocolHeartbeatSession) CheckHealth() bool {
+	// default dial timeout, maybe already timeout by checker
+	conn, err := net.DialTimeout("tcp", s.addr, 30*time.Second)
+	if err != nil {
+		log.DefaultLogger.Infof("[upstream] [health check] [heartbeat session] dial tcp for host %s error: %v", s.addr, err)
+		return false
+	}
+	defer conn.Close()
+	s.mutex.Lock()
+	s.conn = conn
+	s.mutex.Unlock()
+
+	ctx := context.Background()
+	hb := s.proto.Trigger(atomic.AddUint64(&s.requestID, 1))
+	if hb == nil {
+		log.DefaultLogger.Errorf("[upstream] [health check] [heartbeat session] protocol %s does not support heartbeat", s.proto.Name())
+		return false
+	}
+	req, err := s.proto.Encode(ctx, hb)
+	if err != nil {
+		log.DefaultLogger.Errorf("[upstream] [health check] [heartbeat session] encode heartbeat for host %s error: %v", s.addr, err)
+		return false
+	}
+	if _, err := conn.Write(req.Bytes()); err != nil {
+		log.DefaultLogger.Infof("[upstream] [health check] [heartbeat session] send heartbeat to host %s error: %v", s.addr, err)
+		return false
+	}
+	data := buffer.NewIoBuffer(1024)
+	b := make([]byte, 1024)
+	for {
+		n, err := conn.Read(b)
+		if err != nil {
+			log.DefaultLogger.Infof("[upstream] [health check] [heartbeat session] read heartbeat response from host %s error: %v", s.addr, err)
+			return false
+		}
+		data.Write(b[:n])
+		for data.Len() > 0 {
+			resp, err := s.proto.Decode(ctx, data)
+			if err != nil {
+				log.DefaultLogger.Infof("[upstream] [health check] [heartbeat session] decode heartbeat response from host %s error: %v", s.addr, err)
+				return false
+			}
+			// not enough data
+			if resp == nil {
+				break
+			}
+			frame, ok := resp.(xprotocol.XRespFrame)
+			if !ok || frame.GetRequestId() != hb.GetRequestId() {
+				continue
+			}
+			if status := frame.GetStatusCode(); status != s.proto.Mapping(http.StatusOK) {
+				log.DefaultLogger.Infof("[upstream] [health check] [heartbeat session] host %s heartbeat response status %d", s.addr, status)
+				return false
+			}
+			return true
+		}
+	}
+}
+
+// OnTimeout closes the connection, so the running check will be finished
+func (s *XProtocolHeartbeatSession) OnTimeout() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/protocol/xprotocol"
+	"mosn.io/mosn/pkg/protocol/xprotocol/bolt"
+	"mosn.io/mosn/pkg/protocol/xprotocol/dubbo"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/buffer"
+)
+
+// replyHeartbeat replies the heartbeat with the protocol's heartbeat response
+func replyHeartbeat(proto xprotocol.XProtocol, id uint64) xprotocol.XRespFrame {
+	return proto.Reply(id)
+}
+
+// replyError replies the heartbeat with an error response
+func replyError(proto xprotocol.XProtocol, id uint64) xprotocol.XRespFrame {
+	resp := proto.Hijack(proto.Mapping(http.StatusServiceUnavailable))
+	resp.SetRequestId(id)
+	return resp
+}
+
+// serveHeartbeat replies the heartbeat requests, if reply is nil, no response is sent
+func serveHeartbeat(t *testing.T, name types.ProtocolName, reply func(xprotocol.XProtocol, uint64) xprotocol.XRespFrame) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proto := xprotocol.GetProtocol(name)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				data := buffer.NewIoBuffer(1024)
+				b := make([]byte, 1024)
+				for {
+					n, err := conn.Read(b)
+					if err != nil {
+						return
+					}
+					data.Write(b[:n])
+					req, err := proto.Decode(context.Background(), data)
+					if err != nil {
+						return
+					}
+					frame, ok := req.(xprotocol.XFrame)
+					if !ok || !frame.IsHeartbeatFrame() || reply == nil {
+						continue
+					}
+					resp, _ := proto.Encode(context.Background(), reply(proto, frame.GetRequestId()))
+					conn.Write(resp.Bytes())
+				}
+			}()
+		}
+	}()
+	return ln
+}
+
+func TestXProtocolHeartbeatSession(t *testing.T) {
+	for _, name := range []types.ProtocolName{bolt.ProtocolName, dubbo.ProtocolName} {
+		ln := serveHeartbeat(t, name, replyHeartbeat)
+		host := &mockHost{
+			addr: ln.Addr().String(),
+		}
+		factory := sessionFactories[name]
+		session := factory.NewSession(nil, host)
+		for i := 0; i < 2; i++ {
+			if !session.CheckHealth() {
+				t.Errorf("%s heartbeat check health failed", name)
+			}
+		}
+		ln.Close()
+		if session.CheckHealth() {
+			t.Errorf("%s heartbeat check a closed server, but returns ok", name)
+		}
+	}
+	// error response
+	for _, name := range []types.ProtocolName{bolt.ProtocolName, dubbo.ProtocolName} {
+		ln := serveHeartbeat(t, name, replyError)
+		session := sessionFactories[name].NewSession(nil, &mockHost{
+			addr: ln.Addr().String(),
+		})
+		if session.CheckHealth() {
+			t.Errorf("%s heartbeat with error response should be unhealthy", name)
+		}
+		ln.Close()
+	}
+	// no response
+	ln := serveHeartbeat(t, bolt.ProtocolName, nil)
+	defer ln.Close()
+	session := sessionFactories[bolt.ProtocolName].NewSession(nil, &mockHost{
+		addr: ln.Addr().String(),
+	})
+	time.AfterFunc(100*time.Millisecond, session.OnTimeout)
+	if session.CheckHealth() {
+		t.Error("heartbeat without response should be unhealthy")
+	}
+	// not registered protocol
+	factory := &XProtocolHeartbeatSessionFactory{SubProtocol: "not_registered"}
+	if factory.NewSession(nil, &mockHost{}) != nil {
+		t.Error("not registered protocol should not create a session")
+	}
+}
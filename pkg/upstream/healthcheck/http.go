@@ -0,0 +1,149 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+// maxResponseBodySize is the max size of the response body to check the response contains
+const maxResponseBodySize = 64 * 1024
+
+var defaultExpectedStatuses = []v2.Int64Range{
+	{Start: http.StatusOK, End: http.StatusOK + 1},
+}
+
+// parseSessionConfig parses the check_config into the config struct
+func parseSessionConfig(cfg map[string]interface{}, config interface{}) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, config)
+}
+
+type HTTPSessionFactory struct{}
+
+func (f *HTTPSessionFactory) NewSession(cfg map[string]interface{}, host types.Host) types.HealthCheckSession {
+	config := &v2.HTTPHealthCheckConfig{}
+	if err := parseSessionConfig(cfg, config); err != nil {
+		log.DefaultLogger.Errorf("[upstream] [health check] [http session] parse config failed: %v", err)
+		return nil
+	}
+	path := config.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	expected := config.ExpectedStatuses
+	if len(expected) == 0 {
+		expected = defaultExpectedStatuses
+	}
+	return &HTTPSession{
+		url:              "http://" + host.AddressString() + path,
+		host:             config.Host,
+		expectedStatuses: expected,
+		responseContains: []byte(config.ResponseContains),
+		client: &http.Client{
+			// every check uses a new connection, the session cannot be closed when the host is removed
+			Transport: &http.Transport{
+				DisableKeepAlives: true,
+			},
+		},
+	}
+}
+
+// HTTPSession sends a http GET request, the host is healthy if the response status code is expected
+// and the response body contains the configured string
+type HTTPSession struct {
+	url              string
+	host             string
+	expectedStatuses []v2.Int64Range
+	responseContains []byte
+	client           *http.Client
+	mutex            sync.Mutex
+	cancel           context.CancelFunc
+}
+
+func (s *HTTPSession) CheckHealth() bool {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		log.DefaultLogger.Errorf("[upstream] [health check] [http session] create request for %s error: %v", s.url, err)
+		return false
+	}
+	if s.host != "" {
+		req.Host = s.host
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.mutex.Lock()
+	s.cancel = cancel
+	s.mutex.Unlock()
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		log.DefaultLogger.Infof("[upstream] [health check] [http session] request %s error: %v", s.url, err)
+		return false
+	}
+	defer resp.Body.Close()
+	if !s.isExpectedStatus(resp.StatusCode) {
+		log.DefaultLogger.Infof("[upstream] [health check] [http session] request %s response unexpected status code: %d", s.url, resp.StatusCode)
+		return false
+	}
+	if len(s.responseContains) == 0 {
+		return true
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	if err != nil {
+		log.DefaultLogger.Infof("[upstream] [health check] [http session] request %s read body error: %v", s.url, err)
+		return false
+	}
+	if !bytes.Contains(body, s.responseContains) {
+		log.DefaultLogger.Infof("[upstream] [health check] [http session] request %s response body does not contain %s", s.url, s.responseContains)
+		return false
+	}
+	return true
+}
+
+func (s *HTTPSession) isExpectedStatus(code int) bool {
+	for _, r := range s.expectedStatuses {
+		if int64(code) >= r.Start && int64(code) < r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// OnTimeout cancels the running request
+func (s *HTTPSession) OnTimeout() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
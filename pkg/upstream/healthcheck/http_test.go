@@ -0,0 +1,114 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPSession(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "mosn.io" {
+			w.Write([]byte("status: ok"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+	host := &mockHost{
+		addr: strings.Split(s.URL, "http://")[1],
+	}
+	factory := &HTTPSessionFactory{}
+	testCases := []struct {
+		config  map[string]interface{}
+		healthy bool
+	}{
+		{
+			config: map[string]interface{}{
+				"path": "/health",
+				"host": "mosn.io",
+			},
+			healthy: true,
+		},
+		// unexpected status
+		{
+			config: map[string]interface{}{
+				"path": "/health",
+			},
+			healthy: false,
+		},
+		// expected status range
+		{
+			config: map[string]interface{}{
+				"path": "health",
+				"expected_statuses": []interface{}{
+					map[string]interface{}{"start": 200, "end": 300},
+				},
+			},
+			healthy: true,
+		},
+		// response contains
+		{
+			config: map[string]interface{}{
+				"path":              "/health",
+				"host":              "mosn.io",
+				"response_contains": "ok",
+			},
+			healthy: true,
+		},
+		{
+			config: map[string]interface{}{
+				"path":              "/health",
+				"host":              "mosn.io",
+				"response_contains": "fail",
+			},
+			healthy: false,
+		},
+		// not found
+		{
+			config:  nil,
+			healthy: false,
+		},
+	}
+	for i, tc := range testCases {
+		session := factory.NewSession(tc.config, host)
+		if session.CheckHealth() != tc.healthy {
+			t.Errorf("case %d check health expected %v", i, tc.healthy)
+		}
+	}
+	// timeout
+	session := factory.NewSession(map[string]interface{}{"path": "/slow"}, host)
+	time.AfterFunc(100*time.Millisecond, session.OnTimeout)
+	start := time.Now()
+	if session.CheckHealth() || time.Since(start) >= time.Second {
+		t.Error("check health should be canceled by timeout")
+	}
+	// invalid config
+	if factory.NewSession(map[string]interface{}{"path": 1}, host) != nil {
+		t.Error("invalid config should not create a session")
+	}
+}
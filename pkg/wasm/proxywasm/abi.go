@@ -0,0 +1,520 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxywasm
+
+import (
+	"context"
+	"time"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/wasm"
+	"mosn.io/pkg/buffer"
+)
+
+const importModule = "env"
+
+// i32Func defines a host function whose parameters and result are i32
+func i32Func(name string, numParams int, f func(p []uint64) Result) wasm.HostFunction {
+	params := make([]wasm.ValueType, numParams)
+	for idx := range params {
+		params[idx] = wasm.ValueTypeI32
+	}
+	return wasm.HostFunction{
+		Module:  importModule,
+		Name:    name,
+		Params:  params,
+		Results: []wasm.ValueType{wasm.ValueTypeI32},
+		Func: func(p []uint64) uint64 {
+			return uint64(uint32(f(p)))
+		},
+	}
+}
+
+// i32 returns the unsigned i32 parameter, the pointers and the sizes are unsigned
+func i32(v uint64) uint64 {
+	return uint64(uint32(v))
+}
+
+// unimplemented returns a host function that is not supported
+func unimplemented(name string, numParams int) wasm.HostFunction {
+	return i32Func(name, numParams, func(p []uint64) Result {
+		return ResultUnimplemented
+	})
+}
+
+// hostFunctions returns the proxy-wasm abi 0.2 functions imported by the wasm module
+func (i *Instance) hostFunctions() []wasm.HostFunction {
+	metricFunc := func(name string, f func(p []uint64) Result) wasm.HostFunction {
+		fn := i32Func(name, 2, f)
+		fn.Params[1] = wasm.ValueTypeI64
+		return fn
+	}
+	return []wasm.HostFunction{
+		i32Func("proxy_log", 3, i.proxyLog),
+		i32Func("proxy_get_log_level", 1, i.proxyGetLogLevel),
+		i32Func("proxy_set_tick_period_milliseconds", 1, i.proxySetTickPeriod),
+		i32Func("proxy_get_current_time_nanoseconds", 1, i.proxyGetCurrentTime),
+		i32Func("proxy_set_effective_context", 1, i.proxySetEffectiveContext),
+		i32Func("proxy_done", 0, func(p []uint64) Result { return ResultOk }),
+
+		i32Func("proxy_get_buffer_bytes", 5, i.proxyGetBufferBytes),
+		i32Func("proxy_set_buffer_bytes", 5, i.proxySetBufferBytes),
+		i32Func("proxy_get_header_map_pairs", 3, i.proxyGetHeaderMapPairs),
+		i32Func("proxy_set_header_map_pairs", 3, i.proxySetHeaderMapPairs),
+		i32Func("proxy_get_header_map_value", 5, i.proxyGetHeaderMapValue),
+		i32Func("proxy_replace_header_map_value", 5, i.proxyReplaceHeaderMapValue),
+		i32Func("proxy_add_header_map_value", 5, i.proxyAddHeaderMapValue),
+		i32Func("proxy_remove_header_map_value", 3, i.proxyRemoveHeaderMapValue),
+
+		i32Func("proxy_get_property", 4, i.proxyGetProperty),
+		i32Func("proxy_set_property", 4, i.proxySetProperty),
+
+		i32Func("proxy_get_shared_data", 5, i.proxyGetSharedData),
+		i32Func("proxy_set_shared_data", 5, i.proxySetSharedData),
+		unimplemented("proxy_register_shared_queue", 3),
+		unimplemented("proxy_resolve_shared_queue", 5),
+		unimplemented("proxy_dequeue_shared_queue", 3),
+		unimplemented("proxy_enqueue_shared_queue", 3),
+
+		i32Func("proxy_continue_stream", 1, i.proxyContinueStream),
+		i32Func("proxy_close_stream", 1, i.proxyCloseStream),
+		// proxy_continue_request and proxy_continue_response are used by the abi 0.1
+		i32Func("proxy_continue_request", 0, func(p []uint64) Result {
+			return i.proxyContinueStream([]uint64{uint64(StreamTypeRequest)})
+		}),
+		i32Func("proxy_continue_response", 0, func(p []uint64) Result {
+			return i.proxyContinueStream([]uint64{uint64(StreamTypeResponse)})
+		}),
+		i32Func("proxy_send_local_response", 8, i.proxySendLocalResponse),
+
+		i32Func("proxy_http_call", 10, i.proxyHttpCall),
+		unimplemented("proxy_grpc_call", 12),
+		unimplemented("proxy_grpc_stream", 9),
+		unimplemented("proxy_grpc_send", 4),
+		unimplemented("proxy_grpc_cancel", 1),
+		unimplemented("proxy_grpc_close", 1),
+		unimplemented("proxy_get_status", 3),
+		unimplemented("proxy_call_foreign_function", 6),
+
+		i32Func("proxy_define_metric", 4, i.proxyDefineMetric),
+		metricFunc("proxy_increment_metric", i.proxyIncrementMetric),
+		metricFunc("proxy_record_metric", i.proxyRecordMetric),
+		i32Func("proxy_get_metric", 2, i.proxyGetMetric),
+	}
+}
+
+func (i *Instance) currentContext() context.Context {
+	if i.current != nil && i.current.ctx != nil {
+		return i.current.ctx
+	}
+	return context.Background()
+}
+
+func (i *Instance) currentHandler() Handler {
+	if i.current != nil {
+		return i.current.handler
+	}
+	return nil
+}
+
+func (i *Instance) proxyLog(p []uint64) Result {
+	msg, ok := i.readString(i32(p[1]), i32(p[2]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	ctx := i.currentContext()
+	switch LogLevel(int32(p[0])) {
+	case LogLevelTrace, LogLevelDebug:
+		if log.Proxy.GetLogLevel() >= log.DEBUG {
+			log.Proxy.Debugf(ctx, "[wasm] [%s] %s", i.plugin.name, msg)
+		}
+	case LogLevelInfo:
+		log.Proxy.Infof(ctx, "[wasm] [%s] %s", i.plugin.name, msg)
+	case LogLevelWarn:
+		log.Proxy.Warnf(ctx, "[wasm] [%s] %s", i.plugin.name, msg)
+	default:
+		log.Proxy.Errorf(ctx, "[wasm] [%s] %s", i.plugin.name, msg)
+	}
+	return ResultOk
+}
+
+func (i *Instance) proxyGetLogLevel(p []uint64) Result {
+	level := LogLevelError
+	switch log.Proxy.GetLogLevel() {
+	case log.TRACE:
+		level = LogLevelTrace
+	case log.DEBUG:
+		level = LogLevelDebug
+	case log.INFO:
+		level = LogLevelInfo
+	case log.WARN:
+		level = LogLevelWarn
+	case log.FATAL:
+		level = LogLevelCritical
+	}
+	if !i.writeUint32(i32(p[0]), uint32(level)) {
+		return ResultInvalidMemoryAccess
+	}
+	return ResultOk
+}
+
+func (i *Instance) proxySetTickPeriod(p []uint64) Result {
+	i.setTickPeriod(time.Duration(i32(p[0])) * time.Millisecond)
+	return ResultOk
+}
+
+func (i *Instance) proxyGetCurrentTime(p []uint64) Result {
+	if !i.writeUint64(i32(p[0]), uint64(time.Now().UnixNano())) {
+		return ResultInvalidMemoryAccess
+	}
+	return ResultOk
+}
+
+func (i *Instance) proxySetEffectiveContext(p []uint64) Result {
+	c, ok := i.contexts[int32(p[0])]
+	if !ok {
+		return ResultBadArgument
+	}
+	i.current = c
+	return ResultOk
+}
+
+func (i *Instance) getBuffer(bufferType BufferType) buffer.IoBuffer {
+	switch bufferType {
+	case BufferTypeVMConfiguration:
+		return buffer.NewIoBufferBytes(i.plugin.vmConfig)
+	case BufferTypePluginConfiguration:
+		return buffer.NewIoBufferBytes(i.plugin.pluginConfig)
+	case BufferTypeHttpCallResponseBody:
+		if i.callResponse != nil {
+			return i.callResponse.body
+		}
+		return nil
+	}
+	if handler := i.currentHandler(); handler != nil {
+		return handler.GetBuffer(bufferType)
+	}
+	return nil
+}
+
+func (i *Instance) getHeaderMap(mapType MapType) api.HeaderMap {
+	switch mapType {
+	case MapTypeHttpCallResponseHeaders:
+		if i.callResponse != nil {
+			return i.callResponse.headers
+		}
+		return nil
+	case MapTypeHttpCallResponseTrailers:
+		return nil
+	}
+	if handler := i.currentHandler(); handler != nil {
+		return handler.GetHeaderMap(mapType)
+	}
+	return nil
+}
+
+func (i *Instance) proxyGetBufferBytes(p []uint64) Result {
+	buf := i.getBuffer(BufferType(int32(p[0])))
+	if buf == nil {
+		return ResultNotFound
+	}
+	data := buf.Bytes()
+	start, maxSize := i32(p[1]), i32(p[2])
+	if start > uint64(len(data)) {
+		return ResultBadArgument
+	}
+	end := start + maxSize
+	if end > uint64(len(data)) {
+		end = uint64(len(data))
+	}
+	return i.copyOut(data[start:end], i32(p[3]), i32(p[4]))
+}
+
+// proxySetBufferBytes replaces the bytes in [start, start+size) by the data
+func (i *Instance) proxySetBufferBytes(p []uint64) Result {
+	bufferType := BufferType(int32(p[0]))
+	if bufferType > BufferTypeUpstreamData {
+		return ResultBadArgument
+	}
+	buf := i.getBuffer(bufferType)
+	if buf == nil {
+		return ResultNotFound
+	}
+	value, ok := i.readBytes(i32(p[3]), i32(p[4]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	data := buf.Bytes()
+	start, size := i32(p[1]), i32(p[2])
+	if start > uint64(len(data)) {
+		return ResultBadArgument
+	}
+	end := start + size
+	if end > uint64(len(data)) {
+		end = uint64(len(data))
+	}
+	// the data is copied since it shares the memory with the buffer
+	newData := make([]byte, 0, uint64(len(data))-(end-start)+uint64(len(value)))
+	newData = append(newData, data[:start]...)
+	newData = append(newData, value...)
+	newData = append(newData, data[end:]...)
+	buf.Reset()
+	buf.Write(newData)
+	return ResultOk
+}
+
+func (i *Instance) proxyGetHeaderMapPairs(p []uint64) Result {
+	headers := i.getHeaderMap(MapType(int32(p[0])))
+	if headers == nil {
+		return ResultNotFound
+	}
+	return i.copyOut(encodePairs(headerPairs(headers)), i32(p[1]), i32(p[2]))
+}
+
+func (i *Instance) proxySetHeaderMapPairs(p []uint64) Result {
+	headers := i.getHeaderMap(MapType(int32(p[0])))
+	if headers == nil {
+		return ResultNotFound
+	}
+	data, ok := i.readBytes(i32(p[1]), i32(p[2]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	pairs, ok := decodePairs(data)
+	if !ok {
+		return ResultBadArgument
+	}
+	setHeaderPairs(headers, pairs)
+	return ResultOk
+}
+
+func (i *Instance) proxyGetHeaderMapValue(p []uint64) Result {
+	headers := i.getHeaderMap(MapType(int32(p[0])))
+	if headers == nil {
+		return ResultNotFound
+	}
+	key, ok := i.readString(i32(p[1]), i32(p[2]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	value, ok := getHeader(headers, key)
+	if !ok {
+		return ResultNotFound
+	}
+	return i.copyOut([]byte(value), i32(p[3]), i32(p[4]))
+}
+
+// mutateHeader reads the key and the value, and mutates the header map
+func (i *Instance) mutateHeader(p []uint64, mutate func(headers api.HeaderMap, key, value string)) Result {
+	headers := i.getHeaderMap(MapType(int32(p[0])))
+	if headers == nil {
+		return ResultNotFound
+	}
+	key, ok := i.readString(i32(p[1]), i32(p[2]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	var value string
+	if len(p) > 3 {
+		if value, ok = i.readString(i32(p[3]), i32(p[4])); !ok {
+			return ResultInvalidMemoryAccess
+		}
+	}
+	mutate(headers, key, value)
+	return ResultOk
+}
+
+func (i *Instance) proxyReplaceHeaderMapValue(p []uint64) Result {
+	return i.mutateHeader(p, setHeader)
+}
+
+func (i *Instance) proxyAddHeaderMapValue(p []uint64) Result {
+	return i.mutateHeader(p, addHeader)
+}
+
+func (i *Instance) proxyRemoveHeaderMapValue(p []uint64) Result {
+	return i.mutateHeader(p, func(headers api.HeaderMap, key, value string) {
+		removeHeader(headers, key)
+	})
+}
+
+func (i *Instance) proxyGetProperty(p []uint64) Result {
+	data, ok := i.readBytes(i32(p[0]), i32(p[1]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	var ctx context.Context
+	if i.current != nil {
+		ctx = i.current.ctx
+	}
+	value, ok := i.getProperty(ctx, splitPropertyPath(data))
+	if !ok {
+		return ResultNotFound
+	}
+	return i.copyOut([]byte(value), i32(p[2]), i32(p[3]))
+}
+
+func (i *Instance) proxySetProperty(p []uint64) Result {
+	data, ok := i.readBytes(i32(p[0]), i32(p[1]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	value, ok := i.readString(i32(p[2]), i32(p[3]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	var ctx context.Context
+	if i.current != nil {
+		ctx = i.current.ctx
+	}
+	return i.setProperty(ctx, splitPropertyPath(data), value)
+}
+
+func (i *Instance) proxyGetSharedData(p []uint64) Result {
+	key, ok := i.readString(i32(p[0]), i32(p[1]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	value, cas, result := globalSharedData.get(key)
+	if result != ResultOk {
+		return result
+	}
+	if result := i.copyOut(value, i32(p[2]), i32(p[3])); result != ResultOk {
+		return result
+	}
+	if !i.writeUint32(i32(p[4]), cas) {
+		return ResultInvalidMemoryAccess
+	}
+	return ResultOk
+}
+
+func (i *Instance) proxySetSharedData(p []uint64) Result {
+	key, ok := i.readString(i32(p[0]), i32(p[1]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	value, ok := i.readBytes(i32(p[2]), i32(p[3]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	return globalSharedData.set(key, value, uint32(p[4]))
+}
+
+func (i *Instance) proxyContinueStream(p []uint64) Result {
+	handler := i.currentHandler()
+	if handler == nil {
+		return ResultBadArgument
+	}
+	handler.Resume(StreamType(int32(p[0])))
+	return ResultOk
+}
+
+func (i *Instance) proxyCloseStream(p []uint64) Result {
+	handler := i.currentHandler()
+	if handler == nil {
+		return ResultBadArgument
+	}
+	handler.Close(StreamType(int32(p[0])))
+	return ResultOk
+}
+
+func (i *Instance) proxySendLocalResponse(p []uint64) Result {
+	handler := i.currentHandler()
+	if handler == nil {
+		return ResultBadArgument
+	}
+	body, ok := i.readBytes(i32(p[3]), i32(p[4]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	data, ok := i.readBytes(i32(p[5]), i32(p[6]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	headers, ok := decodePairs(data)
+	if !ok {
+		return ResultBadArgument
+	}
+	handler.SendLocalResponse(int(int32(p[0])), headers, body)
+	return ResultOk
+}
+
+func (i *Instance) proxyHttpCall(p []uint64) Result {
+	clusterName, ok := i.readString(i32(p[0]), i32(p[1]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	data, ok := i.readBytes(i32(p[2]), i32(p[3]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	headers, ok := decodePairs(data)
+	if !ok {
+		return ResultBadArgument
+	}
+	body, ok := i.readBytes(i32(p[4]), i32(p[5]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	if i.current == nil {
+		return ResultBadArgument
+	}
+	id, result := i.httpCall(i.current, clusterName, headers, body, time.Duration(i32(p[8]))*time.Millisecond)
+	if result != ResultOk {
+		return result
+	}
+	if !i.writeUint32(i32(p[9]), uint32(id)) {
+		return ResultInvalidMemoryAccess
+	}
+	return ResultOk
+}
+
+func (i *Instance) proxyDefineMetric(p []uint64) Result {
+	name, ok := i.readString(i32(p[1]), i32(p[2]))
+	if !ok {
+		return ResultInvalidMemoryAccess
+	}
+	id, result := i.plugin.metrics.define(MetricType(int32(p[0])), name)
+	if result != ResultOk {
+		return result
+	}
+	if !i.writeUint32(i32(p[3]), uint32(id)) {
+		return ResultInvalidMemoryAccess
+	}
+	return ResultOk
+}
+
+func (i *Instance) proxyIncrementMetric(p []uint64) Result {
+	return i.plugin.metrics.increment(int32(p[0]), int64(p[1]))
+}
+
+func (i *Instance) proxyRecordMetric(p []uint64) Result {
+	return i.plugin.metrics.record(int32(p[0]), int64(p[1]))
+}
+
+func (i *Instance) proxyGetMetric(p []uint64) Result {
+	value, result := i.plugin.metrics.value(int32(p[0]))
+	if result != ResultOk {
+		return result
+	}
+	if !i.writeUint64(i32(p[1]), uint64(value)) {
+		return ResultInvalidMemoryAccess
+	}
+	return ResultOk
+}
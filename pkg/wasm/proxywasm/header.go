@@ -0,0 +1,207 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxywasm
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/types"
+)
+
+// encodePairs serializes the header pairs in the proxy-wasm format:
+// the number of pairs, the sizes of the keys and values, then the null terminated keys and values
+func encodePairs(pairs [][2]string) []byte {
+	size := 4
+	for _, p := range pairs {
+		size += 8 + len(p[0]) + len(p[1]) + 2
+	}
+	data := make([]byte, size)
+	binary.LittleEndian.PutUint32(data, uint32(len(pairs)))
+	pos := 4
+	for _, p := range pairs {
+		binary.LittleEndian.PutUint32(data[pos:], uint32(len(p[0])))
+		binary.LittleEndian.PutUint32(data[pos+4:], uint32(len(p[1])))
+		pos += 8
+	}
+	for _, p := range pairs {
+		pos += copy(data[pos:], p[0]) + 1
+		pos += copy(data[pos:], p[1]) + 1
+	}
+	return data
+}
+
+// decodePairs parses the header pairs serialized by encodePairs
+func decodePairs(data []byte) ([][2]string, bool) {
+	if len(data) < 4 {
+		return nil, len(data) == 0
+	}
+	num := int(binary.LittleEndian.Uint32(data))
+	pos := 4
+	if num < 0 || num > (len(data)-pos)/8 {
+		return nil, false
+	}
+	sizes := make([][2]int, num)
+	for i := 0; i < num; i++ {
+		sizes[i][0] = int(binary.LittleEndian.Uint32(data[pos:]))
+		sizes[i][1] = int(binary.LittleEndian.Uint32(data[pos+4:]))
+		pos += 8
+	}
+	pairs := make([][2]string, 0, num)
+	for _, s := range sizes {
+		if s[0] < 0 || s[1] < 0 || pos+s[0]+s[1]+2 > len(data) {
+			return nil, false
+		}
+		key := string(data[pos : pos+s[0]])
+		pos += s[0] + 1
+		value := string(data[pos : pos+s[1]])
+		pos += s[1] + 1
+		pairs = append(pairs, [2]string{key, value})
+	}
+	return pairs, true
+}
+
+// the http pseudo headers used by the wasm modules, they are mapped to the mosn internal headers
+const (
+	pseudoMethod    = ":method"
+	pseudoPath      = ":path"
+	pseudoAuthority = ":authority"
+	pseudoStatus    = ":status"
+
+	mosnHeaderPrefix = "x-mosn-"
+)
+
+var pseudoHeaders = map[string]string{
+	pseudoMethod:    types.HeaderMethod,
+	pseudoAuthority: types.HeaderHost,
+	pseudoStatus:    types.HeaderStatus,
+}
+
+// getHeader gets the header, the pseudo headers are read from the mosn internal headers
+func getHeader(headers api.HeaderMap, key string) (string, bool) {
+	key = strings.ToLower(key)
+	if key == pseudoPath {
+		path, ok := headers.Get(types.HeaderPath)
+		if !ok {
+			return "", false
+		}
+		if query, _ := headers.Get(types.HeaderQueryString); query != "" {
+			path = path + "?" + query
+		}
+		return path, true
+	}
+	if internal, ok := pseudoHeaders[key]; ok {
+		if value, ok := headers.Get(internal); ok {
+			return value, true
+		}
+		if key == pseudoAuthority {
+			return headers.Get("host")
+		}
+		return "", false
+	}
+	return headers.Get(key)
+}
+
+func setHeader(headers api.HeaderMap, key, value string) {
+	key = strings.ToLower(key)
+	if key == pseudoPath {
+		path, query := value, ""
+		if idx := strings.IndexByte(value, '?'); idx >= 0 {
+			path, query = value[:idx], value[idx+1:]
+		}
+		headers.Set(types.HeaderPath, path)
+		if query != "" {
+			headers.Set(types.HeaderQueryString, query)
+		} else {
+			headers.Del(types.HeaderQueryString)
+		}
+		return
+	}
+	if internal, ok := pseudoHeaders[key]; ok {
+		key = internal
+	}
+	headers.Set(key, value)
+}
+
+// addHeader joins the value to the existing value, since the xprotocol header maps do not support Add
+func addHeader(headers api.HeaderMap, key, value string) {
+	if existing, ok := getHeader(headers, key); ok && existing != "" {
+		value = existing + "," + value
+	}
+	setHeader(headers, key, value)
+}
+
+func removeHeader(headers api.HeaderMap, key string) {
+	key = strings.ToLower(key)
+	if key == pseudoPath {
+		headers.Del(types.HeaderPath)
+		headers.Del(types.HeaderQueryString)
+		return
+	}
+	if internal, ok := pseudoHeaders[key]; ok {
+		key = internal
+	}
+	headers.Del(key)
+}
+
+// headerPairs returns the header pairs, the pseudo headers are in the front,
+// and the other mosn internal headers are not exposed
+func headerPairs(headers api.HeaderMap) [][2]string {
+	var pairs [][2]string
+	for _, key := range []string{pseudoMethod, pseudoPath, pseudoAuthority, pseudoStatus} {
+		internal := types.HeaderPath
+		if key != pseudoPath {
+			internal = pseudoHeaders[key]
+		}
+		if _, ok := headers.Get(internal); !ok {
+			continue
+		}
+		value, _ := getHeader(headers, key)
+		pairs = append(pairs, [2]string{key, value})
+	}
+	headers.Range(func(key, value string) bool {
+		if !strings.HasPrefix(strings.ToLower(key), mosnHeaderPrefix) {
+			pairs = append(pairs, [2]string{strings.ToLower(key), value})
+		}
+		return true
+	})
+	return pairs
+}
+
+// setHeaderPairs replaces all the headers except the mosn internal headers that are not mapped
+func setHeaderPairs(headers api.HeaderMap, pairs [][2]string) {
+	var keys []string
+	headers.Range(func(key, value string) bool {
+		if !strings.HasPrefix(strings.ToLower(key), mosnHeaderPrefix) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	for _, key := range keys {
+		headers.Del(key)
+	}
+	for _, p := range pairs {
+		// the pseudo headers replace the internal headers that are not removed
+		if strings.HasPrefix(p[0], ":") {
+			setHeader(headers, p[0], p[1])
+		} else {
+			addHeader(headers, p[0], p[1])
+		}
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxywasm
+
+import (
+	"reflect"
+	"testing"
+
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestEncodePairs(t *testing.T) {
+	pairs := [][2]string{{":path", "/"}, {"x-empty", ""}, {"key", "value"}}
+	decoded, ok := decodePairs(encodePairs(pairs))
+	if !ok || !reflect.DeepEqual(decoded, pairs) {
+		t.Fatalf("unexpected pairs: %v", decoded)
+	}
+	if decoded, ok := decodePairs(nil); !ok || len(decoded) != 0 {
+		t.Fatal("expected empty pairs")
+	}
+	data := encodePairs(pairs)
+	for _, invalid := range [][]byte{data[:3], data[:len(data)-1], {0xff, 0xff, 0xff, 0x7f}} {
+		if _, ok := decodePairs(invalid); ok {
+			t.Errorf("expected decode %v failed", invalid)
+		}
+	}
+}
+
+func TestPseudoHeaders(t *testing.T) {
+	headers := protocol.CommonHeader{
+		types.HeaderMethod:      "GET",
+		types.HeaderPath:        "/api",
+		types.HeaderQueryString: "a=1",
+		types.HeaderHost:        "mosn.io",
+		types.HeaderStreamID:    "1",
+		"x-foo":                 "bar",
+	}
+	if path, _ := getHeader(headers, ":path"); path != "/api?a=1" {
+		t.Fatalf("unexpected path: %s", path)
+	}
+	pairs := headerPairs(headers)
+	expected := [][2]string{{":method", "GET"}, {":path", "/api?a=1"}, {":authority", "mosn.io"}, {"x-foo", "bar"}}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Fatalf("unexpected pairs: %v", pairs)
+	}
+	setHeader(headers, ":path", "/new")
+	if path, _ := headers.Get(types.HeaderPath); path != "/new" {
+		t.Fatalf("unexpected path: %s", path)
+	}
+	if _, ok := headers.Get(types.HeaderQueryString); ok {
+		t.Fatal("expected query string is removed")
+	}
+	addHeader(headers, "X-Foo", "baz")
+	if foo, _ := headers.Get("x-foo"); foo != "bar,baz" {
+		t.Fatalf("unexpected x-foo: %s", foo)
+	}
+	setHeaderPairs(headers, [][2]string{{":method", "POST"}, {"x-new", "1"}, {"x-new", "2"}})
+	if _, ok := headers.Get("x-foo"); ok {
+		t.Fatal("expected x-foo is removed")
+	}
+	if method, _ := headers.Get(types.HeaderMethod); method != "POST" {
+		t.Fatalf("unexpected method: %s", method)
+	}
+	if v, _ := headers.Get("x-new"); v != "1,2" {
+		t.Fatalf("unexpected x-new: %s", v)
+	}
+	// the internal headers are kept
+	if v, _ := headers.Get(types.HeaderStreamID); v != "1" {
+		t.Fatal("expected the internal header is kept")
+	}
+	removeHeader(headers, ":authority")
+	if _, ok := getHeader(headers, ":authority"); ok {
+		t.Fatal("expected authority is removed")
+	}
+}
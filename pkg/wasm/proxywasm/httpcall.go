@@ -0,0 +1,161 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxywasm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/upstream/cluster"
+	"mosn.io/pkg/buffer"
+	"mosn.io/pkg/utils"
+)
+
+// maxHttpCallResponseBody is the max size of the http callout response body
+const maxHttpCallResponseBody = 4 * 1024 * 1024
+
+var errNoCalloutHost = errors.New("no available host in the callout cluster")
+
+var httpCallClient = &http.Client{}
+
+// httpCallResponse is the response of the http callout, it is nil if the callout fails
+type httpCallResponse struct {
+	headers protocol.CommonHeader
+	body    buffer.IoBuffer
+}
+
+// clusterHost chooses a host in the cluster, it is overridden in the tests
+var clusterHost = func(ctx context.Context, clusterName string) (string, error) {
+	adapter := cluster.GetClusterMngAdapterInstance()
+	if adapter == nil {
+		return "", errNoCalloutHost
+	}
+	snapshot := adapter.GetClusterSnapshot(ctx, clusterName)
+	if snapshot == nil {
+		return "", fmt.Errorf("callout cluster %s is not found", clusterName)
+	}
+	host := snapshot.LoadBalancer().ChooseHost(cluster.NewLoadBalancerContext(ctx))
+	if host == nil {
+		return "", errNoCalloutHost
+	}
+	return host.AddressString(), nil
+}
+
+// httpCall sends the http request to the cluster asynchronously,
+// proxy_on_http_call_response of the context is called when the response is received
+func (i *Instance) httpCall(c *Context, clusterName string, pairs [][2]string, body []byte, timeout time.Duration) (int32, Result) {
+	var method, path, authority string
+	header := http.Header{}
+	for _, p := range pairs {
+		switch strings.ToLower(p[0]) {
+		case pseudoMethod:
+			method = p[1]
+		case pseudoPath:
+			path = p[1]
+		case pseudoAuthority:
+			authority = p[1]
+		default:
+			header.Add(p[0], p[1])
+		}
+	}
+	if clusterName == "" || method == "" || path == "" || authority == "" {
+		return 0, ResultBadArgument
+	}
+	i.nextCallID++
+	id := i.nextCallID
+	utils.GoWithRecover(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		resp, err := doHttpCall(ctx, clusterName, method, path, authority, header, body)
+		if err != nil {
+			log.DefaultLogger.Errorf("[wasm] [%s] http callout to %s failed: %v", i.plugin.name, clusterName, err)
+		}
+		i.onHttpCallResponse(c, id, resp)
+	}, nil)
+	return id, ResultOk
+}
+
+func doHttpCall(ctx context.Context, clusterName, method, path, authority string, header http.Header, body []byte) (*httpCallResponse, error) {
+	host, err := clusterHost(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	var reqBody io.Reader
+	if len(body) > 0 {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, "http://"+host+path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Host = authority
+	req.Header = header
+	resp, err := httpCallClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxHttpCallResponseBody))
+	if err != nil {
+		return nil, err
+	}
+	headers := protocol.CommonHeader{
+		types.HeaderStatus: strconv.Itoa(resp.StatusCode),
+	}
+	for key, values := range resp.Header {
+		headers.Set(strings.ToLower(key), strings.Join(values, ","))
+	}
+	return &httpCallResponse{
+		headers: headers,
+		body:    buffer.NewIoBufferBytes(respBody),
+	}, nil
+}
+
+func (i *Instance) onHttpCallResponse(c *Context, id int32, resp *httpCallResponse) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closed {
+		return
+	}
+	if _, ok := i.contexts[c.id]; !ok {
+		return
+	}
+	var numHeaders, bodySize int
+	if resp != nil {
+		numHeaders = len(resp.headers)
+		bodySize = resp.body.Len()
+	}
+	i.callResponse = resp
+	i.current = c
+	if _, err := i.call("proxy_on_http_call_response", uint64(c.id), uint64(id), uint64(numHeaders), uint64(bodySize), 0); err != nil {
+		log.DefaultLogger.Errorf("[wasm] [%s] call proxy_on_http_call_response failed: %v", i.plugin.name, err)
+	}
+	i.callResponse = nil
+}
@@ -0,0 +1,440 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxywasm
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/wasm"
+	"mosn.io/pkg/buffer"
+	"mosn.io/pkg/utils"
+)
+
+var (
+	ErrInstanceClosed = errors.New("wasm instance is closed")
+	errNoAllocator    = errors.New("wasm module exports neither proxy_on_memory_allocate nor malloc")
+)
+
+// Handler exposes a stream or a connection to the wasm module,
+// the methods are called with the instance locked, so they should not call the instance
+type Handler interface {
+	// GetHeaderMap returns the header map of the type, nil if it is not available
+	GetHeaderMap(mapType MapType) api.HeaderMap
+	// GetBuffer returns the buffer of the type, nil if it is not available
+	GetBuffer(bufferType BufferType) buffer.IoBuffer
+	// SendLocalResponse is called when the module responds the request directly
+	SendLocalResponse(status int, headers [][2]string, body []byte)
+	// Resume is called when the module continues the paused stream
+	Resume(stream StreamType)
+	// Close is called when the module closes the stream
+	Close(stream StreamType)
+}
+
+// Context is a root, stream or connection context in the wasm instance
+type Context struct {
+	id       int32
+	instance *Instance
+	ctx      context.Context
+	handler  Handler
+}
+
+// ID returns the context id
+func (c *Context) ID() int32 {
+	return c.id
+}
+
+// generation is the module and the instances loaded at the same time,
+// the module is closed when all the instances are closed
+type generation struct {
+	module wasm.Module
+	live   int32
+}
+
+func (g *generation) release() {
+	if atomic.AddInt32(&g.live, -1) == 0 {
+		g.module.Close()
+	}
+}
+
+// Instance is a wasm instance that runs the proxy-wasm abi, the calls into the instance are serialized
+type Instance struct {
+	mu       sync.Mutex
+	plugin   *Plugin
+	gen      *generation
+	instance wasm.Instance
+	root     *Context
+	contexts map[int32]*Context
+	nextID   int32
+	// current is the effective context of the host functions
+	current *Context
+	// callResponse is the http callout response being dispatched
+	callResponse *httpCallResponse
+	nextCallID   int32
+	tickStop     chan struct{}
+	closed       bool
+
+	// refs counts the streams and connections that use the instance,
+	// a retired instance is closed when it is not used
+	refs    int32
+	retired int32
+}
+
+func newInstance(p *Plugin, gen *generation) (*Instance, error) {
+	inst := &Instance{
+		plugin:   p,
+		gen:      gen,
+		contexts: make(map[int32]*Context),
+	}
+	instance, err := gen.module.Instantiate(inst.hostFunctions())
+	if err != nil {
+		return nil, err
+	}
+	inst.instance = instance
+	atomic.AddInt32(&gen.live, 1)
+	if err := inst.start(); err != nil {
+		inst.close()
+		return nil, err
+	}
+	return inst, nil
+}
+
+// start initializes the module and creates the root context
+func (i *Instance) start() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, name := range []string{"_initialize", "_start"} {
+		if i.instance.HasFunction(name) {
+			if _, err := i.instance.Call(name); err != nil {
+				return fmt.Errorf("call %s failed: %v", name, err)
+			}
+			break
+		}
+	}
+	i.root = i.newContextLocked(context.Background(), nil)
+	i.current = i.root
+	if _, err := i.call("proxy_on_context_create", uint64(i.root.id), 0); err != nil {
+		return err
+	}
+	if ok, err := i.call("proxy_on_vm_start", uint64(i.root.id), uint64(len(i.plugin.vmConfig))); err != nil {
+		return err
+	} else if int32(ok) == 0 {
+		return errors.New("proxy_on_vm_start returns false")
+	}
+	if ok, err := i.call("proxy_on_configure", uint64(i.root.id), uint64(len(i.plugin.pluginConfig))); err != nil {
+		return err
+	} else if int32(ok) == 0 {
+		return errors.New("proxy_on_configure returns false")
+	}
+	return nil
+}
+
+// call calls the exported function, the function that is not exported is ignored
+func (i *Instance) call(name string, args ...uint64) (uint64, error) {
+	ret, err := i.instance.Call(name, args...)
+	if err == wasm.ErrFunctionNotFound {
+		return 0, nil
+	}
+	return ret, err
+}
+
+func (i *Instance) newContextLocked(ctx context.Context, handler Handler) *Context {
+	i.nextID++
+	c := &Context{
+		id:       i.nextID,
+		instance: i,
+		ctx:      ctx,
+		handler:  handler,
+	}
+	i.contexts[c.id] = c
+	return c
+}
+
+// NewContext creates a stream or connection context, the handler exposes the stream or the connection
+func (i *Instance) NewContext(ctx context.Context, handler Handler) (*Context, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closed {
+		return nil, ErrInstanceClosed
+	}
+	c := i.newContextLocked(ctx, handler)
+	i.current = c
+	if _, err := i.call("proxy_on_context_create", uint64(c.id), uint64(i.root.id)); err != nil {
+		delete(i.contexts, c.id)
+		return nil, err
+	}
+	return c, nil
+}
+
+// call calls the exported function with the context as the effective context,
+// the context id is passed as the first argument
+func (c *Context) call(name string, args ...uint64) (uint64, error) {
+	i := c.instance
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closed {
+		return 0, ErrInstanceClosed
+	}
+	if _, ok := i.contexts[c.id]; !ok {
+		return 0, fmt.Errorf("wasm context %d is deleted", c.id)
+	}
+	i.current = c
+	return i.call(name, append([]uint64{uint64(c.id)}, args...)...)
+}
+
+func (c *Context) callAction(name string, args ...uint64) (Action, error) {
+	ret, err := c.call(name, args...)
+	if err != nil {
+		return ActionContinue, err
+	}
+	return Action(int32(ret)), nil
+}
+
+func boolArg(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// OnRequestHeaders calls proxy_on_request_headers
+func (c *Context) OnRequestHeaders(numHeaders int, endOfStream bool) (Action, error) {
+	return c.callAction("proxy_on_request_headers", uint64(numHeaders), boolArg(endOfStream))
+}
+
+// OnRequestBody calls proxy_on_request_body
+func (c *Context) OnRequestBody(size int, endOfStream bool) (Action, error) {
+	return c.callAction("proxy_on_request_body", uint64(size), boolArg(endOfStream))
+}
+
+// OnRequestTrailers calls proxy_on_request_trailers
+func (c *Context) OnRequestTrailers(numTrailers int) (Action, error) {
+	return c.callAction("proxy_on_request_trailers", uint64(numTrailers))
+}
+
+// OnResponseHeaders calls proxy_on_response_headers
+func (c *Context) OnResponseHeaders(numHeaders int, endOfStream bool) (Action, error) {
+	return c.callAction("proxy_on_response_headers", uint64(numHeaders), boolArg(endOfStream))
+}
+
+// OnResponseBody calls proxy_on_response_body
+func (c *Context) OnResponseBody(size int, endOfStream bool) (Action, error) {
+	return c.callAction("proxy_on_response_body", uint64(size), boolArg(endOfStream))
+}
+
+// OnResponseTrailers calls proxy_on_response_trailers
+func (c *Context) OnResponseTrailers(numTrailers int) (Action, error) {
+	return c.callAction("proxy_on_response_trailers", uint64(numTrailers))
+}
+
+// OnNewConnection calls proxy_on_new_connection
+func (c *Context) OnNewConnection() (Action, error) {
+	return c.callAction("proxy_on_new_connection")
+}
+
+// OnDownstreamData calls proxy_on_downstream_data
+func (c *Context) OnDownstreamData(size int, endOfStream bool) (Action, error) {
+	return c.callAction("proxy_on_downstream_data", uint64(size), boolArg(endOfStream))
+}
+
+// OnUpstreamData calls proxy_on_upstream_data
+func (c *Context) OnUpstreamData(size int, endOfStream bool) (Action, error) {
+	return c.callAction("proxy_on_upstream_data", uint64(size), boolArg(endOfStream))
+}
+
+// OnDownstreamConnectionClose calls proxy_on_downstream_connection_close
+func (c *Context) OnDownstreamConnectionClose(peer PeerType) error {
+	_, err := c.call("proxy_on_downstream_connection_close", uint64(peer))
+	return err
+}
+
+// OnUpstreamConnectionClose calls proxy_on_upstream_connection_close
+func (c *Context) OnUpstreamConnectionClose(peer PeerType) error {
+	_, err := c.call("proxy_on_upstream_connection_close", uint64(peer))
+	return err
+}
+
+// Delete logs and deletes the context, the context can not be used after it is deleted
+func (c *Context) Delete() {
+	i := c.instance
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if _, ok := i.contexts[c.id]; !ok || i.closed {
+		return
+	}
+	i.current = c
+	for _, name := range []string{"proxy_on_log", "proxy_on_done", "proxy_on_delete"} {
+		if _, err := i.call(name, uint64(c.id)); err != nil {
+			log.DefaultLogger.Errorf("[wasm] [%s] call %s of context %d failed: %v", i.plugin.name, name, c.id, err)
+		}
+	}
+	delete(i.contexts, c.id)
+}
+
+// Acquire marks the instance is used by a stream or a connection
+func (i *Instance) Acquire() {
+	atomic.AddInt32(&i.refs, 1)
+}
+
+// Release marks the instance is not used by the stream or the connection
+func (i *Instance) Release() {
+	if atomic.AddInt32(&i.refs, -1) == 0 && atomic.LoadInt32(&i.retired) == 1 {
+		i.close()
+	}
+}
+
+// retire closes the instance when it is not used
+func (i *Instance) retire() {
+	atomic.StoreInt32(&i.retired, 1)
+	if atomic.LoadInt32(&i.refs) == 0 {
+		i.close()
+	}
+}
+
+func (i *Instance) close() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closed {
+		return
+	}
+	i.closed = true
+	i.stopTickLocked()
+	if i.root != nil {
+		i.current = i.root
+		i.call("proxy_on_done", uint64(i.root.id))
+		i.call("proxy_on_delete", uint64(i.root.id))
+	}
+	i.instance.Close()
+	i.gen.release()
+}
+
+// setTickPeriod calls proxy_on_tick of the root context periodically, 0 stops the ticks
+func (i *Instance) setTickPeriod(period time.Duration) {
+	i.stopTickLocked()
+	if period <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	i.tickStop = stop
+	utils.GoWithRecover(func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				i.onTick()
+			}
+		}
+	}, nil)
+}
+
+func (i *Instance) stopTickLocked() {
+	if i.tickStop != nil {
+		close(i.tickStop)
+		i.tickStop = nil
+	}
+}
+
+func (i *Instance) onTick() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closed {
+		return
+	}
+	i.current = i.root
+	if _, err := i.call("proxy_on_tick", uint64(i.root.id)); err != nil {
+		log.DefaultLogger.Errorf("[wasm] [%s] call proxy_on_tick failed: %v", i.plugin.name, err)
+	}
+}
+
+// memory helpers, they are called by the host functions with the instance locked
+
+func (i *Instance) readBytes(ptr, size uint64) ([]byte, bool) {
+	mem := i.instance.Memory()
+	if ptr+size > uint64(len(mem)) || ptr+size < ptr {
+		return nil, false
+	}
+	data := make([]byte, size)
+	copy(data, mem[ptr:ptr+size])
+	return data, true
+}
+
+func (i *Instance) readString(ptr, size uint64) (string, bool) {
+	data, ok := i.readBytes(ptr, size)
+	return string(data), ok
+}
+
+func (i *Instance) writeBytes(ptr uint64, data []byte) bool {
+	mem := i.instance.Memory()
+	if ptr+uint64(len(data)) > uint64(len(mem)) {
+		return false
+	}
+	copy(mem[ptr:], data)
+	return true
+}
+
+func (i *Instance) writeUint32(ptr uint64, v uint32) bool {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	return i.writeBytes(ptr, b[:])
+}
+
+func (i *Instance) writeUint64(ptr uint64, v uint64) bool {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return i.writeBytes(ptr, b[:])
+}
+
+func (i *Instance) malloc(size int) (uint64, error) {
+	for _, name := range []string{"proxy_on_memory_allocate", "malloc"} {
+		if i.instance.HasFunction(name) {
+			addr, err := i.instance.Call(name, uint64(size))
+			return uint64(uint32(addr)), err
+		}
+	}
+	return 0, errNoAllocator
+}
+
+// copyOut allocates the memory in the instance, copies the data to it,
+// and writes the address and the size to the return pointers
+func (i *Instance) copyOut(data []byte, retPtr, retSize uint64) Result {
+	var addr uint64
+	if len(data) > 0 {
+		var err error
+		if addr, err = i.malloc(len(data)); err != nil {
+			log.DefaultLogger.Errorf("[wasm] [%s] allocate memory failed: %v", i.plugin.name, err)
+			return ResultInternalFailure
+		}
+		if !i.writeBytes(addr, data) {
+			return ResultInvalidMemoryAccess
+		}
+	}
+	if !i.writeUint32(retPtr, uint32(addr)) || !i.writeUint32(retSize, uint32(len(data))) {
+		return ResultInvalidMemoryAccess
+	}
+	return ResultOk
+}
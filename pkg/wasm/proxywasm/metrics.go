@@ -0,0 +1,120 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxywasm
+
+import (
+	"sync"
+
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/types"
+)
+
+// pluginMetrics is the metrics defined by the wasm module, the instances of a plugin share the metrics
+type pluginMetrics struct {
+	mu      sync.Mutex
+	stats   types.Metrics
+	defs    []metricDef
+	defined map[metricDef]int32
+}
+
+type metricDef struct {
+	typ  MetricType
+	name string
+}
+
+func newPluginMetrics(pluginName string) *pluginMetrics {
+	return &pluginMetrics{
+		stats:   metrics.NewWasmStats(pluginName),
+		defined: make(map[metricDef]int32),
+	}
+}
+
+// define returns the metric id, the same id is returned if the metric is defined
+func (m *pluginMetrics) define(typ MetricType, name string) (int32, Result) {
+	if typ < MetricTypeCounter || typ > MetricTypeHistogram {
+		return 0, ResultBadArgument
+	}
+	def := metricDef{typ: typ, name: name}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id, ok := m.defined[def]; ok {
+		return id, ResultOk
+	}
+	id := int32(len(m.defs))
+	m.defs = append(m.defs, def)
+	m.defined[def] = id
+	return id, ResultOk
+}
+
+func (m *pluginMetrics) get(id int32) (metricDef, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id < 0 || int(id) >= len(m.defs) {
+		return metricDef{}, false
+	}
+	return m.defs[id], true
+}
+
+func (m *pluginMetrics) increment(id int32, offset int64) Result {
+	def, ok := m.get(id)
+	if !ok {
+		return ResultNotFound
+	}
+	switch def.typ {
+	case MetricTypeCounter:
+		m.stats.Counter(def.name).Inc(offset)
+	case MetricTypeGauge:
+		gauge := m.stats.Gauge(def.name)
+		gauge.Update(gauge.Value() + offset)
+	default:
+		return ResultBadArgument
+	}
+	return ResultOk
+}
+
+func (m *pluginMetrics) record(id int32, value int64) Result {
+	def, ok := m.get(id)
+	if !ok {
+		return ResultNotFound
+	}
+	switch def.typ {
+	case MetricTypeGauge:
+		m.stats.Gauge(def.name).Update(value)
+	case MetricTypeHistogram:
+		// the unit of the plugin histograms is unknown, the duration buckets are not used
+		m.stats.HistogramWithBuckets(def.name, nil).Update(value)
+	default:
+		return ResultBadArgument
+	}
+	return ResultOk
+}
+
+func (m *pluginMetrics) value(id int32) (int64, Result) {
+	def, ok := m.get(id)
+	if !ok {
+		return 0, ResultNotFound
+	}
+	switch def.typ {
+	case MetricTypeCounter:
+		return m.stats.Counter(def.name).Count(), ResultOk
+	case MetricTypeGauge:
+		return m.stats.Gauge(def.name).Value(), ResultOk
+	default:
+		return 0, ResultBadArgument
+	}
+}
@@ -0,0 +1,258 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxywasm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/wasm"
+	"mosn.io/pkg/utils"
+)
+
+// defaultEngine is the engine used if the plugin does not set one,
+// it is registered by the wazero package which is built with the wazero build tag
+const defaultEngine = "wazero"
+
+// Plugin is a proxy-wasm plugin, it runs the module in several instances,
+// and the module is reloaded if the file is changed
+type Plugin struct {
+	name         string
+	config       v2.WasmPluginConfig
+	engine       wasm.Engine
+	vmConfig     []byte
+	pluginConfig []byte
+	metrics      *pluginMetrics
+
+	mu        sync.RWMutex
+	instances []*Instance
+	next      uint32
+	modTime   time.Time
+	size      int64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func configBytes(v interface{}) ([]byte, error) {
+	switch c := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(c), nil
+	default:
+		return json.Marshal(c)
+	}
+}
+
+// NewPlugin creates a plugin and loads the module
+func NewPlugin(cfg *v2.WasmPluginConfig) (*Plugin, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("wasm plugin has no name")
+	}
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("wasm plugin %s has no module path", cfg.Name)
+	}
+	engineName := cfg.Engine
+	if engineName == "" {
+		engineName = defaultEngine
+	}
+	engine, err := wasm.GetEngine(engineName)
+	if err != nil {
+		if engineName == defaultEngine {
+			return nil, fmt.Errorf("wasm plugin %s: engine %s is not registered, mosn should be built with the %s build tag", cfg.Name, engineName, defaultEngine)
+		}
+		return nil, fmt.Errorf("wasm plugin %s: engine %s is not registered", cfg.Name, engineName)
+	}
+	p := &Plugin{
+		name:    cfg.Name,
+		config:  *cfg,
+		engine:  engine,
+		metrics: newPluginMetrics(cfg.Name),
+		stop:    make(chan struct{}),
+	}
+	if p.vmConfig, err = configBytes(cfg.VMConfig); err != nil {
+		return nil, err
+	}
+	if p.pluginConfig, err = configBytes(cfg.Configuration); err != nil {
+		return nil, err
+	}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	if cfg.ReloadInterval != nil && cfg.ReloadInterval.Duration > 0 {
+		interval := cfg.ReloadInterval.Duration
+		utils.GoWithRecover(func() {
+			p.reloadLoop(interval)
+		}, nil)
+	}
+	return p, nil
+}
+
+// Name returns the plugin name
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+// load compiles the module and replaces the instances, the old instances are closed when they are not used
+func (p *Plugin) load() error {
+	info, err := os.Stat(p.config.Path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(p.config.Path)
+	if err != nil {
+		return err
+	}
+	module, err := p.engine.Compile(data)
+	if err != nil {
+		return fmt.Errorf("compile wasm module %s failed: %v", p.config.Path, err)
+	}
+	// holds the module until all the instances are created
+	gen := &generation{
+		module: module,
+		live:   1,
+	}
+	defer gen.release()
+	num := p.config.InstanceNum
+	if num <= 0 {
+		num = runtime.NumCPU()
+	}
+	instances := make([]*Instance, 0, num)
+	for n := 0; n < num; n++ {
+		inst, err := newInstance(p, gen)
+		if err != nil {
+			for _, created := range instances {
+				created.retire()
+			}
+			return fmt.Errorf("start wasm plugin %s failed: %v", p.name, err)
+		}
+		instances = append(instances, inst)
+	}
+	p.mu.Lock()
+	old := p.instances
+	p.instances = instances
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	p.mu.Unlock()
+	for _, inst := range old {
+		inst.retire()
+	}
+	return nil
+}
+
+// changed returns whether the module file is changed since it is loaded
+func (p *Plugin) changed() bool {
+	info, err := os.Stat(p.config.Path)
+	if err != nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return !info.ModTime().Equal(p.modTime) || info.Size() != p.size
+}
+
+func (p *Plugin) reloadLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			if !p.changed() {
+				continue
+			}
+			if err := p.load(); err != nil {
+				log.DefaultLogger.Errorf("[wasm] [%s] reload the module failed, keep the old one: %v", p.name, err)
+			} else {
+				log.DefaultLogger.Infof("[wasm] [%s] the module is reloaded", p.name)
+			}
+		}
+	}
+}
+
+// GetInstance returns an instance in round robin, the instance should be released after it is used
+func (p *Plugin) GetInstance() *Instance {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.instances) == 0 {
+		return nil
+	}
+	inst := p.instances[atomic.AddUint32(&p.next, 1)%uint32(len(p.instances))]
+	inst.Acquire()
+	return inst
+}
+
+// Stop stops reloading the module, the instances are closed when they are not used
+func (p *Plugin) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+		p.mu.Lock()
+		instances := p.instances
+		p.instances = nil
+		p.mu.Unlock()
+		for _, inst := range instances {
+			inst.retire()
+		}
+	})
+}
+
+var plugins = struct {
+	sync.Mutex
+	m map[string]*Plugin
+}{
+	m: make(map[string]*Plugin),
+}
+
+// GetOrCreatePlugin returns the plugin of the config name,
+// a new plugin is created and the old one is stopped if the config is changed
+func GetOrCreatePlugin(cfg *v2.WasmPluginConfig) (*Plugin, error) {
+	plugins.Lock()
+	defer plugins.Unlock()
+	old, ok := plugins.m[cfg.Name]
+	if ok && reflect.DeepEqual(old.config, *cfg) {
+		return old, nil
+	}
+	p, err := NewPlugin(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		old.Stop()
+	}
+	plugins.m[cfg.Name] = p
+	return p, nil
+}
+
+// GetPlugin returns the plugin of the name, nil if it is not found
+func GetPlugin(name string) *Plugin {
+	plugins.Lock()
+	defer plugins.Unlock()
+	return plugins.m[name]
+}
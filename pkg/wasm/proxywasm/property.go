@@ -0,0 +1,111 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxywasm
+
+import (
+	"context"
+	"strings"
+
+	"mosn.io/mosn/pkg/proxy"
+	"mosn.io/mosn/pkg/variable"
+)
+
+// the properties of the plugin
+const (
+	propertyPluginName   = "plugin_name"
+	propertyPluginRootID = "plugin_root_id"
+	propertyPluginVMID   = "plugin_vm_id"
+)
+
+// propertyAliases maps the common proxy-wasm property paths to the mosn variables
+var propertyAliases = map[string]string{
+	"source.address":      proxy.VarDownstreamRemoteAddress,
+	"destination.address": proxy.VarDownstreamLocalAddress,
+	"upstream.address":    proxy.VarUpstreamHost,
+	"request.protocol":    proxy.VarProtocol,
+	"request.duration":    proxy.VarDuration,
+	"response.code":       proxy.VarResponseCode,
+}
+
+// the prefixes of the header properties, such as request.headers.x-foo
+var headerPropertyPrefixes = map[string]string{
+	"request.headers":  "request_header_",
+	"response.headers": "response_header_",
+}
+
+// propertyVariable returns the variable name of the property path,
+// the path segments are joined by '_' if the path has no alias, such as ["upstream_host"]
+func propertyVariable(path []string) string {
+	name := strings.Join(path, ".")
+	if alias, ok := propertyAliases[name]; ok {
+		return alias
+	}
+	if len(path) == 3 {
+		if prefix, ok := headerPropertyPrefixes[path[0]+"."+path[1]]; ok {
+			return prefix + path[2]
+		}
+	}
+	return strings.Join(path, "_")
+}
+
+// splitPropertyPath splits the path serialized by the null terminated segments
+func splitPropertyPath(data []byte) []string {
+	var path []string
+	for _, seg := range strings.Split(string(data), "\x00") {
+		if seg != "" {
+			path = append(path, seg)
+		}
+	}
+	return path
+}
+
+func (i *Instance) getProperty(ctx context.Context, path []string) (value string, ok bool) {
+	if len(path) == 0 {
+		return "", false
+	}
+	switch strings.Join(path, ".") {
+	case propertyPluginName, propertyPluginVMID:
+		return i.plugin.name, true
+	case propertyPluginRootID:
+		return i.plugin.config.RootID, true
+	}
+	if ctx == nil {
+		return "", false
+	}
+	// the getters of some variables require the proxy context
+	defer func() {
+		if r := recover(); r != nil {
+			value, ok = "", false
+		}
+	}()
+	v, err := variable.GetVariableValue(ctx, propertyVariable(path))
+	if err != nil || v == variable.ValueNotFound {
+		return "", false
+	}
+	return v, true
+}
+
+func (i *Instance) setProperty(ctx context.Context, path []string, value string) Result {
+	if len(path) == 0 || ctx == nil {
+		return ResultBadArgument
+	}
+	if err := variable.SetVariableValue(ctx, propertyVariable(path), value); err != nil {
+		return ResultNotFound
+	}
+	return ResultOk
+}
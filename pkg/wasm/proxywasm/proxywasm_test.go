@@ -0,0 +1,557 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxywasm
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/internal/wasmtest"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/variable"
+	"mosn.io/pkg/buffer"
+)
+
+// testGuest is the proxy-wasm sdk of the fake modules
+type testGuest struct {
+	*wasmtest.Guest
+}
+
+func (g testGuest) getHeader(mapType MapType, key string) (string, Result) {
+	kp, ks := g.WriteString(key)
+	data, r := g.HostReturnBytes("proxy_get_header_map_value", uint64(mapType), kp, ks)
+	return string(data), Result(r)
+}
+
+func (g testGuest) mutateHeader(name string, mapType MapType, key, value string) Result {
+	kp, ks := g.WriteString(key)
+	vp, vs := g.WriteString(value)
+	return Result(g.Host(name, uint64(mapType), kp, ks, vp, vs))
+}
+
+func (g testGuest) removeHeader(mapType MapType, key string) Result {
+	kp, ks := g.WriteString(key)
+	return Result(g.Host("proxy_remove_header_map_value", uint64(mapType), kp, ks))
+}
+
+func (g testGuest) getBuffer(bufferType BufferType, start, maxSize int) ([]byte, Result) {
+	data, r := g.HostReturnBytes("proxy_get_buffer_bytes", uint64(bufferType), uint64(start), uint64(maxSize))
+	return data, Result(r)
+}
+
+func (g testGuest) setBuffer(bufferType BufferType, start, size int, data string) Result {
+	dp, ds := g.WriteString(data)
+	return Result(g.Host("proxy_set_buffer_bytes", uint64(bufferType), uint64(start), uint64(size), dp, ds))
+}
+
+func (g testGuest) getProperty(path ...string) (string, Result) {
+	pp, ps := g.WriteString(strings.Join(path, "\x00"))
+	data, r := g.HostReturnBytes("proxy_get_property", pp, ps)
+	return string(data), Result(r)
+}
+
+func (g testGuest) sendLocalResponse(status int, body string, headers [][2]string) Result {
+	bp, bs := g.WriteString(body)
+	hp, hs := g.Write(encodePairs(headers))
+	return Result(g.Host("proxy_send_local_response", uint64(status), 0, 0, bp, bs, hp, hs, 0))
+}
+
+func (g testGuest) httpCall(cluster string, headers [][2]string, timeoutMs int) Result {
+	cp, cs := g.WriteString(cluster)
+	hp, hs := g.Write(encodePairs(headers))
+	idPtr := g.Alloc(4)
+	return Result(g.Host("proxy_http_call", cp, cs, hp, hs, 0, 0, 0, 0, uint64(timeoutMs), idPtr))
+}
+
+func (g testGuest) getSharedData(key string) (string, uint32, Result) {
+	kp, ks := g.WriteString(key)
+	ret := g.Alloc(12)
+	r := Result(g.Host("proxy_get_shared_data", kp, ks, ret, ret+4, ret+8))
+	if r != ResultOk {
+		return "", 0, r
+	}
+	return string(g.Read(uint64(g.ReadUint32(ret)), uint64(g.ReadUint32(ret+4)))), g.ReadUint32(ret + 8), r
+}
+
+func (g testGuest) setSharedData(key, value string, cas uint32) Result {
+	kp, ks := g.WriteString(key)
+	vp, vs := g.WriteString(value)
+	return Result(g.Host("proxy_set_shared_data", kp, ks, vp, vs, uint64(cas)))
+}
+
+func (g testGuest) defineMetric(typ MetricType, name string) uint32 {
+	np, ns := g.WriteString(name)
+	idPtr := g.Alloc(4)
+	g.Host("proxy_define_metric", uint64(typ), np, ns, idPtr)
+	return g.ReadUint32(idPtr)
+}
+
+func (g testGuest) incrementMetric(id uint32, offset int64) Result {
+	return Result(g.Host("proxy_increment_metric", uint64(id), uint64(offset)))
+}
+
+func (g testGuest) getMetric(id uint32) (uint64, Result) {
+	ptr := g.Alloc(8)
+	r := Result(g.Host("proxy_get_metric", uint64(id), ptr))
+	return g.ReadUint64(ptr), r
+}
+
+const (
+	testModule   = "proxywasm_test"
+	testModuleV2 = "proxywasm_test_v2"
+)
+
+func init() {
+	variable.RegisterVariable(variable.NewIndexedVariable("test_wasm_var", nil, nil, variable.BasicSetter, 0))
+	wasmtest.RegisterModule(testModule, newTestModule("v1"))
+	wasmtest.RegisterModule(testModuleV2, newTestModule("v2"))
+}
+
+func newTestModule(version string) wasmtest.ModuleFunc {
+	return func(guest *wasmtest.Guest) wasmtest.Exports {
+		g := testGuest{guest}
+		var requests, ticks uint32
+		return wasmtest.Exports{
+			"proxy_on_vm_start": func(args ...uint64) uint64 {
+				data, _ := g.getBuffer(BufferTypeVMConfiguration, 0, int(args[1]))
+				if string(data) == "fail" {
+					return 0
+				}
+				return 1
+			},
+			"proxy_on_configure": func(args ...uint64) uint64 {
+				requests = g.defineMetric(MetricTypeCounter, "requests")
+				ticks = g.defineMetric(MetricTypeCounter, "ticks")
+				data, _ := g.getBuffer(BufferTypePluginConfiguration, 0, int(args[1]))
+				if string(data) == `{"tick":true}` {
+					g.Host("proxy_set_tick_period_milliseconds", 10)
+				}
+				return 1
+			},
+			"proxy_on_tick": func(args ...uint64) uint64 {
+				g.incrementMetric(ticks, 1)
+				return 0
+			},
+			"proxy_on_request_headers": func(args ...uint64) uint64 {
+				g.incrementMetric(requests, 1)
+				path, _ := g.getHeader(MapTypeHttpRequestHeaders, ":path")
+				switch path {
+				case "/deny":
+					g.sendLocalResponse(http.StatusForbidden, "denied", [][2]string{{"x-reason", "test"}})
+					return uint64(ActionPause)
+				case "/callout":
+					g.httpCall("auth", [][2]string{{":method", "GET"}, {":path", "/check"}, {":authority", "auth"}}, 1000)
+					return uint64(ActionPause)
+				}
+				g.mutateHeader("proxy_replace_header_map_value", MapTypeHttpRequestHeaders, "x-version", version)
+				g.mutateHeader("proxy_add_header_map_value", MapTypeHttpRequestHeaders, "x-tag", "wasm")
+				g.removeHeader(MapTypeHttpRequestHeaders, "x-remove")
+				name, _ := g.getProperty("plugin_name")
+				g.mutateHeader("proxy_replace_header_map_value", MapTypeHttpRequestHeaders, "x-plugin", name)
+				if v, r := g.getProperty("test_wasm_var"); r == ResultOk {
+					g.mutateHeader("proxy_replace_header_map_value", MapTypeHttpRequestHeaders, "x-var", v)
+				}
+				return uint64(ActionContinue)
+			},
+			"proxy_on_request_body": func(args ...uint64) uint64 {
+				g.setBuffer(BufferTypeHttpRequestBody, 0, 5, "HELLO")
+				return uint64(ActionContinue)
+			},
+			"proxy_on_http_call_response": func(args ...uint64) uint64 {
+				status, _ := g.getHeader(MapTypeHttpCallResponseHeaders, ":status")
+				body, _ := g.getBuffer(BufferTypeHttpCallResponseBody, 0, int(args[3]))
+				g.mutateHeader("proxy_replace_header_map_value", MapTypeHttpRequestHeaders, "x-auth-status", status)
+				g.mutateHeader("proxy_replace_header_map_value", MapTypeHttpRequestHeaders, "x-auth-body", string(body))
+				g.Host("proxy_continue_stream", uint64(StreamTypeRequest))
+				return 0
+			},
+			"proxy_on_response_headers": func(args ...uint64) uint64 {
+				g.mutateHeader("proxy_replace_header_map_value", MapTypeHttpResponseHeaders, "x-resp", version)
+				return uint64(ActionContinue)
+			},
+		}
+	}
+}
+
+// testHandler is a Handler implementation
+type testHandler struct {
+	reqHeaders   api.HeaderMap
+	respHeaders  api.HeaderMap
+	reqBody      buffer.IoBuffer
+	localStatus  int
+	localBody    string
+	localHeaders [][2]string
+	resumed      chan StreamType
+}
+
+func newTestHandler(reqHeaders api.HeaderMap) *testHandler {
+	return &testHandler{
+		reqHeaders: reqHeaders,
+		resumed:    make(chan StreamType, 1),
+	}
+}
+
+func (h *testHandler) GetHeaderMap(mapType MapType) api.HeaderMap {
+	switch mapType {
+	case MapTypeHttpRequestHeaders:
+		return h.reqHeaders
+	case MapTypeHttpResponseHeaders:
+		if h.respHeaders != nil {
+			return h.respHeaders
+		}
+	}
+	return nil
+}
+
+func (h *testHandler) GetBuffer(bufferType BufferType) buffer.IoBuffer {
+	if bufferType == BufferTypeHttpRequestBody && h.reqBody != nil {
+		return h.reqBody
+	}
+	return nil
+}
+
+func (h *testHandler) SendLocalResponse(status int, headers [][2]string, body []byte) {
+	h.localStatus = status
+	h.localHeaders = headers
+	h.localBody = string(body)
+}
+
+func (h *testHandler) Resume(stream StreamType) {
+	h.resumed <- stream
+}
+
+func (h *testHandler) Close(stream StreamType) {}
+
+func writeModuleFile(t *testing.T, dir, module string) string {
+	path := filepath.Join(dir, "plugin.wasm")
+	if err := ioutil.WriteFile(path, []byte(module), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestPlugin(t *testing.T, name string, cfg *v2.WasmPluginConfig) *Plugin {
+	dir, err := ioutil.TempDir("", "proxywasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	if cfg == nil {
+		cfg = &v2.WasmPluginConfig{}
+	}
+	cfg.Name = name
+	cfg.Engine = wasmtest.EngineName
+	cfg.InstanceNum = 1
+	if cfg.Path == "" {
+		cfg.Path = writeModuleFile(t, dir, testModule)
+	}
+	p, err := NewPlugin(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Stop)
+	return p
+}
+
+func TestRequestHeaders(t *testing.T) {
+	p := newTestPlugin(t, "test_request_headers", nil)
+	requests := p.metrics.stats.Counter("requests").Count()
+	inst := p.GetInstance()
+	defer inst.Release()
+	ctx := variable.NewVariableContext(context.Background())
+	variable.SetVariableValue(ctx, "test_wasm_var", "value")
+	headers := protocol.CommonHeader{
+		types.HeaderPath: "/api",
+		"x-tag":          "mosn",
+		"x-remove":       "true",
+	}
+	h := newTestHandler(headers)
+	c, err := inst.NewContext(ctx, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Delete()
+	if action, err := c.OnRequestHeaders(len(headers), false); err != nil || action != ActionContinue {
+		t.Fatalf("unexpected action %d, error %v", action, err)
+	}
+	for key, expected := range map[string]string{
+		"x-version": "v1",
+		"x-tag":     "mosn,wasm",
+		"x-plugin":  "test_request_headers",
+		"x-var":     "value",
+	} {
+		if value, _ := headers.Get(key); value != expected {
+			t.Errorf("header %s expected %s, but got %s", key, expected, value)
+		}
+	}
+	if _, ok := headers.Get("x-remove"); ok {
+		t.Fatal("expected x-remove is removed")
+	}
+	h.reqBody = buffer.NewIoBufferString("hello world")
+	if _, err := c.OnRequestBody(h.reqBody.Len(), true); err != nil {
+		t.Fatal(err)
+	}
+	if h.reqBody.String() != "HELLO world" {
+		t.Fatalf("unexpected body: %s", h.reqBody.String())
+	}
+	h.respHeaders = protocol.CommonHeader{}
+	if _, err := c.OnResponseHeaders(0, true); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := h.respHeaders.Get("x-resp"); v != "v1" {
+		t.Fatalf("unexpected response header: %s", v)
+	}
+	if p.metrics.stats.Counter("requests").Count() != requests+1 {
+		t.Fatal("unexpected requests metric")
+	}
+}
+
+func TestLocalResponse(t *testing.T) {
+	p := newTestPlugin(t, "test_local_response", nil)
+	inst := p.GetInstance()
+	defer inst.Release()
+	h := newTestHandler(protocol.CommonHeader{types.HeaderPath: "/deny"})
+	c, err := inst.NewContext(context.Background(), h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Delete()
+	if action, _ := c.OnRequestHeaders(1, true); action != ActionPause {
+		t.Fatal("expected pause")
+	}
+	if h.localStatus != http.StatusForbidden || h.localBody != "denied" || len(h.localHeaders) != 1 || h.localHeaders[0][0] != "x-reason" {
+		t.Fatalf("unexpected local response: %d %s %v", h.localStatus, h.localBody, h.localHeaders)
+	}
+}
+
+func TestHttpCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/check" || r.Host != "auth" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	origin := clusterHost
+	clusterHost = func(ctx context.Context, clusterName string) (string, error) {
+		return strings.TrimPrefix(server.URL, "http://"), nil
+	}
+	defer func() {
+		clusterHost = origin
+	}()
+	p := newTestPlugin(t, "test_http_call", nil)
+	inst := p.GetInstance()
+	defer inst.Release()
+	headers := protocol.CommonHeader{types.HeaderPath: "/callout"}
+	h := newTestHandler(headers)
+	c, err := inst.NewContext(context.Background(), h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Delete()
+	if action, _ := c.OnRequestHeaders(1, true); action != ActionPause {
+		t.Fatal("expected pause")
+	}
+	select {
+	case stream := <-h.resumed:
+		if stream != StreamTypeRequest {
+			t.Fatalf("unexpected stream type: %d", stream)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("the stream is not resumed")
+	}
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if status, _ := headers.Get("x-auth-status"); status != "200" {
+		t.Fatalf("unexpected status: %s", status)
+	}
+	if body, _ := headers.Get("x-auth-body"); body != "ok" {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+func TestSharedDataAndMetrics(t *testing.T) {
+	p := newTestPlugin(t, "test_shared_data", nil)
+	inst := p.GetInstance()
+	defer inst.Release()
+	g := testGuest{inst.instance.(*wasmtest.Guest)}
+	// the shared data is global
+	key := "test_shared_key_" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if _, _, r := g.getSharedData(key); r != ResultNotFound {
+		t.Fatalf("expected not found, but got %d", r)
+	}
+	if r := g.setSharedData(key, "v1", 0); r != ResultOk {
+		t.Fatalf("set shared data failed: %d", r)
+	}
+	value, cas, r := g.getSharedData(key)
+	if r != ResultOk || value != "v1" {
+		t.Fatalf("unexpected shared data: %s %d", value, r)
+	}
+	if r := g.setSharedData(key, "v2", cas+1); r != ResultCasMismatch {
+		t.Fatalf("expected cas mismatch, but got %d", r)
+	}
+	if r := g.setSharedData(key, "v2", cas); r != ResultOk {
+		t.Fatalf("set shared data failed: %d", r)
+	}
+	if value, _, _ := g.getSharedData(key); value != "v2" {
+		t.Fatalf("unexpected shared data: %s", value)
+	}
+	id := g.defineMetric(MetricTypeGauge, "gauge")
+	if id2 := g.defineMetric(MetricTypeGauge, "gauge"); id2 != id {
+		t.Fatal("expected the same metric id")
+	}
+	before, _ := g.getMetric(id)
+	g.incrementMetric(id, 5)
+	g.incrementMetric(id, -2)
+	if v, r := g.getMetric(id); r != ResultOk || v != before+3 {
+		t.Fatalf("unexpected metric value %d %d", v, r)
+	}
+	if r := g.incrementMetric(100, 1); r != ResultNotFound {
+		t.Fatalf("expected not found, but got %d", r)
+	}
+	// the current time
+	ptr := g.Alloc(8)
+	start := time.Now().UnixNano()
+	g.Host("proxy_get_current_time_nanoseconds", ptr)
+	if now := int64(binary.LittleEndian.Uint64(g.Memory()[ptr:])); now < start {
+		t.Fatal("unexpected current time")
+	}
+	// the memory access out of bounds
+	if r := Result(g.Host("proxy_log", uint64(LogLevelInfo), uint64(len(g.Memory())), 10)); r != ResultInvalidMemoryAccess {
+		t.Fatalf("expected invalid memory access, but got %d", r)
+	}
+}
+
+func TestTick(t *testing.T) {
+	p := newTestPlugin(t, "test_tick", &v2.WasmPluginConfig{
+		Configuration: map[string]interface{}{"tick": true},
+	})
+	deadline := time.Now().Add(3 * time.Second)
+	for p.metrics.stats.Counter("ticks").Count() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("proxy_on_tick is not called")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPluginStartFailed(t *testing.T) {
+	if _, err := NewPlugin(&v2.WasmPluginConfig{Name: "test_no_engine", Engine: "unknown", Path: "plugin.wasm"}); err == nil {
+		t.Fatal("expected the engine is not found")
+	}
+	dir, _ := ioutil.TempDir("", "proxywasm")
+	defer os.RemoveAll(dir)
+	path := writeModuleFile(t, dir, testModule)
+	if _, err := NewPlugin(&v2.WasmPluginConfig{Name: "test_vm_start_failed", Engine: wasmtest.EngineName, Path: path, VMConfig: "fail"}); err == nil {
+		t.Fatal("expected proxy_on_vm_start failed")
+	}
+	unknown := writeModuleFile(t, dir, "unknown_module")
+	if _, err := NewPlugin(&v2.WasmPluginConfig{Name: "test_compile_failed", Engine: wasmtest.EngineName, Path: unknown}); err == nil {
+		t.Fatal("expected compile failed")
+	}
+}
+
+func TestPluginReload(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "proxywasm")
+	defer os.RemoveAll(dir)
+	path := writeModuleFile(t, dir, testModule)
+	p := newTestPlugin(t, "test_reload", &v2.WasmPluginConfig{
+		Path:           path,
+		ReloadInterval: &api.DurationConfig{Duration: 10 * time.Millisecond},
+	})
+	old := p.GetInstance()
+	writeModuleFile(t, dir, testModuleV2)
+	var inst *Instance
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		inst = p.GetInstance()
+		if inst != old {
+			break
+		}
+		inst.Release()
+		if time.Now().After(deadline) {
+			t.Fatal("the module is not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer inst.Release()
+	headers := protocol.CommonHeader{types.HeaderPath: "/"}
+	c, err := inst.NewContext(context.Background(), newTestHandler(headers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.OnRequestHeaders(1, true)
+	c.Delete()
+	if v, _ := headers.Get("x-version"); v != "v2" {
+		t.Fatalf("expected the new module is used, but got %s", v)
+	}
+	// the old instance is closed after it is released
+	guest := old.instance.(*wasmtest.Guest)
+	if guest.Closed() {
+		t.Fatal("the old instance is closed before it is released")
+	}
+	old.Release()
+	if !guest.Closed() {
+		t.Fatal("the old instance is not closed")
+	}
+}
+
+func TestGetOrCreatePlugin(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "proxywasm")
+	defer os.RemoveAll(dir)
+	cfg := &v2.WasmPluginConfig{
+		Name:        "test_manager",
+		Engine:      wasmtest.EngineName,
+		Path:        writeModuleFile(t, dir, testModule),
+		InstanceNum: 2,
+	}
+	p1, err := GetOrCreatePlugin(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied := *cfg
+	if p2, _ := GetOrCreatePlugin(&copied); p2 != p1 {
+		t.Fatal("expected the same plugin")
+	}
+	copied.InstanceNum = 1
+	p3, err := GetOrCreatePlugin(&copied)
+	if err != nil || p3 == p1 || GetPlugin("test_manager") != p3 {
+		t.Fatal("expected a new plugin")
+	}
+	if p1.GetInstance() != nil {
+		t.Fatal("expected the old plugin is stopped")
+	}
+	p3.Stop()
+}
@@ -0,0 +1,70 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxywasm
+
+import (
+	"sync"
+)
+
+// sharedData is the key value store shared by all the wasm instances
+type sharedData struct {
+	mu   sync.Mutex
+	data map[string]*sharedValue
+}
+
+type sharedValue struct {
+	data []byte
+	cas  uint32
+}
+
+var globalSharedData = &sharedData{
+	data: make(map[string]*sharedValue),
+}
+
+func (s *sharedData) get(key string) ([]byte, uint32, Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	if !ok {
+		return nil, 0, ResultNotFound
+	}
+	return v.data, v.cas, ResultOk
+}
+
+// set sets the value if the cas is 0 or matches the current cas, the cas is increased after the value is set
+func (s *sharedData) set(key string, data []byte, cas uint32) Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	if !ok {
+		if cas != 0 {
+			return ResultCasMismatch
+		}
+		s.data[key] = &sharedValue{
+			data: data,
+			cas:  1,
+		}
+		return ResultOk
+	}
+	if cas != 0 && cas != v.cas {
+		return ResultCasMismatch
+	}
+	v.data = data
+	v.cas++
+	return ResultOk
+}
@@ -0,0 +1,230 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// gen_module writes proxywasm.wasm, a minimal proxy-wasm abi 0.2 module used by the tests.
+// No wasm toolchain is required, run it in the proxywasm package:
+//
+//	go run testdata/gen_module.go
+//
+// The module is equivalent to:
+//
+//	(module
+//	  (import "env" "proxy_log" (func $log (param i32 i32 i32) (result i32)))
+//	  (import "env" "proxy_get_header_map_value" (func $get (param i32 i32 i32 i32 i32) (result i32)))
+//	  (import "env" "proxy_add_header_map_value" (func $add (param i32 i32 i32 i32 i32) (result i32)))
+//	  (import "env" "proxy_send_local_response" (func $send (param i32 i32 i32 i32 i32 i32 i32 i32) (result i32)))
+//	  (memory (export "memory") 1)
+//	  (global $heap (mut i32) (i32.const 4096))
+//	  (func (export "proxy_abi_version_0_2_0"))
+//	  (func (export "proxy_on_memory_allocate") (param $size i32) (result i32)
+//	    global.get $heap
+//	    global.get $heap local.get $size i32.add global.set $heap)
+//	  (func (export "proxy_on_context_create") (param i32 i32))
+//	  (func (export "proxy_on_vm_start") (param i32 i32) (result i32)
+//	    (drop (call $log (i32.const 2) "vm started")) (i32.const 1))
+//	  (func (export "proxy_on_configure") (param i32 i32) (result i32) (i32.const 1))
+//	  ;; the request with x-user is continued with x-wasm-user, otherwise it is denied
+//	  (func (export "proxy_on_request_headers") (param i32 i32 i32) (result i32)
+//	    (if (result i32) (call $get (i32.const 0) "x-user" (i32.const 2048) (i32.const 2052))
+//	      (then (drop (call $send (i32.const 403) "" "denied" "" (i32.const -1))) (i32.const 1))
+//	      (else (drop (call $add (i32.const 0) "x-wasm-user" (i32.load 2048) (i32.load 2052))) (i32.const 0))))
+//	  (func (export "proxy_on_response_headers") (param i32 i32 i32) (result i32)
+//	    (drop (call $add (i32.const 2) "x-wasm" "wazero")) (i32.const 0)))
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+)
+
+const (
+	i32 = 0x7f
+
+	opIf        = 0x04
+	opElse      = 0x05
+	opEnd       = 0x0b
+	opCall      = 0x10
+	opDrop      = 0x1a
+	opLocalGet  = 0x20
+	opGlobalGet = 0x23
+	opGlobalSet = 0x24
+	opI32Load   = 0x28
+	opI32Const  = 0x41
+	opI32Add    = 0x6a
+
+	// the imported functions
+	funcLog  = 0
+	funcGet  = 1
+	funcAdd  = 2
+	funcSend = 3
+
+	// the return value pointer and size of proxy_get_header_map_value
+	retPtr  = 2048
+	retSize = 2052
+	heap    = 4096
+)
+
+// the strings in the data segment, the offsets start at 1024
+var (
+	dataStrings  = []string{"vm started", "x-user", "x-wasm-user", "denied", "x-wasm", "wazero"}
+	stringOffset = map[string]int32{}
+)
+
+func uleb(v uint32) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			b = append(b, c|0x80)
+			continue
+		}
+		return append(b, c)
+	}
+}
+
+func sleb(v int32) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func name(s string) []byte {
+	return append(uleb(uint32(len(s))), s...)
+}
+
+func vec(items ...[]byte) []byte {
+	b := uleb(uint32(len(items)))
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+func section(id byte, content []byte) []byte {
+	return append(append([]byte{id}, uleb(uint32(len(content)))...), content...)
+}
+
+func funcType(params, results int) []byte {
+	b := []byte{0x60}
+	b = append(b, uleb(uint32(params))...)
+	b = append(b, bytes.Repeat([]byte{i32}, params)...)
+	b = append(b, uleb(uint32(results))...)
+	return append(b, bytes.Repeat([]byte{i32}, results)...)
+}
+
+func i32Const(v int32) []byte {
+	return append([]byte{opI32Const}, sleb(v)...)
+}
+
+// str pushes the pointer and the size of the string
+func str(s string) []byte {
+	return append(i32Const(stringOffset[s]), i32Const(int32(len(s)))...)
+}
+
+func call(idx uint32) []byte {
+	return append([]byte{opCall}, uleb(idx)...)
+}
+
+func load(addr int32) []byte {
+	return append(i32Const(addr), opI32Load, 2, 0)
+}
+
+func code(instrs ...[]byte) []byte {
+	body := []byte{0} // no locals
+	for _, instr := range instrs {
+		body = append(body, instr...)
+	}
+	body = append(body, opEnd)
+	return append(uleb(uint32(len(body))), body...)
+}
+
+func main() {
+	var data []byte
+	for _, s := range dataStrings {
+		stringOffset[s] = int32(1024 + len(data))
+		data = append(data, s...)
+	}
+	// the types
+	const (
+		typeI32x3  = iota // (i32 i32 i32) -> i32
+		typeI32x5         // (i32 * 5) -> i32
+		typeI32x8         // (i32 * 8) -> i32
+		typeVoid          // () -> ()
+		typeAlloc         // (i32) -> i32
+		typeCreate        // (i32 i32) -> ()
+		typeStart         // (i32 i32) -> i32
+	)
+	types := vec(funcType(3, 1), funcType(5, 1), funcType(8, 1), funcType(0, 0), funcType(1, 1), funcType(2, 0), funcType(2, 1))
+	imports := vec(
+		append(append(name("env"), name("proxy_log")...), 0, typeI32x3),
+		append(append(name("env"), name("proxy_get_header_map_value")...), 0, typeI32x5),
+		append(append(name("env"), name("proxy_add_header_map_value")...), 0, typeI32x5),
+		append(append(name("env"), name("proxy_send_local_response")...), 0, typeI32x8),
+	)
+	exports := []string{
+		"proxy_abi_version_0_2_0",
+		"proxy_on_memory_allocate",
+		"proxy_on_context_create",
+		"proxy_on_vm_start",
+		"proxy_on_configure",
+		"proxy_on_request_headers",
+		"proxy_on_response_headers",
+	}
+	functions := vec([]byte{typeVoid}, []byte{typeAlloc}, []byte{typeCreate}, []byte{typeStart}, []byte{typeStart}, []byte{typeI32x3}, []byte{typeI32x3})
+	codes := vec(
+		code(),
+		code([]byte{opGlobalGet, 0, opGlobalGet, 0, opLocalGet, 0, opI32Add, opGlobalSet, 0}),
+		code(),
+		code(i32Const(2), str("vm started"), call(funcLog), []byte{opDrop}, i32Const(1)),
+		code(i32Const(1)),
+		code(
+			i32Const(0), str("x-user"), i32Const(retPtr), i32Const(retSize), call(funcGet),
+			[]byte{opIf, i32},
+			i32Const(403), i32Const(0), i32Const(0), str("denied"), i32Const(0), i32Const(0), i32Const(-1), call(funcSend), []byte{opDrop},
+			i32Const(1),
+			[]byte{opElse},
+			i32Const(0), str("x-wasm-user"), load(retPtr), load(retSize), call(funcAdd), []byte{opDrop},
+			i32Const(0),
+			[]byte{opEnd},
+		),
+		code(i32Const(2), str("x-wasm"), str("wazero"), call(funcAdd), []byte{opDrop}, i32Const(0)),
+	)
+	exportItems := [][]byte{append(name("memory"), 2, 0)}
+	for idx, export := range exports {
+		exportItems = append(exportItems, append(name(export), append([]byte{0}, uleb(uint32(4+idx))...)...))
+	}
+	module := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	module = append(module, section(1, types)...)
+	module = append(module, section(2, imports)...)
+	module = append(module, section(3, functions)...)
+	module = append(module, section(5, vec([]byte{0x00, 1}))...)
+	module = append(module, section(6, vec(append(append([]byte{i32, 1}, i32Const(heap)...), opEnd)))...)
+	module = append(module, section(7, vec(exportItems...))...)
+	module = append(module, section(10, codes)...)
+	module = append(module, section(11, vec(append(append(append([]byte{0}, i32Const(1024)...), opEnd), append(uleb(uint32(len(data))), data...)...)))...)
+	if err := ioutil.WriteFile("testdata/proxywasm.wasm", module, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
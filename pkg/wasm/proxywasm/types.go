@@ -0,0 +1,114 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxywasm
+
+// Result is the status returned by the host functions
+type Result int32
+
+const (
+	ResultOk                   Result = 0
+	ResultNotFound             Result = 1
+	ResultBadArgument          Result = 2
+	ResultSerializationFailure Result = 3
+	ResultParseFailure         Result = 4
+	ResultBadExpression        Result = 5
+	ResultInvalidMemoryAccess  Result = 6
+	ResultEmpty                Result = 7
+	ResultCasMismatch          Result = 8
+	ResultResultMismatch       Result = 9
+	ResultInternalFailure      Result = 10
+	ResultBrokenConnection     Result = 11
+	ResultUnimplemented        Result = 12
+)
+
+// Action is returned by the stream callbacks of the wasm module
+type Action int32
+
+const (
+	ActionContinue Action = 0
+	ActionPause    Action = 1
+)
+
+// BufferType is the type of the buffer accessed by the wasm module
+type BufferType int32
+
+const (
+	BufferTypeHttpRequestBody      BufferType = 0
+	BufferTypeHttpResponseBody     BufferType = 1
+	BufferTypeDownstreamData       BufferType = 2
+	BufferTypeUpstreamData         BufferType = 3
+	BufferTypeHttpCallResponseBody BufferType = 4
+	BufferTypeGrpcReceiveBuffer    BufferType = 5
+	BufferTypeVMConfiguration      BufferType = 6
+	BufferTypePluginConfiguration  BufferType = 7
+	BufferTypeCallData             BufferType = 8
+)
+
+// MapType is the type of the header map accessed by the wasm module
+type MapType int32
+
+const (
+	MapTypeHttpRequestHeaders          MapType = 0
+	MapTypeHttpRequestTrailers         MapType = 1
+	MapTypeHttpResponseHeaders         MapType = 2
+	MapTypeHttpResponseTrailers        MapType = 3
+	MapTypeGrpcReceiveInitialMetadata  MapType = 4
+	MapTypeGrpcReceiveTrailingMetadata MapType = 5
+	MapTypeHttpCallResponseHeaders     MapType = 6
+	MapTypeHttpCallResponseTrailers    MapType = 7
+)
+
+// StreamType is the stream continued or closed by the wasm module
+type StreamType int32
+
+const (
+	StreamTypeRequest    StreamType = 0
+	StreamTypeResponse   StreamType = 1
+	StreamTypeDownstream StreamType = 2
+	StreamTypeUpstream   StreamType = 3
+)
+
+// PeerType is the peer that closes the connection
+type PeerType int32
+
+const (
+	PeerTypeUnknown PeerType = 0
+	PeerTypeLocal   PeerType = 1
+	PeerTypeRemote  PeerType = 2
+)
+
+// LogLevel is the log level of the wasm module
+type LogLevel int32
+
+const (
+	LogLevelTrace    LogLevel = 0
+	LogLevelDebug    LogLevel = 1
+	LogLevelInfo     LogLevel = 2
+	LogLevelWarn     LogLevel = 3
+	LogLevelError    LogLevel = 4
+	LogLevelCritical LogLevel = 5
+)
+
+// MetricType is the type of the metrics defined by the wasm module
+type MetricType int32
+
+const (
+	MetricTypeCounter   MetricType = 0
+	MetricTypeGauge     MetricType = 1
+	MetricTypeHistogram MetricType = 2
+)
@@ -0,0 +1,87 @@
+//go:build wazero
+// +build wazero
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxywasm
+
+import (
+	"context"
+	"testing"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	_ "mosn.io/mosn/pkg/wasm/wazero"
+)
+
+// TestWazeroModule runs testdata/proxywasm.wasm on the default engine,
+// the module is generated by testdata/gen_module.go
+func TestWazeroModule(t *testing.T) {
+	p, err := NewPlugin(&v2.WasmPluginConfig{
+		Name:        "test_wazero_module",
+		Path:        "testdata/proxywasm.wasm",
+		InstanceNum: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	for i := 0; i < 2; i++ {
+		inst := p.GetInstance()
+		headers := protocol.CommonHeader{"x-user": "alice"}
+		h := newTestHandler(headers)
+		c, err := inst.NewContext(context.Background(), h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if action, err := c.OnRequestHeaders(len(headers), false); err != nil || action != ActionContinue {
+			t.Fatalf("unexpected action %d, error %v", action, err)
+		}
+		if v, _ := headers.Get("x-wasm-user"); v != "alice" {
+			t.Fatalf("expected the header value is read by the module, but got %s", v)
+		}
+		h.respHeaders = protocol.CommonHeader{}
+		if _, err := c.OnResponseHeaders(0, true); err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := h.respHeaders.Get("x-wasm"); v != "wazero" {
+			t.Fatalf("unexpected response header: %s", v)
+		}
+		c.Delete()
+		// the request without x-user is denied
+		h = newTestHandler(protocol.CommonHeader{})
+		if c, err = inst.NewContext(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		if action, err := c.OnRequestHeaders(0, true); err != nil || action != ActionPause {
+			t.Fatalf("unexpected action %d, error %v", action, err)
+		}
+		if h.localStatus != 403 || h.localBody != "denied" {
+			t.Fatalf("unexpected local response %d %s", h.localStatus, h.localBody)
+		}
+		c.Delete()
+		inst.Release()
+	}
+}
+
+func TestWazeroCompileFailed(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewPlugin(&v2.WasmPluginConfig{Name: "test_wazero_invalid", Path: writeModuleFile(t, dir, "not a wasm module")}); err == nil {
+		t.Fatal("expected the invalid module is rejected")
+	}
+}
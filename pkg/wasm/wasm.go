@@ -0,0 +1,95 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package wasm defines the webassembly runtime abstraction used by the wasm filters.
+// An engine is provided by an adapter package that calls RegisterEngine in its init function,
+// the pure go engine in the wazero package is registered if mosn is built with the wazero build tag.
+package wasm
+
+import (
+	"errors"
+	"sync"
+)
+
+// ValueType is the type of the parameters and the results of the wasm functions
+type ValueType uint8
+
+const (
+	ValueTypeI32 ValueType = iota
+	ValueTypeI64
+)
+
+var (
+	ErrEngineNotFound   = errors.New("wasm engine is not registered")
+	ErrFunctionNotFound = errors.New("wasm function is not exported")
+	ErrMemoryAccess     = errors.New("wasm memory access out of bounds")
+)
+
+// HostFunction is a function provided by the host and imported by the wasm module
+type HostFunction struct {
+	// Module is the import module name, such as env
+	Module string
+	Name   string
+	Params []ValueType
+	// Results has at most one value
+	Results []ValueType
+	// Func is called with the parameters, the i32 values are in the lower 32 bits
+	Func func(params []uint64) uint64
+}
+
+// Engine compiles the wasm modules
+type Engine interface {
+	// Name is the engine name used in the config
+	Name() string
+	// Compile compiles the wasm binary to a module
+	Compile(wasmBytes []byte) (Module, error)
+}
+
+// Module is a compiled wasm module
+type Module interface {
+	// Instantiate creates an instance of the module, the host functions are linked as the imports.
+	// The engine should provide the wasi imports if the module requires them
+	Instantiate(imports []HostFunction) (Instance, error)
+	Close() error
+}
+
+// Instance is an instantiated wasm module, it is not goroutine safe
+type Instance interface {
+	// HasFunction returns whether the function is exported
+	HasFunction(name string) bool
+	// Call calls the exported function, the i32 values are in the lower 32 bits.
+	// ErrFunctionNotFound is returned if the function is not exported
+	Call(name string, args ...uint64) (uint64, error)
+	// Memory returns the linear memory, it should not be held since the memory may grow
+	Memory() []byte
+	Close() error
+}
+
+var engines sync.Map
+
+// RegisterEngine registers a wasm engine by its name
+func RegisterEngine(engine Engine) {
+	engines.Store(engine.Name(), engine)
+}
+
+// GetEngine returns the registered engine, ErrEngineNotFound is returned if it is not registered
+func GetEngine(name string) (Engine, error) {
+	if v, ok := engines.Load(name); ok {
+		return v.(Engine), nil
+	}
+	return nil, ErrEngineNotFound
+}
@@ -0,0 +1,181 @@
+//go:build wazero
+// +build wazero
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wazero
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"mosn.io/mosn/pkg/wasm"
+)
+
+// EngineName is the name of the wazero engine
+const EngineName = "wazero"
+
+func init() {
+	wasm.RegisterEngine(&engine{})
+}
+
+type engine struct{}
+
+func (e *engine) Name() string {
+	return EngineName
+}
+
+// Compile compiles the module in a new runtime, so the runtime is closed with the module
+func (e *engine) Compile(wasmBytes []byte) (wasm.Module, error) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	compiled, err := r.CompileModule(ctx, wasmBytes)
+	if err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+	m := &module{
+		runtime:  r,
+		compiled: compiled,
+	}
+	for _, fn := range compiled.ImportedFunctions() {
+		if moduleName, _, _ := fn.Import(); moduleName == wasi_snapshot_preview1.ModuleName {
+			m.wasi = true
+			break
+		}
+	}
+	return m, nil
+}
+
+type module struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	// wasi is whether the module imports the wasi functions
+	wasi bool
+}
+
+// Instantiate instantiates the module in a new namespace, the host functions are bound to the instance
+func (m *module) Instantiate(imports []wasm.HostFunction) (wasm.Instance, error) {
+	ctx := context.Background()
+	ns := m.runtime.NewNamespace(ctx)
+	if err := m.instantiateImports(ctx, ns, imports); err != nil {
+		ns.Close(ctx)
+		return nil, err
+	}
+	// the proxy-wasm host calls _initialize or _start itself
+	guest, err := ns.InstantiateModule(ctx, m.compiled, wazero.NewModuleConfig().WithStartFunctions())
+	if err != nil {
+		ns.Close(ctx)
+		return nil, err
+	}
+	return &instance{
+		ns:     ns,
+		module: guest,
+	}, nil
+}
+
+func (m *module) instantiateImports(ctx context.Context, ns wazero.Namespace, imports []wasm.HostFunction) error {
+	builders := make(map[string]wazero.HostModuleBuilder)
+	for _, fn := range imports {
+		b, ok := builders[fn.Module]
+		if !ok {
+			b = m.runtime.NewHostModuleBuilder(fn.Module)
+			builders[fn.Module] = b
+		}
+		b.NewFunctionBuilder().
+			WithGoModuleFunction(hostFunction(fn), valueTypes(fn.Params), valueTypes(fn.Results)).
+			Export(fn.Name)
+	}
+	for _, b := range builders {
+		if _, err := b.Instantiate(ctx, ns); err != nil {
+			return err
+		}
+	}
+	if _, ok := builders[wasi_snapshot_preview1.ModuleName]; m.wasi && !ok {
+		if _, err := wasi_snapshot_preview1.NewBuilder(m.runtime).Instantiate(ctx, ns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *module) Close() error {
+	return m.runtime.Close(context.Background())
+}
+
+func hostFunction(fn wasm.HostFunction) api.GoModuleFunc {
+	params, results := len(fn.Params), len(fn.Results)
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		ret := fn.Func(stack[:params])
+		if results > 0 {
+			stack[0] = ret
+		}
+	}
+}
+
+func valueTypes(types []wasm.ValueType) []api.ValueType {
+	vts := make([]api.ValueType, 0, len(types))
+	for _, t := range types {
+		if t == wasm.ValueTypeI64 {
+			vts = append(vts, api.ValueTypeI64)
+		} else {
+			vts = append(vts, api.ValueTypeI32)
+		}
+	}
+	return vts
+}
+
+type instance struct {
+	ns     wazero.Namespace
+	module api.Module
+}
+
+func (i *instance) HasFunction(name string) bool {
+	return i.module.ExportedFunction(name) != nil
+}
+
+func (i *instance) Call(name string, args ...uint64) (uint64, error) {
+	fn := i.module.ExportedFunction(name)
+	if fn == nil {
+		return 0, wasm.ErrFunctionNotFound
+	}
+	results, err := fn.Call(context.Background(), args...)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	return results[0], nil
+}
+
+func (i *instance) Memory() []byte {
+	ctx := context.Background()
+	mem := i.module.Memory()
+	if mem == nil {
+		return nil
+	}
+	data, _ := mem.Read(ctx, 0, mem.Size(ctx))
+	return data
+}
+
+func (i *instance) Close() error {
+	return i.ns.Close(context.Background())
+}
@@ -0,0 +1,116 @@
+//go:build wazero
+// +build wazero
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wazero
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+
+	"mosn.io/mosn/pkg/wasm"
+)
+
+func newHostFunction(name string, numParams int, f func(params []uint64) uint64) wasm.HostFunction {
+	params := make([]wasm.ValueType, numParams)
+	for idx := range params {
+		params[idx] = wasm.ValueTypeI32
+	}
+	return wasm.HostFunction{
+		Module:  "env",
+		Name:    name,
+		Params:  params,
+		Results: []wasm.ValueType{wasm.ValueTypeI32},
+		Func:    f,
+	}
+}
+
+func TestEngine(t *testing.T) {
+	engine, err := wasm.GetEngine(EngineName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Compile([]byte("invalid")); err == nil {
+		t.Fatal("expected the invalid module is rejected")
+	}
+	data, err := ioutil.ReadFile("../proxywasm/testdata/proxywasm.wasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, err := engine.Compile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer module.Close()
+	var (
+		inst   wasm.Instance
+		logged string
+		added  []string
+	)
+	read := func(ptr, size uint64) string {
+		return string(inst.Memory()[ptr : ptr+size])
+	}
+	imports := []wasm.HostFunction{
+		newHostFunction("proxy_log", 3, func(params []uint64) uint64 {
+			logged = read(params[1], params[2])
+			return 0
+		}),
+		// the value is written to the memory allocated by the module
+		newHostFunction("proxy_get_header_map_value", 5, func(params []uint64) uint64 {
+			ptr, err := inst.Call("proxy_on_memory_allocate", 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			memory := inst.Memory()
+			copy(memory[ptr:], "alice")
+			binary.LittleEndian.PutUint32(memory[params[3]:], uint32(ptr))
+			binary.LittleEndian.PutUint32(memory[params[4]:], 5)
+			return 0
+		}),
+		newHostFunction("proxy_add_header_map_value", 5, func(params []uint64) uint64 {
+			added = append(added, read(params[1], params[2]), read(params[3], params[4]))
+			return 0
+		}),
+		newHostFunction("proxy_send_local_response", 8, func(params []uint64) uint64 { return 0 }),
+	}
+	// the imports are required
+	if _, err := module.Instantiate(imports[:1]); err == nil {
+		t.Fatal("expected the instantiation failed without the imports")
+	}
+	if inst, err = module.Instantiate(imports); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.Close()
+	if !inst.HasFunction("proxy_on_vm_start") || inst.HasFunction("not_exists") {
+		t.Fatal("unexpected exported functions")
+	}
+	if _, err := inst.Call("not_exists"); err != wasm.ErrFunctionNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ret, err := inst.Call("proxy_on_vm_start", 1, 0); err != nil || ret != 1 || logged != "vm started" {
+		t.Fatalf("unexpected result %d, error %v, log %s", ret, err, logged)
+	}
+	if ret, err := inst.Call("proxy_on_request_headers", 2, 1, 0); err != nil || ret != 0 {
+		t.Fatalf("unexpected result %d, error %v", ret, err)
+	}
+	if len(added) != 2 || added[0] != "x-wasm-user" || added[1] != "alice" {
+		t.Fatalf("unexpected added header: %v", added)
+	}
+}
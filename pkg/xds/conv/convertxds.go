@@ -20,6 +20,7 @@ package conv
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"strings"
 	"time"
 
@@ -128,6 +129,9 @@ func ConvertClustersConfig(xdsClusters []*xdsapi.Cluster) []*v2.Cluster {
 			Hosts: convertClusterHosts(xdsCluster.GetHosts()),
 			Spec:  convertSpec(xdsCluster),
 			TLS:   convertTLS(xdsCluster.GetTlsContext()),
+
+			OverprovisioningFactor: xdsCluster.GetLoadAssignment().GetPolicy().GetOverprovisioningFactor().GetValue(),
+			HealthyPanicThreshold:  uint32(xdsCluster.GetCommonLbConfig().GetHealthyPanicThreshold().GetValue()),
 		}
 
 		clusters = append(clusters, cluster)
@@ -141,6 +145,7 @@ func ConvertEndpointsConfig(xdsEndpoint *xdsendpoint.LocalityLbEndpoints) []v2.H
 		return nil
 	}
 	hosts := make([]v2.Host, 0, len(xdsEndpoint.GetLbEndpoints()))
+	locality := convertLocality(xdsEndpoint.GetLocality())
 	for _, xdsHost := range xdsEndpoint.GetLbEndpoints() {
 		var address string
 		if xdsAddress, ok := xdsHost.GetEndpoint().GetAddress().GetAddress().(*xdscore.Address_SocketAddress); ok {
@@ -161,7 +166,10 @@ func ConvertEndpointsConfig(xdsEndpoint *xdsendpoint.LocalityLbEndpoints) []v2.H
 		}
 		host := v2.Host{
 			HostConfig: v2.HostConfig{
-				Address: address,
+				Address:        address,
+				Priority:       xdsEndpoint.GetPriority(),
+				Locality:       locality,
+				LocalityWeight: xdsEndpoint.GetLoadBalancingWeight().GetValue(),
 			},
 			MetaData: convertMeta(xdsHost.Metadata),
 		}
@@ -170,6 +178,8 @@ func ConvertEndpointsConfig(xdsEndpoint *xdsendpoint.LocalityLbEndpoints) []v2.H
 			host.Weight = configmanager.MinHostWeight
 		} else if weight > configmanager.MaxHostWeight {
 			host.Weight = configmanager.MaxHostWeight
+		} else {
+			host.Weight = weight
 		}
 
 		hosts = append(hosts, host)
@@ -177,6 +187,17 @@ func ConvertEndpointsConfig(xdsEndpoint *xdsendpoint.LocalityLbEndpoints) []v2.H
 	return hosts
 }
 
+func convertLocality(xdsLocality *xdscore.Locality) *v2.Locality {
+	if xdsLocality == nil {
+		return nil
+	}
+	return &v2.Locality{
+		Region:  xdsLocality.GetRegion(),
+		Zone:    xdsLocality.GetZone(),
+		SubZone: xdsLocality.GetSubZone(),
+	}
+}
+
 // todo: more filter type support
 func isSupport(xdsListener *xdsapi.Listener) bool {
 	if xdsListener == nil {
@@ -703,8 +724,8 @@ func convertRoutes(xdsRoutes []xdsroute.Route) []v2.Router {
 		} else if xdsRouteAction := xdsRoute.GetRedirect(); xdsRouteAction != nil {
 			route := v2.Router{
 				RouterConfig: v2.RouterConfig{
-					Match: convertRouteMatch(xdsRoute.GetMatch()),
-					//Redirect:  convertRedirectAction(xdsRouteAction),
+					Match:    convertRouteMatch(xdsRoute.GetMatch()),
+					Redirect: convertRedirectAction(xdsRouteAction),
 					//Decorator: v2.Decorator(xdsRoute.GetDecorator().String()),
 				},
 				Metadata: convertMeta(xdsRoute.GetMetadata()),
@@ -761,13 +782,37 @@ func convertPerRouteConfig(xdsPerRouteConfig map[string]*types.Struct) map[strin
 
 func convertRouteMatch(xdsRouteMatch xdsroute.RouteMatch) v2.RouterMatch {
 	return v2.RouterMatch{
-		Prefix: xdsRouteMatch.GetPrefix(),
-		Path:   xdsRouteMatch.GetPath(),
-		Regex:  xdsRouteMatch.GetRegex(),
-		//CaseSensitive: xdsRouteMatch.GetCaseSensitive().GetValue(),
-		//Runtime:       convertRuntime(xdsRouteMatch.GetRuntime()),
-		Headers: convertHeaders(xdsRouteMatch.GetHeaders()),
+		Prefix:          xdsRouteMatch.GetPrefix(),
+		Path:            xdsRouteMatch.GetPath(),
+		Regex:           xdsRouteMatch.GetRegex(),
+		CaseSensitive:   convertBoolValue(xdsRouteMatch.GetCaseSensitive()),
+		RuntimeFraction: convertFractionalPercent(xdsRouteMatch.GetRuntimeFraction().GetDefaultValue()),
+		Headers:         convertHeaders(xdsRouteMatch.GetHeaders()),
+		QueryParameters: convertQueryParameters(xdsRouteMatch.GetQueryParameters()),
+	}
+}
+
+func convertBoolValue(xdsBool *types.BoolValue) *bool {
+	if xdsBool == nil {
+		return nil
+	}
+	value := xdsBool.GetValue()
+	return &value
+}
+
+func convertQueryParameters(xdsQueryParameters []*xdsroute.QueryParameterMatcher) []v2.QueryParameterMatcher {
+	if xdsQueryParameters == nil {
+		return nil
 	}
+	queryParameters := make([]v2.QueryParameterMatcher, 0, len(xdsQueryParameters))
+	for _, xdsQueryParameter := range xdsQueryParameters {
+		queryParameters = append(queryParameters, v2.QueryParameterMatcher{
+			Name:  xdsQueryParameter.GetName(),
+			Value: xdsQueryParameter.GetValue(),
+			Regex: xdsQueryParameter.GetRegex().GetValue(),
+		})
+	}
+	return queryParameters
 }
 
 /*
@@ -788,15 +833,32 @@ func convertHeaders(xdsHeaders []*xdsroute.HeaderMatcher) []v2.HeaderMatcher {
 	}
 	headerMatchers := make([]v2.HeaderMatcher, 0, len(xdsHeaders))
 	for _, xdsHeader := range xdsHeaders {
-		headerMatcher := v2.HeaderMatcher{}
-		if xdsHeader.GetRegexMatch() != "" {
-			headerMatcher.Name = xdsHeader.GetName()
+		headerMatcher := v2.HeaderMatcher{
+			Name:        xdsHeader.GetName(),
+			InvertMatch: xdsHeader.GetInvertMatch(),
+		}
+		switch xdsHeader.GetHeaderMatchSpecifier().(type) {
+		case *xdsroute.HeaderMatcher_RegexMatch:
 			headerMatcher.Value = xdsHeader.GetRegexMatch()
 			headerMatcher.Regex = true
-		} else {
-			headerMatcher.Name = xdsHeader.GetName()
+		case *xdsroute.HeaderMatcher_PresentMatch:
+			// present_match false means the header is absent
+			if xdsHeader.GetPresentMatch() {
+				headerMatcher.PresentMatch = true
+			} else {
+				headerMatcher.AbsentMatch = true
+			}
+		case *xdsroute.HeaderMatcher_PrefixMatch:
+			headerMatcher.PrefixMatch = xdsHeader.GetPrefixMatch()
+		case *xdsroute.HeaderMatcher_SuffixMatch:
+			headerMatcher.SuffixMatch = xdsHeader.GetSuffixMatch()
+		case *xdsroute.HeaderMatcher_RangeMatch:
+			headerMatcher.RangeMatch = &v2.Int64Range{
+				Start: xdsHeader.GetRangeMatch().GetStart(),
+				End:   xdsHeader.GetRangeMatch().GetEnd(),
+			}
+		default:
 			headerMatcher.Value = xdsHeader.GetExactMatch()
-			headerMatcher.Regex = false
 		}
 
 		// as pseudo headers not support when Http1.x upgrade to Http2, change pseudo headers to normal headers
@@ -830,6 +892,8 @@ func convertRouteAction(xdsRouteAction *xdsroute.RouteAction) v2.RouteAction {
 			ClusterHeader:           xdsRouteAction.GetClusterHeader(),
 			WeightedClusters:        convertWeightedClusters(xdsRouteAction.GetWeightedClusters()),
 			RetryPolicy:             convertRetryPolicy(xdsRouteAction.GetRetryPolicy()),
+			HashPolicy:              convertHashPolicy(xdsRouteAction.GetHashPolicy()),
+			RequestMirrorPolicies:   convertRequestMirrorPolicy(xdsRouteAction.GetRequestMirrorPolicy()),
 			PrefixRewrite:           xdsRouteAction.GetPrefixRewrite(),
 			HostRewrite:             xdsRouteAction.GetHostRewrite(),
 			AutoHostRewrite:         xdsRouteAction.GetAutoHostRewrite().GetValue(),
@@ -899,31 +963,105 @@ func convertWeightedCluster(xdsWeightedCluster *xdsroute.WeightedCluster_Cluster
 	}
 }
 
+func convertRequestMirrorPolicy(xdsMirrorPolicy *xdsroute.RouteAction_RequestMirrorPolicy) []*v2.RequestMirrorPolicy {
+	if xdsMirrorPolicy == nil || xdsMirrorPolicy.GetCluster() == "" {
+		return nil
+	}
+	return []*v2.RequestMirrorPolicy{
+		{
+			Cluster:         xdsMirrorPolicy.GetCluster(),
+			RuntimeFraction: convertFractionalPercent(xdsMirrorPolicy.GetRuntimeFraction().GetDefaultValue()),
+		},
+	}
+}
+
+func convertFractionalPercent(xdsPercent *xdstype.FractionalPercent) *v2.FractionalPercent {
+	if xdsPercent == nil {
+		return nil
+	}
+	percent := &v2.FractionalPercent{
+		Numerator:   xdsPercent.GetNumerator(),
+		Denominator: v2.DenominatorHundred,
+	}
+	switch xdsPercent.GetDenominator() {
+	case xdstype.FractionalPercent_TEN_THOUSAND:
+		percent.Denominator = v2.DenominatorTenThousand
+	case xdstype.FractionalPercent_MILLION:
+		percent.Denominator = v2.DenominatorMillion
+	}
+	return percent
+}
+
 func convertRetryPolicy(xdsRetryPolicy *xdsroute.RetryPolicy) *v2.RetryPolicy {
 	if xdsRetryPolicy == nil {
 		return &v2.RetryPolicy{}
 	}
 	return &v2.RetryPolicy{
 		RetryPolicyConfig: v2.RetryPolicyConfig{
-			RetryOn:    len(xdsRetryPolicy.GetRetryOn()) > 0,
-			NumRetries: xdsRetryPolicy.GetNumRetries().GetValue(),
+			RetryOn:              len(xdsRetryPolicy.GetRetryOn()) > 0,
+			NumRetries:           xdsRetryPolicy.GetNumRetries().GetValue(),
+			RetryOnConditions:    xdsRetryPolicy.GetRetryOn(),
+			RetriableStatusCodes: xdsRetryPolicy.GetRetriableStatusCodes(),
 		},
 		RetryTimeout: convertTimeDurPoint2TimeDur(xdsRetryPolicy.GetPerTryTimeout()),
 	}
 }
 
-/*
-func convertRedirectAction(xdsRedirectAction *xdsroute.RedirectAction) v2.RedirectAction {
-	if xdsRedirectAction == nil {
-		return v2.RedirectAction{}
+func convertHashPolicy(xdsHashPolicy []*xdsroute.RouteAction_HashPolicy) []v2.HashPolicy {
+	if len(xdsHashPolicy) == 0 {
+		return nil
 	}
-	return v2.RedirectAction{
-		HostRedirect: xdsRedirectAction.GetHostRedirect(),
-		PathRedirect: xdsRedirectAction.GetPathRedirect(),
-		ResponseCode: uint32(xdsRedirectAction.GetResponseCode()),
+	hashPolicy := make([]v2.HashPolicy, 0, len(xdsHashPolicy))
+	for _, p := range xdsHashPolicy {
+		switch {
+		case p.GetHeader() != nil:
+			hashPolicy = append(hashPolicy, v2.HashPolicy{
+				Header: &v2.HeaderHashPolicy{Key: p.GetHeader().GetHeaderName()},
+			})
+		case p.GetCookie() != nil:
+			hashPolicy = append(hashPolicy, v2.HashPolicy{
+				Cookie: &v2.CookieHashPolicy{Name: p.GetCookie().GetName()},
+			})
+		case p.GetConnectionProperties().GetSourceIp():
+			hashPolicy = append(hashPolicy, v2.HashPolicy{
+				SourceIP: &v2.SourceIPHashPolicy{},
+			})
+		default:
+			log.DefaultLogger.Warnf("unsupported hash policy: %v, ignore it", p)
+		}
 	}
+	return hashPolicy
+}
+
+func convertRedirectAction(xdsRedirectAction *xdsroute.RedirectAction) *v2.RedirectAction {
+	if xdsRedirectAction == nil {
+		return nil
+	}
+	redirect := &v2.RedirectAction{
+		SchemeRedirect: xdsRedirectAction.GetSchemeRedirect(),
+		HostRedirect:   xdsRedirectAction.GetHostRedirect(),
+		PortRedirect:   xdsRedirectAction.GetPortRedirect(),
+		PathRedirect:   xdsRedirectAction.GetPathRedirect(),
+		PrefixRewrite:  xdsRedirectAction.GetPrefixRewrite(),
+		StripQuery:     xdsRedirectAction.GetStripQuery(),
+	}
+	if xdsRedirectAction.GetHttpsRedirect() {
+		redirect.SchemeRedirect = "https"
+	}
+	switch xdsRedirectAction.GetResponseCode() {
+	case xdsroute.RedirectAction_MOVED_PERMANENTLY:
+		redirect.ResponseCode = http.StatusMovedPermanently
+	case xdsroute.RedirectAction_FOUND:
+		redirect.ResponseCode = http.StatusFound
+	case xdsroute.RedirectAction_SEE_OTHER:
+		redirect.ResponseCode = http.StatusSeeOther
+	case xdsroute.RedirectAction_TEMPORARY_REDIRECT:
+		redirect.ResponseCode = http.StatusTemporaryRedirect
+	case xdsroute.RedirectAction_PERMANENT_REDIRECT:
+		redirect.ResponseCode = http.StatusPermanentRedirect
+	}
+	return redirect
 }
-*/
 
 /*
 func convertVirtualClusters(xdsVirtualClusters []*xdsroute.VirtualCluster) []v2.VirtualCluster {
@@ -987,11 +1125,14 @@ func convertLbPolicy(xdsLbPolicy xdsapi.Cluster_LbPolicy) v2.LbType {
 	case xdsapi.Cluster_ROUND_ROBIN:
 		return v2.LB_ROUNDROBIN
 	case xdsapi.Cluster_LEAST_REQUEST:
+		return v2.LB_LEAST_REQUEST
 	case xdsapi.Cluster_RING_HASH:
+		return v2.LB_RINGHASH
 	case xdsapi.Cluster_RANDOM:
 		return v2.LB_RANDOM
 	case xdsapi.Cluster_ORIGINAL_DST_LB:
 	case xdsapi.Cluster_MAGLEV:
+		return v2.LB_MAGLEV
 	}
 	//log.DefaultLogger.Fatalf("unsupported lb policy: %s, exchange to LB_RANDOM", xdsLbPolicy.String())
 	return v2.LB_RANDOM
@@ -100,6 +100,50 @@ func Test_convertEndpointsConfig(t *testing.T) {
 			},
 			want: []v2.Host{},
 		},
+		{
+			name: "locality",
+			args: args{
+				xdsEndpoint: &xdsendpoint.LocalityLbEndpoints{
+					Locality: &xdscore.Locality{
+						Region: "region",
+						Zone:   "zone",
+					},
+					LbEndpoints: []xdsendpoint.LbEndpoint{
+						{
+							HostIdentifier: &xdsendpoint.LbEndpoint_Endpoint{
+								Endpoint: &xdsendpoint.Endpoint{
+									Address: &xdscore.Address{
+										Address: &xdscore.Address_SocketAddress{
+											SocketAddress: &xdscore.SocketAddress{
+												Address:       "127.0.0.1",
+												PortSpecifier: &xdscore.SocketAddress_PortValue{PortValue: 8080},
+											},
+										},
+									},
+								},
+							},
+							LoadBalancingWeight: &types.UInt32Value{Value: 20},
+						},
+					},
+					LoadBalancingWeight: &types.UInt32Value{Value: 10},
+					Priority:            1,
+				},
+			},
+			want: []v2.Host{
+				{
+					HostConfig: v2.HostConfig{
+						Address: "127.0.0.1:8080",
+						Weight:  20,
+						Locality: &v2.Locality{
+							Region: "region",
+							Zone:   "zone",
+						},
+						LocalityWeight: 10,
+						Priority:       1,
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -140,6 +184,47 @@ func Test_convertHeaders(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "case2",
+			args: args{
+				xdsHeaders: []*xdsroute.HeaderMatcher{
+					{
+						Name: "x-canary",
+						HeaderMatchSpecifier: &xdsroute.HeaderMatcher_PresentMatch{
+							PresentMatch: false,
+						},
+					},
+					{
+						Name: "x-user-id",
+						HeaderMatchSpecifier: &xdsroute.HeaderMatcher_RangeMatch{
+							RangeMatch: &xdstype.Int64Range{Start: 100, End: 200},
+						},
+						InvertMatch: true,
+					},
+					{
+						Name: "user-agent",
+						HeaderMatchSpecifier: &xdsroute.HeaderMatcher_SuffixMatch{
+							SuffixMatch: "Mobile",
+						},
+					},
+				},
+			},
+			want: []v2.HeaderMatcher{
+				{
+					Name:        "x-canary",
+					AbsentMatch: true,
+				},
+				{
+					Name:        "x-user-id",
+					RangeMatch:  &v2.Int64Range{Start: 100, End: 200},
+					InvertMatch: true,
+				},
+				{
+					Name:        "user-agent",
+					SuffixMatch: "Mobile",
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -769,3 +854,48 @@ func Test_convertPerRouteConfig(t *testing.T) {
 	}
 
 }
+
+func Test_convertRedirectAction(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *xdsroute.RedirectAction
+		want *v2.RedirectAction
+	}{
+		{
+			name: "https redirect",
+			in: &xdsroute.RedirectAction{
+				SchemeRewriteSpecifier: &xdsroute.RedirectAction_HttpsRedirect{HttpsRedirect: true},
+				HostRedirect:           "mosn.io",
+				PathRewriteSpecifier:   &xdsroute.RedirectAction_PrefixRewrite{PrefixRewrite: "/bar"},
+				StripQuery:             true,
+			},
+			want: &v2.RedirectAction{
+				SchemeRedirect: "https",
+				HostRedirect:   "mosn.io",
+				PrefixRewrite:  "/bar",
+				ResponseCode:   301,
+				StripQuery:     true,
+			},
+		},
+		{
+			name: "path redirect",
+			in: &xdsroute.RedirectAction{
+				PortRedirect:         8080,
+				PathRewriteSpecifier: &xdsroute.RedirectAction_PathRedirect{PathRedirect: "/bar"},
+				ResponseCode:         xdsroute.RedirectAction_TEMPORARY_REDIRECT,
+			},
+			want: &v2.RedirectAction{
+				PortRedirect: 8080,
+				PathRedirect: "/bar",
+				ResponseCode: 307,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertRedirectAction(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("convertRedirectAction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}